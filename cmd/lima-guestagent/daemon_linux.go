@@ -24,6 +24,11 @@ func newDaemonCommand() *cobra.Command {
 	daemonCommand.Flags().Duration("tick", 3*time.Second, "tick for polling events")
 	daemonCommand.Flags().Int("vsock-port", 0, "use vsock server instead a UNIX socket")
 	daemonCommand.Flags().String("virtio-port", "", "use virtio server instead a UNIX socket")
+	daemonCommand.Flags().Int("port-scan-concurrency", 4, "maximum number of the port sources scanned concurrently")
+	daemonCommand.Flags().Duration("port-scan-timeout", 0, "timeout of each scan of the local ports (0 for no timeout)")
+	daemonCommand.Flags().Int("port-scan-max-ports", 0, "maximum number of the local ports reported by each scan (0 for no limit)")
+	daemonCommand.Flags().StringSlice("exclude-interfaces", nil, "do not report the ports bound exclusively to the addresses of these interfaces")
+	daemonCommand.Flags().Duration("iptables-ttl", time.Minute, "maximum age of the cached iptables rules (0 for refreshing only on netfilter audit events)")
 	return daemonCommand
 }
 
@@ -41,6 +46,26 @@ func daemonAction(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
+	portScanConcurrency, err := cmd.Flags().GetInt("port-scan-concurrency")
+	if err != nil {
+		return err
+	}
+	portScanTimeout, err := cmd.Flags().GetDuration("port-scan-timeout")
+	if err != nil {
+		return err
+	}
+	portScanMaxPorts, err := cmd.Flags().GetInt("port-scan-max-ports")
+	if err != nil {
+		return err
+	}
+	iptablesTTL, err := cmd.Flags().GetDuration("iptables-ttl")
+	if err != nil {
+		return err
+	}
+	excludeInterfaces, err := cmd.Flags().GetStringSlice("exclude-interfaces")
+	if err != nil {
+		return err
+	}
 	if tick == 0 {
 		return errors.New("tick must be specified")
 	}
@@ -49,15 +74,16 @@ func daemonAction(cmd *cobra.Command, _ []string) error {
 	}
 	logrus.Infof("event tick: %v", tick)
 
-	newTicker := func() (<-chan time.Time, func()) {
-		// TODO: use an equivalent of `bpftrace -e 'tracepoint:syscalls:sys_*_bind { printf("tick\n"); }')`,
-		// without depending on `bpftrace` binary.
-		// The agent binary will need CAP_BPF file cap.
-		ticker := time.NewTicker(tick)
-		return ticker.C, ticker.Stop
-	}
-
-	agent, err := guestagent.New(newTicker, tick*20)
+	// TODO: use an equivalent of `bpftrace -e 'tracepoint:syscalls:sys_*_bind { printf("tick\n"); }')`,
+	// without depending on `bpftrace` binary.
+	// The agent binary will need CAP_BPF file cap.
+	agent, err := guestagent.New(tick, tick*20,
+		guestagent.WithScanConcurrency(portScanConcurrency),
+		guestagent.WithScanTimeout(portScanTimeout),
+		guestagent.WithScanMaxPorts(portScanMaxPorts),
+		guestagent.WithIPTablesTTL(iptablesTTL),
+		guestagent.WithExcludeInterfaces(excludeInterfaces),
+	)
 	if err != nil {
 		return err
 	}
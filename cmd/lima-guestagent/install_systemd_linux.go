@@ -22,6 +22,7 @@ func newInstallSystemdCommand() *cobra.Command {
 	}
 	installSystemdCommand.Flags().Int("vsock-port", 0, "use vsock server on specified port")
 	installSystemdCommand.Flags().String("virtio-port", "", "use virtio server instead a UNIX socket")
+	installSystemdCommand.Flags().StringSlice("exclude-interfaces", nil, "do not report the ports bound exclusively to the addresses of these interfaces")
 	return installSystemdCommand
 }
 
@@ -34,7 +35,11 @@ func installSystemdAction(cmd *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
-	unit, err := generateSystemdUnit(vsockPort, virtioPort)
+	excludeInterfaces, err := cmd.Flags().GetStringSlice("exclude-interfaces")
+	if err != nil {
+		return err
+	}
+	unit, err := generateSystemdUnit(vsockPort, virtioPort, excludeInterfaces)
 	if err != nil {
 		return err
 	}
@@ -73,7 +78,7 @@ func installSystemdAction(cmd *cobra.Command, _ []string) error {
 //go:embed lima-guestagent.TEMPLATE.service
 var systemdUnitTemplate string
 
-func generateSystemdUnit(vsockPort int, virtioPort string) ([]byte, error) {
+func generateSystemdUnit(vsockPort int, virtioPort string, excludeInterfaces []string) ([]byte, error) {
 	selfExeAbs, err := os.Executable()
 	if err != nil {
 		return nil, err
@@ -86,6 +91,9 @@ func generateSystemdUnit(vsockPort int, virtioPort string) ([]byte, error) {
 	if virtioPort != "" {
 		args = append(args, fmt.Sprintf("--virtio-port %s", virtioPort))
 	}
+	if len(excludeInterfaces) > 0 {
+		args = append(args, fmt.Sprintf("--exclude-interfaces %s", strings.Join(excludeInterfaces, ",")))
+	}
 
 	m := map[string]string{
 		"Binary": selfExeAbs,
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"time"
+
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	hostagentclient "github.com/lima-vm/lima/pkg/hostagent/api/client"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/spf13/cobra"
+)
+
+func newConfigCommand() *cobra.Command {
+	configCommand := &cobra.Command{
+		Use:     "config",
+		Short:   "Manage the runtime config of running instances",
+		GroupID: advancedCommand,
+	}
+	configCommand.AddCommand(newConfigSetCommand())
+	return configCommand
+}
+
+const configSetExample = `
+To change the number of the CPUs to 4, and the memory size to 2GiB:
+$ limactl config set --cpus=4 --memory=2GiB default
+`
+
+func newConfigSetCommand() *cobra.Command {
+	setCommand := &cobra.Command{
+		Use:   "set INSTANCE",
+		Short: "Change the CPUs and the memory of a running instance",
+		Long: `Change the CPUs and the memory of a running instance, and print the effective values.
+
+The changes are not persisted to lima.yaml, and are lost on restarting the instance.
+Use ` + "`limactl edit`" + ` to change the values persistently.
+
+The support depends on the VM type:
+- qemu: requires ` + "`vmOpts.qemu.runtimeResize: true`" + ` in lima.yaml.
+        The CPUs can be hot-added up to the number of the host CPUs (x86_64 only),
+        and the memory can be shrunk and grown back up to the size on boot.
+- others: not supported`,
+		Example:           configSetExample,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              configSetAction,
+		ValidArgsFunction: configBashComplete,
+	}
+	setCommand.Flags().Int("cpus", 0, "Number of CPUs")
+	setCommand.Flags().String("memory", "", "Memory size, e.g., \"2GiB\"")
+	return setCommand
+}
+
+func configSetAction(cmd *cobra.Command, args []string) error {
+	var config hostagentapi.DriverConfig
+	if cmd.Flags().Changed("cpus") {
+		cpus, err := cmd.Flags().GetInt("cpus")
+		if err != nil {
+			return err
+		}
+		config.CPUs = &cpus
+	}
+	if cmd.Flags().Changed("memory") {
+		memory, err := cmd.Flags().GetString("memory")
+		if err != nil {
+			return err
+		}
+		config.Memory = &memory
+	}
+	if len(config.Fields()) == 0 {
+		return errors.New("no value is specified (hint: specify --cpus or --memory)")
+	}
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	if inst.Status == store.StatusStopped {
+		return fmt.Errorf("instance %q is stopped, run `limactl edit %s` to change the config of a stopped instance", inst.Name, inst.Name)
+	}
+	if err := store.CheckRunning(inst); err != nil {
+		return err
+	}
+	haSock := filepath.Join(inst.Dir, filenames.HostAgentSock)
+	haClient, err := hostagentclient.NewHostAgentClient(haSock, hostagentclient.WithTimeout(time.Minute))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), 2*time.Minute)
+	defer cancel()
+	effective, err := haClient.PatchDriverConfig(ctx, config)
+	if err != nil {
+		return fmt.Errorf("failed to change the config of instance %q: %w", inst.Name, err)
+	}
+	printDriverConfig(cmd.OutOrStdout(), effective)
+	return nil
+}
+
+func printDriverConfig(w io.Writer, config *hostagentapi.DriverConfig) {
+	if config.CPUs != nil {
+		fmt.Fprintf(w, "cpus: %d\n", *config.CPUs)
+	}
+	if config.Memory != nil {
+		fmt.Fprintf(w, "memory: %s\n", *config.Memory)
+	}
+}
+
+func configBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
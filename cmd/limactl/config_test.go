@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestConfigSetNotRunning(t *testing.T) {
+	createStoppedInstance(t, "")
+	err := executeApp(t, "config", "set", "foo", "--cpus", "2")
+	assert.ErrorContains(t, err, "run `limactl edit foo`")
+}
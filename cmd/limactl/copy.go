@@ -1,12 +1,17 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"runtime"
+	"strconv"
 	"strings"
 
+	"al.essio.dev/pkg/shellescape"
+	"github.com/containerd/containerd/identifiers"
 	"github.com/coreos/go-semver/semver"
 	"github.com/lima-vm/lima/pkg/sshutil"
 	"github.com/lima-vm/lima/pkg/store"
@@ -17,8 +22,35 @@ import (
 const copyHelp = `Copy files between host and guest
 
 Prefix guest filenames with the instance name and a colon.
+An empty instance name refers to $LIMA_INSTANCE, or "default" when $LIMA_INSTANCE is not set.
 
 Example: limactl copy default:/etc/os-release .
+
+Example: LIMA_INSTANCE=work limactl copy ./file :/tmp/
+
+Host paths with a Windows drive letter are not treated as instance names.
+
+Example: limactl copy C:\data default:/tmp/
+
+Files matching the --exclude patterns are skipped. This requires rsync,
+and only a single instance may be involved.
+
+Example: limactl copy -r --exclude node_modules --exclude .git ./src default:/tmp/src
+
+With --sudo, the files are read and written in the guest as root, e.g., to copy files into
+root-owned directories. This requires rsync and passwordless sudo in the guest.
+
+Example: limactl copy --sudo ./nginx.conf default:/etc/nginx/nginx.conf
+
+For the instances running on a remote host, the SSH jump host can be specified with --jump-host
+(or $` + sshutil.EnvSSHJumpHost + `). The instance still has to exist in the local store (see limactl shell --help).
+
+Example: limactl copy --jump-host user@remote.example.com default:/etc/os-release .
+
+With --tag, the files are copied for each of the instances with the tag (` + "`tags`" + ` in lima.yaml) in turn.
+The guest paths must be specified without the instance name.
+
+Example: limactl copy --tag ci ./config.toml :/tmp/
 `
 
 func newCopyCommand() *cobra.Command {
@@ -34,11 +66,38 @@ func newCopyCommand() *cobra.Command {
 
 	copyCommand.Flags().BoolP("recursive", "r", false, "copy directories recursively")
 	copyCommand.Flags().BoolP("verbose", "v", false, "enable verbose output")
+	copyCommand.Flags().StringArray("exclude", nil, "exclude files matching PATTERN (requires rsync, can be specified multiple times)")
+	copyCommand.Flags().Bool("sudo", false, "read and write the files in the guest as root (requires rsync and passwordless sudo in the guest)")
+	registerJumpHostFlag(copyCommand)
+	registerTagFlag(copyCommand)
 
 	return copyCommand
 }
 
 func copyAction(cmd *cobra.Command, args []string) error {
+	instNames, err := taggedInstanceNames(cmd, nil)
+	if err != nil {
+		return err
+	}
+	if instNames == nil {
+		return copyPaths(cmd, args)
+	}
+	var errs []error
+	for _, instName := range instNames {
+		instArgs, err := copyArgsForInstance(args, instName)
+		if err != nil {
+			return err
+		}
+		logrus.Infof("Copying files for instance %q", instName)
+		if err := copyPaths(cmd, instArgs); err != nil {
+			errs = append(errs, fmt.Errorf("instance %q: %w", instName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// copyPaths copies the files specified in args, as described in copyHelp.
+func copyPaths(cmd *cobra.Command, args []string) error {
 	recursive, err := cmd.Flags().GetBool("recursive")
 	if err != nil {
 		return err
@@ -49,10 +108,28 @@ func copyAction(cmd *cobra.Command, args []string) error {
 		return err
 	}
 
-	arg0, err := exec.LookPath("scp")
+	excludes, err := cmd.Flags().GetStringArray("exclude")
 	if err != nil {
 		return err
 	}
+	if err := validateCopyExcludes(excludes); err != nil {
+		return err
+	}
+
+	sudo, err := cmd.Flags().GetBool("sudo")
+	if err != nil {
+		return err
+	}
+
+	tool, arg0, err := copyTool(excludes, sudo)
+	if err != nil {
+		return err
+	}
+	jumpHost, err := jumpHost(cmd)
+	if err != nil {
+		return err
+	}
+	var paths []copyPath
 	instances := make(map[string]*store.Instance)
 	scpFlags := []string{}
 	scpArgs := []string{}
@@ -75,34 +152,41 @@ func copyAction(cmd *cobra.Command, args []string) error {
 		scpFlags = append(scpFlags, "-r")
 	}
 	// this assumes that ssh and scp come from the same place, but scp has no -V
-	legacySSH := sshutil.DetectOpenSSHVersion("ssh").LessThan(*semver.New("8.0.0"))
-	for _, arg := range args {
-		path := strings.Split(arg, ":")
-		switch len(path) {
-		case 1:
+	var legacySSH bool
+	if sshVersion, err := sshutil.DetectOpenSSHVersion("ssh"); err != nil {
+		logrus.WithError(err).Warn("Failed to detect the OpenSSH version; assuming OpenSSH 8.0 or later")
+	} else {
+		legacySSH = sshVersion.LessThan(*semver.New("8.0.0"))
+	}
+	for i, arg := range args {
+		instName, guestPath, isGuest, err := parseCopyArg(arg)
+		if err != nil {
+			return err
+		}
+		if !isGuest {
 			scpArgs = append(scpArgs, arg)
-		case 2:
-			instName := path[0]
-			inst, err := store.Inspect(instName)
-			if err != nil {
-				if errors.Is(err, os.ErrNotExist) {
-					return fmt.Errorf("instance %q does not exist, run `limactl create %s` to create a new instance", instName, instName)
-				}
-				return err
-			}
-			if inst.Status == store.StatusStopped {
-				return fmt.Errorf("instance %q is stopped, run `limactl start %s` to start the instance", instName, instName)
-			}
-			if legacySSH {
-				scpFlags = append(scpFlags, "-P", fmt.Sprintf("%d", inst.SSHLocalPort))
-				scpArgs = append(scpArgs, fmt.Sprintf("%s@127.0.0.1:%s", *inst.Config.User.Name, path[1]))
-			} else {
-				scpArgs = append(scpArgs, fmt.Sprintf("scp://%s@127.0.0.1:%d/%s", *inst.Config.User.Name, inst.SSHLocalPort, path[1]))
-			}
-			instances[instName] = inst
-		default:
-			return fmt.Errorf("path %q contains multiple colons", arg)
+			paths = append(paths, copyPath{path: arg})
+			continue
 		}
+		// The last arg is the target
+		inst, err := inspectCopyInstance(instName, i == len(args)-1, jumpHost)
+		if err != nil {
+			return err
+		}
+		if legacySSH {
+			scpFlags = append(scpFlags, "-P", fmt.Sprintf("%d", inst.SSHLocalPort))
+			scpArgs = append(scpArgs, fmt.Sprintf("%s@%s:%s", *inst.Config.User.Name, inst.SSHAddress, guestPath))
+		} else {
+			scpArgs = append(scpArgs, fmt.Sprintf("scp://%s@%s:%d/%s", *inst.Config.User.Name, inst.SSHAddress, inst.SSHLocalPort, guestPath))
+		}
+		instances[instName] = inst
+		paths = append(paths, copyPath{user: *inst.Config.User.Name, host: inst.SSHAddress, port: inst.SSHLocalPort, path: guestPath})
+	}
+	if sudo && len(instances) == 0 {
+		return errors.New("--sudo requires a guest path")
+	}
+	if tool == "rsync" {
+		return rsyncCopy(cmd, arg0, instances, paths, excludes, jumpHost, verbose, recursive, sudo)
 	}
 	if legacySSH && len(instances) > 1 {
 		return errors.New("more than one (instance) host is involved in this command, this is only supported for openSSH v8.0 or higher")
@@ -116,7 +200,7 @@ func copyAction(cmd *cobra.Command, args []string) error {
 		// arguments such as ControlPath.  This is preferred as we can multiplex
 		// sessions without re-authenticating (MaxSessions permitting).
 		for _, inst := range instances {
-			sshOpts, err = sshutil.SSHOpts("ssh", inst.Dir, *inst.Config.User.Name, false, false, false, false)
+			sshOpts, err = copySSHOpts(inst, jumpHost)
 			if err != nil {
 				return err
 			}
@@ -127,6 +211,10 @@ func copyAction(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		if jumpHost != "" {
+			// The instances are on the same remote host
+			sshOpts = append(sshOpts, "ProxyJump="+jumpHost)
+		}
 	}
 	sshArgs := sshutil.SSHArgsFromOpts(sshOpts)
 
@@ -139,3 +227,192 @@ func copyAction(cmd *cobra.Command, args []string) error {
 	// TODO: use syscall.Exec directly (results in losing tty?)
 	return sshCmd.Run()
 }
+
+// parseCopyArg parses "INSTANCE:PATH" (guest) or "PATH" (host).
+// An empty INSTANCE (":PATH") resolves to $LIMA_INSTANCE, or "default" when $LIMA_INSTANCE is not set.
+func parseCopyArg(arg string) (instName, path string, isGuest bool, err error) {
+	if isWindowsDrivePath(arg, runtime.GOOS) {
+		return "", arg, false, nil
+	}
+	parts := strings.Split(arg, ":")
+	switch len(parts) {
+	case 1:
+		return "", arg, false, nil
+	case 2:
+		instName = parts[0]
+		if instName == "" {
+			instName = os.Getenv("LIMA_INSTANCE")
+		}
+		if instName == "" {
+			instName = DefaultInstanceName
+		}
+		// Existing instances may have names that are not allowed by store.ValidateInstanceName
+		// (e.g., uppercase), so only the syntax accepted by store.InstanceDir is checked here.
+		if err := identifiers.Validate(instName); err != nil {
+			return "", "", false, fmt.Errorf("invalid instance name in %q: %w", arg, err)
+		}
+		return instName, parts[1], true, nil
+	default:
+		return "", "", false, fmt.Errorf("path %q contains multiple colons", arg)
+	}
+}
+
+// isWindowsDrivePath returns true if arg is a host path with a drive letter, e.g., `C:\data`.
+// `C:/data` is only recognized as a drive path on Windows hosts, as it is also a valid path in
+// an instance named "C" elsewhere.
+func isWindowsDrivePath(arg, goos string) bool {
+	if len(arg) < 3 || arg[1] != ':' {
+		return false
+	}
+	c := arg[0]
+	if (c < 'A' || c > 'Z') && (c < 'a' || c > 'z') {
+		return false
+	}
+	return arg[2] == '\\' || (arg[2] == '/' && goos == "windows")
+}
+
+// copyPath is a path on the host, or a path in an instance when port is non-zero.
+type copyPath struct {
+	user string
+	host string // the SSH address of the instance
+	port int
+	path string
+}
+
+func validateCopyExcludes(excludes []string) error {
+	for _, pattern := range excludes {
+		if strings.TrimSpace(pattern) == "" {
+			return errors.New("--exclude pattern must not be empty")
+		}
+	}
+	return nil
+}
+
+// copyTool returns the name and the path of the tool used for copying.
+// scp is preferred, as rsync is not always installed on the host and the guest,
+// but scp can neither exclude files nor run as root in the guest.
+func copyTool(excludes []string, sudo bool) (tool, path string, err error) {
+	if len(excludes) == 0 && !sudo {
+		path, err = exec.LookPath("scp")
+		return "scp", path, err
+	}
+	path, err = exec.LookPath("rsync")
+	if err != nil {
+		if sudo {
+			return "", "", fmt.Errorf("--sudo requires rsync, as scp cannot run as root in the guest: %w", err)
+		}
+		return "", "", fmt.Errorf("--exclude requires rsync, as scp does not support excluding files: %w", err)
+	}
+	return "rsync", path, nil
+}
+
+// inspectCopyInstance inspects the instance to copy files from, or to when isTarget is true.
+// It fails fast unless the instance is running, as SSH would hang or fail obscurely.
+// Files cannot be copied into a read-only instance.
+func inspectCopyInstance(instName string, isTarget bool, jumpHost string) (*store.Instance, error) {
+	inst, err := store.Inspect(instName)
+	if err != nil {
+		if jumpHost != "" {
+			return nil, jumpHostInstanceError(err)
+		}
+		return nil, err
+	}
+	if isTarget {
+		if err := store.CheckWritable(inst, "copying files into the guest"); err != nil {
+			return nil, err
+		}
+	}
+	if jumpHost != "" {
+		if err := checkJumpHostInstance(inst); err != nil {
+			return nil, err
+		}
+	} else if err := store.CheckRunning(inst); err != nil {
+		return nil, err
+	}
+	if len(inst.DegradedReasons) > 0 {
+		logrus.Warnf("Instance %q is degraded (%s), SSH may be unavailable", instName, strings.Join(inst.DegradedReasons, "; "))
+	}
+	return inst, nil
+}
+
+// copySSHOpts returns the ssh options for copying files from and to the instance.
+// The agent and X11 are never forwarded for copying, even when they are enabled in lima.yaml.
+func copySSHOpts(inst *store.Instance, jumpHost string) ([]string, error) {
+	return inst.SSHOpts("ssh",
+		sshutil.WithJumpHost(jumpHost),
+		sshutil.WithForwardAgent(false),
+		sshutil.WithForwardX11(false))
+}
+
+func rsyncCopy(cmd *cobra.Command, arg0 string, instances map[string]*store.Instance, paths []copyPath, excludes []string, jumpHost string, verbose, recursive, sudo bool) error {
+	if len(instances) > 1 {
+		return errors.New("more than one (instance) host is involved in this command, this is not supported with --exclude or --sudo")
+	}
+	sshExe, err := exec.LookPath("ssh")
+	if err != nil {
+		return err
+	}
+	sshCmd := []string{sshExe}
+	for _, inst := range instances {
+		sshOpts, err := copySSHOpts(inst, jumpHost)
+		if err != nil {
+			return err
+		}
+		sshCmd = append(sshCmd, sshutil.SSHArgsFromOpts(sshOpts)...)
+		if sudo {
+			checkPasswordlessSudo(cmd.Context(), append(sshCmd, "-p", strconv.Itoa(inst.SSHLocalPort), inst.SSHAddress), inst.Name)
+		}
+	}
+	rsyncCmd := exec.Command(arg0, rsyncCopyArgs(shellescape.QuoteCommand(sshCmd), paths, excludes, verbose, recursive, sudo)...)
+	rsyncCmd.Stdin = cmd.InOrStdin()
+	rsyncCmd.Stdout = cmd.OutOrStdout()
+	rsyncCmd.Stderr = cmd.ErrOrStderr()
+	logrus.Debugf("executing rsync (may take a long time): %+v", rsyncCmd.Args)
+	return rsyncCmd.Run()
+}
+
+// checkPasswordlessSudo warns when sudo in the guest asks for a password, as rsync would hang or fail then.
+// sshCmd is the ssh command line to connect to the instance, without the remote command.
+func checkPasswordlessSudo(ctx context.Context, sshCmd []string, instName string) {
+	checkCmd := exec.CommandContext(ctx, sshCmd[0], append(sshCmd[1:], "--", "sudo", "-n", "true")...)
+	logrus.Debugf("executing %v", checkCmd.Args)
+	if out, err := checkCmd.CombinedOutput(); err != nil {
+		logrus.WithError(err).Warnf("The user of instance %q does not seem to have passwordless sudo, --sudo may fail: %q", instName, strings.TrimSpace(string(out)))
+	}
+}
+
+// rsyncCopyArgs returns the rsync arguments for copying paths.
+// At most one instance may be involved, as rsync cannot copy between two remote hosts.
+// With sudo, rsync runs as root in the guest.
+func rsyncCopyArgs(sshCmd string, paths []copyPath, excludes []string, verbose, recursive, sudo bool) []string {
+	var args []string
+	if verbose {
+		args = append(args, "-v")
+	} else {
+		args = append(args, "-q")
+	}
+	if recursive {
+		args = append(args, "-r")
+	}
+	if sudo {
+		args = append(args, "--rsync-path=sudo rsync")
+	}
+	for _, pattern := range excludes {
+		args = append(args, "--exclude", pattern)
+	}
+	for _, p := range paths {
+		if p.port != 0 {
+			args = append(args, "-e", fmt.Sprintf("%s -p %d", sshCmd, p.port))
+			break
+		}
+	}
+	args = append(args, "--")
+	for _, p := range paths {
+		if p.port != 0 {
+			args = append(args, fmt.Sprintf("%s@%s:%s", p.user, p.host, p.path))
+		} else {
+			args = append(args, p.path)
+		}
+	}
+	return args
+}
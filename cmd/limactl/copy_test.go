@@ -0,0 +1,190 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestRsyncCopyArgs(t *testing.T) {
+	paths := []copyPath{
+		{path: "./src"},
+		{user: "foo", host: "127.0.0.1", port: 60022, path: "/tmp/src"},
+	}
+	args := rsyncCopyArgs("ssh -o IdentityFile=/key", paths, []string{"node_modules", ".git"}, false, true, false)
+	assert.DeepEqual(t, args, []string{
+		"-q", "-r",
+		"--exclude", "node_modules",
+		"--exclude", ".git",
+		"-e", "ssh -o IdentityFile=/key -p 60022",
+		"--",
+		"./src", "foo@127.0.0.1:/tmp/src",
+	})
+}
+
+func TestRsyncCopyArgsSudo(t *testing.T) {
+	paths := []copyPath{
+		{path: "./nginx.conf"},
+		{user: "foo", host: "127.0.0.1", port: 60022, path: "/etc/nginx/nginx.conf"},
+	}
+	args := rsyncCopyArgs("ssh", paths, nil, false, false, true)
+	assert.Assert(t, slices.Contains(args, "--rsync-path=sudo rsync"), "%v", args)
+
+	args = rsyncCopyArgs("ssh", paths, nil, false, false, false)
+	assert.Assert(t, !slices.ContainsFunc(args, func(arg string) bool { return strings.HasPrefix(arg, "--rsync-path") }), "%v", args)
+}
+
+func TestValidateCopyExcludes(t *testing.T) {
+	assert.NilError(t, validateCopyExcludes(nil))
+	assert.NilError(t, validateCopyExcludes([]string{"*.o"}))
+	assert.ErrorContains(t, validateCopyExcludes([]string{"*.o", " "}), "must not be empty")
+}
+
+func TestCopyTool(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"scp", "rsync"} {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"), 0o755))
+	}
+	t.Setenv("PATH", dir)
+
+	tool, _, err := copyTool(nil, false)
+	assert.NilError(t, err)
+	assert.Equal(t, tool, "scp")
+
+	tool, _, err = copyTool([]string{"node_modules"}, false)
+	assert.NilError(t, err)
+	assert.Equal(t, tool, "rsync")
+
+	tool, _, err = copyTool(nil, true)
+	assert.NilError(t, err)
+	assert.Equal(t, tool, "rsync")
+
+	assert.NilError(t, os.Remove(filepath.Join(dir, "rsync")))
+	_, _, err = copyTool([]string{"node_modules"}, false)
+	assert.ErrorContains(t, err, "--exclude requires rsync")
+	_, _, err = copyTool(nil, true)
+	assert.ErrorContains(t, err, "--sudo requires rsync")
+}
+
+func TestParseCopyArg(t *testing.T) {
+	t.Setenv("LIMA_INSTANCE", "")
+	instName, path, isGuest, err := parseCopyArg("./file")
+	assert.NilError(t, err)
+	assert.Assert(t, !isGuest)
+	assert.Equal(t, path, "./file")
+
+	instName, path, isGuest, err = parseCopyArg("foo:/tmp/")
+	assert.NilError(t, err)
+	assert.Assert(t, isGuest)
+	assert.Equal(t, instName, "foo")
+	assert.Equal(t, path, "/tmp/")
+
+	instName, path, isGuest, err = parseCopyArg(":/tmp/")
+	assert.NilError(t, err)
+	assert.Assert(t, isGuest)
+	assert.Equal(t, instName, DefaultInstanceName)
+	assert.Equal(t, path, "/tmp/")
+
+	t.Setenv("LIMA_INSTANCE", "work")
+	instName, _, isGuest, err = parseCopyArg(":/tmp/")
+	assert.NilError(t, err)
+	assert.Assert(t, isGuest)
+	assert.Equal(t, instName, "work")
+
+	// An explicit instance name takes precedence over $LIMA_INSTANCE
+	instName, _, _, err = parseCopyArg("foo:/tmp/")
+	assert.NilError(t, err)
+	assert.Equal(t, instName, "foo")
+
+	_, _, _, err = parseCopyArg("foo:/tmp:/bar")
+	assert.ErrorContains(t, err, "multiple colons")
+
+	_, _, _, err = parseCopyArg("foo bar:/tmp/")
+	assert.ErrorContains(t, err, "invalid instance name")
+
+	// A drive letter followed by a backslash is a host path on any host
+	instName, path, isGuest, err = parseCopyArg(`C:\data`)
+	assert.NilError(t, err)
+	assert.Assert(t, !isGuest)
+	assert.Equal(t, instName, "")
+	assert.Equal(t, path, `C:\data`)
+
+	// A single letter followed by a colon without a separator is an instance name
+	instName, path, isGuest, err = parseCopyArg("c:tmp")
+	assert.NilError(t, err)
+	assert.Assert(t, isGuest)
+	assert.Equal(t, instName, "c")
+	assert.Equal(t, path, "tmp")
+}
+
+func TestIsWindowsDrivePath(t *testing.T) {
+	testCases := []struct {
+		arg      string
+		goos     string
+		expected bool
+	}{
+		{`C:\data`, "windows", true},
+		{`c:\Users\foo\file.txt`, "windows", true},
+		{`C:\data`, "linux", true},
+		{"C:/data", "windows", true},
+		{"C:/data", "darwin", false},
+		{"default:/tmp", "windows", false},
+		{":/tmp", "windows", false},
+		{"1:/tmp", "windows", false},
+		{"C:", "windows", false},
+		{"C:data", "windows", false},
+		{"./file", "windows", false},
+		{`\\server\share`, "windows", false},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, isWindowsDrivePath(tc.arg, tc.goos), tc.expected, "%q on %s", tc.arg, tc.goos)
+	}
+}
+
+func TestCopyReadOnly(t *testing.T) {
+	createStoppedInstance(t, "readOnly: true\n")
+	dir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, "scp"), []byte("#!/bin/sh\n"), 0o755))
+	t.Setenv("PATH", dir)
+	// Copying into the guest is refused
+	err := executeApp(t, "copy", "./file", "foo:/tmp/file")
+	assert.Assert(t, errors.Is(err, store.ErrInstanceReadOnly), "%v", err)
+	// Copying from the guest is allowed, but the instance has to be running
+	err = executeApp(t, "copy", "foo:/etc/os-release", ".")
+	assert.Assert(t, errors.Is(err, store.ErrInstanceStopped), "%v", err)
+}
+
+func TestCopySSHOpts(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	inst := &store.Instance{
+		Dir: filepath.Join(limaHome, "default"),
+		Config: &limayaml.LimaYAML{
+			User: limayaml.User{Name: ptr.Of("foo")},
+			SSH: limayaml.SSH{
+				LoadDotSSHPubKeys: ptr.Of(false),
+				ForwardAgent:      ptr.Of(true),
+				ForwardX11:        ptr.Of(true),
+				ForwardX11Trusted: ptr.Of(true),
+				KeepAlive: limayaml.SSHKeepAlive{
+					Interval: ptr.Of(30),
+					CountMax: ptr.Of(3),
+				},
+			},
+		},
+	}
+	opts, err := copySSHOpts(inst, "")
+	assert.NilError(t, err)
+	assert.Assert(t, !slices.Contains(opts, "ForwardAgent=yes"))
+	assert.Assert(t, !slices.Contains(opts, "ForwardX11=yes"))
+}
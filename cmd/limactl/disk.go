@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io/fs"
 	"os"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/docker/go-units"
@@ -192,7 +193,11 @@ func diskListAction(cmd *cobra.Command, args []string) error {
 			logrus.WithError(err).Errorf("disk %q does not exist?", diskName)
 			continue
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", disk.Name, units.BytesSize(float64(disk.Size)), disk.Format, disk.Dir, disk.Instance)
+		inUseBy := disk.Instance
+		if inUseBy == "" && len(disk.ReadOnlyInstances) > 0 {
+			inUseBy = strings.Join(disk.ReadOnlyInstances, ",") + " (read-only)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", disk.Name, units.BytesSize(float64(disk.Size)), disk.Format, disk.Dir, inUseBy)
 	}
 
 	return w.Flush()
@@ -251,6 +256,9 @@ func diskDeleteAction(cmd *cobra.Command, args []string) error {
 			if disk.Instance != "" {
 				return fmt.Errorf("cannot delete disk %q in use by instance %q", disk.Name, disk.Instance)
 			}
+			if len(disk.ReadOnlyInstances) > 0 {
+				return fmt.Errorf("cannot delete disk %q in use by instances %q (read-only)", disk.Name, disk.ReadOnlyInstances)
+			}
 			var refInstances []string
 			for _, inst := range instances {
 				for _, d := range inst.AdditionalDisks {
@@ -316,31 +324,48 @@ func diskUnlockAction(_ *cobra.Command, args []string) error {
 			}
 			return err
 		}
-		if disk.Instance == "" {
+		if disk.Instance == "" && len(disk.ReadOnlyInstances) == 0 {
 			logrus.Warnf("Ignoring unlocked disk %q", diskName)
 			continue
 		}
-		// if store.Inspect throws an error, the instance does not exist, and it is safe to unlock
-		inst, err := store.Inspect(disk.Instance)
-		if err == nil {
-			if len(inst.Errors) > 0 {
-				logrus.Warnf("Cannot unlock disk %q, attached instance %q has errors: %+v",
-					diskName, disk.Instance, inst.Errors)
-				continue
+		if disk.Instance != "" && canUnlockDisk(diskName, disk.Instance) {
+			if err := disk.Unlock(); err != nil {
+				return fmt.Errorf("failed to unlock disk %q: %w", diskName, err)
 			}
-			if inst.Status == store.StatusRunning {
-				logrus.Warnf("Cannot unlock disk %q used by running instance %q", diskName, disk.Instance)
+			logrus.Infof("Unlocked disk %q (%q)", diskName, disk.Dir)
+		}
+		for _, instName := range disk.ReadOnlyInstances {
+			if !canUnlockDisk(diskName, instName) {
 				continue
 			}
+			if err := disk.UnlockReadOnly(instName); err != nil {
+				return fmt.Errorf("failed to unlock disk %q for instance %q: %w", diskName, instName, err)
+			}
+			logrus.Infof("Unlocked disk %q (%q) for instance %q", diskName, disk.Dir, instName)
 		}
-		if err := disk.Unlock(); err != nil {
-			return fmt.Errorf("failed to unlock disk %q: %w", diskName, err)
-		}
-		logrus.Infof("Unlocked disk %q (%q)", diskName, disk.Dir)
 	}
 	return nil
 }
 
+// canUnlockDisk returns whether the lock of the disk held by the instance is stale.
+func canUnlockDisk(diskName, instName string) bool {
+	// if store.Inspect throws an error, the instance does not exist, and it is safe to unlock
+	inst, err := store.Inspect(instName)
+	if err != nil {
+		return true
+	}
+	if len(inst.Errors) > 0 {
+		logrus.Warnf("Cannot unlock disk %q, attached instance %q has errors: %+v",
+			diskName, instName, inst.Errors)
+		return false
+	}
+	if inst.Status == store.StatusRunning || inst.Status == store.StatusPaused {
+		logrus.Warnf("Cannot unlock disk %q used by running instance %q", diskName, instName)
+		return false
+	}
+	return true
+}
+
 func newDiskResizeCommand() *cobra.Command {
 	diskResizeCommand := &cobra.Command{
 		Use: "resize DISK",
@@ -385,7 +410,7 @@ func diskResizeAction(cmd *cobra.Command, args []string) error {
 	if disk.Instance != "" {
 		inst, err := store.Inspect(disk.Instance)
 		if err == nil {
-			if inst.Status == store.StatusRunning {
+			if inst.Status == store.StatusRunning || inst.Status == store.StatusPaused {
 				return fmt.Errorf("cannot resize disk %q used by running instance %q. Please stop the VM instance", diskName, disk.Instance)
 			}
 		}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/lima-vm/lima/pkg/doctor"
+	"github.com/spf13/cobra"
+)
+
+func newDoctorCommand() *cobra.Command {
+	doctorCommand := &cobra.Command{
+		Use:     "doctor",
+		Short:   "Check the host prerequisites",
+		Args:    WrapArgsError(cobra.NoArgs),
+		RunE:    doctorAction,
+		GroupID: advancedCommand,
+	}
+	return doctorCommand
+}
+
+func doctorAction(cmd *cobra.Command, _ []string) error {
+	return runDoctorChecks(cmd.OutOrStdout(), doctor.Checks())
+}
+
+// runDoctorChecks prints the results of the checks, and returns an error when any of them failed.
+func runDoctorChecks(w io.Writer, checks []doctor.Check) error {
+	var failed int
+	for _, check := range checks {
+		res := check.Run()
+		fmt.Fprintf(w, "[%s] %s: %s\n", res.Status, check.Name, res.Message)
+		if res.Status != doctor.StatusPass && res.Hint != "" {
+			fmt.Fprintf(w, "       hint: %s\n", res.Hint)
+		}
+		if res.Status == doctor.StatusFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%w: %d check(s) failed", errPrerequisiteMissing, failed)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/doctor"
+	"gotest.tools/v3/assert"
+)
+
+func TestRunDoctorChecks(t *testing.T) {
+	result := func(res doctor.Result) func() doctor.Result {
+		return func() doctor.Result { return res }
+	}
+	cases := []struct {
+		name     string
+		checks   []doctor.Check
+		expected string
+		failed   bool
+	}{
+		{
+			name: "pass",
+			checks: []doctor.Check{
+				{Name: "ssh", Run: result(doctor.Result{Status: doctor.StatusPass, Message: "/usr/bin/ssh", Hint: "Install OpenSSH client"})},
+			},
+			expected: "[pass] ssh: /usr/bin/ssh\n",
+		},
+		{
+			name: "warn with hint",
+			checks: []doctor.Check{
+				{Name: "ssh", Run: result(doctor.Result{Status: doctor.StatusPass, Message: "/usr/bin/ssh"})},
+				{Name: "rsync", Run: result(doctor.Result{Status: doctor.StatusWarn, Message: "not found", Hint: "Install rsync"})},
+			},
+			expected: "[pass] ssh: /usr/bin/ssh\n[warn] rsync: not found\n       hint: Install rsync\n",
+		},
+		{
+			name: "fail",
+			checks: []doctor.Check{
+				{Name: "ssh", Run: result(doctor.Result{Status: doctor.StatusFail, Message: "not found", Hint: "Install OpenSSH client"})},
+				{Name: "QEMU", Run: result(doctor.Result{Status: doctor.StatusFail, Message: "not found"})},
+			},
+			expected: "[fail] ssh: not found\n       hint: Install OpenSSH client\n[fail] QEMU: not found\n",
+			failed:   true,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := runDoctorChecks(&buf, tc.checks)
+			assert.Equal(t, buf.String(), tc.expected)
+			if tc.failed {
+				assert.Assert(t, errors.Is(err, errPrerequisiteMissing), err)
+				assert.Equal(t, exitCode(err), ExitCodePrerequisiteMissing)
+			} else {
+				assert.NilError(t, err)
+			}
+		})
+	}
+}
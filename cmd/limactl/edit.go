@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
+	"syscall"
 
 	"github.com/lima-vm/lima/cmd/limactl/editflags"
 	"github.com/lima-vm/lima/pkg/editutil"
@@ -66,7 +68,7 @@ func editAction(cmd *cobra.Command, args []string) error {
 			return err
 		}
 
-		if inst.Status == store.StatusRunning {
+		if inst.Status == store.StatusRunning || inst.Status == store.StatusPaused {
 			return errors.New("cannot edit a running instance")
 		}
 		filePath = filepath.Join(inst.Dir, filenames.LimaYAML)
@@ -154,6 +156,9 @@ func editAction(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return err
 	}
+	// Ctrl-C during the start stops the host agent launched by instance.Start
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	return instance.Start(ctx, inst, "", false)
 }
 
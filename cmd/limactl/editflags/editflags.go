@@ -238,7 +238,8 @@ func YQExpressions(flags *flag.FlagSet, newInstance bool) ([]string, error) {
 			true,
 			false,
 		},
-		{"disk", d(".disk= \"%sGiB\""), true, false},
+		// `disk` may be either a scalar size or a map with `size`
+		{"disk", d(".disk |= ((select(tag == \"!!map\") | .size = \"%[1]sGiB\") // \"%[1]sGiB\")"), true, false},
 		{"vm-type", d(".vmType = %q"), true, false},
 		{"plain", d(".plain = %s"), true, false},
 	}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const execHelp = `Execute a command in Lima non-interactively
+
+Unlike 'limactl shell', no pseudo-terminal is allocated, so stdin, stdout, and stderr are passed through as-is,
+and stdout and stderr are not merged. The exit code of the command is propagated.
+
+Example:
+  $ limactl exec default -- uname -a
+  $ tar c ./src | limactl exec --workdir /tmp default -- tar x
+  $ limactl exec --env FOO=bar --env HOME default -- sh -c 'echo $FOO'
+`
+
+func newExecCommand() *cobra.Command {
+	execCmd := &cobra.Command{
+		Use:               "exec [flags] INSTANCE [--] COMMAND [ARGS...]",
+		Short:             "Execute a command in Lima non-interactively",
+		Long:              execHelp,
+		Args:              WrapArgsError(cobra.MinimumNArgs(2)),
+		RunE:              execAction,
+		ValidArgsFunction: execBashComplete,
+		SilenceErrors:     true,
+		GroupID:           basicCommand,
+	}
+
+	execCmd.Flags().SetInterspersed(false)
+
+	execCmd.Flags().StringArrayP("env", "e", nil, "set an environment variable (KEY=VALUE), or pass through the variable of the host (KEY)")
+	execCmd.Flags().String("workdir", "", "working directory")
+	registerJumpHostFlag(execCmd)
+	return execCmd
+}
+
+func execAction(cmd *cobra.Command, args []string) error {
+	instName := args[0]
+	args = args[1:]
+	if args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("requires a command to execute in instance %q", instName)
+	}
+
+	envs, err := cmd.Flags().GetStringArray("env")
+	if err != nil {
+		return err
+	}
+	workDir, err := cmd.Flags().GetString("workdir")
+	if err != nil {
+		return err
+	}
+	script, err := execScript(workDir, envs, args)
+	if err != nil {
+		return err
+	}
+
+	jumpHost, err := jumpHost(cmd)
+	if err != nil {
+		return err
+	}
+	inst, err := inspectInstance(instName, jumpHost)
+	if err != nil {
+		return err
+	}
+	// No pseudo-terminal, and no escape character, so that the binary stdin is not interpreted
+	sshCmd, err := inst.SSHCommand(cmd.Context(), []sshutil.SSHOptsOpt{
+		sshutil.WithJumpHost(jumpHost),
+		sshutil.WithOptions("RequestTTY=no", "EscapeChar=none", "LogLevel=ERROR"),
+	}, "--", script)
+	if err != nil {
+		return err
+	}
+	sshCmd.Stdin = os.Stdin
+	sshCmd.Stdout = os.Stdout
+	sshCmd.Stderr = os.Stderr
+	logrus.Debugf("executing ssh: %+v", sshCmd.Args)
+	// *exec.ExitError is translated to the exit code of limactl by handleExitCoder
+	return sshCmd.Run()
+}
+
+var envKeyRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// execScript returns the shell script that executes args in workDir, with envs.
+// An env without "=" is taken from the host, and ignored when the host does not have it.
+func execScript(workDir string, envs, args []string) (string, error) {
+	var sb strings.Builder
+	if workDir != "" {
+		fmt.Fprintf(&sb, "cd %s || exit 1; ", shellescape.Quote(workDir))
+	}
+	for _, env := range envs {
+		k, v, ok := strings.Cut(env, "=")
+		if !envKeyRegexp.MatchString(k) {
+			return "", fmt.Errorf("invalid environment variable %q", env)
+		}
+		if !ok {
+			if v, ok = os.LookupEnv(k); !ok {
+				continue
+			}
+		}
+		fmt.Fprintf(&sb, "export %s=%s; ", k, shellescape.Quote(v))
+	}
+	quotedArgs := make([]string, len(args))
+	for i, arg := range args {
+		quotedArgs[i] = shellescape.Quote(arg)
+	}
+	sb.WriteString("exec " + strings.Join(quotedArgs, " "))
+	return sb.String(), nil
+}
+
+func execBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
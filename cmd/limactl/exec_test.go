@@ -0,0 +1,24 @@
+package main
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestExecScript(t *testing.T) {
+	t.Setenv("LIMA_TEST_HOST_ENV", "host value")
+
+	script, err := execScript("", nil, []string{"uname", "-a"})
+	assert.NilError(t, err)
+	assert.Equal(t, script, "exec uname -a")
+
+	script, err = execScript("/tmp/work dir", []string{"FOO=bar baz", "EMPTY=", "LIMA_TEST_HOST_ENV", "LIMA_TEST_UNSET_ENV"}, []string{"sh", "-c", "echo $FOO"})
+	assert.NilError(t, err)
+	assert.Equal(t, script, `cd '/tmp/work dir' || exit 1; export FOO='bar baz'; export EMPTY=''; export LIMA_TEST_HOST_ENV='host value'; exec sh -c 'echo $FOO'`)
+
+	_, err = execScript("", []string{"1FOO=bar"}, []string{"true"})
+	assert.ErrorContains(t, err, "invalid environment variable")
+	_, err = execScript("", []string{"=bar"}, []string{"true"})
+	assert.ErrorContains(t, err, "invalid environment variable")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+
+	"github.com/lima-vm/lima/pkg/store"
+)
+
+// Exit codes of limactl.
+// The specific codes are allocated from 241, as `limactl shell` and `limactl exec`
+// pass through the exit codes of the guest commands, which mostly use the small numbers.
+// See also website/content/en/docs/reference/exit-codes.md.
+const (
+	ExitCodeError               = 1
+	ExitCodeInstanceNotFound    = 241
+	ExitCodeInstanceStopped     = 242
+	ExitCodePrerequisiteMissing = 243
+	ExitCodeInstanceBroken      = 244
+)
+
+// errPrerequisiteMissing is returned when a host prerequisite is missing.
+// Errors wrapping exec.ErrNotFound are treated in the same way.
+var errPrerequisiteMissing = errors.New("host prerequisite missing")
+
+// exitCode translates the error returned by a subcommand to the exit code.
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, store.ErrInstanceNotFound):
+		return ExitCodeInstanceNotFound
+	case errors.Is(err, store.ErrInstanceStopped):
+		return ExitCodeInstanceStopped
+	case errors.Is(err, store.ErrInstanceBroken):
+		return ExitCodeInstanceBroken
+	case errors.Is(err, errPrerequisiteMissing), errors.Is(err, exec.ErrNotFound):
+		return ExitCodePrerequisiteMissing
+	default:
+		return ExitCodeError
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func executeApp(t *testing.T, args ...string) error {
+	t.Helper()
+	app := newApp()
+	app.SetArgs(args)
+	app.SetOut(io.Discard)
+	app.SetErr(io.Discard)
+	return app.Execute()
+}
+
+func TestExitCode(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+
+	err := executeApp(t, "shell", "foo", "true")
+	assert.Equal(t, exitCode(err), ExitCodeInstanceNotFound, "%v", err)
+
+	instDir := filepath.Join(os.Getenv("LIMA_HOME"), "foo")
+	assert.NilError(t, os.MkdirAll(instDir, 0o755))
+	limaYAML := `images: [{"location": "/"}]
+user: {uid: 1000}
+`
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.LimaYAML), []byte(limaYAML), 0o644))
+	err = executeApp(t, "shell", "foo", "true")
+	assert.Equal(t, exitCode(err), ExitCodeInstanceStopped, "%v", err)
+
+	// lima.yaml is invalid, so the instance is broken
+	brokenDir := filepath.Join(os.Getenv("LIMA_HOME"), "broken")
+	assert.NilError(t, os.MkdirAll(brokenDir, 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(brokenDir, filenames.LimaYAML), []byte("{}\n"), 0o644))
+	err = executeApp(t, "shell", "broken", "true")
+	assert.Equal(t, exitCode(err), ExitCodeInstanceBroken, "%v", err)
+
+	t.Setenv("PATH", t.TempDir())
+	err = executeApp(t, "copy", "--exclude", ".git", "foo:/tmp", ".")
+	assert.Equal(t, exitCode(err), ExitCodePrerequisiteMissing, "%v", err)
+}
+
+func TestExitCodeMapping(t *testing.T) {
+	assert.Equal(t, exitCode(errors.New("other")), ExitCodeError)
+	assert.Equal(t, exitCode(fmt.Errorf("%w: 1 check(s) failed", errPrerequisiteMissing)), ExitCodePrerequisiteMissing)
+	assert.Equal(t, exitCode(&exec.Error{Name: "ssh", Err: exec.ErrNotFound}), ExitCodePrerequisiteMissing)
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	hostagentclient "github.com/lima-vm/lima/pkg/hostagent/api/client"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/spf13/cobra"
+)
+
+func newForwardsCommand() *cobra.Command {
+	forwardsCommand := &cobra.Command{
+		Use:   "forwards INSTANCE",
+		Short: "Show the port forwards of a running instance",
+		Long: `Show the TCP and UDP ports of the guest, and the status of forwarding them to the host.
+UDP ports are only forwarded when LIMA_SSH_PORT_FORWARDER=false is set.
+
+The status is one of:
+- active:  the port is forwarded to the host address
+- failed:  forwarding the port failed, e.g., because the host address is already in use
+- skipped: the port is not forwarded, e.g., because it is ignored by the port forwarding rules`,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              forwardsAction,
+		ValidArgsFunction: forwardsBashComplete,
+		GroupID:           advancedCommand,
+	}
+	return forwardsCommand
+}
+
+func forwardsAction(cmd *cobra.Command, args []string) error {
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	if err := store.CheckRunning(inst); err != nil {
+		return err
+	}
+	haSock := filepath.Join(inst.Dir, filenames.HostAgentSock)
+	haClient, err := hostagentclient.NewHostAgentClient(haSock, hostagentclient.WithTimeout(10*time.Second))
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(cmd.Context(), 30*time.Second)
+	defer cancel()
+	info, err := haClient.Info(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the port forwards of instance %q: %w", inst.Name, err)
+	}
+	return printForwards(cmd.OutOrStdout(), info.PortForwards)
+}
+
+func printForwards(w io.Writer, forwards []hostagentapi.PortForward) error {
+	tw := tabwriter.NewWriter(w, 4, 8, 4, ' ', 0)
+	fmt.Fprintln(tw, "GUEST\tHOST\tPROTO\tSTATUS")
+	for _, fwd := range forwards {
+		host := fwd.HostAddr
+		if host == "" {
+			host = "-"
+		}
+		proto := fwd.Protocol
+		if proto == "" {
+			proto = "tcp"
+		}
+		status := fwd.Status
+		if status == "" {
+			// the host agent prior to the status tracking only reported the active ones
+			status = hostagentapi.PortForwardActive
+		}
+		if fwd.Remapped {
+			status += " (remapped)"
+		}
+		if fwd.Reason != "" {
+			status += ": " + fwd.Reason
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", fwd.GuestAddr, host, proto, status)
+	}
+	return tw.Flush()
+}
+
+func forwardsBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
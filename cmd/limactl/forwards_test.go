@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintForwards(t *testing.T) {
+	const infoJSON = `{
+  "sshLocalPort": 60022,
+  "portForwards": [
+    {"guestAddr": "0.0.0.0:80", "hostAddr": "127.0.0.1:50000", "protocol": "tcp", "remapped": true, "status": "active"},
+    {"guestAddr": "0.0.0.0:8080", "hostAddr": "127.0.0.1:8080", "protocol": "tcp", "status": "failed", "reason": "host address 127.0.0.1:8080 is already in use"},
+    {"guestAddr": "127.0.0.1:9000", "hostAddr": "", "protocol": "tcp", "status": "skipped", "reason": "ignored by the port forwarding rules"}
+  ]
+}`
+	var info hostagentapi.Info
+	assert.NilError(t, json.Unmarshal([]byte(infoJSON), &info))
+	var b strings.Builder
+	assert.NilError(t, printForwards(&b, info.PortForwards))
+	expected := `GUEST             HOST               PROTO    STATUS
+0.0.0.0:80        127.0.0.1:50000    tcp      active (remapped)
+0.0.0.0:8080      127.0.0.1:8080     tcp      failed: host address 127.0.0.1:8080 is already in use
+127.0.0.1:9000    -                  tcp      skipped: ignored by the port forwarding rules
+`
+	assert.Equal(t, b.String(), expected)
+}
+
+func TestForwardsNotRunning(t *testing.T) {
+	createStoppedInstance(t, "")
+	err := executeApp(t, "forwards", "foo")
+	assert.Assert(t, errors.Is(err, store.ErrInstanceStopped), "%v", err)
+}
@@ -18,11 +18,12 @@ import (
 
 func newGenSchemaCommand() *cobra.Command {
 	genschemaCommand := &cobra.Command{
-		Use:    "generate-jsonschema",
-		Short:  "Generate json-schema document",
-		Args:   WrapArgsError(cobra.ArbitraryArgs),
-		RunE:   genschemaAction,
-		Hidden: true,
+		Use:     "generate-jsonschema",
+		Aliases: []string{"generate-schema"},
+		Short:   "Generate json-schema document",
+		Args:    WrapArgsError(cobra.ArbitraryArgs),
+		RunE:    genschemaAction,
+		Hidden:  true,
 	}
 	genschemaCommand.Flags().String("schemafile", "", "Output file")
 	return genschemaCommand
@@ -44,12 +45,9 @@ func getProp(props *orderedmap.OrderedMap[string, *jsonschema.Schema], key strin
 	return value
 }
 
-func genschemaAction(cmd *cobra.Command, args []string) error {
-	file, err := cmd.Flags().GetString("schemafile")
-	if err != nil {
-		return err
-	}
-
+// limaYAMLSchema returns the JSON schema of lima.yaml.
+// The fields without `omitempty` in the json tags, e.g., `images`, are listed as `required`.
+func limaYAMLSchema() *jsonschema.Schema {
 	schema := jsonschema.Reflect(&limayaml.LimaYAML{})
 	// allow Disk to be either string (name) or object (struct)
 	schema.Definitions["Disk"].Type = "" // was: "object"
@@ -57,12 +55,30 @@ func genschemaAction(cmd *cobra.Command, args []string) error {
 		{Type: "string"},
 		{Type: "object"},
 	}
+	// allow PrimaryDisk to be either string (size) or object (struct)
+	schema.Definitions["PrimaryDisk"].Type = "" // was: "object"
+	schema.Definitions["PrimaryDisk"].OneOf = []*jsonschema.Schema{
+		{Type: "string"},
+		{Type: "object"},
+	}
 	properties := schema.Definitions["LimaYAML"].Properties
 	getProp(properties, "os").Enum = toAny(limayaml.OSTypes)
 	getProp(properties, "arch").Enum = toAny(limayaml.ArchTypes)
 	getProp(properties, "mountType").Enum = toAny(limayaml.MountTypes)
 	getProp(properties, "vmType").Enum = toAny(limayaml.VMTypes)
-	j, err := json.MarshalIndent(schema, "", "    ")
+	getProp(schema.Definitions["Provision"].Properties, "mode").Enum = toAny(limayaml.ProvisionModes)
+	getProp(schema.Definitions["Probe"].Properties, "mode").Enum = toAny(limayaml.ProbeModes)
+	getProp(schema.Definitions["PortForward"].Properties, "proto").Enum = toAny(limayaml.Protos)
+	return schema
+}
+
+func genschemaAction(cmd *cobra.Command, args []string) error {
+	file, err := cmd.Flags().GetString("schemafile")
+	if err != nil {
+		return err
+	}
+
+	j, err := json.MarshalIndent(limaYAMLSchema(), "", "    ")
 	if err != nil {
 		return err
 	}
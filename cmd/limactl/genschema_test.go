@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	jsonschema2 "github.com/santhosh-tekuri/jsonschema/v6"
+	"gotest.tools/v3/assert"
+)
+
+func TestLimaYAMLSchemaRequired(t *testing.T) {
+	schema := limaYAMLSchema()
+	assert.DeepEqual(t, schema.Definitions["LimaYAML"].Required, []string{"images"})
+	assert.DeepEqual(t, schema.Definitions["Image"].Required, []string{"location"})
+	assert.DeepEqual(t, schema.Definitions["Mount"].Required, []string{"location"})
+
+	j, err := json.Marshal(schema)
+	assert.NilError(t, err)
+	schemaFile := filepath.Join(t.TempDir(), "schema-limayaml.json")
+	assert.NilError(t, os.WriteFile(schemaFile, j, 0o644))
+	compiled, err := jsonschema2.NewCompiler().Compile(schemaFile)
+	assert.NilError(t, err)
+
+	validate := func(s string) error {
+		var y any
+		assert.NilError(t, yaml.Unmarshal([]byte(s), &y))
+		return compiled.Validate(y)
+	}
+	assert.NilError(t, validate(`images: [{"location": "/"}]`))
+	assert.ErrorContains(t, validate(`cpus: 2`), "missing property 'images'")
+	assert.ErrorContains(t, validate(`images: [{"arch": "x86_64"}]`), "missing property 'location'")
+}
+
+func TestLimaYAMLSchemaDefaultTemplate(t *testing.T) {
+	j, err := json.Marshal(limaYAMLSchema())
+	assert.NilError(t, err)
+	schemaFile := filepath.Join(t.TempDir(), "schema-limayaml.json")
+	assert.NilError(t, os.WriteFile(schemaFile, j, 0o644))
+	compiled, err := jsonschema2.NewCompiler().Compile(schemaFile)
+	assert.NilError(t, err)
+
+	b, err := os.ReadFile(filepath.Join("..", "..", "templates", "default.yaml"))
+	assert.NilError(t, err)
+	var y any
+	assert.NilError(t, yaml.Unmarshal(b, &y))
+	assert.NilError(t, compiled.Validate(y))
+}
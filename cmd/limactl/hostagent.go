@@ -8,12 +8,18 @@ import (
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"runtime"
 	"strconv"
 	"syscall"
 
+	"github.com/docker/go-units"
 	"github.com/lima-vm/lima/pkg/hostagent"
 	"github.com/lima-vm/lima/pkg/hostagent/api/server"
+	"github.com/lima-vm/lima/pkg/logrotate"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/lima-vm/lima/pkg/tracing"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
@@ -30,6 +36,7 @@ func newHostagentCommand() *cobra.Command {
 	hostagentCommand.Flags().String("socket", "", "hostagent socket")
 	hostagentCommand.Flags().Bool("run-gui", false, "run gui synchronously within hostagent")
 	hostagentCommand.Flags().String("nerdctl-archive", "", "local file path (not URL) of nerdctl-full-VERSION-GOOS-GOARCH.tar.gz")
+	hostagentCommand.Flags().String("guest-agent-address", "", "guest agent address, e.g., \"vsock://CID:PORT\", \"tcp://HOST:PORT\", or \"unix:///PATH\" (default: decided by the driver)")
 	return hostagentCommand
 }
 
@@ -70,8 +77,24 @@ func hostagentAction(cmd *cobra.Command, args []string) error {
 	signalCh := make(chan os.Signal, 1)
 	signal.Notify(signalCh, os.Interrupt, syscall.SIGTERM)
 
-	stdout := &syncWriter{w: cmd.OutOrStdout()}
-	stderr := &syncWriter{w: cmd.ErrOrStderr()}
+	instDir, err := store.InstanceDir(instName)
+	if err != nil {
+		return err
+	}
+	logMaxSize, logMaxFiles, err := hostagentLogRotation()
+	if err != nil {
+		return err
+	}
+	stdoutW, err := rotatingLogWriter(cmd.OutOrStdout(), filepath.Join(instDir, filenames.HostAgentStdoutLog), logMaxSize, logMaxFiles)
+	if err != nil {
+		return err
+	}
+	stderrW, err := rotatingLogWriter(cmd.ErrOrStderr(), filepath.Join(instDir, filenames.HostAgentStderrLog), logMaxSize, logMaxFiles)
+	if err != nil {
+		return err
+	}
+	stdout := &syncWriter{w: stdoutW}
+	stderr := &syncWriter{w: stderrW}
 
 	initLogrus(stderr)
 	var opts []hostagent.Opt
@@ -82,13 +105,36 @@ func hostagentAction(cmd *cobra.Command, args []string) error {
 	if nerdctlArchive != "" {
 		opts = append(opts, hostagent.WithNerdctlArchive(nerdctlArchive))
 	}
-	ha, err := hostagent.New(instName, stdout, signalCh, opts...)
+	guestAgentAddress, err := cmd.Flags().GetString("guest-agent-address")
+	if err != nil {
+		return err
+	}
+	if guestAgentAddress != "" {
+		opts = append(opts, hostagent.WithGuestAgentAddress(guestAgentAddress))
+	}
+	// The spans of the host agent belong to the trace of `limactl start`, when the tracing is enabled
+	ctx := tracing.ContextFromEnv(cmd.Context())
+	ha, err := hostagent.New(ctx, instName, stdout, signalCh, opts...)
 	if err != nil {
 		return err
 	}
 
 	backend := &server.Backend{
-		Agent: ha,
+		Agent:    ha,
+		ReadOnly: ha.ReadOnly(),
+	}
+	// $LIMA_HOSTAGENT_METRICS overrides `hostAgentMetrics` in lima.yaml
+	metricsEnabled := ha.MetricsEnabled()
+	if envVar := os.Getenv("LIMA_HOSTAGENT_METRICS"); envVar != "" {
+		b, err := strconv.ParseBool(envVar)
+		if err != nil {
+			logrus.WithError(err).Warnf("invalid LIMA_HOSTAGENT_METRICS value %q", envVar)
+		} else {
+			metricsEnabled = b
+		}
+	}
+	if metricsEnabled {
+		backend.Metrics = ha.Metrics()
 	}
 	r := http.NewServeMux()
 	server.AddRoutes(r, backend)
@@ -112,6 +158,55 @@ func hostagentAction(cmd *cobra.Command, args []string) error {
 	return ha.Run(cmd.Context())
 }
 
+const (
+	HostAgentLogMaxSizeEnv  = "LIMA_HOSTAGENT_LOG_MAX_SIZE"
+	HostAgentLogMaxFilesEnv = "LIMA_HOSTAGENT_LOG_MAX_FILES"
+
+	defaultHostAgentLogMaxSize  = 10 * units.MiB
+	defaultHostAgentLogMaxFiles = 3
+)
+
+// hostagentLogRotation returns the thresholds of rotating the host agent logs.
+// A zero maxSize disables the rotation.
+func hostagentLogRotation() (maxSize int64, maxFiles int, err error) {
+	maxSize, maxFiles = defaultHostAgentLogMaxSize, defaultHostAgentLogMaxFiles
+	if envVar := os.Getenv(HostAgentLogMaxSizeEnv); envVar != "" {
+		maxSize, err = units.RAMInBytes(envVar)
+		if err != nil || maxSize < 0 {
+			return 0, 0, fmt.Errorf("invalid %s value %q", HostAgentLogMaxSizeEnv, envVar)
+		}
+	}
+	if envVar := os.Getenv(HostAgentLogMaxFilesEnv); envVar != "" {
+		maxFiles, err = strconv.Atoi(envVar)
+		if err != nil || maxFiles < 1 {
+			return 0, 0, fmt.Errorf("invalid %s value %q", HostAgentLogMaxFilesEnv, envVar)
+		}
+	}
+	return maxSize, maxFiles, nil
+}
+
+// rotatingLogWriter returns a rotating writer of the log file at the path, when w is the log file
+// created by `limactl start`.
+// Otherwise, e.g., when the host agent is running in the foreground on a terminal, w is returned as is.
+func rotatingLogWriter(w io.Writer, path string, maxSize int64, maxFiles int) (io.Writer, error) {
+	if maxSize == 0 {
+		return w, nil
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return w, nil
+	}
+	fSt, err := f.Stat()
+	if err != nil {
+		return w, nil
+	}
+	pathSt, err := os.Stat(path)
+	if err != nil || !os.SameFile(fSt, pathSt) {
+		return w, nil
+	}
+	return logrotate.New(path, maxSize, maxFiles)
+}
+
 // syncer is implemented by *os.File.
 type syncer interface {
 	Sync() error
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/logrotate"
+	"gotest.tools/v3/assert"
+)
+
+func TestHostagentLogRotation(t *testing.T) {
+	maxSize, maxFiles, err := hostagentLogRotation()
+	assert.NilError(t, err)
+	assert.Equal(t, maxSize, int64(defaultHostAgentLogMaxSize))
+	assert.Equal(t, maxFiles, defaultHostAgentLogMaxFiles)
+
+	t.Setenv(HostAgentLogMaxSizeEnv, "1MiB")
+	t.Setenv(HostAgentLogMaxFilesEnv, "5")
+	maxSize, maxFiles, err = hostagentLogRotation()
+	assert.NilError(t, err)
+	assert.Equal(t, maxSize, int64(1024*1024))
+	assert.Equal(t, maxFiles, 5)
+
+	t.Setenv(HostAgentLogMaxSizeEnv, "0")
+	maxSize, _, err = hostagentLogRotation()
+	assert.NilError(t, err)
+	assert.Equal(t, maxSize, int64(0))
+
+	t.Setenv(HostAgentLogMaxSizeEnv, "-1")
+	_, _, err = hostagentLogRotation()
+	assert.ErrorContains(t, err, "invalid "+HostAgentLogMaxSizeEnv)
+
+	t.Setenv(HostAgentLogMaxSizeEnv, "")
+	t.Setenv(HostAgentLogMaxFilesEnv, "0")
+	_, _, err = hostagentLogRotation()
+	assert.ErrorContains(t, err, "invalid "+HostAgentLogMaxFilesEnv)
+}
+
+func TestRotatingLogWriter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ha.stdout.log")
+	f, err := os.Create(path)
+	assert.NilError(t, err)
+	defer f.Close()
+
+	w, err := rotatingLogWriter(f, path, 1024, 3)
+	assert.NilError(t, err)
+	_, ok := w.(*logrotate.Writer)
+	assert.Assert(t, ok)
+	assert.NilError(t, w.(*logrotate.Writer).Close())
+
+	// disabled
+	w, err = rotatingLogWriter(f, path, 0, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, w, f)
+
+	// not the log file
+	other, err := os.Create(path + ".other")
+	assert.NilError(t, err)
+	defer other.Close()
+	w, err = rotatingLogWriter(other, path, 1024, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, w, other)
+
+	var buf bytes.Buffer
+	w, err = rotatingLogWriter(&buf, path, 1024, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, w, &buf)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/docker/go-units"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func newInspectCommand() *cobra.Command {
+	inspectCommand := &cobra.Command{
+		Use:   "inspect INSTANCE [INSTANCE, ...]",
+		Short: "Display the detailed information of instances",
+		Long: `Display the detailed information of instances.
+
+With --json, each instance is printed as a JSON line with the stable field names
+(version "` + store.InspectOutputVersion + `"), which are not affected by the changes of the internal structs.
+The fields are: version, name, status, dir, vmType, arch, cpus, memory, disk,
+sshAddress, sshLocalPort, sshConfigFile, configDigest, protected, and errors.`,
+		Args:              WrapArgsError(cobra.MinimumNArgs(1)),
+		RunE:              inspectAction,
+		ValidArgsFunction: inspectBashComplete,
+		GroupID:           advancedCommand,
+	}
+	inspectCommand.Flags().Bool("json", false, "JSONify output")
+	return inspectCommand
+}
+
+func inspectAction(cmd *cobra.Command, args []string) error {
+	jsonFormat, err := cmd.Flags().GetBool("json")
+	if err != nil {
+		return err
+	}
+	var outputs []*store.InspectOutput
+	for _, instName := range args {
+		inst, err := store.Inspect(instName)
+		if err != nil {
+			return err
+		}
+		out, err := store.NewInspectOutput(inst)
+		if err != nil {
+			return fmt.Errorf("failed to inspect instance %q: %w", instName, err)
+		}
+		outputs = append(outputs, out)
+	}
+	w := cmd.OutOrStdout()
+	if jsonFormat {
+		enc := json.NewEncoder(w)
+		for _, out := range outputs {
+			if err := enc.Encode(out); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	tw := tabwriter.NewWriter(w, 4, 8, 4, ' ', 0)
+	for i, out := range outputs {
+		if i > 0 {
+			fmt.Fprintln(tw)
+		}
+		fmt.Fprintf(tw, "Name:\t%s\n", out.Name)
+		fmt.Fprintf(tw, "Status:\t%s\n", out.Status)
+		fmt.Fprintf(tw, "Dir:\t%s\n", out.Dir)
+		fmt.Fprintf(tw, "VMType:\t%s\n", out.VMType)
+		fmt.Fprintf(tw, "Arch:\t%s\n", out.Arch)
+		fmt.Fprintf(tw, "CPUs:\t%d\n", out.CPUs)
+		fmt.Fprintf(tw, "Memory:\t%s\n", units.BytesSize(float64(out.Memory)))
+		fmt.Fprintf(tw, "Disk:\t%s\n", units.BytesSize(float64(out.Disk)))
+		fmt.Fprintf(tw, "SSH:\t%s:%d\n", out.SSHAddress, out.SSHLocalPort)
+		fmt.Fprintf(tw, "SSHConfigFile:\t%s\n", out.SSHConfigFile)
+		fmt.Fprintf(tw, "ConfigDigest:\t%s\n", out.ConfigDigest)
+		fmt.Fprintf(tw, "Protected:\t%v\n", out.Protected)
+		for _, e := range out.Errors {
+			fmt.Fprintf(tw, "Error:\t%s\n", e)
+		}
+	}
+	return tw.Flush()
+}
+
+func inspectBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func registerJumpHostFlag(cmd *cobra.Command) {
+	cmd.Flags().String("jump-host", "", "connect via the SSH jump host ([USER@]HOST[:PORT]), for the instances of the local store running on a remote host "+
+		"(default: $"+sshutil.EnvSSHJumpHost+")")
+}
+
+// jumpHost returns the SSH jump host specified with --jump-host or $LIMA_SSH_JUMP_HOST, after checking that it is reachable.
+// An empty string is returned when no jump host is specified.
+func jumpHost(cmd *cobra.Command) (string, error) {
+	jumpHost, err := cmd.Flags().GetString("jump-host")
+	if err != nil {
+		return "", err
+	}
+	if jumpHost == "" {
+		jumpHost = os.Getenv(sshutil.EnvSSHJumpHost)
+	}
+	if jumpHost == "" {
+		return "", nil
+	}
+	if err := sshutil.CheckJumpHost(cmd.Context(), jumpHost); err != nil {
+		return "", err
+	}
+	return jumpHost, nil
+}
+
+// inspectInstance inspects the instance to connect to.
+// Without a jump host, the instance has to be running on the local host.
+// With a jump host, see checkJumpHostInstance.
+func inspectInstance(instName, jumpHost string) (*store.Instance, error) {
+	if jumpHost == "" {
+		return store.InspectRunning(instName)
+	}
+	inst, err := store.Inspect(instName)
+	if err != nil {
+		return nil, jumpHostInstanceError(err)
+	}
+	if err := checkJumpHostInstance(inst); err != nil {
+		return nil, err
+	}
+	return inst, nil
+}
+
+// jumpHostInstanceError adds a hint to the error of looking up an instance to connect to via the jump host.
+func jumpHostInstanceError(err error) error {
+	if errors.Is(err, store.ErrInstanceNotFound) {
+		return fmt.Errorf("%w (the instances connected via the jump host have to exist in the local store, "+
+			"e.g., with the instance directory copied from the remote host)", err)
+	}
+	return err
+}
+
+// checkJumpHostInstance checks that the instance can be connected via the jump host.
+// The instances on the remote host are not looked up: the local store only provides the configuration
+// and the SSH key of the instance. The status is not checked, as the instance is running on the remote host,
+// and `ssh.localPort` has to be set in lima.yaml, as the port assigned on the remote host is not known locally.
+func checkJumpHostInstance(inst *store.Instance) error {
+	if inst.Config == nil {
+		return fmt.Errorf("instance %q is broken: %w", inst.Name, errors.Join(inst.Errors...))
+	}
+	if inst.SSHLocalPort == 0 {
+		return fmt.Errorf("instance %q must have `ssh.localPort` set in lima.yaml to be connected via the jump host", inst.Name)
+	}
+	return nil
+}
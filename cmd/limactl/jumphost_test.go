@@ -0,0 +1,35 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckJumpHostInstance(t *testing.T) {
+	inst := &store.Instance{Name: "default", Status: store.StatusStopped, SSHLocalPort: 60022, Config: &limayaml.LimaYAML{}}
+	// the status in the local store does not matter
+	assert.NilError(t, checkJumpHostInstance(inst))
+
+	inst.SSHLocalPort = 0
+	assert.ErrorContains(t, checkJumpHostInstance(inst), "must have `ssh.localPort` set in lima.yaml")
+
+	broken := &store.Instance{Name: "default", Status: store.StatusBroken, Errors: []error{errors.New("invalid lima.yaml")}}
+	assert.ErrorContains(t, checkJumpHostInstance(broken), "invalid lima.yaml")
+}
+
+func TestInspectInstanceJumpHost(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	_, err := inspectInstance("default", "user@remote.example.com")
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+	assert.ErrorContains(t, err, "have to exist in the local store")
+
+	_, err = inspectInstance("default", "")
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+	assert.Assert(t, !strings.Contains(err.Error(), "local store"), err.Error())
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"os"
 	"reflect"
+	"slices"
 	"sort"
 	"strings"
 
 	"github.com/cheggaaa/pb/v3/termutil"
+	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/lima-vm/lima/pkg/store"
 	"github.com/mattn/go-isatty"
 	"github.com/sirupsen/logrus"
@@ -59,10 +61,57 @@ The following legacy flags continue to function:
 	listCommand.Flags().Bool("json", false, "JSONify output")
 	listCommand.Flags().BoolP("quiet", "q", false, "Only show names")
 	listCommand.Flags().Bool("all-fields", false, "Show all fields")
+	listCommand.Flags().String("status", "", "Only show the instances with the status, e.g., \"running\", \"stopped\", or \"broken\"")
+	listCommand.Flags().String("arch", "", "Only show the instances with the architecture, e.g., \"x86_64\" or \"aarch64\"")
+	listCommand.Flags().String("tag", "", "Only show the instances with the tag (`tags` in lima.yaml)")
 
 	return listCommand
 }
 
+var listStatuses = []store.Status{store.StatusUninitialized, store.StatusInstalling, store.StatusBroken, store.StatusStopped, store.StatusRunning, store.StatusPaused}
+
+// listFilter returns the predicate for the --status, --arch, and --tag flags, or nil when all of them are empty.
+func listFilter(status, arch, tag string) (func(*store.Instance) bool, error) {
+	var preds []func(*store.Instance) bool
+	if status != "" {
+		i := slices.IndexFunc(listStatuses, func(s store.Status) bool {
+			return strings.EqualFold(s, status)
+		})
+		if i < 0 {
+			return nil, fmt.Errorf("invalid status %q, must be one of %v", status, listStatuses)
+		}
+		preds = append(preds, func(inst *store.Instance) bool {
+			return inst.Status == listStatuses[i]
+		})
+	}
+	if arch != "" {
+		// e.g., "arm64" is accepted as "aarch64"
+		arch = limayaml.NewArch(arch)
+		preds = append(preds, func(inst *store.Instance) bool {
+			return inst.Arch == arch
+		})
+	}
+	if tag != "" {
+		if err := limayaml.ValidateTag(tag); err != nil {
+			return nil, fmt.Errorf("invalid tag %q: %w", tag, err)
+		}
+		preds = append(preds, func(inst *store.Instance) bool {
+			return inst.Config != nil && slices.Contains(inst.Config.Tags, tag)
+		})
+	}
+	if len(preds) == 0 {
+		return nil, nil
+	}
+	return func(inst *store.Instance) bool {
+		for _, pred := range preds {
+			if !pred(inst) {
+				return false
+			}
+		}
+		return true
+	}, nil
+}
+
 func instanceMatches(arg string, instances []string) []string {
 	matches := []string{}
 	for _, instance := range instances {
@@ -119,6 +168,25 @@ func listAction(cmd *cobra.Command, args []string) error {
 	if quiet && format != "table" {
 		return errors.New("option --quiet can only be used with '--format table'")
 	}
+	if err := store.ValidateFormat(format); err != nil {
+		return err
+	}
+	status, err := cmd.Flags().GetString("status")
+	if err != nil {
+		return err
+	}
+	arch, err := cmd.Flags().GetString("arch")
+	if err != nil {
+		return err
+	}
+	tag, err := cmd.Flags().GetString("tag")
+	if err != nil {
+		return err
+	}
+	filter, err := listFilter(status, arch, tag)
+	if err != nil {
+		return err
+	}
 
 	if listFields {
 		names := fieldNames()
@@ -156,7 +224,7 @@ func listAction(cmd *cobra.Command, args []string) error {
 		instanceNames = allinstances
 	}
 
-	if quiet {
+	if quiet && filter == nil {
 		for _, instName := range instanceNames {
 			fmt.Fprintln(cmd.OutOrStdout(), instName)
 		}
@@ -168,18 +236,40 @@ func listAction(cmd *cobra.Command, args []string) error {
 
 	// get the state and config for all the requested instances
 	var instances []*store.Instance
-	for _, instanceName := range instanceNames {
-		instance, err := store.Inspect(instanceName)
+	if len(args) == 0 {
+		instances, err = store.InstancesFiltered(filter)
 		if err != nil {
-			return fmt.Errorf("unable to load instance %s: %w", instanceName, err)
+			return err
+		}
+	} else {
+		for _, instanceName := range instanceNames {
+			instance, err := store.Inspect(instanceName)
+			if err != nil {
+				return fmt.Errorf("unable to load instance %s: %w", instanceName, err)
+			}
+			if filter == nil || filter(instance) {
+				instances = append(instances, instance)
+			}
 		}
-		instances = append(instances, instance)
+	}
+
+	if quiet {
+		for _, instance := range instances {
+			fmt.Fprintln(cmd.OutOrStdout(), instance.Name)
+		}
+		if unmatchedInstances {
+			return unmatchedInstancesError{}
+		}
+		return nil
 	}
 
 	for _, instance := range instances {
 		if len(instance.Errors) > 0 {
 			logrus.WithField("errors", instance.Errors).Warnf("instance %q has errors", instance.Name)
 		}
+		if len(instance.DegradedReasons) > 0 {
+			logrus.WithField("reasons", instance.DegradedReasons).Warnf("instance %q is degraded", instance.Name)
+		}
 	}
 
 	allFields, err := cmd.Flags().GetBool("all-fields")
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestListFilter(t *testing.T) {
+	filter, err := listFilter("", "", "")
+	assert.NilError(t, err)
+	assert.Assert(t, filter == nil)
+
+	running := &store.Instance{Status: store.StatusRunning, Arch: limayaml.AARCH64, Config: &limayaml.LimaYAML{Tags: []string{"ci"}}}
+	stopped := &store.Instance{Status: store.StatusStopped, Arch: limayaml.X8664}
+
+	filter, err = listFilter("running", "", "")
+	assert.NilError(t, err)
+	assert.Assert(t, filter(running))
+	assert.Assert(t, !filter(stopped))
+
+	filter, err = listFilter("", "arm64", "")
+	assert.NilError(t, err)
+	assert.Assert(t, filter(running))
+	assert.Assert(t, !filter(stopped))
+
+	filter, err = listFilter("Stopped", "aarch64", "")
+	assert.NilError(t, err)
+	assert.Assert(t, !filter(running))
+	assert.Assert(t, !filter(stopped))
+
+	filter, err = listFilter("", "", "ci")
+	assert.NilError(t, err)
+	assert.Assert(t, filter(running))
+	assert.Assert(t, !filter(stopped))
+
+	_, err = listFilter("sleeping", "", "")
+	assert.ErrorContains(t, err, `invalid status "sleeping"`)
+
+	_, err = listFilter("", "", "CI")
+	assert.ErrorContains(t, err, `invalid tag "CI"`)
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/logrotate"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/nxadm/tail"
+	"github.com/spf13/cobra"
+)
+
+const logsExample = `
+To show the events of the host agent:
+$ limactl logs default
+
+To follow the logs of the host agent, starting from the last 10 minutes:
+$ limactl logs --stderr --follow --since=10m default
+`
+
+func newLogsCommand() *cobra.Command {
+	logsCommand := &cobra.Command{
+		Use:   "logs INSTANCE",
+		Short: "Show the logs of the host agent",
+		Long: `Show the logs of the host agent.
+
+By default, the events of the host agent (` + filenames.HostAgentStdoutLog + `) are shown.
+With --stderr, the logs of the host agent (` + filenames.HostAgentStderrLog + `) are shown instead.
+Both files are JSON lines, and are recreated on each start of the instance.
+The files rotated by the host agent (e.g., ` + filenames.HostAgentStdoutLog + `.1) are shown first.`,
+		Example:           logsExample,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              logsAction,
+		ValidArgsFunction: logsBashComplete,
+		GroupID:           advancedCommand,
+	}
+	logsCommand.Flags().BoolP("follow", "f", false, "Follow the log output")
+	logsCommand.Flags().Bool("stderr", false, "Show "+filenames.HostAgentStderrLog+" instead of "+filenames.HostAgentStdoutLog)
+	logsCommand.Flags().String("since", "", "Show the logs since a relative duration (e.g., \"10m\") or a timestamp (e.g., \"2025-01-02T15:04:05Z\")")
+	return logsCommand
+}
+
+func logsAction(cmd *cobra.Command, args []string) error {
+	follow, err := cmd.Flags().GetBool("follow")
+	if err != nil {
+		return err
+	}
+	stderr, err := cmd.Flags().GetBool("stderr")
+	if err != nil {
+		return err
+	}
+	sinceStr, err := cmd.Flags().GetString("since")
+	if err != nil {
+		return err
+	}
+	var since time.Time
+	if sinceStr != "" {
+		since, err = parseSince(sinceStr, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	logPath := filepath.Join(inst.Dir, filenames.HostAgentStdoutLog)
+	if stderr {
+		logPath = filepath.Join(inst.Dir, filenames.HostAgentStderrLog)
+	}
+	ctx := cmd.Context()
+	w := cmd.OutOrStdout()
+	filter := newSinceFilter(since)
+	rotated, err := logrotate.RotatedFiles(logPath)
+	if err != nil {
+		return err
+	}
+	for _, f := range rotated {
+		if err := printLogFile(w, f, filter); err != nil {
+			return err
+		}
+	}
+	t, err := tail.TailFile(logPath, tail.Config{
+		Follow: follow,
+		// The file is rotated by the host agent, and recreated by `limactl start`
+		ReOpen:    follow,
+		MustExist: true,
+		Logger:    tail.DiscardingLogger,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to open the log of instance %q (hint: the log is created on starting the instance): %w", inst.Name, err)
+	}
+	defer func() {
+		_ = t.Stop()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case line, ok := <-t.Lines:
+			if !ok {
+				return t.Wait()
+			}
+			if line.Err != nil {
+				return line.Err
+			}
+			if filter(line.Text) {
+				fmt.Fprintln(w, line.Text)
+			}
+		}
+	}
+}
+
+func printLogFile(w io.Writer, path string, filter func(line string) bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			// rotated out in the meantime
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	for {
+		line, err := r.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if line != "" && filter(line) {
+			fmt.Fprintln(w, line)
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// parseSince parses a relative duration or an RFC 3339 timestamp.
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("--since must not be negative, got %q", s)
+		}
+		return now.Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("--since must be a duration (e.g., \"10m\") or an RFC 3339 timestamp, got %q", s)
+	}
+	return t, nil
+}
+
+// newSinceFilter returns a function that returns true for the log lines since the time.
+// The lines are expected to be chronological JSON objects with the "time" field.
+// Once a line since the time is found, the following lines are not filtered, even if they lack the time.
+func newSinceFilter(since time.Time) func(line string) bool {
+	if since.IsZero() {
+		return func(string) bool { return true }
+	}
+	var found bool
+	return func(line string) bool {
+		if found {
+			return true
+		}
+		var v struct {
+			Time time.Time `json:"time"`
+		}
+		if err := json.Unmarshal([]byte(line), &v); err != nil || v.Time.IsZero() {
+			return false
+		}
+		found = !v.Time.Before(since)
+		return found
+	}
+}
+
+func logsBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
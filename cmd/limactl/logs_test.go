@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2025, 1, 2, 15, 4, 5, 0, time.UTC)
+	since, err := parseSince("10m", now)
+	assert.NilError(t, err)
+	assert.Equal(t, since, now.Add(-10*time.Minute))
+
+	since, err = parseSince("2025-01-02T15:00:00Z", now)
+	assert.NilError(t, err)
+	assert.Equal(t, since, time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC))
+
+	_, err = parseSince("-10m", now)
+	assert.ErrorContains(t, err, "must not be negative")
+	_, err = parseSince("yesterday", now)
+	assert.ErrorContains(t, err, "must be a duration")
+}
+
+func TestSinceFilter(t *testing.T) {
+	lines := []string{
+		`{"level":"info","msg":"old","time":"2025-01-02T14:00:00Z"}`,
+		`not a JSON line`,
+		`{"level":"info","msg":"new","time":"2025-01-02T15:00:00Z"}`,
+		`not a JSON line`,
+		`{"level":"info","msg":"newer","time":"2025-01-02T15:01:00Z"}`,
+	}
+	var filtered []string
+	filter := newSinceFilter(time.Date(2025, 1, 2, 15, 0, 0, 0, time.UTC))
+	for _, line := range lines {
+		if filter(line) {
+			filtered = append(filtered, line)
+		}
+	}
+	assert.DeepEqual(t, filtered, lines[2:])
+
+	filter = newSinceFilter(time.Time{})
+	for _, line := range lines {
+		assert.Assert(t, filter(line))
+	}
+}
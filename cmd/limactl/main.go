@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -12,6 +13,7 @@ import (
 	"github.com/lima-vm/lima/pkg/fsutil"
 	"github.com/lima-vm/lima/pkg/osutil"
 	"github.com/lima-vm/lima/pkg/store/dirnames"
+	"github.com/lima-vm/lima/pkg/tracing"
 	"github.com/lima-vm/lima/pkg/version"
 	"github.com/mattn/go-isatty"
 	"github.com/sirupsen/logrus"
@@ -25,9 +27,19 @@ const (
 )
 
 func main() {
-	if err := newApp().Execute(); err != nil {
+	shutdownTracing, err := tracing.Init(context.Background(), "limactl")
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to initialize the tracing")
+	}
+	err = newApp().Execute()
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		logrus.WithError(shutdownErr).Warn("Failed to export the tracing spans")
+	}
+	if err != nil {
 		handleExitCoder(err)
-		logrus.Fatal(err)
+		// Same as logrus.Fatal, but with the exit code translated from the error
+		logrus.StandardLogger().Log(logrus.FatalLevel, err)
+		logrus.StandardLogger().Exit(exitCode(err))
 	}
 }
 
@@ -63,6 +75,8 @@ func newApp() *cobra.Command {
 	rootCmd.PersistentFlags().String("log-level", "", "Set the logging level [trace, debug, info, warn, error]")
 	rootCmd.PersistentFlags().String("log-format", "text", "Set the logging format [text, json]")
 	rootCmd.PersistentFlags().Bool("debug", false, "debug mode")
+	rootCmd.PersistentFlags().Bool("quiet", false, "Only show warnings and errors in the logs (ignored with --debug)")
+	rootCmd.PersistentFlags().Bool("offline", false, "Do not access the network for downloading images and archives; use only the cache and the local files (same as LIMA_OFFLINE=1)")
 	// TODO: "survey" does not support using cygwin terminal on windows yet
 	rootCmd.PersistentFlags().Bool("tty", isatty.IsTerminal(os.Stdout.Fd()), "Enable TUI interactions such as opening an editor. Defaults to true when stdout is a terminal. Set to false for automation.")
 	rootCmd.PersistentPreRunE = func(cmd *cobra.Command, _ []string) error {
@@ -98,6 +112,25 @@ func newApp() *cobra.Command {
 			debugutil.Debug = true
 		}
 
+		// Note that `limactl list` and `limactl snapshot list` have their own --quiet flag,
+		// which also suppresses the info logs.
+		quiet, _ := cmd.Flags().GetBool("quiet")
+		if quiet {
+			if debug {
+				logrus.Warn("Ignoring --quiet, as --debug is specified")
+			} else if logrus.GetLevel() > logrus.WarnLevel {
+				logrus.SetLevel(logrus.WarnLevel)
+			}
+		}
+
+		offline, _ := cmd.Flags().GetBool("offline")
+		if offline {
+			// Propagated to the hostagent process via the environment
+			if err := os.Setenv("LIMA_OFFLINE", "1"); err != nil {
+				return err
+			}
+		}
+
 		if osutil.IsBeingRosettaTranslated() && cmd.Parent().Name() != "completion" && cmd.Name() != "generate-doc" && cmd.Name() != "validate" {
 			// running under rosetta would provide inappropriate runtime.GOARCH info, see: https://github.com/lima-vm/lima/issues/543
 			// allow commands that are used for packaging to run under rosetta to allow cross-architecture builds
@@ -135,6 +168,7 @@ func newApp() *cobra.Command {
 		newStartCommand(),
 		newStopCommand(),
 		newShellCommand(),
+		newExecCommand(),
 		newCopyCommand(),
 		newListCommand(),
 		newDeleteCommand(),
@@ -156,6 +190,17 @@ func newApp() *cobra.Command {
 		newUnprotectCommand(),
 		newTunnelCommand(),
 		newTemplateCommand(),
+		newProvisionCommand(),
+		newDoctorCommand(),
+		newShowProvenanceCommand(),
+		newShowMACCommand(),
+		newInspectCommand(),
+		newLogsCommand(),
+		newForwardsCommand(),
+		newConfigCommand(),
+		newPauseCommand(),
+		newResumeCommand(),
+		newSaveCommand(),
 	)
 	if runtime.GOOS == "darwin" || runtime.GOOS == "linux" {
 		rootCmd.AddCommand(startAtLoginCommand())
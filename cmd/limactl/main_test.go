@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/debugutil"
+	"github.com/sirupsen/logrus"
+	"gotest.tools/v3/assert"
+)
+
+func TestQuiet(t *testing.T) {
+	t.Cleanup(func() {
+		logrus.SetLevel(logrus.InfoLevel)
+		debugutil.Debug = false
+	})
+
+	assert.NilError(t, executeApp(t, "--quiet", "completion", "bash"))
+	assert.Equal(t, logrus.GetLevel(), logrus.WarnLevel)
+
+	// --log-level=error is not loosened by --quiet
+	assert.NilError(t, executeApp(t, "--quiet", "--log-level=error", "completion", "bash"))
+	assert.Equal(t, logrus.GetLevel(), logrus.ErrorLevel)
+
+	// --debug wins over --quiet
+	assert.NilError(t, executeApp(t, "--quiet", "--debug", "completion", "bash"))
+	assert.Equal(t, logrus.GetLevel(), logrus.DebugLevel)
+}
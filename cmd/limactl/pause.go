@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/lima-vm/lima/pkg/instance"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func newPauseCommand() *cobra.Command {
+	pauseCmd := &cobra.Command{
+		Use:   "pause INSTANCE",
+		Short: "Pause an instance",
+		Long: `Pause an instance without shutting down the guest OS, preserving the memory state.
+The paused instance does not consume the host CPU, but still occupies the host memory.
+Use 'limactl resume' to resume the instance.
+
+The support depends on the VM type:
+- qemu: supported
+- others: not supported`,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              pauseAction,
+		ValidArgsFunction: pauseBashComplete,
+		GroupID:           advancedCommand,
+	}
+	return pauseCmd
+}
+
+func pauseAction(cmd *cobra.Command, args []string) error {
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	return instance.Pause(cmd.Context(), inst)
+}
+
+func pauseBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
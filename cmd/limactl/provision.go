@@ -0,0 +1,113 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+const provisionHelp = `Run a provision script in a running instance
+
+The script is executed in the same way as the "provision" scripts in lima.yaml,
+without restarting the instance. This is useful for iterating on a provision script.
+
+Example: limactl provision --mode=system default ./script.sh
+`
+
+func newProvisionCommand() *cobra.Command {
+	provisionCommand := &cobra.Command{
+		Use:               "provision INSTANCE SCRIPT",
+		Short:             "Run a provision script in a running instance",
+		Long:              provisionHelp,
+		Args:              WrapArgsError(cobra.ExactArgs(2)),
+		RunE:              provisionAction,
+		ValidArgsFunction: provisionBashComplete,
+		GroupID:           advancedCommand,
+	}
+	provisionCommand.Flags().String("mode", limayaml.ProvisionModeSystem,
+		fmt.Sprintf("provision mode (%q or %q)", limayaml.ProvisionModeSystem, limayaml.ProvisionModeUser))
+	return provisionCommand
+}
+
+// provisionRemoteCommand returns the command executed in the guest.
+// The command receives the script from stdin into a temporary file and executes it.
+func provisionRemoteCommand(mode limayaml.ProvisionMode) (string, error) {
+	var exe string
+	switch mode {
+	case limayaml.ProvisionModeSystem:
+		exe = `sudo "$f"`
+	case limayaml.ProvisionModeUser:
+		exe = `"$f"`
+	default:
+		return "", fmt.Errorf("provision mode %q is not supported, must be %q or %q", mode, limayaml.ProvisionModeSystem, limayaml.ProvisionModeUser)
+	}
+	return `set -eu; f="$(mktemp)"; trap 'rm -f "$f"' EXIT; cat >"$f"; chmod 755 "$f"; ` + exe, nil
+}
+
+func provisionAction(cmd *cobra.Command, args []string) error {
+	mode, err := cmd.Flags().GetString("mode")
+	if err != nil {
+		return err
+	}
+	remoteCommand, err := provisionRemoteCommand(mode)
+	if err != nil {
+		return err
+	}
+	instName := args[0]
+	inst, err := store.Inspect(instName)
+	if err != nil {
+		return err
+	}
+	if err := store.CheckWritable(inst, "provisioning"); err != nil {
+		return err
+	}
+	if err := store.CheckRunning(inst); err != nil {
+		return err
+	}
+	script, err := os.Open(args[1])
+	if err != nil {
+		return err
+	}
+	defer script.Close()
+
+	arg0, arg0Args, err := sshutil.SSHArguments()
+	if err != nil {
+		return err
+	}
+	sshOpts, err := sshutil.SSHOpts(
+		arg0,
+		inst.Dir,
+		*inst.Config.User.Name,
+		sshutil.WithDotSSH(*inst.Config.SSH.LoadDotSSHPubKeys))
+	if err != nil {
+		return err
+	}
+	sshArgs := sshutil.SSHArgsFromOpts(sshOpts)
+	sshArgs = append(sshArgs, []string{
+		"-o", "LogLevel=ERROR",
+		"-p", strconv.Itoa(inst.SSHLocalPort),
+		inst.SSHAddress,
+		"--",
+		remoteCommand,
+	}...)
+	sshCmd := exec.Command(arg0, append(arg0Args, sshArgs...)...)
+	sshCmd.Stdin = script
+	sshCmd.Stdout = cmd.OutOrStdout()
+	sshCmd.Stderr = cmd.ErrOrStderr()
+	logrus.Debugf("executing ssh: %+v", sshCmd.Args)
+	return sshCmd.Run()
+}
+
+func provisionBashComplete(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return bashCompleteInstanceNames(cmd)
+	}
+	return nil, cobra.ShellCompDirectiveDefault
+}
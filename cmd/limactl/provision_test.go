@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestProvisionRemoteCommand(t *testing.T) {
+	system, err := provisionRemoteCommand(limayaml.ProvisionModeSystem)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasSuffix(system, `; sudo "$f"`), system)
+
+	user, err := provisionRemoteCommand(limayaml.ProvisionModeUser)
+	assert.NilError(t, err)
+	assert.Assert(t, !strings.Contains(user, "sudo"), user)
+	assert.Assert(t, strings.HasSuffix(user, `; "$f"`), user)
+
+	_, err = provisionRemoteCommand(limayaml.ProvisionModeBoot)
+	assert.ErrorContains(t, err, "not supported")
+}
+
+// createStoppedInstance creates the instance "foo" in a temporary $LIMA_HOME, with extraYAML appended to lima.yaml.
+func createStoppedInstance(t *testing.T, extraYAML string) {
+	t.Helper()
+	t.Setenv("LIMA_HOME", t.TempDir())
+	instDir := filepath.Join(os.Getenv("LIMA_HOME"), "foo")
+	assert.NilError(t, os.MkdirAll(instDir, 0o755))
+	y := "images: [{location: /dummy.img}]\nuser: {uid: 1000}\n" + extraYAML
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.LimaYAML), []byte(y), 0o644))
+}
+
+func TestProvisionReadOnly(t *testing.T) {
+	script := filepath.Join(t.TempDir(), "script.sh")
+	assert.NilError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0o755))
+
+	createStoppedInstance(t, "readOnly: true\n")
+	err := executeApp(t, "provision", "foo", script)
+	assert.Assert(t, errors.Is(err, store.ErrInstanceReadOnly), "%v", err)
+
+	createStoppedInstance(t, "")
+	err = executeApp(t, "provision", "foo", script)
+	assert.Assert(t, errors.Is(err, store.ErrInstanceStopped), "%v", err)
+}
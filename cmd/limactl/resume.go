@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/lima-vm/lima/pkg/instance"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func newResumeCommand() *cobra.Command {
+	resumeCmd := &cobra.Command{
+		Use:               "resume INSTANCE",
+		Short:             "Resume an instance paused by 'limactl pause'",
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              resumeAction,
+		ValidArgsFunction: resumeBashComplete,
+		GroupID:           advancedCommand,
+	}
+	return resumeCmd
+}
+
+func resumeAction(cmd *cobra.Command, args []string) error {
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	return instance.Resume(cmd.Context(), inst)
+}
+
+func resumeBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
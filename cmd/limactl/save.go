@@ -0,0 +1,44 @@
+package main
+
+import (
+	"github.com/lima-vm/lima/pkg/instance"
+	networks "github.com/lima-vm/lima/pkg/networks/reconcile"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func newSaveCommand() *cobra.Command {
+	saveCmd := &cobra.Command{
+		Use:   "save INSTANCE",
+		Short: "Save the state of an instance to disk and stop it",
+		Long: `Save the state of an instance to disk and stop it, without shutting down the guest OS.
+The next 'limactl start' restores the saved state instead of booting the guest OS.
+The saved state is discarded, with a warning, when it cannot be restored, e.g., after modifying lima.yaml
+or the disks (limactl snapshot apply, limactl disk resize), upgrading Lima, or when the ports of the
+host resolver are no longer available.
+
+The support depends on the VM type:
+- qemu: supported
+- others: not supported`,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              saveAction,
+		ValidArgsFunction: saveBashComplete,
+		GroupID:           advancedCommand,
+	}
+	return saveCmd
+}
+
+func saveAction(cmd *cobra.Command, args []string) error {
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	if err := instance.Save(cmd.Context(), inst); err != nil {
+		return err
+	}
+	return networks.Reconcile(cmd.Context(), "")
+}
+
+func saveBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
@@ -1,17 +1,14 @@
 package main
 
 import (
-	"errors"
 	"fmt"
 	"os"
-	"os/exec"
-	"strconv"
 	"strings"
 
 	"al.essio.dev/pkg/shellescape"
 	"github.com/coreos/go-semver/semver"
+	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/lima-vm/lima/pkg/sshutil"
-	"github.com/lima-vm/lima/pkg/store"
 	"github.com/mattn/go-isatty"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
@@ -24,6 +21,22 @@ lima command is provided as an alias for limactl shell $LIMA_INSTANCE. $LIMA_INS
 By default, the first 'ssh' executable found in the host's PATH is used to connect to the Lima instance.
 A custom ssh alias can be used instead by setting the $` + sshutil.EnvShellSSH + ` environment variable.
 
+The ssh agent of the host can be forwarded with --forward-agent (or ` + "`ssh.forwardAgent`" + ` in lima.yaml).
+Only forward the agent to trusted instances, as the users who can access the agent socket in the guest
+can authenticate with the keys of the host.
+
+For the instances running on a remote host, the SSH jump host can be specified with --jump-host
+(or $` + sshutil.EnvSSHJumpHost + `), e.g., "user@remote.example.com". The instance is then connected
+at its SSH port on the remote host. The instance still has to exist in the local store, which provides
+the configuration and the SSH key, with ` + "`ssh.localPort`" + ` set in lima.yaml.
+
+Keepalive messages are sent every ` + "`ssh.keepAlive.interval`" + ` seconds (30 by default) over the connection
+of the host agent, so that idle sessions survive flaky networks. Use --keepalive-interval to override it.
+
+X11 can be forwarded with --x11 (or ` + "`ssh.forwardX11`" + ` in lima.yaml), so that the GUI applications
+of the guest are displayed on the host. This requires an X server running on the host, with $DISPLAY set
+(e.g., XQuartz on macOS), and the xauth command in the guest.
+
 Hint: try --debug to show the detailed logs, if it seems hanging (mostly due to some SSH issue).
 `
 
@@ -43,6 +56,12 @@ func newShellCommand() *cobra.Command {
 
 	shellCmd.Flags().String("shell", "", "shell interpreter, e.g. /bin/bash")
 	shellCmd.Flags().String("workdir", "", "working directory")
+	shellCmd.Flags().Bool("forward-agent", false, "forward the ssh agent of the host (default: `ssh.forwardAgent` in lima.yaml). "+
+		"Caution: the users who can access the agent socket in the guest can use the keys of the host, while the shell is running")
+	shellCmd.Flags().Bool("x11", false, "forward X11 to display the GUI applications of the guest on the host (default: `ssh.forwardX11` in lima.yaml). "+
+		"Requires an X server on the host (e.g., XQuartz on macOS), with $DISPLAY set")
+	shellCmd.Flags().Int("keepalive-interval", 0, "interval in seconds of the keepalive messages, 0 to disable (default: `ssh.keepAlive.interval` in lima.yaml)")
+	registerJumpHostFlag(shellCmd)
 	return shellCmd
 }
 
@@ -64,21 +83,21 @@ func shellAction(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	inst, err := store.Inspect(instName)
+	jumpHost, err := jumpHost(cmd)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("instance %q does not exist, run `limactl create %s` to create a new instance", instName, instName)
-		}
 		return err
 	}
-	if inst.Status == store.StatusStopped {
-		return fmt.Errorf("instance %q is stopped, run `limactl start %s` to start the instance", instName, instName)
+	inst, err := inspectInstance(instName, jumpHost)
+	if err != nil {
+		return err
 	}
 
 	// When workDir is explicitly set, the shell MUST have workDir as the cwd, or exit with an error.
 	//
 	// changeDirCmd := "cd workDir || exit 1"                  if workDir != ""
 	//              := "cd hostCurrentDir || cd hostHomeDir"   if workDir == ""
+	//
+	// hostCurrentDir and hostHomeDir are translated to the guest paths through the mounts.
 	var changeDirCmd string
 	workDir, err := cmd.Flags().GetString("workdir")
 	if err != nil {
@@ -86,18 +105,17 @@ func shellAction(cmd *cobra.Command, args []string) error {
 	}
 	if workDir != "" {
 		changeDirCmd = fmt.Sprintf("cd %s || exit 1", shellescape.Quote(workDir))
-		// FIXME: check whether y.Mounts contains the home, not just len > 0
 	} else if len(inst.Config.Mounts) > 0 {
 		hostCurrentDir, err := os.Getwd()
 		if err == nil {
-			changeDirCmd = fmt.Sprintf("cd %s", shellescape.Quote(hostCurrentDir))
+			changeDirCmd = fmt.Sprintf("cd %s", shellescape.Quote(guestPathOrSelf(inst.Config.Mounts, hostCurrentDir)))
 		} else {
 			changeDirCmd = "false"
 			logrus.WithError(err).Warn("failed to get the current directory")
 		}
 		hostHomeDir, err := os.UserHomeDir()
 		if err == nil {
-			changeDirCmd = fmt.Sprintf("%s || cd %s", changeDirCmd, shellescape.Quote(hostHomeDir))
+			changeDirCmd = fmt.Sprintf("%s || cd %s", changeDirCmd, shellescape.Quote(guestPathOrSelf(inst.Config.Mounts, hostHomeDir)))
 		} else {
 			logrus.WithError(err).Warn("failed to get the home directory")
 		}
@@ -137,46 +155,65 @@ func shellAction(cmd *cobra.Command, args []string) error {
 		)
 	}
 
-	arg0, arg0Args, err := sshutil.SSHArguments()
-	if err != nil {
-		return err
+	forwardAgent := *inst.Config.SSH.ForwardAgent
+	if cmd.Flags().Changed("forward-agent") {
+		if forwardAgent, err = cmd.Flags().GetBool("forward-agent"); err != nil {
+			return err
+		}
 	}
-
-	sshOpts, err := sshutil.SSHOpts(
-		arg0,
-		inst.Dir,
-		*inst.Config.User.Name,
-		*inst.Config.SSH.LoadDotSSHPubKeys,
-		*inst.Config.SSH.ForwardAgent,
-		*inst.Config.SSH.ForwardX11,
-		*inst.Config.SSH.ForwardX11Trusted)
-	if err != nil {
-		return err
+	forwardX11 := *inst.Config.SSH.ForwardX11
+	if cmd.Flags().Changed("x11") {
+		if forwardX11, err = cmd.Flags().GetBool("x11"); err != nil {
+			return err
+		}
+	}
+	if forwardX11 && os.Getenv("DISPLAY") == "" {
+		logrus.Warn("X11 forwarding is enabled, but $DISPLAY is not set on the host, so the GUI applications of the guest will not be displayed " +
+			"(hint: start an X server, e.g., XQuartz on macOS)")
+	}
+	keepAliveInterval := *inst.Config.SSH.KeepAlive.Interval
+	if cmd.Flags().Changed("keepalive-interval") {
+		if keepAliveInterval, err = cmd.Flags().GetInt("keepalive-interval"); err != nil {
+			return err
+		}
+	}
+	sshOptsOpts := []sshutil.SSHOptsOpt{
+		sshutil.WithForwardAgent(forwardAgent),
+		sshutil.WithForwardX11(forwardX11),
+		sshutil.WithKeepAlive(keepAliveInterval, *inst.Config.SSH.KeepAlive.CountMax),
+		sshutil.WithJumpHost(jumpHost),
+	}
+	// The master connection opened by the host agent only supports the forwarding enabled in lima.yaml,
+	// and only sends the keepalive messages of lima.yaml
+	if (forwardAgent && !*inst.Config.SSH.ForwardAgent) || (forwardX11 && !*inst.Config.SSH.ForwardX11) ||
+		keepAliveInterval != *inst.Config.SSH.KeepAlive.Interval {
+		sshOptsOpts = append(sshOptsOpts, sshutil.WithoutControlMaster())
 	}
-	sshArgs := sshutil.SSHArgsFromOpts(sshOpts)
 	if isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd()) {
 		// required for showing the shell prompt: https://stackoverflow.com/a/626574
-		sshArgs = append(sshArgs, "-t")
+		sshOptsOpts = append(sshOptsOpts, sshutil.WithOptions("RequestTTY=yes"))
 	}
 	if _, present := os.LookupEnv("COLORTERM"); present {
 		// SendEnv config is cumulative, with already existing options in ssh_config
-		sshArgs = append(sshArgs, "-o", "SendEnv=COLORTERM")
+		sshOptsOpts = append(sshOptsOpts, sshutil.WithOptions("SendEnv=COLORTERM"))
 	}
 	logLevel := "ERROR"
 	// For versions older than OpenSSH 8.9p, LogLevel=QUIET was needed to
 	// avoid the "Shared connection to 127.0.0.1 closed." message with -t.
-	olderSSH := sshutil.DetectOpenSSHVersion(arg0).LessThan(*semver.New("8.9.0"))
-	if olderSSH {
+	arg0, _, err := sshutil.SSHArguments()
+	if err != nil {
+		return err
+	}
+	if sshVersion, err := sshutil.DetectOpenSSHVersion(arg0); err != nil {
+		logrus.WithError(err).Warn("Failed to detect the OpenSSH version; assuming OpenSSH 8.9 or later")
+	} else if sshVersion.LessThan(*semver.New("8.9.0")) {
 		logLevel = "QUIET"
 	}
-	sshArgs = append(sshArgs, []string{
-		"-o", fmt.Sprintf("LogLevel=%s", logLevel),
-		"-p", strconv.Itoa(inst.SSHLocalPort),
-		inst.SSHAddress,
-		"--",
-		script,
-	}...)
-	sshCmd := exec.Command(arg0, append(arg0Args, sshArgs...)...)
+	sshOptsOpts = append(sshOptsOpts, sshutil.WithOptions(fmt.Sprintf("LogLevel=%s", logLevel)))
+	sshCmd, err := inst.SSHCommand(cmd.Context(), sshOptsOpts, "--", script)
+	if err != nil {
+		return err
+	}
 	sshCmd.Stdin = os.Stdin
 	sshCmd.Stdout = os.Stdout
 	sshCmd.Stderr = os.Stderr
@@ -190,6 +227,16 @@ func shellBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobr
 	return bashCompleteInstanceNames(cmd)
 }
 
+// guestPathOrSelf returns the guest path of hostPath through the mounts.
+// When hostPath is not under any mount, hostPath itself is returned, as the guest may still have the same path (e.g., "/tmp").
+func guestPathOrSelf(mounts []limayaml.Mount, hostPath string) string {
+	if guestPath := limayaml.GuestPathOfHostPath(mounts, hostPath); guestPath != "" {
+		return guestPath
+	}
+	logrus.Debugf("%q is not mounted in the guest", hostPath)
+	return hostPath
+}
+
 func isEnv(arg string) bool {
 	return len(strings.Split(arg, "=")) > 1
 }
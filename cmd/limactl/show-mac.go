@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/lima-vm/lima/pkg/hostagent"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+const showMACExample = `
+  $ limactl show-mac default
+  INTERFACE    MAC                  NETWORK
+  eth0         52:55:55:12:34:56    user-mode
+  lima0        52:55:55:ab:cd:ef    lima:bridged
+
+  $ limactl show-mac --interface=lima0 default
+  52:55:55:ab:cd:ef
+`
+
+func newShowMACCommand() *cobra.Command {
+	showMACCommand := &cobra.Command{
+		Use:   "show-mac INSTANCE",
+		Short: "Show the MAC addresses of the network interfaces of the instance",
+		Long: `Show the MAC addresses of the network interfaces of the instance.
+
+The MAC addresses that are not specified in the config are derived from the instance directory,
+so they do not change across the restarts of the instance.
+They can be used for configuring the DHCP reservations for the bridged networks.`,
+		Example:           showMACExample,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              showMACAction,
+		ValidArgsFunction: showMACBashComplete,
+		GroupID:           advancedCommand,
+	}
+	showMACCommand.Flags().String("interface", "", "Show only the MAC address of the interface, e.g., \"lima0\"")
+	return showMACCommand
+}
+
+func showMACAction(cmd *cobra.Command, args []string) error {
+	iface, err := cmd.Flags().GetString("interface")
+	if err != nil {
+		return err
+	}
+	inst, err := store.Inspect(args[0])
+	if err != nil {
+		return err
+	}
+	if inst.Config == nil {
+		return fmt.Errorf("failed to load the config of instance %q: %+v", inst.Name, inst.Errors)
+	}
+	if inst.VMType == limayaml.WSL2 {
+		return fmt.Errorf("the MAC addresses of instance %q are not managed by Lima, as the VM type is %q", inst.Name, inst.VMType)
+	}
+	nws := hostagent.ConfiguredNetworks(inst.Dir, inst.Config)
+	if iface == "" {
+		return printMACAddresses(cmd.OutOrStdout(), nws)
+	}
+	for _, nw := range nws {
+		if nw.Interface == iface {
+			fmt.Fprintln(cmd.OutOrStdout(), nw.MACAddress)
+			return nil
+		}
+	}
+	return fmt.Errorf("instance %q has no interface %q", inst.Name, iface)
+}
+
+func printMACAddresses(w io.Writer, nws []hostagentapi.Network) error {
+	tw := tabwriter.NewWriter(w, 4, 8, 4, ' ', 0)
+	fmt.Fprintln(tw, "INTERFACE\tMAC\tNETWORK")
+	for i, nw := range nws {
+		var network string
+		switch {
+		case i == 0:
+			// always the first interface, see hostagent.ConfiguredNetworks
+			network = "user-mode"
+		case nw.Lima != "":
+			network = "lima:" + nw.Lima
+		case nw.Socket != "":
+			network = "socket:" + nw.Socket
+		default:
+			network = "vzNAT"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", nw.Interface, nw.MACAddress, network)
+	}
+	return tw.Flush()
+}
+
+func showMACBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
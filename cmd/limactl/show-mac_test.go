@@ -0,0 +1,27 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"gotest.tools/v3/assert"
+)
+
+func TestPrintMACAddresses(t *testing.T) {
+	nws := []hostagentapi.Network{
+		{Interface: "eth0", MACAddress: "52:55:55:12:34:56"},
+		{Interface: "lima0", MACAddress: "52:55:55:ab:cd:ef", Lima: "bridged"},
+		{Interface: "lima1", MACAddress: "52:55:55:00:00:01", Socket: "/var/run/socket_vmnet"},
+		{Interface: "lima2", MACAddress: "52:55:55:00:00:02"},
+	}
+	var b strings.Builder
+	assert.NilError(t, printMACAddresses(&b, nws))
+	expected := `INTERFACE    MAC                  NETWORK
+eth0         52:55:55:12:34:56    user-mode
+lima0        52:55:55:ab:cd:ef    lima:bridged
+lima1        52:55:55:00:00:01    socket:/var/run/socket_vmnet
+lima2        52:55:55:00:00:02    vzNAT
+`
+	assert.Equal(t, b.String(), expected)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+const showProvenanceExample = `
+  $ limactl show-provenance default
+  {
+    "limaVersion": "1.0.6",
+    "image": {
+      "location": "https://cloud-images.ubuntu.com/releases/24.10/release-20250129/ubuntu-24.10-server-cloudimg-arm64.img",
+      "digest": "sha256:..."
+    },
+    "nerdctlArchive": {
+      "location": "https://github.com/containerd/nerdctl/releases/download/v2.0.3/nerdctl-full-2.0.3-linux-arm64.tar.gz",
+      "digest": "sha256:...",
+      "version": "2.0.3"
+    }
+  }
+`
+
+func newShowProvenanceCommand() *cobra.Command {
+	showProvenanceCommand := &cobra.Command{
+		Use:               "show-provenance INSTANCE",
+		Short:             "Show the artifacts that the instance was built from",
+		Example:           showProvenanceExample,
+		Args:              WrapArgsError(cobra.ExactArgs(1)),
+		RunE:              showProvenanceAction,
+		ValidArgsFunction: showProvenanceBashComplete,
+		GroupID:           advancedCommand,
+	}
+	return showProvenanceCommand
+}
+
+func showProvenanceAction(cmd *cobra.Command, args []string) error {
+	instName := args[0]
+	inst, err := store.Inspect(instName)
+	if err != nil {
+		return err
+	}
+	if inst.Provenance == nil {
+		return fmt.Errorf("the provenance of the instance %q is not recorded, run `limactl start %s` to record it", instName, instName)
+	}
+	b, err := json.MarshalIndent(inst.Provenance, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(cmd.OutOrStdout(), string(b))
+	return err
+}
+
+func showProvenanceBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
+	return bashCompleteInstanceNames(cmd)
+}
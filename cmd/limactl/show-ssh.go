@@ -1,9 +1,7 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"os"
 	"path/filepath"
 	"strings"
 
@@ -81,9 +79,6 @@ func showSSHAction(cmd *cobra.Command, args []string) error {
 	w := cmd.OutOrStdout()
 	inst, err := store.Inspect(instName)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("instance %q does not exist, run `limactl create %s` to create a new instance", instName, instName)
-		}
 		return err
 	}
 	logrus.Warnf("`limactl show-ssh` is deprecated. Instead, use `ssh -F %s %s`.",
@@ -92,10 +87,10 @@ func showSSHAction(cmd *cobra.Command, args []string) error {
 		"ssh",
 		inst.Dir,
 		*inst.Config.User.Name,
-		*inst.Config.SSH.LoadDotSSHPubKeys,
-		*inst.Config.SSH.ForwardAgent,
-		*inst.Config.SSH.ForwardX11,
-		*inst.Config.SSH.ForwardX11Trusted)
+		sshutil.WithDotSSH(*inst.Config.SSH.LoadDotSSHPubKeys),
+		sshutil.WithForwardAgent(*inst.Config.SSH.ForwardAgent),
+		sshutil.WithForwardX11(*inst.Config.SSH.ForwardX11),
+		sshutil.WithForwardX11Trusted(*inst.Config.SSH.ForwardX11Trusted))
 	if err != nil {
 		return err
 	}
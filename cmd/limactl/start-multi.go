@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+// startMultiFlags are the flags of `limactl start` that can be used for starting multiple instances.
+// The other flags, such as `--set`, are only for creating an instance.
+var startMultiFlags = []string{"timeout", "attach", "max-concurrency", "tag"}
+
+// startMultiAction starts the existing instances concurrently, by running `limactl start INSTANCE` for each instance.
+// The outputs of the child processes are prefixed with the instance names.
+func startMultiAction(cmd *cobra.Command, instNames []string) error {
+	var unsupported []string
+	cmd.LocalFlags().Visit(func(f *pflag.Flag) {
+		if !slices.Contains(startMultiFlags, f.Name) {
+			unsupported = append(unsupported, "--"+f.Name)
+		}
+	})
+	if len(unsupported) > 0 {
+		return fmt.Errorf("flags %v cannot be used for starting multiple instances", unsupported)
+	}
+	for i, instName := range instNames {
+		if slices.Contains(instNames[:i], instName) {
+			return fmt.Errorf("instance %q is specified multiple times", instName)
+		}
+		inst, err := store.Inspect(instName)
+		if err != nil {
+			return fmt.Errorf("failed to inspect instance %q (hint: multiple instances can be started only when they exist already): %w", instName, err)
+		}
+		if len(inst.Errors) > 0 {
+			return fmt.Errorf("errors inspecting instance %q: %+v", instName, inst.Errors)
+		}
+	}
+	maxConcurrency, err := cmd.Flags().GetInt("max-concurrency")
+	if err != nil {
+		return err
+	}
+	if maxConcurrency < 1 {
+		return fmt.Errorf("--max-concurrency must be positive, got %d", maxConcurrency)
+	}
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return err
+	}
+	attach, err := cmd.Flags().GetBool("attach")
+	if err != nil {
+		return err
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// Ctrl-C cancels all the starts
+	ctx, stop := signal.NotifyContext(cmd.Context(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var mu sync.Mutex // for writing the outputs
+	results := make([]error, len(instNames))
+	sem := make(chan struct{}, maxConcurrency)
+	var wg sync.WaitGroup
+	for i, instName := range instNames {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				results[i] = ctx.Err()
+				return
+			}
+			args := []string{"start", "--tty=false", "--timeout=" + timeout.String()}
+			if attach {
+				args = append(args, "--attach")
+			}
+			if logrus.GetLevel() >= logrus.DebugLevel {
+				args = append(args, "--debug")
+			}
+			args = append(args, instName)
+			w := newPrefixWriter(cmd.ErrOrStderr(), &mu, "["+instName+"] ")
+			c := exec.CommandContext(ctx, self, args...)
+			c.Stdout = w
+			c.Stderr = w
+			c.Cancel = func() error {
+				// Let the child process stop the host agent it launched
+				if err := c.Process.Signal(os.Interrupt); err != nil {
+					return c.Process.Kill()
+				}
+				return nil
+			}
+			c.WaitDelay = 30 * time.Second
+			results[i] = c.Run()
+			_ = w.Flush()
+		}()
+	}
+	wg.Wait()
+
+	var errs []error
+	for i, instName := range instNames {
+		if results[i] != nil {
+			logrus.Errorf("Failed to start instance %q: %v", instName, results[i])
+			errs = append(errs, fmt.Errorf("instance %q: %w", instName, results[i]))
+		} else {
+			logrus.Infof("Instance %q is running", instName)
+		}
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to start %d of %d instances: %w", len(errs), len(instNames), errors.Join(errs...))
+	}
+	return nil
+}
+
+// prefixWriter writes the lines with the prefix to w.
+// The incomplete line is buffered until it is completed, or Flush is called.
+type prefixWriter struct {
+	w      io.Writer
+	mu     *sync.Mutex // shared by the writers of w
+	prefix []byte
+	buf    []byte
+}
+
+func newPrefixWriter(w io.Writer, mu *sync.Mutex, prefix string) *prefixWriter {
+	return &prefixWriter{w: w, mu: mu, prefix: []byte(prefix)}
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	pw.buf = append(pw.buf, p...)
+	for {
+		i := bytes.IndexByte(pw.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := pw.writeLine(pw.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		pw.buf = pw.buf[i+1:]
+	}
+}
+
+// Flush writes the incomplete line, if any.
+func (pw *prefixWriter) Flush() error {
+	if len(pw.buf) == 0 {
+		return nil
+	}
+	err := pw.writeLine(append(pw.buf, '\n'))
+	pw.buf = nil
+	return err
+}
+
+func (pw *prefixWriter) writeLine(line []byte) error {
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	_, err := pw.w.Write(append(slices.Clip(pw.prefix), line...))
+	return err
+}
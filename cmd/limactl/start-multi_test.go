@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"sync"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPrefixWriter(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		mu  sync.Mutex
+	)
+	foo := newPrefixWriter(&buf, &mu, "[foo] ")
+	bar := newPrefixWriter(&buf, &mu, "[bar] ")
+	_, err := fmt.Fprint(foo, "line 1\nline ")
+	assert.NilError(t, err)
+	_, err = fmt.Fprint(bar, "line 1\n")
+	assert.NilError(t, err)
+	_, err = fmt.Fprint(foo, "2\nincomplete")
+	assert.NilError(t, err)
+	assert.NilError(t, foo.Flush())
+	assert.NilError(t, bar.Flush())
+	assert.Equal(t, buf.String(), "[foo] line 1\n[bar] line 1\n[foo] line 2\n[foo] incomplete\n")
+}
@@ -4,9 +4,11 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"runtime"
 	"strings"
+	"syscall"
 
 	"github.com/containerd/containerd/identifiers"
 	"github.com/lima-vm/lima/cmd/limactl/editflags"
@@ -28,6 +30,7 @@ func registerCreateFlags(cmd *cobra.Command, commentPrefix string) {
 	flags := cmd.Flags()
 	flags.String("name", "", commentPrefix+"override the instance name")
 	flags.Bool("list-templates", false, commentPrefix+"list available templates and exit")
+	flags.Bool("allow-host-hooks", false, commentPrefix+"allow `hostHooks` of a template fetched from a remote URL to run commands on the host")
 	editflags.RegisterCreate(cmd, commentPrefix)
 }
 
@@ -56,6 +59,9 @@ $ limactl create --name=default /usr/local/share/lima/templates/fedora.yaml
 To create an instance "default" from a remote URL (use carefully, with a trustable source):
 $ limactl create --name=default https://raw.githubusercontent.com/lima-vm/lima/master/templates/alpine.yaml
 
+To create an instance "golang-dev" from a template repository "team" configured in $LIMA_HOME/_config/template-repos.yaml:
+$ limactl create team:golang-dev
+
 To create an instance "local" from a template passed to stdin (--name parameter is required):
 $ cat template.yaml | limactl create --name=local -
 `,
@@ -71,11 +77,17 @@ $ cat template.yaml | limactl create --name=local -
 
 func newStartCommand() *cobra.Command {
 	startCommand := &cobra.Command{
-		Use: "start NAME|FILE.yaml|URL",
+		Use: "start NAME|FILE.yaml|URL [NAME]...",
 		Example: `
 To create an instance "default" (if not created yet) from the default Ubuntu template, and start it:
 $ limactl start
 
+To start the existing instances "foo" and "bar" concurrently:
+$ limactl start foo bar
+
+To start the existing instances with the tag "ci" (` + "`tags`" + ` in lima.yaml) concurrently:
+$ limactl start --tag ci
+
 To create an instance "default" from a template "docker", and start it:
 $ limactl start --name=default template://docker
 
@@ -83,7 +95,7 @@ $ limactl start --name=default template://docker
 See the examples in 'limactl create --help'.
 `,
 		Short:             "Start an instance of Lima",
-		Args:              WrapArgsError(cobra.MaximumNArgs(1)),
+		Args:              WrapArgsError(cobra.ArbitraryArgs),
 		ValidArgsFunction: startBashComplete,
 		RunE:              startAction,
 		GroupID:           basicCommand,
@@ -93,6 +105,9 @@ See the examples in 'limactl create --help'.
 		startCommand.Flags().Bool("foreground", false, "run the hostagent in the foreground")
 	}
 	startCommand.Flags().Duration("timeout", instance.DefaultWatchHostAgentEventsTimeout, "duration to wait for the instance to be running before timing out")
+	startCommand.Flags().Bool("attach", false, "attach to the host agent if it is already running, instead of failing")
+	startCommand.Flags().Int("max-concurrency", 4, "maximum number of instances to start concurrently, when multiple instances are specified")
+	registerTagFlag(startCommand)
 	return startCommand
 }
 
@@ -134,7 +149,7 @@ func loadOrCreateInstance(cmd *cobra.Command, args []string, createOnly bool) (*
 			logrus.Warn("template://experimental/virtiofs-linux was removed in Lima v1.0. Use `limactl create --mount-type=virtiofs template://default` instead. See also <https://lima-vm.io/docs/config/mount/>.")
 		}
 	}
-	if arg == "-" {
+	if arg == limatmpl.StdinLocator {
 		if name == "" {
 			return nil, errors.New("must pass instance name with --name when reading template from stdin")
 		}
@@ -145,7 +160,7 @@ func loadOrCreateInstance(cmd *cobra.Command, args []string, createOnly bool) (*
 		}
 		tty = false
 	}
-	tmpl, err := limatmpl.Read(cmd.Context(), name, arg)
+	tmpl, err := limatmpl.ReadWithStdin(cmd.Context(), cmd.InOrStdin(), name, arg)
 	if err != nil {
 		return nil, err
 	}
@@ -220,10 +235,54 @@ func loadOrCreateInstance(cmd *cobra.Command, args []string, createOnly bool) (*
 			return nil, err
 		}
 	}
+	allowHostHooks, err := flags.GetBool("allow-host-hooks")
+	if err != nil {
+		return nil, err
+	}
+	if err := confirmHostHooks(tmpl, tty, allowHostHooks); err != nil {
+		return nil, err
+	}
 	saveBrokenYAML := tty
 	return instance.Create(cmd.Context(), tmpl.Name, tmpl.Bytes, saveBrokenYAML)
 }
 
+// confirmHostHooks refuses `hostHooks` of a remote template unless the user allows them,
+// as the hooks run arbitrary commands on the host with the privileges of the user.
+// The hooks of the local templates are trusted, in the same way as the local files themselves.
+func confirmHostHooks(tmpl *limatmpl.Template, tty, allowHostHooks bool) error {
+	if !tmpl.Remote {
+		return nil
+	}
+	var y limayaml.LimaYAML
+	if err := limayaml.Unmarshal(tmpl.Bytes, &y, tmpl.Locator); err != nil {
+		return err
+	}
+	if len(y.HostHooks) == 0 {
+		return nil
+	}
+	if allowHostHooks {
+		logrus.Warnf("Allowing `hostHooks` of the remote template %q to run commands on the host", tmpl.Locator)
+		return nil
+	}
+	if !tty {
+		return fmt.Errorf("the remote template %q has `hostHooks` that run commands on the host; pass --allow-host-hooks to allow them", tmpl.Locator)
+	}
+	var commands []string
+	for _, hook := range y.HostHooks {
+		commands = append(commands, fmt.Sprintf("- %s: %q", hook.Event, hook.Command))
+	}
+	message := fmt.Sprintf("The remote template %q has `hostHooks` that run the following commands on the host:\n%s\nAllow them?",
+		tmpl.Locator, strings.Join(commands, "\n"))
+	ans, err := uiutil.Confirm(message, false)
+	if err != nil {
+		return err
+	}
+	if !ans {
+		return errors.New("the remote `hostHooks` were not allowed")
+	}
+	return nil
+}
+
 func applyYQExpressionToExistingInstance(inst *store.Instance, yq string) (*store.Instance, error) {
 	if strings.TrimSpace(yq) == "" {
 		return inst, nil
@@ -403,6 +462,14 @@ func startAction(cmd *cobra.Command, args []string) error {
 	} else if exit {
 		return nil
 	}
+	if instNames, err := taggedInstanceNames(cmd, args); err != nil {
+		return err
+	} else if instNames != nil {
+		args = instNames
+	}
+	if len(args) > 1 {
+		return startMultiAction(cmd, args)
+	}
 	inst, err := loadOrCreateInstance(cmd, args, false)
 	if err != nil {
 		return err
@@ -410,18 +477,34 @@ func startAction(cmd *cobra.Command, args []string) error {
 	if len(inst.Errors) > 0 {
 		return fmt.Errorf("errors inspecting instance: %+v", inst.Errors)
 	}
+	attach, err := cmd.Flags().GetBool("attach")
+	if err != nil {
+		return err
+	}
+	ctx := cmd.Context()
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return err
+	}
+	if timeout > 0 {
+		ctx = instance.WithWatchHostAgentTimeout(ctx, timeout)
+	}
+	if attach && inst.HostAgentPID > 0 {
+		return instance.Attach(ctx, inst)
+	}
 	switch inst.Status {
 	case store.StatusRunning:
 		logrus.Infof("The instance %q is already running. Run `%s` to open the shell.",
 			inst.Name, instance.LimactlShellCmd(inst.Name))
 		// Not an error
 		return nil
+	case store.StatusPaused:
+		return fmt.Errorf("instance %q is paused, run `limactl resume %s` to resume the instance", inst.Name, inst.Name)
 	case store.StatusStopped:
 		// NOP
 	default:
 		logrus.Warnf("expected status %q, got %q", store.StatusStopped, inst.Status)
 	}
-	ctx := cmd.Context()
 	err = networks.Reconcile(ctx, inst.Name)
 	if err != nil {
 		return err
@@ -435,14 +518,10 @@ func startAction(cmd *cobra.Command, args []string) error {
 		}
 		launchHostAgentForeground = foreground
 	}
-	timeout, err := cmd.Flags().GetDuration("timeout")
-	if err != nil {
-		return err
-	}
-	if timeout > 0 {
-		ctx = instance.WithWatchHostAgentTimeout(ctx, timeout)
-	}
 
+	// Ctrl-C during the start stops the host agent launched by instance.Start
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	return instance.Start(ctx, inst, "", launchHostAgentForeground)
 }
 
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limatmpl"
+	"gotest.tools/v3/assert"
+)
+
+func TestConfirmHostHooks(t *testing.T) {
+	hooks := []byte(`
+hostHooks:
+- event: portOpened
+  command: ["touch", "/tmp/pwned"]
+`)
+	local := &limatmpl.Template{Locator: "foo.yaml", Bytes: hooks}
+	assert.NilError(t, confirmHostHooks(local, false, false))
+
+	remote := &limatmpl.Template{Locator: "https://example.com/foo.yaml", Bytes: hooks, Remote: true}
+	assert.ErrorContains(t, confirmHostHooks(remote, false, false), "--allow-host-hooks")
+	assert.NilError(t, confirmHostHooks(remote, false, true))
+
+	noHooks := &limatmpl.Template{Locator: "https://example.com/bar.yaml", Bytes: []byte("cpus: 2\n"), Remote: true}
+	assert.NilError(t, confirmHostHooks(noHooks, false, false))
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/lima-vm/lima/pkg/instance"
 	networks "github.com/lima-vm/lima/pkg/networks/reconcile"
 	"github.com/lima-vm/lima/pkg/store"
+	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
@@ -18,34 +23,80 @@ func newStopCommand() *cobra.Command {
 	}
 
 	stopCmd.Flags().BoolP("force", "f", false, "force stop the instance")
+	stopCmd.Flags().Duration("timeout", 0, "duration to wait for the instance to shut down gracefully before forcibly stopping it "+
+		"(default: the timeout of the driver, e.g., 3m for QEMU and 5s for VZ)")
+	registerTagFlag(stopCmd)
 	return stopCmd
 }
 
 func stopAction(cmd *cobra.Command, args []string) error {
-	instName := DefaultInstanceName
-	if len(args) > 0 {
-		instName = args[0]
-	}
-
-	inst, err := store.Inspect(instName)
+	instNames, err := taggedInstanceNames(cmd, args)
 	if err != nil {
 		return err
 	}
+	tagged := instNames != nil
+	if !tagged {
+		instName := DefaultInstanceName
+		if len(args) > 0 {
+			instName = args[0]
+		}
+		instNames = []string{instName}
+	}
 
 	force, err := cmd.Flags().GetBool("force")
 	if err != nil {
 		return err
 	}
-	if force {
-		instance.StopForcibly(inst)
-	} else {
-		err = instance.StopGracefully(inst)
+	timeout, err := stopTimeout(cmd)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	stopped := false
+	for _, instName := range instNames {
+		inst, err := store.Inspect(instName)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if tagged && !force && inst.Status == store.StatusStopped {
+			logrus.Infof("The instance %q is already stopped", instName)
+			continue
+		}
+		if force {
+			instance.StopForcibly(inst)
+		} else if err := instance.StopGracefully(cmd.Context(), inst, timeout); err != nil {
+			if tagged {
+				err = fmt.Errorf("instance %q: %w", instName, err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		stopped = true
 	}
 	// TODO: should we also reconcile networks if graceful stop returned an error?
-	if err == nil {
-		err = networks.Reconcile(cmd.Context(), "")
+	if stopped {
+		if err := networks.Reconcile(cmd.Context(), ""); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// stopTimeout returns the duration specified with --timeout, or 0 when --timeout is not specified,
+// so that the drivers keep their own default timeouts.
+func stopTimeout(cmd *cobra.Command) (time.Duration, error) {
+	if !cmd.Flags().Changed("timeout") {
+		return 0, nil
+	}
+	timeout, err := cmd.Flags().GetDuration("timeout")
+	if err != nil {
+		return 0, err
+	}
+	if timeout <= 0 {
+		return 0, fmt.Errorf("--timeout must be positive, got %v", timeout)
 	}
-	return err
+	return timeout, nil
 }
 
 func stopBashComplete(cmd *cobra.Command, _ []string, _ string) ([]string, cobra.ShellCompDirective) {
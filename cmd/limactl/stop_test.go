@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestStopTimeout(t *testing.T) {
+	timeout, err := stopTimeout(newStopCommand())
+	assert.NilError(t, err)
+	// the drivers use their own default timeouts
+	assert.Equal(t, timeout, time.Duration(0))
+
+	for _, tc := range []struct {
+		arg      string
+		expected time.Duration
+		err      string
+	}{
+		{arg: "30s", expected: 30 * time.Second},
+		{arg: "0s", err: "--timeout must be positive, got 0s"},
+		{arg: "-1m", err: "--timeout must be positive, got -1m0s"},
+	} {
+		cmd := newStopCommand()
+		assert.NilError(t, cmd.Flags().Set("timeout", tc.arg))
+		timeout, err := stopTimeout(cmd)
+		if tc.err != "" {
+			assert.Error(t, err, tc.err)
+		} else {
+			assert.NilError(t, err)
+			assert.Equal(t, timeout, tc.expected)
+		}
+	}
+}
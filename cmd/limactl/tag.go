@@ -0,0 +1,58 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+func registerTagFlag(cmd *cobra.Command) {
+	cmd.Flags().String("tag", "", "select the instances with the tag (`tags` in lima.yaml), instead of the instance names")
+}
+
+// taggedInstanceNames returns the names of the instances selected with --tag, or nil when --tag is not specified.
+// --tag cannot be used together with the instance names in args.
+func taggedInstanceNames(cmd *cobra.Command, args []string) ([]string, error) {
+	if !cmd.Flags().Changed("tag") {
+		return nil, nil
+	}
+	tag, err := cmd.Flags().GetString("tag")
+	if err != nil {
+		return nil, err
+	}
+	if len(args) > 0 {
+		return nil, fmt.Errorf("--tag cannot be used with the instance names, got %v", args)
+	}
+	instances, err := store.InstancesByTag(tag)
+	if err != nil {
+		return nil, err
+	}
+	if len(instances) == 0 {
+		return nil, fmt.Errorf("no instance has the tag %q", tag)
+	}
+	names := make([]string, len(instances))
+	for i, inst := range instances {
+		names[i] = inst.Name
+	}
+	return names, nil
+}
+
+// copyArgsForInstance fills instName into the guest paths without the instance name (":PATH") in args.
+func copyArgsForInstance(args []string, instName string) ([]string, error) {
+	res := make([]string, len(args))
+	filled := false
+	for i, arg := range args {
+		if strings.HasPrefix(arg, ":") {
+			arg = instName + arg
+			filled = true
+		}
+		res[i] = arg
+	}
+	if !filled {
+		return nil, errors.New("--tag requires the guest paths without the instance name, e.g., \":/tmp/\"")
+	}
+	return res, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/spf13/cobra"
+	"gotest.tools/v3/assert"
+)
+
+func TestTaggedInstanceNames(t *testing.T) {
+	createStoppedInstance(t, "tags: [ci, team-a]\n")
+	instDir := filepath.Join(os.Getenv("LIMA_HOME"), "bar")
+	assert.NilError(t, os.MkdirAll(instDir, 0o755))
+	y := "images: [{location: /dummy.img}]\nuser: {uid: 1000}\ntags: [team-a]\n"
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.LimaYAML), []byte(y), 0o644))
+
+	newCmd := func(args ...string) *cobra.Command {
+		cmd := &cobra.Command{}
+		registerTagFlag(cmd)
+		assert.NilError(t, cmd.ParseFlags(args))
+		return cmd
+	}
+
+	names, err := taggedInstanceNames(newCmd(), nil)
+	assert.NilError(t, err)
+	assert.Assert(t, names == nil)
+
+	names, err = taggedInstanceNames(newCmd("--tag", "team-a"), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"bar", "foo"})
+
+	names, err = taggedInstanceNames(newCmd("--tag=ci"), nil)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names, []string{"foo"})
+
+	_, err = taggedInstanceNames(newCmd("--tag", "team-b"), nil)
+	assert.Error(t, err, `no instance has the tag "team-b"`)
+
+	_, err = taggedInstanceNames(newCmd("--tag", "Team-A"), nil)
+	assert.ErrorContains(t, err, `invalid tag "Team-A"`)
+
+	_, err = taggedInstanceNames(newCmd("--tag", "ci"), []string{"foo"})
+	assert.ErrorContains(t, err, "--tag cannot be used with the instance names")
+
+	// The tagged instances that are already stopped are skipped
+	assert.NilError(t, executeApp(t, "stop", "--tag", "ci"))
+}
+
+func TestCopyArgsForInstance(t *testing.T) {
+	args, err := copyArgsForInstance([]string{"./file", "bar:/etc/hosts", ":/tmp/"}, "foo")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, args, []string{"./file", "bar:/etc/hosts", "foo:/tmp/"})
+
+	_, err = copyArgsForInstance([]string{"./file", "bar:/tmp/"}, "foo")
+	assert.ErrorContains(t, err, "--tag requires the guest paths without the instance name")
+}
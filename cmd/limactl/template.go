@@ -60,7 +60,7 @@ func newTemplateCopyCommand() *cobra.Command {
 }
 
 func templateCopyAction(cmd *cobra.Command, args []string) error {
-	tmpl, err := limatmpl.Read(cmd.Context(), "", args[0])
+	tmpl, err := limatmpl.ReadWithStdin(cmd.Context(), cmd.InOrStdin(), "", args[0])
 	if err != nil {
 		return err
 	}
@@ -81,35 +81,68 @@ func templateCopyAction(cmd *cobra.Command, args []string) error {
 	return err
 }
 
+var templateValidateExample = `  Template locators are local files, file://, https://, or template:// URLs, or "-" for stdin
+
+  # Validate a local file
+  limactl template validate lima.yaml
+
+  # Validate a template passed to stdin, and print the effective config
+  cat lima.yaml | limactl template validate --fill -
+`
+
 func newTemplateValidateCommand() *cobra.Command {
 	templateValidateCommand := &cobra.Command{
-		Use:   "validate TEMPLATE [TEMPLATE, ...]",
-		Short: "Validate YAML templates",
-		Args:  WrapArgsError(cobra.MinimumNArgs(1)),
-		RunE:  templateValidateAction,
+		Use:     "validate TEMPLATE [TEMPLATE, ...]",
+		Short:   "Validate YAML templates",
+		Example: templateValidateExample,
+		Args:    WrapArgsError(cobra.MinimumNArgs(1)),
+		RunE:    templateValidateAction,
 	}
 	templateValidateCommand.Flags().Bool("fill", false, "fill defaults")
+	templateValidateCommand.Flags().Bool("fill-defaults", false, "print the effective config, with the defaults filled and the default.yaml and override.yaml merged (alias of --fill)")
 	return templateValidateCommand
 }
 
+// redactedConfig returns the config to be printed by `limactl validate --fill-defaults`.
+// LimaYAML does not contain secrets today, so nothing is redacted yet.
+func redactedConfig(y *limayaml.LimaYAML) *limayaml.LimaYAML {
+	return y
+}
+
 func templateValidateAction(cmd *cobra.Command, args []string) error {
 	fill, err := cmd.Flags().GetBool("fill")
 	if err != nil {
 		return err
 	}
+	fillDefaults, err := cmd.Flags().GetBool("fill-defaults")
+	if err != nil {
+		return err
+	}
+	fill = fill || fillDefaults
 	limaDir, err := dirnames.LimaDir()
 	if err != nil {
 		return err
 	}
 
+	var stdinUsed bool
 	for _, arg := range args {
-		tmpl, err := limatmpl.Read(cmd.Context(), "", arg)
+		if arg == limatmpl.StdinLocator {
+			if stdinUsed {
+				return fmt.Errorf("template locator %q (stdin) can be specified only once", arg)
+			}
+			stdinUsed = true
+		}
+		tmpl, err := limatmpl.ReadWithStdin(cmd.Context(), cmd.InOrStdin(), "", arg)
 		if err != nil {
 			return err
 		}
 		if len(tmpl.Bytes) == 0 {
 			return fmt.Errorf("don't know how to interpret %q as a template locator", arg)
 		}
+		if tmpl.Name == "" && arg == limatmpl.StdinLocator {
+			// The name is only used for the potential instance directory
+			tmpl.Name = "stdin"
+		}
 		if tmpl.Name == "" {
 			return fmt.Errorf("can't determine instance name from template locator %q", arg)
 		}
@@ -125,7 +158,7 @@ func templateValidateAction(cmd *cobra.Command, args []string) error {
 		}
 		logrus.Infof("%q: OK", arg)
 		if fill {
-			b, err := limayaml.Marshal(y, len(args) > 1)
+			b, err := limayaml.Marshal(redactedConfig(y), len(args) > 1)
 			if err != nil {
 				return fmt.Errorf("failed to marshal template %q again after filling defaults: %w", arg, err)
 			}
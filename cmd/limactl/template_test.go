@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestTemplateValidateStdin(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	configDir := filepath.Join(limaHome, filenames.ConfigDir)
+	assert.NilError(t, os.MkdirAll(configDir, 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(configDir, filenames.Default), []byte("cpus: 7\n"), 0o644))
+
+	const yaml = `images:
+- location: https://example.com/image-x86_64.img
+  arch: x86_64
+- location: https://example.com/image-aarch64.img
+  arch: aarch64
+`
+	var stdout bytes.Buffer
+	app := newApp()
+	app.SetArgs([]string{"validate", "--fill", "-"})
+	app.SetIn(strings.NewReader(yaml))
+	app.SetOut(&stdout)
+	assert.NilError(t, app.Execute())
+	// default.yaml is merged
+	assert.Assert(t, strings.Contains(stdout.String(), "cpus: 7"), stdout.String())
+
+	app = newApp()
+	app.SetArgs([]string{"validate", "-", "-"})
+	app.SetIn(strings.NewReader(yaml))
+	assert.ErrorContains(t, app.Execute(), "can be specified only once")
+}
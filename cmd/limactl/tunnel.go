@@ -1,9 +1,7 @@
 package main
 
 import (
-	"errors"
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"strconv"
@@ -60,16 +58,10 @@ func tunnelAction(cmd *cobra.Command, args []string) error {
 	}
 	stdout, stderr := cmd.OutOrStdout(), cmd.ErrOrStderr()
 	instName := args[0]
-	inst, err := store.Inspect(instName)
+	inst, err := store.InspectRunning(instName)
 	if err != nil {
-		if errors.Is(err, os.ErrNotExist) {
-			return fmt.Errorf("instance %q does not exist, run `limactl create %s` to create a new instance", instName, instName)
-		}
 		return err
 	}
-	if inst.Status == store.StatusStopped {
-		return fmt.Errorf("instance %q is stopped, run `limactl start %s` to start the instance", instName, instName)
-	}
 
 	if port == 0 {
 		port, err = freeport.TCP()
@@ -87,10 +79,10 @@ func tunnelAction(cmd *cobra.Command, args []string) error {
 		arg0,
 		inst.Dir,
 		*inst.Config.User.Name,
-		*inst.Config.SSH.LoadDotSSHPubKeys,
-		*inst.Config.SSH.ForwardAgent,
-		*inst.Config.SSH.ForwardX11,
-		*inst.Config.SSH.ForwardX11Trusted)
+		sshutil.WithDotSSH(*inst.Config.SSH.LoadDotSSHPubKeys),
+		sshutil.WithForwardAgent(*inst.Config.SSH.ForwardAgent),
+		sshutil.WithForwardX11(*inst.Config.SSH.ForwardX11),
+		sshutil.WithForwardX11Trusted(*inst.Config.SSH.ForwardX11Trusted))
 	if err != nil {
 		return err
 	}
@@ -42,6 +42,10 @@ require (
 	github.com/spf13/cobra v1.8.1
 	github.com/spf13/pflag v1.0.6 // gomodjail:confined
 	github.com/wk8/go-ordered-map/v2 v2.1.8 // gomodjail:confined
+	go.opentelemetry.io/otel v1.32.0 // gomodjail:confined
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp v1.32.0 // gomodjail:confined
+	go.opentelemetry.io/otel/sdk v1.32.0 // gomodjail:confined
+	go.opentelemetry.io/otel/trace v1.32.0 // gomodjail:confined
 	golang.org/x/net v0.35.0 // gomodjail:confined
 	golang.org/x/sync v0.11.0 // gomodjail:confined
 	golang.org/x/sys v0.30.0
@@ -64,6 +68,7 @@ require (
 	github.com/bmatcuk/doublestar/v4 v4.7.1 // indirect // gomodjail:confined
 	github.com/braydonk/yaml v0.9.0 // indirect // gomodjail:confined
 	github.com/buger/jsonparser v1.1.1 // indirect // gomodjail:confined
+	github.com/cenkalti/backoff/v4 v4.3.0 // indirect // gomodjail:confined
 	github.com/containerd/errdefs v0.3.0 // indirect // gomodjail:confined
 	github.com/containerd/log v0.1.0 // indirect // gomodjail:confined
 	github.com/davecgh/go-spew v1.1.2-0.20180830191138-d8f796af33cc // indirect // gomodjail:confined
@@ -71,11 +76,13 @@ require (
 	github.com/dimchansky/utfbom v1.1.1 // indirect // gomodjail:confined
 	github.com/djherbis/times v1.6.0 // indirect // gomodjail:confined
 	github.com/elliotchance/orderedmap v1.7.1 // indirect // gomodjail:confined
+	github.com/elliotwutingfeng/asciiset v0.0.0-20230602022725-51bbb787efab // indirect
 	github.com/emicklei/go-restful/v3 v3.11.0 // indirect // gomodjail:confined
 	github.com/fatih/color v1.18.0 // indirect // gomodjail:confined
 	github.com/fsnotify/fsnotify v1.8.0 // indirect
 	github.com/fxamacker/cbor/v2 v2.7.0 // indirect // gomodjail:confined
 	github.com/go-logr/logr v1.4.2 // indirect // gomodjail:confined
+	github.com/go-logr/stdr v1.2.2 // indirect // gomodjail:confined
 	github.com/go-openapi/jsonpointer v0.21.0 // indirect // gomodjail:confined
 	github.com/go-openapi/jsonreference v0.20.2 // indirect // gomodjail:confined
 	github.com/go-openapi/swag v0.23.0 // indirect // gomodjail:confined
@@ -87,6 +94,7 @@ require (
 	github.com/google/gofuzz v1.2.0 // indirect // gomodjail:confined
 	github.com/google/gopacket v1.1.19 // indirect // gomodjail:confined
 	github.com/google/uuid v1.6.0 // indirect // gomodjail:confined
+	github.com/grpc-ecosystem/grpc-gateway/v2 v2.23.0 // indirect // gomodjail:confined
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect // gomodjail:confined
 	github.com/insomniacslk/dhcp v0.0.0-20240710054256-ddd8a41251c9 // indirect
 	github.com/jinzhu/copier v0.4.0 // indirect // gomodjail:confined
@@ -115,12 +123,16 @@ require (
 	github.com/u-root/uio v0.0.0-20240224005618-d2acac8f3701 // indirect
 	github.com/x448/float16 v0.8.4 // indirect // gomodjail:confined
 	github.com/yuin/gopher-lua v1.1.1 // indirect // gomodjail:confined
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace v1.32.0 // indirect // gomodjail:confined
+	go.opentelemetry.io/otel/metric v1.32.0 // indirect // gomodjail:confined
+	go.opentelemetry.io/proto/otlp v1.3.1 // indirect // gomodjail:confined
 	golang.org/x/crypto v0.33.0 // indirect // gomodjail:confined
 	golang.org/x/mod v0.22.0 // indirect // gomodjail:confined
 	golang.org/x/oauth2 v0.24.0 // indirect // gomodjail:confined
 	golang.org/x/term v0.29.0 // indirect // gomodjail:confined
 	golang.org/x/time v0.7.0 // indirect // gomodjail:confined
 	golang.org/x/tools v0.28.0 // indirect // gomodjail:confined
+	google.golang.org/genproto/googleapis/api v0.0.0-20241202173237-19429a94021a // indirect // gomodjail:confined
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20241202173237-19429a94021a // indirect // gomodjail:confined
 	gopkg.in/evanphx/json-patch.v4 v4.12.0 // indirect // gomodjail:confined
 	gopkg.in/inf.v0 v0.9.1 // indirect // gomodjail:confined
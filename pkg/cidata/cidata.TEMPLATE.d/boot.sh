@@ -50,6 +50,10 @@ fi
 
 if [ -d "${LIMA_CIDATA_MNT}"/provision.system ]; then
 	for f in "${LIMA_CIDATA_MNT}"/provision.system/*; do
+		if ! provision_when.sh "$f"; then
+			INFO "Skipping $f (the condition is not satisfied)"
+			continue
+		fi
 		INFO "Executing $f"
 		if ! "$f"; then
 			WARNING "Failed to execute $f"
@@ -58,6 +62,26 @@ if [ -d "${LIMA_CIDATA_MNT}"/provision.system ]; then
 	done
 fi
 
+if [ -d "${LIMA_CIDATA_MNT}"/systemd ]; then
+	if [ -d /run/systemd/system ]; then
+		for f in "${LIMA_CIDATA_MNT}"/systemd/*; do
+			INFO "Installing systemd unit $(basename "$f")"
+			install -m 644 "$f" /etc/systemd/system/
+		done
+		systemctl daemon-reload
+		for unit in ${LIMA_CIDATA_SYSTEMD_UNITS_ENABLED}; do
+			INFO "Enabling systemd unit ${unit}"
+			if ! systemctl enable --now "${unit}"; then
+				WARNING "Failed to enable systemd unit ${unit}"
+				CODE=1
+			fi
+		done
+	else
+		WARNING "systemd is not running, skipping to install the systemd units"
+		CODE=1
+	fi
+fi
+
 USER_SCRIPT="${LIMA_CIDATA_HOME}/.lima-user-script"
 if [ -d "${LIMA_CIDATA_MNT}"/provision.user ]; then
 	if [ ! -f /sbin/openrc-run ]; then
@@ -65,6 +89,10 @@ if [ -d "${LIMA_CIDATA_MNT}"/provision.user ]; then
 	fi
 	params=$(grep -o '^PARAM_[^=]*' "${LIMA_CIDATA_MNT}"/param.env | paste -sd ,)
 	for f in "${LIMA_CIDATA_MNT}"/provision.user/*; do
+		if ! provision_when.sh "$f"; then
+			INFO "Skipping $f (the condition is not satisfied)"
+			continue
+		fi
 		INFO "Executing $f (as user ${LIMA_CIDATA_USER})"
 		cp "$f" "${USER_SCRIPT}"
 		chown "${LIMA_CIDATA_USER}" "${USER_SCRIPT}"
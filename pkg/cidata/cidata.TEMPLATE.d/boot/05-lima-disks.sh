@@ -15,9 +15,23 @@ for i in $(seq 0 $((LIMA_CIDATA_DISKS - 1))); do
 	FORMAT_DISK="$(get_disk_var "$i" "FORMAT")"
 	FORMAT_FSTYPE="$(get_disk_var "$i" "FSTYPE")"
 	FORMAT_FSARGS="$(get_disk_var "$i" "FSARGS")"
+	READONLY="$(get_disk_var "$i" "READONLY")"
 
 	test -n "$FORMAT_DISK" || FORMAT_DISK=true
 	test -n "$FORMAT_FSTYPE" || FORMAT_FSTYPE=ext4
+	test -n "$READONLY" || READONLY=false
+
+	if $READONLY; then
+		# The read-only disk may be shared with other instances, so it is neither formatted nor resized
+		mkdir -p "/mnt/lima-${DISK_NAME}"
+		# "ro" alone still replays the journal of ext4, which writes to the disk
+		MOUNT_OPTS=ro
+		case "$FORMAT_FSTYPE" in
+		ext3 | ext4) MOUNT_OPTS=ro,noload ;;
+		esac
+		mount -t "$FORMAT_FSTYPE" -o "$MOUNT_OPTS" "/dev/${DEVICE_NAME}1" "/mnt/lima-${DISK_NAME}"
+		continue
+	fi
 
 	# first time setup
 	if [[ ! -b "/dev/disk/by-label/lima-${DISK_NAME}" ]]; then
@@ -35,7 +35,7 @@ name="lima-guestagent"
 description="Forward ports to the lima-hostagent"
 
 command=${LIMA_CIDATA_GUEST_INSTALL_PREFIX}/bin/lima-guestagent
-command_args="daemon --debug=${LIMA_CIDATA_DEBUG} --vsock-port \"${LIMA_CIDATA_VSOCK_PORT}\" --virtio-port \"${LIMA_CIDATA_VIRTIO_PORT}\""
+command_args="daemon --debug=${LIMA_CIDATA_DEBUG} --vsock-port \"${LIMA_CIDATA_VSOCK_PORT}\" --virtio-port \"${LIMA_CIDATA_VIRTIO_PORT}\" --exclude-interfaces \"${LIMA_CIDATA_PORT_FORWARDS_EXCLUDED_INTERFACES}\""
 command_background=true
 pidfile="/run/lima-guestagent.pid"
 EOF
@@ -47,11 +47,14 @@ else
 	# Remove legacy systemd service
 	rm -f "${LIMA_CIDATA_HOME}/.config/systemd/user/lima-guestagent.service"
 
+	set --
 	if [ "${LIMA_CIDATA_VSOCK_PORT}" != "0" ]; then
-		sudo "${LIMA_CIDATA_GUEST_INSTALL_PREFIX}"/bin/lima-guestagent install-systemd --vsock-port "${LIMA_CIDATA_VSOCK_PORT}"
+		set -- --vsock-port "${LIMA_CIDATA_VSOCK_PORT}"
 	elif [ "${LIMA_CIDATA_VIRTIO_PORT}" != "" ]; then
-		sudo "${LIMA_CIDATA_GUEST_INSTALL_PREFIX}"/bin/lima-guestagent install-systemd --virtio-port "${LIMA_CIDATA_VIRTIO_PORT}"
-	else
-		sudo "${LIMA_CIDATA_GUEST_INSTALL_PREFIX}"/bin/lima-guestagent install-systemd
+		set -- --virtio-port "${LIMA_CIDATA_VIRTIO_PORT}"
 	fi
+	if [ "${LIMA_CIDATA_PORT_FORWARDS_EXCLUDED_INTERFACES}" != "" ]; then
+		set -- "$@" --exclude-interfaces "${LIMA_CIDATA_PORT_FORWARDS_EXCLUDED_INTERFACES}"
+	fi
+	sudo "${LIMA_CIDATA_GUEST_INSTALL_PREFIX}"/bin/lima-guestagent install-systemd "$@"
 fi
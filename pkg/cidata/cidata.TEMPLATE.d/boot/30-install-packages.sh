@@ -15,6 +15,10 @@ if [ -d "${LIMA_CIDATA_MNT}"/provision.dependency ]; then
 	echo "Detected dependency provisioning scripts, running before default dependency installation"
 	CODE=0
 	for f in "${LIMA_CIDATA_MNT}"/provision.dependency/*; do
+		if ! provision_when.sh "$f"; then
+			echo "Skipping $f (the condition is not satisfied)"
+			continue
+		fi
 		if ! "$f"; then
 			CODE=1
 		fi
@@ -0,0 +1,10 @@
+#!/bin/sh
+# Usage: provision_when.sh SCRIPT
+# Exits with non-zero when the `when` condition of the provisioning script is not satisfied.
+set -eu
+
+when="${LIMA_CIDATA_MNT}/provision.when/$(basename "$1")"
+if [ ! -e "${when}" ]; then
+	exit 0
+fi
+exec /bin/sh "${when}"
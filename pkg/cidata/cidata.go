@@ -1,7 +1,8 @@
 package cidata
 
 import (
-	"compress/gzip"
+	"context"
+	"encoding/base64"
 	"errors"
 	"fmt"
 	"io"
@@ -16,6 +17,7 @@ import (
 
 	"github.com/docker/go-units"
 	"github.com/lima-vm/lima/pkg/debugutil"
+	"github.com/lima-vm/lima/pkg/fatutil"
 	"github.com/lima-vm/lima/pkg/identifierutil"
 	"github.com/lima-vm/lima/pkg/iso9660util"
 	"github.com/lima-vm/lima/pkg/limayaml"
@@ -23,8 +25,10 @@ import (
 	"github.com/lima-vm/lima/pkg/networks"
 	"github.com/lima-vm/lima/pkg/networks/usernet"
 	"github.com/lima-vm/lima/pkg/osutil"
+	"github.com/lima-vm/lima/pkg/provisioncond"
 	"github.com/lima-vm/lima/pkg/sshutil"
 	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/lima-vm/lima/pkg/tracing"
 	"github.com/lima-vm/lima/pkg/usrlocalsharelima"
 	"github.com/sirupsen/logrus"
 )
@@ -127,6 +131,10 @@ func templateArgs(bootScripts bool, instDir, name string, instConfig *limayaml.L
 		Home:               *instConfig.User.Home,
 		Shell:              *instConfig.User.Shell,
 		UID:                *instConfig.User.UID,
+		Groups:             instConfig.User.Groups,
+		Password:           *instConfig.User.Password,
+		PlainTextPassword:  *instConfig.User.InsecurePlainTextPassword,
+		UnlockPassword:     !*instConfig.User.LockPassword,
 		GuestInstallPrefix: *instConfig.GuestInstallPrefix,
 		UpgradePackages:    *instConfig.UpgradePackages,
 		Containerd:         Containerd{System: *instConfig.Containerd.System, User: *instConfig.Containerd.User, Archive: archive},
@@ -139,6 +147,7 @@ func templateArgs(bootScripts bool, instDir, name string, instConfig *limayaml.L
 		VirtioPort:     virtioPort,
 		Plain:          *instConfig.Plain,
 		TimeZone:       *instConfig.TimeZone,
+		Locale:         *instConfig.Locale,
 		Param:          instConfig.Param,
 	}
 
@@ -175,6 +184,11 @@ func templateArgs(bootScripts bool, instDir, name string, instConfig *limayaml.L
 	if err != nil {
 		return nil, err
 	}
+	additionalPubKeys, err := sshutil.AuthorizedKeys(instConfig.SSH.AdditionalAuthorizedKeys)
+	if err != nil {
+		return nil, err
+	}
+	pubKeys = append(pubKeys, additionalPubKeys...)
 	if len(pubKeys) == 0 {
 		return nil, errors.New("no SSH key was found, run `ssh-keygen`")
 	}
@@ -249,21 +263,38 @@ func templateArgs(bootScripts bool, instDir, name string, instConfig *limayaml.L
 		if d.FSType != nil {
 			fstype = *d.FSType
 		}
+		readOnly := d.ReadOnly != nil && *d.ReadOnly
+		if readOnly {
+			// A read-only disk is never formatted
+			format = false
+		}
 		args.Disks = append(args.Disks, Disk{
-			Name:   d.Name,
-			Device: diskDeviceNameFromOrder(i),
-			Format: format,
-			FSType: fstype,
-			FSArgs: d.FSArgs,
+			Name:     d.Name,
+			Device:   diskDeviceNameFromOrder(i),
+			Format:   format,
+			FSType:   fstype,
+			FSArgs:   d.FSArgs,
+			ReadOnly: readOnly,
 		})
 	}
 
-	args.Networks = append(args.Networks, Network{MACAddress: limayaml.MACAddress(instDir), Interface: networks.SlirpNICName, Metric: 200})
+	var slirpMTU uint32
+	if instConfig.MTU != nil {
+		slirpMTU = *instConfig.MTU
+	}
+	args.Networks = append(args.Networks, Network{MACAddress: limayaml.MACAddress(instDir), Interface: networks.SlirpNICName, Metric: 200, MTU: slirpMTU})
 	for i, nw := range instConfig.Networks {
 		if i == firstUsernetIndex {
 			continue
 		}
-		args.Networks = append(args.Networks, Network{MACAddress: nw.MACAddress, Interface: nw.Interface, Metric: *nw.Metric})
+		var mtu uint32
+		if nw.MTU != nil {
+			mtu = *nw.MTU
+		}
+		args.Networks = append(args.Networks, Network{MACAddress: nw.MACAddress, Interface: nw.Interface, Metric: *nw.Metric, MTU: mtu})
+		if nw.PortForwards != nil && !*nw.PortForwards {
+			args.PortForwardsExcludedInterfaces = append(args.PortForwardsExcludedInterfaces, nw.Interface)
+		}
 	}
 
 	args.Env, err = setupEnv(instConfig.Env, *instConfig.PropagateProxyEnv, args.SlirpGateway)
@@ -319,12 +350,23 @@ func templateArgs(bootScripts bool, instDir, name string, instConfig *limayaml.L
 
 	args.BootCmds = getBootCmds(instConfig.Provision)
 
+	args.WriteFiles, err = getWriteFiles(instConfig.Files)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, f := range instConfig.Provision {
 		if f.Mode == limayaml.ProvisionModeDependency && *f.SkipDefaultDependencyResolution {
 			args.SkipDefaultDependencyResolution = true
 		}
 	}
 
+	for _, unit := range instConfig.SystemdUnits {
+		if *unit.Enabled {
+			args.SystemdUnitsEnabled = append(args.SystemdUnitsEnabled, unit.Name)
+		}
+	}
+
 	return &args, nil
 }
 
@@ -351,11 +393,46 @@ func GenerateCloudConfig(instDir, name string, instConfig *limayaml.LimaYAML) er
 	return os.WriteFile(filepath.Join(instDir, filenames.CloudConfig), config, 0o444)
 }
 
-func GenerateISO9660(instDir, name string, instConfig *limayaml.LimaYAML, udpDNSLocalPort, tcpDNSLocalPort int, nerdctlArchive string, vsockPort int, virtioPort string) error {
+// readSSHHostKey reads the SSH host key generated by sshutil.EnsureHostKey.
+// Nil is returned when the host key does not exist, so that the guest generates its own host keys.
+func readSSHHostKey(instDir string) (*SSHHostKey, error) {
+	private, err := os.ReadFile(filepath.Join(instDir, filenames.SSHHostKey))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	public, err := os.ReadFile(filepath.Join(instDir, filenames.SSHHostKeyPub))
+	if err != nil {
+		return nil, err
+	}
+	return &SSHHostKey{
+		PrivateLines: strings.Split(strings.TrimSpace(string(private)), "\n"),
+		Public:       strings.TrimSpace(string(public)),
+	}, nil
+}
+
+// GenerateISO9660 generates the cidata image.
+// The image is written as filenames.CIDataISO in ISO9660, or as filenames.CIDataVFAT in VFAT when WithVFAT is specified.
+func GenerateISO9660(ctx context.Context, instDir, name string, instConfig *limayaml.LimaYAML, udpDNSLocalPort, tcpDNSLocalPort int, nerdctlArchive string, vsockPort int, virtioPort string, opts ...Opt) (retErr error) {
+	_, span := tracing.Start(ctx, "cidata.GenerateISO9660")
+	defer func() { tracing.End(span, retErr) }()
+
+	o, err := newOptions(opts)
+	if err != nil {
+		return err
+	}
+
 	args, err := templateArgs(true, instDir, name, instConfig, udpDNSLocalPort, tcpDNSLocalPort, vsockPort, virtioPort)
 	if err != nil {
 		return err
 	}
+	if args.VMType != limayaml.WSL2 {
+		if args.SSHHostKey, err = readSSHHostKey(instDir); err != nil {
+			return err
+		}
+	}
 
 	if err := ValidateTemplateArgs(args); err != nil {
 		return err
@@ -366,41 +443,16 @@ func GenerateISO9660(instDir, name string, instConfig *limayaml.LimaYAML, udpDNS
 		return err
 	}
 
-	for i, f := range instConfig.Provision {
-		switch f.Mode {
-		case limayaml.ProvisionModeSystem, limayaml.ProvisionModeUser, limayaml.ProvisionModeDependency:
-			layout = append(layout, iso9660util.Entry{
-				Path:   fmt.Sprintf("provision.%s/%08d", f.Mode, i),
-				Reader: strings.NewReader(f.Script),
-			})
-		case limayaml.ProvisionModeBoot:
-			continue
-		case limayaml.ProvisionModeAnsible:
-			continue
-		default:
-			return fmt.Errorf("unknown provision mode %q", f.Mode)
-		}
-	}
-
-	guestAgentBinary, err := usrlocalsharelima.GuestAgentBinary(*instConfig.OS, *instConfig.Arch)
+	provisionLayout, err := getProvisionLayout(instConfig.Provision)
 	if err != nil {
 		return err
 	}
-	var guestAgent io.ReadCloser
-	guestAgent, err = os.Open(guestAgentBinary)
+	layout = append(layout, provisionLayout...)
+	layout = append(layout, getSystemdUnitsLayout(instConfig.SystemdUnits)...)
+
+	guestAgent, err := usrlocalsharelima.OpenGuestAgentBinary(*instConfig.OS, *instConfig.Arch)
 	if err != nil {
-		if !errors.Is(err, os.ErrNotExist) {
-			return err
-		}
-		compressedGuestAgent, err := os.Open(guestAgentBinary + ".gz")
-		if err != nil {
-			return err
-		}
-		logrus.Debugf("Decompressing %s.gz", guestAgentBinary)
-		guestAgent, err = gzip.NewReader(compressedGuestAgent)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 	defer guestAgent.Close()
 	layout = append(layout, iso9660util.Entry{
@@ -430,7 +482,10 @@ func GenerateISO9660(instDir, name string, instConfig *limayaml.LimaYAML, udpDNS
 		return writeCIDataDir(filepath.Join(instDir, filenames.CIDataISODir), layout)
 	}
 
-	return iso9660util.Write(filepath.Join(instDir, filenames.CIDataISO), "cidata", layout)
+	if o.vfat {
+		return fatutil.Write(filepath.Join(instDir, filenames.CIDataVFAT), o.volumeLabel, layout)
+	}
+	return iso9660util.Write(filepath.Join(instDir, filenames.CIDataISO), o.volumeLabel, layout)
 }
 
 func getCert(content string) Cert {
@@ -445,6 +500,63 @@ func getCert(content string) Cert {
 	return Cert{Lines: lines}
 }
 
+// getProvisionLayout returns the layout entries of the provisioning scripts executed by boot.sh.
+func getProvisionLayout(p []limayaml.Provision) ([]iso9660util.Entry, error) {
+	var layout []iso9660util.Entry
+	for i, f := range p {
+		switch f.Mode {
+		case limayaml.ProvisionModeSystem, limayaml.ProvisionModeUser, limayaml.ProvisionModeDependency:
+			layout = append(layout, iso9660util.Entry{
+				Path:   fmt.Sprintf("provision.%s/%08d", f.Mode, i),
+				Reader: strings.NewReader(provisionScript(f)),
+			})
+			if f.When != nil {
+				cond, err := provisioncond.Parse(*f.When)
+				if err != nil {
+					return nil, fmt.Errorf("field `provision[%d].when` is invalid: %w", i, err)
+				}
+				// Evaluated by the boot scripts before executing provision.<mode>/<index>
+				layout = append(layout, iso9660util.Entry{
+					Path:   fmt.Sprintf("provision.when/%08d", i),
+					Reader: strings.NewReader(cond.Shell()),
+				})
+			}
+		case limayaml.ProvisionModeBoot:
+			continue
+		case limayaml.ProvisionModeAnsible:
+			continue
+		default:
+			return nil, fmt.Errorf("unknown provision mode %q", f.Mode)
+		}
+	}
+	return layout, nil
+}
+
+// provisionScript returns the script with the hash-bang line of the interpreter, if specified.
+// The existing hash-bang line of the script is replaced.
+func provisionScript(p limayaml.Provision) string {
+	if p.Interpreter == nil || *p.Interpreter == "" {
+		return p.Script
+	}
+	script := p.Script
+	if strings.HasPrefix(script, "#!") {
+		_, script, _ = strings.Cut(script, "\n")
+	}
+	return "#!" + *p.Interpreter + "\n" + script
+}
+
+// getSystemdUnitsLayout returns the layout of the systemd units, which are installed by boot.sh.
+func getSystemdUnitsLayout(units []limayaml.SystemdUnit) []iso9660util.Entry {
+	var layout []iso9660util.Entry
+	for _, unit := range units {
+		layout = append(layout, iso9660util.Entry{
+			Path:   "systemd/" + unit.Name,
+			Reader: strings.NewReader(unit.Content),
+		})
+	}
+	return layout
+}
+
 func getBootCmds(p []limayaml.Provision) []BootCmds {
 	var bootCmds []BootCmds
 	for _, f := range p {
@@ -494,3 +606,35 @@ func writeCIDataDir(rootPath string, layout []iso9660util.Entry) error {
 
 	return nil
 }
+
+func getWriteFiles(files []limayaml.WriteFile) ([]WriteFile, error) {
+	var res []WriteFile
+	for _, f := range files {
+		var content []byte
+		switch {
+		case f.Content != nil:
+			content = []byte(*f.Content)
+		case f.Source != nil:
+			expanded, err := localpathutil.Expand(*f.Source)
+			if err != nil {
+				return nil, err
+			}
+			content, err = os.ReadFile(expanded)
+			if err != nil {
+				return nil, err
+			}
+		}
+		wf := WriteFile{
+			Path:    f.Path,
+			Content: base64.StdEncoding.EncodeToString(content),
+		}
+		if f.Owner != nil {
+			wf.Owner = *f.Owner
+		}
+		if f.Permissions != nil {
+			wf.Permissions = *f.Permissions
+		}
+		res = append(res, wf)
+	}
+	return res, nil
+}
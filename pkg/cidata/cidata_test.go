@@ -1,12 +1,15 @@
 package cidata
 
 import (
+	"io"
 	"net"
 	"net/url"
 	"strings"
 	"testing"
 
+	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/lima-vm/lima/pkg/networks"
+	"github.com/lima-vm/lima/pkg/ptr"
 
 	"gotest.tools/v3/assert"
 )
@@ -57,3 +60,53 @@ func TestSetupInvalidEnv(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Equal(t, envs[envKey], envValue)
 }
+
+func TestGetProvisionLayout(t *testing.T) {
+	provision := []limayaml.Provision{
+		{Mode: limayaml.ProvisionModeSystem, Script: "#!/bin/bash\necho system\n"},
+		{Mode: limayaml.ProvisionModeBoot, Script: "echo boot\n"},
+		{Mode: limayaml.ProvisionModeUser, Script: "#!/bin/sh\nprint('user')\n", Interpreter: ptr.Of("/usr/bin/env python3")},
+		{Mode: limayaml.ProvisionModeDependency, Script: "echo dependency\n", Interpreter: ptr.Of("/bin/bash")},
+		{Mode: limayaml.ProvisionModeSystem, Script: "#!/bin/sh\necho aarch64\n", When: ptr.Of("arch==aarch64")},
+	}
+	layout, err := getProvisionLayout(provision)
+	assert.NilError(t, err)
+	scripts := make(map[string]string)
+	for _, e := range layout {
+		b, err := io.ReadAll(e.Reader)
+		assert.NilError(t, err)
+		scripts[e.Path] = string(b)
+	}
+	when := scripts["provision.when/00000004"]
+	assert.Assert(t, strings.Contains(when, `{ [ "${ARCH}" = 'aarch64' ]; }`), when)
+	delete(scripts, "provision.when/00000004")
+	assert.DeepEqual(t, scripts, map[string]string{
+		"provision.system/00000000":     "#!/bin/bash\necho system\n",
+		"provision.user/00000002":       "#!/usr/bin/env python3\nprint('user')\n",
+		"provision.dependency/00000003": "#!/bin/bash\necho dependency\n",
+		"provision.system/00000004":     "#!/bin/sh\necho aarch64\n",
+	})
+
+	_, err = getProvisionLayout([]limayaml.Provision{{Mode: "unknown"}})
+	assert.ErrorContains(t, err, "unknown provision mode")
+	_, err = getProvisionLayout([]limayaml.Provision{{Mode: limayaml.ProvisionModeSystem, When: ptr.Of("arch = aarch64")}})
+	assert.ErrorContains(t, err, "field `provision[0].when` is invalid")
+}
+
+func TestGetSystemdUnitsLayout(t *testing.T) {
+	units := []limayaml.SystemdUnit{
+		{Name: "foo.service", Content: "[Service]\nExecStart=/usr/local/bin/foo\n", Enabled: ptr.Of(true)},
+		{Name: "bar.timer", Content: "[Timer]\nOnCalendar=daily\n", Enabled: ptr.Of(false)},
+	}
+	layout := getSystemdUnitsLayout(units)
+	contents := make(map[string]string)
+	for _, e := range layout {
+		b, err := io.ReadAll(e.Reader)
+		assert.NilError(t, err)
+		contents[e.Path] = string(b)
+	}
+	assert.DeepEqual(t, contents, map[string]string{
+		"systemd/foo.service": "[Service]\nExecStart=/usr/local/bin/foo\n",
+		"systemd/bar.timer":   "[Timer]\nOnCalendar=daily\n",
+	})
+}
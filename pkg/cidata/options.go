@@ -0,0 +1,51 @@
+package cidata
+
+import (
+	"fmt"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+)
+
+// DefaultVolumeLabel is the volume label of the NoCloud datasource.
+const DefaultVolumeLabel = "cidata"
+
+type options struct {
+	volumeLabel string
+	vfat        bool
+}
+
+type Opt func(*options) error
+
+// WithVolumeLabel specifies the volume label of the cidata image.
+// cloud-init only detects the NoCloud datasource with the label "cidata" (case-insensitive),
+// so the label should be changed only for the guests that look up another label.
+func WithVolumeLabel(label string) Opt {
+	return func(o *options) error {
+		o.volumeLabel = label
+		return nil
+	}
+}
+
+// WithVFAT specifies to write a VFAT image (filenames.CIDataVFAT) instead of the ISO9660 image
+// (filenames.CIDataISO), for the guests that can only read the NoCloud datasource from VFAT.
+func WithVFAT(vfat bool) Opt {
+	return func(o *options) error {
+		o.vfat = vfat
+		return nil
+	}
+}
+
+func newOptions(opts []Opt) (*options, error) {
+	o := &options{
+		volumeLabel: DefaultVolumeLabel,
+	}
+	for _, f := range opts {
+		if err := f(o); err != nil {
+			return nil, err
+		}
+	}
+	if err := limayaml.ValidateCIDataVolumeLabel(o.volumeLabel, o.vfat); err != nil {
+		return nil, fmt.Errorf("invalid volume label: %w", err)
+	}
+	return o, nil
+}
@@ -0,0 +1,25 @@
+package cidata
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestNewOptions(t *testing.T) {
+	o, err := newOptions(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, o.volumeLabel, DefaultVolumeLabel)
+	assert.Equal(t, o.vfat, false)
+
+	o, err = newOptions([]Opt{WithVolumeLabel("CIDATA"), WithVFAT(true)})
+	assert.NilError(t, err)
+	assert.Equal(t, o.volumeLabel, "CIDATA")
+	assert.Equal(t, o.vfat, true)
+
+	// the label is validated after all the options are applied
+	_, err = newOptions([]Opt{WithVolumeLabel("lima-cidata"), WithVFAT(true)})
+	assert.NilError(t, err)
+	_, err = newOptions([]Opt{WithVolumeLabel("lima-cidata-0"), WithVFAT(true)})
+	assert.ErrorContains(t, err, "must not be longer than 11 characters")
+}
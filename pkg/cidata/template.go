@@ -28,6 +28,19 @@ type Cert struct {
 	Lines []string
 }
 
+// SSHHostKey is the SSH host key of the instance, generated on the host (see sshutil.EnsureHostKey).
+type SSHHostKey struct {
+	PrivateLines []string
+	Public       string
+}
+
+type WriteFile struct {
+	Path        string
+	Content     string // base64
+	Owner       string
+	Permissions string
+}
+
 type Containerd struct {
 	System  bool
 	User    bool
@@ -37,6 +50,7 @@ type Network struct {
 	MACAddress string
 	Interface  string
 	Metric     uint32
+	MTU        uint32 // 0 means the default MTU of the guest OS
 }
 type Mount struct {
 	Tag        string
@@ -48,11 +62,12 @@ type BootCmds struct {
 	Lines []string
 }
 type Disk struct {
-	Name   string
-	Device string
-	Format bool
-	FSType string
-	FSArgs []string
+	Name     string
+	Device   string
+	Format   bool
+	FSType   string
+	FSArgs   []string
+	ReadOnly bool
 }
 type TemplateArgs struct {
 	Debug                           bool
@@ -64,7 +79,12 @@ type TemplateArgs struct {
 	Home                            string // home directory
 	Shell                           string // login shell
 	UID                             uint32
+	Groups                          []string // supplementary groups
+	Password                        string   // crypt(3) hash, or plain text when PlainTextPassword is true
+	PlainTextPassword               bool
+	UnlockPassword                  bool // the password is locked by default
 	SSHPubKeys                      []string
+	SSHHostKey                      *SSHHostKey // only set for the ISO9660, as cloud-config.yaml is not secret
 	Mounts                          []Mount
 	MountType                       string
 	Disks                           []Disk
@@ -72,6 +92,7 @@ type TemplateArgs struct {
 	UpgradePackages                 bool
 	Containerd                      Containerd
 	Networks                        []Network
+	PortForwardsExcludedInterfaces  []string // interfaces whose ports are not reported by the guest agent
 	SlirpNICName                    string
 	SlirpGateway                    string
 	SlirpDNS                        string
@@ -85,14 +106,17 @@ type TemplateArgs struct {
 	CACerts                         CACerts
 	HostHomeMountPoint              string
 	BootCmds                        []BootCmds
+	WriteFiles                      []WriteFile
 	RosettaEnabled                  bool
 	RosettaBinFmt                   bool
 	SkipDefaultDependencyResolution bool
+	SystemdUnitsEnabled             []string // names of the systemd units to be enabled
 	VMType                          string
 	VSockPort                       int
 	VirtioPort                      string
 	Plain                           bool
 	TimeZone                        string
+	Locale                          string
 }
 
 func ValidateTemplateArgs(args *TemplateArgs) error {
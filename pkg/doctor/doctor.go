@@ -0,0 +1,206 @@
+// Package doctor implements the checks of `limactl doctor`.
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/lima-vm/lima/pkg/freeport"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/osutil"
+	"github.com/lima-vm/lima/pkg/qemu"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/usrlocalsharelima"
+)
+
+type Status = string
+
+const (
+	StatusPass Status = "pass"
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+type Result struct {
+	Status  Status `json:"status"`
+	Message string `json:"message"`
+	// Hint is the remediation hint for StatusWarn and StatusFail.
+	Hint string `json:"hint,omitempty"`
+}
+
+type Check struct {
+	Name string
+	Run  func() Result
+}
+
+// Checks returns the checks of the host prerequisites.
+func Checks() []Check {
+	hostArch := limayaml.NewArch(runtime.GOARCH)
+	checks := []Check{
+		{Name: "ssh", Run: checkBinary("ssh", StatusFail, "Install OpenSSH client")},
+		{Name: "scp", Run: checkBinary("scp", StatusWarn, "Install OpenSSH client; scp is needed by `limactl copy`")},
+		{Name: "rsync", Run: checkBinary("rsync", StatusWarn, "Install rsync; rsync is needed by `limactl copy --exclude`")},
+		{Name: "OpenSSH version", Run: checkOpenSSHVersion},
+		{Name: "SSH keys", Run: checkSSHKeys(sshutil.ExistingPubKeys)},
+		{Name: "QEMU", Run: checkQEMU(hostArch)},
+		{Name: "qemu-img", Run: checkBinary("qemu-img", StatusWarn, "Install QEMU; qemu-img is needed for converting disk images")},
+		{Name: "guest agent", Run: checkGuestAgent(hostArch)},
+		{Name: "free port", Run: checkFreePort},
+		{Name: "proxy env", Run: checkProxyEnv(os.LookupEnv)},
+	}
+	if runtime.GOOS == "darwin" {
+		checks = append(checks, Check{Name: "DNS", Run: checkDNS})
+	}
+	return checks
+}
+
+func checkBinary(name string, failStatus Status, hint string) func() Result {
+	return func() Result {
+		p, err := exec.LookPath(name)
+		if err != nil {
+			return Result{Status: failStatus, Message: err.Error(), Hint: hint}
+		}
+		return Result{Status: StatusPass, Message: p}
+	}
+}
+
+func checkOpenSSHVersion() Result {
+	v, err := sshutil.DetectOpenSSHVersion("ssh")
+	if err != nil {
+		return Result{Status: StatusFail, Message: err.Error(), Hint: "Make sure that `ssh -V` prints the OpenSSH version"}
+	}
+	if v.LessThan(*semver.New("8.0.0")) {
+		return Result{
+			Status:  StatusWarn,
+			Message: fmt.Sprintf("OpenSSH %s is older than 8.0", v),
+			Hint:    "Upgrade OpenSSH to 8.0 or later to copy files among multiple instances with `limactl copy`",
+		}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("OpenSSH %s", v)}
+}
+
+// checkSSHKeys checks the existing keys, so that `limactl doctor` does not generate $LIMA_HOME/_config/user.
+func checkSSHKeys(existingPubKeys func(loadDotSSH bool) ([]sshutil.PubKey, error)) func() Result {
+	return func() Result {
+		pubKeys, err := existingPubKeys(true)
+		if errors.Is(err, os.ErrNotExist) {
+			return Result{
+				Status:  StatusWarn,
+				Message: err.Error(),
+				Hint:    "The key is generated on the first `limactl start`; make sure that ssh-keygen is installed and that $LIMA_HOME/_config is writable",
+			}
+		}
+		if err != nil {
+			return Result{
+				Status:  StatusFail,
+				Message: err.Error(),
+				Hint:    "Remove $LIMA_HOME/_config/user and $LIMA_HOME/_config/user.pub to regenerate the key",
+			}
+		}
+		if len(pubKeys) == 0 {
+			return Result{Status: StatusFail, Message: "no SSH public keys found", Hint: "Remove $LIMA_HOME/_config/user to regenerate the key"}
+		}
+		filenames := make([]string, len(pubKeys))
+		for i, pubKey := range pubKeys {
+			filenames[i] = pubKey.Filename
+		}
+		return Result{Status: StatusPass, Message: strings.Join(filenames, ", ")}
+	}
+}
+
+// proxyEnvVars are the proxy variables propagated to the guest (see pkg/cidata).
+var proxyEnvVars = []string{"ftp_proxy", "http_proxy", "https_proxy", "no_proxy"}
+
+func checkProxyEnv(lookupEnv func(string) (string, bool)) func() Result {
+	return func() Result {
+		var problems []string
+		for _, lowerName := range proxyEnvVars {
+			upperName := strings.ToUpper(lowerName)
+			lowerValue, lowerOk := lookupEnv(lowerName)
+			upperValue, upperOk := lookupEnv(upperName)
+			if lowerOk && upperOk && lowerValue != upperValue {
+				problems = append(problems, fmt.Sprintf("%s=%q and %s=%q differ; %s takes precedence", lowerName, lowerValue, upperName, upperValue, lowerName))
+			}
+			if lowerName == "no_proxy" {
+				continue
+			}
+			for _, name := range []string{lowerName, upperName} {
+				value, _ := lookupEnv(name)
+				if value == "" {
+					continue
+				}
+				if u, err := url.Parse(value); err != nil || u.Host == "" {
+					problems = append(problems, fmt.Sprintf("%s=%q is not a valid proxy URL", name, value))
+				}
+			}
+		}
+		if len(problems) > 0 {
+			return Result{
+				Status:  StatusWarn,
+				Message: strings.Join(problems, "; "),
+				Hint:    "Set the lowercase and uppercase proxy variables to the same URL, e.g., \"http://proxy.example.com:3128\"",
+			}
+		}
+		return Result{Status: StatusPass, Message: "consistent"}
+	}
+}
+
+func checkQEMU(arch limayaml.Arch) func() Result {
+	return func() Result {
+		exe, _, err := qemu.Exe(arch)
+		if err != nil {
+			status := StatusWarn
+			if runtime.GOOS == "linux" {
+				// QEMU is the only driver on Linux
+				status = StatusFail
+			}
+			return Result{Status: status, Message: err.Error(), Hint: "Install QEMU, or use another vmType"}
+		}
+		return Result{Status: StatusPass, Message: exe}
+	}
+}
+
+func checkGuestAgent(arch limayaml.Arch) func() Result {
+	return func() Result {
+		p, err := usrlocalsharelima.GuestAgentBinary(limayaml.LINUX, arch)
+		if err != nil {
+			return Result{Status: StatusFail, Message: err.Error(), Hint: "Reinstall Lima"}
+		}
+		// p is the path of the compressed binary (".gz" or ".xz"), when only the compressed one exists
+		if _, err := os.Stat(p); err == nil {
+			return Result{Status: StatusPass, Message: p}
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return Result{Status: StatusFail, Message: err.Error()}
+		}
+		return Result{
+			Status:  StatusFail,
+			Message: fmt.Sprintf("%q does not exist", p),
+			Hint:    "Reinstall Lima, or run `make install` with the guest agent for the host architecture",
+		}
+	}
+}
+
+func checkFreePort() Result {
+	port, err := freeport.TCP()
+	if err != nil {
+		return Result{Status: StatusFail, Message: err.Error(), Hint: "Make sure that 127.0.0.1 is available for listening"}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("127.0.0.1:%d is available", port)}
+}
+
+func checkDNS() Result {
+	addrs, err := osutil.DNSAddresses()
+	if err != nil {
+		return Result{Status: StatusWarn, Message: err.Error(), Hint: "Check the network settings of the host"}
+	}
+	if len(addrs) == 0 {
+		return Result{Status: StatusWarn, Message: "no DNS servers detected", Hint: "Check the network settings of the host; the guest may fail to resolve names"}
+	}
+	return Result{Status: StatusPass, Message: fmt.Sprintf("%v", addrs)}
+}
@@ -0,0 +1,93 @@
+package doctor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"gotest.tools/v3/assert"
+)
+
+func TestChecks(t *testing.T) {
+	names := make(map[string]bool)
+	for _, check := range Checks() {
+		assert.Assert(t, !names[check.Name], "duplicated check %q", check.Name)
+		names[check.Name] = true
+		assert.Assert(t, check.Run != nil, check.Name)
+	}
+}
+
+func TestCheckBinary(t *testing.T) {
+	cases := []struct {
+		name       string
+		failStatus Status
+		expected   Status
+		hint       string
+	}{
+		{name: "go", failStatus: StatusFail, expected: StatusPass},
+		{name: "lima-doctor-nonexistent", failStatus: StatusWarn, expected: StatusWarn, hint: "Install it"},
+		{name: "lima-doctor-nonexistent", failStatus: StatusFail, expected: StatusFail, hint: "Install it"},
+	}
+	for _, tc := range cases {
+		res := checkBinary(tc.name, tc.failStatus, "Install it")()
+		assert.Equal(t, res.Status, tc.expected, tc.name)
+		assert.Equal(t, res.Hint, tc.hint, tc.name)
+	}
+}
+
+func TestCheckFreePort(t *testing.T) {
+	res := checkFreePort()
+	assert.Equal(t, res.Status, StatusPass, res.Message)
+	assert.Assert(t, strings.HasPrefix(res.Message, "127.0.0.1:"), res.Message)
+}
+
+func TestCheckSSHKeys(t *testing.T) {
+	res := checkSSHKeys(func(bool) ([]sshutil.PubKey, error) {
+		return nil, fmt.Errorf("failed to read ssh public key %q: %w", "/lima/_config/user.pub", os.ErrNotExist)
+	})()
+	assert.Equal(t, res.Status, StatusWarn)
+
+	res = checkSSHKeys(func(bool) ([]sshutil.PubKey, error) {
+		return nil, errors.New("permission denied")
+	})()
+	assert.Equal(t, res.Status, StatusFail)
+	assert.Equal(t, res.Message, "permission denied")
+
+	res = checkSSHKeys(func(bool) ([]sshutil.PubKey, error) {
+		return []sshutil.PubKey{{Filename: "/lima/_config/user.pub"}, {Filename: "/home/foo/.ssh/id_ed25519.pub"}}, nil
+	})()
+	assert.Equal(t, res.Status, StatusPass)
+	assert.Equal(t, res.Message, "/lima/_config/user.pub, /home/foo/.ssh/id_ed25519.pub")
+}
+
+func TestCheckProxyEnv(t *testing.T) {
+	lookupEnv := func(env map[string]string) func(string) (string, bool) {
+		return func(name string) (string, bool) {
+			value, ok := env[name]
+			return value, ok
+		}
+	}
+
+	res := checkProxyEnv(lookupEnv(map[string]string{
+		"http_proxy": "http://proxy.example.com:3128",
+		"HTTP_PROXY": "http://proxy.example.com:3128",
+		"no_proxy":   "localhost",
+	}))()
+	assert.Equal(t, res.Status, StatusPass)
+
+	res = checkProxyEnv(lookupEnv(map[string]string{
+		"https_proxy": "http://proxy.example.com:3128",
+		"HTTPS_PROXY": "http://other.example.com:3128",
+	}))()
+	assert.Equal(t, res.Status, StatusWarn)
+	assert.Assert(t, strings.Contains(res.Message, "https_proxy takes precedence"), res.Message)
+
+	res = checkProxyEnv(lookupEnv(map[string]string{
+		"ftp_proxy": "proxy.example.com",
+	}))()
+	assert.Equal(t, res.Status, StatusWarn)
+	assert.Equal(t, res.Message, `ftp_proxy="proxy.example.com" is not a valid proxy URL`)
+}
@@ -12,6 +12,7 @@ import (
 	"os/exec"
 	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -49,13 +50,23 @@ type Result struct {
 	LastModified    time.Time
 	ContentType     string
 	ValidatedDigest bool
+	// Digest is the digest of the downloaded file: the expected digest when it is validated,
+	// otherwise the sha256 digest computed while downloading the file over HTTP.
+	// Empty when the digest is not known, e.g., for the local files without the expected digest.
+	Digest digest.Digest
 }
 
+// ErrOffline is returned when the remote resource is not cached in the offline mode.
+var ErrOffline = errors.New("offline mode: artifact not cached")
+
 type options struct {
 	cacheDir       string // default: empty (disables caching)
+	offline        bool   // default: $LIMA_OFFLINE
 	decompress     bool   // default: false (keep compression)
 	description    string // default: url
 	expectedDigest digest.Digest
+	timeout        time.Duration // default: $LIMA_DOWNLOAD_TIMEOUT, or no timeout
+	retries        int           // default: $LIMA_DOWNLOAD_RETRIES, or 0 (no retry)
 }
 
 func (o *options) apply(opts []Opt) error {
@@ -131,6 +142,78 @@ func WithExpectedDigest(expectedDigest digest.Digest) Opt {
 	}
 }
 
+// WithOffline disables network access, so that only the cache and the local files are used.
+// The default value is taken from $LIMA_OFFLINE.
+func WithOffline(offline bool) Opt {
+	return func(o *options) error {
+		o.offline = offline
+		return nil
+	}
+}
+
+// WithTimeout sets the total timeout of the download, including the retries.
+// The default value is taken from $LIMA_DOWNLOAD_TIMEOUT (e.g., "30m").
+func WithTimeout(timeout time.Duration) Opt {
+	return func(o *options) error {
+		if timeout <= 0 {
+			return fmt.Errorf("download timeout must be positive, got %v", timeout)
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetries sets the number of the retries when downloading the remote resource fails,
+// e.g., due to a transient network failure.
+// The default value is taken from $LIMA_DOWNLOAD_RETRIES.
+func WithRetries(retries int) Opt {
+	return func(o *options) error {
+		if retries < 0 {
+			return fmt.Errorf("download retries must not be negative, got %d", retries)
+		}
+		o.retries = retries
+		return nil
+	}
+}
+
+// optsFromEnv returns the options set by $LIMA_DOWNLOAD_TIMEOUT and $LIMA_DOWNLOAD_RETRIES.
+func optsFromEnv() ([]Opt, error) {
+	var opts []Opt
+	if envVar := os.Getenv("LIMA_DOWNLOAD_TIMEOUT"); envVar != "" {
+		timeout, err := time.ParseDuration(envVar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LIMA_DOWNLOAD_TIMEOUT value %q: %w", envVar, err)
+		}
+		opts = append(opts, WithTimeout(timeout))
+	}
+	if envVar := os.Getenv("LIMA_DOWNLOAD_RETRIES"); envVar != "" {
+		retries, err := strconv.Atoi(envVar)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LIMA_DOWNLOAD_RETRIES value %q: %w", envVar, err)
+		}
+		opts = append(opts, WithRetries(retries))
+	}
+	return opts, nil
+}
+
+// OfflineFromEnv returns whether $LIMA_OFFLINE is set to a true value.
+func OfflineFromEnv() bool {
+	envVar := os.Getenv("LIMA_OFFLINE")
+	if envVar == "" {
+		return false
+	}
+	b, err := strconv.ParseBool(envVar)
+	if err != nil {
+		logrus.WithError(err).Warnf("invalid LIMA_OFFLINE value %q", envVar)
+		return false
+	}
+	return b
+}
+
+func offlineError(remote string) error {
+	return fmt.Errorf("%w: %q", ErrOffline, remote)
+}
+
 func readFile(path string) string {
 	if path == "" {
 		return ""
@@ -172,11 +255,23 @@ func readTime(path string) time.Time {
 // (So, the local path cannot be set to /dev/null for "caching only" mode.)
 //
 // The local path can be an empty string for "caching only" mode.
+//
+// In the offline mode (see WithOffline), Download returns an error wrapping ErrOffline
+// when the remote resource is not cached.
 func Download(ctx context.Context, local, remote string, opts ...Opt) (*Result, error) {
-	var o options
-	if err := o.apply(opts); err != nil {
+	o := options{offline: OfflineFromEnv()}
+	envOpts, err := optsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if err := o.apply(append(envOpts, opts...)); err != nil {
 		return nil, err
 	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
 
 	var localPath string
 	if local == "" {
@@ -184,7 +279,6 @@ func Download(ctx context.Context, local, remote string, opts ...Opt) (*Result,
 			return nil, errors.New("caching-only mode requires the cache directory to be specified")
 		}
 	} else {
-		var err error
 		localPath, err = canonicalLocalPath(local)
 		if err != nil {
 			return nil, err
@@ -214,17 +308,23 @@ func Download(ctx context.Context, local, remote string, opts ...Opt) (*Result,
 		res := &Result{
 			Status:          StatusDownloaded,
 			ValidatedDigest: o.expectedDigest != "",
+			Digest:          o.expectedDigest,
 		}
 		return res, nil
 	}
 
 	if o.cacheDir == "" {
-		if err := downloadHTTP(ctx, localPath, "", "", remote, o.description, o.expectedDigest); err != nil {
+		if o.offline {
+			return nil, offlineError(remote)
+		}
+		actualDigest, err := downloadHTTPWithRetries(ctx, localPath, "", "", remote, o)
+		if err != nil {
 			return nil, err
 		}
 		res := &Result{
 			Status:          StatusDownloaded,
 			ValidatedDigest: o.expectedDigest != "",
+			Digest:          actualDigest,
 		}
 		return res, nil
 	}
@@ -234,20 +334,45 @@ func Download(ctx context.Context, local, remote string, opts ...Opt) (*Result,
 		return nil, err
 	}
 
-	var res *Result
-	err := lockutil.WithDirLock(shad, func() error {
-		var err error
-		res, err = getCached(ctx, localPath, remote, o)
-		if err != nil {
+	// Concurrent downloads of the same remote resource share the transfer to the cache,
+	// and then copy the cached file to their local paths.
+	key := fmt.Sprintf("%s\x00%s\x00%t", shad, o.expectedDigest, o.offline)
+	res, shared, err := shareTransfer(ctx, key, remote, func(ctx context.Context) (*Result, error) {
+		var res *Result
+		err := lockutil.WithDirLock(shad, func() error {
+			var err error
+			res, err = getCached(ctx, "", remote, o)
+			if err != nil {
+				return err
+			}
+			if res != nil {
+				return nil
+			}
+			if o.offline {
+				return offlineError(remote)
+			}
+			res, err = fetch(ctx, "", remote, o)
 			return err
-		}
-		if res != nil {
-			return nil
-		}
-		res, err = fetch(ctx, localPath, remote, o)
-		return err
+		})
+		return res, err
 	})
-	return res, err
+	if err != nil {
+		return nil, err
+	}
+	description := ""
+	if shared || res.Status == StatusUsedCache {
+		// The progress bar was not shown for the cached file
+		description = o.description
+	}
+	if err := copyLocal(ctx, localPath, res.CachePath, ext, o.decompress, description, ""); err != nil {
+		return nil, err
+	}
+	if shared {
+		sharedRes := *res
+		sharedRes.Status = StatusUsedCache
+		return &sharedRes, nil
+	}
+	return res, nil
 }
 
 // getCached tries to copy the file from the cache to local path. Return result,
@@ -276,6 +401,11 @@ func getCached(ctx context.Context, localPath, remote string, o options) (*Resul
 		if err := copyLocal(ctx, localPath, shadData, ext, o.decompress, "", ""); err != nil {
 			return nil, err
 		}
+	} else if o.offline {
+		logrus.Debugf("offline mode: using the cached digest-less file %q without checking the last-modified time", shadData)
+		if err := copyLocal(ctx, localPath, shadData, ext, o.decompress, o.description, o.expectedDigest); err != nil {
+			return nil, err
+		}
 	} else {
 		if match, lmCached, lmRemote, err := matchLastModified(ctx, shadTime, remote); err != nil {
 			logrus.WithError(err).Info("Failed to retrieve last-modified for cached digest-less image; using cached image.")
@@ -294,6 +424,7 @@ func getCached(ctx context.Context, localPath, remote string, o options) (*Resul
 		LastModified:    readTime(shadTime),
 		ContentType:     readFile(shadType),
 		ValidatedDigest: o.expectedDigest != "",
+		Digest:          cachedDigest(shad, o.expectedDigest),
 	}
 	return res, nil
 }
@@ -313,14 +444,23 @@ func fetch(ctx context.Context, localPath, remote string, o options) (*Result, e
 	if err := os.WriteFile(shadURL, []byte(remote), 0o644); err != nil {
 		return nil, err
 	}
-	if err := downloadHTTP(ctx, shadData, shadTime, shadType, remote, o.description, o.expectedDigest); err != nil {
+	// The digest files of the previous data must not remain, even if the download fails
+	if err := removeCacheDigests(shad); err != nil {
 		return nil, err
 	}
-	if shadDigest != "" && o.expectedDigest != "" {
-		if err := os.WriteFile(shadDigest, []byte(o.expectedDigest.String()), 0o644); err != nil {
+	actualDigest, err := downloadHTTPWithRetries(ctx, shadData, shadTime, shadType, remote, o)
+	if err != nil {
+		return nil, err
+	}
+	if shadDigest == "" {
+		shadDigest, err = cacheDigestPath(shad, actualDigest)
+		if err != nil {
 			return nil, err
 		}
 	}
+	if err := os.WriteFile(shadDigest, []byte(actualDigest.String()), 0o644); err != nil {
+		return nil, err
+	}
 	// no need to pass the digest to copyLocal(), as we already verified the digest
 	if err := copyLocal(ctx, localPath, shadData, ext, o.decompress, "", ""); err != nil {
 		return nil, err
@@ -331,6 +471,7 @@ func fetch(ctx context.Context, localPath, remote string, o options) (*Result, e
 		LastModified:    readTime(shadTime),
 		ContentType:     readFile(shadType),
 		ValidatedDigest: o.expectedDigest != "",
+		Digest:          actualDigest,
 	}
 	return res, nil
 }
@@ -394,6 +535,7 @@ func Cached(remote string, opts ...Opt) (*Result, error) {
 		LastModified:    readTime(shadTime),
 		ContentType:     readFile(shadType),
 		ValidatedDigest: o.expectedDigest != "",
+		Digest:          cachedDigest(shad, o.expectedDigest),
 	}
 	return res, nil
 }
@@ -407,6 +549,29 @@ func cacheDirectoryPath(cacheDir, remote string) string {
 	return filepath.Join(cacheDir, "download", "by-url-sha256", CacheKey(remote))
 }
 
+// removeCacheDigests removes the "<ALGO>.digest" files in the cache directory.
+func removeCacheDigests(shad string) error {
+	digests, err := filepath.Glob(filepath.Join(shad, "*.digest"))
+	if err != nil {
+		return err
+	}
+	for _, f := range digests {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cachedDigest returns the digest of the cached data: expectedDigest, which has been validated,
+// or the sha256 digest computed by fetch. Empty when the digest is not known.
+func cachedDigest(shad string, expectedDigest digest.Digest) digest.Digest {
+	if expectedDigest != "" {
+		return expectedDigest
+	}
+	return digest.Digest(readFile(filepath.Join(shad, digest.SHA256.String()+".digest")))
+}
+
 // cacheDigestPath returns the cache digest file path.
 //   - "<ALGO>.digest" contains the digest
 func cacheDigestPath(shad string, expectedDigest digest.Digest) (string, error) {
@@ -649,32 +814,54 @@ func matchLastModified(ctx context.Context, lastModifiedPath, url string) (match
 	return false, lmCached, lmRemote, nil
 }
 
-func downloadHTTP(ctx context.Context, localPath, lastModified, contentType, url, description string, expectedDigest digest.Digest) error {
+// retryInterval is the interval between the retries of downloadHTTPWithRetries.
+// Variable for testing.
+var retryInterval = 3 * time.Second
+
+// downloadHTTPWithRetries calls downloadHTTP, retrying up to o.retries times on failure.
+func downloadHTTPWithRetries(ctx context.Context, localPath, lastModified, contentType, url string, o options) (digest.Digest, error) {
+	for i := 0; ; i++ {
+		actualDigest, err := downloadHTTP(ctx, localPath, lastModified, contentType, url, o.description, o.expectedDigest)
+		if err == nil || i >= o.retries || ctx.Err() != nil {
+			return actualDigest, err
+		}
+		logrus.WithError(err).Warnf("Failed to download %q, retrying (%d/%d)", url, i+1, o.retries)
+		select {
+		case <-ctx.Done():
+			return "", fmt.Errorf("failed to download %q: %w (last error: %w)", url, ctx.Err(), err)
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// downloadHTTP downloads url to localPath, and returns the digest of the downloaded file.
+// The digest is computed with the algorithm of expectedDigest, or with sha256 when expectedDigest is empty.
+func downloadHTTP(ctx context.Context, localPath, lastModified, contentType, url, description string, expectedDigest digest.Digest) (digest.Digest, error) {
 	if localPath == "" {
-		return errors.New("downloadHTTP: got empty localPath")
+		return "", errors.New("downloadHTTP: got empty localPath")
 	}
 	logrus.Debugf("downloading %q into %q", url, localPath)
 
 	resp, err := httpclientutil.Get(ctx, http.DefaultClient, url)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if lastModified != "" {
 		lm := resp.Header.Get("Last-Modified")
 		if err := os.WriteFile(lastModified, []byte(lm), 0o644); err != nil {
-			return err
+			return "", err
 		}
 	}
 	if contentType != "" {
 		ct := resp.Header.Get("Content-Type")
 		if err := os.WriteFile(contentType, []byte(ct), 0o644); err != nil {
-			return err
+			return "", err
 		}
 	}
 	defer resp.Body.Close()
 	bar, err := progressbar.New(resp.ContentLength)
 	if err != nil {
-		return err
+		return "", err
 	}
 	if HideProgress {
 		hideBar(bar)
@@ -683,22 +870,21 @@ func downloadHTTP(ctx context.Context, localPath, lastModified, contentType, url
 	localPathTmp := perProcessTempfile(localPath)
 	fileWriter, err := os.Create(localPathTmp)
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer fileWriter.Close()
 	defer os.RemoveAll(localPathTmp)
 
 	writers := []io.Writer{fileWriter}
-	var digester digest.Digester
+	algo := digest.SHA256
 	if expectedDigest != "" {
-		algo := expectedDigest.Algorithm()
+		algo = expectedDigest.Algorithm()
 		if !algo.Available() {
-			return fmt.Errorf("unsupported digest algorithm %q", algo)
+			return "", fmt.Errorf("unsupported digest algorithm %q", algo)
 		}
-		digester = algo.Digester()
-		hasher := digester.Hash()
-		writers = append(writers, hasher)
 	}
+	digester := algo.Digester()
+	writers = append(writers, digester.Hash())
 	multiWriter := io.MultiWriter(writers...)
 
 	if !HideProgress {
@@ -710,25 +896,26 @@ func downloadHTTP(ctx context.Context, localPath, lastModified, contentType, url
 	}
 	bar.Start()
 	if _, err := io.Copy(multiWriter, bar.NewProxyReader(resp.Body)); err != nil {
-		return err
+		return "", err
 	}
 	bar.Finish()
 
-	if digester != nil {
-		actualDigest := digester.Digest()
-		if actualDigest != expectedDigest {
-			return fmt.Errorf("expected digest %q, got %q", expectedDigest, actualDigest)
-		}
+	actualDigest := digester.Digest()
+	if expectedDigest != "" && actualDigest != expectedDigest {
+		return "", fmt.Errorf("expected digest %q, got %q", expectedDigest, actualDigest)
 	}
 
 	if err := fileWriter.Sync(); err != nil {
-		return err
+		return "", err
 	}
 	if err := fileWriter.Close(); err != nil {
-		return err
+		return "", err
 	}
 
-	return os.Rename(localPathTmp, localPath)
+	if err := os.Rename(localPathTmp, localPath); err != nil {
+		return "", err
+	}
+	return actualDigest, nil
 }
 
 var tempfileCount atomic.Uint64
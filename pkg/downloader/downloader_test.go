@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -44,6 +45,7 @@ func TestDownloadRemote(t *testing.T) {
 			r, err := Download(context.Background(), localPath, dummyRemoteFileURL)
 			assert.NilError(t, err)
 			assert.Equal(t, StatusDownloaded, r.Status)
+			assert.Equal(t, digest.Digest(dummyRemoteFileDigest), r.Digest)
 
 			// download again, make sure StatusSkippedIsReturned
 			r, err = Download(context.Background(), localPath, dummyRemoteFileURL)
@@ -206,27 +208,39 @@ func TestRedownloadRemote(t *testing.T) {
 		assert.NilError(t, os.WriteFile(remoteFile, []byte("digest-less"), 0o644))
 		assert.NilError(t, os.Chtimes(remoteFile, time.Now(), time.Now().Add(-time.Hour)))
 		opt := []Opt{cacheOpt}
+		// The digest is computed while downloading, even if it is not expected
+		expectedDigest := digest.SHA256.FromString("digest-less")
 
 		// Download on the first call
 		r, err := Download(context.Background(), filepath.Join(downloadDir, "1"), ts.URL+"/digest-less.txt", opt...)
 		assert.NilError(t, err)
 		assert.Equal(t, StatusDownloaded, r.Status)
+		assert.Equal(t, expectedDigest, r.Digest)
 
 		// Next download will use the cached download
 		r, err = Download(context.Background(), filepath.Join(downloadDir, "2"), ts.URL+"/digest-less.txt", opt...)
 		assert.NilError(t, err)
 		assert.Equal(t, StatusUsedCache, r.Status)
+		assert.Equal(t, expectedDigest, r.Digest)
 
 		// Modifying remote file will cause redownload
+		assert.NilError(t, os.WriteFile(remoteFile, []byte("digest-less-modified"), 0o644))
 		assert.NilError(t, os.Chtimes(remoteFile, time.Now(), time.Now()))
+		expectedDigest = digest.SHA256.FromString("digest-less-modified")
 		r, err = Download(context.Background(), filepath.Join(downloadDir, "3"), ts.URL+"/digest-less.txt", opt...)
 		assert.NilError(t, err)
 		assert.Equal(t, StatusDownloaded, r.Status)
+		assert.Equal(t, expectedDigest, r.Digest)
 
 		// Next download will use the cached download
 		r, err = Download(context.Background(), filepath.Join(downloadDir, "4"), ts.URL+"/digest-less.txt", opt...)
 		assert.NilError(t, err)
 		assert.Equal(t, StatusUsedCache, r.Status)
+		assert.Equal(t, expectedDigest, r.Digest)
+
+		r, err = Cached(ts.URL+"/digest-less.txt", opt...)
+		assert.NilError(t, err)
+		assert.Equal(t, expectedDigest, r.Digest)
 	})
 
 	t.Run("has-digest", func(t *testing.T) {
@@ -255,6 +269,113 @@ func TestRedownloadRemote(t *testing.T) {
 	})
 }
 
+func TestDownloadOffline(t *testing.T) {
+	remoteDir := t.TempDir()
+	ts := httptest.NewServer(http.FileServer(http.Dir(remoteDir)))
+	t.Cleanup(ts.Close)
+	remoteURL := ts.URL + "/offline.txt"
+	assert.NilError(t, os.WriteFile(filepath.Join(remoteDir, "offline.txt"), []byte("offline"), 0o644))
+	cacheOpt := WithCacheDir(t.TempDir())
+
+	t.Run("not cached", func(t *testing.T) {
+		_, err := Download(context.Background(), filepath.Join(t.TempDir(), "1"), remoteURL, cacheOpt, WithOffline(true))
+		assert.ErrorIs(t, err, ErrOffline)
+		_, err = Download(context.Background(), filepath.Join(t.TempDir(), "1"), remoteURL, WithOffline(true))
+		assert.ErrorIs(t, err, ErrOffline)
+	})
+	t.Run("cached", func(t *testing.T) {
+		r, err := Download(context.Background(), filepath.Join(t.TempDir(), "1"), remoteURL, cacheOpt)
+		assert.NilError(t, err)
+		assert.Equal(t, StatusDownloaded, r.Status)
+
+		// The remote is not accessed, so the last-modified time is not checked either
+		ts.Close()
+		r, err = Download(context.Background(), filepath.Join(t.TempDir(), "2"), remoteURL, cacheOpt, WithOffline(true))
+		assert.NilError(t, err)
+		assert.Equal(t, StatusUsedCache, r.Status)
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("LIMA_OFFLINE", "1")
+		_, err := Download(context.Background(), filepath.Join(t.TempDir(), "1"), ts.URL+"/uncached.txt", cacheOpt)
+		assert.ErrorIs(t, err, ErrOffline)
+		assert.ErrorContains(t, err, "offline mode: artifact not cached")
+	})
+	t.Run("local", func(t *testing.T) {
+		r, err := Download(context.Background(), filepath.Join(t.TempDir(), "1"), filepath.Join(remoteDir, "offline.txt"), WithOffline(true))
+		assert.NilError(t, err)
+		assert.Equal(t, StatusDownloaded, r.Status)
+	})
+}
+
+func TestDownloadRetries(t *testing.T) {
+	defer func(d time.Duration) { retryInterval = d }(retryInterval)
+	retryInterval = time.Millisecond
+
+	// flakyServer fails the first `failures` requests, and then serves the content
+	flakyServer := func(t *testing.T, failures int) (string, *atomic.Int32) {
+		var requests atomic.Int32
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			if int(requests.Add(1)) <= failures {
+				http.Error(w, "transient failure", http.StatusServiceUnavailable)
+				return
+			}
+			_, _ = w.Write([]byte("flaky"))
+		}))
+		t.Cleanup(ts.Close)
+		return ts.URL + "/flaky.txt", &requests
+	}
+
+	t.Run("retry succeeds", func(t *testing.T) {
+		remoteURL, requests := flakyServer(t, 1)
+		localPath := filepath.Join(t.TempDir(), "flaky.txt")
+		r, err := Download(context.Background(), localPath, remoteURL, WithCacheDir(t.TempDir()), WithRetries(1))
+		assert.NilError(t, err)
+		assert.Equal(t, StatusDownloaded, r.Status)
+		assert.Equal(t, requests.Load(), int32(2))
+		b, err := os.ReadFile(localPath)
+		assert.NilError(t, err)
+		assert.Equal(t, string(b), "flaky")
+	})
+	t.Run("no retry", func(t *testing.T) {
+		remoteURL, requests := flakyServer(t, 1)
+		_, err := Download(context.Background(), filepath.Join(t.TempDir(), "flaky.txt"), remoteURL)
+		assert.ErrorContains(t, err, "Service Unavailable")
+		assert.Equal(t, requests.Load(), int32(1))
+	})
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("LIMA_DOWNLOAD_RETRIES", "2")
+		remoteURL, requests := flakyServer(t, 2)
+		r, err := Download(context.Background(), filepath.Join(t.TempDir(), "flaky.txt"), remoteURL)
+		assert.NilError(t, err)
+		assert.Equal(t, StatusDownloaded, r.Status)
+		assert.Equal(t, requests.Load(), int32(3))
+	})
+	t.Run("timeout", func(t *testing.T) {
+		retryInterval = time.Hour
+		t.Cleanup(func() { retryInterval = time.Millisecond })
+		remoteURL, _ := flakyServer(t, 1)
+		_, err := Download(context.Background(), filepath.Join(t.TempDir(), "flaky.txt"), remoteURL,
+			WithRetries(1), WithTimeout(100*time.Millisecond))
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+	t.Run("invalid", func(t *testing.T) {
+		remoteURL, requests := flakyServer(t, 0)
+		_, err := Download(context.Background(), filepath.Join(t.TempDir(), "1"), remoteURL, WithRetries(-1))
+		assert.ErrorContains(t, err, "must not be negative")
+		_, err = Download(context.Background(), filepath.Join(t.TempDir(), "2"), remoteURL, WithTimeout(0))
+		assert.ErrorContains(t, err, "must be positive")
+
+		t.Setenv("LIMA_DOWNLOAD_TIMEOUT", "-1s")
+		_, err = Download(context.Background(), filepath.Join(t.TempDir(), "3"), remoteURL)
+		assert.ErrorContains(t, err, "must be positive")
+		t.Setenv("LIMA_DOWNLOAD_TIMEOUT", "")
+		t.Setenv("LIMA_DOWNLOAD_RETRIES", "foo")
+		_, err = Download(context.Background(), filepath.Join(t.TempDir(), "4"), remoteURL)
+		assert.ErrorContains(t, err, "invalid LIMA_DOWNLOAD_RETRIES value")
+		assert.Equal(t, requests.Load(), int32(0))
+	})
+}
+
 func TestDownloadLocal(t *testing.T) {
 	const emptyFileDigest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
 	const testDownloadLocalDigest = "sha256:0c1e0fba69e8919b306d030bf491e3e0c46cf0a8140ff5d7516ba3a83cbea5b3"
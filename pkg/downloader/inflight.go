@@ -0,0 +1,100 @@
+package downloader
+
+import (
+	"context"
+	"slices"
+	"sync"
+)
+
+// transfer is a download of a remote resource to the cache, shared by the concurrent calls of Download.
+type transfer struct {
+	remote  string
+	done    chan struct{}
+	res     *Result
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+var transfers = struct {
+	sync.Mutex
+	m map[string]*transfer
+}{m: make(map[string]*transfer)}
+
+// shareTransfer calls f, unless a call of f with the same key is already in progress,
+// in which case it waits for the result of that call. shared is true for the waiters.
+//
+// The transfer is canceled when all the callers waiting for it are canceled, or by Cancel.
+func shareTransfer(ctx context.Context, key, remote string, f func(context.Context) (*Result, error)) (res *Result, shared bool, err error) {
+	transfers.Lock()
+	t, shared := transfers.m[key]
+	if !shared {
+		transferCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		t = &transfer{
+			remote: remote,
+			done:   make(chan struct{}),
+			cancel: cancel,
+		}
+		transfers.m[key] = t
+		go func() {
+			defer cancel()
+			t.res, t.err = f(transferCtx)
+			transfers.Lock()
+			if transfers.m[key] == t {
+				delete(transfers.m, key)
+			}
+			transfers.Unlock()
+			close(t.done)
+		}()
+	}
+	t.waiters++
+	transfers.Unlock()
+
+	select {
+	case <-t.done:
+		return t.res, shared, t.err
+	case <-ctx.Done():
+		transfers.Lock()
+		t.waiters--
+		if t.waiters == 0 {
+			t.cancel()
+			// Do not let new callers join the canceled transfer
+			if transfers.m[key] == t {
+				delete(transfers.m, key)
+			}
+		}
+		transfers.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
+
+// InFlight returns the remote resources that are being downloaded to the cache.
+func InFlight() []string {
+	transfers.Lock()
+	defer transfers.Unlock()
+	var remotes []string
+	for _, t := range transfers.m {
+		if !slices.Contains(remotes, t.remote) {
+			remotes = append(remotes, t.remote)
+		}
+	}
+	slices.Sort(remotes)
+	return remotes
+}
+
+// Cancel cancels the downloads of the remote resource to the cache.
+// The calls of Download waiting for them return an error wrapping context.Canceled.
+// Cancel returns false if the remote resource is not being downloaded.
+func Cancel(remote string) bool {
+	transfers.Lock()
+	defer transfers.Unlock()
+	var canceled bool
+	for key, t := range transfers.m {
+		if t.remote == remote {
+			t.cancel()
+			delete(transfers.m, key)
+			canceled = true
+		}
+	}
+	return canceled
+}
@@ -0,0 +1,131 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+	"gotest.tools/v3/poll"
+)
+
+// blockingServer serves "content" for every GET request after unblock is closed.
+func blockingServer(t *testing.T) (ts *httptest.Server, gets *atomic.Int32, unblock chan struct{}) {
+	gets = &atomic.Int32{}
+	unblock = make(chan struct{})
+	ts = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			return
+		}
+		gets.Add(1)
+		select {
+		case <-unblock:
+			_, _ = w.Write([]byte("content"))
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(ts.Close)
+	return ts, gets, unblock
+}
+
+func waiters(remote string) int {
+	transfers.Lock()
+	defer transfers.Unlock()
+	var n int
+	for _, t := range transfers.m {
+		if t.remote == remote {
+			n += t.waiters
+		}
+	}
+	return n
+}
+
+func waitForWaiters(t *testing.T, remote string, n int) {
+	poll.WaitOn(t, func(poll.LogT) poll.Result {
+		if waiters(remote) == n {
+			return poll.Success()
+		}
+		return poll.Continue("waiting for %d waiters", n)
+	}, poll.WithTimeout(10*time.Second))
+}
+
+func TestDownloadShared(t *testing.T) {
+	ts, gets, unblock := blockingServer(t)
+	remote := ts.URL + "/shared.txt"
+	cacheDir := filepath.Join(t.TempDir(), "cache")
+
+	results := make(chan downloadResult, 2)
+	for i := range 2 {
+		localPath := filepath.Join(t.TempDir(), "local")
+		go func() {
+			r, err := Download(context.Background(), localPath, remote, WithCacheDir(cacheDir))
+			if err == nil {
+				var b []byte
+				b, err = os.ReadFile(localPath)
+				if err == nil && string(b) != "content" {
+					err = errors.New("unexpected content")
+				}
+			}
+			results <- downloadResult{r, err}
+		}()
+		waitForWaiters(t, remote, i+1)
+	}
+	assert.DeepEqual(t, InFlight(), []string{remote})
+	close(unblock)
+
+	var statuses []Status
+	for range 2 {
+		result := <-results
+		assert.NilError(t, result.err)
+		statuses = append(statuses, result.r.Status)
+	}
+	assert.Assert(t, slices.Contains(statuses, StatusDownloaded))
+	assert.Assert(t, slices.Contains(statuses, StatusUsedCache))
+	assert.Equal(t, gets.Load(), int32(1))
+	assert.Equal(t, len(InFlight()), 0)
+}
+
+func TestDownloadCancel(t *testing.T) {
+	t.Run("context", func(t *testing.T) {
+		ts, _, _ := blockingServer(t)
+		remote := ts.URL + "/canceled.txt"
+		cacheDir := filepath.Join(t.TempDir(), "cache")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := Download(ctx, filepath.Join(t.TempDir(), "local"), remote, WithCacheDir(cacheDir))
+			errCh <- err
+		}()
+		waitForWaiters(t, remote, 1)
+		cancel()
+		assert.Assert(t, errors.Is(<-errCh, context.Canceled))
+		assert.Equal(t, len(InFlight()), 0)
+	})
+	t.Run("Cancel", func(t *testing.T) {
+		ts, _, _ := blockingServer(t)
+		remote := ts.URL + "/canceled.txt"
+		cacheDir := filepath.Join(t.TempDir(), "cache")
+
+		errCh := make(chan error, 2)
+		for i := range 2 {
+			go func() {
+				_, err := Download(context.Background(), filepath.Join(t.TempDir(), "local"), remote, WithCacheDir(cacheDir))
+				errCh <- err
+			}()
+			waitForWaiters(t, remote, i+1)
+		}
+		assert.Assert(t, Cancel(remote))
+		for range 2 {
+			assert.Assert(t, errors.Is(<-errCh, context.Canceled))
+		}
+		assert.Assert(t, !Cancel(remote))
+	})
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net"
 
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
 	"github.com/lima-vm/lima/pkg/store"
 )
 
@@ -40,8 +41,12 @@ type Driver interface {
 	RunGUI() error
 
 	// Stop will terminate the running vm instance.
+	// When force is false, Stop asks the guest to shut down gracefully, and forcibly
+	// terminates the vm if it does not shut down until the deadline of the context.
+	// When the context has no deadline, the default timeout of the driver is used.
+	// When force is true, Stop terminates the vm immediately.
 	// It returns error if there are any errors during Stop
-	Stop(_ context.Context) error
+	Stop(_ context.Context, force bool) error
 
 	// Register will add an instance to a registry.
 	// It returns error if there are any errors during Register
@@ -68,6 +73,26 @@ type Driver interface {
 
 	// GuestAgentConn returns the guest agent connection, or nil (if forwarded by ssh).
 	GuestAgentConn(_ context.Context) (net.Conn, error)
+
+	// RuntimeConfig applies the non-nil fields of config to the running vm, and returns the effective runtime config.
+	// An empty config just returns the current runtime config.
+	// It returns *hostagentapi.UnsupportedFieldsError for the fields that cannot be changed at runtime.
+	RuntimeConfig(_ context.Context, config hostagentapi.DriverConfig) (hostagentapi.DriverConfig, error)
+
+	// RunState returns the run state of the vm as reported by the hypervisor, e.g., "running", "paused", or "io-error".
+	// An empty string is returned when the driver cannot query the run state.
+	RunState(_ context.Context) (string, error)
+
+	// Pause pauses the running vm, preserving its memory state.
+	Pause(_ context.Context) error
+
+	// Resume resumes the vm paused by Pause.
+	Resume(_ context.Context) error
+
+	// SaveState saves the state of the running vm, including the memory, to the savedstate file in the instance directory.
+	// The vm is no longer running after saving the state, and the following Stop terminates the vm
+	// without shutting down the guest.
+	SaveState(_ context.Context) error
 }
 
 type BaseDriver struct {
@@ -76,6 +101,13 @@ type BaseDriver struct {
 	SSHLocalPort int
 	VSockPort    int
 	VirtioPort   string
+
+	// RestoreState is true when Start has to restore the vm from the savedstate file in the instance directory.
+	// The driver falls back to booting the vm when the state cannot be restored.
+	RestoreState bool
+	// PrepareColdBoot is called before falling back to booting the vm, to regenerate the cidata
+	// that is not regenerated for restoring the state.
+	PrepareColdBoot func(ctx context.Context) error
 }
 
 var _ Driver = (*BaseDriver)(nil)
@@ -104,7 +136,7 @@ func (d *BaseDriver) RunGUI() error {
 	return nil
 }
 
-func (d *BaseDriver) Stop(_ context.Context) error {
+func (d *BaseDriver) Stop(_ context.Context, _ bool) error {
 	return nil
 }
 
@@ -149,3 +181,33 @@ func (d *BaseDriver) GuestAgentConn(_ context.Context) (net.Conn, error) {
 	// use the unix socket forwarded by host agent
 	return nil, nil
 }
+
+func (d *BaseDriver) RuntimeConfig(_ context.Context, config hostagentapi.DriverConfig) (hostagentapi.DriverConfig, error) {
+	if fields := config.Fields(); len(fields) > 0 {
+		return hostagentapi.DriverConfig{}, &hostagentapi.UnsupportedFieldsError{Fields: fields}
+	}
+	var current hostagentapi.DriverConfig
+	if d.Instance != nil {
+		current.CPUs = &d.Instance.CPUs
+		if d.Instance.Config != nil {
+			current.Memory = d.Instance.Config.Memory
+		}
+	}
+	return current, nil
+}
+
+func (d *BaseDriver) RunState(_ context.Context) (string, error) {
+	return "", nil
+}
+
+func (d *BaseDriver) Pause(_ context.Context) error {
+	return errors.New("unimplemented")
+}
+
+func (d *BaseDriver) Resume(_ context.Context) error {
+	return errors.New("unimplemented")
+}
+
+func (d *BaseDriver) SaveState(_ context.Context) error {
+	return errors.New("unimplemented")
+}
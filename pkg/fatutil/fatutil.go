@@ -0,0 +1,107 @@
+// Package fatutil writes VFAT (FAT32) images, for the guests that can only read
+// the NoCloud datasource from a VFAT volume.
+package fatutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/diskfs/go-diskfs/backend/file"
+	"github.com/diskfs/go-diskfs/filesystem/fat32"
+	"github.com/lima-vm/lima/pkg/iso9660util"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MaxLabelLength is the maximum length of a FAT volume label.
+	MaxLabelLength = 11
+
+	mib = 1024 * 1024
+	// minSize is slightly larger than the minimum size of FAT32 with 512-byte clusters.
+	minSize = 64 * mib
+	// overhead is reserved for the file allocation tables and the directories.
+	overhead = 16 * mib
+)
+
+// Write writes the layout to a FAT32 image.
+// The image is sized to fit the layout, so the entries are buffered in a temporary directory
+// to determine their sizes before the image is created.
+func Write(imgPath, label string, layout []iso9660util.Entry) error {
+	if err := os.RemoveAll(imgPath); err != nil {
+		return err
+	}
+
+	workdir, err := os.MkdirTemp("", "diskfs_fat")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(workdir)
+	logrus.Debugf("Using %s as workspace", workdir)
+
+	bufs := make([]string, len(layout))
+	var total int64
+	for i, f := range layout {
+		bufs[i] = filepath.Join(workdir, filepath.FromSlash(f.Path))
+		n, err := bufferEntry(bufs[i], f.Reader)
+		if err != nil {
+			return err
+		}
+		total += n
+	}
+
+	imgFile, err := os.Create(imgPath)
+	if err != nil {
+		return err
+	}
+	defer imgFile.Close()
+	size := imageSize(total)
+	if err := imgFile.Truncate(size); err != nil {
+		return err
+	}
+
+	logrus.Debugf("Creating FAT image %s (%d bytes)", imgFile.Name(), size)
+	fs, err := fat32.Create(file.New(imgFile, false), size, 0, 0, label)
+	if err != nil {
+		return err
+	}
+	for i, f := range layout {
+		if err := writeEntry(fs, "/"+f.Path, bufs[i]); err != nil {
+			return err
+		}
+	}
+	return imgFile.Close()
+}
+
+// imageSize returns the size of the image for the files of the total size, rounded up to MiB.
+func imageSize(total int64) int64 {
+	size := total + total/10 + overhead
+	size = (size + mib - 1) / mib * mib
+	return max(size, minSize)
+}
+
+func bufferEntry(bufPath string, r io.Reader) (int64, error) {
+	if err := os.MkdirAll(filepath.Dir(bufPath), 0o700); err != nil {
+		return 0, err
+	}
+	buf, err := os.Create(bufPath)
+	if err != nil {
+		return 0, err
+	}
+	defer buf.Close()
+	n, err := io.Copy(buf, r)
+	if err != nil {
+		return 0, err
+	}
+	return n, buf.Close()
+}
+
+func writeEntry(fs *fat32.FileSystem, pathStr, bufPath string) error {
+	buf, err := os.Open(bufPath)
+	if err != nil {
+		return err
+	}
+	defer buf.Close()
+	_, err = iso9660util.WriteFile(fs, pathStr, buf)
+	return err
+}
@@ -0,0 +1,55 @@
+package fatutil
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/diskfs/go-diskfs/backend/file"
+	"github.com/diskfs/go-diskfs/filesystem/fat32"
+	"github.com/lima-vm/lima/pkg/iso9660util"
+	"gotest.tools/v3/assert"
+)
+
+func TestWrite(t *testing.T) {
+	imgPath := filepath.Join(t.TempDir(), "cidata.vfat")
+	layout := []iso9660util.Entry{
+		{Path: "meta-data", Reader: strings.NewReader("instance-id: foo\n")},
+		{Path: "user-data", Reader: strings.NewReader("#cloud-config\n")},
+		{Path: "provision.system/00000000", Reader: strings.NewReader("#!/bin/sh\n")},
+	}
+	assert.NilError(t, Write(imgPath, "CIDATA", layout))
+
+	st, err := os.Stat(imgPath)
+	assert.NilError(t, err)
+	assert.Equal(t, st.Size(), int64(minSize))
+
+	// The boot sector has the FAT32 signatures and the label
+	b, err := os.ReadFile(imgPath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, b[510:512], []byte{0x55, 0xAA})
+	assert.Equal(t, string(b[82:90]), "FAT32   ")
+	assert.Assert(t, strings.EqualFold(strings.TrimRight(string(b[71:82]), " "), "cidata"))
+
+	// The files can be read back
+	f, err := os.Open(imgPath)
+	assert.NilError(t, err)
+	defer f.Close()
+	fs, err := fat32.Read(file.New(f, true), st.Size(), 0, 512)
+	assert.NilError(t, err)
+	for _, path := range []string{"/user-data", "/provision.system/00000000"} {
+		r, err := fs.OpenFile(path, os.O_RDONLY)
+		assert.NilError(t, err, path)
+		content, err := io.ReadAll(r)
+		assert.NilError(t, err, path)
+		assert.Assert(t, len(content) > 0, path)
+	}
+}
+
+func TestImageSize(t *testing.T) {
+	assert.Equal(t, imageSize(0), int64(minSize))
+	assert.Equal(t, imageSize(200*mib), int64(236*mib))
+	assert.Equal(t, imageSize(200*mib+1)%mib, int64(0))
+}
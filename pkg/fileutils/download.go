@@ -8,7 +8,9 @@ import (
 
 	"github.com/lima-vm/lima/pkg/downloader"
 	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // ErrSkipped is returned when the downloader did not attempt to download the specified file.
@@ -16,19 +18,32 @@ var ErrSkipped = errors.New("skipped to download")
 
 // DownloadFile downloads a file to the cache, optionally copying it to the destination. Returns path in cache.
 func DownloadFile(ctx context.Context, dest string, f limayaml.File, decompress bool, description string, expectedArch limayaml.Arch) (string, error) {
+	res, err := Download(ctx, dest, f, decompress, description, expectedArch)
+	if err != nil {
+		return "", err
+	}
+	return res.CachePath, nil
+}
+
+// Download is like DownloadFile, but returns the result of the downloader, including the digest of the downloaded file.
+func Download(ctx context.Context, dest string, f limayaml.File, decompress bool, description string, expectedArch limayaml.Arch) (*downloader.Result, error) {
 	if f.Arch != expectedArch {
-		return "", fmt.Errorf("%w: %q: unsupported arch: %q", ErrSkipped, f.Location, f.Arch)
+		return nil, fmt.Errorf("%w: %q: unsupported arch: %q", ErrSkipped, f.Location, f.Arch)
 	}
 	fields := logrus.Fields{"location": f.Location, "arch": f.Arch, "digest": f.Digest}
 	logrus.WithFields(fields).Infof("Attempting to download %s", description)
+	ctx, span := tracing.Start(ctx, "fileutils.DownloadFile",
+		attribute.String("description", description),
+		attribute.String("location", f.Location))
 	res, err := downloader.Download(ctx, dest, f.Location,
 		downloader.WithCache(),
 		downloader.WithDecompress(decompress),
 		downloader.WithDescription(fmt.Sprintf("%s (%s)", description, path.Base(f.Location))),
 		downloader.WithExpectedDigest(f.Digest),
 	)
+	tracing.End(span, err)
 	if err != nil {
-		return "", fmt.Errorf("failed to download %q: %w", f.Location, err)
+		return nil, fmt.Errorf("failed to download %q: %w", f.Location, err)
 	}
 	logrus.Debugf("res.ValidatedDigest=%v", res.ValidatedDigest)
 	switch res.Status {
@@ -39,7 +54,7 @@ func DownloadFile(ctx context.Context, dest string, f limayaml.File, decompress
 	default:
 		logrus.Warnf("Unexpected result from downloader.Download(): %+v", res)
 	}
-	return res.CachePath, nil
+	return res, nil
 }
 
 // CachedFile checks if a file is in the cache, validating the digest if it is available. Returns path in cache.
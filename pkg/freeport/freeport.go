@@ -2,30 +2,96 @@
 package freeport
 
 import (
+	"errors"
 	"fmt"
 	"net"
+	"strconv"
+	"sync"
 )
 
+var (
+	// reservedTCP is the set of the TCP ports handed out by the process.
+	// The ports are never handed out twice, even after the reservation is released,
+	// as the consumer may not have bound the port yet.
+	reservedTCP   = map[int]struct{}{}
+	reservedTCPMu sync.Mutex
+)
+
+// maxReserveAttempts is the maximum number of ports to probe for a port not yet handed out.
+const maxReserveAttempts = 32
+
+// Reservation is a TCP port on 127.0.0.1 reserved for the process.
+// The port is held by an open listener until Release is called, so that no other
+// process can bind the port in the meantime.
+type Reservation struct {
+	Port     int
+	l        net.Listener
+	closeErr error
+	once     sync.Once
+}
+
+// ReserveTCP reserves a free TCP port on 127.0.0.1.
+// Release must be called right before the actual consumer binds the port.
+func ReserveTCP() (*Reservation, error) {
+	var rejected []net.Listener
+	defer func() {
+		// rejected listeners are held open until the end, so that the kernel does not return the same ports again
+		for _, l := range rejected {
+			_ = l.Close()
+		}
+	}()
+	for range maxReserveAttempts {
+		l, err := net.Listen("tcp4", "127.0.0.1:0")
+		if err != nil {
+			return nil, err
+		}
+		lTCPAddr, ok := l.Addr().(*net.TCPAddr)
+		if !ok {
+			_ = l.Close()
+			return nil, fmt.Errorf("expected *net.TCPAddr, got %v", l.Addr())
+		}
+		port := lTCPAddr.Port
+		if port <= 0 {
+			_ = l.Close()
+			return nil, fmt.Errorf("unexpected port %d", port)
+		}
+		reservedTCPMu.Lock()
+		_, alreadyReserved := reservedTCP[port]
+		if !alreadyReserved {
+			reservedTCP[port] = struct{}{}
+		}
+		reservedTCPMu.Unlock()
+		if alreadyReserved {
+			rejected = append(rejected, l)
+			continue
+		}
+		return &Reservation{Port: port, l: l}, nil
+	}
+	return nil, errors.New("failed to find a free port that has not been reserved yet")
+}
+
+// Release closes the listener that holds the port, so that the actual consumer can bind it.
+// The port is never handed out again by ReserveTCP or TCP in this process.
+// Release is idempotent.
+func (r *Reservation) Release() error {
+	r.once.Do(func() {
+		r.closeErr = r.l.Close()
+	})
+	return r.closeErr
+}
+
+// TCP returns a free TCP port on 127.0.0.1.
+// The port is not reserved; use ReserveTCP to prevent other processes from binding the port
+// before the actual consumer does.
 func TCP() (int, error) {
-	lAddr0, err := net.ResolveTCPAddr("tcp4", "127.0.0.1:0")
+	r, err := ReserveTCP()
 	if err != nil {
 		return 0, err
 	}
-	l, err := net.ListenTCP("tcp4", lAddr0)
-	if err != nil {
+	if err := r.Release(); err != nil {
 		return 0, err
 	}
-	defer l.Close()
-	lAddr := l.Addr()
-	lTCPAddr, ok := lAddr.(*net.TCPAddr)
-	if !ok {
-		return 0, fmt.Errorf("expected *net.TCPAddr, got %v", lAddr)
-	}
-	port := lTCPAddr.Port
-	if port <= 0 {
-		return 0, fmt.Errorf("unexpected port %d", port)
-	}
-	return port, nil
+	return r.Port, nil
 }
 
 func UDP() (int, error) {
@@ -49,3 +115,21 @@ func UDP() (int, error) {
 	}
 	return port, nil
 }
+
+// CheckTCP returns an error when the TCP port on 127.0.0.1 cannot be bound, e.g., because it is in use.
+func CheckTCP(port int) error {
+	l, err := net.Listen("tcp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
+
+// CheckUDP returns an error when the UDP port on 127.0.0.1 cannot be bound, e.g., because it is in use.
+func CheckUDP(port int) error {
+	l, err := net.ListenPacket("udp4", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)))
+	if err != nil {
+		return err
+	}
+	return l.Close()
+}
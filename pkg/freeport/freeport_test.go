@@ -0,0 +1,81 @@
+package freeport
+
+import (
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReserveTCPConcurrent(t *testing.T) {
+	const n = 100
+	var (
+		wg           sync.WaitGroup
+		reservations = make([]*Reservation, n)
+		errs         = make([]error, n)
+	)
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reservations[i], errs[i] = ReserveTCP()
+		}()
+	}
+	wg.Wait()
+
+	seen := make(map[int]struct{}, n)
+	for i := range n {
+		assert.NilError(t, errs[i])
+		_, dup := seen[reservations[i].Port]
+		assert.Assert(t, !dup, "port %d was handed out twice", reservations[i].Port)
+		seen[reservations[i].Port] = struct{}{}
+	}
+	for _, r := range reservations {
+		assert.NilError(t, r.Release())
+		// Release is idempotent
+		assert.NilError(t, r.Release())
+	}
+
+	// The released ports are not handed out again
+	for range n {
+		port, err := TCP()
+		assert.NilError(t, err)
+		_, dup := seen[port]
+		assert.Assert(t, !dup, "port %d was handed out twice", port)
+		seen[port] = struct{}{}
+	}
+}
+
+func TestReservationHoldsPort(t *testing.T) {
+	r, err := ReserveTCP()
+	assert.NilError(t, err)
+	addr := net.JoinHostPort("127.0.0.1", strconv.Itoa(r.Port))
+
+	_, err = net.Listen("tcp4", addr)
+	assert.Assert(t, err != nil, "the reserved port must not be bindable")
+
+	assert.NilError(t, r.Release())
+	l, err := net.Listen("tcp4", addr)
+	assert.NilError(t, err)
+	assert.NilError(t, l.Close())
+}
+
+func TestCheckTCP(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.NilError(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.Assert(t, CheckTCP(port) != nil)
+	assert.NilError(t, l.Close())
+	assert.NilError(t, CheckTCP(port))
+}
+
+func TestCheckUDP(t *testing.T) {
+	l, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	assert.NilError(t, err)
+	port := l.LocalAddr().(*net.UDPAddr).Port
+	assert.Assert(t, CheckUDP(port) != nil)
+	assert.NilError(t, l.Close())
+	assert.NilError(t, CheckUDP(port))
+}
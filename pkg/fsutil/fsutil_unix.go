@@ -0,0 +1,17 @@
+//go:build !windows
+
+package fsutil
+
+import (
+	"golang.org/x/sys/unix"
+)
+
+// AvailableSpace returns the number of bytes available to an unprivileged user
+// on the filesystem containing the path.
+func AvailableSpace(path string) (uint64, error) {
+	var sf unix.Statfs_t
+	if err := unix.Statfs(path, &sf); err != nil {
+		return 0, err
+	}
+	return uint64(sf.Bavail) * uint64(sf.Bsize), nil //nolint:unconvert // the types vary across the platforms
+}
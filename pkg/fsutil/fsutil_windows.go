@@ -0,0 +1,19 @@
+package fsutil
+
+import (
+	"golang.org/x/sys/windows"
+)
+
+// AvailableSpace returns the number of bytes available to the current user
+// on the filesystem containing the path.
+func AvailableSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var freeBytesAvailable uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &freeBytesAvailable, nil, nil); err != nil {
+		return 0, err
+	}
+	return freeBytesAvailable, nil
+}
@@ -0,0 +1,112 @@
+// Package address parses the address of the guest agent.
+//
+// The package is kept free of the gRPC dependencies, so that it can be imported from pkg/limayaml.
+package address
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Transport is the transport for connecting to the guest agent.
+type Transport = string
+
+const (
+	TransportUnix  Transport = "unix"
+	TransportVSock Transport = "vsock"
+	TransportTCP   Transport = "tcp"
+)
+
+// Address is the address of the guest agent.
+type Address struct {
+	Transport Transport
+	// Path is the socket path, for TransportUnix.
+	Path string
+	// CID is the context ID of the VM, for TransportVSock.
+	CID uint32
+	// Host is the host name or the IP address, for TransportTCP.
+	Host string
+	// Port is the port, for TransportVSock and TransportTCP.
+	Port uint32
+}
+
+// Parse parses the address of the guest agent. The following forms are supported:
+//
+//   - "unix:///path/to/ga.sock", or an absolute path
+//   - "vsock://CID:PORT", e.g., "vsock://3:2222"
+//   - "tcp://HOST:PORT", or "PORT" for "tcp://127.0.0.1:PORT"
+func Parse(s string) (*Address, error) {
+	if s == "" {
+		return nil, errors.New("the guest agent address must not be empty")
+	}
+	if filepath.IsAbs(s) {
+		return &Address{Transport: TransportUnix, Path: s}, nil
+	}
+	if !strings.Contains(s, "://") {
+		port, err := parsePort(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid guest agent address %q (must be a URL, an absolute path, or a port): %w", s, err)
+		}
+		return &Address{Transport: TransportTCP, Host: "127.0.0.1", Port: port}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid guest agent address %q: %w", s, err)
+	}
+	switch u.Scheme {
+	case TransportUnix:
+		if u.Host != "" || u.Path == "" {
+			return nil, fmt.Errorf("invalid guest agent address %q (must be unix:///path/to/socket)", s)
+		}
+		return &Address{Transport: TransportUnix, Path: u.Path}, nil
+	case TransportVSock:
+		cid, err := strconv.ParseUint(u.Hostname(), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CID in guest agent address %q: %w", s, err)
+		}
+		port, err := parsePort(u.Port())
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in guest agent address %q: %w", s, err)
+		}
+		return &Address{Transport: TransportVSock, CID: uint32(cid), Port: port}, nil
+	case TransportTCP:
+		if u.Hostname() == "" {
+			return nil, fmt.Errorf("invalid guest agent address %q (must be tcp://HOST:PORT)", s)
+		}
+		port, err := parsePort(u.Port())
+		if err != nil {
+			return nil, fmt.Errorf("invalid port in guest agent address %q: %w", s, err)
+		}
+		return &Address{Transport: TransportTCP, Host: u.Hostname(), Port: port}, nil
+	default:
+		return nil, fmt.Errorf("unsupported transport %q in guest agent address %q (must be %q, %q, or %q)",
+			u.Scheme, s, TransportUnix, TransportVSock, TransportTCP)
+	}
+}
+
+func parsePort(s string) (uint32, error) {
+	port, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	if port == 0 {
+		return 0, errors.New("port must not be zero")
+	}
+	return uint32(port), nil
+}
+
+func (a *Address) String() string {
+	switch a.Transport {
+	case TransportUnix:
+		return "unix://" + a.Path
+	case TransportVSock:
+		return fmt.Sprintf("vsock://%d:%d", a.CID, a.Port)
+	default:
+		return "tcp://" + net.JoinHostPort(a.Host, strconv.FormatUint(uint64(a.Port), 10))
+	}
+}
@@ -0,0 +1,44 @@
+package address
+
+import (
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	absPath := "/run/lima-guestagent.sock"
+	if runtime.GOOS == "windows" {
+		absPath = `C:\lima\ga.sock`
+	}
+	valid := map[string]Address{
+		absPath:                            {Transport: TransportUnix, Path: absPath},
+		"unix:///run/lima-guestagent.sock": {Transport: TransportUnix, Path: "/run/lima-guestagent.sock"},
+		"vsock://3:2222":                   {Transport: TransportVSock, CID: 3, Port: 2222},
+		"tcp://192.168.5.15:2222":          {Transport: TransportTCP, Host: "192.168.5.15", Port: 2222},
+		"tcp://[::1]:2222":                 {Transport: TransportTCP, Host: "::1", Port: 2222},
+		"2222":                             {Transport: TransportTCP, Host: "127.0.0.1", Port: 2222},
+	}
+	for s, expected := range valid {
+		a, err := Parse(s)
+		assert.NilError(t, err, s)
+		assert.DeepEqual(t, *a, expected)
+	}
+
+	invalid := []string{
+		"",
+		"ga.sock",
+		"0",
+		"unix://ga.sock",
+		"vsock://3",
+		"vsock://host:2222",
+		"tcp://:2222",
+		"tcp://127.0.0.1",
+		"http://127.0.0.1:2222",
+	}
+	for _, s := range invalid {
+		_, err := Parse(s)
+		assert.Assert(t, err != nil, s)
+	}
+}
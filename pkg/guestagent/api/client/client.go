@@ -4,10 +4,12 @@ import (
 	"context"
 	"math"
 	"net"
+	"time"
 
 	"github.com/lima-vm/lima/pkg/guestagent/api"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/resolver"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -65,6 +67,12 @@ func (c *GuestAgentClient) Inotify(ctx context.Context) (api.GuestService_PostIn
 	return inotify, nil
 }
 
+// SetPollInterval changes the interval of polling the events in the guest.
+func (c *GuestAgentClient) SetPollInterval(ctx context.Context, d time.Duration) error {
+	_, err := c.cli.SetPollInterval(ctx, &api.SetPollIntervalRequest{PollInterval: durationpb.New(d)})
+	return err
+}
+
 func (c *GuestAgentClient) Tunnel(ctx context.Context) (api.GuestService_TunnelClient, error) {
 	stream, err := c.cli.Tunnel(ctx)
 	if err != nil {
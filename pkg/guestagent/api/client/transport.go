@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/lima-vm/lima/pkg/guestagent/address"
+	"github.com/mdlayher/vsock"
+)
+
+// DialAddress connects to the address of the guest agent. The context is not honored while dialing vsock.
+func DialAddress(ctx context.Context, a *address.Address) (net.Conn, error) {
+	switch a.Transport {
+	case address.TransportUnix:
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", a.Path)
+	case address.TransportVSock:
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		// vsock is not supported on non-Linux hosts
+		return vsock.Dial(a.CID, a.Port, nil)
+	case address.TransportTCP:
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", net.JoinHostPort(a.Host, strconv.FormatUint(uint64(a.Port), 10)))
+	default:
+		return nil, fmt.Errorf("unsupported transport %q", a.Transport)
+	}
+}
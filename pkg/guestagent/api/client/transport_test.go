@@ -0,0 +1,37 @@
+package client
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/guestagent/address"
+	"gotest.tools/v3/assert"
+)
+
+func TestDialAddress(t *testing.T) {
+	t.Run("tcp", func(t *testing.T) {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NilError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+		a, err := address.Parse(strconv.Itoa(l.Addr().(*net.TCPAddr).Port))
+		assert.NilError(t, err)
+		conn, err := DialAddress(context.Background(), a)
+		assert.NilError(t, err)
+		assert.NilError(t, conn.Close())
+	})
+	t.Run("unix", func(t *testing.T) {
+		sockPath := filepath.Join(t.TempDir(), "ga.sock")
+		l, err := net.Listen("unix", sockPath)
+		assert.NilError(t, err)
+		t.Cleanup(func() { _ = l.Close() })
+		a, err := address.Parse(sockPath)
+		assert.NilError(t, err)
+		assert.Equal(t, a.String(), "unix://"+sockPath)
+		conn, err := DialAddress(context.Background(), a)
+		assert.NilError(t, err)
+		assert.NilError(t, conn.Close())
+	})
+}
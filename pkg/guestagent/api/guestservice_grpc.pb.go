@@ -26,6 +26,7 @@ type GuestServiceClient interface {
 	GetInfo(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Info, error)
 	GetEvents(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (GuestService_GetEventsClient, error)
 	PostInotify(ctx context.Context, opts ...grpc.CallOption) (GuestService_PostInotifyClient, error)
+	SetPollInterval(ctx context.Context, in *SetPollIntervalRequest, opts ...grpc.CallOption) (*emptypb.Empty, error)
 	Tunnel(ctx context.Context, opts ...grpc.CallOption) (GuestService_TunnelClient, error)
 }
 
@@ -112,6 +113,15 @@ func (x *guestServicePostInotifyClient) CloseAndRecv() (*emptypb.Empty, error) {
 	return m, nil
 }
 
+func (c *guestServiceClient) SetPollInterval(ctx context.Context, in *SetPollIntervalRequest, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, "/GuestService/SetPollInterval", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *guestServiceClient) Tunnel(ctx context.Context, opts ...grpc.CallOption) (GuestService_TunnelClient, error) {
 	stream, err := c.cc.NewStream(ctx, &GuestService_ServiceDesc.Streams[2], "/GuestService/Tunnel", opts...)
 	if err != nil {
@@ -150,6 +160,7 @@ type GuestServiceServer interface {
 	GetInfo(context.Context, *emptypb.Empty) (*Info, error)
 	GetEvents(*emptypb.Empty, GuestService_GetEventsServer) error
 	PostInotify(GuestService_PostInotifyServer) error
+	SetPollInterval(context.Context, *SetPollIntervalRequest) (*emptypb.Empty, error)
 	Tunnel(GuestService_TunnelServer) error
 	mustEmbedUnimplementedGuestServiceServer()
 }
@@ -167,6 +178,9 @@ func (UnimplementedGuestServiceServer) GetEvents(*emptypb.Empty, GuestService_Ge
 func (UnimplementedGuestServiceServer) PostInotify(GuestService_PostInotifyServer) error {
 	return status.Errorf(codes.Unimplemented, "method PostInotify not implemented")
 }
+func (UnimplementedGuestServiceServer) SetPollInterval(context.Context, *SetPollIntervalRequest) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetPollInterval not implemented")
+}
 func (UnimplementedGuestServiceServer) Tunnel(GuestService_TunnelServer) error {
 	return status.Errorf(codes.Unimplemented, "method Tunnel not implemented")
 }
@@ -248,6 +262,24 @@ func (x *guestServicePostInotifyServer) Recv() (*Inotify, error) {
 	return m, nil
 }
 
+func _GuestService_SetPollInterval_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetPollIntervalRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GuestServiceServer).SetPollInterval(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/GuestService/SetPollInterval",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GuestServiceServer).SetPollInterval(ctx, req.(*SetPollIntervalRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _GuestService_Tunnel_Handler(srv interface{}, stream grpc.ServerStream) error {
 	return srv.(GuestServiceServer).Tunnel(&guestServiceTunnelServer{stream})
 }
@@ -285,6 +317,10 @@ var GuestService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetInfo",
 			Handler:    _GuestService_GetInfo_Handler,
 		},
+		{
+			MethodName: "SetPollInterval",
+			Handler:    _GuestService_SetPollInterval_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -3,8 +3,84 @@ package api
 import (
 	"net"
 	"strconv"
+	"strings"
 )
 
+// HostString returns the IP address and the port in the "host:port" form (or "[host]:port" for IPv6).
+// The IP address is canonicalized, so that the equivalent addresses such as "0:0:0:0:0:0:0:1" and "::1",
+// or "::ffff:127.0.0.1" and "127.0.0.1", produce the same string.
 func (x *IPPort) HostString() string {
-	return net.JoinHostPort(x.Ip, strconv.Itoa(int(x.Port)))
+	return net.JoinHostPort(canonicalIP(x.Ip), strconv.Itoa(int(x.Port)))
+}
+
+// canonicalIP formats the IP address with net.IP.String.
+// The IPv6 zone (e.g., "%eth0") is preserved.
+// The string is returned as is when it is not an IP address.
+func canonicalIP(s string) string {
+	addr, zone, hasZone := strings.Cut(s, "%")
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return s
+	}
+	if hasZone {
+		return ip.String() + "%" + zone
+	}
+	return ip.String()
+}
+
+// ipPortKey identifies an IPPort by the protocol, the IP address, and the port.
+func ipPortKey(x *IPPort) string {
+	return x.Protocol + "/" + x.HostString()
+}
+
+// protocolPortKey identifies an IPPort by the protocol and the port, ignoring the IP address.
+func protocolPortKey(x *IPPort) string {
+	return x.Protocol + "/" + strconv.Itoa(int(x.Port))
+}
+
+// DiffIPPorts compares the old and the new list of the ports.
+//
+//   - added contains the entries of neww that are missing in old.
+//   - removed contains the entries of old that are missing in neww.
+//   - changed is the subset of added whose protocol and port were present in old
+//     with a different IP address, e.g., a port re-bound from 127.0.0.1 to 0.0.0.0.
+//
+// The entries are identified by the protocol, the IP address, and the port.
+// Duplicated entries are reported only once, and the order of the results follows
+// the order of the inputs.
+func DiffIPPorts(old, neww []*IPPort) (added, removed, changed []*IPPort) {
+	oldKeys := make(map[string]struct{}, len(old))
+	oldProtocolPorts := make(map[string]struct{}, len(old))
+	for _, x := range old {
+		oldKeys[ipPortKey(x)] = struct{}{}
+		oldProtocolPorts[protocolPortKey(x)] = struct{}{}
+	}
+	newKeys := make(map[string]struct{}, len(neww))
+	for _, x := range neww {
+		k := ipPortKey(x)
+		if _, ok := newKeys[k]; ok {
+			continue
+		}
+		newKeys[k] = struct{}{}
+		if _, ok := oldKeys[k]; ok {
+			continue
+		}
+		added = append(added, x)
+		if _, ok := oldProtocolPorts[protocolPortKey(x)]; ok {
+			changed = append(changed, x)
+		}
+	}
+	removedKeys := make(map[string]struct{})
+	for _, x := range old {
+		k := ipPortKey(x)
+		if _, ok := newKeys[k]; ok {
+			continue
+		}
+		if _, ok := removedKeys[k]; ok {
+			continue
+		}
+		removedKeys[k] = struct{}{}
+		removed = append(removed, x)
+	}
+	return added, removed, changed
 }
@@ -0,0 +1,153 @@
+package api
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestDiffIPPorts(t *testing.T) {
+	tcp := func(ip string, port int32) *IPPort {
+		return &IPPort{Protocol: "tcp", Ip: ip, Port: port}
+	}
+	udp := func(ip string, port int32) *IPPort {
+		return &IPPort{Protocol: "udp", Ip: ip, Port: port}
+	}
+	testCases := []struct {
+		name            string
+		old             []*IPPort
+		neww            []*IPPort
+		expectedAdded   []*IPPort
+		expectedRemoved []*IPPort
+		expectedChanged []*IPPort
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:          "added to empty",
+			neww:          []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+			expectedAdded: []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+		},
+		{
+			name:            "removed all",
+			old:             []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+			expectedRemoved: []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+		},
+		{
+			name: "unchanged",
+			old:  []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+			neww: []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+		},
+		{
+			name: "reordered",
+			old:  []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443), udp("0.0.0.0", 53)},
+			neww: []*IPPort{udp("0.0.0.0", 53), tcp("::", 443), tcp("127.0.0.1", 80)},
+		},
+		{
+			name:            "added and removed",
+			old:             []*IPPort{tcp("127.0.0.1", 80), tcp("0.0.0.0", 8080)},
+			neww:            []*IPPort{tcp("127.0.0.1", 80), tcp("0.0.0.0", 8443)},
+			expectedAdded:   []*IPPort{tcp("0.0.0.0", 8443)},
+			expectedRemoved: []*IPPort{tcp("0.0.0.0", 8080)},
+		},
+		{
+			name:            "protocols are distinguished",
+			old:             []*IPPort{tcp("0.0.0.0", 53)},
+			neww:            []*IPPort{udp("0.0.0.0", 53)},
+			expectedAdded:   []*IPPort{udp("0.0.0.0", 53)},
+			expectedRemoved: []*IPPort{tcp("0.0.0.0", 53)},
+		},
+		{
+			name:            "re-bound to another address",
+			old:             []*IPPort{tcp("127.0.0.1", 80)},
+			neww:            []*IPPort{tcp("0.0.0.0", 80)},
+			expectedAdded:   []*IPPort{tcp("0.0.0.0", 80)},
+			expectedRemoved: []*IPPort{tcp("127.0.0.1", 80)},
+			expectedChanged: []*IPPort{tcp("0.0.0.0", 80)},
+		},
+		{
+			name:            "bound to an additional address",
+			old:             []*IPPort{tcp("0.0.0.0", 80)},
+			neww:            []*IPPort{tcp("0.0.0.0", 80), tcp("::", 80)},
+			expectedAdded:   []*IPPort{tcp("::", 80)},
+			expectedChanged: []*IPPort{tcp("::", 80)},
+		},
+		{
+			name:          "duplicates in new",
+			neww:          []*IPPort{tcp("127.0.0.1", 80), tcp("127.0.0.1", 80), tcp("::", 443)},
+			expectedAdded: []*IPPort{tcp("127.0.0.1", 80), tcp("::", 443)},
+		},
+		{
+			name:            "duplicates in old",
+			old:             []*IPPort{tcp("127.0.0.1", 80), tcp("127.0.0.1", 80), tcp("::", 443)},
+			neww:            []*IPPort{tcp("::", 443)},
+			expectedRemoved: []*IPPort{tcp("127.0.0.1", 80)},
+		},
+		{
+			name: "duplicates in both",
+			old:  []*IPPort{tcp("127.0.0.1", 80), tcp("127.0.0.1", 80)},
+			neww: []*IPPort{tcp("127.0.0.1", 80), tcp("127.0.0.1", 80), tcp("127.0.0.1", 80)},
+		},
+		{
+			name:            "IPv6 addresses",
+			old:             []*IPPort{tcp("::1", 80)},
+			neww:            []*IPPort{tcp("::1", 80), tcp("fe80::1", 80), tcp("::1", 8080)},
+			expectedAdded:   []*IPPort{tcp("fe80::1", 80), tcp("::1", 8080)},
+			expectedChanged: []*IPPort{tcp("fe80::1", 80)},
+		},
+		{
+			name: "equivalent IPv6 addresses",
+			old:  []*IPPort{tcp("::1", 80), tcp("fe80::1", 80), tcp("::ffff:127.0.0.1", 8080), tcp("fe80::1%eth0", 443)},
+			neww: []*IPPort{tcp("0:0:0:0:0:0:0:1", 80), tcp("FE80:0000::0001", 80), tcp("127.0.0.1", 8080), tcp("fe80:0::1%eth0", 443)},
+		},
+		{
+			name:            "IPv6 zones are distinguished",
+			old:             []*IPPort{tcp("fe80::1%eth0", 443)},
+			neww:            []*IPPort{tcp("fe80::1%eth1", 443)},
+			expectedAdded:   []*IPPort{tcp("fe80::1%eth1", 443)},
+			expectedRemoved: []*IPPort{tcp("fe80::1%eth0", 443)},
+			expectedChanged: []*IPPort{tcp("fe80::1%eth1", 443)},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			added, removed, changed := DiffIPPorts(tc.old, tc.neww)
+			assert.DeepEqual(t, keys(added), keys(tc.expectedAdded))
+			assert.DeepEqual(t, keys(removed), keys(tc.expectedRemoved))
+			assert.DeepEqual(t, keys(changed), keys(tc.expectedChanged))
+		})
+	}
+}
+
+func TestHostString(t *testing.T) {
+	testCases := []struct {
+		ip       string
+		port     int32
+		expected string
+	}{
+		{ip: "127.0.0.1", port: 80, expected: "127.0.0.1:80"},
+		{ip: "0.0.0.0", port: 0, expected: "0.0.0.0:0"},
+		{ip: "::", port: 443, expected: "[::]:443"},
+		{ip: "0:0:0:0:0:0:0:0", port: 443, expected: "[::]:443"},
+		{ip: "0:0:0:0:0:0:0:1", port: 8080, expected: "[::1]:8080"},
+		{ip: "2001:DB8:0:0:0:0:0:1", port: 22, expected: "[2001:db8::1]:22"},
+		{ip: "::ffff:192.168.5.15", port: 22, expected: "192.168.5.15:22"},
+		{ip: "fe80:0:0:0:0:0:0:1%eth0", port: 53, expected: "[fe80::1%eth0]:53"},
+		{ip: "localhost", port: 80, expected: "localhost:80"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.ip, func(t *testing.T) {
+			x := &IPPort{Protocol: "tcp", Ip: tc.ip, Port: tc.port}
+			assert.Equal(t, x.HostString(), tc.expected)
+		})
+	}
+}
+
+func keys(x []*IPPort) []string {
+	var res []string
+	for _, f := range x {
+		res = append(res, ipPortKey(f))
+	}
+	return res
+}
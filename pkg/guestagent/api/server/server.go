@@ -8,6 +8,8 @@ import (
 	"github.com/lima-vm/lima/pkg/guestagent/api"
 	"github.com/lima-vm/lima/pkg/portfwdserver"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
 )
 
@@ -49,6 +51,17 @@ func (s *GuestServer) PostInotify(server api.GuestService_PostInotifyServer) err
 	}
 }
 
+// SetPollInterval changes the interval of polling the events, e.g., to poll less frequently when the host is idle.
+func (s *GuestServer) SetPollInterval(_ context.Context, req *api.SetPollIntervalRequest) (*emptypb.Empty, error) {
+	if req.GetPollInterval() == nil {
+		return nil, status.Error(codes.InvalidArgument, "poll_interval must be set")
+	}
+	if err := s.Agent.SetPollInterval(req.GetPollInterval().AsDuration()); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
 func (s *GuestServer) Tunnel(stream api.GuestService_TunnelServer) error {
 	return s.TunnelS.Start(stream)
 }
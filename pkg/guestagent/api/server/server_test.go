@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/guestagent"
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	"github.com/lima-vm/lima/pkg/guestagent/api/client"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gotest.tools/v3/assert"
+)
+
+type fakeAgent struct {
+	guestagent.Agent
+	pollInterval time.Duration
+}
+
+func (a *fakeAgent) SetPollInterval(d time.Duration) error {
+	if d <= 0 {
+		return errors.New("poll interval must be positive")
+	}
+	a.pollInterval = d
+	return nil
+}
+
+func TestSetPollInterval(t *testing.T) {
+	sock := filepath.Join(t.TempDir(), "ga.sock")
+	lis, err := net.Listen("unix", sock)
+	assert.NilError(t, err)
+	agent := &fakeAgent{pollInterval: 3 * time.Second}
+	go func() {
+		_ = StartServer(lis, &GuestServer{Agent: agent})
+	}()
+	t.Cleanup(func() { _ = lis.Close() })
+
+	cli, err := client.NewGuestAgentClient(func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "unix", sock)
+	})
+	assert.NilError(t, err)
+	ctx := context.Background()
+	assert.NilError(t, cli.SetPollInterval(ctx, 10*time.Second))
+	assert.Equal(t, agent.pollInterval, 10*time.Second)
+
+	err = cli.SetPollInterval(ctx, 0)
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+	assert.Equal(t, agent.pollInterval, 10*time.Second)
+
+	_, err = (&GuestServer{Agent: agent}).SetPollInterval(ctx, &api.SetPollIntervalRequest{})
+	assert.Equal(t, status.Code(err), codes.InvalidArgument)
+}
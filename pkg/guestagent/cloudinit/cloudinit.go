@@ -0,0 +1,46 @@
+// Package cloudinit reads the timing information recorded by cloud-init.
+package cloudinit
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BootFinishedFile is written by cloud-init when all the modules have been run.
+const BootFinishedFile = "/var/lib/cloud/instance/boot-finished"
+
+// ParseBootFinished parses the content of the boot-finished file, e.g.,
+// "23.51 - Mon, 02 Oct 2023 10:11:12 +0000 - v. 23.3.1-0ubuntu1~22.04.1",
+// and returns the uptime of the system when cloud-init finished.
+func ParseBootFinished(r io.Reader) (time.Duration, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	s := strings.TrimSpace(string(b))
+	uptime, _, _ := strings.Cut(s, " ")
+	sec, err := strconv.ParseFloat(uptime, 64)
+	if err != nil || sec < 0 {
+		return 0, fmt.Errorf("unparsable boot-finished content %q", s)
+	}
+	return time.Duration(sec * float64(time.Second)), nil
+}
+
+// BootFinished returns the uptime of the system when cloud-init finished.
+// The returned error wraps os.ErrNotExist while cloud-init is still running.
+func BootFinished() (time.Duration, error) {
+	f, err := os.Open(BootFinishedFile)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	d, err := ParseBootFinished(f)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %q: %w", BootFinishedFile, err)
+	}
+	return d, nil
+}
@@ -0,0 +1,21 @@
+package cloudinit
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseBootFinished(t *testing.T) {
+	d, err := ParseBootFinished(strings.NewReader("23.51 - Mon, 02 Oct 2023 10:11:12 +0000 - v. 23.3.1-0ubuntu1~22.04.1\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, d, 23*time.Second+510*time.Millisecond)
+
+	_, err = ParseBootFinished(strings.NewReader(""))
+	assert.ErrorContains(t, err, "unparsable boot-finished content")
+
+	_, err = ParseBootFinished(strings.NewReader("Mon, 02 Oct 2023 10:11:12 +0000"))
+	assert.ErrorContains(t, err, "unparsable boot-finished content")
+}
@@ -2,6 +2,7 @@ package guestagent
 
 import (
 	"context"
+	"time"
 
 	"github.com/lima-vm/lima/pkg/guestagent/api"
 )
@@ -10,5 +11,11 @@ type Agent interface {
 	Info(ctx context.Context) (*api.Info, error)
 	Events(ctx context.Context, ch chan *api.Event)
 	LocalPorts(ctx context.Context) ([]*api.IPPort, error)
+	LocalSockets(ctx context.Context) ([]*api.UnixSocket, error)
 	HandleInotify(event *api.Inotify)
+	// PollInterval returns the current interval of polling the events.
+	PollInterval() time.Duration
+	// SetPollInterval changes the interval of polling the events, e.g., to poll less frequently when idle.
+	// The new interval takes effect on the next cycle of the running Events calls.
+	SetPollInterval(d time.Duration) error
 }
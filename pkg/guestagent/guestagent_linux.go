@@ -3,74 +3,119 @@ package guestagent
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net"
 	"os"
 	"reflect"
+	"slices"
 	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/elastic/go-libaudit/v2"
 	"github.com/elastic/go-libaudit/v2/auparse"
 	"github.com/lima-vm/lima/pkg/guestagent/api"
+	"github.com/lima-vm/lima/pkg/guestagent/cloudinit"
 	"github.com/lima-vm/lima/pkg/guestagent/iptables"
 	"github.com/lima-vm/lima/pkg/guestagent/kubernetesservice"
+	"github.com/lima-vm/lima/pkg/guestagent/procmounts"
 	"github.com/lima-vm/lima/pkg/guestagent/procnettcp"
+	"github.com/lima-vm/lima/pkg/guestagent/procnetunix"
+	"github.com/lima-vm/lima/pkg/guestagent/procstats"
 	"github.com/lima-vm/lima/pkg/guestagent/timesync"
 	"github.com/sirupsen/logrus"
 	"golang.org/x/sys/cpu"
+	"google.golang.org/protobuf/types/known/durationpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
-func New(newTicker func() (<-chan time.Time, func()), iptablesIdle time.Duration) (Agent, error) {
+// New creates the agent. The events are polled with pollInterval, which can be changed later with SetPollInterval.
+func New(pollInterval time.Duration, iptablesIdle time.Duration, opts ...Opt) (Agent, error) {
+	o, err := newOptions(opts)
+	if err != nil {
+		return nil, err
+	}
+	poll, err := newPollInterval(pollInterval)
+	if err != nil {
+		return nil, err
+	}
 	a := &agent{
-		newTicker:                newTicker,
+		pollInterval:             poll,
+		opts:                     o,
 		kubernetesServiceWatcher: kubernetesservice.NewServiceWatcher(),
 	}
 
-	auditClient, err := libaudit.NewMulticastAuditClient(nil)
+	client, wasEnabled, err := setupAudit(func() (auditClient, error) {
+		return libaudit.NewMulticastAuditClient(nil)
+	})
 	if err != nil {
 		// syscall.EPROTONOSUPPORT or syscall.EAFNOSUPPORT is returned when calling attempting to connect to NETLINK_AUDIT
 		// on a kernel built without auditing support.
 		// https://github.com/elastic/go-libaudit/blob/ec298e53a6841a1f7715abbc7122635622f349bd/audit.go#L112-L115
-		if !errors.Is(err, syscall.EPROTONOSUPPORT) && !errors.Is(err, syscall.EAFNOSUPPORT) {
-			return nil, err
+		//
+		// syscall.EPERM is returned when using audit from a non-initial namespace
+		// https://github.com/torvalds/linux/blob/633b47cb009d09dc8f4ba9cdb3a0ca138809c7c7/kernel/audit.c#L1054-L1057
+		if errors.Is(err, syscall.EPROTONOSUPPORT) || errors.Is(err, syscall.EAFNOSUPPORT) || errors.Is(err, syscall.EPERM) {
+			logrus.Infof("Auditing is not available: %s", err)
+		} else {
+			logrus.WithError(err).Warn("Auditing is not available, falling back to checking iptables on every scan")
 		}
-		logrus.Infof("Auditing is not available: %s", err)
 		return startGuestAgentRoutines(a, false), nil
 	}
+	if wasEnabled {
+		a.worthCheckingIPTables = true
+	} else {
+		go a.setWorthCheckingIPTablesRoutine(client, iptablesIdle)
+	}
+	logrus.Info("Auditing enabled")
+	return startGuestAgentRoutines(a, true), nil
+}
+
+// auditClient is implemented by *libaudit.AuditClient.
+type auditClient interface {
+	auditReceiver
+	GetStatus() (*libaudit.AuditStatus, error)
+	SetEnabled(enabled bool, wm libaudit.WaitMode) error
+	Close() error
+}
 
-	auditStatus, err := auditClient.GetStatus()
+// setupAudit creates the audit client with newClient, and enables auditing unless it is enabled already.
+// wasEnabled is true when auditing was enabled already.
+//
+// An error is returned when auditing is not available, e.g., on a kernel without CONFIG_AUDIT,
+// in which case the guest agent should still work without auditing.
+func setupAudit(newClient func() (auditClient, error)) (_ auditClient, wasEnabled bool, retErr error) {
+	client, err := newClient()
 	if err != nil {
-		// syscall.EPERM is returned when using audit from a non-initial namespace
-		// https://github.com/torvalds/linux/blob/633b47cb009d09dc8f4ba9cdb3a0ca138809c7c7/kernel/audit.c#L1054-L1057
-		if !errors.Is(err, syscall.EPERM) {
-			return nil, err
+		return nil, false, fmt.Errorf("failed to create the audit client: %w", err)
+	}
+	defer func() {
+		if retErr != nil {
+			_ = client.Close()
 		}
-		logrus.Infof("Auditing is not permitted: %s", err)
-		return startGuestAgentRoutines(a, false), nil
+	}()
+	auditStatus, err := client.GetStatus()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get the audit status: %w", err)
+	}
+	if auditStatus.Enabled != 0 {
+		return client, true, nil
+	}
+	logrus.Info("Enabling auditing")
+	if err = client.SetEnabled(true, libaudit.WaitForReply); err != nil {
+		return nil, false, fmt.Errorf("failed to enable auditing: %w", err)
+	}
+	auditStatus, err = client.GetStatus()
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get the audit status: %w", err)
 	}
-
 	if auditStatus.Enabled == 0 {
-		logrus.Info("Enabling auditing")
-		if err = auditClient.SetEnabled(true, libaudit.WaitForReply); err != nil {
-			return nil, err
+		if err = client.SetEnabled(true, libaudit.WaitForReply); err != nil {
+			return nil, false, fmt.Errorf("failed to enable auditing: %w", err)
 		}
-		auditStatus, err := auditClient.GetStatus()
-		if err != nil {
-			return nil, err
-		}
-		if auditStatus.Enabled == 0 {
-			if err = auditClient.SetEnabled(true, libaudit.WaitForReply); err != nil {
-				return nil, err
-			}
-		}
-
-		go a.setWorthCheckingIPTablesRoutine(auditClient, iptablesIdle)
-	} else {
-		a.worthCheckingIPTables = true
 	}
-	logrus.Infof("Auditing enabled (%d)", auditStatus.Enabled)
-	return startGuestAgentRoutines(a, true), nil
+	return client, false, nil
 }
 
 // startGuestAgentRoutines sets worthCheckingIPTables to true if auditing is not supported,
@@ -89,15 +134,17 @@ func startGuestAgentRoutines(a *agent, supportsAuditing bool) *agent {
 }
 
 type agent struct {
-	// Ticker is like time.Ticker.
-	// We can't use inotify for /proc/net/tcp, so we need this ticker to
-	// reload /proc/net/tcp.
-	newTicker func() (<-chan time.Time, func())
+	// We can't use inotify for /proc/net/tcp, so we need to poll
+	// /proc/net/tcp with this interval.
+	pollInterval *pollInterval
+	opts         *options
 
 	worthCheckingIPTables    bool
 	worthCheckingIPTablesMu  sync.RWMutex
 	latestIPTables           []iptables.Entry
-	latestIPTablesMu         sync.RWMutex
+	latestIPTablesTime       time.Time // when latestIPTables was read
+	latestIPTablesMu         sync.Mutex
+	iptablesRefreshes        atomic.Uint64 // number of the times latestIPTables was read
 	kubernetesServiceWatcher *kubernetesservice.ServiceWatcher
 }
 
@@ -106,7 +153,7 @@ type agent struct {
 //
 // setWorthCheckingIPTablesRoutine sets worthCheckingIPTables to be false
 // when no NETFILTER_CFG audit message was received for the iptablesIdle time.
-func (a *agent) setWorthCheckingIPTablesRoutine(auditClient *libaudit.AuditClient, iptablesIdle time.Duration) {
+func (a *agent) setWorthCheckingIPTablesRoutine(auditClient auditReceiver, iptablesIdle time.Duration) {
 	logrus.Info("setWorthCheckingIPTablesRoutine(): monitoring netfilter audit events")
 	var latestTrue time.Time
 	go func() {
@@ -122,12 +169,7 @@ func (a *agent) setWorthCheckingIPTablesRoutine(auditClient *libaudit.AuditClien
 			a.worthCheckingIPTablesMu.Unlock()
 		}
 	}()
-	for {
-		msg, err := auditClient.Receive(false)
-		if err != nil {
-			logrus.Error(err)
-			continue
-		}
+	receiveAuditMessages(context.Background(), auditClient, time.Sleep, func(msg *libaudit.RawAuditMessage) {
 		if msg.Type == auparse.AUDIT_NETFILTER_CFG {
 			a.worthCheckingIPTablesMu.Lock()
 			logrus.Debug("setWorthCheckingIPTablesRoutine(): setting to true")
@@ -135,38 +177,50 @@ func (a *agent) setWorthCheckingIPTablesRoutine(auditClient *libaudit.AuditClien
 			latestTrue = time.Now()
 			a.worthCheckingIPTablesMu.Unlock()
 		}
-	}
+	})
 }
 
-type eventState struct {
-	ports []*api.IPPort
+// auditReceiver is implemented by *libaudit.AuditClient.
+type auditReceiver interface {
+	Receive(nonBlocking bool) (*libaudit.RawAuditMessage, error)
 }
 
-func comparePorts(old, neww []*api.IPPort) (added, removed []*api.IPPort) {
-	mRaw := make(map[string]*api.IPPort, len(old))
-	mStillExist := make(map[string]bool, len(old))
-
-	for _, f := range old {
-		k := f.String()
-		mRaw[k] = f
-		mStillExist[k] = false
-	}
-	for _, f := range neww {
-		k := f.String()
-		if _, ok := mRaw[k]; !ok {
-			added = append(added, f)
-		}
-		mStillExist[k] = true
-	}
+const (
+	auditReceiveBackoffMin = 100 * time.Millisecond
+	auditReceiveBackoffMax = 30 * time.Second
+)
 
-	for k, stillExist := range mStillExist {
-		if !stillExist {
-			if x, ok := mRaw[k]; ok {
-				removed = append(removed, x)
+// receiveAuditMessages calls onMessage for each audit message until ctx is done.
+//
+// Consecutive receive errors are retried with an exponential backoff, which is reset
+// on a successful receive. Only the first of the repeated identical errors is logged
+// as an error, so that a misbehaving audit subsystem neither pins a CPU nor floods the log.
+func receiveAuditMessages(ctx context.Context, client auditReceiver, sleep func(time.Duration), onMessage func(*libaudit.RawAuditMessage)) {
+	var (
+		backoff time.Duration
+		lastErr string
+	)
+	for ctx.Err() == nil {
+		msg, err := client.Receive(false)
+		if err != nil {
+			if err.Error() != lastErr {
+				logrus.Error(err)
+				lastErr = err.Error()
+			} else {
+				logrus.Debug(err)
 			}
+			backoff = min(max(2*backoff, auditReceiveBackoffMin), auditReceiveBackoffMax)
+			sleep(backoff)
+			continue
 		}
+		backoff, lastErr = 0, ""
+		onMessage(msg)
 	}
-	return
+}
+
+type eventState struct {
+	ports             []*api.IPPort
+	iptablesRefreshes uint64
 }
 
 func (a *agent) collectEvent(ctx context.Context, st eventState) (*api.Event, eventState) {
@@ -176,12 +230,23 @@ func (a *agent) collectEvent(ctx context.Context, st eventState) (*api.Event, ev
 	)
 	newSt := st
 	newSt.ports, err = a.LocalPorts(ctx)
+	newSt.iptablesRefreshes = a.iptablesRefreshes.Load()
+	ev.IptablesRefreshes = newSt.iptablesRefreshes - st.iptablesRefreshes
+	if errors.Is(err, ErrPartialScan) {
+		// Report the ports found so far, but do not treat the ports missing
+		// from the partial results as removed.
+		ev.Errors = append(ev.Errors, err.Error())
+		ev.LocalPortsAdded, _, _ = api.DiffIPPorts(st.ports, newSt.ports)
+		newSt.ports = append(st.ports, ev.LocalPortsAdded...)
+		ev.Time = timestamppb.Now()
+		return ev, newSt
+	}
 	if err != nil {
 		ev.Errors = append(ev.Errors, err.Error())
 		ev.Time = timestamppb.Now()
 		return ev, newSt
 	}
-	ev.LocalPortsAdded, ev.LocalPortsRemoved = comparePorts(st.ports, newSt.ports)
+	ev.LocalPortsAdded, ev.LocalPortsRemoved, _ = api.DiffIPPorts(st.ports, newSt.ports)
 	ev.Time = timestamppb.Now()
 	return ev, newSt
 }
@@ -195,9 +260,10 @@ func isEventEmpty(ev *api.Event) bool {
 
 func (a *agent) Events(ctx context.Context, ch chan *api.Event) {
 	defer close(ch)
-	tickerCh, tickerClose := a.newTicker()
+	tickerCh, tickerClose := a.pollInterval.newTicker()
 	defer tickerClose()
-	var st eventState
+	// The refreshes caused by the other streams before this one was opened are not reported.
+	st := eventState{iptablesRefreshes: a.iptablesRefreshes.Load()}
 	for {
 		var ev *api.Event
 		ev, st = a.collectEvent(ctx, st)
@@ -216,33 +282,106 @@ func (a *agent) Events(ctx context.Context, ch chan *api.Event) {
 	}
 }
 
-func (a *agent) LocalPorts(_ context.Context) ([]*api.IPPort, error) {
+// LocalPorts returns the local ports.
+// When the scan is not completed, LocalPorts returns the partial results with an error that wraps ErrPartialScan.
+func (a *agent) LocalPorts(ctx context.Context) ([]*api.IPPort, error) {
 	if cpu.IsBigEndian {
 		return nil, errors.New("big endian architecture is unsupported, because I don't know how /proc/net/tcp looks like on big endian hosts")
 	}
-	var res []*api.IPPort
-	tcpParsed, err := procnettcp.ParseFiles()
+	var sources []portSource
+	for _, f := range procnettcp.Files {
+		sources = append(sources, portSource{
+			name: f.Path,
+			scan: func(context.Context) ([]*api.IPPort, error) {
+				return procNetTCPPorts(f)
+			},
+		})
+	}
+	sources = append(sources,
+		portSource{
+			name:           "iptables",
+			skipKnownPorts: true,
+			scan: func(context.Context) ([]*api.IPPort, error) {
+				return a.iptablesPorts(iptables.GetPorts)
+			},
+		},
+		portSource{
+			name:           "kubernetes",
+			skipKnownPorts: true,
+			scan: func(context.Context) ([]*api.IPPort, error) {
+				return a.kubernetesPorts(), nil
+			},
+		},
+	)
+	ports, err := scanPorts(ctx, sources, a.opts)
+	if len(a.opts.excludeInterfaces) > 0 {
+		// The addresses are looked up on each scan, as they may be assigned by DHCP after the agent is started
+		excludedAddrs, addrsErr := exclusiveInterfaceAddrs(a.opts.excludeInterfaces)
+		if addrsErr != nil {
+			return nil, addrsErr
+		}
+		ports = excludePorts(ports, excludedAddrs)
+	}
+	return ports, err
+}
+
+// exclusiveInterfaceAddrs returns the addresses of the named interfaces,
+// except the addresses that are also assigned to the other interfaces.
+func exclusiveInterfaceAddrs(names []string) ([]net.IP, error) {
+	ifaces, err := net.Interfaces()
 	if err != nil {
-		return res, err
+		return nil, err
 	}
+	var excluded, others []net.IP
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the addresses of interface %q: %w", iface.Name, err)
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			if slices.Contains(names, iface.Name) {
+				excluded = append(excluded, ipNet.IP)
+			} else {
+				others = append(others, ipNet.IP)
+			}
+		}
+	}
+	var res []net.IP
+	for _, ip := range excluded {
+		if !slices.ContainsFunc(others, ip.Equal) {
+			res = append(res, ip)
+		}
+	}
+	return res, nil
+}
 
-	for _, f := range tcpParsed {
-		switch f.Kind {
+func procNetTCPPorts(f procnettcp.File) ([]*api.IPPort, error) {
+	parsed, err := procnettcp.ParseFile(f)
+	if err != nil {
+		return nil, err
+	}
+	var res []*api.IPPort
+	for _, e := range parsed {
+		switch e.Kind {
 		case procnettcp.TCP, procnettcp.TCP6:
-			if f.State == procnettcp.TCPListen {
+			if e.State == procnettcp.TCPListen {
 				res = append(res,
 					&api.IPPort{
-						Ip:       f.IP.String(),
-						Port:     int32(f.Port),
+						Ip:       e.IP.String(),
+						Port:     int32(e.Port),
 						Protocol: "tcp",
 					})
 			}
 		case procnettcp.UDP, procnettcp.UDP6:
-			if f.State == procnettcp.UDPEstablished {
+			if e.State == procnettcp.UDPEstablished {
 				res = append(res,
 					&api.IPPort{
-						Ip:       f.IP.String(),
-						Port:     int32(f.Port),
+						Ip:       e.IP.String(),
+						Port:     int32(e.Port),
 						Protocol: "udp",
 					})
 			}
@@ -250,79 +389,254 @@ func (a *agent) LocalPorts(_ context.Context) ([]*api.IPPort, error) {
 			continue
 		}
 	}
+	return res, nil
+}
 
+// iptablesPorts returns the TCP ports read by getPorts (or the cache).
+// worthCheckingIPTables is read only once, as it may be flipped by setWorthCheckingIPTablesRoutine concurrently.
+func (a *agent) iptablesPorts(getPorts func() ([]iptables.Entry, error)) ([]*api.IPPort, error) {
 	a.worthCheckingIPTablesMu.RLock()
 	worthCheckingIPTables := a.worthCheckingIPTables
 	a.worthCheckingIPTablesMu.RUnlock()
 	logrus.Debugf("LocalPorts(): worthCheckingIPTables=%v", worthCheckingIPTables)
 
-	var ipts []iptables.Entry
-	if a.worthCheckingIPTables {
-		ipts, err = iptables.GetPorts()
-		if err != nil {
-			return res, err
-		}
-		a.latestIPTablesMu.Lock()
-		a.latestIPTables = ipts
-		a.latestIPTablesMu.Unlock()
-	} else {
-		a.latestIPTablesMu.RLock()
-		ipts = a.latestIPTables
-		a.latestIPTablesMu.RUnlock()
+	ipts, err := a.cachedIPTables(worthCheckingIPTables, time.Now(), getPorts)
+	if err != nil {
+		return nil, err
 	}
 
+	var res []*api.IPPort
 	for _, ipt := range ipts {
-		// Make sure the port isn't already listed from procnettcp
-		found := false
-		for _, re := range res {
-			if re.Port == int32(ipt.Port) {
-				found = true
-			}
+		if ipt.TCP {
+			res = append(res,
+				&api.IPPort{
+					Ip:       ipt.IP.String(),
+					Port:     int32(ipt.Port), // The port value is already ensured to be within int32 bounds in iptables.go
+					Protocol: "tcp",
+				})
 		}
-		if !found {
-			if ipt.TCP {
-				res = append(res,
-					&api.IPPort{
-						Ip:       ipt.IP.String(),
-						Port:     int32(ipt.Port), // The port value is already ensured to be within int32 bounds in iptables.go
-						Protocol: "tcp",
-					})
-			}
+	}
+	return res, nil
+}
+
+// cachedIPTables returns the iptables rules read by getPorts.
+// The rules are read when worthCheckingIPTables is true (i.e., a netfilter audit event was received recently),
+// or when the cached rules are older than the TTL, so that the cache does not go stale when the audit events
+// are lost or unavailable. The lock is held while reading the rules, so the concurrent callers do not read them
+// again when the rules were just read by the other trigger.
+func (a *agent) cachedIPTables(worthCheckingIPTables bool, now time.Time, getPorts func() ([]iptables.Entry, error)) ([]iptables.Entry, error) {
+	a.latestIPTablesMu.Lock()
+	defer a.latestIPTablesMu.Unlock()
+	expired := a.latestIPTablesTime.IsZero() || (a.opts.iptablesTTL > 0 && now.Sub(a.latestIPTablesTime) >= a.opts.iptablesTTL)
+	if !worthCheckingIPTables && !expired {
+		return a.latestIPTables, nil
+	}
+	if !worthCheckingIPTables {
+		logrus.Debugf("LocalPorts(): iptables cache expired (read at %v)", a.latestIPTablesTime)
+	}
+	ipts, err := getPorts()
+	if err != nil {
+		return nil, err
+	}
+	a.latestIPTables = ipts
+	a.latestIPTablesTime = now
+	a.iptablesRefreshes.Add(1)
+	return ipts, nil
+}
+
+func (a *agent) kubernetesPorts() []*api.IPPort {
+	var res []*api.IPPort
+	for _, entry := range a.kubernetesServiceWatcher.GetPorts() {
+		res = append(res,
+			&api.IPPort{
+				Ip:       entry.IP.String(),
+				Port:     int32(entry.Port),
+				Protocol: string(entry.Protocol),
+			})
+	}
+	return res
+}
+
+// Info returns the information of the guest.
+// The sections are collected on the best-effort basis; the errors are reported in the Errors field
+// rather than failing the whole Info call.
+func (a *agent) Info(ctx context.Context) (*api.Info, error) {
+	var info api.Info
+	info.LocalPorts = bestEffort(&info.Errors, "local ports", func() ([]*api.IPPort, error) {
+		ports, err := a.LocalPorts(ctx)
+		if errors.Is(err, ErrPartialScan) {
+			// Report the ports found so far along with the error
+			info.Errors = append(info.Errors, err.Error())
+			return ports, nil
 		}
+		return ports, err
+	})
+	info.Mounts = bestEffort(&info.Errors, "mounts", mountStatuses)
+	info.LocalSockets = bestEffort(&info.Errors, "local sockets", func() ([]*api.UnixSocket, error) {
+		return a.LocalSockets(ctx)
+	})
+	if bt, err := bootTime(); err == nil {
+		info.BootTime = timestamppb.New(bt)
+	} else {
+		logrus.WithError(err).Warn("failed to get the boot time")
+	}
+	if d, err := cloudinit.BootFinished(); err == nil {
+		info.CloudInitDuration = durationpb.New(d)
+	} else if !errors.Is(err, os.ErrNotExist) {
+		logrus.WithError(err).Warn("failed to get the cloud-init duration")
+	}
+	info.Interfaces = bestEffort(&info.Errors, "network interfaces", networkInterfaces)
+	info.PollInterval = durationpb.New(a.PollInterval())
+	info.ResourceStats = resourceStats()
+	return &info, nil
+}
+
+// bestEffort returns the result of f.
+// When f fails, the error is appended to errs and the zero value is returned.
+func bestEffort[T any](errs *[]string, section string, f func() (T, error)) T {
+	res, err := f()
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to get the %s", section)
+		*errs = append(*errs, fmt.Sprintf("failed to get the %s: %v", section, err))
+		var zero T
+		return zero
 	}
+	return res
+}
 
-	kubernetesEntries := a.kubernetesServiceWatcher.GetPorts()
-	for _, entry := range kubernetesEntries {
-		found := false
-		for _, re := range res {
-			if re.Port == int32(entry.Port) {
-				found = true
+func (a *agent) PollInterval() time.Duration {
+	d, _ := a.pollInterval.get()
+	return d
+}
+
+func (a *agent) SetPollInterval(d time.Duration) error {
+	if err := a.pollInterval.set(d); err != nil {
+		return err
+	}
+	logrus.Infof("event poll interval: %v", d)
+	return nil
+}
+
+// networkInterfaces returns the non-loopback network interfaces with their addresses.
+func networkInterfaces() ([]*api.NetworkInterface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var res []*api.NetworkInterface
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the addresses of interface %q: %w", iface.Name, err)
+		}
+		ni := &api.NetworkInterface{
+			Name:       iface.Name,
+			MacAddress: iface.HardwareAddr.String(),
+		}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				ni.Addresses = append(ni.Addresses, ipNet.String())
 			}
 		}
+		res = append(res, ni)
+	}
+	return res, nil
+}
 
-		if !found {
-			res = append(res,
-				&api.IPPort{
-					Ip:       entry.IP.String(),
-					Port:     int32(entry.Port),
-					Protocol: string(entry.Protocol),
-				})
-		}
+// resourceStats returns the memory, the CPUs, and the load average of the guest.
+// The stats are read on the best-effort basis; the errors are reported in the Errors field
+// rather than failing the whole Info call.
+func resourceStats() *api.ResourceStats {
+	var res api.ResourceStats
+	if m, err := procstats.ParseMeminfoFile(); err == nil {
+		res.MemoryTotalBytes = m.TotalBytes
+		res.MemoryAvailableBytes = m.AvailableBytes
+	} else {
+		res.Errors = append(res.Errors, fmt.Sprintf("failed to read /proc/meminfo: %v", err))
+	}
+	if cpus, err := procstats.ParseStatFile(); err == nil {
+		res.Cpus = uint32(cpus)
+	} else {
+		res.Errors = append(res.Errors, fmt.Sprintf("failed to read /proc/stat: %v", err))
+	}
+	if l, err := procstats.ParseLoadavgFile(); err == nil {
+		res.Load1, res.Load5, res.Load15 = l.Load1, l.Load5, l.Load15
+	} else {
+		res.Errors = append(res.Errors, fmt.Sprintf("failed to read /proc/loadavg: %v", err))
 	}
+	return &res
+}
 
-	return res, nil
+// bootTime returns the time when the system was booted.
+func bootTime() (time.Time, error) {
+	var si syscall.Sysinfo_t
+	if err := syscall.Sysinfo(&si); err != nil {
+		return time.Time{}, err
+	}
+	return time.Now().Add(-time.Duration(si.Uptime) * time.Second).Truncate(time.Second), nil
 }
 
-func (a *agent) Info(ctx context.Context) (*api.Info, error) {
-	var (
-		info api.Info
-		err  error
-	)
-	info.LocalPorts, err = a.LocalPorts(ctx)
+// LocalSockets returns the listening unix sockets on the filesystem, with their file mode and owner.
+func (a *agent) LocalSockets(_ context.Context) ([]*api.UnixSocket, error) {
+	entries, err := procnetunix.ParseFile()
 	if err != nil {
 		return nil, err
 	}
-	return &info, nil
+	return unixSockets(entries), nil
+}
+
+// unixSockets stats the listening unix sockets on the filesystem.
+// The sockets that cannot be stat-ed, e.g., because they were removed after
+// /proc/net/unix was read, are skipped.
+func unixSockets(entries []procnetunix.Entry) []*api.UnixSocket {
+	var res []*api.UnixSocket
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !e.Listening || e.Path == "" || e.Abstract() || seen[e.Path] {
+			continue
+		}
+		seen[e.Path] = true
+		st, err := os.Stat(e.Path)
+		if err != nil {
+			logrus.WithError(err).Debugf("Skipping unix socket %q", e.Path)
+			continue
+		}
+		sock := &api.UnixSocket{
+			Path: e.Path,
+			Type: e.Type,
+			Mode: uint32(st.Mode().Perm()),
+		}
+		if sys, ok := st.Sys().(*syscall.Stat_t); ok {
+			sock.Uid = sys.Uid
+			sock.Gid = sys.Gid
+		}
+		res = append(res, sock)
+	}
+	return res
+}
+
+// mountStatuses returns the filesystems that may be mounted by Lima, with the actual mount type.
+func mountStatuses() ([]*api.MountStatus, error) {
+	entries, err := procmounts.ParseFile()
+	if err != nil {
+		return nil, err
+	}
+	var res []*api.MountStatus
+	for _, e := range entries {
+		mountType := e.LimaMountType()
+		if mountType == "" {
+			continue
+		}
+		res = append(res, &api.MountStatus{
+			Path:     e.MountPoint,
+			Type:     mountType,
+			ReadOnly: e.ReadOnly(),
+		})
+	}
+	return res, nil
 }
 
 const deltaLimit = 2 * time.Second
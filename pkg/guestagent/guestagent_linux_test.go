@@ -0,0 +1,290 @@
+package guestagent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/elastic/go-libaudit/v2"
+	"github.com/elastic/go-libaudit/v2/auparse"
+	"github.com/lima-vm/lima/pkg/guestagent/iptables"
+	"github.com/lima-vm/lima/pkg/guestagent/procnetunix"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gotest.tools/v3/assert"
+)
+
+func TestUnixSockets(t *testing.T) {
+	dir := t.TempDir()
+	sockPath := filepath.Join(dir, "test.sock")
+	l, err := net.Listen("unix", sockPath)
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+	assert.NilError(t, os.Chmod(sockPath, 0o660))
+
+	entries := []procnetunix.Entry{
+		{Type: procnetunix.Stream, Listening: true, Path: sockPath},
+		// duplicated entries are reported once
+		{Type: procnetunix.Stream, Listening: true, Path: sockPath},
+		// vanished between enumeration and stat
+		{Type: procnetunix.Stream, Listening: true, Path: filepath.Join(dir, "vanished.sock")},
+		{Type: procnetunix.Stream, Listening: true, Path: "@abstract"},
+		{Type: procnetunix.Stream},
+		{Type: procnetunix.Dgram, Path: sockPath},
+	}
+	socks := unixSockets(entries)
+	assert.Equal(t, len(socks), 1)
+	assert.Equal(t, socks[0].Path, sockPath)
+	assert.Equal(t, socks[0].Type, procnetunix.Stream)
+	assert.Equal(t, socks[0].Mode, uint32(0o660))
+	assert.Equal(t, socks[0].Uid, uint32(os.Getuid()))
+	assert.Equal(t, socks[0].Gid, uint32(os.Getgid()))
+}
+
+type fakeAuditReceiver struct {
+	results []func() (*libaudit.RawAuditMessage, error)
+	cancel  context.CancelFunc
+}
+
+func (r *fakeAuditReceiver) Receive(bool) (*libaudit.RawAuditMessage, error) {
+	if len(r.results) == 0 {
+		r.cancel()
+		return nil, errors.New("no more results")
+	}
+	f := r.results[0]
+	r.results = r.results[1:]
+	return f()
+}
+
+func TestReceiveAuditMessagesBackoff(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+	logrus.SetLevel(logrus.DebugLevel)
+	t.Cleanup(func() { logrus.SetLevel(logrus.InfoLevel) })
+
+	fail := func() (*libaudit.RawAuditMessage, error) {
+		return nil, errors.New("audit socket is broken")
+	}
+	succeed := func() (*libaudit.RawAuditMessage, error) {
+		return &libaudit.RawAuditMessage{Type: auparse.AUDIT_NETFILTER_CFG}, nil
+	}
+	var results []func() (*libaudit.RawAuditMessage, error)
+	for range 12 {
+		results = append(results, fail)
+	}
+	results = append(results, succeed, fail, fail)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	client := &fakeAuditReceiver{results: results, cancel: cancel}
+	var sleeps []time.Duration
+	var received int
+	receiveAuditMessages(ctx, client, func(d time.Duration) { sleeps = append(sleeps, d) }, func(msg *libaudit.RawAuditMessage) {
+		assert.Equal(t, msg.Type, auparse.AUDIT_NETFILTER_CFG)
+		received++
+	})
+	assert.Equal(t, received, 1)
+
+	var expected []time.Duration
+	for d := auditReceiveBackoffMin; len(expected) < 12; d = min(2*d, auditReceiveBackoffMax) {
+		expected = append(expected, d)
+	}
+	// capped
+	assert.Equal(t, expected[11], auditReceiveBackoffMax)
+	// reset after the successful receive, and the final error that cancels the context
+	expected = append(expected, auditReceiveBackoffMin, 2*auditReceiveBackoffMin, 4*auditReceiveBackoffMin)
+	assert.DeepEqual(t, sleeps, expected)
+
+	var errorEntries, debugEntries int
+	for _, e := range hook.AllEntries() {
+		switch e.Level {
+		case logrus.ErrorLevel:
+			errorEntries++
+		case logrus.DebugLevel:
+			debugEntries++
+		}
+	}
+	// "audit socket is broken" after the first and the successful receive, and "no more results"
+	assert.Equal(t, errorEntries, 3)
+	assert.Equal(t, debugEntries, 12)
+}
+
+func TestBestEffort(t *testing.T) {
+	var errs []string
+	ports := bestEffort(&errs, "ports", func() ([]int, error) {
+		return []int{22}, nil
+	})
+	assert.DeepEqual(t, ports, []int{22})
+	assert.Equal(t, len(errs), 0)
+
+	socks := bestEffort(&errs, "local sockets", func() ([]string, error) {
+		return []string{"partial"}, errors.New("permission denied")
+	})
+	assert.Assert(t, socks == nil)
+	ifaces := bestEffort(&errs, "network interfaces", func() ([]string, error) {
+		return nil, errors.New("netlink failed")
+	})
+	assert.Assert(t, ifaces == nil)
+	assert.DeepEqual(t, errs, []string{
+		"failed to get the local sockets: permission denied",
+		"failed to get the network interfaces: netlink failed",
+	})
+}
+
+func TestCachedIPTables(t *testing.T) {
+	const ttl = time.Minute
+	o, err := newOptions([]Opt{WithIPTablesTTL(ttl)})
+	assert.NilError(t, err)
+	a := &agent{opts: o}
+	var polls int
+	getPorts := func() ([]iptables.Entry, error) {
+		polls++
+		return []iptables.Entry{{TCP: true, IP: net.IPv4zero, Port: 8080 + polls}}, nil
+	}
+	start := time.Now()
+
+	// the first call always reads the rules
+	ipts, err := a.cachedIPTables(false, start, getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 1)
+	assert.Equal(t, ipts[0].Port, 8081)
+
+	// the cache is used until the TTL expires
+	ipts, err = a.cachedIPTables(false, start.Add(ttl/2), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 1)
+	assert.Equal(t, ipts[0].Port, 8081)
+
+	// the audit events trigger a read, which also resets the TTL
+	_, err = a.cachedIPTables(true, start.Add(ttl/2), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 2)
+	_, err = a.cachedIPTables(false, start.Add(ttl), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 2)
+
+	// the expired cache is read again without audit events
+	ipts, err = a.cachedIPTables(false, start.Add(ttl/2+ttl), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 3)
+	assert.Equal(t, ipts[0].Port, 8083)
+	// and the concurrent caller with an earlier time does not read it again
+	_, err = a.cachedIPTables(false, start.Add(ttl), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 3)
+
+	// errors are not cached
+	_, err = a.cachedIPTables(true, start.Add(3*ttl), func() ([]iptables.Entry, error) {
+		return nil, errors.New("iptables failed")
+	})
+	assert.ErrorContains(t, err, "iptables failed")
+	ipts, err = a.cachedIPTables(false, start.Add(3*ttl), getPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, polls, 4)
+	assert.Equal(t, ipts[0].Port, 8084)
+
+	// only the successful reads are counted as refreshes
+	assert.Equal(t, a.iptablesRefreshes.Load(), uint64(4))
+}
+
+// TestIPTablesPortsConcurrent is meaningful with `go test -race`.
+func TestIPTablesPortsConcurrent(t *testing.T) {
+	o, err := newOptions(nil)
+	assert.NilError(t, err)
+	a := &agent{opts: o}
+	getPorts := func() ([]iptables.Entry, error) {
+		return []iptables.Entry{{TCP: true, IP: net.IPv4zero, Port: 8080}}, nil
+	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		// like setWorthCheckingIPTablesRoutine
+		for i := range 1000 {
+			a.worthCheckingIPTablesMu.Lock()
+			a.worthCheckingIPTables = i%2 == 0
+			a.worthCheckingIPTablesMu.Unlock()
+		}
+	}()
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				ports, err := a.iptablesPorts(getPorts)
+				assert.Check(t, err)
+				assert.Check(t, len(ports) == 1 && ports[0].Port == 8080)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+type fakeAuditClient struct {
+	fakeAuditReceiver
+	statuses  []*libaudit.AuditStatus
+	statusErr error
+	enabled   int
+	closed    bool
+}
+
+func (c *fakeAuditClient) GetStatus() (*libaudit.AuditStatus, error) {
+	if c.statusErr != nil {
+		return nil, c.statusErr
+	}
+	st := c.statuses[0]
+	c.statuses = c.statuses[1:]
+	return st, nil
+}
+
+func (c *fakeAuditClient) SetEnabled(bool, libaudit.WaitMode) error {
+	c.enabled++
+	return nil
+}
+
+func (c *fakeAuditClient) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestSetupAudit(t *testing.T) {
+	t.Run("creation failure", func(t *testing.T) {
+		_, _, err := setupAudit(func() (auditClient, error) {
+			return nil, syscall.EPROTONOSUPPORT
+		})
+		assert.Assert(t, errors.Is(err, syscall.EPROTONOSUPPORT))
+	})
+	t.Run("status failure", func(t *testing.T) {
+		client := &fakeAuditClient{statusErr: syscall.EPERM}
+		_, _, err := setupAudit(func() (auditClient, error) {
+			return client, nil
+		})
+		assert.Assert(t, errors.Is(err, syscall.EPERM))
+		assert.Assert(t, client.closed)
+	})
+	t.Run("enabled already", func(t *testing.T) {
+		client := &fakeAuditClient{statuses: []*libaudit.AuditStatus{{Enabled: 1}}}
+		_, wasEnabled, err := setupAudit(func() (auditClient, error) {
+			return client, nil
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, wasEnabled)
+		assert.Equal(t, client.enabled, 0)
+	})
+	t.Run("enable", func(t *testing.T) {
+		client := &fakeAuditClient{statuses: []*libaudit.AuditStatus{{Enabled: 0}, {Enabled: 1}}}
+		_, wasEnabled, err := setupAudit(func() (auditClient, error) {
+			return client, nil
+		})
+		assert.NilError(t, err)
+		assert.Assert(t, !wasEnabled)
+		assert.Equal(t, client.enabled, 1)
+		assert.Assert(t, !client.closed)
+	})
+}
@@ -3,12 +3,16 @@ package iptables
 import (
 	"bytes"
 	"errors"
+	"fmt"
 	"net"
 	"os/exec"
 	"regexp"
 	"strconv"
 	"strings"
+	"sync/atomic"
 	"time"
+
+	"github.com/sirupsen/logrus"
 )
 
 type Entry struct {
@@ -36,30 +40,97 @@ var findPortRegex = regexp.MustCompile(`-A\s+CNI-DN-\w*\s+(?:-d ((?:\b25[0-5]|\b
 func GetPorts() ([]Entry, error) {
 	// TODO: add support for ipv6
 
-	// Detect the location of iptables. If it is not installed skip the lookup
-	// and return no results. The lookup is performed on each run so that the
-	// agent does not need to be started to detect if iptables was installed
-	// after the agent is already running.
-	pth, err := exec.LookPath("iptables")
+	// Both iptables and nft are queried when they are installed, as the rules
+	// added with nft are not visible to iptables, and the rules added with
+	// iptables-legacy are not visible to nft. The rules added with the
+	// iptables-nft shim may be visible to both, so the results are deduplicated.
+	pts, err := collectPorts(iptablesRulePorts, nftRulesetPorts)
 	if err != nil {
-		if errors.Is(err, exec.ErrNotFound) {
-			return nil, nil
-		}
+		return nil, err
+	}
+	return checkPortsOpen(pts)
+}
+
+// nftErrorLogged is set after the first failure of nft is logged as a warning,
+// so that the failure on every poll does not flood the log.
+var nftErrorLogged atomic.Bool
 
+// collectPorts returns the ports found by iptables and nft.
+// A failure of nft is logged, and does not discard the ports found by iptables.
+func collectPorts(listIPTables, listNFT func() ([]Entry, error)) ([]Entry, error) {
+	pts, err := listIPTables()
+	if err != nil {
 		return nil, err
 	}
+	nftPts, err := listNFT()
+	if err != nil {
+		if nftErrorLogged.CompareAndSwap(false, true) {
+			logrus.WithError(err).Warn("failed to list the ports with nft, using only the ports found by iptables")
+		} else {
+			logrus.WithError(err).Debug("failed to list the ports with nft")
+		}
+	} else {
+		nftErrorLogged.Store(false)
+	}
+	pts = append(pts, nftPts...)
+	return uniqueEntries(pts), nil
+}
 
+// iptablesRulePorts returns the ports found in the NAT rules of iptables.
+//
+// The location of iptables is detected on each run so that the agent does not need to
+// be restarted to detect if iptables was installed after the agent is already running.
+// If iptables is not installed, the lookup is skipped.
+func iptablesRulePorts() ([]Entry, error) {
+	pth, err := lookPath("iptables")
+	if err != nil || pth == "" {
+		return nil, err
+	}
 	res, err := listNATRules(pth)
 	if err != nil {
 		return nil, err
 	}
+	return parsePortsFromRules(res)
+}
 
-	pts, err := parsePortsFromRules(res)
+// nftRulesetPorts returns the ports found in the ruleset of nft.
+// If nft is not installed, the lookup is skipped.
+func nftRulesetPorts() ([]Entry, error) {
+	pth, err := lookPath("nft")
+	if err != nil || pth == "" {
+		return nil, err
+	}
+	res, err := listNFTRuleset(pth)
 	if err != nil {
 		return nil, err
 	}
+	return parsePortsFromNFTRuleset(res)
+}
 
-	return checkPortsOpen(pts)
+// lookPath returns an empty string when the command is not installed.
+func lookPath(cmd string) (string, error) {
+	pth, err := exec.LookPath(cmd)
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", nil
+		}
+		return "", err
+	}
+	return pth, nil
+}
+
+func uniqueEntries(pts []Entry) []Entry {
+	var entries []Entry
+	seen := make(map[string]bool)
+	for _, pt := range pts {
+		key := fmt.Sprintf("%t/%s/%d", pt.TCP, pt.IP, pt.Port)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		entries = append(entries, pt)
+	}
+	return entries
 }
 
 func parsePortsFromRules(rules []string) ([]Entry, error) {
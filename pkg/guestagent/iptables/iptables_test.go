@@ -1,6 +1,8 @@
 package iptables
 
 import (
+	"errors"
+	"net"
 	"strings"
 	"testing"
 
@@ -88,3 +90,31 @@ func TestParsePortsFromRules(t *testing.T) {
 		t.Errorf("expected port 8081 on IP 127.0.0.1 with TCP true but go port %d on IP %s with TCP %t", res[1].Port, res[1].IP.String(), res[1].TCP)
 	}
 }
+
+func TestCollectPorts(t *testing.T) {
+	iptablesPorts := func() ([]Entry, error) {
+		return []Entry{{TCP: true, IP: net.ParseIP("0.0.0.0"), Port: 8080}}, nil
+	}
+	nftPorts := func() ([]Entry, error) {
+		return []Entry{
+			{TCP: true, IP: net.ParseIP("0.0.0.0"), Port: 8080},
+			{TCP: true, IP: net.ParseIP("0.0.0.0"), Port: 8081},
+		}, nil
+	}
+	nftFailure := func() ([]Entry, error) {
+		return nil, errors.New("nft: permission denied")
+	}
+
+	pts, err := collectPorts(iptablesPorts, nftPorts)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pts), 2)
+
+	// A failure of nft does not discard the ports found by iptables
+	pts, err = collectPorts(iptablesPorts, nftFailure)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pts), 1)
+	assert.Equal(t, pts[0].Port, 8080)
+
+	_, err = collectPorts(nftFailure, nftPorts)
+	assert.ErrorContains(t, err, "permission denied")
+}
@@ -0,0 +1,175 @@
+package iptables
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os/exec"
+)
+
+// nftRuleset is the subset of the `nft -j list ruleset` output that is needed
+// to detect the port forwarding rules. Objects other than rules (metainfo,
+// tables, chains, sets, ...) are ignored.
+type nftRuleset struct {
+	Nftables []struct {
+		Rule *nftRule `json:"rule,omitempty"`
+	} `json:"nftables"`
+}
+
+type nftRule struct {
+	Family string            `json:"family"`
+	Table  string            `json:"table"`
+	Chain  string            `json:"chain"`
+	Expr   []json.RawMessage `json:"expr"`
+}
+
+type nftMatch struct {
+	Op    string          `json:"op"`
+	Left  json.RawMessage `json:"left"`
+	Right json.RawMessage `json:"right"`
+}
+
+type nftPayload struct {
+	Protocol string `json:"protocol"`
+	Field    string `json:"field"`
+}
+
+type nftMeta struct {
+	Key string `json:"key"`
+}
+
+// parsePortsFromNFTRuleset detects the ports forwarded by the `dnat` and the
+// `redirect` statements of an nftables ruleset in the JSON format. The
+// following two are examples of rules that are detected (as shown by
+// `nft list ruleset`):
+//
+//	ip daddr 127.0.0.1 tcp dport 8081 dnat to 10.4.0.7:80
+//	meta l4proto udp th dport 8082 redirect to :53
+//
+// Like parsePortsFromRules, only IPv4 is supported for now.
+func parsePortsFromNFTRuleset(b []byte) ([]Entry, error) {
+	var ruleset nftRuleset
+	if err := json.Unmarshal(b, &ruleset); err != nil {
+		return nil, fmt.Errorf("failed to parse the nftables ruleset: %w", err)
+	}
+	var entries []Entry
+	for _, o := range ruleset.Nftables {
+		if o.Rule == nil || (o.Rule.Family != "ip" && o.Rule.Family != "inet") {
+			continue
+		}
+		entries = append(entries, parsePortsFromNFTRule(o.Rule)...)
+	}
+	return entries, nil
+}
+
+func parsePortsFromNFTRule(rule *nftRule) []Entry {
+	var (
+		ip       net.IP
+		protocol string // "tcp" or "udp"
+		ports    []int
+		forward  bool
+	)
+	for _, raw := range rule.Expr {
+		var expr map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &expr); err != nil {
+			// e.g., an anonymous chain
+			continue
+		}
+		if _, ok := expr["dnat"]; ok {
+			forward = true
+		}
+		if _, ok := expr["redirect"]; ok {
+			forward = true
+		}
+		m, ok := expr["match"]
+		if !ok {
+			continue
+		}
+		var match nftMatch
+		if err := json.Unmarshal(m, &match); err != nil || (match.Op != "==" && match.Op != "in") {
+			continue
+		}
+		var left struct {
+			Payload *nftPayload `json:"payload,omitempty"`
+			Meta    *nftMeta    `json:"meta,omitempty"`
+		}
+		if err := json.Unmarshal(match.Left, &left); err != nil {
+			continue
+		}
+		switch {
+		case left.Meta != nil && left.Meta.Key == "l4proto":
+			var s string
+			if err := json.Unmarshal(match.Right, &s); err == nil {
+				protocol = s
+			}
+		case left.Payload != nil && left.Payload.Protocol == "ip" && left.Payload.Field == "daddr":
+			var s string
+			if err := json.Unmarshal(match.Right, &s); err == nil {
+				ip = net.ParseIP(s).To4()
+			}
+		case left.Payload != nil && left.Payload.Field == "dport":
+			if left.Payload.Protocol != "th" {
+				protocol = left.Payload.Protocol
+			}
+			ports = nftPorts(match.Right)
+		}
+	}
+	if !forward || (protocol != "tcp" && protocol != "udp") {
+		return nil
+	}
+	// When no IP is present the rule applies to all interfaces.
+	if ip == nil {
+		ip = net.IPv4zero
+	}
+	var entries []Entry
+	for _, port := range ports {
+		entries = append(entries, Entry{
+			IP:   ip,
+			Port: port,
+			TCP:  protocol == "tcp",
+		})
+	}
+	return entries
+}
+
+// nftPorts returns the ports of the right hand side of a match, which is
+// either a single port or an anonymous set of ports. Ranges are ignored.
+func nftPorts(right json.RawMessage) []int {
+	var port int
+	if err := json.Unmarshal(right, &port); err == nil {
+		return []int{port}
+	}
+	var set struct {
+		Set []json.RawMessage `json:"set"`
+	}
+	if err := json.Unmarshal(right, &set); err != nil {
+		return nil
+	}
+	var ports []int
+	for _, elem := range set.Set {
+		if err := json.Unmarshal(elem, &port); err == nil {
+			ports = append(ports, port)
+		}
+	}
+	return ports
+}
+
+// listNFTRuleset performs the lookup with nft and returns the ruleset in the
+// JSON format.
+func listNFTRuleset(pth string) ([]byte, error) {
+	args := []string{pth, "-j", "list", "ruleset"}
+
+	var stdout bytes.Buffer
+	var stderr bytes.Buffer
+	cmd := exec.Cmd{
+		Path:   pth,
+		Args:   args,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to run %v: %w (stderr=%q)", args, err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,100 @@
+package iptables
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+// nftData is the output of `nft -j list ruleset`, reformatted for readability.
+// The rules are equivalent to:
+//
+//	table ip nat {
+//		chain PREROUTING {
+//			type nat hook prerouting priority dstnat; policy accept;
+//			ip daddr 127.0.0.1 tcp dport 8081 dnat to 10.4.0.7:80
+//			tcp dport { 8082, 8083 } dnat to 10.4.0.10:80
+//			meta l4proto udp th dport 5353 redirect to :53
+//			tcp dport 9000-9010 dnat to 10.4.0.11:80
+//			tcp dport 8084 counter accept
+//		}
+//	}
+//	table ip6 nat {
+//		chain PREROUTING {
+//			type nat hook prerouting priority dstnat; policy accept;
+//			tcp dport 8085 dnat to [fd00::7]:80
+//		}
+//	}
+const nftData = `{"nftables": [
+{"metainfo": {"version": "1.0.2", "release_name": "Lester Gooch", "json_schema_version": 1}},
+{"table": {"family": "ip", "name": "nat", "handle": 1}},
+{"chain": {"family": "ip", "table": "nat", "name": "PREROUTING", "handle": 1, "type": "nat", "hook": "prerouting", "prio": -100, "policy": "accept"}},
+{"rule": {"family": "ip", "table": "nat", "chain": "PREROUTING", "handle": 2, "expr": [
+  {"match": {"op": "==", "left": {"payload": {"protocol": "ip", "field": "daddr"}}, "right": "127.0.0.1"}},
+  {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 8081}},
+  {"dnat": {"addr": "10.4.0.7", "port": 80}}
+]}},
+{"rule": {"family": "ip", "table": "nat", "chain": "PREROUTING", "handle": 3, "expr": [
+  {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": {"set": [8082, 8083]}}},
+  {"dnat": {"addr": "10.4.0.10", "port": 80}}
+]}},
+{"rule": {"family": "ip", "table": "nat", "chain": "PREROUTING", "handle": 4, "expr": [
+  {"match": {"op": "==", "left": {"meta": {"key": "l4proto"}}, "right": "udp"}},
+  {"match": {"op": "==", "left": {"payload": {"protocol": "th", "field": "dport"}}, "right": 5353}},
+  {"redirect": {"port": 53}}
+]}},
+{"rule": {"family": "ip", "table": "nat", "chain": "PREROUTING", "handle": 5, "expr": [
+  {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": {"range": [9000, 9010]}}},
+  {"dnat": {"addr": "10.4.0.11", "port": 80}}
+]}},
+{"rule": {"family": "ip", "table": "nat", "chain": "PREROUTING", "handle": 6, "expr": [
+  {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 8084}},
+  {"counter": {"packets": 0, "bytes": 0}},
+  {"accept": null}
+]}},
+{"table": {"family": "ip6", "name": "nat", "handle": 2}},
+{"chain": {"family": "ip6", "table": "nat", "name": "PREROUTING", "handle": 1, "type": "nat", "hook": "prerouting", "prio": -100, "policy": "accept"}},
+{"rule": {"family": "ip6", "table": "nat", "chain": "PREROUTING", "handle": 2, "expr": [
+  {"match": {"op": "==", "left": {"payload": {"protocol": "tcp", "field": "dport"}}, "right": 8085}},
+  {"dnat": {"addr": "fd00::7", "port": 80}}
+]}}
+]}`
+
+func TestParsePortsFromNFTRuleset(t *testing.T) {
+	res, err := parsePortsFromNFTRuleset([]byte(nftData))
+	assert.NilError(t, err, "parsing nftables ports failed")
+
+	type entry struct {
+		TCP  bool
+		IP   string
+		Port int
+	}
+	var got []entry
+	for _, e := range res {
+		got = append(got, entry{TCP: e.TCP, IP: e.IP.String(), Port: e.Port})
+	}
+	expected := []entry{
+		{TCP: true, IP: "127.0.0.1", Port: 8081},
+		{TCP: true, IP: "0.0.0.0", Port: 8082},
+		{TCP: true, IP: "0.0.0.0", Port: 8083},
+		{TCP: false, IP: "0.0.0.0", Port: 5353},
+	}
+	assert.DeepEqual(t, got, expected)
+}
+
+func TestParsePortsFromNFTRulesetInvalid(t *testing.T) {
+	_, err := parsePortsFromNFTRuleset([]byte("table ip nat {"))
+	assert.ErrorContains(t, err, "failed to parse the nftables ruleset")
+}
+
+func TestUniqueEntries(t *testing.T) {
+	rules := []string{
+		"-A CNI-DN-2e2f8d5b91929ef9fc152 -d 127.0.0.1/32 -p tcp -m tcp --dport 8081 -j DNAT --to-destination 10.4.0.7:80",
+	}
+	pts, err := parsePortsFromRules(rules)
+	assert.NilError(t, err)
+	ents, err := parsePortsFromNFTRuleset([]byte(nftData))
+	assert.NilError(t, err)
+	res := uniqueEntries(append(pts, ents...))
+	assert.Equal(t, len(res), 4)
+}
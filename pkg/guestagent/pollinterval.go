@@ -0,0 +1,75 @@
+package guestagent
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// pollInterval is the interval of polling the events, which can be changed at runtime.
+type pollInterval struct {
+	mu      sync.Mutex
+	d       time.Duration
+	changed chan struct{} // closed and replaced on each change
+}
+
+func newPollInterval(d time.Duration) (*pollInterval, error) {
+	if d <= 0 {
+		return nil, fmt.Errorf("poll interval must be positive, got %v", d)
+	}
+	return &pollInterval{d: d, changed: make(chan struct{})}, nil
+}
+
+func (p *pollInterval) get() (time.Duration, <-chan struct{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.d, p.changed
+}
+
+func (p *pollInterval) set(d time.Duration) error {
+	if d <= 0 {
+		return fmt.Errorf("poll interval must be positive, got %v", d)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if d != p.d {
+		p.d = d
+		close(p.changed)
+		p.changed = make(chan struct{})
+	}
+	return nil
+}
+
+// newTicker returns a channel like time.Ticker.C, and the function to stop the ticker.
+// When the interval is changed, the ticker is restarted with the new interval,
+// i.e., the next tick comes after the new interval.
+func (p *pollInterval) newTicker() (<-chan time.Time, func()) {
+	d, changed := p.get()
+	ticker := time.NewTicker(d)
+	ch := make(chan time.Time, 1)
+	stopCh := make(chan struct{})
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopCh:
+				return
+			case <-changed:
+				d, changed = p.get()
+				ticker.Reset(d)
+			case t := <-ticker.C:
+				// Drop the tick when the receiver is slow, like time.Ticker
+				select {
+				case ch <- t:
+				default:
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			close(stopCh)
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package guestagent
+
+import (
+	"testing"
+	"time"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestPollInterval(t *testing.T) {
+	_, err := newPollInterval(0)
+	assert.ErrorContains(t, err, "must be positive")
+
+	p, err := newPollInterval(10 * time.Millisecond)
+	assert.NilError(t, err)
+	tickerCh, tickerClose := p.newTicker()
+	defer tickerClose()
+	select {
+	case <-tickerCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no tick with the initial interval")
+	}
+
+	// the longer interval takes effect on the next cycle
+	assert.NilError(t, p.set(time.Hour))
+	d, _ := p.get()
+	assert.Equal(t, d, time.Hour)
+	// drain the tick that might have been sent before the change
+	select {
+	case <-tickerCh:
+	case <-time.After(50 * time.Millisecond):
+	}
+	select {
+	case <-tickerCh:
+		t.Fatal("unexpected tick after changing the interval to 1h")
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	// and so does the shorter interval, without waiting for the previous interval
+	assert.NilError(t, p.set(10*time.Millisecond))
+	select {
+	case <-tickerCh:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no tick after changing the interval to 10ms")
+	}
+
+	assert.ErrorContains(t, p.set(-time.Second), "must be positive")
+}
@@ -0,0 +1,219 @@
+package guestagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"slices"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+)
+
+// ErrPartialScan is returned with the partial results when a scan of the local ports
+// was not completed, e.g., due to the timeout or the limit of the ports.
+var ErrPartialScan = errors.New("partial scan of local ports")
+
+const (
+	defaultScanConcurrency = 4
+	defaultIPTablesTTL     = time.Minute
+)
+
+type options struct {
+	scanConcurrency int
+	scanTimeout     time.Duration
+	scanMaxPorts    int
+	iptablesTTL     time.Duration
+	// excludeInterfaces are the names of the interfaces whose addresses are excluded from the results
+	excludeInterfaces []string
+}
+
+type Opt func(*options) error
+
+// WithScanConcurrency specifies the maximum number of the port sources
+// (e.g., /proc/net/tcp, iptables) that are scanned concurrently.
+func WithScanConcurrency(n int) Opt {
+	return func(o *options) error {
+		if n < 1 {
+			return fmt.Errorf("scan concurrency must be positive, got %d", n)
+		}
+		o.scanConcurrency = n
+		return nil
+	}
+}
+
+// WithScanTimeout specifies the timeout of each scan of the local ports.
+// The sources that are not scanned within the timeout are skipped.
+// Zero means no timeout.
+func WithScanTimeout(d time.Duration) Opt {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("scan timeout must not be negative, got %v", d)
+		}
+		o.scanTimeout = d
+		return nil
+	}
+}
+
+// WithScanMaxPorts specifies the maximum number of the local ports returned by each scan.
+// Zero means no limit.
+func WithScanMaxPorts(n int) Opt {
+	return func(o *options) error {
+		if n < 0 {
+			return fmt.Errorf("scan max ports must not be negative, got %d", n)
+		}
+		o.scanMaxPorts = n
+		return nil
+	}
+}
+
+// WithIPTablesTTL specifies the maximum age of the cached iptables rules.
+// The rules are re-read when they are older than the TTL, even when no
+// netfilter audit event was received since the last read.
+// Zero means that the rules are only re-read on the audit events.
+func WithIPTablesTTL(d time.Duration) Opt {
+	return func(o *options) error {
+		if d < 0 {
+			return fmt.Errorf("iptables TTL must not be negative, got %v", d)
+		}
+		o.iptablesTTL = d
+		return nil
+	}
+}
+
+// WithExcludeInterfaces specifies the names of the network interfaces (e.g., "lima0")
+// whose ports are not reported. The ports bound to the wildcard addresses (0.0.0.0 and ::)
+// are still reported, as they are also reachable via the other interfaces.
+func WithExcludeInterfaces(names []string) Opt {
+	return func(o *options) error {
+		for _, name := range names {
+			if name == "" {
+				return errors.New("interface name must not be empty")
+			}
+		}
+		o.excludeInterfaces = names
+		return nil
+	}
+}
+
+func newOptions(opts []Opt) (*options, error) {
+	o := &options{
+		scanConcurrency: defaultScanConcurrency,
+		iptablesTTL:     defaultIPTablesTTL,
+	}
+	for _, f := range opts {
+		if err := f(o); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// portSource is a source of the local ports, such as /proc/net/tcp.
+type portSource struct {
+	name string
+	// skipKnownPorts skips the ports that are already reported by the preceding sources.
+	skipKnownPorts bool
+	scan           func(ctx context.Context) ([]*api.IPPort, error)
+}
+
+type portSourceResult struct {
+	ports []*api.IPPort
+	err   error
+}
+
+// scanPorts scans the sources with at most o.scanConcurrency workers.
+// The results are merged in the order of the sources, regardless of the order of completion.
+//
+// When some sources fail or time out, or when the number of the ports exceeds o.scanMaxPorts,
+// scanPorts returns the partial results with an error that wraps ErrPartialScan.
+func scanPorts(ctx context.Context, sources []portSource, o *options) ([]*api.IPPort, error) {
+	if o.scanTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.scanTimeout)
+		defer cancel()
+	}
+	// Each channel is buffered so that the workers never block after the scan is abandoned
+	results := make([]chan portSourceResult, len(sources))
+	for i := range results {
+		results[i] = make(chan portSourceResult, 1)
+	}
+	go func() {
+		sem := make(chan struct{}, max(o.scanConcurrency, 1))
+		for i, src := range sources {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				results[i] <- portSourceResult{err: ctx.Err()}
+				continue
+			}
+			go func() {
+				defer func() { <-sem }()
+				ports, err := src.scan(ctx)
+				results[i] <- portSourceResult{ports: ports, err: err}
+			}()
+		}
+	}()
+
+	var (
+		res  []*api.IPPort
+		errs []error
+	)
+	for i, src := range sources {
+		var r portSourceResult
+		select {
+		case r = <-results[i]:
+		case <-ctx.Done():
+			// Prefer the result if it is already available
+			select {
+			case r = <-results[i]:
+			default:
+				r.err = ctx.Err()
+			}
+		}
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", src.name, r.err))
+		}
+		for _, p := range r.ports {
+			if src.skipKnownPorts && hasPort(res, p.Port) {
+				continue
+			}
+			res = append(res, p)
+		}
+	}
+	if o.scanMaxPorts > 0 && len(res) > o.scanMaxPorts {
+		errs = append(errs, fmt.Errorf("found %d ports, exceeding the limit %d", len(res), o.scanMaxPorts))
+		res = res[:o.scanMaxPorts]
+	}
+	if len(errs) > 0 {
+		return res, fmt.Errorf("%w: %w", ErrPartialScan, errors.Join(errs...))
+	}
+	return res, nil
+}
+
+func hasPort(ports []*api.IPPort, port int32) bool {
+	for _, p := range ports {
+		if p.Port == port {
+			return true
+		}
+	}
+	return false
+}
+
+// excludePorts removes the ports bound to the excluded addresses.
+// The ports bound to the wildcard addresses are never removed.
+func excludePorts(ports []*api.IPPort, excludedAddrs []net.IP) []*api.IPPort {
+	if len(excludedAddrs) == 0 {
+		return ports
+	}
+	var res []*api.IPPort
+	for _, port := range ports {
+		ip := net.ParseIP(port.Ip)
+		if ip != nil && !ip.IsUnspecified() && slices.ContainsFunc(excludedAddrs, ip.Equal) {
+			continue
+		}
+		res = append(res, port)
+	}
+	return res
+}
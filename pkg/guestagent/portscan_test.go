@@ -0,0 +1,177 @@
+package guestagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	"gotest.tools/v3/assert"
+)
+
+func testPortSource(name string, delay time.Duration, ports ...int32) portSource {
+	return portSource{
+		name: name,
+		scan: func(ctx context.Context) ([]*api.IPPort, error) {
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			var res []*api.IPPort
+			for _, port := range ports {
+				res = append(res, &api.IPPort{Ip: "127.0.0.1", Port: port, Protocol: "tcp"})
+			}
+			return res, nil
+		},
+	}
+}
+
+func portNumbers(ports []*api.IPPort) []int32 {
+	var res []int32
+	for _, p := range ports {
+		res = append(res, p.Port)
+	}
+	return res
+}
+
+func TestScanPortsConcurrency(t *testing.T) {
+	const concurrency = 3
+	var inFlight, maxInFlight atomic.Int32
+	var sources []portSource
+	for i := range 20 {
+		sources = append(sources, portSource{
+			name: fmt.Sprintf("source%d", i),
+			scan: func(context.Context) ([]*api.IPPort, error) {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					m := maxInFlight.Load()
+					if n <= m || maxInFlight.CompareAndSwap(m, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return []*api.IPPort{{Ip: "127.0.0.1", Port: int32(1000 + i), Protocol: "tcp"}}, nil
+			},
+		})
+	}
+	o, err := newOptions([]Opt{WithScanConcurrency(concurrency)})
+	assert.NilError(t, err)
+	ports, err := scanPorts(context.Background(), sources, o)
+	assert.NilError(t, err)
+	assert.Equal(t, len(ports), len(sources))
+	assert.Assert(t, maxInFlight.Load() <= concurrency, "max in flight: %d", maxInFlight.Load())
+}
+
+func TestScanPortsOrder(t *testing.T) {
+	sources := []portSource{
+		testPortSource("slow", 20*time.Millisecond, 80),
+		testPortSource("fast", 0, 443, 80),
+	}
+	sources[1].skipKnownPorts = true
+	o, err := newOptions(nil)
+	assert.NilError(t, err)
+	ports, err := scanPorts(context.Background(), sources, o)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, portNumbers(ports), []int32{80, 443})
+}
+
+func TestScanPortsPartial(t *testing.T) {
+	t.Run("timeout", func(t *testing.T) {
+		sources := []portSource{
+			testPortSource("fast", 0, 22),
+			testPortSource("hang", time.Hour, 80),
+		}
+		o, err := newOptions([]Opt{WithScanTimeout(50 * time.Millisecond)})
+		assert.NilError(t, err)
+		ports, err := scanPorts(context.Background(), sources, o)
+		assert.Assert(t, errors.Is(err, ErrPartialScan))
+		assert.Assert(t, errors.Is(err, context.DeadlineExceeded))
+		assert.ErrorContains(t, err, "hang")
+		assert.DeepEqual(t, portNumbers(ports), []int32{22})
+	})
+	t.Run("max ports", func(t *testing.T) {
+		sources := []portSource{
+			testPortSource("a", 0, 22, 80),
+			testPortSource("b", 0, 443),
+		}
+		o, err := newOptions([]Opt{WithScanMaxPorts(2)})
+		assert.NilError(t, err)
+		ports, err := scanPorts(context.Background(), sources, o)
+		assert.Assert(t, errors.Is(err, ErrPartialScan))
+		assert.DeepEqual(t, portNumbers(ports), []int32{22, 80})
+	})
+	t.Run("source error", func(t *testing.T) {
+		sources := []portSource{
+			{name: "broken", scan: func(context.Context) ([]*api.IPPort, error) { return nil, errors.New("broken") }},
+			testPortSource("ok", 0, 22),
+		}
+		o, err := newOptions(nil)
+		assert.NilError(t, err)
+		ports, err := scanPorts(context.Background(), sources, o)
+		assert.Assert(t, errors.Is(err, ErrPartialScan))
+		assert.DeepEqual(t, portNumbers(ports), []int32{22})
+	})
+}
+
+func TestNewOptions(t *testing.T) {
+	o, err := newOptions(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, o.scanConcurrency, defaultScanConcurrency)
+	assert.Equal(t, o.iptablesTTL, defaultIPTablesTTL)
+
+	_, err = newOptions([]Opt{WithScanConcurrency(0)})
+	assert.ErrorContains(t, err, "must be positive")
+	_, err = newOptions([]Opt{WithScanTimeout(-time.Second)})
+	assert.ErrorContains(t, err, "must not be negative")
+	_, err = newOptions([]Opt{WithIPTablesTTL(-time.Second)})
+	assert.ErrorContains(t, err, "must not be negative")
+	_, err = newOptions([]Opt{WithExcludeInterfaces([]string{"lima0", ""})})
+	assert.ErrorContains(t, err, "must not be empty")
+}
+
+func TestExcludePorts(t *testing.T) {
+	tcp := func(ip string, port int32) *api.IPPort {
+		return &api.IPPort{Protocol: "tcp", Ip: ip, Port: port}
+	}
+	ports := []*api.IPPort{
+		tcp("0.0.0.0", 22),
+		tcp("::", 80),
+		tcp("127.0.0.1", 8080),
+		tcp("192.168.5.15", 8081),         // slirp
+		tcp("192.168.105.2", 8082),        // excluded
+		tcp("fd00::2", 8083),              // excluded
+		tcp("fd00:0:0:0:0:0:0:2", 8084),   // excluded, same as fd00::2
+		tcp("::ffff:192.168.105.2", 8085), // excluded, same as 192.168.105.2
+		tcp("192.168.105.3", 8086),
+	}
+	excluded := []net.IP{net.ParseIP("192.168.105.2"), net.ParseIP("fd00::2")}
+	res := excludePorts(ports, excluded)
+	assert.DeepEqual(t, portNumbers(res), []int32{22, 80, 8080, 8081, 8086})
+
+	// wildcard addresses are never excluded
+	res = excludePorts(ports, []net.IP{net.IPv4zero, net.IPv6unspecified})
+	assert.DeepEqual(t, portNumbers(res), portNumbers(ports))
+
+	assert.DeepEqual(t, portNumbers(excludePorts(ports, nil)), portNumbers(ports))
+}
+
+func BenchmarkScanPorts(b *testing.B) {
+	var sources []portSource
+	for i := range 100 {
+		sources = append(sources, testPortSource(fmt.Sprintf("source%d", i), 0, int32(i), int32(1000+i)))
+	}
+	o, err := newOptions(nil)
+	assert.NilError(b, err)
+	b.ResetTimer()
+	for range b.N {
+		if _, err := scanPorts(context.Background(), sources, o); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
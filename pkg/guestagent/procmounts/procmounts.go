@@ -0,0 +1,87 @@
+// Package procmounts parses /proc/mounts to detect the mount type of Lima mounts.
+package procmounts
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type Entry struct {
+	Device     string   `json:"device"`
+	MountPoint string   `json:"mountPoint"`
+	FSType     string   `json:"fsType"`
+	Options    []string `json:"options"`
+}
+
+// ReadOnly returns true when the entry is mounted with the "ro" option.
+func (e *Entry) ReadOnly() bool {
+	return slices.Contains(e.Options, "ro")
+}
+
+// limaMountTypes maps the filesystem types in /proc/mounts to the Lima mount types.
+var limaMountTypes = map[string]string{
+	"fuse.sshfs": "reverse-sshfs",
+	"9p":         "9p",
+	"virtiofs":   "virtiofs",
+}
+
+// LimaMountType returns the Lima mount type (e.g., "reverse-sshfs") of the entry.
+// An empty string is returned for the filesystems that are not used for Lima mounts.
+func (e *Entry) LimaMountType() string {
+	return limaMountTypes[e.FSType]
+}
+
+// Parse parses /proc/mounts.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 4 {
+			return entries, fmt.Errorf("unexpected line %q", line)
+		}
+		mountPoint, err := unescape(fields[1])
+		if err != nil {
+			return entries, fmt.Errorf("unexpected mount point in line %q: %w", line, err)
+		}
+		entries = append(entries, Entry{
+			Device:     fields[0],
+			MountPoint: mountPoint,
+			FSType:     fields[2],
+			Options:    strings.Split(fields[3], ","),
+		})
+	}
+	return entries, sc.Err()
+}
+
+// unescape decodes the octal escapes (e.g., `\040` for a space) in /proc/mounts.
+func unescape(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			sb.WriteByte(s[i])
+			continue
+		}
+		if i+4 > len(s) {
+			return "", fmt.Errorf("truncated escape sequence in %q", s)
+		}
+		c, err := strconv.ParseUint(s[i+1:i+4], 8, 8)
+		if err != nil {
+			return "", err
+		}
+		sb.WriteByte(byte(c))
+		i += 3
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,15 @@
+package procmounts
+
+import (
+	"os"
+)
+
+// ParseFile parses /proc/mounts.
+func ParseFile() ([]Entry, error) {
+	r, err := os.Open("/proc/mounts")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r)
+}
@@ -0,0 +1,49 @@
+package procmounts
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	procMounts := `sysfs /sys sysfs rw,nosuid,nodev,noexec,relatime 0 0
+proc /proc proc rw,nosuid,nodev,noexec,relatime 0 0
+/dev/vda1 / ext4 rw,relatime,discard,errors=remount-ro 0 0
+mount0 /Users/foo virtiofs ro,relatime 0 0
+mount1 /tmp/lima 9p rw,sync,dirsync,relatime,access=client,trans=virtio,version=9p2000.L,msize=131072 0 0
+:/Users/foo/My\040Documents /Users/foo/My\040Documents fuse.sshfs rw,nosuid,nodev,relatime,user_id=501,group_id=1000,allow_other 0 0
+`
+	entries, err := Parse(strings.NewReader(procMounts))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 6)
+
+	var lima []Entry
+	for _, e := range entries {
+		if e.LimaMountType() != "" {
+			lima = append(lima, e)
+		}
+	}
+	assert.Equal(t, len(lima), 3)
+
+	assert.Equal(t, lima[0].MountPoint, "/Users/foo")
+	assert.Equal(t, lima[0].LimaMountType(), "virtiofs")
+	assert.Assert(t, lima[0].ReadOnly())
+
+	assert.Equal(t, lima[1].MountPoint, "/tmp/lima")
+	assert.Equal(t, lima[1].LimaMountType(), "9p")
+	assert.Assert(t, !lima[1].ReadOnly())
+
+	assert.Equal(t, lima[2].MountPoint, "/Users/foo/My Documents")
+	assert.Equal(t, lima[2].LimaMountType(), "reverse-sshfs")
+	assert.Assert(t, !lima[2].ReadOnly())
+}
+
+func TestParseInvalid(t *testing.T) {
+	_, err := Parse(strings.NewReader("foo /bar\n"))
+	assert.ErrorContains(t, err, "unexpected line")
+
+	_, err = Parse(strings.NewReader(`foo /bar\04 ext4 rw 0 0` + "\n"))
+	assert.ErrorContains(t, err, "unexpected mount point")
+}
@@ -3,32 +3,61 @@ package procnettcp
 import (
 	"errors"
 	"os"
+	"path/filepath"
 )
 
-// ParseFiles parses /proc/net/{tcp, tcp6}.
+// DefaultRoot is the default root directory of the net files.
+const DefaultRoot = "/proc"
+
+// File is a /proc/net file that lists the sockets of Kind.
+type File struct {
+	Path string
+	Kind Kind
+}
+
+// Files lists the /proc/net files parsed by ParseFiles.
+var Files = FilesIn(DefaultRoot)
+
+// FilesIn lists the net/{tcp, tcp6, udp, udp6} files under root,
+// e.g., "/proc", or "/proc/<PID>" for the network namespace of the process.
+func FilesIn(root string) []File {
+	return []File{
+		{Path: filepath.Join(root, "net", "tcp"), Kind: TCP},
+		{Path: filepath.Join(root, "net", "tcp6"), Kind: TCP6},
+		{Path: filepath.Join(root, "net", "udp"), Kind: UDP},
+		{Path: filepath.Join(root, "net", "udp6"), Kind: UDP6},
+	}
+}
+
+// ParseFiles parses /proc/net/{tcp, tcp6, udp, udp6}.
 func ParseFiles() ([]Entry, error) {
+	return ParseFilesIn(DefaultRoot)
+}
+
+// ParseFilesIn parses net/{tcp, tcp6, udp, udp6} under root.
+// See FilesIn for the root.
+func ParseFilesIn(root string) ([]Entry, error) {
 	var res []Entry
-	files := map[string]Kind{
-		"/proc/net/tcp":  TCP,
-		"/proc/net/tcp6": TCP6,
-		"/proc/net/udp":  UDP,
-		"/proc/net/udp6": UDP6,
-	}
-	for file, kind := range files {
-		r, err := os.Open(file)
-		if err != nil {
-			if errors.Is(err, os.ErrNotExist) {
-				continue
-			}
-			return res, err
-		}
-		parsed, err := Parse(r, kind)
+	for _, f := range FilesIn(root) {
+		parsed, err := ParseFile(f)
 		if err != nil {
-			_ = r.Close()
 			return res, err
 		}
-		_ = r.Close()
 		res = append(res, parsed...)
 	}
 	return res, nil
 }
+
+// ParseFile parses a /proc/net file.
+// A missing file is not treated as an error.
+func ParseFile(f File) ([]Entry, error) {
+	r, err := os.Open(f.Path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer r.Close()
+	return Parse(r, f.Kind)
+}
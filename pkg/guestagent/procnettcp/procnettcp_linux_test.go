@@ -0,0 +1,43 @@
+package procnettcp
+
+import (
+	"net"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseFilesIn(t *testing.T) {
+	// testdata/proc/net lacks udp and udp6, which must not be treated as an error
+	entries, err := ParseFilesIn(filepath.Join("testdata", "proc"))
+	assert.NilError(t, err)
+	t.Log(entries)
+	assert.Equal(t, len(entries), 5)
+
+	assert.Equal(t, entries[0].Kind, TCP)
+	assert.Check(t, net.ParseIP("127.0.0.1").Equal(entries[0].IP))
+	assert.Equal(t, entries[0].Port, uint16(35567))
+	assert.Equal(t, entries[0].State, TCPListen)
+
+	assert.Equal(t, entries[2].Kind, TCP)
+	assert.Check(t, net.ParseIP("192.168.60.11").Equal(entries[2].IP))
+	assert.Equal(t, entries[2].Port, uint16(22))
+	assert.Equal(t, entries[2].State, TCPEstablished)
+
+	assert.Equal(t, entries[3].Kind, TCP6)
+	assert.Check(t, net.IPv6zero.Equal(entries[3].IP))
+	assert.Equal(t, entries[3].Port, uint16(8080))
+	assert.Equal(t, entries[3].State, TCPListen)
+
+	assert.Equal(t, entries[4].Kind, TCP6)
+	assert.Check(t, net.ParseIP("fe80::70a6:57ff:fe71:c75d").Equal(entries[4].IP))
+	assert.Equal(t, entries[4].Port, uint16(80))
+}
+
+func TestFilesIn(t *testing.T) {
+	files := FilesIn("/proc/42")
+	assert.Equal(t, files[0].Path, "/proc/42/net/tcp")
+	assert.Equal(t, files[3].Path, "/proc/42/net/udp6")
+	assert.DeepEqual(t, Files, FilesIn(DefaultRoot))
+}
@@ -0,0 +1,83 @@
+// Package procnetunix parses /proc/net/unix to list the unix sockets in the guest.
+package procnetunix
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Type = string
+
+const (
+	Stream    Type = "stream"
+	Dgram     Type = "dgram"
+	SeqPacket Type = "seqpacket"
+)
+
+// types maps the "Type" column (SOCK_STREAM, SOCK_DGRAM, SOCK_SEQPACKET) to Type.
+var types = map[uint64]Type{
+	1: Stream,
+	2: Dgram,
+	5: SeqPacket,
+}
+
+// flagAcceptCon is __SO_ACCEPTCON in the "Flags" column, set for listening sockets.
+const flagAcceptCon = 0x10000
+
+type Entry struct {
+	Type      Type   `json:"type"`
+	Listening bool   `json:"listening"`
+	Inode     uint64 `json:"inode"`
+	// Path is empty for unnamed sockets, and starts with "@" for abstract sockets.
+	Path string `json:"path,omitempty"`
+}
+
+// Abstract returns true for the sockets in the abstract namespace.
+func (e *Entry) Abstract() bool {
+	return strings.HasPrefix(e.Path, "@")
+}
+
+// Parse parses /proc/net/unix.
+func Parse(r io.Reader) ([]Entry, error) {
+	var entries []Entry
+	sc := bufio.NewScanner(r)
+	for i := 0; sc.Scan(); i++ {
+		line := strings.TrimSpace(sc.Text())
+		if i == 0 || line == "" {
+			// skip the header: "Num RefCount Protocol Flags Type St Inode Path"
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) < 7 {
+			return entries, fmt.Errorf("unparsable line %q", line)
+		}
+		flags, err := strconv.ParseUint(fields[3], 16, 32)
+		if err != nil {
+			return entries, fmt.Errorf("unparsable flags in line %q: %w", line, err)
+		}
+		typ, err := strconv.ParseUint(fields[4], 16, 16)
+		if err != nil {
+			return entries, fmt.Errorf("unparsable type in line %q: %w", line, err)
+		}
+		inode, err := strconv.ParseUint(fields[6], 10, 64)
+		if err != nil {
+			return entries, fmt.Errorf("unparsable inode in line %q: %w", line, err)
+		}
+		ent := Entry{
+			Type:      types[typ],
+			Listening: flags&flagAcceptCon != 0,
+			Inode:     inode,
+		}
+		if len(fields) > 7 {
+			ent.Path = strings.Join(fields[7:], " ")
+		}
+		entries = append(entries, ent)
+	}
+	if err := sc.Err(); err != nil {
+		return entries, err
+	}
+	return entries, nil
+}
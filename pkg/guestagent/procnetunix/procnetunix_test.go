@@ -0,0 +1,36 @@
+package procnetunix
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	procNetUnix := `Num       RefCount Protocol Flags    Type St Inode Path
+0000000000000000: 00000002 00000000 00010000 0001 01 20567 /run/containerd/containerd.sock
+0000000000000000: 00000002 00000000 00010000 0001 01 18432 /run/user/501/docker.sock
+0000000000000000: 00000003 00000000 00000000 0001 03 21001
+0000000000000000: 00000002 00000000 00010000 0001 01 17650 @/org/freedesktop/systemd1/notify
+0000000000000000: 00000002 00000000 00000000 0002 01 15123 /run/systemd/journal/dev-log
+0000000000000000: 00000002 00000000 00010000 0005 01 16001 /run/udev/control
+0000000000000000: 00000002 00000000 00010000 0001 01 16002 /tmp/with space.sock
+`
+	entries, err := Parse(strings.NewReader(procNetUnix))
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 7)
+
+	assert.DeepEqual(t, entries[0], Entry{Type: Stream, Listening: true, Inode: 20567, Path: "/run/containerd/containerd.sock"})
+	assert.DeepEqual(t, entries[2], Entry{Type: Stream, Inode: 21001})
+	assert.Assert(t, entries[3].Abstract())
+	assert.Assert(t, !entries[4].Listening)
+	assert.Equal(t, entries[4].Type, Dgram)
+	assert.Equal(t, entries[5].Type, SeqPacket)
+	assert.Equal(t, entries[6].Path, "/tmp/with space.sock")
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse(strings.NewReader("Num RefCount Protocol Flags Type St Inode Path\n0000000000000000: 00000002\n"))
+	assert.ErrorContains(t, err, "unparsable line")
+}
@@ -0,0 +1,106 @@
+// Package procstats parses /proc/meminfo, /proc/stat, and /proc/loadavg to report the resource usage of the guest.
+package procstats
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Meminfo struct {
+	TotalBytes     uint64 `json:"totalBytes"`
+	AvailableBytes uint64 `json:"availableBytes"`
+}
+
+// ParseMeminfo parses /proc/meminfo.
+func ParseMeminfo(r io.Reader) (*Meminfo, error) {
+	var (
+		m                  Meminfo
+		hasTotal, hasAvail bool
+	)
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := sc.Text()
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		var dst *uint64
+		switch k {
+		case "MemTotal":
+			dst, hasTotal = &m.TotalBytes, true
+		case "MemAvailable":
+			dst, hasAvail = &m.AvailableBytes, true
+		default:
+			continue
+		}
+		fields := strings.Fields(v)
+		if len(fields) != 2 || fields[1] != "kB" {
+			return nil, fmt.Errorf("unexpected line %q", line)
+		}
+		kib, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected line %q: %w", line, err)
+		}
+		*dst = kib * 1024
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if !hasTotal || !hasAvail {
+		return nil, errors.New("MemTotal or MemAvailable not found")
+	}
+	return &m, nil
+}
+
+// ParseStat parses /proc/stat and returns the number of CPUs.
+func ParseStat(r io.Reader) (int, error) {
+	var cpus int
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		// "cpu" is the total of all the CPUs, "cpu0", "cpu1", ... are the individual ones
+		name, _, _ := strings.Cut(sc.Text(), " ")
+		if suffix, ok := strings.CutPrefix(name, "cpu"); ok && suffix != "" {
+			if _, err := strconv.Atoi(suffix); err != nil {
+				return 0, fmt.Errorf("unexpected line %q", sc.Text())
+			}
+			cpus++
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return 0, err
+	}
+	if cpus == 0 {
+		return 0, errors.New("no CPU found")
+	}
+	return cpus, nil
+}
+
+type Loadavg struct {
+	Load1  float64 `json:"load1"`
+	Load5  float64 `json:"load5"`
+	Load15 float64 `json:"load15"`
+}
+
+// ParseLoadavg parses /proc/loadavg.
+func ParseLoadavg(r io.Reader) (*Loadavg, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(b))
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected content %q", string(b))
+	}
+	var loads [3]float64
+	for i := range loads {
+		loads[i], err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected content %q: %w", string(b), err)
+		}
+	}
+	return &Loadavg{Load1: loads[0], Load5: loads[1], Load15: loads[2]}, nil
+}
@@ -0,0 +1,35 @@
+package procstats
+
+import (
+	"os"
+)
+
+// ParseMeminfoFile parses /proc/meminfo.
+func ParseMeminfoFile() (*Meminfo, error) {
+	r, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ParseMeminfo(r)
+}
+
+// ParseStatFile parses /proc/stat and returns the number of CPUs.
+func ParseStatFile() (int, error) {
+	r, err := os.Open("/proc/stat")
+	if err != nil {
+		return 0, err
+	}
+	defer r.Close()
+	return ParseStat(r)
+}
+
+// ParseLoadavgFile parses /proc/loadavg.
+func ParseLoadavgFile() (*Loadavg, error) {
+	r, err := os.Open("/proc/loadavg")
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return ParseLoadavg(r)
+}
@@ -0,0 +1,69 @@
+package procstats
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParseMeminfo(t *testing.T) {
+	procMeminfo := `MemTotal:        4005564 kB
+MemFree:         2779372 kB
+MemAvailable:    3521288 kB
+Buffers:           38660 kB
+Cached:           813744 kB
+HugePages_Total:       0
+`
+	m, err := ParseMeminfo(strings.NewReader(procMeminfo))
+	assert.NilError(t, err)
+	assert.Equal(t, m.TotalBytes, uint64(4005564*1024))
+	assert.Equal(t, m.AvailableBytes, uint64(3521288*1024))
+}
+
+func TestParseMeminfoInvalid(t *testing.T) {
+	_, err := ParseMeminfo(strings.NewReader("MemTotal:        4005564 kB\n"))
+	assert.ErrorContains(t, err, "not found")
+
+	_, err = ParseMeminfo(strings.NewReader("MemTotal:        foo kB\nMemAvailable:    3521288 kB\n"))
+	assert.ErrorContains(t, err, "unexpected line")
+}
+
+func TestParseStat(t *testing.T) {
+	procStat := `cpu  1318 0 1995 272893 411 0 38 0 0 0
+cpu0 330 0 490 68149 103 0 31 0 0 0
+cpu1 314 0 514 68265 96 0 2 0 0 0
+cpu2 339 0 496 68231 105 0 3 0 0 0
+cpu3 334 0 493 68247 106 0 1 0 0 0
+intr 271528 0 4187 2788 0 0 0 0 0 0 0 0
+ctxt 448631
+btime 1729000000
+processes 1637
+procs_running 1
+procs_blocked 0
+softirq 113914 2 17390 1 4094 6587 0 167 43612 0 42061
+`
+	cpus, err := ParseStat(strings.NewReader(procStat))
+	assert.NilError(t, err)
+	assert.Equal(t, cpus, 4)
+}
+
+func TestParseStatInvalid(t *testing.T) {
+	_, err := ParseStat(strings.NewReader("cpu  1318 0 1995 272893 411 0 38 0 0 0\n"))
+	assert.ErrorContains(t, err, "no CPU found")
+
+	_, err = ParseStat(strings.NewReader("cpufoo 330 0 490 68149 103 0 31 0 0 0\n"))
+	assert.ErrorContains(t, err, "unexpected line")
+}
+
+func TestParseLoadavg(t *testing.T) {
+	l, err := ParseLoadavg(strings.NewReader("0.52 0.34 0.15 1/123 4567\n"))
+	assert.NilError(t, err)
+	assert.Equal(t, *l, Loadavg{Load1: 0.52, Load5: 0.34, Load15: 0.15})
+
+	_, err = ParseLoadavg(strings.NewReader("0.52 0.34\n"))
+	assert.ErrorContains(t, err, "unexpected content")
+
+	_, err = ParseLoadavg(strings.NewReader("0.52 foo 0.15 1/123 4567\n"))
+	assert.ErrorContains(t, err, "unexpected content")
+}
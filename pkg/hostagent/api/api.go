@@ -1,5 +1,147 @@
 package api
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/docker/go-units"
+)
+
 type Info struct {
-	SSHLocalPort int `json:"sshLocalPort,omitempty"`
+	SSHLocalPort int           `json:"sshLocalPort,omitempty"`
+	Mounts       []MountStatus `json:"mounts,omitempty"`
+	PortForwards []PortForward `json:"portForwards,omitempty"`
+	// BootTime is the time when the guest was booted, as reported by the guest agent.
+	BootTime *time.Time `json:"bootTime,omitempty"`
+	// CloudInitDuration is the time from the guest boot until cloud-init finished.
+	// It is zero while cloud-init is running.
+	CloudInitDuration time.Duration `json:"cloudInitDuration,omitempty"`
+	// Degraded is true when the instance is running, but some features such as file sharing
+	// and port forwarding may not work.
+	Degraded bool `json:"degraded,omitempty"`
+	// DegradedReasons are the short descriptions of the reasons of Degraded, e.g., "sshfs mount failed".
+	DegradedReasons []string `json:"degradedReasons,omitempty"`
+	// VMRunState is the run state of the vm as reported by the hypervisor, e.g., "running", "paused", or "io-error".
+	// It is empty when the driver cannot query the run state.
+	VMRunState string `json:"vmRunState,omitempty"`
+}
+
+const (
+	// VMRunStateRunning is the VMRunState of the vm that is running normally.
+	VMRunStateRunning = "running"
+	// VMRunStatePaused is the VMRunState of the vm paused by `limactl pause`.
+	VMRunStatePaused = "paused"
+)
+
+// PortForward is a TCP or UDP port of the guest, with the status of forwarding it to the host.
+type PortForward struct {
+	GuestAddr string `json:"guestAddr"`
+	// HostAddr is empty when the port is skipped because no rule forwards it.
+	HostAddr string `json:"hostAddr"`
+	// Protocol is "tcp" or "udp".
+	// UDP ports are only forwarded over the guest agent connection (LIMA_SSH_PORT_FORWARDER=false).
+	Protocol string `json:"protocol,omitempty"`
+	// Remapped is true when HostAddr was picked automatically, because the host address
+	// in the port forwarding rule was already in use.
+	Remapped bool `json:"remapped,omitempty"`
+	// Static is true when the port is forwarded on start by a static rule,
+	// regardless of whether the guest is listening on the port.
+	Static bool              `json:"static,omitempty"`
+	Status PortForwardStatus `json:"status,omitempty"`
+	// Reason is the reason of the failed or skipped status.
+	Reason string `json:"reason,omitempty"`
+}
+
+type PortForwardStatus = string
+
+const (
+	PortForwardActive  PortForwardStatus = "active"
+	PortForwardFailed  PortForwardStatus = "failed"
+	PortForwardSkipped PortForwardStatus = "skipped"
+)
+
+// StopTimeout is the body of PUT /v1/driver/stop-timeout.
+type StopTimeout struct {
+	// Timeout is the duration to wait for the guest to shut down gracefully when the host agent is interrupted,
+	// before the driver stops the vm forcibly. Zero restores the default of the driver.
+	Timeout time.Duration `json:"timeout"`
+}
+
+// MountStatus is the status of a mount in the guest.
+type MountStatus struct {
+	Path string `json:"path"`
+	// Type is the actual mount type, e.g., "reverse-sshfs", "9p", or "virtiofs".
+	// It may differ from the mount type in the config, when the mount type fell back to another one.
+	Type     string `json:"type"`
+	ReadOnly bool   `json:"readOnly"`
+}
+
+// Network is a network interface of the guest, for GET /v1/networks.
+type Network struct {
+	// Interface is the name of the interface in the guest, e.g., "eth0" or "lima0".
+	Interface  string `json:"interface"`
+	MACAddress string `json:"macAddress"`
+	// Lima and Socket are copied from the network config. Both are empty for the user-mode network.
+	Lima   string `json:"lima,omitempty"`
+	Socket string `json:"socket,omitempty"`
+	// Addresses are the addresses in the CIDR notation, as reported by the guest agent.
+	// Empty while the guest agent is not connected.
+	Addresses []string `json:"addresses,omitempty"`
+}
+
+// DriverConfig is the runtime config of the driver, for GET and PATCH /v1/driver/config.
+// For PATCH, nil fields are left unchanged.
+type DriverConfig struct {
+	CPUs *int `json:"cpus,omitempty"`
+	// Memory is a size string, e.g., "4GiB".
+	Memory *string `json:"memory,omitempty"`
+}
+
+// Validate validates the values of the non-nil fields.
+func (c *DriverConfig) Validate() error {
+	if c.CPUs != nil && *c.CPUs < 1 {
+		return fmt.Errorf("field `cpus` must be positive, got %d", *c.CPUs)
+	}
+	if c.Memory != nil {
+		if _, err := units.RAMInBytes(*c.Memory); err != nil {
+			return fmt.Errorf("field `memory` has an invalid value %q: %w", *c.Memory, err)
+		}
+	}
+	return nil
+}
+
+// Fields returns the JSON names of the non-nil fields.
+func (c *DriverConfig) Fields() []string {
+	var fields []string
+	if c.CPUs != nil {
+		fields = append(fields, "cpus")
+	}
+	if c.Memory != nil {
+		fields = append(fields, "memory")
+	}
+	return fields
+}
+
+// UnsupportedFieldsError is returned when the driver cannot apply the fields of DriverConfig.
+// The host agent returns it with the status code 422.
+type UnsupportedFieldsError struct {
+	Fields []string `json:"unsupportedFields"`
+}
+
+func (e *UnsupportedFieldsError) Error() string {
+	return fmt.Sprintf("the driver does not support changing %s at runtime", strings.Join(e.Fields, ", "))
+}
+
+// UnsupportedValueError is returned when the driver supports changing the field at runtime,
+// but cannot apply the value, e.g., because the value exceeds the maximum.
+// The host agent returns it with the status code 422.
+type UnsupportedValueError struct {
+	Field  string `json:"field"`
+	Value  string `json:"value"`
+	Reason string `json:"reason"`
+}
+
+func (e *UnsupportedValueError) Error() string {
+	return fmt.Sprintf("the driver cannot change %s to %s at runtime: %s", e.Field, e.Value, e.Reason)
 }
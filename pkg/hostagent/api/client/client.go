@@ -4,44 +4,148 @@ package client
 // Apache License 2.0
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"time"
 
 	"github.com/lima-vm/lima/pkg/hostagent/api"
 	"github.com/lima-vm/lima/pkg/httpclientutil"
 )
 
+// HostAgentClient is the client of the host agent API.
+// With WithRetry, only the GET requests (Info, Networks, and DriverConfig) are retried.
 type HostAgentClient interface {
 	HTTPClient() *http.Client
 	Info(context.Context) (*api.Info, error)
+	Networks(context.Context) ([]api.Network, error)
+	DriverConfig(context.Context) (*api.DriverConfig, error)
+	// PatchDriverConfig applies the non-nil fields of config, and returns the effective config.
+	// It returns *api.UnsupportedFieldsError when the driver cannot apply the fields,
+	// and *api.UnsupportedValueError when the driver cannot apply the values.
+	PatchDriverConfig(ctx context.Context, config api.DriverConfig) (*api.DriverConfig, error)
+	// SaveState saves the vm state to the instance directory.
+	// The vm does not continue running after saving the state, so the host agent has to be stopped.
+	SaveState(context.Context) error
+	// SetStopTimeout sets the duration to wait for the guest to shut down gracefully,
+	// when the host agent is interrupted next time.
+	SetStopTimeout(ctx context.Context, timeout time.Duration) error
+}
+
+// ErrNotReady is returned when the client failed to connect to the host agent socket,
+// e.g., because the host agent is still starting up.
+// Errors returned by the host agent itself do not match ErrNotReady.
+var ErrNotReady = errors.New("host agent socket is not ready")
+
+type options struct {
+	timeout          time.Duration // default: 0 (no timeout)
+	retryMaxAttempts int           // default: 0 (no retry)
+	retryMaxDuration time.Duration // default: 0 (no retry)
+}
+
+func (o *options) apply(opts []Opt) error {
+	for _, f := range opts {
+		if err := f(o); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Opt func(*options) error
+
+// WithTimeout sets the timeout of each request, including the time for dialing the socket.
+// Regardless of this option, the deadline of the context passed to the client methods is honored.
+func WithTimeout(timeout time.Duration) Opt {
+	return func(o *options) error {
+		if timeout < 0 {
+			return fmt.Errorf("timeout must not be negative, got %v", timeout)
+		}
+		o.timeout = timeout
+		return nil
+	}
+}
+
+// WithRetry retries the GET requests with exponential backoff when the client fails to connect to the socket.
+// The requests are attempted at most maxAttempts times, until maxDuration has elapsed since the first attempt.
+// Errors returned by the host agent are not retried.
+//
+// The requests that change the state of the host agent (PatchDriverConfig, SaveState, and SetStopTimeout)
+// are not retried; the callers have to retry them explicitly if needed.
+func WithRetry(maxAttempts int, maxDuration time.Duration) Opt {
+	return func(o *options) error {
+		if maxAttempts < 1 {
+			return fmt.Errorf("maxAttempts must be positive, got %d", maxAttempts)
+		}
+		if maxDuration <= 0 {
+			return fmt.Errorf("maxDuration must be positive, got %v", maxDuration)
+		}
+		o.retryMaxAttempts = maxAttempts
+		o.retryMaxDuration = maxDuration
+		return nil
+	}
 }
 
 // NewHostAgentClient creates a client.
 // socketPath is a path to the UNIX socket, without unix:// prefix.
-func NewHostAgentClient(socketPath string) (HostAgentClient, error) {
+//
+// The socket must exist, unless WithRetry is specified.
+func NewHostAgentClient(socketPath string, opts ...Opt) (HostAgentClient, error) {
+	var o options
+	if err := o.apply(opts); err != nil {
+		return nil, err
+	}
 	hc, err := httpclientutil.NewHTTPClientWithSocketPath(socketPath)
 	if err != nil {
-		return nil, err
+		if o.retryMaxAttempts == 0 || !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		// The socket may be created by the host agent later
+		hc, err = httpclientutil.NewHTTPClientWithDialFn(func(ctx context.Context) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", socketPath)
+		})
+		if err != nil {
+			return nil, err
+		}
 	}
-	return NewHostAgentClientWithHTTPClient(hc), nil
+	return NewHostAgentClientWithHTTPClient(hc, opts...)
 }
 
-func NewHostAgentClientWithHTTPClient(hc *http.Client) HostAgentClient {
-	return &client{
-		Client:    hc,
-		version:   "v1",
-		dummyHost: "lima-hostagent",
+// NewHostAgentClientWithHTTPClient creates a client with the HTTP client.
+// hc is not modified by the options.
+func NewHostAgentClientWithHTTPClient(hc *http.Client, opts ...Opt) (HostAgentClient, error) {
+	var o options
+	if err := o.apply(opts); err != nil {
+		return nil, err
 	}
+	if o.timeout > 0 {
+		hcCopy := *hc
+		hcCopy.Timeout = o.timeout
+		hc = &hcCopy
+	}
+	return &client{
+		Client:           hc,
+		version:          "v1",
+		dummyHost:        "lima-hostagent",
+		retryMaxAttempts: o.retryMaxAttempts,
+		retryMaxDuration: o.retryMaxDuration,
+	}, nil
 }
 
 type client struct {
 	*http.Client
 	// version is always "v1"
 	// TODO(AkihiroSuda): negotiate the version
-	version   string
-	dummyHost string
+	version          string
+	dummyHost        string
+	retryMaxAttempts int
+	retryMaxDuration time.Duration
 }
 
 func (c *client) HTTPClient() *http.Client {
@@ -50,7 +154,7 @@ func (c *client) HTTPClient() *http.Client {
 
 func (c *client) Info(ctx context.Context) (*api.Info, error) {
 	u := fmt.Sprintf("http://%s/%s/info", c.dummyHost, c.version)
-	resp, err := httpclientutil.Get(ctx, c.HTTPClient(), u)
+	resp, err := c.get(ctx, u)
 	if err != nil {
 		return nil, err
 	}
@@ -58,7 +162,137 @@ func (c *client) Info(ctx context.Context) (*api.Info, error) {
 	var info api.Info
 	dec := json.NewDecoder(resp.Body)
 	if err := dec.Decode(&info); err != nil {
-		return nil, err
+		return nil, c.wrapError(err)
 	}
 	return &info, nil
 }
+
+func (c *client) Networks(ctx context.Context) ([]api.Network, error) {
+	u := fmt.Sprintf("http://%s/%s/networks", c.dummyHost, c.version)
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var networks []api.Network
+	if err := json.NewDecoder(resp.Body).Decode(&networks); err != nil {
+		return nil, c.wrapError(err)
+	}
+	return networks, nil
+}
+
+func (c *client) DriverConfig(ctx context.Context) (*api.DriverConfig, error) {
+	u := fmt.Sprintf("http://%s/%s/driver/config", c.dummyHost, c.version)
+	resp, err := c.get(ctx, u)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	var config api.DriverConfig
+	if err := json.NewDecoder(resp.Body).Decode(&config); err != nil {
+		return nil, c.wrapError(err)
+	}
+	return &config, nil
+}
+
+func (c *client) PatchDriverConfig(ctx context.Context, config api.DriverConfig) (*api.DriverConfig, error) {
+	u := fmt.Sprintf("http://%s/%s/driver/config", c.dummyHost, c.version)
+	b, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := httpclientutil.Patch(ctx, c.HTTPClient(), u, bytes.NewReader(b))
+	if err != nil {
+		var statusErr *httpclientutil.HTTPStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+			var unsupportedErr api.UnsupportedFieldsError
+			if json.Unmarshal([]byte(statusErr.Body), &unsupportedErr) == nil && len(unsupportedErr.Fields) > 0 {
+				return nil, &unsupportedErr
+			}
+			var unsupportedValueErr api.UnsupportedValueError
+			if json.Unmarshal([]byte(statusErr.Body), &unsupportedValueErr) == nil && unsupportedValueErr.Field != "" {
+				return nil, &unsupportedValueErr
+			}
+		}
+		return nil, c.wrapError(err)
+	}
+	defer resp.Body.Close()
+	var effective api.DriverConfig
+	if err := json.NewDecoder(resp.Body).Decode(&effective); err != nil {
+		return nil, c.wrapError(err)
+	}
+	return &effective, nil
+}
+
+func (c *client) SaveState(ctx context.Context) error {
+	u := fmt.Sprintf("http://%s/%s/driver/save", c.dummyHost, c.version)
+	resp, err := httpclientutil.Post(ctx, c.HTTPClient(), u, nil)
+	if err != nil {
+		return c.wrapError(err)
+	}
+	return resp.Body.Close()
+}
+
+func (c *client) SetStopTimeout(ctx context.Context, timeout time.Duration) error {
+	u := fmt.Sprintf("http://%s/%s/driver/stop-timeout", c.dummyHost, c.version)
+	b, err := json.Marshal(api.StopTimeout{Timeout: timeout})
+	if err != nil {
+		return err
+	}
+	resp, err := httpclientutil.Put(ctx, c.HTTPClient(), u, bytes.NewReader(b))
+	if err != nil {
+		return c.wrapError(err)
+	}
+	return resp.Body.Close()
+}
+
+const (
+	retryInitialBackoff = 100 * time.Millisecond
+	retryMaxBackoff     = 2 * time.Second
+)
+
+// get calls httpclientutil.Get, with retries on connection errors when WithRetry is specified.
+func (c *client) get(ctx context.Context, u string) (*http.Response, error) {
+	deadline := time.Now().Add(c.retryMaxDuration)
+	backoff := retryInitialBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := httpclientutil.Get(ctx, c.HTTPClient(), u)
+		if err == nil {
+			return resp, nil
+		}
+		if !isConnectionError(err) {
+			return nil, c.wrapError(err)
+		}
+		err = fmt.Errorf("%w: %w", ErrNotReady, err)
+		if attempt >= c.retryMaxAttempts || time.Now().Add(backoff).After(deadline) {
+			if attempt > 1 {
+				return nil, fmt.Errorf("gave up after %d attempts: %w", attempt, err)
+			}
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w (last error: %w)", ctx.Err(), err)
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, retryMaxBackoff)
+	}
+}
+
+// isConnectionError returns true when err was caused by failing to connect to the socket.
+func isConnectionError(err error) bool {
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && opErr.Op == "dial"
+}
+
+// wrapError adds a hint to the timeout error.
+func (c *client) wrapError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		if c.Timeout > 0 {
+			return fmt.Errorf("timed out waiting for the host agent to respond (timeout: %v): %w", c.Timeout, err)
+		}
+		return fmt.Errorf("timed out waiting for the host agent to respond: %w", err)
+	}
+	return err
+}
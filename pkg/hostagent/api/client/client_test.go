@@ -0,0 +1,253 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/hostagent/api/server"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"gotest.tools/v3/assert"
+)
+
+func newSlowServer(t *testing.T, delay time.Duration) string {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"sshLocalPort":60022}`))
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+	return socketPath
+}
+
+func TestInfoTimeout(t *testing.T) {
+	socketPath := newSlowServer(t, 5*time.Second)
+	c, err := NewHostAgentClient(socketPath, WithTimeout(100*time.Millisecond))
+	assert.NilError(t, err)
+	_, err = c.Info(context.Background())
+	assert.ErrorContains(t, err, "timed out waiting for the host agent to respond (timeout: 100ms)")
+}
+
+func TestInfoContextDeadline(t *testing.T) {
+	socketPath := newSlowServer(t, 5*time.Second)
+	c, err := NewHostAgentClient(socketPath)
+	assert.NilError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = c.Info(ctx)
+	assert.Assert(t, errors.Is(err, context.DeadlineExceeded), err)
+}
+
+func TestInfo(t *testing.T) {
+	socketPath := newSlowServer(t, 0)
+	c, err := NewHostAgentClient(socketPath, WithTimeout(5*time.Second))
+	assert.NilError(t, err)
+	info, err := c.Info(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, info.SSHLocalPort, 60022)
+}
+
+func TestWithTimeoutNegative(t *testing.T) {
+	_, err := NewHostAgentClientWithHTTPClient(&http.Client{}, WithTimeout(-time.Second))
+	assert.ErrorContains(t, err, "must not be negative")
+}
+
+func TestInfoRetry(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(`{"sshLocalPort":60022}`))
+	}))
+	_ = srv.Listener.Close()
+	started := make(chan struct{})
+	go func() {
+		defer close(started)
+		// Start listening after the client has made the first attempt
+		time.Sleep(300 * time.Millisecond)
+		l, err := net.Listen("unix", socketPath)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		srv.Listener = l
+		srv.Start()
+	}()
+	t.Cleanup(func() {
+		<-started
+		srv.Close()
+	})
+
+	c, err := NewHostAgentClient(socketPath, WithRetry(100, 10*time.Second))
+	assert.NilError(t, err)
+	info, err := c.Info(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, info.SSHLocalPort, 60022)
+}
+
+func TestInfoNotReady(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	c, err := NewHostAgentClient(socketPath, WithRetry(3, 10*time.Second))
+	assert.NilError(t, err)
+	_, err = c.Info(context.Background())
+	assert.Assert(t, errors.Is(err, ErrNotReady), err)
+	assert.ErrorContains(t, err, "gave up after 3 attempts")
+
+	_, err = NewHostAgentClient(socketPath)
+	assert.Assert(t, errors.Is(err, os.ErrNotExist), err)
+}
+
+func TestInfoAgentErrorIsNotRetried(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	var requests atomic.Int32
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		http.Error(w, "something went wrong", http.StatusInternalServerError)
+	}))
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewHostAgentClient(socketPath, WithRetry(5, 10*time.Second))
+	assert.NilError(t, err)
+	_, err = c.Info(context.Background())
+	assert.Assert(t, err != nil)
+	assert.Assert(t, !errors.Is(err, ErrNotReady), err)
+	assert.Equal(t, requests.Load(), int32(1))
+}
+
+type fakeAgent struct{}
+
+func (fakeAgent) Info(context.Context) (*api.Info, error) {
+	return &api.Info{
+		SSHLocalPort:    60022,
+		Degraded:        true,
+		DegradedReasons: []string{"sshfs mount failed", `vm is not running (run state: "io-error")`},
+		VMRunState:      "io-error",
+	}, nil
+}
+
+func (fakeAgent) Networks(context.Context) ([]api.Network, error) {
+	return []api.Network{
+		{Interface: "eth0", MACAddress: "52:55:55:12:34:56", Addresses: []string{"192.168.5.15/24"}},
+		{Interface: "lima0", MACAddress: "52:55:55:12:34:57", Lima: "shared"},
+	}, nil
+}
+
+func (fakeAgent) DriverRuntimeConfig(_ context.Context, config api.DriverConfig) (api.DriverConfig, error) {
+	if config.Memory != nil {
+		return api.DriverConfig{}, &api.UnsupportedValueError{Field: "memory", Value: *config.Memory, Reason: "must not exceed 4GiB"}
+	}
+	if fields := config.Fields(); len(fields) > 0 {
+		return api.DriverConfig{}, &api.UnsupportedFieldsError{Fields: fields}
+	}
+	return api.DriverConfig{CPUs: ptr.Of(4)}, nil
+}
+
+func (fakeAgent) SaveState(context.Context) error {
+	return errors.New("unimplemented")
+}
+
+func (fakeAgent) SetStopTimeout(time.Duration) {}
+
+func TestDriverConfig(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	r := http.NewServeMux()
+	server.AddRoutes(r, &server.Backend{Agent: fakeAgent{}})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewHostAgentClient(socketPath)
+	assert.NilError(t, err)
+	config, err := c.DriverConfig(context.Background())
+	assert.NilError(t, err)
+	assert.Equal(t, *config.CPUs, 4)
+
+	_, err = c.PatchDriverConfig(context.Background(), api.DriverConfig{CPUs: ptr.Of(2)})
+	var unsupportedErr *api.UnsupportedFieldsError
+	assert.Assert(t, errors.As(err, &unsupportedErr), err)
+	assert.DeepEqual(t, unsupportedErr.Fields, []string{"cpus"})
+
+	_, err = c.PatchDriverConfig(context.Background(), api.DriverConfig{Memory: ptr.Of("8GiB")})
+	var unsupportedValueErr *api.UnsupportedValueError
+	assert.Assert(t, errors.As(err, &unsupportedValueErr), err)
+	assert.DeepEqual(t, *unsupportedValueErr, api.UnsupportedValueError{Field: "memory", Value: "8GiB", Reason: "must not exceed 4GiB"})
+}
+
+func TestNetworks(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	r := http.NewServeMux()
+	server.AddRoutes(r, &server.Backend{Agent: fakeAgent{}})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewHostAgentClient(socketPath)
+	assert.NilError(t, err)
+	networks, err := c.Networks(context.Background())
+	assert.NilError(t, err)
+	expected, err := fakeAgent{}.Networks(context.Background())
+	assert.NilError(t, err)
+	assert.DeepEqual(t, networks, expected)
+}
+
+func TestInfoDegraded(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	r := http.NewServeMux()
+	server.AddRoutes(r, &server.Backend{Agent: fakeAgent{}})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewHostAgentClient(socketPath)
+	assert.NilError(t, err)
+	info, err := c.Info(context.Background())
+	assert.NilError(t, err)
+	assert.Assert(t, info.Degraded)
+	assert.DeepEqual(t, info.DegradedReasons, []string{"sshfs mount failed", `vm is not running (run state: "io-error")`})
+	assert.Equal(t, info.VMRunState, "io-error")
+}
+
+func TestSaveState(t *testing.T) {
+	socketPath := filepath.Join(t.TempDir(), "ha.sock")
+	l, err := net.Listen("unix", socketPath)
+	assert.NilError(t, err)
+	r := http.NewServeMux()
+	server.AddRoutes(r, &server.Backend{Agent: fakeAgent{}})
+	srv := httptest.NewUnstartedServer(r)
+	srv.Listener = l
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	c, err := NewHostAgentClient(socketPath)
+	assert.NilError(t, err)
+	err = c.SaveState(context.Background())
+	assert.ErrorContains(t, err, "unimplemented")
+}
@@ -1,16 +1,35 @@
 package server
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
+	"time"
 
-	"github.com/lima-vm/lima/pkg/hostagent"
+	"github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/hostagent/metrics"
 	"github.com/lima-vm/lima/pkg/httputil"
 )
 
+// Agent is implemented by *hostagent.HostAgent.
+type Agent interface {
+	Info(ctx context.Context) (*api.Info, error)
+	Networks(ctx context.Context) ([]api.Network, error)
+	DriverRuntimeConfig(ctx context.Context, config api.DriverConfig) (api.DriverConfig, error)
+	SaveState(ctx context.Context) error
+	SetStopTimeout(timeout time.Duration)
+}
+
 type Backend struct {
-	Agent *hostagent.HostAgent
+	Agent Agent
+	// Metrics is exposed via GET /v1/metrics.
+	// Nil disables the endpoint.
+	Metrics *metrics.Registry
+	// ReadOnly refuses PATCH /v1/driver/config with the status code 403,
+	// for the instances with `readOnly: true` in lima.yaml.
+	ReadOnly bool
 }
 
 func (b *Backend) onError(w http.ResponseWriter, err error, ec int) {
@@ -50,6 +69,173 @@ func (b *Backend) GetInfo(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(m)
 }
 
+// GetNetworks is the handler for GET /v1/networks.
+func (b *Backend) GetNetworks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	networks, err := b.Agent.Networks(ctx)
+	if err != nil {
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	m, err := json.Marshal(networks)
+	if err != nil {
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(m)
+}
+
+// unsupportedFieldsErrorJSON is returned with the status code 422.
+type unsupportedFieldsErrorJSON struct {
+	Message           string   `json:"message"`
+	UnsupportedFields []string `json:"unsupportedFields"`
+}
+
+// unsupportedValueErrorJSON is returned with the status code 422.
+type unsupportedValueErrorJSON struct {
+	Message string `json:"message"`
+	api.UnsupportedValueError
+}
+
+// DriverConfig is the handler for GET and PATCH /v1/driver/config.
+// PATCH applies the non-nil fields of api.DriverConfig, and returns the effective config.
+func (b *Backend) DriverConfig(w http.ResponseWriter, r *http.Request) {
+	var config api.DriverConfig
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPatch:
+		if b.ReadOnly {
+			b.onError(w, errors.New("instance is read-only, so the driver config cannot be changed"), http.StatusForbidden)
+			return
+		}
+		dec := json.NewDecoder(r.Body)
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&config); err != nil {
+			b.onError(w, err, http.StatusBadRequest)
+			return
+		}
+		if err := config.Validate(); err != nil {
+			b.onError(w, err, http.StatusBadRequest)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	effective, err := b.Agent.DriverRuntimeConfig(ctx, config)
+	if err != nil {
+		var unsupportedErr *api.UnsupportedFieldsError
+		if errors.As(err, &unsupportedErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(unsupportedFieldsErrorJSON{
+				Message:           err.Error(),
+				UnsupportedFields: unsupportedErr.Fields,
+			})
+			return
+		}
+		var unsupportedValueErr *api.UnsupportedValueError
+		if errors.As(err, &unsupportedValueErr) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			_ = json.NewEncoder(w).Encode(unsupportedValueErrorJSON{
+				Message:               err.Error(),
+				UnsupportedValueError: *unsupportedValueErr,
+			})
+			return
+		}
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	m, err := json.Marshal(effective)
+	if err != nil {
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(m)
+}
+
+// SaveState is the handler for POST /v1/driver/save.
+// The host agent has to be stopped after saving the state, as the vm cannot continue running.
+func (b *Backend) SaveState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	ctx := r.Context()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := b.Agent.SaveState(ctx); err != nil {
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetMetrics is the handler for GET /v1/metrics, and GET /metrics for the default path of Prometheus.
+// The metrics are written in the Prometheus text format.
+func (b *Backend) GetMetrics(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var buf bytes.Buffer
+	if err := b.Metrics.Write(&buf); err != nil {
+		b.onError(w, err, http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(buf.Bytes())
+}
+
+// StopTimeout is the handler for PUT /v1/driver/stop-timeout.
+// The timeout is applied on the next SIGINT, e.g., sent by `limactl stop`.
+func (b *Backend) StopTimeout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req api.StopTimeout
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		b.onError(w, err, http.StatusBadRequest)
+		return
+	}
+	if req.Timeout < 0 {
+		b.onError(w, errors.New("timeout must not be negative"), http.StatusBadRequest)
+		return
+	}
+	b.Agent.SetStopTimeout(req.Timeout)
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func AddRoutes(r *http.ServeMux, b *Backend) {
 	r.Handle("/v1/info", http.HandlerFunc(b.GetInfo))
+	r.Handle("/v1/networks", http.HandlerFunc(b.GetNetworks))
+	r.Handle("/v1/driver/config", http.HandlerFunc(b.DriverConfig))
+	r.Handle("/v1/driver/save", http.HandlerFunc(b.SaveState))
+	r.Handle("/v1/driver/stop-timeout", http.HandlerFunc(b.StopTimeout))
+	if b.Metrics != nil {
+		r.Handle("/v1/metrics", http.HandlerFunc(b.GetMetrics))
+		r.Handle("/metrics", http.HandlerFunc(b.GetMetrics))
+	}
 }
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/hostagent/metrics"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"gotest.tools/v3/assert"
+)
+
+func scrape(t *testing.T, url string) string {
+	t.Helper()
+	resp, err := http.Get(url)
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+	assert.Assert(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+	b, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	return string(b)
+}
+
+func TestGetMetrics(t *testing.T) {
+	reg := metrics.NewRegistry()
+	events := reg.NewCounter("lima_hostagent_guest_events_total", "Number of the events received from the guest agent.")
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{Metrics: reg})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	assert.Assert(t, strings.Contains(scrape(t, srv.URL+"/v1/metrics"), "lima_hostagent_guest_events_total 0\n"))
+	// simulate an event
+	events.Inc()
+	assert.Assert(t, strings.Contains(scrape(t, srv.URL+"/v1/metrics"), "lima_hostagent_guest_events_total 1\n"))
+	assert.Assert(t, strings.Contains(scrape(t, srv.URL+"/metrics"), "lima_hostagent_guest_events_total 1\n"))
+}
+
+func TestGetMetricsDisabled(t *testing.T) {
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for _, path := range []string{"/v1/metrics", "/metrics"} {
+		resp, err := http.Get(srv.URL + path)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusNotFound, path)
+	}
+}
+
+// fakeAgent supports changing cpus up to 8, but not memory.
+type fakeAgent struct {
+	config      api.DriverConfig
+	stopTimeout time.Duration
+}
+
+func (a *fakeAgent) Info(context.Context) (*api.Info, error) {
+	return &api.Info{}, nil
+}
+
+func (a *fakeAgent) Networks(context.Context) ([]api.Network, error) {
+	return nil, nil
+}
+
+func (a *fakeAgent) DriverRuntimeConfig(_ context.Context, config api.DriverConfig) (api.DriverConfig, error) {
+	if config.Memory != nil {
+		return api.DriverConfig{}, &api.UnsupportedFieldsError{Fields: []string{"memory"}}
+	}
+	if config.CPUs != nil {
+		if *config.CPUs > 8 {
+			return api.DriverConfig{}, &api.UnsupportedValueError{Field: "cpus", Value: strconv.Itoa(*config.CPUs), Reason: "must be between 1 and 8"}
+		}
+		a.config.CPUs = config.CPUs
+	}
+	return a.config, nil
+}
+
+func (a *fakeAgent) SaveState(context.Context) error {
+	return nil
+}
+
+func (a *fakeAgent) SetStopTimeout(timeout time.Duration) {
+	a.stopTimeout = timeout
+}
+
+func patchDriverConfig(t *testing.T, url, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPatch, url+"/v1/driver/config", strings.NewReader(body))
+	assert.NilError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	assert.NilError(t, err)
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	assert.NilError(t, err)
+	return resp.StatusCode, string(b)
+}
+
+func TestDriverConfig(t *testing.T) {
+	agent := &fakeAgent{config: api.DriverConfig{CPUs: ptr.Of(4), Memory: ptr.Of("4GiB")}}
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{Agent: agent})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	t.Run("get", func(t *testing.T) {
+		resp, err := http.Get(srv.URL + "/v1/driver/config")
+		assert.NilError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, resp.StatusCode, http.StatusOK)
+		var config api.DriverConfig
+		assert.NilError(t, json.NewDecoder(resp.Body).Decode(&config))
+		assert.DeepEqual(t, config, agent.config)
+	})
+	t.Run("valid", func(t *testing.T) {
+		code, body := patchDriverConfig(t, srv.URL, `{"cpus": 2}`)
+		assert.Equal(t, code, http.StatusOK, body)
+		var config api.DriverConfig
+		assert.NilError(t, json.Unmarshal([]byte(body), &config))
+		assert.Equal(t, *config.CPUs, 2)
+		assert.Equal(t, *config.Memory, "4GiB")
+	})
+	t.Run("unknown field", func(t *testing.T) {
+		code, body := patchDriverConfig(t, srv.URL, `{"cpus": 2, "disk": "200GiB"}`)
+		assert.Equal(t, code, http.StatusBadRequest, body)
+		assert.Assert(t, strings.Contains(body, "disk"), body)
+	})
+	t.Run("invalid value", func(t *testing.T) {
+		code, body := patchDriverConfig(t, srv.URL, `{"cpus": 0}`)
+		assert.Equal(t, code, http.StatusBadRequest, body)
+		assert.Assert(t, strings.Contains(body, "field `cpus` must be positive"), body)
+	})
+	t.Run("unsupported by driver", func(t *testing.T) {
+		code, body := patchDriverConfig(t, srv.URL, `{"memory": "8GiB"}`)
+		assert.Equal(t, code, http.StatusUnprocessableEntity, body)
+		var e unsupportedFieldsErrorJSON
+		assert.NilError(t, json.Unmarshal([]byte(body), &e))
+		assert.DeepEqual(t, e.UnsupportedFields, []string{"memory"})
+	})
+	t.Run("unsupported value by driver", func(t *testing.T) {
+		code, body := patchDriverConfig(t, srv.URL, `{"cpus": 16}`)
+		assert.Equal(t, code, http.StatusUnprocessableEntity, body)
+		var e unsupportedValueErrorJSON
+		assert.NilError(t, json.Unmarshal([]byte(body), &e))
+		assert.Equal(t, e.Field, "cpus")
+		assert.Equal(t, e.Value, "16")
+		assert.Assert(t, strings.Contains(e.Message, "must be between 1 and 8"), body)
+	})
+}
+
+func TestDriverConfigReadOnly(t *testing.T) {
+	agent := &fakeAgent{config: api.DriverConfig{CPUs: ptr.Of(4), Memory: ptr.Of("4GiB")}}
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{Agent: agent, ReadOnly: true})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	// GET is still allowed
+	resp, err := http.Get(srv.URL + "/v1/driver/config")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusOK)
+
+	code, body := patchDriverConfig(t, srv.URL, `{"cpus": 2}`)
+	assert.Equal(t, code, http.StatusForbidden, body)
+	assert.Assert(t, strings.Contains(body, "read-only"), body)
+	assert.Equal(t, *agent.config.CPUs, 4)
+}
+
+func TestSaveState(t *testing.T) {
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{Agent: &fakeAgent{}})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/v1/driver/save")
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusMethodNotAllowed)
+
+	resp, err = http.Post(srv.URL+"/v1/driver/save", "", nil)
+	assert.NilError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, resp.StatusCode, http.StatusNoContent)
+}
+
+func TestStopTimeout(t *testing.T) {
+	agent := &fakeAgent{}
+	r := http.NewServeMux()
+	AddRoutes(r, &Backend{Agent: agent})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	put := func(body string) int {
+		req, err := http.NewRequest(http.MethodPut, srv.URL+"/v1/driver/stop-timeout", strings.NewReader(body))
+		assert.NilError(t, err)
+		resp, err := http.DefaultClient.Do(req)
+		assert.NilError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	assert.Equal(t, put(`{"timeout": 30000000000}`), http.StatusNoContent)
+	assert.Equal(t, agent.stopTimeout, 30*time.Second)
+	assert.Equal(t, put(`{"timeout": -1}`), http.StatusBadRequest)
+	assert.Equal(t, put(`{`), http.StatusBadRequest)
+	assert.Equal(t, agent.stopTimeout, 30*time.Second)
+}
@@ -2,18 +2,30 @@ package events
 
 import (
 	"time"
+
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
 )
 
 type Status struct {
 	Running bool `json:"running,omitempty"`
 	// When Degraded is true, Running must be true as well
 	Degraded bool `json:"degraded,omitempty"`
+	// DegradedReasons are the short descriptions of the reasons of Degraded, e.g., "sshfs mount failed".
+	DegradedReasons []string `json:"degradedReasons,omitempty"`
 	// When Exiting is true, Running must be false
 	Exiting bool `json:"exiting,omitempty"`
 
 	Errors []string `json:"errors,omitempty"`
 
 	SSHLocalPort int `json:"sshLocalPort,omitempty"`
+
+	// PortForwards contains the port forwards whose host address has been remapped
+	// due to a conflict on the host.
+	PortForwards []hostagentapi.PortForward `json:"portForwards,omitempty"`
+
+	// CloudInitDuration is the time from the guest boot until cloud-init finished,
+	// reported with the Running status when cloud-init has already finished.
+	CloudInitDuration time.Duration `json:"cloudInitDuration,omitempty"`
 }
 
 type Event struct {
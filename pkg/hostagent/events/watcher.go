@@ -14,7 +14,9 @@ import (
 func Watch(ctx context.Context, haStdoutPath, haStderrPath string, begin time.Time, onEvent func(Event) bool) error {
 	haStdoutTail, err := tail.TailFile(haStdoutPath,
 		tail.Config{
-			Follow:    true,
+			Follow: true,
+			// The file is rotated by the host agent
+			ReOpen:    true,
 			MustExist: true,
 		})
 	if err != nil {
@@ -27,7 +29,9 @@ func Watch(ctx context.Context, haStdoutPath, haStderrPath string, begin time.Ti
 
 	haStderrTail, err := tail.TailFile(haStderrPath,
 		tail.Config{
-			Follow:    true,
+			Follow: true,
+			// The file is rotated by the host agent
+			ReOpen:    true,
 			MustExist: true,
 		})
 	if err != nil {
@@ -0,0 +1,130 @@
+package hostagent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/sirupsen/logrus"
+)
+
+// hostHookQueueSize is the number of the pending hook executions; the further events are dropped.
+const hostHookQueueSize = 256
+
+type hostHookJob struct {
+	hook     limayaml.HostHook
+	event    limayaml.HostHookEvent
+	port     *api.IPPort
+	hostAddr string
+}
+
+// hostHooks runs the commands of `hostHooks` in lima.yaml for the events reported by the guest agent.
+// The commands are executed one by one in the order of the events, so that the hook for a closed port
+// never runs before the hook for the same port being opened.
+type hostHooks struct {
+	instName string
+	instDir  string
+	hooks    []limayaml.HostHook
+	// hostAddr returns the host address of the forwarded guest address, or "".
+	hostAddr func(port *api.IPPort) string
+
+	queue chan hostHookJob
+}
+
+func newHostHooks(instName, instDir string, hooks []limayaml.HostHook, hostAddr func(port *api.IPPort) string) *hostHooks {
+	return &hostHooks{
+		instName: instName,
+		instDir:  instDir,
+		hooks:    hooks,
+		hostAddr: hostAddr,
+		queue:    make(chan hostHookJob, hostHookQueueSize),
+	}
+}
+
+// hostHookMatches returns true if the hook is configured for the event on the guest port.
+func hostHookMatches(hook limayaml.HostHook, event limayaml.HostHookEvent, port *api.IPPort) bool {
+	if hook.Event != event {
+		return false
+	}
+	if hook.Proto != limayaml.ProtoAny && hook.Proto != port.Protocol {
+		return false
+	}
+	return int(port.Port) >= hook.GuestPortRange[0] && int(port.Port) <= hook.GuestPortRange[1]
+}
+
+// OnEvent queues the hooks for the event. Must be called after the port forwarder has processed the event,
+// so that the host address of the opened port is known.
+func (h *hostHooks) OnEvent(ev *api.Event) {
+	h.enqueue(limayaml.HostHookEventPortClosed, ev.LocalPortsRemoved)
+	h.enqueue(limayaml.HostHookEventPortOpened, ev.LocalPortsAdded)
+}
+
+func (h *hostHooks) enqueue(event limayaml.HostHookEvent, ports []*api.IPPort) {
+	for _, port := range ports {
+		for _, hook := range h.hooks {
+			if !hostHookMatches(hook, event, port) {
+				continue
+			}
+			job := hostHookJob{hook: hook, event: event, port: port}
+			if event == limayaml.HostHookEventPortOpened && h.hostAddr != nil {
+				job.hostAddr = h.hostAddr(port)
+			}
+			select {
+			case h.queue <- job:
+			default:
+				logrus.Warnf("Dropping the host hook %v for %s %s/%s, as too many hooks are pending", hook.Command, event, port.Protocol, port.HostString())
+			}
+		}
+	}
+}
+
+// Run executes the queued hooks until ctx is done.
+func (h *hostHooks) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case job := <-h.queue:
+			if err := h.run(ctx, job); err != nil {
+				logrus.WithError(err).Warnf("Host hook %v for %s %s/%s failed", job.hook.Command, job.event, job.port.Protocol, job.port.HostString())
+			}
+		}
+	}
+}
+
+// run executes the command of the hook with the privileges of the host agent, i.e., the host user.
+// The details of the event are passed as the environment variables, and the output is logged.
+func (h *hostHooks) run(ctx context.Context, job hostHookJob) error {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(*job.hook.Timeout)*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, job.hook.Command[0], job.hook.Command[1:]...)
+	cmd.Dir = h.instDir
+	cmd.Env = append(os.Environ(),
+		"LIMA_INSTANCE="+h.instName,
+		"LIMA_HOOK_EVENT="+job.event,
+		"LIMA_HOOK_PROTO="+job.port.Protocol,
+		"LIMA_HOOK_GUEST_IP="+job.port.Ip,
+		"LIMA_HOOK_GUEST_PORT="+strconv.Itoa(int(job.port.Port)),
+		"LIMA_HOOK_HOST_ADDR="+job.hostAddr,
+	)
+	logger := logrus.WithField("hook", job.hook.Command).WithField("event", job.event)
+	stdout := logger.WithField("stream", "stdout").WriterLevel(logrus.InfoLevel)
+	defer stdout.Close()
+	stderr := logger.WithField("stream", "stderr").WriterLevel(logrus.WarnLevel)
+	defer stderr.Close()
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+	// Do not wait for the grandchildren that keep stdout and stderr open after the timeout
+	cmd.WaitDelay = 5 * time.Second
+	logger.Debugf("Executing the host hook for %s/%s", job.port.Protocol, job.port.HostString())
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %ds: %w", *job.hook.Timeout, err)
+	}
+	return err
+}
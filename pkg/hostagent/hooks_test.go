@@ -0,0 +1,96 @@
+package hostagent
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/portfwd"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"gotest.tools/v3/assert"
+)
+
+func TestHostHookMatches(t *testing.T) {
+	hook := limayaml.HostHook{
+		Event:          limayaml.HostHookEventPortOpened,
+		GuestPortRange: [2]int{8000, 8999},
+		Proto:          limayaml.ProtoTCP,
+	}
+	tcp8080 := &api.IPPort{Protocol: "tcp", Ip: "0.0.0.0", Port: 8080}
+	assert.Assert(t, hostHookMatches(hook, limayaml.HostHookEventPortOpened, tcp8080))
+	assert.Assert(t, !hostHookMatches(hook, limayaml.HostHookEventPortClosed, tcp8080))
+	assert.Assert(t, !hostHookMatches(hook, limayaml.HostHookEventPortOpened, &api.IPPort{Protocol: "udp", Ip: "0.0.0.0", Port: 8080}))
+	assert.Assert(t, !hostHookMatches(hook, limayaml.HostHookEventPortOpened, &api.IPPort{Protocol: "tcp", Ip: "0.0.0.0", Port: 9000}))
+
+	hook.Proto = limayaml.ProtoAny
+	assert.Assert(t, hostHookMatches(hook, limayaml.HostHookEventPortOpened, &api.IPPort{Protocol: "udp", Ip: "0.0.0.0", Port: 8999}))
+}
+
+func TestHostHooksRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires /bin/sh")
+	}
+	instDir := t.TempDir()
+	out := filepath.Join(instDir, "out")
+	hooks := []limayaml.HostHook{
+		{
+			Event:          limayaml.HostHookEventPortOpened,
+			GuestPortRange: [2]int{1, 65535},
+			Proto:          limayaml.ProtoTCP,
+			Command:        []string{"/bin/sh", "-c", `echo "$LIMA_INSTANCE $LIMA_HOOK_EVENT $LIMA_HOOK_PROTO $LIMA_HOOK_GUEST_IP $LIMA_HOOK_GUEST_PORT $LIMA_HOOK_HOST_ADDR" >>` + out},
+			Timeout:        ptr.Of(10),
+		},
+		{
+			Event:          limayaml.HostHookEventPortClosed,
+			GuestPortRange: [2]int{1, 65535},
+			Proto:          limayaml.ProtoAny,
+			Command:        []string{"/bin/sh", "-c", `echo "$LIMA_HOOK_EVENT $LIMA_HOOK_GUEST_PORT" >>` + out},
+			Timeout:        ptr.Of(10),
+		},
+	}
+	h := newHostHooks("default", instDir, hooks, func(port *api.IPPort) string {
+		return "127.0.0.1:" + strings.TrimPrefix(port.HostString(), "0.0.0.0:")
+	})
+	h.OnEvent(&api.Event{
+		LocalPortsAdded: []*api.IPPort{
+			{Protocol: "tcp", Ip: "0.0.0.0", Port: 8080},
+			{Protocol: "udp", Ip: "0.0.0.0", Port: 5353},
+		},
+	})
+	h.OnEvent(&api.Event{
+		LocalPortsRemoved: []*api.IPPort{{Protocol: "tcp", Ip: "0.0.0.0", Port: 8080}},
+	})
+	assert.Equal(t, len(h.queue), 2)
+	for len(h.queue) > 0 {
+		assert.NilError(t, h.run(context.Background(), <-h.queue))
+	}
+	b, err := os.ReadFile(out)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "default portOpened tcp 0.0.0.0 8080 127.0.0.1:8080\nportClosed 8080\n")
+
+	timeout := limayaml.HostHook{Command: []string{"sleep", "10"}, Timeout: ptr.Of(1)}
+	err = h.run(context.Background(), hostHookJob{hook: timeout, event: limayaml.HostHookEventPortOpened, port: &api.IPPort{Protocol: "tcp", Port: 80}})
+	assert.ErrorContains(t, err, "timed out after 1s")
+}
+
+func TestForwardedHostAddrGRPC(t *testing.T) {
+	t.Setenv("LIMA_SSH_PORT_FORWARDER", "false")
+	rules := []limayaml.PortForward{
+		{
+			GuestIP:        net.IPv4zero,
+			GuestPortRange: [2]int{1, 65535},
+			HostIP:         net.IPv4(127, 0, 0, 1),
+			HostPortRange:  [2]int{1, 65535},
+			Proto:          limayaml.ProtoAny,
+		},
+	}
+	a := &HostAgent{grpcPortForwarder: portfwd.NewPortForwarder(rules, false, false)}
+	assert.Equal(t, a.forwardedHostAddr(&api.IPPort{Protocol: "tcp", Ip: "0.0.0.0", Port: 8080}), "127.0.0.1:8080")
+	assert.Equal(t, a.forwardedHostAddr(&api.IPPort{Protocol: "udp", Ip: "0.0.0.0", Port: 5353}), "127.0.0.1:5353")
+}
@@ -2,6 +2,7 @@ package hostagent
 
 import (
 	"bytes"
+	"cmp"
 	"context"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,8 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
@@ -23,6 +26,7 @@ import (
 	"github.com/lima-vm/lima/pkg/driver"
 	"github.com/lima-vm/lima/pkg/driverutil"
 	"github.com/lima-vm/lima/pkg/freeport"
+	guestagentaddress "github.com/lima-vm/lima/pkg/guestagent/address"
 	guestagentapi "github.com/lima-vm/lima/pkg/guestagent/api"
 	guestagentclient "github.com/lima-vm/lima/pkg/guestagent/api/client"
 	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
@@ -52,6 +56,7 @@ type HostAgent struct {
 	sshConfig         *ssh.SSHConfig
 	portForwarder     *portForwarder
 	grpcPortForwarder *portfwd.Forwarder
+	hostHooks         *hostHooks
 
 	onClose []func() error // LIFO
 
@@ -63,16 +68,33 @@ type HostAgent struct {
 
 	vSockPort  int
 	virtioPort string
+	// guestAgentAddress overrides the driver's connection to the guest agent; nil by default
+	guestAgentAddress *guestagentaddress.Address
 
 	clientMu sync.RWMutex
 	client   *guestagentclient.GuestAgentClient
 
 	guestAgentAliveCh     chan struct{} // closed on establishing the connection
 	guestAgentAliveChOnce sync.Once
+
+	firstBootComplete     chan struct{} // closed when cloud-init has completed, gates the port forwarding
+	firstBootCompleteOnce sync.Once
+
+	metrics *hostAgentMetrics
+
+	degradedMu      sync.RWMutex
+	degradedReasons []string
+
+	stopTimeoutMu sync.Mutex
+	stopTimeout   time.Duration // set by `limactl stop --timeout`; 0 uses the default of the driver
+
+	// sshLocalPortReservation holds sshLocalPort until the driver binds it; nil unless the port was picked automatically
+	sshLocalPortReservation *freeport.Reservation
 }
 
 type options struct {
-	nerdctlArchive string // local path, not URL
+	nerdctlArchive    string                     // local path, not URL
+	guestAgentAddress *guestagentaddress.Address // default: nil (the driver decides)
 }
 
 type Opt func(*options) error
@@ -84,10 +106,23 @@ func WithNerdctlArchive(s string) Opt {
 	}
 }
 
+// WithGuestAgentAddress connects to the guest agent at the address (see guestagentaddress.Parse),
+// e.g., "vsock://3:2222", instead of the connection provided by the driver.
+func WithGuestAgentAddress(s string) Opt {
+	return func(o *options) error {
+		addr, err := guestagentaddress.Parse(s)
+		if err != nil {
+			return err
+		}
+		o.guestAgentAddress = addr
+		return nil
+	}
+}
+
 // New creates the HostAgent.
 //
 // stdout is for emitting JSON lines of Events.
-func New(instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt) (*HostAgent, error) {
+func New(ctx context.Context, instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt) (_ *HostAgent, retErr error) {
 	var o options
 	for _, f := range opts {
 		if err := f(&o); err != nil {
@@ -100,16 +135,39 @@ func New(instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt
 	}
 
 	// inst.Config is loaded with FillDefault() already, so no need to care about nil pointers.
-	sshLocalPort, err := determineSSHLocalPort(*inst.Config.SSH.LocalPort, instName)
+	sshLocalPort, sshLocalPortReservation, err := determineSSHLocalPort(*inst.Config.SSH.LocalPort, instName)
 	if err != nil {
 		return nil, err
 	}
 	if *inst.Config.VMType == limayaml.WSL2 {
 		sshLocalPort = inst.SSHLocalPort
 	}
+	// The reservation is released on returning an error, or by Run before starting the driver
+	defer func() {
+		if retErr != nil && sshLocalPortReservation != nil {
+			_ = sshLocalPortReservation.Release()
+		}
+	}()
+
+	savedState, err := store.ReadSavedState(inst.Dir)
+	if err != nil {
+		return nil, err
+	}
+	if savedState != nil {
+		if err := checkSavedState(savedState, inst); err != nil {
+			logrus.WithError(err).Warn("Discarding the saved state")
+			if err := store.RemoveSavedState(inst.Dir); err != nil {
+				return nil, err
+			}
+			savedState = nil
+		}
+	}
 
 	var udpDNSLocalPort, tcpDNSLocalPort int
-	if *inst.Config.HostResolver.Enabled {
+	if savedState != nil {
+		// The guest of the saved state is already configured to use these ports
+		udpDNSLocalPort, tcpDNSLocalPort = savedState.UDPDNSLocalPort, savedState.TCPDNSLocalPort
+	} else if *inst.Config.HostResolver.Enabled {
 		udpDNSLocalPort, err = freeport.UDP()
 		if err != nil {
 			return nil, err
@@ -134,22 +192,49 @@ func New(instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt
 		// virtserialport doesn't seem to work reliably: https://github.com/lima-vm/lima/issues/2064
 		virtioPort = "" // filenames.VirtioPort
 	}
+	if addr := o.guestAgentAddress; addr != nil && addr.Transport == guestagentaddress.TransportVSock {
+		// The QEMU driver attaches the vsock device with the CID of `guestAgent.address` (see qemu.Cmdline)
+		if *inst.Config.VMType != limayaml.QEMU || runtime.GOOS != "linux" {
+			return nil, fmt.Errorf("the guest agent address %q is only supported for QEMU on Linux hosts", addr)
+		}
+		// Let the guest agent listen on the vsock port, instead of the socket forwarded over SSH
+		vSockPort = int(addr.Port)
+	}
 
-	if err := cidata.GenerateCloudConfig(inst.Dir, instName, inst.Config); err != nil {
-		return nil, err
+	generateCIData := func(ctx context.Context) error {
+		// The host key has to exist before generating the cidata that injects it into the guest.
+		// It is not checked until the guest is confirmed to present it (see confirmHostKey).
+		if *inst.Config.VMType != limayaml.WSL2 {
+			if created, err := sshutil.EnsureHostKey(inst.Dir); err != nil {
+				return err
+			} else if created {
+				logrus.Infof("Generated the SSH host key %q", filepath.Join(inst.Dir, filenames.SSHHostKey))
+			}
+		}
+		if err := cidata.GenerateCloudConfig(inst.Dir, instName, inst.Config); err != nil {
+			return err
+		}
+		return cidata.GenerateISO9660(ctx, inst.Dir, instName, inst.Config, udpDNSLocalPort, tcpDNSLocalPort, o.nerdctlArchive, vSockPort, virtioPort,
+			cidata.WithVolumeLabel(*inst.Config.CIData.VolumeLabel), cidata.WithVFAT(*inst.Config.CIData.VFAT))
 	}
-	if err := cidata.GenerateISO9660(inst.Dir, instName, inst.Config, udpDNSLocalPort, tcpDNSLocalPort, o.nerdctlArchive, vSockPort, virtioPort); err != nil {
-		return nil, err
+	// The cidata is not regenerated on restoring the saved state, as the guest has already consumed it.
+	// The driver regenerates it via PrepareColdBoot when it falls back to booting the vm.
+	if savedState == nil {
+		if err := generateCIData(ctx); err != nil {
+			return nil, err
+		}
 	}
 
 	sshOpts, err := sshutil.SSHOpts(
 		"ssh",
 		inst.Dir,
 		*inst.Config.User.Name,
-		*inst.Config.SSH.LoadDotSSHPubKeys,
-		*inst.Config.SSH.ForwardAgent,
-		*inst.Config.SSH.ForwardX11,
-		*inst.Config.SSH.ForwardX11Trusted)
+		sshutil.WithDotSSH(*inst.Config.SSH.LoadDotSSHPubKeys),
+		sshutil.WithForwardAgent(*inst.Config.SSH.ForwardAgent),
+		sshutil.WithForwardX11(*inst.Config.SSH.ForwardX11),
+		sshutil.WithForwardX11Trusted(*inst.Config.SSH.ForwardX11Trusted),
+		// Keeps the master connection alive while idle, as the sessions multiplexed on it do not send keepalive messages
+		sshutil.WithKeepAlive(*inst.Config.SSH.KeepAlive.Interval, *inst.Config.SSH.KeepAlive.CountMax))
 	if err != nil {
 		return nil, err
 	}
@@ -189,17 +274,25 @@ func New(instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt
 	}
 	rules = append(rules, inst.Config.PortForwards...)
 	// Default forwards for all non-privileged ports from "127.0.0.1" and "::1"
-	rule := limayaml.PortForward{}
+	rule := limayaml.PortForward{HostIP: inst.Config.PortForwardsBindAddress}
 	limayaml.FillPortForwardDefaults(&rule, inst.Dir, inst.Config.User, inst.Param)
 	rules = append(rules, rule)
 
+	if err := checkHostSockets(inst.Config.PortForwards, inst.Config.SocketForwards, inst.Dir); err != nil {
+		return nil, err
+	}
+
 	limaDriver := driverutil.CreateTargetDriverInstance(&driver.BaseDriver{
-		Instance:     inst,
-		SSHLocalPort: sshLocalPort,
-		VSockPort:    vSockPort,
-		VirtioPort:   virtioPort,
+		Instance:        inst,
+		SSHLocalPort:    sshLocalPort,
+		VSockPort:       vSockPort,
+		VirtioPort:      virtioPort,
+		RestoreState:    savedState != nil,
+		PrepareColdBoot: generateCIData,
 	})
 
+	m := newHostAgentMetrics()
+	_, dynamicRules := splitStaticPortForwards(rules)
 	a := &HostAgent{
 		instConfig:        inst.Config,
 		sshLocalPort:      sshLocalPort,
@@ -209,18 +302,36 @@ func New(instName string, stdout io.Writer, signalCh chan os.Signal, opts ...Opt
 		instName:          instName,
 		instSSHAddress:    inst.SSHAddress,
 		sshConfig:         sshConfig,
-		portForwarder:     newPortForwarder(sshConfig, sshLocalPort, rules, ignoreTCP, inst.VMType),
-		grpcPortForwarder: portfwd.NewPortForwarder(rules, ignoreTCP, ignoreUDP),
+		portForwarder:     newPortForwarder(sshConfig, sshLocalPort, rules, ignoreTCP, inst.VMType, *inst.Config.PortForwardConflict, m),
+		grpcPortForwarder: portfwd.NewPortForwarder(dynamicRules, ignoreTCP, ignoreUDP),
 		driver:            limaDriver,
 		signalCh:          signalCh,
 		eventEnc:          json.NewEncoder(stdout),
 		vSockPort:         vSockPort,
 		virtioPort:        virtioPort,
 		guestAgentAliveCh: make(chan struct{}),
+		firstBootComplete: make(chan struct{}),
+		metrics:           m,
+
+		sshLocalPortReservation: sshLocalPortReservation,
+		guestAgentAddress:       o.guestAgentAddress,
+	}
+	a.portForwarder.emitStatus = func(st events.Status) {
+		a.emitEvent(context.Background(), events.Event{Status: st})
+	}
+	a.hostHooks = newHostHooks(instName, inst.Dir, inst.Config.HostHooks, a.forwardedHostAddr)
+	if !*inst.Config.WaitForCloudInit || *inst.Config.Plain {
+		a.markFirstBootComplete()
 	}
 	return a, nil
 }
 
+func (a *HostAgent) markFirstBootComplete() {
+	a.firstBootCompleteOnce.Do(func() {
+		close(a.firstBootComplete)
+	})
+}
+
 func writeSSHConfigFile(sshPath, instName, instDir, instSSHAddress string, sshLocalPort int, sshOpts []string) error {
 	if instDir == "" {
 		return fmt.Errorf("directory is unknown for the instance %q", instName)
@@ -243,22 +354,34 @@ func writeSSHConfigFile(sshPath, instName, instDir, instSSHAddress string, sshLo
 	return os.WriteFile(fileName, b.Bytes(), 0o600)
 }
 
-func determineSSHLocalPort(confLocalPort int, instName string) (int, error) {
+// determineSSHLocalPort returns the ssh local port.
+// When the port is picked automatically, the port is reserved until the returned reservation is released.
+func determineSSHLocalPort(confLocalPort int, instName string) (int, *freeport.Reservation, error) {
 	if confLocalPort > 0 {
-		return confLocalPort, nil
+		return confLocalPort, nil, nil
 	}
 	if confLocalPort < 0 {
-		return 0, fmt.Errorf("invalid ssh local port %d", confLocalPort)
+		return 0, nil, fmt.Errorf("invalid ssh local port %d", confLocalPort)
 	}
 	if instName == "default" {
 		// use hard-coded value for "default" instance, for backward compatibility
-		return 60022, nil
+		return 60022, nil, nil
 	}
-	sshLocalPort, err := freeport.TCP()
+	r, err := freeport.ReserveTCP()
 	if err != nil {
-		return 0, fmt.Errorf("failed to find a free port, try setting `ssh.localPort` manually: %w", err)
+		return 0, nil, fmt.Errorf("failed to find a free port, try setting `ssh.localPort` manually: %w", err)
+	}
+	return r.Port, r, nil
+}
+
+// releaseSSHLocalPort releases the reservation of the ssh local port, so that the driver can bind it.
+func (a *HostAgent) releaseSSHLocalPort() {
+	if a.sshLocalPortReservation == nil {
+		return
+	}
+	if err := a.sshLocalPortReservation.Release(); err != nil {
+		logrus.WithError(err).Warnf("failed to release the reservation of the ssh local port %d", a.sshLocalPort)
 	}
-	return sshLocalPort, nil
 }
 
 func (a *HostAgent) emitEvent(_ context.Context, ev events.Event) {
@@ -309,6 +432,7 @@ func (a *HostAgent) Run(ctx context.Context) error {
 		defer dnsServer.Shutdown()
 	}
 
+	a.releaseSSHLocalPort()
 	errCh, err := a.driver.Start(ctx)
 	if err != nil {
 		return err
@@ -390,9 +514,13 @@ func (a *HostAgent) startRoutinesAndWait(ctx context.Context, errCh <-chan error
 		stRunning := stBase
 		if haErr := a.startHostAgentRoutines(ctxHA); haErr != nil {
 			stRunning.Degraded = true
+			stRunning.DegradedReasons = a.DegradedReasons()
 			stRunning.Errors = append(stRunning.Errors, haErr.Error())
 		}
 		stRunning.Running = true
+		if info, err := a.Info(ctx); err == nil {
+			stRunning.CloudInitDuration = info.CloudInitDuration
+		}
 		a.emitEvent(ctx, events.Event{Status: stRunning})
 	}()
 	for {
@@ -403,7 +531,8 @@ func (a *HostAgent) startRoutinesAndWait(ctx context.Context, errCh <-chan error
 			if closeErr := a.close(); closeErr != nil {
 				logrus.WithError(closeErr).Warn("an error during shutting down the host agent")
 			}
-			err := a.driver.Stop(ctx)
+			// The vm is no longer running, so there is nothing to shut down gracefully
+			err := a.driver.Stop(ctx, true)
 			return err
 		case sig := <-a.signalCh:
 			logrus.Infof("Received %s, shutting down the host agent", osutil.SignalName(sig))
@@ -411,19 +540,189 @@ func (a *HostAgent) startRoutinesAndWait(ctx context.Context, errCh <-chan error
 			if closeErr := a.close(); closeErr != nil {
 				logrus.WithError(closeErr).Warn("an error during shutting down the host agent")
 			}
-			err := a.driver.Stop(ctx)
+			stopCtx := ctx
+			if timeout := a.StopTimeout(); timeout > 0 {
+				var cancel context.CancelFunc
+				stopCtx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			err := a.driver.Stop(stopCtx, false)
 			return err
 		}
 	}
 }
 
-func (a *HostAgent) Info(_ context.Context) (*hostagentapi.Info, error) {
+func (a *HostAgent) Info(ctx context.Context) (*hostagentapi.Info, error) {
 	info := &hostagentapi.Info{
 		SSHLocalPort: a.sshLocalPort,
+		PortForwards: a.portForwards(),
+	}
+	reasons := a.DegradedReasons()
+	runState, err := a.driver.RunState(ctx)
+	if err != nil {
+		logrus.WithError(err).Debug("failed to get the run state of the vm")
+	} else {
+		info.VMRunState = runState
+		if reason := runStateDegradedReason(runState); reason != "" {
+			reasons = append(reasons, reason)
+		}
+	}
+	if len(reasons) > 0 {
+		info.Degraded = true
+		info.DegradedReasons = reasons
+	}
+	a.clientMu.RLock()
+	client := a.client
+	a.clientMu.RUnlock()
+	if client != nil {
+		guestInfo, err := client.Info(ctx)
+		if err != nil {
+			logrus.WithError(err).Debug("failed to get the mount status from the guest agent")
+		} else {
+			info.Mounts = a.mountStatuses(guestInfo.Mounts)
+			if guestInfo.BootTime != nil {
+				bootTime := guestInfo.BootTime.AsTime()
+				info.BootTime = &bootTime
+			}
+			info.CloudInitDuration = guestInfo.CloudInitDuration.AsDuration()
+		}
 	}
 	return info, nil
 }
 
+// Networks returns the network interfaces in the config, with the addresses reported by the guest agent.
+func (a *HostAgent) Networks(ctx context.Context) ([]hostagentapi.Network, error) {
+	nws := ConfiguredNetworks(a.instDir, a.instConfig)
+	a.clientMu.RLock()
+	client := a.client
+	a.clientMu.RUnlock()
+	if client != nil {
+		guestInfo, err := client.Info(ctx)
+		if err != nil {
+			logrus.WithError(err).Debug("failed to get the network interfaces from the guest agent")
+		} else {
+			fillNetworkAddresses(nws, guestInfo.Interfaces)
+		}
+	}
+	return nws, nil
+}
+
+// ConfiguredNetworks returns the network interfaces configured by cidata, without the addresses.
+// The MAC addresses are stable as long as the instance directory and the config are not changed.
+func ConfiguredNetworks(instDir string, y *limayaml.LimaYAML) []hostagentapi.Network {
+	res := []hostagentapi.Network{
+		{Interface: networks.SlirpNICName, MACAddress: limayaml.MACAddress(instDir)},
+	}
+	firstUsernetIndex := limayaml.FirstUsernetIndex(y)
+	for i, nw := range y.Networks {
+		if i == firstUsernetIndex {
+			continue
+		}
+		res = append(res, hostagentapi.Network{
+			Interface:  nw.Interface,
+			MACAddress: nw.MACAddress,
+			Lima:       nw.Lima,
+			Socket:     nw.Socket,
+		})
+	}
+	return res
+}
+
+// fillNetworkAddresses sets the addresses of the guest interfaces to nws.
+// The interfaces are matched by the MAC address, or by the name when no interface has the MAC address.
+func fillNetworkAddresses(nws []hostagentapi.Network, ifaces []*guestagentapi.NetworkInterface) {
+	for i := range nws {
+		idx := slices.IndexFunc(ifaces, func(iface *guestagentapi.NetworkInterface) bool {
+			return nws[i].MACAddress != "" && strings.EqualFold(iface.MacAddress, nws[i].MACAddress)
+		})
+		if idx < 0 {
+			idx = slices.IndexFunc(ifaces, func(iface *guestagentapi.NetworkInterface) bool {
+				return iface.Name == nws[i].Interface
+			})
+		}
+		if idx >= 0 {
+			nws[i].Addresses = ifaces[idx].Addresses
+		}
+	}
+}
+
+// ReadOnly returns true when the instance is read-only (`readOnly` in lima.yaml),
+// so that the API server refuses to change the driver config.
+func (a *HostAgent) ReadOnly() bool {
+	return *a.instConfig.ReadOnly
+}
+
+// DriverRuntimeConfig applies the non-nil fields of config to the driver, and returns the effective runtime config.
+func (a *HostAgent) DriverRuntimeConfig(ctx context.Context, config hostagentapi.DriverConfig) (hostagentapi.DriverConfig, error) {
+	if err := config.Validate(); err != nil {
+		return hostagentapi.DriverConfig{}, err
+	}
+	return a.driver.RuntimeConfig(ctx, config)
+}
+
+// SaveState saves the vm state to the instance directory, along with the metadata for restoring it on the next start.
+func (a *HostAgent) SaveState(ctx context.Context) error {
+	if err := a.driver.SaveState(ctx); err != nil {
+		return err
+	}
+	savedState, err := store.NewSavedState(a.instDir, a.instConfig, a.udpDNSLocalPort, a.tcpDNSLocalPort)
+	if err != nil {
+		return err
+	}
+	return store.WriteSavedState(a.instDir, savedState)
+}
+
+// SetStopTimeout sets the duration to wait for the guest to shut down gracefully on the next interrupt,
+// before the driver stops the vm forcibly.
+func (a *HostAgent) SetStopTimeout(timeout time.Duration) {
+	a.stopTimeoutMu.Lock()
+	defer a.stopTimeoutMu.Unlock()
+	a.stopTimeout = timeout
+}
+
+func (a *HostAgent) StopTimeout() time.Duration {
+	a.stopTimeoutMu.Lock()
+	defer a.stopTimeoutMu.Unlock()
+	return a.stopTimeout
+}
+
+// mountStatuses returns the status of the mounts in the config, as reported by the guest agent.
+func (a *HostAgent) mountStatuses(guestMounts []*guestagentapi.MountStatus) []hostagentapi.MountStatus {
+	var res []hostagentapi.MountStatus
+	for _, m := range a.instConfig.Mounts {
+		if m.MountPoint == nil {
+			continue
+		}
+		for _, gm := range guestMounts {
+			if gm.Path == *m.MountPoint {
+				res = append(res, hostagentapi.MountStatus{
+					Path:     gm.Path,
+					Type:     gm.Type,
+					ReadOnly: gm.ReadOnly,
+				})
+			}
+		}
+	}
+	return res
+}
+
+// confirmHostKey writes the known_hosts file of the instance when the guest presents the injected host key,
+// so that the host key is strictly checked from the next start.
+func (a *HostAgent) confirmHostKey(ctx context.Context) {
+	if _, err := os.Stat(filepath.Join(a.instDir, filenames.SSHKnownHosts)); err == nil {
+		return
+	}
+	confirmed, err := sshutil.ConfirmHostKey(ctx, a.instDir, a.instSSHAddress, a.sshLocalPort)
+	switch {
+	case err != nil:
+		logrus.WithError(err).Warn("Failed to confirm the SSH host key of the guest")
+	case confirmed:
+		logrus.Info("The guest presents the SSH host key of the instance, so it will be strictly checked from the next start")
+	default:
+		logrus.Warn("The guest does not present the SSH host key injected via cidata (e.g., the image ignores `ssh_keys` of cloud-config), so the host key is not checked")
+	}
+}
+
 func (a *HostAgent) startHostAgentRoutines(ctx context.Context) error {
 	if *a.instConfig.Plain {
 		logrus.Info("Running in plain mode. Mounts, port forwarding, containerd, etc. will be ignored. Guest agent will not be running.")
@@ -438,6 +737,10 @@ func (a *HostAgent) startHostAgentRoutines(ctx context.Context) error {
 	var errs []error
 	if err := a.waitForRequirements("essential", a.essentialRequirements()); err != nil {
 		errs = append(errs, err)
+		a.addDegradedReason("essential requirements not satisfied")
+	}
+	if *a.instConfig.VMType != limayaml.WSL2 {
+		a.confirmHostKey(ctx)
 	}
 	if *a.instConfig.SSH.ForwardAgent {
 		faScript := `#!/bin/bash
@@ -450,12 +753,14 @@ sudo chown -R "${USER}" /run/host-services`
 		logrus.Debugf("stdout=%q, stderr=%q, err=%v", stdout, stderr, err)
 		if err != nil {
 			errs = append(errs, fmt.Errorf("stdout=%q, stderr=%q: %w", stdout, stderr, err))
+			a.addDegradedReason("ssh agent forwarding failed")
 		}
 	}
 	if *a.instConfig.MountType == limayaml.REVSSHFS && !*a.instConfig.Plain {
 		mounts, err := a.setupMounts()
 		if err != nil {
 			errs = append(errs, err)
+			a.addDegradedReason("sshfs mount failed")
 		}
 		a.onClose = append(a.onClose, func() error {
 			var unmountErrs []error
@@ -477,7 +782,11 @@ sudo chown -R "${USER}" /run/host-services`
 					continue
 				}
 				logrus.Infof("Unmounting disk %q", disk.Name)
-				if unlockErr := disk.Unlock(); unlockErr != nil {
+				unlock := disk.Unlock
+				if d.ReadOnly != nil && *d.ReadOnly {
+					unlock = func() error { return disk.UnlockReadOnly(a.instName) }
+				}
+				if unlockErr := unlock(); unlockErr != nil {
 					unlockErrs = append(unlockErrs, unlockErr)
 				}
 			}
@@ -489,6 +798,7 @@ sudo chown -R "${USER}" /run/host-services`
 	}
 	if err := a.waitForRequirements("optional", a.optionalRequirements()); err != nil {
 		errs = append(errs, err)
+		a.addDegradedReason("optional requirements not satisfied")
 	}
 	if !*a.instConfig.Plain {
 		logrus.Info("Waiting for the guest agent to be running")
@@ -497,15 +807,26 @@ sudo chown -R "${USER}" /run/host-services`
 			// NOP
 		case <-time.After(time.Minute):
 			errs = append(errs, errors.New("guest agent does not seem to be running; port forwards will not work"))
+			a.addDegradedReason("guest agent unreachable")
 		}
 	}
+	if *a.instConfig.WaitForCloudInit && !*a.instConfig.Plain {
+		if err := a.waitForRequirements("first boot", a.firstBootRequirements()); err != nil {
+			errs = append(errs, err)
+			a.addDegradedReason("cloud-init did not complete")
+		}
+		// Forward the ports even when cloud-init did not complete in time, as the instance is still usable
+		a.markFirstBootComplete()
+	}
 	if err := a.waitForRequirements("final", a.finalRequirements()); err != nil {
 		errs = append(errs, err)
+		a.addDegradedReason("final requirements not satisfied")
 	}
 	// Copy all config files _after_ the requirements are done
 	for _, rule := range a.instConfig.CopyToHost {
 		if err := copyToHost(ctx, a.sshConfig, a.sshLocalPort, rule.HostFile, rule.GuestFile); err != nil {
 			errs = append(errs, err)
+			a.addDegradedReason(fmt.Sprintf("copying %q to the host failed", rule.GuestFile))
 		}
 	}
 	a.onClose = append(a.onClose, func() error {
@@ -523,6 +844,32 @@ sudo chown -R "${USER}" /run/host-services`
 	return errors.Join(errs...)
 }
 
+// addDegradedReason records the reason why the instance is degraded.
+func (a *HostAgent) addDegradedReason(reason string) {
+	a.degradedMu.Lock()
+	defer a.degradedMu.Unlock()
+	a.degradedReasons = append(a.degradedReasons, reason)
+}
+
+// DegradedReasons returns the reasons why the instance is degraded.
+// Empty unless the instance is degraded.
+func (a *HostAgent) DegradedReasons() []string {
+	a.degradedMu.RLock()
+	defer a.degradedMu.RUnlock()
+	return slices.Clone(a.degradedReasons)
+}
+
+// runStateDegradedReason returns the degraded reason for the run state of the vm,
+// or an empty string if the run state is unknown, "running", or "paused".
+// The paused vm is not degraded, as it is reported as store.StatusPaused.
+func runStateDegradedReason(runState string) string {
+	switch runState {
+	case "", hostagentapi.VMRunStateRunning, hostagentapi.VMRunStatePaused:
+		return ""
+	}
+	return fmt.Sprintf("vm is not running (run state: %q)", runState)
+}
+
 func (a *HostAgent) close() error {
 	logrus.Infof("Shutting down the host agent")
 	var errs []error
@@ -547,6 +894,15 @@ func (a *HostAgent) watchGuestAgentEvents(ctx context.Context) {
 				_ = forwardSSH(ctx, a.sshConfig, a.sshLocalPort, local, rule.GuestSocket, verbForward, rule.Reverse)
 			}
 		}
+		for _, rule := range a.instConfig.SocketForwards {
+			_ = forwardSSH(ctx, a.sshConfig, a.sshLocalPort, rule.HostSocket, rule.GuestSocket, verbForward, false)
+		}
+		// The static port forwards are always set up with SSH, without waiting for the guest agent events
+		go a.portForwarder.ForwardStatic(ctx)
+	}
+
+	if len(a.instConfig.HostHooks) > 0 {
+		go a.hostHooks.Run(ctx)
 	}
 
 	localUnix := filepath.Join(a.instDir, filenames.GuestAgentSock)
@@ -564,6 +920,12 @@ func (a *HostAgent) watchGuestAgentEvents(ctx context.Context) {
 				}
 			}
 		}
+		for _, rule := range a.instConfig.SocketForwards {
+			// forwardSSH also removes the host socket
+			if err := forwardSSH(context.Background(), a.sshConfig, a.sshLocalPort, rule.HostSocket, rule.GuestSocket, verbCancel, false); err != nil {
+				errs = append(errs, err)
+			}
+		}
 		if a.driver.ForwardGuestAgent() {
 			if err := forwardSSH(context.Background(), a.sshConfig, a.sshLocalPort, localUnix, remoteUnix, verbCancel, false); err != nil {
 				errs = append(errs, err)
@@ -629,6 +991,9 @@ func (a *HostAgent) getOrCreateClient(ctx context.Context) (*guestagentclient.Gu
 }
 
 func (a *HostAgent) createConnection(ctx context.Context) (net.Conn, error) {
+	if a.guestAgentAddress != nil {
+		return guestagentclient.DialAddress(ctx, a.guestAgentAddress)
+	}
 	conn, err := a.driver.GuestAgentConn(ctx)
 	// default to forwarded sock
 	if conn == nil && err == nil {
@@ -638,39 +1003,84 @@ func (a *HostAgent) createConnection(ctx context.Context) (net.Conn, error) {
 	return conn, err
 }
 
+// useSSHPortForwarder returns whether the guest ports are forwarded over SSH, instead of over the guest agent connection.
+func useSSHPortForwarder() bool {
+	// useSSHFwd was false by default in v1.0, but reverted to true by default in v1.0.1
+	// due to stability issues
+	useSSHFwd := true
+	if envVar := os.Getenv("LIMA_SSH_PORT_FORWARDER"); envVar != "" {
+		b, err := strconv.ParseBool(os.Getenv("LIMA_SSH_PORT_FORWARDER"))
+		if err != nil {
+			logrus.WithError(err).Warnf("invalid LIMA_SSH_PORT_FORWARDER value %q", envVar)
+		} else {
+			useSSHFwd = b
+		}
+	}
+	return useSSHFwd
+}
+
+// portForwards returns the port forwards with their status, sorted by the guest address and the protocol.
+// The static port forwards are always set up by the SSH port forwarder, even when the other ports are
+// forwarded over the guest agent connection.
+func (a *HostAgent) portForwards() []hostagentapi.PortForward {
+	res := a.portForwarder.PortForwards()
+	if !useSSHPortForwarder() {
+		res = append(res, a.grpcPortForwarder.PortForwards()...)
+		slices.SortFunc(res, func(a, b hostagentapi.PortForward) int {
+			return cmp.Or(strings.Compare(a.GuestAddr, b.GuestAddr), strings.Compare(a.Protocol, b.Protocol))
+		})
+	}
+	return res
+}
+
+// forwardedHostAddr returns the host address that the guest port is forwarded to, or "".
+func (a *HostAgent) forwardedHostAddr(port *guestagentapi.IPPort) string {
+	if useSSHPortForwarder() {
+		// The SSH port forwarder does not forward UDP
+		if port.Protocol != "tcp" {
+			return ""
+		}
+		return a.portForwarder.hostAddr(port.HostString())
+	}
+	return a.grpcPortForwarder.HostAddress(port)
+}
+
 func (a *HostAgent) processGuestAgentEvents(ctx context.Context, client *guestagentclient.GuestAgentClient) error {
 	info, err := client.Info(ctx)
 	if err != nil {
 		return err
 	}
 	logrus.Info("Guest agent is running")
+	a.metrics.guestAgentConnections.Inc()
 	a.guestAgentAliveChOnce.Do(func() {
 		close(a.guestAgentAliveCh)
 	})
 
 	logrus.Debugf("guest agent info: %+v", info)
 
+	select {
+	case <-a.firstBootComplete:
+	default:
+		logrus.Info("Waiting for cloud-init to complete before forwarding ports")
+		select {
+		case <-a.firstBootComplete:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
 	onEvent := func(ev *guestagentapi.Event) {
 		logrus.Debugf("guest agent event: %+v", ev)
+		a.metrics.observeGuestEvent(ev)
 		for _, f := range ev.Errors {
 			logrus.Warnf("received error from the guest: %q", f)
 		}
-		// useSSHFwd was false by default in v1.0, but reverted to true by default in v1.0.1
-		// due to stability issues
-		useSSHFwd := true
-		if envVar := os.Getenv("LIMA_SSH_PORT_FORWARDER"); envVar != "" {
-			b, err := strconv.ParseBool(os.Getenv("LIMA_SSH_PORT_FORWARDER"))
-			if err != nil {
-				logrus.WithError(err).Warnf("invalid LIMA_SSH_PORT_FORWARDER value %q", envVar)
-			} else {
-				useSSHFwd = b
-			}
-		}
-		if useSSHFwd {
+		if useSSHPortForwarder() {
 			a.portForwarder.OnEvent(ctx, ev)
 		} else {
 			a.grpcPortForwarder.OnEvent(ctx, client, ev)
 		}
+		a.hostHooks.OnEvent(ev)
 	}
 
 	if err := client.Events(ctx, onEvent); err != nil {
@@ -702,6 +1112,61 @@ func executeSSH(ctx context.Context, sshConfig *ssh.SSHConfig, port int, command
 	return nil
 }
 
+// checkSavedState returns an error when the saved state cannot be restored,
+// including when the ports of the host resolver embedded in the guest are no longer free.
+func checkSavedState(savedState *store.SavedState, inst *store.Instance) error {
+	if err := savedState.Check(inst.Dir, inst.Config); err != nil {
+		return err
+	}
+	if savedState.UDPDNSLocalPort != 0 {
+		if err := freeport.CheckUDP(savedState.UDPDNSLocalPort); err != nil {
+			return fmt.Errorf("the UDP port %d of the host resolver is not available: %w", savedState.UDPDNSLocalPort, err)
+		}
+	}
+	if savedState.TCPDNSLocalPort != 0 {
+		if err := freeport.CheckTCP(savedState.TCPDNSLocalPort); err != nil {
+			return fmt.Errorf("the TCP port %d of the host resolver is not available: %w", savedState.TCPDNSLocalPort, err)
+		}
+	}
+	return nil
+}
+
+// checkHostSockets checks that the parent directories of the host sockets of
+// the port forwarding rules and the socket forwarding rules exist.
+func checkHostSockets(portForwards []limayaml.PortForward, socketForwards []limayaml.SocketForward, instDir string) error {
+	for i, rule := range portForwards {
+		if rule.HostSocket == "" {
+			continue
+		}
+		if err := checkHostSocketDir(rule.HostSocket, instDir); err != nil {
+			return fmt.Errorf("field `portForwards[%d].hostSocket`: %w", i, err)
+		}
+	}
+	for i, rule := range socketForwards {
+		if err := checkHostSocketDir(rule.HostSocket, instDir); err != nil {
+			return fmt.Errorf("field `socketForwards[%d].hostSocket`: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// checkHostSocketDir checks that the parent directory of the host socket exists.
+// The directories inside the instance directory are created on demand by forwardSSH.
+func checkHostSocketDir(hostSocket, instDir string) error {
+	dir := filepath.Dir(hostSocket)
+	if rel, err := filepath.Rel(instDir, dir); err == nil && filepath.IsLocal(rel) {
+		return nil
+	}
+	st, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("parent directory %q does not exist: %w", dir, err)
+	}
+	if !st.IsDir() {
+		return fmt.Errorf("%q is not a directory", dir)
+	}
+	return nil
+}
+
 func forwardSSH(ctx context.Context, sshConfig *ssh.SSHConfig, port int, local, remote, verb string, reverse bool) error {
 	args := sshConfig.Args()
 	args = append(args,
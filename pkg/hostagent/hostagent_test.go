@@ -0,0 +1,74 @@
+package hostagent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	guestagentapi "github.com/lima-vm/lima/pkg/guestagent/api"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckHostSockets(t *testing.T) {
+	instDir := t.TempDir()
+	hostDir := t.TempDir()
+	file := filepath.Join(hostDir, "file")
+	assert.NilError(t, os.WriteFile(file, nil, 0o600))
+
+	validPortForwards := []limayaml.PortForward{
+		{GuestPort: 80},
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(hostDir, "docker.sock")},
+	}
+	validSocketForwards := []limayaml.SocketForward{
+		// created on demand inside the instance directory
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(instDir, "sock", "docker.sock")},
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(hostDir, "docker.sock")},
+	}
+	assert.NilError(t, checkHostSockets(validPortForwards, validSocketForwards, instDir))
+
+	missingPortForwards := []limayaml.PortForward{
+		{GuestPort: 80},
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(hostDir, "missing", "docker.sock")},
+	}
+	assert.ErrorContains(t, checkHostSockets(missingPortForwards, nil, instDir), "field `portForwards[1].hostSocket`: parent directory")
+
+	missingSocketForwards := []limayaml.SocketForward{
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(hostDir, "missing", "docker.sock")},
+	}
+	assert.ErrorContains(t, checkHostSockets(validPortForwards, missingSocketForwards, instDir), "field `socketForwards[0].hostSocket`: parent directory")
+
+	notDir := []limayaml.SocketForward{
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(hostDir, "docker.sock")},
+		{GuestSocket: "/run/docker.sock", HostSocket: filepath.Join(file, "docker.sock")},
+	}
+	assert.ErrorContains(t, checkHostSockets(nil, notDir, instDir), fmt.Sprintf("field `socketForwards[1].hostSocket`: %q is not a directory", file))
+}
+
+func TestFillNetworkAddresses(t *testing.T) {
+	nws := []hostagentapi.Network{
+		{Interface: "eth0", MACAddress: "52:55:55:12:34:56"},
+		{Interface: "lima0", MACAddress: "52:55:55:12:34:57"},
+		{Interface: "lima1", MACAddress: "52:55:55:12:34:58"},
+	}
+	ifaces := []*guestagentapi.NetworkInterface{
+		// the interface may be renamed by the guest OS
+		{Name: "enp0s1", MacAddress: "52:55:55:12:34:56", Addresses: []string{"192.168.5.15/24"}},
+		{Name: "lima0", MacAddress: "52:55:55:AB:CD:EF", Addresses: []string{"192.168.105.2/24"}},
+		{Name: "lima2", MacAddress: "52:55:55:12:34:57", Addresses: []string{"192.168.106.2/24", "fd00::2/64"}},
+	}
+	fillNetworkAddresses(nws, ifaces)
+	assert.DeepEqual(t, nws[0].Addresses, []string{"192.168.5.15/24"})
+	// the MAC address takes precedence over the name
+	assert.DeepEqual(t, nws[1].Addresses, []string{"192.168.106.2/24", "fd00::2/64"})
+	assert.Equal(t, len(nws[2].Addresses), 0)
+}
+
+func TestRunStateDegradedReason(t *testing.T) {
+	assert.Equal(t, runStateDegradedReason(""), "")
+	assert.Equal(t, runStateDegradedReason(hostagentapi.VMRunStateRunning), "")
+	assert.Equal(t, runStateDegradedReason(hostagentapi.VMRunStatePaused), "")
+	assert.Equal(t, runStateDegradedReason("io-error"), `vm is not running (run state: "io-error")`)
+}
@@ -3,6 +3,7 @@
 package hostagent
 
 import (
+	"errors"
 	"syscall"
 
 	"github.com/sirupsen/logrus"
@@ -26,3 +27,7 @@ func adjustNofileRlimit() {
 		}
 	}
 }
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
@@ -2,4 +2,14 @@
 
 package hostagent
 
+import (
+	"errors"
+
+	"golang.org/x/sys/windows"
+)
+
 func adjustNofileRlimit() {}
+
+func isAddrInUse(err error) bool {
+	return errors.Is(err, windows.WSAEADDRINUSE)
+}
@@ -0,0 +1,62 @@
+package hostagent
+
+import (
+	"time"
+
+	guestagentapi "github.com/lima-vm/lima/pkg/guestagent/api"
+	"github.com/lima-vm/lima/pkg/hostagent/metrics"
+)
+
+// hostAgentMetrics is exposed via GET /metrics and /v1/metrics, when enabled with `hostAgentMetrics` in lima.yaml
+// or LIMA_HOSTAGENT_METRICS.
+type hostAgentMetrics struct {
+	registry              *metrics.Registry
+	guestAgentConnections *metrics.Counter
+	guestEvents           *metrics.Counter
+	guestEventErrors      *metrics.Counter
+	guestPortsAdded       *metrics.Counter
+	guestPortsRemoved     *metrics.Counter
+	guestIPTablesRefresh  *metrics.Counter
+	portForwards          *metrics.Gauge
+	portForwardErrors     *metrics.Counter
+}
+
+func newHostAgentMetrics() *hostAgentMetrics {
+	r := metrics.NewRegistry()
+	startTime := time.Now()
+	r.NewGaugeFunc("lima_hostagent_start_time_seconds", "Start time of the host agent since the Unix epoch in seconds.", func() int64 {
+		return startTime.Unix()
+	})
+	r.NewGaugeFunc("lima_hostagent_uptime_seconds", "Uptime of the host agent in seconds.", func() int64 {
+		return int64(time.Since(startTime).Seconds())
+	})
+	return &hostAgentMetrics{
+		registry:              r,
+		guestAgentConnections: r.NewCounter("lima_hostagent_guest_agent_connections_total", "Number of the connections established to the guest agent, including the reconnections."),
+		guestEvents:           r.NewCounter("lima_hostagent_guest_events_total", "Number of the events received from the guest agent."),
+		guestEventErrors:      r.NewCounter("lima_hostagent_guest_event_errors_total", "Number of the errors reported in the events from the guest agent."),
+		guestPortsAdded:       r.NewCounter("lima_hostagent_guest_ports_added_total", "Number of the guest local ports reported as added."),
+		guestPortsRemoved:     r.NewCounter("lima_hostagent_guest_ports_removed_total", "Number of the guest local ports reported as removed."),
+		guestIPTablesRefresh:  r.NewCounter("lima_hostagent_guest_iptables_refreshes_total", "Number of the times the guest agent read the iptables rules, as reported in the events."),
+		portForwards:          r.NewGauge("lima_hostagent_port_forwards", "Number of the active TCP port forwards of the SSH port forwarder."),
+		portForwardErrors:     r.NewCounter("lima_hostagent_port_forward_errors_total", "Number of the failures to set up or stop TCP port forwards of the SSH port forwarder."),
+	}
+}
+
+func (m *hostAgentMetrics) observeGuestEvent(ev *guestagentapi.Event) {
+	m.guestEvents.Inc()
+	m.guestEventErrors.Add(uint64(len(ev.Errors)))
+	m.guestPortsAdded.Add(uint64(len(ev.LocalPortsAdded)))
+	m.guestPortsRemoved.Add(uint64(len(ev.LocalPortsRemoved)))
+	m.guestIPTablesRefresh.Add(ev.IptablesRefreshes)
+}
+
+// Metrics returns the metrics registry of the host agent.
+func (a *HostAgent) Metrics() *metrics.Registry {
+	return a.metrics.registry
+}
+
+// MetricsEnabled returns whether the metrics are enabled with `hostAgentMetrics` in lima.yaml.
+func (a *HostAgent) MetricsEnabled() bool {
+	return *a.instConfig.HostAgentMetrics
+}
@@ -0,0 +1,124 @@
+// Package metrics implements minimal counters and gauges exposed in the Prometheus text format.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"sync/atomic"
+)
+
+type Type = string
+
+const (
+	TypeCounter Type = "counter"
+	TypeGauge   Type = "gauge"
+)
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v atomic.Uint64
+}
+
+func (c *Counter) Inc() {
+	c.v.Add(1)
+}
+
+func (c *Counter) Add(n uint64) {
+	c.v.Add(n)
+}
+
+func (c *Counter) Value() uint64 {
+	return c.v.Load()
+}
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	v atomic.Int64
+}
+
+func (g *Gauge) Set(n int64) {
+	g.v.Store(n)
+}
+
+func (g *Gauge) Inc() {
+	g.v.Add(1)
+}
+
+func (g *Gauge) Dec() {
+	g.v.Add(-1)
+}
+
+func (g *Gauge) Value() int64 {
+	return g.v.Load()
+}
+
+type metric struct {
+	name  string
+	help  string
+	typ   Type
+	value func() string
+}
+
+// Registry holds the metrics in the order of registration.
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	names   map[string]struct{}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		names: make(map[string]struct{}),
+	}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.names[m.name]; ok {
+		panic(fmt.Sprintf("metric %q is already registered", m.name))
+	}
+	r.names[m.name] = struct{}{}
+	r.metrics = append(r.metrics, m)
+}
+
+// NewCounter registers a new counter.
+// The name should have the "_total" suffix, by the Prometheus convention.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	c := &Counter{}
+	r.register(metric{name: name, help: help, typ: TypeCounter, value: func() string {
+		return fmt.Sprintf("%d", c.Value())
+	}})
+	return c
+}
+
+// NewGauge registers a new gauge.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	g := &Gauge{}
+	r.register(metric{name: name, help: help, typ: TypeGauge, value: func() string {
+		return fmt.Sprintf("%d", g.Value())
+	}})
+	return g
+}
+
+// NewGaugeFunc registers a new gauge whose value is computed by f on every scrape.
+func (r *Registry) NewGaugeFunc(name, help string, f func() int64) {
+	r.register(metric{name: name, help: help, typ: TypeGauge, value: func() string {
+		return fmt.Sprintf("%d", f())
+	}})
+}
+
+// Write writes the metrics in the Prometheus text format.
+// https://prometheus.io/docs/instrumenting/exposition_formats/#text-based-format
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	metrics := r.metrics
+	r.mu.Unlock()
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", m.name, m.help, m.name, m.typ, m.name, m.value()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
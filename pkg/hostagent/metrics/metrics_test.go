@@ -0,0 +1,42 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("foo_total", "Number of foos.")
+	g := r.NewGauge("bar", "Current bars.")
+	baz := int64(7)
+	r.NewGaugeFunc("baz", "Computed baz.", func() int64 { return baz })
+	c.Inc()
+	c.Add(2)
+	g.Set(5)
+	g.Dec()
+
+	var buf bytes.Buffer
+	assert.NilError(t, r.Write(&buf))
+	assert.Equal(t, buf.String(), `# HELP foo_total Number of foos.
+# TYPE foo_total counter
+foo_total 3
+# HELP bar Current bars.
+# TYPE bar gauge
+bar 4
+# HELP baz Computed baz.
+# TYPE baz gauge
+baz 7
+`)
+}
+
+func TestRegistryDuplicate(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("foo_total", "Number of foos.")
+	defer func() {
+		assert.Assert(t, recover() != nil)
+	}()
+	r.NewGauge("foo_total", "Number of foos.")
+}
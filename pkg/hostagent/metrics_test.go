@@ -0,0 +1,35 @@
+package hostagent
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	guestagentapi "github.com/lima-vm/lima/pkg/guestagent/api"
+	"gotest.tools/v3/assert"
+)
+
+func TestObserveGuestEvent(t *testing.T) {
+	m := newHostAgentMetrics()
+	m.observeGuestEvent(&guestagentapi.Event{
+		LocalPortsAdded: []*guestagentapi.IPPort{
+			{Ip: "127.0.0.1", Port: 8080, Protocol: "tcp"},
+			{Ip: "127.0.0.1", Port: 8443, Protocol: "tcp"},
+		},
+		Errors:            []string{"partial scan"},
+		IptablesRefreshes: 3,
+	})
+
+	var buf bytes.Buffer
+	assert.NilError(t, m.registry.Write(&buf))
+	for _, line := range []string{
+		"lima_hostagent_guest_events_total 1",
+		"lima_hostagent_guest_event_errors_total 1",
+		"lima_hostagent_guest_ports_added_total 2",
+		"lima_hostagent_guest_ports_removed_total 0",
+		"lima_hostagent_guest_iptables_refreshes_total 3",
+		"lima_hostagent_uptime_seconds 0",
+	} {
+		assert.Assert(t, strings.Contains(buf.String(), line+"\n"), buf.String())
+	}
+}
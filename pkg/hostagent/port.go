@@ -2,9 +2,18 @@ package hostagent
 
 import (
 	"context"
+	"fmt"
 	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 
+	"github.com/lima-vm/lima/pkg/freeport"
 	"github.com/lima-vm/lima/pkg/guestagent/api"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/hostagent/events"
 	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/lima-vm/sshocker/pkg/ssh"
 	"github.com/sirupsen/logrus"
@@ -14,21 +23,157 @@ type portForwarder struct {
 	sshConfig   *ssh.SSHConfig
 	sshHostPort int
 	rules       []limayaml.PortForward
+	staticRules []limayaml.PortForward
 	ignore      bool
 	vmType      limayaml.VMType
+	metrics     *hostAgentMetrics
+
+	conflictPolicy limayaml.PortForwardConflictPolicy
+	addrInUse      func(addr string) bool
+	freePort       func(ip string) (int, error)
+	forwardTCP     func(ctx context.Context, local, remote, verb string) error
+	// emitStatus emits the remapped port forwards and the conflict errors to the event stream.
+	emitStatus func(events.Status)
+
+	forwardsMu sync.Mutex
+	forwards   map[string]hostagentapi.PortForward // keyed by the guest address, including the failed and skipped ones
 }
 
 const sshGuestPort = 22
 
 var IPv4loopback1 = limayaml.IPv4loopback1
 
-func newPortForwarder(sshConfig *ssh.SSHConfig, sshHostPort int, rules []limayaml.PortForward, ignore bool, vmType limayaml.VMType) *portForwarder {
+func newPortForwarder(sshConfig *ssh.SSHConfig, sshHostPort int, rules []limayaml.PortForward, ignore bool, vmType limayaml.VMType, conflictPolicy limayaml.PortForwardConflictPolicy, metrics *hostAgentMetrics) *portForwarder {
+	staticRules, dynamicRules := splitStaticPortForwards(rules)
 	return &portForwarder{
-		sshConfig:   sshConfig,
-		sshHostPort: sshHostPort,
-		rules:       rules,
-		ignore:      ignore,
-		vmType:      vmType,
+		sshConfig:      sshConfig,
+		sshHostPort:    sshHostPort,
+		rules:          dynamicRules,
+		staticRules:    staticRules,
+		ignore:         ignore,
+		vmType:         vmType,
+		metrics:        metrics,
+		conflictPolicy: conflictPolicy,
+		addrInUse:      hostAddrInUse,
+		freePort:       freeHostPort,
+		forwardTCP: func(ctx context.Context, local, remote, verb string) error {
+			return forwardTCP(ctx, sshConfig, sshHostPort, local, remote, verb)
+		},
+		forwards: make(map[string]hostagentapi.PortForward),
+	}
+}
+
+// splitStaticPortForwards returns the static rules, and the rules for forwarding the ports dynamically.
+// In the latter, the static rules are replaced with the ignore rules, so that the statically forwarded
+// ports are not forwarded again when the guest starts listening on them.
+func splitStaticPortForwards(rules []limayaml.PortForward) (static, dynamic []limayaml.PortForward) {
+	dynamic = make([]limayaml.PortForward, 0, len(rules))
+	for _, rule := range rules {
+		if rule.Static {
+			static = append(static, rule)
+			rule.Static = false
+			rule.Ignore = true
+		}
+		dynamic = append(dynamic, rule)
+	}
+	return static, dynamic
+}
+
+// hostAddrInUse returns true if the TCP address is already bound on the host.
+func hostAddrInUse(addr string) bool {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		// Other errors, e.g., EACCES for the privileged ports on macOS, are not conflicts
+		return isAddrInUse(err)
+	}
+	_ = l.Close()
+	return false
+}
+
+// freeHostPort returns a free TCP port on the host IP.
+func freeHostPort(ip string) (int, error) {
+	if ip == IPv4loopback1.String() {
+		return freeport.TCP()
+	}
+	l, err := net.Listen("tcp", net.JoinHostPort(ip, "0"))
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// resolveHostAddr applies the conflict policy when the host address is already in use.
+// An empty string is returned with a nil error when the port is skipped.
+func (pf *portForwarder) resolveHostAddr(hostAddr string) (resolved string, remapped bool, err error) {
+	if strings.HasPrefix(hostAddr, "/") || !pf.addrInUse(hostAddr) {
+		return hostAddr, false, nil
+	}
+	conflictErr := fmt.Errorf("host address %s is already in use", hostAddr)
+	switch pf.conflictPolicy {
+	case limayaml.PortForwardConflictFail:
+		return "", false, conflictErr
+	case limayaml.PortForwardConflictRemap:
+		hostIP, _, err := net.SplitHostPort(hostAddr)
+		if err != nil {
+			return "", false, err
+		}
+		port, err := pf.freePort(hostIP)
+		if err != nil {
+			return "", false, fmt.Errorf("%w, and failed to find a free port: %w", conflictErr, err)
+		}
+		return net.JoinHostPort(hostIP, strconv.Itoa(port)), true, nil
+	default:
+		return "", false, nil
+	}
+}
+
+// isForwarded returns true if the host address is already forwarded by pf.
+func (pf *portForwarder) isForwarded(hostAddr string) bool {
+	pf.forwardsMu.Lock()
+	defer pf.forwardsMu.Unlock()
+	for _, fwd := range pf.forwards {
+		if fwd.Status == hostagentapi.PortForwardActive && fwd.HostAddr == hostAddr {
+			return true
+		}
+	}
+	return false
+}
+
+// hostAddr returns the host address that the guest address is actively forwarded to, or "".
+func (pf *portForwarder) hostAddr(guestAddr string) string {
+	pf.forwardsMu.Lock()
+	defer pf.forwardsMu.Unlock()
+	if fwd, ok := pf.forwards[guestAddr]; ok && fwd.Status == hostagentapi.PortForwardActive {
+		return fwd.HostAddr
+	}
+	return ""
+}
+
+func (pf *portForwarder) setForward(fwd hostagentapi.PortForward) {
+	fwd.Protocol = "tcp"
+	pf.forwardsMu.Lock()
+	pf.forwards[fwd.GuestAddr] = fwd
+	pf.forwardsMu.Unlock()
+}
+
+// PortForwards returns the port forwards with their status, sorted by the guest address.
+func (pf *portForwarder) PortForwards() []hostagentapi.PortForward {
+	pf.forwardsMu.Lock()
+	defer pf.forwardsMu.Unlock()
+	res := make([]hostagentapi.PortForward, 0, len(pf.forwards))
+	for _, fwd := range pf.forwards {
+		res = append(res, fwd)
+	}
+	slices.SortFunc(res, func(a, b hostagentapi.PortForward) int {
+		return strings.Compare(a.GuestAddr, b.GuestAddr)
+	})
+	return res
+}
+
+func (pf *portForwarder) emit(st events.Status) {
+	if pf.emitStatus != nil {
+		pf.emitStatus(st)
 	}
 }
 
@@ -81,18 +226,104 @@ func (pf *portForwarder) forwardingAddresses(guest *api.IPPort) (hostAddr, guest
 	return "", guest.HostString()
 }
 
+// ForwardStatic forwards the ports of the static rules, without waiting for the guest to listen on them.
+// As the guest port is connected on every connection to the host port, the connections fail until the
+// guest starts listening on the port, but the forward remains.
+// The forwards that fail to be set up are retried until ctx is done.
+func (pf *portForwarder) ForwardStatic(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, rule := range pf.staticRules {
+		local := net.JoinHostPort(rule.HostIP.String(), strconv.Itoa(rule.HostPortRange[0]))
+		remote := net.JoinHostPort(rule.GuestIP.String(), strconv.Itoa(rule.GuestPortRange[0]))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			backoff := staticForwardInitialBackoff
+			for pf.forward(ctx, local, remote, true) != nil {
+				select {
+				case <-ctx.Done():
+					return
+				case <-time.After(backoff):
+				}
+				backoff = min(backoff*2, staticForwardMaxBackoff)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+const (
+	staticForwardInitialBackoff = time.Second
+	staticForwardMaxBackoff     = time.Minute
+)
+
+// forward forwards the host address to the guest address, after applying the conflict policy.
+// The status of the forward is recorded, and the error is returned only when the forward failed to be set up.
+func (pf *portForwarder) forward(ctx context.Context, local, remote string, static bool) error {
+	resolved, remapped, err := pf.resolveHostAddr(local)
+	if err != nil {
+		logrus.WithError(err).Warnf("failed to set up forwarding TCP from %s", remote)
+		pf.metrics.portForwardErrors.Inc()
+		pf.emit(events.Status{Errors: []string{fmt.Sprintf("failed to forward TCP %s: %v", remote, err)}})
+		pf.setForward(hostagentapi.PortForward{GuestAddr: remote, HostAddr: local, Static: static, Status: hostagentapi.PortForwardFailed, Reason: err.Error()})
+		return nil
+	}
+	if resolved == "" {
+		logrus.Warnf("Not forwarding TCP %s, as %s is already in use on the host", remote, local)
+		pf.setForward(hostagentapi.PortForward{GuestAddr: remote, HostAddr: local, Static: static, Status: hostagentapi.PortForwardSkipped, Reason: "host address is already in use"})
+		return nil
+	}
+	if remapped {
+		logrus.Infof("Forwarding TCP from %s to %s (remapped from %s, which is already in use)", remote, resolved, local)
+	} else {
+		logrus.Infof("Forwarding TCP from %s to %s", remote, resolved)
+	}
+	if err := pf.forwardTCP(ctx, resolved, remote, verbForward); err != nil {
+		logrus.WithError(err).Warnf("failed to set up forwarding TCP from %s", remote)
+		pf.metrics.portForwardErrors.Inc()
+		pf.setForward(hostagentapi.PortForward{GuestAddr: remote, HostAddr: resolved, Remapped: remapped, Static: static, Status: hostagentapi.PortForwardFailed, Reason: err.Error()})
+		return err
+	}
+	pf.metrics.portForwards.Inc()
+	fwd := hostagentapi.PortForward{GuestAddr: remote, HostAddr: resolved, Protocol: "tcp", Remapped: remapped, Static: static, Status: hostagentapi.PortForwardActive}
+	pf.setForward(fwd)
+	if remapped {
+		pf.emit(events.Status{PortForwards: []hostagentapi.PortForward{fwd}})
+	}
+	return nil
+}
+
+// isStatic returns true if the guest address is forwarded by a static rule.
+func (pf *portForwarder) isStatic(guestAddr string) bool {
+	pf.forwardsMu.Lock()
+	defer pf.forwardsMu.Unlock()
+	return pf.forwards[guestAddr].Static
+}
+
 func (pf *portForwarder) OnEvent(ctx context.Context, ev *api.Event) {
 	for _, f := range ev.LocalPortsRemoved {
 		if f.Protocol != "tcp" {
 			continue
 		}
-		local, remote := pf.forwardingAddresses(f)
-		if local == "" {
+		remote := f.HostString()
+		if pf.isStatic(remote) {
+			// The static forward remains, as the guest may listen on the port again
+			continue
+		}
+		pf.forwardsMu.Lock()
+		fwd, ok := pf.forwards[remote]
+		delete(pf.forwards, remote)
+		pf.forwardsMu.Unlock()
+		if !ok || fwd.Status != hostagentapi.PortForwardActive {
 			continue
 		}
+		local := fwd.HostAddr
 		logrus.Infof("Stopping forwarding TCP from %s to %s", remote, local)
-		if err := forwardTCP(ctx, pf.sshConfig, pf.sshHostPort, local, remote, verbCancel); err != nil {
+		if err := pf.forwardTCP(ctx, local, remote, verbCancel); err != nil {
 			logrus.WithError(err).Warnf("failed to stop forwarding tcp port %d", f.Port)
+			pf.metrics.portForwardErrors.Inc()
+		} else {
+			pf.metrics.portForwards.Dec()
 		}
 	}
 	for _, f := range ev.LocalPortsAdded {
@@ -100,15 +331,21 @@ func (pf *portForwarder) OnEvent(ctx context.Context, ev *api.Event) {
 			continue
 		}
 		local, remote := pf.forwardingAddresses(f)
+		if pf.isStatic(remote) {
+			continue
+		}
 		if local == "" {
 			if !pf.ignore {
 				logrus.Infof("Not forwarding TCP %s", remote)
+				pf.setForward(hostagentapi.PortForward{GuestAddr: remote, Status: hostagentapi.PortForwardSkipped, Reason: "ignored by the port forwarding rules"})
 			}
 			continue
 		}
-		logrus.Infof("Forwarding TCP from %s to %s", remote, local)
-		if err := forwardTCP(ctx, pf.sshConfig, pf.sshHostPort, local, remote, verbForward); err != nil {
-			logrus.WithError(err).Warnf("failed to set up forwarding tcp port %d (negligible if already forwarded)", f.Port)
+		if pf.isForwarded(local) {
+			logrus.Debugf("Not forwarding TCP %s, as %s is already forwarded", remote, local)
+			pf.setForward(hostagentapi.PortForward{GuestAddr: remote, HostAddr: local, Status: hostagentapi.PortForwardSkipped, Reason: "host address is already forwarded from another guest address"})
+			continue
 		}
+		_ = pf.forward(ctx, local, remote, false)
 	}
 }
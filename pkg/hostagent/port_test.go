@@ -0,0 +1,117 @@
+package hostagent
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"gotest.tools/v3/assert"
+)
+
+func TestResolveHostAddr(t *testing.T) {
+	newPF := func(policy limayaml.PortForwardConflictPolicy, inUse bool) *portForwarder {
+		pf := newPortForwarder(nil, 0, nil, false, limayaml.QEMU, policy, newHostAgentMetrics())
+		pf.addrInUse = func(string) bool { return inUse }
+		pf.freePort = func(string) (int, error) { return 50000, nil }
+		return pf
+	}
+
+	for _, policy := range limayaml.PortForwardConflictPolicies {
+		resolved, remapped, err := newPF(policy, false).resolveHostAddr("127.0.0.1:8080")
+		assert.NilError(t, err, policy)
+		assert.Equal(t, resolved, "127.0.0.1:8080", policy)
+		assert.Assert(t, !remapped, policy)
+	}
+
+	resolved, remapped, err := newPF(limayaml.PortForwardConflictSkip, true).resolveHostAddr("127.0.0.1:8080")
+	assert.NilError(t, err)
+	assert.Equal(t, resolved, "")
+	assert.Assert(t, !remapped)
+
+	_, _, err = newPF(limayaml.PortForwardConflictFail, true).resolveHostAddr("127.0.0.1:8080")
+	assert.Error(t, err, "host address 127.0.0.1:8080 is already in use")
+
+	resolved, remapped, err = newPF(limayaml.PortForwardConflictRemap, true).resolveHostAddr("127.0.0.1:8080")
+	assert.NilError(t, err)
+	assert.Equal(t, resolved, "127.0.0.1:50000")
+	assert.Assert(t, remapped)
+
+	resolved, _, err = newPF(limayaml.PortForwardConflictRemap, true).resolveHostAddr("[::1]:8080")
+	assert.NilError(t, err)
+	assert.Equal(t, resolved, "[::1]:50000")
+
+	pf := newPF(limayaml.PortForwardConflictRemap, true)
+	pf.freePort = func(string) (int, error) { return 0, errors.New("no free port") }
+	_, _, err = pf.resolveHostAddr("127.0.0.1:8080")
+	assert.Error(t, err, "host address 127.0.0.1:8080 is already in use, and failed to find a free port: no free port")
+
+	// Sockets are never in conflict
+	resolved, _, err = newPF(limayaml.PortForwardConflictFail, true).resolveHostAddr("/tmp/lima.sock")
+	assert.NilError(t, err)
+	assert.Equal(t, resolved, "/tmp/lima.sock")
+}
+
+func TestHostAddrInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	addr := l.Addr().String()
+	assert.Assert(t, hostAddrInUse(addr))
+	assert.NilError(t, l.Close())
+	assert.Assert(t, !hostAddrInUse(addr))
+}
+
+func TestPortForwards(t *testing.T) {
+	pf := newPortForwarder(nil, 0, nil, false, limayaml.QEMU, limayaml.PortForwardConflictRemap, newHostAgentMetrics())
+	assert.Equal(t, len(pf.PortForwards()), 0)
+	pf.setForward(hostagentapi.PortForward{GuestAddr: "127.0.0.1:8443", HostAddr: "127.0.0.1:8443", Status: hostagentapi.PortForwardActive})
+	pf.setForward(hostagentapi.PortForward{GuestAddr: "127.0.0.1:80", HostAddr: "127.0.0.1:50000", Remapped: true, Status: hostagentapi.PortForwardActive})
+	pf.setForward(hostagentapi.PortForward{GuestAddr: "0.0.0.0:9000", HostAddr: "127.0.0.1:9000", Status: hostagentapi.PortForwardFailed, Reason: "host address 127.0.0.1:9000 is already in use"})
+	assert.DeepEqual(t, pf.PortForwards(), []hostagentapi.PortForward{
+		{GuestAddr: "0.0.0.0:9000", HostAddr: "127.0.0.1:9000", Protocol: "tcp", Status: hostagentapi.PortForwardFailed, Reason: "host address 127.0.0.1:9000 is already in use"},
+		{GuestAddr: "127.0.0.1:80", HostAddr: "127.0.0.1:50000", Protocol: "tcp", Remapped: true, Status: hostagentapi.PortForwardActive},
+		{GuestAddr: "127.0.0.1:8443", HostAddr: "127.0.0.1:8443", Protocol: "tcp", Status: hostagentapi.PortForwardActive},
+	})
+	assert.Assert(t, pf.isForwarded("127.0.0.1:50000"))
+	assert.Assert(t, !pf.isForwarded("127.0.0.1:80"))
+	// the failed forward does not occupy the host address
+	assert.Assert(t, !pf.isForwarded("127.0.0.1:9000"))
+}
+
+func TestForwardStatic(t *testing.T) {
+	rules := []limayaml.PortForward{
+		{GuestIP: IPv4loopback1, GuestPortRange: [2]int{5432, 5432}, HostIP: IPv4loopback1, HostPortRange: [2]int{15432, 15432}, Proto: limayaml.ProtoTCP, Static: true},
+		{GuestIP: net.IPv4zero, GuestPortRange: [2]int{1, 65535}, HostIP: IPv4loopback1, HostPortRange: [2]int{1, 65535}, Proto: limayaml.ProtoTCP},
+	}
+	pf := newPortForwarder(nil, 0, rules, false, limayaml.QEMU, limayaml.PortForwardConflictSkip, newHostAgentMetrics())
+	pf.addrInUse = func(string) bool { return false }
+	var attempts int
+	pf.forwardTCP = func(_ context.Context, local, remote, verb string) error {
+		assert.Equal(t, local, "127.0.0.1:15432")
+		assert.Equal(t, remote, "127.0.0.1:5432")
+		assert.Equal(t, verb, verbForward)
+		if attempts++; attempts == 1 {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	// the static forward is set up before any event from the guest agent, with a retry
+	pf.ForwardStatic(context.Background())
+	assert.Equal(t, attempts, 2)
+	fwd := hostagentapi.PortForward{GuestAddr: "127.0.0.1:5432", HostAddr: "127.0.0.1:15432", Protocol: "tcp", Static: true, Status: hostagentapi.PortForwardActive}
+	assert.DeepEqual(t, pf.PortForwards(), []hostagentapi.PortForward{fwd})
+
+	// the static forward is neither forwarded again nor cancelled by the events
+	pf.forwardTCP = func(context.Context, string, string, string) error {
+		t.Fatal("unexpected forwardTCP")
+		return nil
+	}
+	ipPort := &api.IPPort{Ip: "127.0.0.1", Port: 5432, Protocol: "tcp"}
+	pf.OnEvent(context.Background(), &api.Event{LocalPortsAdded: []*api.IPPort{ipPort}})
+	pf.OnEvent(context.Background(), &api.Event{LocalPortsRemoved: []*api.IPPort{ipPort}})
+	assert.DeepEqual(t, pf.PortForwards(), []hostagentapi.PortForward{fwd})
+}
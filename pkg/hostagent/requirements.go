@@ -223,6 +223,33 @@ Also see "/var/log/cloud-init-output.log" in the guest.
 	return req
 }
 
+func (a *HostAgent) firstBootRequirements() []requirement {
+	req := make([]requirement, 0)
+	req = append(req,
+		requirement{
+			description: "cloud-init to complete",
+			script: `#!/bin/bash
+set -eux -o pipefail
+if ! command -v cloud-init >/dev/null 2>&1; then
+	exit 0
+fi
+rc=0
+timeout 30s sudo cloud-init status --wait >/dev/null || rc=$?
+if [ "$rc" -eq 124 ]; then
+	echo >&2 "cloud-init has not completed yet"
+	exit 1
+fi
+# cloud-init exits with 1 (error) or 2 (recoverable error) when it has completed with errors
+exit 0
+`,
+			debugHint: `The port forwarding is not started until cloud-init completes.
+Run "cloud-init status --long" in the guest to see the status of cloud-init, and
+check "/var/log/cloud-init-output.log" in the guest to see where the process is blocked!
+`,
+		})
+	return req
+}
+
 func (a *HostAgent) finalRequirements() []requirement {
 	req := make([]requirement, 0)
 	req = append(req,
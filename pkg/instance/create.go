@@ -25,6 +25,9 @@ func Create(ctx context.Context, instName string, instConfig []byte, saveBrokenY
 		return nil, errors.New("got empty instConfig")
 	}
 
+	if err := store.ValidateInstanceName(instName); err != nil {
+		return nil, err
+	}
 	instDir, err := store.InstanceDir(instName)
 	if err != nil {
 		return nil, err
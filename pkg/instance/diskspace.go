@@ -0,0 +1,155 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	"github.com/lima-vm/lima/pkg/fileutils"
+	"github.com/lima-vm/lima/pkg/fsutil"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DiskSpaceMarginEnv is the name of the environment variable to override the margin of the disk space check,
+	// e.g., "512MiB". "0" disables the margin but not the check.
+	DiskSpaceMarginEnv = "LIMA_DISK_SPACE_MARGIN"
+	// defaultDiskSpaceMargin is the free space left after creating the instance files.
+	defaultDiskSpaceMargin = 1 * units.GiB
+	// cidataSizeEstimate is the estimated size of the cidata ISO without the nerdctl archive
+	// (mostly the guest agent binary).
+	cidataSizeEstimate = 64 * units.MiB
+	// nerdctlArchiveSizeEstimate is the estimated size of the nerdctl archive that is not cached yet.
+	nerdctlArchiveSizeEstimate = 256 * units.MiB
+	// imageSizeEstimate is the estimated size of the image that is not cached yet.
+	imageSizeEstimate = 1 * units.GiB
+)
+
+func diskSpaceMargin() (int64, error) {
+	envVar := os.Getenv(DiskSpaceMarginEnv)
+	if envVar == "" {
+		return defaultDiskSpaceMargin, nil
+	}
+	margin, err := units.RAMInBytes(envVar)
+	if err != nil || margin < 0 {
+		return 0, fmt.Errorf("invalid %s value %q", DiskSpaceMarginEnv, envVar)
+	}
+	return margin, nil
+}
+
+// requiredDiskSpace estimates the space required in the instance directory for
+// the disk (when it is not created yet) and the cidata ISO including the nerdctl archive.
+// The disk is sparse, so it is counted with the space allocated for the image, not with its full size.
+func requiredDiskSpace(inst *store.Instance) (int64, error) {
+	var required int64
+	creating, err := creatingDisk(inst)
+	if err != nil {
+		return 0, err
+	}
+	if creating {
+		required += imageDiskSpace(inst.Config)
+	}
+	required += cidataSizeEstimate
+	y := inst.Config
+	if *y.Containerd.System || *y.Containerd.User {
+		nerdctlArchiveSize := int64(nerdctlArchiveSizeEstimate)
+		for _, f := range y.Containerd.Archives {
+			if f.Arch != *y.Arch {
+				continue
+			}
+			if path, err := fileutils.CachedFile(f); err == nil {
+				if st, err := os.Stat(path); err == nil {
+					nerdctlArchiveSize = st.Size()
+				}
+			}
+			break
+		}
+		required += nerdctlArchiveSize
+	}
+	return required, nil
+}
+
+// creatingDisk returns whether the disk of the instance is not created yet.
+func creatingDisk(inst *store.Instance) (bool, error) {
+	// WSL2 does not use the diff disk
+	if inst.VMType == limayaml.WSL2 {
+		return false, nil
+	}
+	if _, err := os.Stat(filepath.Join(inst.Dir, filenames.DiffDisk)); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
+// imageDiskSpace estimates the space allocated for the disk created from the image.
+// The base disk is a copy of the image, and the diff disk may be converted from it
+// (e.g., into a raw disk for vz), so twice the size of the image is counted.
+func imageDiskSpace(y *limayaml.LimaYAML) int64 {
+	for _, f := range y.Images {
+		if f.Arch != *y.Arch {
+			continue
+		}
+		if path, err := fileutils.CachedFile(f.File); err == nil {
+			if st, err := os.Stat(path); err == nil {
+				return 2 * st.Size()
+			}
+		}
+		break
+	}
+	return 2 * imageSizeEstimate
+}
+
+// checkDiskSpace returns an error when the filesystem of dir does not have the required space and the margin available.
+func checkDiskSpace(dir string, required, margin int64, availableSpace func(string) (uint64, error)) error {
+	available, err := availableSpace(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get the available space of %q: %w", dir, err)
+	}
+	if uint64(required+margin) <= available {
+		return nil
+	}
+	return fmt.Errorf("insufficient disk space in %q: %s is required (including the margin of %s), but only %s is available "+
+		"(hint: free up the disk space, reduce `disk` in the instance config, or set %s to change the margin)",
+		dir, units.BytesSize(float64(required+margin)), units.BytesSize(float64(margin)), units.BytesSize(float64(available)), DiskSpaceMarginEnv)
+}
+
+// ensureDiskSpace checks that the instance directory has enough space to create the disk and the cidata ISO,
+// so that the large allocations do not fail halfway with a cryptic error.
+// Only a warning is printed when the disk cannot grow to its full size.
+func ensureDiskSpace(inst *store.Instance) error {
+	margin, err := diskSpaceMargin()
+	if err != nil {
+		return err
+	}
+	required, err := requiredDiskSpace(inst)
+	if err != nil {
+		return err
+	}
+	if err := checkDiskSpace(inst.Dir, required, margin, fsutil.AvailableSpace); err != nil {
+		return err
+	}
+	if creating, err := creatingDisk(inst); err == nil && creating {
+		warnDiskGrowth(inst.Dir, inst.Disk, fsutil.AvailableSpace)
+	}
+	return nil
+}
+
+// warnDiskGrowth warns when the filesystem of dir does not have the space for the disk to grow to its full size.
+// This is not an error, as the disk is allocated on demand.
+func warnDiskGrowth(dir string, diskSize int64, availableSpace func(string) (uint64, error)) bool {
+	available, err := availableSpace(dir)
+	if err != nil || uint64(diskSize) <= available {
+		return false
+	}
+	logrus.Warnf("The disk of the instance can grow up to %s, but only %s is available in %q",
+		units.BytesSize(float64(diskSize)), units.BytesSize(float64(available)), dir)
+	return true
+}
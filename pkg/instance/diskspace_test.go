@@ -0,0 +1,96 @@
+package instance
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	availableSpace := func(available uint64) func(string) (uint64, error) {
+		return func(string) (uint64, error) {
+			return available, nil
+		}
+	}
+	const required = 100 * units.GiB
+	const margin = 1 * units.GiB
+
+	assert.NilError(t, checkDiskSpace("/dummy", required, margin, availableSpace(required+margin)))
+
+	err := checkDiskSpace("/dummy", required, margin, availableSpace(required))
+	assert.ErrorContains(t, err, `insufficient disk space in "/dummy": 101GiB is required (including the margin of 1GiB), but only 100GiB is available`)
+
+	assert.NilError(t, checkDiskSpace("/dummy", required, 0, availableSpace(required)))
+
+	err = checkDiskSpace("/dummy", required, margin, func(string) (uint64, error) {
+		return 0, errors.New("statfs failed")
+	})
+	assert.ErrorContains(t, err, "statfs failed")
+}
+
+func TestDiskSpaceMargin(t *testing.T) {
+	t.Setenv(DiskSpaceMarginEnv, "")
+	margin, err := diskSpaceMargin()
+	assert.NilError(t, err)
+	assert.Equal(t, margin, int64(defaultDiskSpaceMargin))
+
+	t.Setenv(DiskSpaceMarginEnv, "512MiB")
+	margin, err = diskSpaceMargin()
+	assert.NilError(t, err)
+	assert.Equal(t, margin, int64(512*units.MiB))
+
+	t.Setenv(DiskSpaceMarginEnv, "0")
+	margin, err = diskSpaceMargin()
+	assert.NilError(t, err)
+	assert.Equal(t, margin, int64(0))
+
+	t.Setenv(DiskSpaceMarginEnv, "foo")
+	_, err = diskSpaceMargin()
+	assert.ErrorContains(t, err, "invalid LIMA_DISK_SPACE_MARGIN")
+}
+
+func TestWarnDiskGrowth(t *testing.T) {
+	availableSpace := func(available uint64) func(string) (uint64, error) {
+		return func(string) (uint64, error) {
+			return available, nil
+		}
+	}
+	assert.Assert(t, !warnDiskGrowth("/dummy", 100*units.GiB, availableSpace(100*units.GiB)))
+	assert.Assert(t, warnDiskGrowth("/dummy", 100*units.GiB, availableSpace(50*units.GiB)))
+}
+
+func TestRequiredDiskSpace(t *testing.T) {
+	// the image is not cached
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	inst := &store.Instance{
+		Dir:    t.TempDir(),
+		VMType: limayaml.QEMU,
+		Disk:   100 * units.GiB,
+		Config: &limayaml.LimaYAML{
+			Arch:   ptr.Of(limayaml.X8664),
+			Images: []limayaml.Image{{File: limayaml.File{Location: "https://example.com/image.img", Arch: limayaml.X8664}}},
+			Containerd: limayaml.Containerd{
+				System: ptr.Of(false),
+				User:   ptr.Of(false),
+			},
+		},
+	}
+	// the full size of the disk is not counted
+	required, err := requiredDiskSpace(inst)
+	assert.NilError(t, err)
+	assert.Equal(t, required, int64(2*imageSizeEstimate+cidataSizeEstimate))
+
+	assert.NilError(t, os.WriteFile(filepath.Join(inst.Dir, filenames.DiffDisk), nil, 0o644))
+	required, err = requiredDiskSpace(inst)
+	assert.NilError(t, err)
+	assert.Equal(t, required, int64(cidataSizeEstimate))
+}
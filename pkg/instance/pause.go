@@ -0,0 +1,63 @@
+package instance
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lima-vm/lima/pkg/driver"
+	"github.com/lima-vm/lima/pkg/driverutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/sirupsen/logrus"
+)
+
+// Pause pauses the running instance without shutting down the guest, preserving the memory state.
+func Pause(ctx context.Context, inst *store.Instance) error {
+	if err := checkPause(inst); err != nil {
+		return err
+	}
+	limaDriver := driverutil.CreateTargetDriverInstance(&driver.BaseDriver{
+		Instance: inst,
+	})
+	if err := limaDriver.Pause(ctx); err != nil {
+		return fmt.Errorf("failed to pause instance %q: %w", inst.Name, err)
+	}
+	logrus.Infof("Paused instance %q", inst.Name)
+	return nil
+}
+
+// Resume resumes the instance paused by Pause.
+func Resume(ctx context.Context, inst *store.Instance) error {
+	if err := checkResume(inst); err != nil {
+		return err
+	}
+	limaDriver := driverutil.CreateTargetDriverInstance(&driver.BaseDriver{
+		Instance: inst,
+	})
+	if err := limaDriver.Resume(ctx); err != nil {
+		return fmt.Errorf("failed to resume instance %q: %w", inst.Name, err)
+	}
+	logrus.Infof("Resumed instance %q", inst.Name)
+	return nil
+}
+
+func checkPause(inst *store.Instance) error {
+	switch inst.Status {
+	case store.StatusRunning:
+		return nil
+	case store.StatusPaused:
+		return fmt.Errorf("instance %q is already paused", inst.Name)
+	default:
+		return fmt.Errorf("expected status %q, got %q", store.StatusRunning, inst.Status)
+	}
+}
+
+func checkResume(inst *store.Instance) error {
+	switch inst.Status {
+	case store.StatusPaused:
+		return nil
+	case store.StatusRunning:
+		return fmt.Errorf("instance %q is not paused", inst.Name)
+	default:
+		return fmt.Errorf("expected status %q, got %q", store.StatusPaused, inst.Status)
+	}
+}
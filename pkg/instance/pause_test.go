@@ -0,0 +1,24 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckPauseAndResume(t *testing.T) {
+	inst := &store.Instance{Name: "foo", Status: store.StatusRunning}
+	assert.NilError(t, checkPause(inst))
+	assert.ErrorContains(t, checkResume(inst), "is not paused")
+
+	inst.Status = store.StatusPaused
+	assert.ErrorContains(t, checkPause(inst), "is already paused")
+	assert.NilError(t, checkResume(inst))
+
+	for _, status := range []store.Status{store.StatusStopped, store.StatusBroken, store.StatusUnknown} {
+		inst.Status = status
+		assert.ErrorContains(t, checkPause(inst), "expected status \"Running\"", status)
+		assert.ErrorContains(t, checkResume(inst), "expected status \"Paused\"", status)
+	}
+}
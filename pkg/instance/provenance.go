@@ -0,0 +1,45 @@
+package instance
+
+import (
+	"path"
+	"regexp"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/version"
+)
+
+// writeProvenance writes the provenance.json file in the instance directory.
+// The image is recorded by the driver when it creates the disk (see store.WriteProvenanceImage),
+// so the image already recorded in the provenance is kept.
+func writeProvenance(inst *store.Instance, nerdctlArchive *limayaml.File) error {
+	p := &store.Provenance{
+		LimaVersion: version.Version,
+	}
+	recorded, err := store.ReadProvenance(inst.Dir)
+	if err != nil {
+		return err
+	}
+	if recorded != nil {
+		p.Image = recorded.Image
+	}
+	if nerdctlArchive != nil {
+		p.NerdctlArchive = &store.Artifact{
+			Location: nerdctlArchive.Location,
+			Digest:   nerdctlArchive.Digest,
+			Version:  nerdctlVersion(nerdctlArchive.Location),
+		}
+	}
+	return store.WriteProvenance(inst.Dir, p)
+}
+
+var nerdctlVersionRegexp = regexp.MustCompile(`^nerdctl-full-(.+)-[a-z]+-[a-z0-9_]+\.tar\.gz$`)
+
+// nerdctlVersion returns the version from the file name of nerdctl-full-VERSION-GOOS-GOARCH.tar.gz,
+// or an empty string.
+func nerdctlVersion(location string) string {
+	if m := nerdctlVersionRegexp.FindStringSubmatch(path.Base(location)); m != nil {
+		return m[1]
+	}
+	return ""
+}
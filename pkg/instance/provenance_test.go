@@ -0,0 +1,49 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/version"
+	"gotest.tools/v3/assert"
+)
+
+func TestNerdctlVersion(t *testing.T) {
+	assert.Equal(t, nerdctlVersion("https://github.com/containerd/nerdctl/releases/download/v2.0.3/nerdctl-full-2.0.3-linux-amd64.tar.gz"), "2.0.3")
+	assert.Equal(t, nerdctlVersion("/tmp/nerdctl-full-2.1.0-beta.1-linux-arm64.tar.gz"), "2.1.0-beta.1")
+	assert.Equal(t, nerdctlVersion("/tmp/nerdctl.tgz"), "")
+}
+
+func TestWriteProvenance(t *testing.T) {
+	inst := &store.Instance{Dir: t.TempDir()}
+	nerdctlArchive := &limayaml.File{
+		Location: "https://example.com/nerdctl-full-2.0.3-linux-amd64.tar.gz",
+		Digest:   "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}
+
+	// the image is unknown until the driver records it
+	assert.NilError(t, writeProvenance(inst, nil))
+	p, err := store.ReadProvenance(inst.Dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p, &store.Provenance{LimaVersion: version.Version})
+
+	// the image recorded by the driver is kept
+	image := &store.Artifact{
+		Location: "https://example.com/image.img",
+		Digest:   "sha256:1111111111111111111111111111111111111111111111111111111111111111",
+	}
+	assert.NilError(t, store.WriteProvenanceImage(inst.Dir, image))
+	assert.NilError(t, writeProvenance(inst, nerdctlArchive))
+	p, err = store.ReadProvenance(inst.Dir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p, &store.Provenance{
+		LimaVersion: version.Version,
+		Image:       image,
+		NerdctlArchive: &store.Artifact{
+			Location: nerdctlArchive.Location,
+			Digest:   nerdctlArchive.Digest,
+			Version:  "2.0.3",
+		},
+	})
+}
@@ -0,0 +1,63 @@
+package instance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	hostagentclient "github.com/lima-vm/lima/pkg/hostagent/api/client"
+	"github.com/lima-vm/lima/pkg/osutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+// saveStateTimeout is the duration to wait for the host agent to save the vm state.
+// Writing the whole memory of the vm may take long.
+const saveStateTimeout = 10 * time.Minute
+
+// Save saves the vm state of the running instance to disk, and stops the instance.
+// The next start of the instance restores the saved state instead of booting the guest.
+func Save(ctx context.Context, inst *store.Instance) error {
+	if err := checkSave(inst); err != nil {
+		return err
+	}
+	haSock := filepath.Join(inst.Dir, filenames.HostAgentSock)
+	haClient, err := hostagentclient.NewHostAgentClient(haSock, hostagentclient.WithTimeout(saveStateTimeout))
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Saving the state of instance %q", inst.Name)
+	if err := haClient.SaveState(ctx); err != nil {
+		return fmt.Errorf("failed to save the state of instance %q: %w", inst.Name, err)
+	}
+
+	begin := time.Now() // used for logrus propagation
+	logrus.Infof("Sending SIGINT to hostagent process %d", inst.HostAgentPID)
+	if err := osutil.SysKill(inst.HostAgentPID, osutil.SigInt); err != nil {
+		logrus.Error(err)
+	}
+	logrus.Info("Waiting for the host agent and the driver processes to shut down")
+	err = waitForHostAgentTermination(ctx, inst, begin, DefaultStopTimeout)
+	if errors.Is(err, errStopTimeout) {
+		logrus.Warnf("The instance %q did not shut down in %v, stopping it forcibly", inst.Name, DefaultStopTimeout)
+		StopForcibly(inst)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Saved the state of instance %q, run `limactl start %s` to restore it", inst.Name, inst.Name)
+	return nil
+}
+
+func checkSave(inst *store.Instance) error {
+	switch inst.Status {
+	case store.StatusRunning, store.StatusPaused:
+		return nil
+	default:
+		return fmt.Errorf("expected status %q or %q, got %q", store.StatusRunning, store.StatusPaused, inst.Status)
+	}
+}
@@ -0,0 +1,17 @@
+package instance
+
+import (
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestCheckSave(t *testing.T) {
+	for _, status := range []store.Status{store.StatusRunning, store.StatusPaused} {
+		assert.NilError(t, checkSave(&store.Instance{Name: "foo", Status: status}), status)
+	}
+	for _, status := range []store.Status{store.StatusStopped, store.StatusBroken, store.StatusUnknown} {
+		assert.ErrorContains(t, checkSave(&store.Instance{Name: "foo", Status: status}), "expected status \"Running\" or \"Paused\"", status)
+	}
+}
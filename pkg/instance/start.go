@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"strings"
 	"syscall"
 	"text/template"
 	"time"
@@ -27,9 +28,12 @@ import (
 	"github.com/lima-vm/lima/pkg/fileutils"
 	hostagentevents "github.com/lima-vm/lima/pkg/hostagent/events"
 	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/logrotate"
 	"github.com/lima-vm/lima/pkg/store"
 	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/lima-vm/lima/pkg/tracing"
 	"github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 // DefaultWatchHostAgentEventsTimeout is the duration to wait for the instance
@@ -38,20 +42,25 @@ const DefaultWatchHostAgentEventsTimeout = 10 * time.Minute
 
 // ensureNerdctlArchiveCache prefetches the nerdctl-full-VERSION-GOOS-GOARCH.tar.gz archive
 // into the cache before launching the hostagent process, so that we can show the progress in tty.
+// It returns the path of the archive, and the entry of `containerd.archives` that was used.
 // https://github.com/lima-vm/lima/issues/326
-func ensureNerdctlArchiveCache(ctx context.Context, y *limayaml.LimaYAML, created bool) (string, error) {
+func ensureNerdctlArchiveCache(ctx context.Context, y *limayaml.LimaYAML, created bool) (string, *limayaml.File, error) {
 	if !*y.Containerd.System && !*y.Containerd.User {
 		// nerdctl archive is not needed
-		return "", nil
+		return "", nil, nil
 	}
 
-	errs := make([]error, len(y.Containerd.Archives))
-	for i, f := range y.Containerd.Archives {
+	archives, err := limayaml.FillNerdctlDigests(y.Containerd.Archives)
+	if err != nil {
+		return "", nil, err
+	}
+	errs := make([]error, len(archives))
+	for i, f := range archives {
 		// Skip downloading again if the file is already in the cache
 		if created && f.Arch == *y.Arch && !downloader.IsLocal(f.Location) {
 			path, err := fileutils.CachedFile(f)
 			if err == nil {
-				return path, nil
+				return path, &f, nil
 			}
 		}
 		path, err := fileutils.DownloadFile(ctx, "", f, false, "the nerdctl archive", *y.Arch)
@@ -61,14 +70,18 @@ func ensureNerdctlArchiveCache(ctx context.Context, y *limayaml.LimaYAML, create
 		}
 		if path == "" {
 			if downloader.IsLocal(f.Location) {
-				return f.Location, nil
+				return f.Location, &f, nil
 			}
-			return "", fmt.Errorf("cache did not contain %q", f.Location)
+			return "", nil, fmt.Errorf("cache did not contain %q", f.Location)
 		}
-		return path, nil
+		return path, &f, nil
 	}
 
-	return "", fileutils.Errors(errs)
+	err = fileutils.Errors(errs)
+	if errors.Is(err, downloader.ErrOffline) {
+		return "", nil, fmt.Errorf("%w (hint: start an instance without the offline mode once to cache the nerdctl archive, or set `containerd.archives` to a local file)", err)
+	}
+	return "", nil, err
 }
 
 type Prepared struct {
@@ -77,7 +90,10 @@ type Prepared struct {
 }
 
 // Prepare ensures the disk, the nerdctl archive, etc.
-func Prepare(ctx context.Context, inst *store.Instance) (*Prepared, error) {
+func Prepare(ctx context.Context, inst *store.Instance) (_ *Prepared, retErr error) {
+	ctx, span := tracing.Start(ctx, "instance.Prepare")
+	defer func() { tracing.End(span, retErr) }()
+
 	limaDriver := driverutil.CreateTargetDriverInstance(&driver.BaseDriver{
 		Instance: inst,
 	})
@@ -95,13 +111,19 @@ func Prepare(ctx context.Context, inst *store.Instance) (*Prepared, error) {
 	_, err := os.Stat(baseDisk)
 	created := err == nil
 
-	if err := limaDriver.CreateDisk(ctx); err != nil {
+	if err := validateAdditionalDisks(inst.Config); err != nil {
+		return nil, err
+	}
+	if err := ensureDisk(ctx, inst, limaDriver); err != nil {
 		return nil, err
 	}
-	nerdctlArchiveCache, err := ensureNerdctlArchiveCache(ctx, inst.Config, created)
+	nerdctlArchiveCache, nerdctlArchive, err := ensureNerdctlArchiveCache(ctx, inst.Config, created)
 	if err != nil {
 		return nil, err
 	}
+	if err := writeProvenance(inst, nerdctlArchive); err != nil {
+		logrus.WithError(err).Warn("Failed to write the provenance")
+	}
 
 	return &Prepared{
 		Driver:              limaDriver,
@@ -109,6 +131,43 @@ func Prepare(ctx context.Context, inst *store.Instance) (*Prepared, error) {
 	}, nil
 }
 
+// ensureDisk ensures the disk space and the disk of the instance, downloading the image if needed.
+func ensureDisk(ctx context.Context, inst *store.Instance, limaDriver driver.Driver) (retErr error) {
+	ctx, span := tracing.Start(ctx, "instance.ensureDisk")
+	defer func() { tracing.End(span, retErr) }()
+
+	if err := ensureDiskSpace(inst); err != nil {
+		return err
+	}
+	return limaDriver.CreateDisk(ctx)
+}
+
+// validateAdditionalDisks checks that the disks referred by `additionalDisks` exist in the store.
+func validateAdditionalDisks(y *limayaml.LimaYAML) error {
+	var errs []error
+	for i, d := range y.AdditionalDisks {
+		diskDir, err := store.DiskDir(d.Name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("field `additionalDisks[%d].name` is invalid: %w", i, err))
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(diskDir, filenames.DataDisk)); err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				err = fmt.Errorf("disk %q does not exist (hint: create the disk with `limactl disk create %s --size SIZE`)", d.Name, d.Name)
+			}
+			errs = append(errs, fmt.Errorf("field `additionalDisks[%d]`: %w", i, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createHostAgentLog creates the log file to be passed to the host agent as stdout or stderr.
+// The file is opened with O_APPEND, as the host agent also appends to the file with its rotating
+// log writer; writing at the offset of this file descriptor would overwrite those logs.
+func createHostAgentLog(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC|os.O_APPEND, 0o644)
+}
+
 // Start starts the hostagent in the background, which in turn will start the instance.
 // Start will listen to hostagent events and log them to STDOUT until either the instance
 // is running, or has failed to start.
@@ -122,10 +181,17 @@ func Prepare(ctx context.Context, inst *store.Instance) (*Prepared, error) {
 // shut down again.
 //
 // Start calls Prepare by itself, so you do not need to call Prepare manually before calling Start.
-func Start(ctx context.Context, inst *store.Instance, limactl string, launchHostAgentForeground bool) error {
+func Start(ctx context.Context, inst *store.Instance, limactl string, launchHostAgentForeground bool) (retErr error) {
+	ctx, span := tracing.Start(ctx, "instance.Start",
+		attribute.String("instance", inst.Name),
+		attribute.String("vmType", string(inst.VMType)))
+	defer func() { tracing.End(span, retErr) }()
+
 	haPIDPath := filepath.Join(inst.Dir, filenames.HostAgentPID)
 	if _, err := os.Stat(haPIDPath); !errors.Is(err, os.ErrNotExist) {
-		return fmt.Errorf("instance %q seems running (hint: remove %q if the instance is not actually running)", inst.Name, haPIDPath)
+		if err := removeStaleHostAgentPIDFile(haPIDPath); err != nil {
+			return fmt.Errorf("instance %q seems running (hint: remove %q if the instance is not actually running): %w", inst.Name, haPIDPath, err)
+		}
 	}
 	logrus.Infof("Starting the instance %q with VM driver %q", inst.Name, inst.VMType)
 
@@ -163,18 +229,19 @@ func Start(ctx context.Context, inst *store.Instance, limactl string, launchHost
 	}
 	haStdoutPath := filepath.Join(inst.Dir, filenames.HostAgentStdoutLog)
 	haStderrPath := filepath.Join(inst.Dir, filenames.HostAgentStderrLog)
-	if err := os.RemoveAll(haStdoutPath); err != nil {
+	// Remove the logs of the previous run, including the files rotated by the host agent
+	if err := logrotate.Remove(haStdoutPath); err != nil {
 		return err
 	}
-	if err := os.RemoveAll(haStderrPath); err != nil {
+	if err := logrotate.Remove(haStderrPath); err != nil {
 		return err
 	}
-	haStdoutW, err := os.Create(haStdoutPath)
+	haStdoutW, err := createHostAgentLog(haStdoutPath)
 	if err != nil {
 		return err
 	}
 	// no defer haStdoutW.Close()
-	haStderrW, err := os.Create(haStderrPath)
+	haStderrW, err := createHostAgentLog(haStderrPath)
 	if err != nil {
 		return err
 	}
@@ -194,8 +261,17 @@ func Start(ctx context.Context, inst *store.Instance, limactl string, launchHost
 	if prepared.NerdctlArchiveCache != "" {
 		args = append(args, "--nerdctl-archive", prepared.NerdctlArchiveCache)
 	}
+	if addr := inst.Config.GuestAgent.Address; addr != nil && *addr != "" {
+		args = append(args, "--guest-agent-address", *addr)
+	}
 	args = append(args, inst.Name)
-	haCmd := exec.CommandContext(ctx, limactl, args...)
+	// Not exec.CommandContext, as the host agent has to keep running after returning from Start.
+	// On cancellation during Start, the host agent is stopped by stopHostAgentOnCancel.
+	haCmd := exec.Command(limactl, args...)
+	// Propagate the span to the host agent, so that the spans of the host agent belong to the same trace
+	if env := tracing.Environ(ctx); len(env) > 0 {
+		haCmd.Env = append(os.Environ(), env...)
+	}
 
 	if launchHostAgentForeground {
 		haCmd.SysProcAttr = executil.ForegroundSysProcAttr
@@ -230,28 +306,43 @@ func Start(ctx context.Context, inst *store.Instance, limactl string, launchHost
 		if err := syscall.Exec(limactl, haCmd.Args, haCmd.Environ()); err != nil {
 			return err
 		}
-	} else if err := haCmd.Start(); err != nil {
+	}
+	_, spawnSpan := tracing.Start(ctx, "instance.spawnHostAgent")
+	if err := haCmd.Start(); err != nil {
+		tracing.End(spawnSpan, err)
 		return err
 	}
+	waitErrCh := make(chan error, 1)
+	go func() {
+		waitErrCh <- haCmd.Wait()
+		close(waitErrCh)
+	}()
 
-	if err := waitHostAgentStart(ctx, haPIDPath, haStderrPath); err != nil {
+	err = waitHostAgentStart(ctx, haPIDPath, haStderrPath)
+	tracing.End(spawnSpan, err)
+	if err != nil {
+		if ctx.Err() != nil {
+			stopHostAgentOnCancel(haCmd.Process, waitErrCh, haPIDPath, haStderrPath)
+		}
 		return err
 	}
 
 	watchErrCh := make(chan error)
 	go func() {
-		watchErrCh <- watchHostAgentEvents(ctx, inst, haStdoutPath, haStderrPath, begin)
+		watchCtx, watchSpan := tracing.Start(ctx, "instance.waitForRunning")
+		watchErr := watchHostAgentEvents(watchCtx, inst, haStdoutPath, haStderrPath, begin)
+		tracing.End(watchSpan, watchErr)
+		watchErrCh <- watchErr
 		close(watchErrCh)
 	}()
-	waitErrCh := make(chan error)
-	go func() {
-		waitErrCh <- haCmd.Wait()
-		close(waitErrCh)
-	}()
 
 	select {
 	case watchErr := <-watchErrCh:
 		// watchErr can be nil
+		if watchErr != nil && ctx.Err() != nil {
+			stopHostAgentOnCancel(haCmd.Process, waitErrCh, haPIDPath, haStderrPath)
+			return fmt.Errorf("canceled starting the instance %q: %w", inst.Name, ctx.Err())
+		}
 		return watchErr
 		// leave the hostagent process running
 	case waitErr := <-waitErrCh:
@@ -260,16 +351,122 @@ func Start(ctx context.Context, inst *store.Instance, limactl string, launchHost
 	}
 }
 
-func waitHostAgentStart(_ context.Context, haPIDPath, haStderrPath string) error {
-	begin := time.Now()
-	deadlineDuration := 5 * time.Second
-	deadline := begin.Add(deadlineDuration)
+// hostAgentCancelTimeout is the duration to wait for the host agent to shut down the VM,
+// when Start is canceled.
+const hostAgentCancelTimeout = time.Minute
+
+// stopHostAgentOnCancel stops the host agent launched by Start, when Start is canceled, e.g., with Ctrl-C.
+// The host agent is interrupted to shut down the VM gracefully, and killed if it does not exit in time.
+// The PID file left by the killed host agent is removed, while the logs are kept for the diagnosis.
+func stopHostAgentOnCancel(proc *os.Process, waitErrCh <-chan error, haPIDPath, haStderrPath string) {
+	logrus.Infof("Stopping the host agent process %d, as starting the instance was canceled", proc.Pid)
+	// os.Interrupt is not implemented on Windows
+	if err := proc.Signal(os.Interrupt); err != nil {
+		logrus.WithError(err).Debug("Failed to interrupt the host agent, killing it")
+		_ = proc.Kill()
+	}
+	select {
+	case <-waitErrCh:
+	case <-time.After(hostAgentCancelTimeout):
+		logrus.Warnf("The host agent process %d did not exit in %v, killing it (hint: run `limactl stop -f` to stop the VM, and see %q)",
+			proc.Pid, hostAgentCancelTimeout, haStderrPath)
+		_ = proc.Kill()
+		<-waitErrCh
+	}
+	// ReadPIDFile removes the PID file of the process that has already exited
+	if _, err := store.ReadPIDFile(haPIDPath); err != nil {
+		logrus.WithError(err).Warnf("Failed to remove the host agent PID file %q", haPIDPath)
+	}
+}
+
+// Attach attaches to the hostagent that is already running for the instance, and
+// logs its events to STDOUT until either the instance is running, or has failed to start.
+// Attach is useful when a previous `limactl start` was interrupted while the hostagent
+// kept running in the background.
+//
+// Attach returns an error if the hostagent is not running.
+// A stale PID file left by a dead hostagent is removed by store.ReadPIDFile.
+func Attach(ctx context.Context, inst *store.Instance) error {
+	haPIDPath := filepath.Join(inst.Dir, filenames.HostAgentPID)
+	haPID, err := hostAgentPID(haPIDPath)
+	if err != nil {
+		return err
+	}
+	if haPID == 0 {
+		return fmt.Errorf("the host agent of instance %q is not running", inst.Name)
+	}
+	logrus.Infof("Attaching to the host agent process %d of the instance %q", haPID, inst.Name)
+
+	haStdoutPath := filepath.Join(inst.Dir, filenames.HostAgentStdoutLog)
+	haStderrPath := filepath.Join(inst.Dir, filenames.HostAgentStderrLog)
+	// The events are replayed from the beginning of the log, so the zero begin time is used
+	// to propagate all the hostagent logs as well.
+	return watchHostAgentEvents(ctx, inst, haStdoutPath, haStderrPath, time.Time{})
+}
+
+// removeStaleHostAgentPIDFile removes the hostagent PID file if the hostagent is no longer running.
+// An error is returned if the hostagent still seems running.
+func removeStaleHostAgentPIDFile(haPIDPath string) error {
+	haPID, err := hostAgentPID(haPIDPath)
+	if err != nil {
+		return err
+	}
+	if haPID != 0 {
+		return fmt.Errorf("host agent process %d is running", haPID)
+	}
+	return nil
+}
+
+// hostAgentPID returns the PID of the running hostagent, or 0 if the hostagent is not running.
+// The PID file is removed if the process is no longer running,
+// or if the PID has been reused by a process that is not the hostagent.
+func hostAgentPID(haPIDPath string) (int, error) {
+	if _, err := os.Stat(haPIDPath); errors.Is(err, os.ErrNotExist) {
+		return 0, nil
+	}
+	// ReadPIDFile removes the PID file when the process has already terminated
+	haPID, err := store.ReadPIDFile(haPIDPath)
+	if err != nil {
+		return 0, err
+	}
+	if haPID == 0 {
+		logrus.Infof("Removed the stale host agent PID file %q", haPIDPath)
+		return 0, nil
+	}
+	cmdline, err := osutil.ProcessCommandLine(haPID)
+	if err != nil {
+		// Can't tell whether the process is the hostagent; assume it is.
+		logrus.WithError(err).Debugf("Failed to get the command line of process %d", haPID)
+		return haPID, nil
+	}
+	// The hostagent is launched with `--pidfile <haPIDPath>`
+	if !strings.Contains(cmdline, "hostagent") || !strings.Contains(cmdline, haPIDPath) {
+		logrus.Infof("Removing the stale host agent PID file %q (PID %d is now used by an unrelated process %q)", haPIDPath, haPID, cmdline)
+		return 0, os.Remove(haPIDPath)
+	}
+	return haPID, nil
+}
+
+// hostAgentStartTimeout is the duration to wait for the host agent to create the PID file.
+const hostAgentStartTimeout = 5 * time.Second
+
+// waitHostAgentStart waits for the host agent to create the PID file.
+// It returns immediately with the context error when ctx is done.
+func waitHostAgentStart(ctx context.Context, haPIDPath, haStderrPath string) error {
+	timer := time.NewTimer(hostAgentStartTimeout)
+	defer timer.Stop()
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
 	for {
 		if _, err := os.Stat(haPIDPath); !errors.Is(err, os.ErrNotExist) {
 			return nil
 		}
-		if time.Now().After(deadline) {
-			return fmt.Errorf("hostagent (%q) did not start up in %v (hint: see %q)", haPIDPath, deadlineDuration, haStderrPath)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("canceled waiting for hostagent (%q) to start up: %w", haPIDPath, ctx.Err())
+		case <-timer.C:
+			return fmt.Errorf("hostagent (%q) did not start up in %v (hint: see %q)", haPIDPath, hostAgentStartTimeout, haStderrPath)
+		case <-ticker.C:
 		}
 	}
 }
@@ -298,7 +495,11 @@ func watchHostAgentEvents(ctx context.Context, inst *store.Instance, haStdoutPat
 		} else if ev.Status.Running {
 			receivedRunningEvent = true
 			if ev.Status.Degraded {
-				logrus.Warnf("DEGRADED. The VM seems running, but file sharing and port forwarding may not work. (hint: see %q)", haStderrPath)
+				if len(ev.Status.DegradedReasons) > 0 {
+					logrus.Warnf("DEGRADED. The VM seems running, but %s. (hint: see %q)", strings.Join(ev.Status.DegradedReasons, ", "), haStderrPath)
+				} else {
+					logrus.Warnf("DEGRADED. The VM seems running, but file sharing and port forwarding may not work. (hint: see %q)", haStderrPath)
+				}
 				err = fmt.Errorf("degraded, status=%+v", ev.Status)
 				return true
 			}
@@ -307,10 +508,18 @@ func watchHostAgentEvents(ctx context.Context, inst *store.Instance, haStdoutPat
 				err = xerr
 				return true
 			}
+			ready := "READY."
+			if d := ev.Status.CloudInitDuration; d > 0 {
+				ready = fmt.Sprintf("READY (boot took %s).", d.Round(time.Second))
+			}
 			if *inst.Config.Plain {
-				logrus.Infof("READY. Run `ssh -F %q %s` to open the shell.", inst.SSHConfigFile, inst.Hostname)
+				logrus.Infof("%s Run `ssh -F %q %s` to open the shell.", ready, inst.SSHConfigFile, inst.Hostname)
 			} else {
-				logrus.Infof("READY. Run `%s` to open the shell.", LimactlShellCmd(inst.Name))
+				logrus.Infof("%s Run `%s` to open the shell.", ready, LimactlShellCmd(inst.Name))
+			}
+			if !logrus.IsLevelEnabled(logrus.InfoLevel) {
+				// e.g., `limactl --quiet start`; the readiness is still detectable on stdout
+				fmt.Fprintln(os.Stdout, ready)
 			}
 			_ = ShowMessage(inst)
 			err = nil
@@ -360,7 +569,7 @@ func LimactlShellCmd(instName string) string {
 }
 
 func ShowMessage(inst *store.Instance) error {
-	if inst.Message == "" {
+	if inst.Message == "" || !logrus.IsLevelEnabled(logrus.InfoLevel) {
 		return nil
 	}
 	t, err := template.New("message").Parse(inst.Message)
@@ -0,0 +1,199 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	hostagentevents "github.com/lima-vm/lima/pkg/hostagent/events"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestWaitHostAgentStart(t *testing.T) {
+	dir := t.TempDir()
+	haPIDPath := filepath.Join(dir, "ha.pid")
+	haStderrPath := filepath.Join(dir, "ha.stderr.log")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+	begin := time.Now()
+	err := waitHostAgentStart(ctx, haPIDPath, haStderrPath)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Assert(t, time.Since(begin) < hostAgentStartTimeout, "the cancellation must not wait for the timeout")
+
+	time.AfterFunc(100*time.Millisecond, func() {
+		_ = os.WriteFile(haPIDPath, []byte("42\n"), 0o644)
+	})
+	assert.NilError(t, waitHostAgentStart(context.Background(), haPIDPath, haStderrPath))
+}
+
+func TestStopHostAgentOnCancel(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses the sleep command")
+	}
+	dir := t.TempDir()
+	haPIDPath := filepath.Join(dir, "ha.pid")
+	haStderrPath := filepath.Join(dir, "ha.stderr.log")
+
+	// A process that exits without removing its PID file
+	cmd := exec.Command("sleep", "60")
+	assert.NilError(t, cmd.Start())
+	assert.NilError(t, os.WriteFile(haPIDPath, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o644))
+	waitErrCh := make(chan error, 1)
+	go func() {
+		waitErrCh <- cmd.Wait()
+		close(waitErrCh)
+	}()
+
+	stopHostAgentOnCancel(cmd.Process, waitErrCh, haPIDPath, haStderrPath)
+	assert.Assert(t, cmd.ProcessState != nil)
+	_, err := os.Stat(haPIDPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestValidateAdditionalDisks(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	diskDir, err := store.DiskDir("data")
+	assert.NilError(t, err)
+	assert.NilError(t, os.MkdirAll(diskDir, 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(diskDir, filenames.DataDisk), nil, 0o644))
+
+	y := &limayaml.LimaYAML{
+		AdditionalDisks: []limayaml.Disk{{Name: "data", ReadOnly: ptr.Of(true)}},
+	}
+	assert.NilError(t, validateAdditionalDisks(y))
+
+	y.AdditionalDisks = append(y.AdditionalDisks, limayaml.Disk{Name: "missing"})
+	err = validateAdditionalDisks(y)
+	assert.ErrorContains(t, err, "additionalDisks[1]")
+	assert.ErrorContains(t, err, `disk "missing" does not exist`)
+}
+
+func TestCreateHostAgentLog(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "ha.stderr.log")
+	assert.NilError(t, os.WriteFile(logPath, []byte("previous run\n"), 0o644))
+	f, err := createHostAgentLog(logPath)
+	assert.NilError(t, err)
+	defer f.Close()
+
+	// Another writer appends to the same file, like the rotating log writer of the host agent
+	other, err := os.OpenFile(logPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	assert.NilError(t, err)
+	defer other.Close()
+
+	_, err = f.WriteString("foo\n")
+	assert.NilError(t, err)
+	_, err = other.WriteString("bar\n")
+	assert.NilError(t, err)
+	_, err = f.WriteString("baz\n")
+	assert.NilError(t, err)
+
+	b, err := os.ReadFile(logPath)
+	assert.NilError(t, err)
+	assert.Equal(t, string(b), "foo\nbar\nbaz\n")
+}
+
+func TestAttach(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test uses the sleep command")
+	}
+	instDir := t.TempDir()
+	inst := &store.Instance{
+		Name:   "foo",
+		Dir:    instDir,
+		Config: &limayaml.LimaYAML{Plain: ptr.Of(false)},
+	}
+	err := Attach(context.Background(), inst)
+	assert.ErrorContains(t, err, `the host agent of instance "foo" is not running`)
+
+	// A process that stands for the running host agent; "; :" prevents sh from exec-ing sleep
+	haPIDPath := filepath.Join(instDir, filenames.HostAgentPID)
+	cmd := exec.Command("sh", "-c", "sleep 60; :", "hostagent", "--pidfile", haPIDPath)
+	assert.NilError(t, cmd.Start())
+	t.Cleanup(func() {
+		_ = cmd.Process.Kill()
+		_ = cmd.Wait()
+	})
+	assert.NilError(t, os.WriteFile(haPIDPath, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.HostAgentStderrLog), nil, 0o644))
+
+	writeEvents := func(events ...hostagentevents.Event) {
+		var b []byte
+		for _, ev := range events {
+			j, err := json.Marshal(ev)
+			assert.NilError(t, err)
+			b = append(append(b, j...), '\n')
+		}
+		assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.HostAgentStdoutLog), b, 0o644))
+	}
+	ctx := WithWatchHostAgentTimeout(context.Background(), 10*time.Second)
+
+	// The events logged before attaching are replayed
+	writeEvents(
+		hostagentevents.Event{Time: time.Now(), Status: hostagentevents.Status{SSHLocalPort: 60022}},
+		hostagentevents.Event{Time: time.Now(), Status: hostagentevents.Status{Running: true}},
+	)
+	assert.NilError(t, Attach(ctx, inst))
+
+	writeEvents(hostagentevents.Event{Time: time.Now(), Status: hostagentevents.Status{Exiting: true}})
+	assert.ErrorContains(t, Attach(ctx, inst), "exiting")
+
+	if runtime.GOOS == "linux" {
+		// The PID has been reused by a process that is not the host agent
+		unrelated := exec.Command("sleep", "60")
+		assert.NilError(t, unrelated.Start())
+		t.Cleanup(func() {
+			_ = unrelated.Process.Kill()
+			_ = unrelated.Wait()
+		})
+		assert.NilError(t, os.WriteFile(haPIDPath, []byte(strconv.Itoa(unrelated.Process.Pid)+"\n"), 0o644))
+		assert.ErrorContains(t, Attach(ctx, inst), `the host agent of instance "foo" is not running`)
+	}
+}
+
+func TestRemoveStaleHostAgentPIDFile(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the test relies on /proc/PID/cmdline")
+	}
+	haPIDPath := filepath.Join(t.TempDir(), filenames.HostAgentPID)
+	startProcess := func(name string, args ...string) *exec.Cmd {
+		cmd := exec.Command(name, args...)
+		assert.NilError(t, cmd.Start())
+		t.Cleanup(func() {
+			_ = cmd.Process.Kill()
+			_ = cmd.Wait()
+		})
+		assert.NilError(t, os.WriteFile(haPIDPath, []byte(strconv.Itoa(cmd.Process.Pid)+"\n"), 0o644))
+		return cmd
+	}
+
+	// The process has already exited
+	exited := exec.Command("true")
+	assert.NilError(t, exited.Run())
+	assert.NilError(t, os.WriteFile(haPIDPath, []byte(strconv.Itoa(exited.Process.Pid)+"\n"), 0o644))
+	assert.NilError(t, removeStaleHostAgentPIDFile(haPIDPath))
+	_, err := os.Stat(haPIDPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// The PID has been reused by an unrelated process
+	startProcess("sleep", "60")
+	assert.NilError(t, removeStaleHostAgentPIDFile(haPIDPath))
+	_, err = os.Stat(haPIDPath)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// The host agent is still running; "; :" prevents sh from exec-ing sleep, which would replace the command line
+	startProcess("sh", "-c", "sleep 60; :", "hostagent", "--pidfile", haPIDPath)
+	assert.ErrorContains(t, removeStaleHostAgentPIDFile(haPIDPath), "is running")
+	_, err = os.Stat(haPIDPath)
+	assert.NilError(t, err)
+}
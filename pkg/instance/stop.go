@@ -9,6 +9,7 @@ import (
 	"strings"
 	"time"
 
+	hostagentclient "github.com/lima-vm/lima/pkg/hostagent/api/client"
 	hostagentevents "github.com/lima-vm/lima/pkg/hostagent/events"
 	"github.com/lima-vm/lima/pkg/osutil"
 	"github.com/lima-vm/lima/pkg/store"
@@ -16,11 +17,55 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func StopGracefully(inst *store.Instance) error {
+// DefaultStopTimeout is the duration to wait for the instance to shut down gracefully
+// before stopping it forcibly.
+const DefaultStopTimeout = 3*time.Minute + 10*time.Second
+
+// errStopTimeout is returned by waitForHostAgentTermination when the host agent
+// did not exit before the timeout.
+var errStopTimeout = errors.New("timed out waiting for the host agent to exit")
+
+// StopGracefully asks the host agent to shut down the instance, and waits up to timeout for
+// the host agent and the driver processes to exit.
+// If they do not exit in time, the instance is stopped forcibly with StopForcibly.
+// When timeout is 0, the driver uses its own default timeout for shutting down the guest,
+// and the host agent is waited for up to DefaultStopTimeout.
+func StopGracefully(ctx context.Context, inst *store.Instance, timeout time.Duration) error {
+	if timeout < 0 {
+		return fmt.Errorf("timeout must not be negative, got %v", timeout)
+	}
+	if inst.Status == store.StatusPaused {
+		// The paused guest cannot handle the power button
+		logrus.Infof("Resuming the paused instance %q before stopping it", inst.Name)
+		if err := Resume(ctx, inst); err != nil {
+			return err
+		}
+		inst.Status = store.StatusRunning
+	}
 	if inst.Status != store.StatusRunning {
 		return fmt.Errorf("expected status %q, got %q (maybe use `limactl stop -f`?)", store.StatusRunning, inst.Status)
 	}
 
+	var diskSizeBeforeTrim int64
+	if shouldTrimDisk(inst) {
+		var err error
+		diskSizeBeforeTrim, err = diffDiskActualSize(inst)
+		if err != nil {
+			logrus.WithError(err).Debug("Failed to inspect the disk size before trimming")
+		}
+		logrus.Info("Trimming the guest filesystems")
+		if err := trimDisk(inst); err != nil {
+			logrus.WithError(err).Warn("Failed to trim the guest filesystems")
+			diskSizeBeforeTrim = 0
+		}
+	}
+
+	if timeout > 0 {
+		setStopTimeout(ctx, inst, driverStopTimeout(timeout))
+	} else {
+		timeout = DefaultStopTimeout
+	}
+
 	begin := time.Now() // used for logrus propagation
 	logrus.Infof("Sending SIGINT to hostagent process %d", inst.HostAgentPID)
 	if err := osutil.SysKill(inst.HostAgentPID, osutil.SigInt); err != nil {
@@ -28,11 +73,43 @@ func StopGracefully(inst *store.Instance) error {
 	}
 
 	logrus.Info("Waiting for the host agent and the driver processes to shut down")
-	return waitForHostAgentTermination(context.TODO(), inst, begin)
+	err := waitForHostAgentTermination(ctx, inst, begin, timeout)
+	if errors.Is(err, errStopTimeout) {
+		logrus.Warnf("The instance %q did not shut down in %v, stopping it forcibly (forced stop)", inst.Name, timeout)
+		StopForcibly(inst)
+		return nil
+	}
+	if err == nil && diskSizeBeforeTrim > 0 {
+		reportReclaimedDiskSpace(inst, diskSizeBeforeTrim)
+	}
+	return err
+}
+
+// driverStopTimeout returns the timeout for the driver to shut down the guest, within the timeout of StopGracefully.
+// The driver stops the vm forcibly on its timeout, so some time is left for the host agent to exit cleanly after that.
+func driverStopTimeout(timeout time.Duration) time.Duration {
+	const hostAgentExitTime = 10 * time.Second
+	if timeout > 2*hostAgentExitTime {
+		return timeout - hostAgentExitTime
+	}
+	return timeout / 2
+}
+
+// setStopTimeout tells the host agent the timeout for the driver to shut down the guest.
+// A failure is not fatal, as the driver still stops the vm with its default timeout.
+func setStopTimeout(ctx context.Context, inst *store.Instance, timeout time.Duration) {
+	haSock := filepath.Join(inst.Dir, filenames.HostAgentSock)
+	haClient, err := hostagentclient.NewHostAgentClient(haSock, hostagentclient.WithTimeout(10*time.Second))
+	if err == nil {
+		err = haClient.SetStopTimeout(ctx, timeout)
+	}
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to set the stop timeout of the host agent, the driver will use its default timeout")
+	}
 }
 
-func waitForHostAgentTermination(ctx context.Context, inst *store.Instance, begin time.Time) error {
-	ctx2, cancel := context.WithTimeout(ctx, 3*time.Minute+10*time.Second)
+func waitForHostAgentTermination(ctx context.Context, inst *store.Instance, begin time.Time, timeout time.Duration) error {
+	ctx2, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
 	var receivedExitingEvent bool
@@ -55,6 +132,9 @@ func waitForHostAgentTermination(ctx context.Context, inst *store.Instance, begi
 	}
 
 	if !receivedExitingEvent {
+		if errors.Is(ctx2.Err(), context.DeadlineExceeded) {
+			return errStopTimeout
+		}
 		return errors.New("did not receive an event with the \"exiting\" status")
 	}
 
@@ -67,6 +147,7 @@ func StopForcibly(inst *store.Instance) {
 		if err := osutil.SysKill(inst.DriverPID, osutil.SigKill); err != nil {
 			logrus.Error(err)
 		}
+		waitForDriverTermination(inst)
 	} else {
 		logrus.Infof("The %s driver process seems already stopped", inst.VMType)
 	}
@@ -78,7 +159,11 @@ func StopForcibly(inst *store.Instance) {
 			logrus.Warnf("Disk %q does not exist", diskName)
 			continue
 		}
-		if err := disk.Unlock(); err != nil {
+		unlock := disk.Unlock
+		if d.ReadOnly != nil && *d.ReadOnly {
+			unlock = func() error { return disk.UnlockReadOnly(inst.Name) }
+		}
+		if err := unlock(); err != nil {
 			logrus.Warnf("Failed to unlock disk %q. To use, run `limactl disk unlock %v`", diskName, diskName)
 		}
 	}
@@ -117,3 +202,27 @@ func StopForcibly(inst *store.Instance) {
 		}
 	}
 }
+
+// waitForDriverTermination waits for the driver process to exit, so that the host agent
+// is terminated and the PID files are removed only after the VM is confirmed to be down.
+func waitForDriverTermination(inst *store.Instance) {
+	pidPath := filepath.Join(inst.Dir, filenames.PIDFile(inst.VMType))
+	timeout := 10 * time.Second
+	deadline := time.Now().Add(timeout)
+	for {
+		// ReadPIDFile returns 0 when the process has already terminated
+		pid, err := store.ReadPIDFile(pidPath)
+		if err != nil {
+			logrus.WithError(err).Debugf("Failed to read %q", pidPath)
+			return
+		}
+		if pid == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			logrus.Warnf("The %s driver process %d did not exit in %v", inst.VMType, pid, timeout)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,22 @@
+package instance
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestDriverStopTimeout(t *testing.T) {
+	assert.Equal(t, driverStopTimeout(DefaultStopTimeout), 3*time.Minute)
+	assert.Equal(t, driverStopTimeout(30*time.Second), 20*time.Second)
+	assert.Equal(t, driverStopTimeout(10*time.Second), 5*time.Second)
+}
+
+func TestStopGracefullyNegativeTimeout(t *testing.T) {
+	inst := &store.Instance{Name: "default", Status: store.StatusRunning}
+	err := StopGracefully(context.Background(), inst, -time.Second)
+	assert.Error(t, err, "timeout must not be negative, got -1s")
+}
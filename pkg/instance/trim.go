@@ -0,0 +1,86 @@
+package instance
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+
+	"github.com/docker/go-units"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/qemu/imgutil"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/lima-vm/sshocker/pkg/ssh"
+	"github.com/sirupsen/logrus"
+)
+
+// shouldTrimDisk returns true when `disk.autoTrim` is enabled and the instance is healthy enough
+// to run `fstrim` over SSH.
+func shouldTrimDisk(inst *store.Instance) bool {
+	if inst.Config == nil || inst.Config.Disk.AutoTrim == nil || !*inst.Config.Disk.AutoTrim {
+		return false
+	}
+	if *inst.Config.VMType != limayaml.QEMU {
+		// Only QEMU attaches the disks with discard enabled; VZ and WSL2 do not reclaim the trimmed blocks on the host
+		logrus.Debugf("disk.autoTrim is not supported for vmType %q", *inst.Config.VMType)
+		return false
+	}
+	if inst.Status != store.StatusRunning || len(inst.Errors) > 0 {
+		logrus.Warnf("Skipping disk trim, as the instance %q is not healthy", inst.Name)
+		return false
+	}
+	return true
+}
+
+// trimDisk runs `fstrim` in the guest, so that the freed blocks are discarded from the disk image.
+// No separate `qemu-img` step is needed after stopping the vm: QEMU attaches the disks with `discard=on`,
+// so the blocks trimmed in the guest are punched out of the image while the vm is running.
+func trimDisk(inst *store.Instance) error {
+	sshOpts, err := inst.SSHOpts("ssh",
+		sshutil.WithForwardAgent(false),
+		sshutil.WithForwardX11(false))
+	if err != nil {
+		return err
+	}
+	sshConfig := &ssh.SSHConfig{
+		AdditionalArgs: sshutil.SSHArgsFromOpts(sshOpts),
+	}
+	const script = `#!/bin/sh
+set -eu
+sudo fstrim --all --verbose
+`
+	stdout, stderr, err := ssh.ExecuteScript(inst.SSHAddress, inst.SSHLocalPort, sshConfig, script, "fstrim")
+	if err != nil {
+		return fmt.Errorf("failed to run fstrim: stdout=%q, stderr=%q: %w", stdout, stderr, err)
+	}
+	logrus.Debugf("fstrim: %s", stdout)
+	return nil
+}
+
+// diffDiskActualSize returns the size of the diff disk allocated on the host.
+func diffDiskActualSize(inst *store.Instance) (int64, error) {
+	info, err := imgutil.GetInfo(filepath.Join(inst.Dir, filenames.DiffDisk))
+	if err != nil {
+		return 0, err
+	}
+	if info.ActualSize == 0 {
+		return 0, errors.New("qemu-img did not report the actual size")
+	}
+	return info.ActualSize, nil
+}
+
+// reportReclaimedDiskSpace logs the difference of the diff disk size from sizeBefore.
+func reportReclaimedDiskSpace(inst *store.Instance, sizeBefore int64) {
+	sizeAfter, err := diffDiskActualSize(inst)
+	if err != nil {
+		logrus.WithError(err).Debug("Failed to inspect the disk size after trimming")
+		return
+	}
+	reclaimed := sizeBefore - sizeAfter
+	if reclaimed < 0 {
+		reclaimed = 0
+	}
+	logrus.Infof("Reclaimed %s of disk space (%s -> %s)",
+		units.BytesSize(float64(reclaimed)), units.BytesSize(float64(sizeBefore)), units.BytesSize(float64(sizeAfter)))
+}
@@ -0,0 +1,126 @@
+package instance
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"gotest.tools/v3/assert"
+)
+
+func TestShouldTrimDisk(t *testing.T) {
+	newInstance := func(vmType limayaml.VMType, autoTrim *bool) *store.Instance {
+		return &store.Instance{
+			Name:   "default",
+			Status: store.StatusRunning,
+			Config: &limayaml.LimaYAML{
+				VMType: ptr.Of(vmType),
+				Disk:   limayaml.PrimaryDisk{AutoTrim: autoTrim},
+			},
+		}
+	}
+
+	assert.Assert(t, shouldTrimDisk(newInstance(limayaml.QEMU, ptr.Of(true))))
+	assert.Assert(t, !shouldTrimDisk(newInstance(limayaml.QEMU, ptr.Of(false))))
+	assert.Assert(t, !shouldTrimDisk(newInstance(limayaml.QEMU, nil)))
+	assert.Assert(t, !shouldTrimDisk(newInstance(limayaml.VZ, ptr.Of(true))))
+	assert.Assert(t, !shouldTrimDisk(newInstance(limayaml.WSL2, ptr.Of(true))))
+	assert.Assert(t, !shouldTrimDisk(&store.Instance{Name: "default", Status: store.StatusRunning}))
+
+	stopped := newInstance(limayaml.QEMU, ptr.Of(true))
+	stopped.Status = store.StatusStopped
+	assert.Assert(t, !shouldTrimDisk(stopped))
+
+	broken := newInstance(limayaml.QEMU, ptr.Of(true))
+	broken.Errors = []error{os.ErrNotExist}
+	assert.Assert(t, !shouldTrimDisk(broken))
+}
+
+// fakeCommand writes an executable shell script into dir.
+func fakeCommand(t *testing.T, dir, name, script string) {
+	t.Helper()
+	assert.NilError(t, os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755))
+}
+
+func TestTrimDisk(t *testing.T) {
+	sshKeygen, err := exec.LookPath("ssh-keygen")
+	if err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	t.Setenv("LIMA_HOME", t.TempDir())
+	binDir := t.TempDir()
+	outDir := t.TempDir()
+	assert.NilError(t, os.Symlink(sshKeygen, filepath.Join(binDir, "ssh-keygen")))
+	fakeCommand(t, binDir, "ssh", `if [ "$1" = "-V" ]; then
+  echo "OpenSSH_9.6p1, OpenSSL 3.0.13 30 Jan 2024" >&2
+  exit 0
+fi
+echo "$@" >`+filepath.Join(outDir, "args")+`
+while IFS= read -r line; do echo "$line"; done >`+filepath.Join(outDir, "stdin")+`
+echo "/: 1 GiB (1073741824 bytes) trimmed"
+`)
+	t.Setenv("PATH", binDir)
+	_, err = sshutil.DefaultPubKeys(false)
+	assert.NilError(t, err)
+
+	inst := &store.Instance{
+		Name:         "default",
+		Dir:          t.TempDir(),
+		SSHAddress:   "127.0.0.1",
+		SSHLocalPort: 60022,
+		Config: &limayaml.LimaYAML{
+			User: limayaml.User{Name: ptr.Of("foo")},
+			SSH: limayaml.SSH{
+				LoadDotSSHPubKeys: ptr.Of(false),
+				ForwardAgent:      ptr.Of(true),
+				ForwardX11:        ptr.Of(true),
+				ForwardX11Trusted: ptr.Of(false),
+				KeepAlive:         limayaml.SSHKeepAlive{Interval: ptr.Of(30), CountMax: ptr.Of(3)},
+			},
+		},
+	}
+	assert.NilError(t, trimDisk(inst))
+
+	args, err := os.ReadFile(filepath.Join(outDir, "args"))
+	assert.NilError(t, err)
+	// the options of the instance are used, except for forwarding
+	assert.Assert(t, strings.Contains(string(args), "User=foo"), string(args))
+	assert.Assert(t, strings.Contains(string(args), "ServerAliveInterval=30"), string(args))
+	assert.Assert(t, !strings.Contains(string(args), "ForwardAgent=yes"), string(args))
+	assert.Assert(t, !strings.Contains(string(args), "ForwardX11=yes"), string(args))
+	assert.Assert(t, strings.Contains(string(args), "60022"), string(args))
+	stdin, err := os.ReadFile(filepath.Join(outDir, "stdin"))
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(stdin), "fstrim --all"), string(stdin))
+}
+
+func TestReportReclaimedDiskSpace(t *testing.T) {
+	binDir := t.TempDir()
+	fakeCommand(t, binDir, "qemu-img", `echo '{"virtual-size": 107374182400, "actual-size": 1073741824, "format": "qcow2"}'
+`)
+	t.Setenv("PATH", binDir)
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+
+	inst := &store.Instance{Name: "default", Dir: t.TempDir()}
+	reportReclaimedDiskSpace(inst, 3*1073741824)
+	entry := hook.LastEntry()
+	assert.Assert(t, entry != nil)
+	assert.Equal(t, entry.Level, logrus.InfoLevel)
+	assert.Equal(t, entry.Message, "Reclaimed 2GiB of disk space (3GiB -> 1GiB)")
+
+	// the disk may grow while the guest is shutting down
+	hook.Reset()
+	reportReclaimedDiskSpace(inst, 1024)
+	entry = hook.LastEntry()
+	assert.Assert(t, entry != nil)
+	assert.Assert(t, strings.HasPrefix(entry.Message, "Reclaimed 0B of disk space"), entry.Message)
+}
@@ -1,7 +1,6 @@
 package ioutilx
 
 import (
-	"errors"
 	"fmt"
 	"io"
 	"os/exec"
@@ -13,18 +12,20 @@ import (
 )
 
 // ReadAtMaximum reads n at maximum.
+// An error is returned when r has more than n bytes.
 func ReadAtMaximum(r io.Reader, n int64) ([]byte, error) {
 	lr := &io.LimitedReader{
 		R: r,
-		N: n,
+		N: n + 1,
 	}
 	b, err := io.ReadAll(lr)
 	if err != nil {
-		if errors.Is(err, io.EOF) && lr.N <= 0 {
-			err = fmt.Errorf("exceeded the limit (%d bytes): %w", n, err)
-		}
+		return b, err
 	}
-	return b, err
+	if int64(len(b)) > n {
+		return b[:n], fmt.Errorf("exceeded the limit (%d bytes)", n)
+	}
+	return b, nil
 }
 
 // FromUTF16le returns an io.Reader for UTF16le data.
@@ -0,0 +1,55 @@
+package ioutilx
+
+import (
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadAtMaximum(t *testing.T) {
+	testCases := []struct {
+		name     string
+		input    string
+		n        int64
+		expected string
+		err      string
+	}{
+		{
+			name:     "empty",
+			input:    "",
+			n:        4,
+			expected: "",
+		},
+		{
+			name:     "shorter than the limit",
+			input:    "foo",
+			n:        4,
+			expected: "foo",
+		},
+		{
+			name:     "exactly the limit",
+			input:    "foob",
+			n:        4,
+			expected: "foob",
+		},
+		{
+			name:     "exceeding the limit",
+			input:    "foobar",
+			n:        4,
+			expected: "foob",
+			err:      "exceeded the limit (4 bytes)",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := ReadAtMaximum(strings.NewReader(tc.input), tc.n)
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+			} else {
+				assert.NilError(t, err)
+			}
+			assert.Equal(t, string(b), tc.expected)
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package iso9660util
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestWriteLabel(t *testing.T) {
+	isoPath := filepath.Join(t.TempDir(), "cidata.iso")
+	layout := []Entry{
+		{Path: "meta-data", Reader: strings.NewReader("instance-id: foo\n")},
+		{Path: "user-data", Reader: strings.NewReader("#cloud-config\n")},
+	}
+	assert.NilError(t, Write(isoPath, "config-2", layout))
+
+	isISO, err := IsISO9660(isoPath)
+	assert.NilError(t, err)
+	assert.Assert(t, isISO)
+
+	// The volume identifier is at the offset 40 of the primary volume descriptor (sector 16)
+	b, err := os.ReadFile(isoPath)
+	assert.NilError(t, err)
+	const pvd = 16 * 2048
+	assert.Equal(t, b[pvd], byte(1))
+	assert.Assert(t, strings.EqualFold(strings.TrimRight(string(b[pvd+40:pvd+72]), " \x00"), "config-2"))
+}
@@ -21,11 +21,21 @@ type Template struct {
 	Name    string
 	Locator string
 	Bytes   []byte
+	// Remote is true when the template was fetched over the network, e.g., from an http(s) URL.
+	Remote bool
 }
 
 const yBytesLimit = 4 * 1024 * 1024 // 4MiB
 
+// StdinLocator is the locator for reading the template from stdin.
+const StdinLocator = "-"
+
 func Read(ctx context.Context, name, locator string) (*Template, error) {
+	return ReadWithStdin(ctx, os.Stdin, name, locator)
+}
+
+// ReadWithStdin is like Read, but reads the template from stdin instead of os.Stdin for StdinLocator.
+func ReadWithStdin(ctx context.Context, stdin io.Reader, name, locator string) (*Template, error) {
 	var err error
 
 	tmpl := &Template{
@@ -33,6 +43,14 @@ func Read(ctx context.Context, name, locator string) (*Template, error) {
 		Locator: locator,
 	}
 
+	isRepoTemplate, err := resolveRepoTemplate(ctx, tmpl)
+	if err != nil {
+		return nil, err
+	}
+	if isRepoTemplate {
+		return tmpl, nil
+	}
+
 	isTemplateURL, templateURL := SeemsTemplateURL(locator)
 	switch {
 	case isTemplateURL:
@@ -55,19 +73,11 @@ func Read(ctx context.Context, name, locator string) (*Template, error) {
 			}
 		}
 		logrus.Debugf("interpreting argument %q as a http url for instance %q", locator, tmpl.Name)
-		req, err := http.NewRequestWithContext(ctx, http.MethodGet, locator, http.NoBody)
-		if err != nil {
-			return nil, err
-		}
-		resp, err := http.DefaultClient.Do(req)
-		if err != nil {
-			return nil, err
-		}
-		defer resp.Body.Close()
-		tmpl.Bytes, err = ioutilx.ReadAtMaximum(resp.Body, yBytesLimit)
+		tmpl.Bytes, err = readHTTP(ctx, locator)
 		if err != nil {
 			return nil, err
 		}
+		tmpl.Remote = true
 	case SeemsFileURL(locator):
 		if tmpl.Name == "" {
 			tmpl.Name, err = InstNameFromURL(locator)
@@ -102,8 +112,8 @@ func Read(ctx context.Context, name, locator string) (*Template, error) {
 		if err != nil {
 			return nil, err
 		}
-	case locator == "-":
-		tmpl.Bytes, err = io.ReadAll(os.Stdin)
+	case locator == StdinLocator:
+		tmpl.Bytes, err = ioutilx.ReadAtMaximum(stdin, yBytesLimit)
 		if err != nil {
 			return nil, fmt.Errorf("unexpected error reading stdin: %w", err)
 		}
@@ -111,6 +121,22 @@ func Read(ctx context.Context, name, locator string) (*Template, error) {
 	return tmpl, nil
 }
 
+func readHTTP(ctx context.Context, u string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, http.NoBody)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch %q: %s", u, resp.Status)
+	}
+	return ioutilx.ReadAtMaximum(resp.Body, yBytesLimit)
+}
+
 func SeemsTemplateURL(arg string) (bool, *url.URL) {
 	u, err := url.Parse(arg)
 	if err != nil {
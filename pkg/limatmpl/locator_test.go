@@ -0,0 +1,21 @@
+package limatmpl
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestReadWithStdin(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	const content = "cpus: 2\n"
+	tmpl, err := ReadWithStdin(context.Background(), strings.NewReader(content), "foo", StdinLocator)
+	assert.NilError(t, err)
+	assert.Equal(t, tmpl.Name, "foo")
+	assert.Equal(t, string(tmpl.Bytes), content)
+
+	_, err = ReadWithStdin(context.Background(), strings.NewReader(strings.Repeat("#", yBytesLimit+1)), "foo", StdinLocator)
+	assert.ErrorContains(t, err, "exceeded")
+}
@@ -0,0 +1,218 @@
+package limatmpl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	securejoin "github.com/cyphar/filepath-securejoin"
+	"github.com/goccy/go-yaml"
+	"github.com/lima-vm/lima/pkg/ioutilx"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store/dirnames"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+// Repos returns the template repositories configured in `$LIMA_HOME/_config/template-repos.yaml`.
+// The file maps a repository name to its base, which is either a local directory,
+// a file:// URL, an http(s) URL, or a git repository URL prefixed with "git+",
+// optionally followed by "#<branch or tag>":
+//
+//	team: https://example.com/lima-templates
+//	local: /opt/lima-templates
+//	upstream: git+https://github.com/example/lima-templates.git#main
+//
+// A template in a repository is referenced as "team:golang-dev", which resolves to
+// "https://example.com/lima-templates/golang-dev.yaml".
+// The git repositories are cloned with the `git` command each time a template is read from them.
+func Repos() (map[string]string, error) {
+	configDir, err := dirnames.LimaConfigDir()
+	if err != nil {
+		return nil, err
+	}
+	reposPath := filepath.Join(configDir, filenames.TemplateRepos)
+	b, err := os.ReadFile(reposPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var repos map[string]string
+	if err := yaml.UnmarshalWithOptions(b, &repos, yaml.Strict()); err != nil {
+		return nil, fmt.Errorf("cannot parse %q: %w", reposPath, err)
+	}
+	for name := range repos {
+		if err := validateRepoName(name); err != nil {
+			return nil, fmt.Errorf("invalid repository in %q: %w", reposPath, err)
+		}
+	}
+	return repos, nil
+}
+
+var repoNameRegexp = regexp.MustCompile(`^[a-z][a-z0-9-]+$`)
+
+// builtinSchemes cannot be used as repository names, as they already have a meaning as template locators.
+var builtinSchemes = []string{"template", "http", "https", "file"}
+
+func validateRepoName(name string) error {
+	// Single-letter names are rejected by the regexp, so that Windows drive letters are never
+	// interpreted as repository names.
+	if !repoNameRegexp.MatchString(name) {
+		return fmt.Errorf("repository name %q must match %s", name, repoNameRegexp.String())
+	}
+	for _, scheme := range builtinSchemes {
+		if name == scheme {
+			return fmt.Errorf("repository name %q is reserved", name)
+		}
+	}
+	return nil
+}
+
+// splitRepoLocator splits a locator like "team:golang-dev" into the repository name and the template name.
+func splitRepoLocator(locator string) (repoName, templateName string, ok bool) {
+	repoName, templateName, ok = strings.Cut(locator, ":")
+	if !ok || validateRepoName(repoName) != nil || templateName == "" || strings.HasPrefix(templateName, "/") {
+		return "", "", false
+	}
+	return repoName, templateName, true
+}
+
+// validateRepoTemplateName rejects the template names that could escape the repository base,
+// such as "../secret" or "go/../../secret".
+func validateRepoTemplateName(templateName string) error {
+	if strings.Contains(templateName, `\`) {
+		return fmt.Errorf("template name %q must not contain a backslash", templateName)
+	}
+	for _, elem := range strings.Split(templateName, "/") {
+		switch elem {
+		case "", ".", "..":
+			return fmt.Errorf("template name %q must not contain an empty, \".\", or \"..\" path element", templateName)
+		}
+	}
+	return nil
+}
+
+// gitRepoPrefix is the prefix of the repository bases that are git repositories.
+const gitRepoPrefix = "git+"
+
+func isGitRepo(base string) bool {
+	return strings.HasPrefix(base, gitRepoPrefix)
+}
+
+// readRepoTemplate reads the template from the repository base.
+func readRepoTemplate(ctx context.Context, base, templateName string) (string, []byte, error) {
+	if SeemsHTTPURL(base) {
+		u := strings.TrimSuffix(base, "/") + "/" + templateName + ".yaml"
+		b, err := readHTTP(ctx, u)
+		return u, b, err
+	}
+	if isGitRepo(base) {
+		return readGitRepoTemplate(ctx, base, templateName)
+	}
+	return readLocalRepoTemplate(strings.TrimPrefix(base, "file://"), templateName)
+}
+
+// readGitRepoTemplate clones the git repository into a temporary directory, and reads the template from it.
+func readGitRepoTemplate(ctx context.Context, base, templateName string) (string, []byte, error) {
+	repoURL, ref, _ := strings.Cut(strings.TrimPrefix(base, gitRepoPrefix), "#")
+	if repoURL == "" {
+		return "", nil, fmt.Errorf("git repository %q has no URL", base)
+	}
+	dir, err := os.MkdirTemp("", "lima-template-repo-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.RemoveAll(dir)
+	args := []string{"clone", "--quiet", "--depth=1"}
+	if ref != "" {
+		args = append(args, "--branch", ref)
+	}
+	args = append(args, "--", repoURL, dir)
+	cmd := exec.CommandContext(ctx, "git", args...)
+	// Fail instead of prompting for credentials
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return "", nil, fmt.Errorf("failed to run %v: %q: %w", cmd.Args, string(out), err)
+	}
+	_, b, err := readLocalRepoTemplate(dir, templateName)
+	source := strings.TrimSuffix(repoURL, "/") + "/" + templateName + ".yaml"
+	if ref != "" {
+		source += " (" + ref + ")"
+	}
+	return source, b, err
+}
+
+// readLocalRepoTemplate reads the template from the repository directory.
+func readLocalRepoTemplate(dir, templateName string) (string, []byte, error) {
+	yamlPath, err := securejoin.SecureJoin(dir, templateName+".yaml")
+	if err != nil {
+		return "", nil, err
+	}
+	r, err := os.Open(yamlPath)
+	if err != nil {
+		return "", nil, err
+	}
+	defer r.Close()
+	b, err := ioutilx.ReadAtMaximum(r, yBytesLimit)
+	return yamlPath, b, err
+}
+
+// validateRepoTemplate validates the template, as templates in third-party repositories
+// are not tested along with Lima.
+func validateRepoTemplate(tmpl *Template) error {
+	limaDir, err := dirnames.LimaDir()
+	if err != nil {
+		return err
+	}
+	// Load() needs the potential instance directory to validate host templates using {{.Dir}}.
+	y, err := limayaml.Load(tmpl.Bytes, filepath.Join(limaDir, tmpl.Name))
+	if err != nil {
+		return err
+	}
+	return limayaml.Validate(y, false)
+}
+
+// resolveRepoTemplate resolves the locator like "team:golang-dev" against the configured repositories.
+// It returns false when the locator does not refer to a configured repository.
+func resolveRepoTemplate(ctx context.Context, tmpl *Template) (bool, error) {
+	repoName, templateName, ok := splitRepoLocator(tmpl.Locator)
+	if !ok {
+		return false, nil
+	}
+	repos, err := Repos()
+	if err != nil {
+		return false, err
+	}
+	base, ok := repos[repoName]
+	if !ok {
+		return false, nil
+	}
+	if err := validateRepoTemplateName(templateName); err != nil {
+		return false, err
+	}
+	if tmpl.Name == "" {
+		// e.g., templateName = "go/golang-dev", tmpl.Name = "golang-dev"
+		tmpl.Name, err = InstNameFromYAMLPath(templateName)
+		if err != nil {
+			return false, err
+		}
+	}
+	source, b, err := readRepoTemplate(ctx, base, templateName)
+	if err != nil {
+		return false, fmt.Errorf("failed to read template %q from repository %q (%q): %w", templateName, repoName, base, err)
+	}
+	tmpl.Bytes = b
+	tmpl.Remote = SeemsHTTPURL(base) || isGitRepo(base)
+	logrus.Infof("Using template %q from repository %q (%q)", templateName, repoName, source)
+	if err := validateRepoTemplate(tmpl); err != nil {
+		return false, fmt.Errorf("failed to validate template %q from repository %q: %w", templateName, repoName, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,91 @@
+package limatmpl
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestSplitRepoLocator(t *testing.T) {
+	cases := []struct {
+		locator      string
+		repoName     string
+		templateName string
+		ok           bool
+	}{
+		{"team:golang-dev", "team", "golang-dev", true},
+		{"team:go/golang-dev", "team", "go/golang-dev", true},
+		{"template://default", "", "", false},
+		{"https://example.com/default.yaml", "", "", false},
+		{"file:///tmp/default.yaml", "", "", false},
+		{`C:\Users\foo\default.yaml`, "", "", false},
+		{"team:", "", "", false},
+		{"team:/etc/passwd", "", "", false},
+		{"default.yaml", "", "", false},
+	}
+	for _, tc := range cases {
+		t.Run(tc.locator, func(t *testing.T) {
+			repoName, templateName, ok := splitRepoLocator(tc.locator)
+			assert.Equal(t, ok, tc.ok)
+			assert.Equal(t, repoName, tc.repoName)
+			assert.Equal(t, templateName, tc.templateName)
+		})
+	}
+}
+
+func TestValidateRepoName(t *testing.T) {
+	assert.NilError(t, validateRepoName("team"))
+	assert.ErrorContains(t, validateRepoName("template"), "reserved")
+	assert.ErrorContains(t, validateRepoName("c"), "must match")
+	assert.ErrorContains(t, validateRepoName("Team"), "must match")
+}
+
+func TestValidateRepoTemplateName(t *testing.T) {
+	assert.NilError(t, validateRepoTemplateName("golang-dev"))
+	assert.NilError(t, validateRepoTemplateName("go/golang-dev"))
+	for _, name := range []string{"../secret", "go/../../secret", "go/..", "./golang-dev", "go//golang-dev", `go\golang-dev`} {
+		assert.Assert(t, validateRepoTemplateName(name) != nil, name)
+	}
+}
+
+func TestReadGitRepoTemplate(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	repoDir := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repoDir, "-c", "user.name=lima", "-c", "user.email=lima@localhost"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		assert.NilError(t, err, string(out))
+	}
+	git("init", "--quiet")
+	assert.NilError(t, os.MkdirAll(filepath.Join(repoDir, "go"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(repoDir, "go", "golang-dev.yaml"), []byte("cpus: 2\n"), 0o644))
+	git("add", ".")
+	git("commit", "--quiet", "-m", "v1")
+	git("tag", "v1")
+	assert.NilError(t, os.WriteFile(filepath.Join(repoDir, "go", "golang-dev.yaml"), []byte("cpus: 4\n"), 0o644))
+	git("commit", "--quiet", "-a", "-m", "v2")
+
+	ctx := context.Background()
+	base := "git+file://" + filepath.ToSlash(repoDir)
+	source, b, err := readRepoTemplate(ctx, base, "go/golang-dev")
+	assert.NilError(t, err)
+	assert.Equal(t, source, "file://"+filepath.ToSlash(repoDir)+"/go/golang-dev.yaml")
+	assert.Equal(t, string(b), "cpus: 4\n")
+
+	source, b, err = readRepoTemplate(ctx, base+"#v1", "go/golang-dev")
+	assert.NilError(t, err)
+	assert.Equal(t, source, "file://"+filepath.ToSlash(repoDir)+"/go/golang-dev.yaml (v1)")
+	assert.Equal(t, string(b), "cpus: 2\n")
+
+	_, _, err = readRepoTemplate(ctx, base, "go/missing")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, _, err = readRepoTemplate(ctx, base+"#missing", "go/golang-dev")
+	assert.ErrorContains(t, err, "failed to run")
+}
@@ -108,6 +108,13 @@ func FirstUsernetIndex(l *LimaYAML) int {
 	return slices.IndexFunc(l.Networks, func(network Network) bool { return networks.IsUsernet(network.Lima) })
 }
 
+// MACAddress returns a locally administered MAC address derived from uniqueID and the machine ID of the host.
+// The result is deterministic: the same uniqueID always results in the same address on the same host,
+// so that the DHCP servers can assign the same IP address across the restarts of the instance.
+//
+// The instance directory is used as uniqueID for the user-mode network, and "<lima.yaml path>#<index>"
+// for the other networks without the explicit macAddress.
+// So, the address changes when the instance is renamed, or $LIMA_HOME is moved.
 func MACAddress(uniqueID string) string {
 	sha := sha256.Sum256([]byte(osutil.MachineID() + uniqueID))
 	// "5" is the magic number in the Lima ecosystem.
@@ -121,26 +128,6 @@ func MACAddress(uniqueID string) string {
 	return hw.String()
 }
 
-func hostTimeZone() string {
-	// WSL2 will automatically set the timezone
-	if runtime.GOOS != "windows" {
-		tz, err := os.ReadFile("/etc/timezone")
-		if err == nil {
-			return strings.TrimSpace(string(tz))
-		}
-		zoneinfoFile, err := filepath.EvalSymlinks("/etc/localtime")
-		if err == nil {
-			for baseDir := filepath.Dir(zoneinfoFile); baseDir != "/"; baseDir = filepath.Dir(baseDir) {
-				if _, err = os.Stat(filepath.Join(baseDir, "Etc/UTC")); err == nil {
-					return strings.TrimPrefix(zoneinfoFile, baseDir+"/")
-				}
-			}
-			logrus.Warnf("could not locate zoneinfo directory from %q", zoneinfoFile)
-		}
-	}
-	return ""
-}
-
 func defaultCPUs() int {
 	const x = 4
 	if hostCPUs := runtime.NumCPU(); hostCPUs < x {
@@ -184,6 +171,8 @@ func defaultGuestInstallPrefix() string {
 //   - Networks are appended in d, y, o order
 //   - DNS are picked from the highest priority where DNS is not empty.
 //   - CACertificates Files and Certs are uniquely appended in d, y, o order
+//   - SSH AdditionalAuthorizedKeys are uniquely appended in d, y, o order
+//   - User Groups are uniquely appended in d, y, o order
 func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 	instDir := filepath.Dir(filePath)
 
@@ -215,6 +204,15 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 	if y.User.UID == nil {
 		y.User.UID = d.User.UID
 	}
+	if y.User.Password == nil {
+		y.User.Password = d.User.Password
+	}
+	if y.User.InsecurePlainTextPassword == nil {
+		y.User.InsecurePlainTextPassword = d.User.InsecurePlainTextPassword
+	}
+	if y.User.LockPassword == nil {
+		y.User.LockPassword = d.User.LockPassword
+	}
 	if o.User.Name != nil {
 		y.User.Name = o.User.Name
 	}
@@ -230,6 +228,16 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 	if o.User.UID != nil {
 		y.User.UID = o.User.UID
 	}
+	if o.User.Password != nil {
+		y.User.Password = o.User.Password
+	}
+	if o.User.InsecurePlainTextPassword != nil {
+		y.User.InsecurePlainTextPassword = o.User.InsecurePlainTextPassword
+	}
+	if o.User.LockPassword != nil {
+		y.User.LockPassword = o.User.LockPassword
+	}
+	y.User.Groups = unique(append(append(d.User.Groups, y.User.Groups...), o.User.Groups...))
 	if y.User.Name == nil {
 		y.User.Name = ptr.Of(osutil.LimaUser(existingLimaVersion, warn).Username)
 		warn = false
@@ -256,6 +264,16 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		}
 		// warn = false
 	}
+	if y.User.Password == nil {
+		y.User.Password = ptr.Of("")
+	}
+	if y.User.InsecurePlainTextPassword == nil {
+		y.User.InsecurePlainTextPassword = ptr.Of(false)
+	}
+	if y.User.LockPassword == nil {
+		// The password is locked unless it is set
+		y.User.LockPassword = ptr.Of(*y.User.Password == "")
+	}
 	if out, err := executeGuestTemplate(*y.User.Home, instDir, y.User, y.Param); err == nil {
 		y.User.Home = ptr.Of(out.String())
 	} else {
@@ -269,6 +287,15 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.VMType = o.VMType
 	}
 	y.VMType = ptr.Of(ResolveVMType(y, d, o, filePath))
+	if y.VMOpts.QEMU.RuntimeResize == nil {
+		y.VMOpts.QEMU.RuntimeResize = d.VMOpts.QEMU.RuntimeResize
+	}
+	if o.VMOpts.QEMU.RuntimeResize != nil {
+		y.VMOpts.QEMU.RuntimeResize = o.VMOpts.QEMU.RuntimeResize
+	}
+	if y.VMOpts.QEMU.RuntimeResize == nil {
+		y.VMOpts.QEMU.RuntimeResize = ptr.Of(false)
+	}
 	if y.OS == nil {
 		y.OS = d.OS
 	}
@@ -342,14 +369,24 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.Memory = ptr.Of(defaultMemoryAsString())
 	}
 
-	if y.Disk == nil {
-		y.Disk = d.Disk
+	if y.Disk.Size == nil {
+		y.Disk.Size = d.Disk.Size
+	}
+	if o.Disk.Size != nil {
+		y.Disk.Size = o.Disk.Size
+	}
+	if y.Disk.Size == nil || *y.Disk.Size == "" {
+		y.Disk.Size = ptr.Of(defaultDiskSizeAsString())
+	}
+
+	if y.Disk.AutoTrim == nil {
+		y.Disk.AutoTrim = d.Disk.AutoTrim
 	}
-	if o.Disk != nil {
-		y.Disk = o.Disk
+	if o.Disk.AutoTrim != nil {
+		y.Disk.AutoTrim = o.Disk.AutoTrim
 	}
-	if y.Disk == nil || *y.Disk == "" {
-		y.Disk = ptr.Of(defaultDiskSizeAsString())
+	if y.Disk.AutoTrim == nil {
+		y.Disk.AutoTrim = ptr.Of(false)
 	}
 
 	y.AdditionalDisks = append(append(o.AdditionalDisks, y.AdditionalDisks...), d.AdditionalDisks...)
@@ -409,7 +446,17 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.TimeZone = o.TimeZone
 	}
 	if y.TimeZone == nil {
-		y.TimeZone = ptr.Of(hostTimeZone())
+		y.TimeZone = ptr.Of(hostTimeZone(osutil.TimeZone()))
+	}
+
+	if y.Locale == nil {
+		y.Locale = d.Locale
+	}
+	if o.Locale != nil {
+		y.Locale = o.Locale
+	}
+	if y.Locale == nil {
+		y.Locale = ptr.Of("")
 	}
 
 	if y.SSH.LocalPort == nil {
@@ -462,6 +509,28 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.SSH.ForwardX11Trusted = ptr.Of(false)
 	}
 
+	if y.SSH.KeepAlive.Interval == nil {
+		y.SSH.KeepAlive.Interval = d.SSH.KeepAlive.Interval
+	}
+	if o.SSH.KeepAlive.Interval != nil {
+		y.SSH.KeepAlive.Interval = o.SSH.KeepAlive.Interval
+	}
+	if y.SSH.KeepAlive.Interval == nil {
+		y.SSH.KeepAlive.Interval = ptr.Of(30)
+	}
+
+	if y.SSH.KeepAlive.CountMax == nil {
+		y.SSH.KeepAlive.CountMax = d.SSH.KeepAlive.CountMax
+	}
+	if o.SSH.KeepAlive.CountMax != nil {
+		y.SSH.KeepAlive.CountMax = o.SSH.KeepAlive.CountMax
+	}
+	if y.SSH.KeepAlive.CountMax == nil {
+		y.SSH.KeepAlive.CountMax = ptr.Of(3)
+	}
+
+	y.SSH.AdditionalAuthorizedKeys = unique(append(append(d.SSH.AdditionalAuthorizedKeys, y.SSH.AdditionalAuthorizedKeys...), o.SSH.AdditionalAuthorizedKeys...))
+
 	hosts := make(map[string]string)
 	// Values can be either names or IP addresses. Name values are canonicalized in the hostResolver.
 	for k, v := range d.HostResolver.Hosts {
@@ -562,17 +631,83 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		}
 	}
 
+	if y.PortForwardsBindAddress == nil {
+		y.PortForwardsBindAddress = d.PortForwardsBindAddress
+	}
+	if o.PortForwardsBindAddress != nil {
+		y.PortForwardsBindAddress = o.PortForwardsBindAddress
+	}
+	if y.PortForwardsBindAddress == nil {
+		y.PortForwardsBindAddress = IPv4loopback1
+	}
+
 	y.PortForwards = append(append(o.PortForwards, y.PortForwards...), d.PortForwards...)
 	for i := range y.PortForwards {
+		if y.PortForwards[i].HostIP == nil {
+			y.PortForwards[i].HostIP = y.PortForwardsBindAddress
+		}
 		FillPortForwardDefaults(&y.PortForwards[i], instDir, y.User, y.Param)
 		// After defaults processing the singular HostPort and GuestPort values should not be used again.
 	}
 
+	y.SocketForwards = append(append(o.SocketForwards, y.SocketForwards...), d.SocketForwards...)
+	for i := range y.SocketForwards {
+		FillSocketForwardDefaults(&y.SocketForwards[i], instDir, y.User, y.Param)
+	}
+
+	y.Files = append(append(o.Files, y.Files...), d.Files...)
+
+	// The units are merged by name: o overrides y, and y overrides d.
+	// The duplicated names within a single file are kept, to be rejected by Validate.
+	units := make([]SystemdUnit, 0, len(d.SystemdUnits)+len(y.SystemdUnits)+len(o.SystemdUnits))
+	for _, layer := range [][]SystemdUnit{d.SystemdUnits, y.SystemdUnits, o.SystemdUnits} {
+		merged := len(units)
+		for _, unit := range layer {
+			i := slices.IndexFunc(units[:merged], func(u SystemdUnit) bool { return u.Name == unit.Name })
+			if i < 0 {
+				units = append(units, unit)
+				continue
+			}
+			if unit.Content != "" {
+				units[i].Content = unit.Content
+			}
+			if unit.Enabled != nil {
+				units[i].Enabled = unit.Enabled
+			}
+		}
+	}
+	y.SystemdUnits = units
+	for i := range y.SystemdUnits {
+		unit := &y.SystemdUnits[i]
+		if unit.Enabled == nil {
+			unit.Enabled = ptr.Of(true)
+		}
+		if out, err := executeGuestTemplate(unit.Content, instDir, y.User, y.Param); err == nil {
+			unit.Content = out.String()
+		} else {
+			logrus.WithError(err).Warnf("Couldn't process systemd unit %q as a template", unit.Name)
+		}
+	}
+
 	y.CopyToHost = append(append(o.CopyToHost, y.CopyToHost...), d.CopyToHost...)
 	for i := range y.CopyToHost {
 		FillCopyToHostDefaults(&y.CopyToHost[i], instDir, y.User, y.Param)
 	}
 
+	// The duplicated tags are removed, keeping the first one
+	tags := append(append(o.Tags, y.Tags...), d.Tags...)
+	y.Tags = nil
+	for _, tag := range tags {
+		if !slices.Contains(y.Tags, tag) {
+			y.Tags = append(y.Tags, tag)
+		}
+	}
+
+	y.HostHooks = append(append(o.HostHooks, y.HostHooks...), d.HostHooks...)
+	for i := range y.HostHooks {
+		FillHostHookDefaults(&y.HostHooks[i], instDir, y.Param)
+	}
+
 	if y.HostResolver.Enabled == nil {
 		y.HostResolver.Enabled = d.HostResolver.Enabled
 	}
@@ -603,6 +738,14 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.PropagateProxyEnv = ptr.Of(true)
 	}
 
+	if y.MTU == nil {
+		y.MTU = d.MTU
+	}
+	if o.MTU != nil {
+		y.MTU = o.MTU
+	}
+	// y.MTU can be still nil here, to use the default MTU of the guest OS
+
 	networks := make([]Network, 0, len(d.Networks)+len(y.Networks)+len(o.Networks))
 	iface := make(map[string]int)
 	for _, nw := range append(append(d.Networks, y.Networks...), o.Networks...) {
@@ -626,6 +769,12 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 			if nw.Metric != nil {
 				networks[i].Metric = nw.Metric
 			}
+			if nw.MTU != nil {
+				networks[i].MTU = nw.MTU
+			}
+			if nw.PortForwards != nil {
+				networks[i].PortForwards = nw.PortForwards
+			}
 		} else {
 			// unnamed network definitions are not combined/overwritten
 			if nw.Interface != "" {
@@ -647,6 +796,12 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		if nw.Metric == nil {
 			nw.Metric = ptr.Of(uint32(100))
 		}
+		if nw.MTU == nil {
+			nw.MTU = y.MTU
+		}
+		if nw.PortForwards == nil {
+			nw.PortForwards = ptr.Of(true)
+		}
 	}
 
 	y.MountTypesUnsupported = append(append(o.MountTypesUnsupported, y.MountTypesUnsupported...), d.MountTypesUnsupported...)
@@ -869,6 +1024,65 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.NestedVirtualization = ptr.Of(false)
 	}
 
+	if y.WaitForCloudInit == nil {
+		y.WaitForCloudInit = d.WaitForCloudInit
+	}
+	if o.WaitForCloudInit != nil {
+		y.WaitForCloudInit = o.WaitForCloudInit
+	}
+	if y.WaitForCloudInit == nil {
+		y.WaitForCloudInit = ptr.Of(false)
+	}
+
+	if y.HostAgentMetrics == nil {
+		y.HostAgentMetrics = d.HostAgentMetrics
+	}
+	if o.HostAgentMetrics != nil {
+		y.HostAgentMetrics = o.HostAgentMetrics
+	}
+	if y.HostAgentMetrics == nil {
+		y.HostAgentMetrics = ptr.Of(false)
+	}
+
+	if y.GuestAgent.Address == nil {
+		y.GuestAgent.Address = d.GuestAgent.Address
+	}
+	if o.GuestAgent.Address != nil {
+		y.GuestAgent.Address = o.GuestAgent.Address
+	}
+	if y.GuestAgent.Address == nil {
+		y.GuestAgent.Address = ptr.Of("")
+	}
+
+	if y.CIData.VolumeLabel == nil {
+		y.CIData.VolumeLabel = d.CIData.VolumeLabel
+	}
+	if o.CIData.VolumeLabel != nil {
+		y.CIData.VolumeLabel = o.CIData.VolumeLabel
+	}
+	if y.CIData.VolumeLabel == nil {
+		y.CIData.VolumeLabel = ptr.Of("cidata")
+	}
+	if y.CIData.VFAT == nil {
+		y.CIData.VFAT = d.CIData.VFAT
+	}
+	if o.CIData.VFAT != nil {
+		y.CIData.VFAT = o.CIData.VFAT
+	}
+	if y.CIData.VFAT == nil {
+		y.CIData.VFAT = ptr.Of(false)
+	}
+
+	if y.PortForwardConflict == nil {
+		y.PortForwardConflict = d.PortForwardConflict
+	}
+	if o.PortForwardConflict != nil {
+		y.PortForwardConflict = o.PortForwardConflict
+	}
+	if y.PortForwardConflict == nil {
+		y.PortForwardConflict = ptr.Of(PortForwardConflictSkip)
+	}
+
 	if y.Plain == nil {
 		y.Plain = d.Plain
 	}
@@ -879,6 +1093,16 @@ func FillDefault(y, d, o *LimaYAML, filePath string, warn bool) {
 		y.Plain = ptr.Of(false)
 	}
 
+	if y.ReadOnly == nil {
+		y.ReadOnly = d.ReadOnly
+	}
+	if o.ReadOnly != nil {
+		y.ReadOnly = o.ReadOnly
+	}
+	if y.ReadOnly == nil {
+		y.ReadOnly = ptr.Of(false)
+	}
+
 	fixUpForPlainMode(y)
 }
 
@@ -989,6 +1213,26 @@ func FillPortForwardDefaults(rule *PortForward, instDir string, user User, param
 	}
 }
 
+func FillSocketForwardDefaults(rule *SocketForward, instDir string, user User, param map[string]string) {
+	if rule.GuestSocket != "" {
+		if out, err := executeGuestTemplate(rule.GuestSocket, instDir, user, param); err == nil {
+			rule.GuestSocket = out.String()
+		} else {
+			logrus.WithError(err).Warnf("Couldn't process guestSocket %q as a template", rule.GuestSocket)
+		}
+	}
+	if rule.HostSocket != "" {
+		if out, err := executeHostTemplate(rule.HostSocket, instDir, param); err == nil {
+			rule.HostSocket = out.String()
+		} else {
+			logrus.WithError(err).Warnf("Couldn't process hostSocket %q as a template", rule.HostSocket)
+		}
+		if !filepath.IsAbs(rule.HostSocket) {
+			rule.HostSocket = filepath.Join(instDir, filenames.SocketDir, rule.HostSocket)
+		}
+	}
+}
+
 func FillCopyToHostDefaults(rule *CopyToHost, instDir string, user User, param map[string]string) {
 	if rule.GuestFile != "" {
 		if out, err := executeGuestTemplate(rule.GuestFile, instDir, user, param); err == nil {
@@ -1006,6 +1250,25 @@ func FillCopyToHostDefaults(rule *CopyToHost, instDir string, user User, param m
 	}
 }
 
+func FillHostHookDefaults(hook *HostHook, instDir string, param map[string]string) {
+	if hook.GuestPortRange[0] == 0 && hook.GuestPortRange[1] == 0 {
+		hook.GuestPortRange = [2]int{1, 65535}
+	}
+	if hook.Proto == "" {
+		hook.Proto = ProtoAny
+	}
+	if hook.Timeout == nil {
+		hook.Timeout = ptr.Of(60)
+	}
+	for i, arg := range hook.Command {
+		if out, err := executeHostTemplate(arg, instDir, param); err == nil {
+			hook.Command[i] = out.String()
+		} else {
+			logrus.WithError(err).Warnf("Couldn't process host hook command %q as a template", arg)
+		}
+	}
+}
+
 func NewOS(osname string) OS {
 	switch osname {
 	case "linux":
@@ -1072,7 +1335,7 @@ func isExistingInstanceDir(dir string) bool {
 	// because the file is created during the initialization of the instance.
 	for _, f := range []string{
 		filenames.HostAgentStdoutLog, filenames.HostAgentStderrLog,
-		filenames.VzIdentifier, filenames.BaseDisk, filenames.DiffDisk, filenames.CIDataISO,
+		filenames.VzIdentifier, filenames.BaseDisk, filenames.DiffDisk, filenames.CIDataISO, filenames.CIDataVFAT,
 	} {
 		file := filepath.Join(dir, f)
 		if _, err := os.Lstat(file); !errors.Is(err, os.ErrNotExist) {
@@ -1251,3 +1514,17 @@ func unique(s []string) []string {
 	}
 	return list
 }
+
+// hostTimeZone returns the timezone of the host to be inherited by the instance.
+// The timezone of the host is not validated like a timezone set in lima.yaml, as the user
+// cannot fix it in lima.yaml; an invalid one is skipped with a warning instead.
+func hostTimeZone(tz string) string {
+	if tz == "" {
+		return ""
+	}
+	if err := checkTimeZone(tz); err != nil {
+		logrus.WithError(err).Warnf("Not inheriting the timezone %q of the host; set `timezone` in lima.yaml to specify the timezone", tz)
+		return ""
+	}
+	return tz
+}
@@ -69,13 +69,17 @@ func TestFillDefault(t *testing.T) {
 
 	// Builtin default values
 	builtin := LimaYAML{
-		VMType:             &defaultVMType,
-		OS:                 ptr.Of(LINUX),
-		Arch:               ptr.Of(arch),
-		CPUType:            defaultCPUType(),
-		CPUs:               ptr.Of(defaultCPUs()),
-		Memory:             ptr.Of(defaultMemoryAsString()),
-		Disk:               ptr.Of(defaultDiskSizeAsString()),
+		VMType:  &defaultVMType,
+		VMOpts:  VMOpts{QEMU: QEMUOpts{RuntimeResize: ptr.Of(false)}},
+		OS:      ptr.Of(LINUX),
+		Arch:    ptr.Of(arch),
+		CPUType: defaultCPUType(),
+		CPUs:    ptr.Of(defaultCPUs()),
+		Memory:  ptr.Of(defaultMemoryAsString()),
+		Disk: PrimaryDisk{
+			Size:     ptr.Of(defaultDiskSizeAsString()),
+			AutoTrim: ptr.Of(false),
+		},
 		GuestInstallPrefix: ptr.Of(defaultGuestInstallPrefix()),
 		UpgradePackages:    ptr.Of(false),
 		Containerd: Containerd{
@@ -89,8 +93,13 @@ func TestFillDefault(t *testing.T) {
 			ForwardAgent:      ptr.Of(false),
 			ForwardX11:        ptr.Of(false),
 			ForwardX11Trusted: ptr.Of(false),
+			KeepAlive: SSHKeepAlive{
+				Interval: ptr.Of(30),
+				CountMax: ptr.Of(3),
+			},
 		},
-		TimeZone: ptr.Of(hostTimeZone()),
+		TimeZone: ptr.Of(osutil.TimeZone()),
+		Locale:   ptr.Of(""),
 		Firmware: Firmware{
 			LegacyBIOS: ptr.Of(false),
 		},
@@ -103,6 +112,7 @@ func TestFillDefault(t *testing.T) {
 				Display: ptr.Of("127.0.0.1:0,to=9"),
 			},
 		},
+		PortForwardsBindAddress: IPv4loopback1,
 		HostResolver: HostResolver{
 			Enabled: ptr.Of(true),
 			IPv6:    ptr.Of(false),
@@ -112,13 +122,28 @@ func TestFillDefault(t *testing.T) {
 			RemoveDefaults: ptr.Of(false),
 		},
 		NestedVirtualization: ptr.Of(false),
+		WaitForCloudInit:     ptr.Of(false),
+		HostAgentMetrics:     ptr.Of(false),
+		PortForwardConflict:  ptr.Of(PortForwardConflictSkip),
 		Plain:                ptr.Of(false),
+		ReadOnly:             ptr.Of(false),
+		GuestAgent: GuestAgent{
+			Address: ptr.Of(""),
+		},
+		CIData: CIData{
+			VolumeLabel: ptr.Of("cidata"),
+			VFAT:        ptr.Of(false),
+		},
 		User: User{
 			Name:    ptr.Of(user.Username),
 			Comment: ptr.Of(user.Name),
 			Home:    ptr.Of(user.HomeDir),
 			Shell:   ptr.Of("/bin/bash"),
 			UID:     ptr.Of(uint32(uid)),
+
+			Password:                  ptr.Of(""),
+			InsecurePlainTextPassword: ptr.Of(false),
+			LockPassword:              ptr.Of(true),
 		},
 	}
 
@@ -168,12 +193,25 @@ func TestFillDefault(t *testing.T) {
 				HostSocket:  "{{.Home}} | {{.Dir}} | {{.Name}} | {{.UID}} | {{.User}} | {{.Param.ONE}}",
 			},
 		},
+		SocketForwards: []SocketForward{
+			{
+				GuestSocket: "/run/user/{{.UID}}/docker.sock",
+				HostSocket:  "docker.sock",
+			},
+		},
 		CopyToHost: []CopyToHost{
 			{
 				GuestFile: "{{.Home}} | {{.UID}} | {{.User}} | {{.Param.ONE}}",
 				HostFile:  "{{.Home}} | {{.Dir}} | {{.Name}} | {{.UID}} | {{.User}} | {{.Param.ONE}}",
 			},
 		},
+		Tags: []string{"ci", "team-a", "ci"},
+		HostHooks: []HostHook{
+			{
+				Event:   HostHookEventPortOpened,
+				Command: []string{"/bin/echo", "{{.Dir}} | {{.Name}} | {{.Param.ONE}}"},
+			},
+		},
 		Env: map[string]string{
 			"ONE": "Eins",
 		},
@@ -187,6 +225,7 @@ func TestFillDefault(t *testing.T) {
 			},
 		},
 		TimeZone: ptr.Of("Antarctica/Troll"),
+		Locale:   ptr.Of("de_DE.UTF-8"),
 		Firmware: Firmware{
 			LegacyBIOS: ptr.Of(false),
 			Images: []FileWithVMType{
@@ -257,6 +296,7 @@ func TestFillDefault(t *testing.T) {
 	expect.Networks[0].MACAddress = MACAddress(fmt.Sprintf("%s#%d", filePath, 0))
 	expect.Networks[0].Interface = "lima0"
 	expect.Networks[0].Metric = ptr.Of(uint32(100))
+	expect.Networks[0].PortForwards = ptr.Of(true)
 
 	expect.DNS = slices.Clone(y.DNS)
 	expect.PortForwards = []PortForward{
@@ -265,6 +305,12 @@ func TestFillDefault(t *testing.T) {
 		defaultPortForward,
 		defaultPortForward,
 	}
+	expect.SocketForwards = []SocketForward{
+		{
+			GuestSocket: fmt.Sprintf("/run/user/%s/docker.sock", user.Uid),
+			HostSocket:  filepath.Join(instDir, filenames.SocketDir, "docker.sock"),
+		},
+	}
 	expect.CopyToHost = []CopyToHost{
 		{},
 	}
@@ -286,6 +332,18 @@ func TestFillDefault(t *testing.T) {
 	expect.CopyToHost[0].GuestFile = fmt.Sprintf("%s | %s | %s | %s", user.HomeDir, user.Uid, user.Username, y.Param["ONE"])
 	expect.CopyToHost[0].HostFile = fmt.Sprintf("%s | %s | %s | %s | %s | %s", hostHome, instDir, instName, currentUser.Uid, currentUser.Username, y.Param["ONE"])
 
+	expect.Tags = []string{"ci", "team-a"}
+
+	expect.HostHooks = []HostHook{
+		{
+			Event:          HostHookEventPortOpened,
+			GuestPortRange: [2]int{1, 65535},
+			Proto:          ProtoAny,
+			Command:        []string{"/bin/echo", fmt.Sprintf("%s | %s | %s", instDir, instName, y.Param["ONE"])},
+			Timeout:        ptr.Of(60),
+		},
+	}
+
 	expect.Env = y.Env
 
 	expect.Param = y.Param
@@ -299,6 +357,7 @@ func TestFillDefault(t *testing.T) {
 	}
 
 	expect.TimeZone = y.TimeZone
+	expect.Locale = y.Locale
 	expect.Firmware = y.Firmware
 	expect.Firmware.Images = slices.Clone(y.Firmware.Images)
 
@@ -308,6 +367,10 @@ func TestFillDefault(t *testing.T) {
 	}
 
 	expect.NestedVirtualization = ptr.Of(false)
+	expect.WaitForCloudInit = ptr.Of(false)
+	expect.HostAgentMetrics = ptr.Of(false)
+	expect.PortForwardConflict = ptr.Of(PortForwardConflictSkip)
+	expect.PortForwardsBindAddress = IPv4loopback1
 
 	FillDefault(&y, &LimaYAML{}, &LimaYAML{}, filePath, false)
 	assert.DeepEqual(t, &y, &expect, opts...)
@@ -320,6 +383,7 @@ func TestFillDefault(t *testing.T) {
 	// Choose values that are different from the "builtin" defaults
 	d = LimaYAML{
 		VMType: ptr.Of("vz"),
+		VMOpts: VMOpts{QEMU: QEMUOpts{RuntimeResize: ptr.Of(true)}},
 		OS:     ptr.Of("unknown"),
 		Arch:   ptr.Of("unknown"),
 		CPUType: CPUType{
@@ -330,7 +394,10 @@ func TestFillDefault(t *testing.T) {
 		},
 		CPUs:   ptr.Of(7),
 		Memory: ptr.Of("5GiB"),
-		Disk:   ptr.Of("105GiB"),
+		Disk: PrimaryDisk{
+			Size:     ptr.Of("105GiB"),
+			AutoTrim: ptr.Of(true),
+		},
 		AdditionalDisks: []Disk{
 			{Name: "data"},
 		},
@@ -349,8 +416,13 @@ func TestFillDefault(t *testing.T) {
 			ForwardAgent:      ptr.Of(true),
 			ForwardX11:        ptr.Of(false),
 			ForwardX11Trusted: ptr.Of(false),
+			KeepAlive: SSHKeepAlive{
+				Interval: ptr.Of(60),
+				CountMax: ptr.Of(5),
+			},
 		},
 		TimeZone: ptr.Of("Zulu"),
+		Locale:   ptr.Of("en_US.UTF-8"),
 		Firmware: Firmware{
 			LegacyBIOS: ptr.Of(true),
 			Images: []FileWithVMType{
@@ -371,6 +443,7 @@ func TestFillDefault(t *testing.T) {
 				Display: ptr.Of("none"),
 			},
 		},
+		PortForwardsBindAddress: net.IPv4zero,
 		HostResolver: HostResolver{
 			Enabled: ptr.Of(false),
 			IPv6:    ptr.Of(true),
@@ -401,9 +474,10 @@ func TestFillDefault(t *testing.T) {
 		},
 		Networks: []Network{
 			{
-				MACAddress: "11:22:33:44:55:66",
-				Interface:  "def0",
-				Metric:     ptr.Of(uint32(50)),
+				MACAddress:   "11:22:33:44:55:66",
+				Interface:    "def0",
+				Metric:       ptr.Of(uint32(50)),
+				PortForwards: ptr.Of(false),
 			},
 		},
 		DNS: []net.IP{
@@ -419,6 +493,14 @@ func TestFillDefault(t *testing.T) {
 			Proto:          ProtoTCP,
 		}},
 		CopyToHost: []CopyToHost{{}},
+		Tags:       []string{"team-b"},
+		HostHooks: []HostHook{{
+			Event:          HostHookEventPortClosed,
+			GuestPortRange: [2]int{80, 80},
+			Proto:          ProtoTCP,
+			Command:        []string{"/bin/true"},
+			Timeout:        ptr.Of(10),
+		}},
 		Env: map[string]string{
 			"ONE": "one",
 			"TWO": "two",
@@ -438,12 +520,27 @@ func TestFillDefault(t *testing.T) {
 			BinFmt:  ptr.Of(true),
 		},
 		NestedVirtualization: ptr.Of(true),
+		WaitForCloudInit:     ptr.Of(true),
+		HostAgentMetrics:     ptr.Of(true),
+		PortForwardConflict:  ptr.Of(PortForwardConflictRemap),
+		GuestAgent: GuestAgent{
+			Address: ptr.Of("vsock://3:2222"),
+		},
+		CIData: CIData{
+			VolumeLabel: ptr.Of("CIDATA"),
+			VFAT:        ptr.Of(true),
+		},
 		User: User{
 			Name:    ptr.Of("xxx"),
 			Comment: ptr.Of("Foo Bar"),
 			Home:    ptr.Of("/tmp"),
 			Shell:   ptr.Of("/bin/tcsh"),
 			UID:     ptr.Of(uint32(8080)),
+			Groups:  []string{"docker"},
+
+			Password:                  ptr.Of("$6$salt$hash"),
+			InsecurePlainTextPassword: ptr.Of(false),
+			LockPassword:              ptr.Of(false),
 		},
 	}
 
@@ -483,6 +580,7 @@ func TestFillDefault(t *testing.T) {
 		}
 	}
 	expect.Plain = ptr.Of(false)
+	expect.ReadOnly = ptr.Of(false)
 
 	y = LimaYAML{}
 	FillDefault(&y, &d, &LimaYAML{}, filePath, false)
@@ -504,6 +602,8 @@ func TestFillDefault(t *testing.T) {
 	expect.Probes = append(append([]Probe{}, y.Probes...), dExpect.Probes...)
 	expect.PortForwards = append(append([]PortForward{}, y.PortForwards...), dExpect.PortForwards...)
 	expect.CopyToHost = append(append([]CopyToHost{}, y.CopyToHost...), dExpect.CopyToHost...)
+	expect.HostHooks = append(append([]HostHook{}, y.HostHooks...), dExpect.HostHooks...)
+	expect.Tags = []string{"ci", "team-a", "team-b"}
 	expect.Containerd.Archives = append(append([]File{}, y.Containerd.Archives...), dExpect.Containerd.Archives...)
 	expect.Containerd.Archives[2].Arch = *expect.Arch
 	expect.AdditionalDisks = append(append([]Disk{}, y.AdditionalDisks...), dExpect.AdditionalDisks...)
@@ -522,6 +622,8 @@ func TestFillDefault(t *testing.T) {
 
 	expect.Param["TWO"] = dExpect.Param["TWO"]
 
+	expect.User.Groups = dExpect.User.Groups
+
 	t.Logf("d.vmType=%q, y.vmType=%q, expect.vmType=%q", *d.VMType, *y.VMType, *expect.VMType)
 
 	FillDefault(&y, &d, &LimaYAML{}, filePath, false)
@@ -532,6 +634,7 @@ func TestFillDefault(t *testing.T) {
 
 	o = LimaYAML{
 		VMType: ptr.Of("qemu"),
+		VMOpts: VMOpts{QEMU: QEMUOpts{RuntimeResize: ptr.Of(false)}},
 		OS:     ptr.Of(LINUX),
 		Arch:   ptr.Of(arch),
 		CPUType: CPUType{
@@ -542,7 +645,10 @@ func TestFillDefault(t *testing.T) {
 		},
 		CPUs:   ptr.Of(12),
 		Memory: ptr.Of("7GiB"),
-		Disk:   ptr.Of("117GiB"),
+		Disk: PrimaryDisk{
+			Size:     ptr.Of("117GiB"),
+			AutoTrim: ptr.Of(false),
+		},
 		AdditionalDisks: []Disk{
 			{Name: "test"},
 		},
@@ -565,8 +671,13 @@ func TestFillDefault(t *testing.T) {
 			ForwardAgent:      ptr.Of(true),
 			ForwardX11:        ptr.Of(false),
 			ForwardX11Trusted: ptr.Of(false),
+			KeepAlive: SSHKeepAlive{
+				Interval: ptr.Of(0),
+				CountMax: ptr.Of(10),
+			},
 		},
 		TimeZone: ptr.Of("Universal"),
+		Locale:   ptr.Of("ja_JP.UTF-8"),
 		Firmware: Firmware{
 			LegacyBIOS: ptr.Of(true),
 		},
@@ -579,6 +690,7 @@ func TestFillDefault(t *testing.T) {
 				Display: ptr.Of("none"),
 			},
 		},
+		PortForwardsBindAddress: net.ParseIP("192.168.5.15"),
 		HostResolver: HostResolver{
 			Enabled: ptr.Of(false),
 			IPv6:    ptr.Of(false),
@@ -623,10 +735,11 @@ func TestFillDefault(t *testing.T) {
 		},
 		Networks: []Network{
 			{
-				Lima:       "shared",
-				MACAddress: "10:20:30:40:50:60",
-				Interface:  "def1",
-				Metric:     ptr.Of(uint32(25)),
+				Lima:         "shared",
+				MACAddress:   "10:20:30:40:50:60",
+				Interface:    "def1",
+				Metric:       ptr.Of(uint32(25)),
+				PortForwards: ptr.Of(true),
 			},
 			{
 				Lima:      "bridged",
@@ -646,6 +759,14 @@ func TestFillDefault(t *testing.T) {
 			Proto:          ProtoTCP,
 		}},
 		CopyToHost: []CopyToHost{{}},
+		Tags:       []string{"team-b", "override"},
+		HostHooks: []HostHook{{
+			Event:          HostHookEventPortOpened,
+			GuestPortRange: [2]int{8080, 8080},
+			Proto:          ProtoUDP,
+			Command:        []string{"/bin/false"},
+			Timeout:        ptr.Of(30),
+		}},
 		Env: map[string]string{
 			"TWO":   "deux",
 			"THREE": "trois",
@@ -662,12 +783,27 @@ func TestFillDefault(t *testing.T) {
 			BinFmt:  ptr.Of(false),
 		},
 		NestedVirtualization: ptr.Of(false),
+		WaitForCloudInit:     ptr.Of(false),
+		HostAgentMetrics:     ptr.Of(false),
+		PortForwardConflict:  ptr.Of(PortForwardConflictFail),
+		GuestAgent: GuestAgent{
+			Address: ptr.Of("tcp://127.0.0.1:2222"),
+		},
+		CIData: CIData{
+			VolumeLabel: ptr.Of("config-2"),
+			VFAT:        ptr.Of(false),
+		},
 		User: User{
 			Name:    ptr.Of("foo"),
 			Comment: ptr.Of("foo bar baz"),
 			Home:    ptr.Of("/override"),
 			Shell:   ptr.Of("/bin/sh"),
 			UID:     ptr.Of(uint32(1122)),
+			Groups:  []string{"kvm", "docker"},
+
+			Password:                  ptr.Of("override"),
+			InsecurePlainTextPassword: ptr.Of(true),
+			LockPassword:              ptr.Of(true),
 		},
 	}
 
@@ -678,7 +814,10 @@ func TestFillDefault(t *testing.T) {
 	expect.Provision = append(append(o.Provision, y.Provision...), dExpect.Provision...)
 	expect.Probes = append(append(o.Probes, y.Probes...), dExpect.Probes...)
 	expect.PortForwards = append(append(o.PortForwards, y.PortForwards...), dExpect.PortForwards...)
+	expect.SocketForwards = append(append(o.SocketForwards, y.SocketForwards...), dExpect.SocketForwards...)
 	expect.CopyToHost = append(append(o.CopyToHost, y.CopyToHost...), dExpect.CopyToHost...)
+	expect.HostHooks = append(append(o.HostHooks, y.HostHooks...), dExpect.HostHooks...)
+	expect.Tags = []string{"team-b", "override", "ci", "team-a"}
 	expect.Containerd.Archives = append(append(o.Containerd.Archives, y.Containerd.Archives...), dExpect.Containerd.Archives...)
 	expect.Containerd.Archives[3].Arch = *expect.Arch
 	expect.AdditionalDisks = append(append(o.AdditionalDisks, y.AdditionalDisks...), dExpect.AdditionalDisks...)
@@ -713,6 +852,9 @@ func TestFillDefault(t *testing.T) {
 
 	expect.Param["ONE"] = y.Param["ONE"]
 
+	// User groups are uniquely appended in d, y, o order
+	expect.User.Groups = []string{"docker", "kvm"}
+
 	expect.CACertificates.RemoveDefaults = ptr.Of(true)
 	expect.CACertificates.Files = []string{"ca.crt"}
 	expect.CACertificates.Certs = []string{
@@ -724,8 +866,11 @@ func TestFillDefault(t *testing.T) {
 		BinFmt:  ptr.Of(false),
 	}
 	expect.Plain = ptr.Of(false)
+	expect.ReadOnly = ptr.Of(false)
 
 	expect.NestedVirtualization = ptr.Of(false)
+	expect.WaitForCloudInit = ptr.Of(false)
+	expect.HostAgentMetrics = ptr.Of(false)
 
 	FillDefault(&y, &d, &o, filePath, false)
 	assert.DeepEqual(t, &y, &expect, opts...)
@@ -735,3 +880,67 @@ func TestContainerdDefault(t *testing.T) {
 	archives := defaultContainerdArchives()
 	assert.Assert(t, len(archives) > 0)
 }
+
+func TestMACAddress(t *testing.T) {
+	mac := MACAddress("/home/user/.lima/default")
+	assert.Equal(t, MACAddress("/home/user/.lima/default"), mac)
+	hw, err := net.ParseMAC(mac)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, []byte(hw[:3]), []byte{0x52, 0x55, 0x55})
+
+	assert.Assert(t, MACAddress("/home/user/.lima/another") != mac)
+	assert.Assert(t, MACAddress("/home/user/.lima/default/lima.yaml#0") != MACAddress("/home/user/.lima/default/lima.yaml#1"))
+}
+
+func TestFillDefaultUserPassword(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), filenames.LimaYAML)
+	var y LimaYAML
+	FillDefault(&y, &LimaYAML{}, &LimaYAML{}, filePath, false)
+	assert.Equal(t, *y.User.LockPassword, true)
+
+	y = LimaYAML{User: User{Password: ptr.Of("$6$salt$hash")}}
+	FillDefault(&y, &LimaYAML{}, &LimaYAML{}, filePath, false)
+	assert.Equal(t, *y.User.LockPassword, false)
+
+	y = LimaYAML{User: User{Password: ptr.Of("$6$salt$hash"), LockPassword: ptr.Of(true)}}
+	FillDefault(&y, &LimaYAML{}, &LimaYAML{}, filePath, false)
+	assert.Equal(t, *y.User.LockPassword, true)
+}
+
+func TestHostTimeZone(t *testing.T) {
+	assert.Equal(t, hostTimeZone(""), "")
+	assert.Equal(t, hostTimeZone("UTC"), "UTC")
+	// An invalid timezone of the host is skipped, instead of failing the validation
+	assert.Equal(t, hostTimeZone("Local"), "")
+	assert.Equal(t, hostTimeZone("../../etc/passwd"), "")
+}
+
+func TestFillDefaultSystemdUnits(t *testing.T) {
+	filePath := filepath.Join(t.TempDir(), filenames.LimaYAML)
+	d := LimaYAML{SystemdUnits: []SystemdUnit{
+		{Name: "foo.service", Content: "[Service]\nExecStart=/usr/bin/foo-d"},
+		{Name: "bar.service", Content: "[Service]\nExecStart=/usr/bin/bar-d"},
+	}}
+	y := LimaYAML{SystemdUnits: []SystemdUnit{
+		{Name: "foo.service", Content: "[Service]\nExecStart=/usr/bin/foo-y"},
+		{Name: "baz.timer", Content: "[Timer]\nOnCalendar=daily"},
+	}}
+	o := LimaYAML{SystemdUnits: []SystemdUnit{
+		{Name: "bar.service", Enabled: ptr.Of(false)},
+		{Name: "baz.timer", Content: "[Timer]\nOnCalendar=weekly"},
+	}}
+	FillDefault(&y, &d, &o, filePath, false)
+	assert.DeepEqual(t, y.SystemdUnits, []SystemdUnit{
+		{Name: "foo.service", Content: "[Service]\nExecStart=/usr/bin/foo-y", Enabled: ptr.Of(true)},
+		{Name: "bar.service", Content: "[Service]\nExecStart=/usr/bin/bar-d", Enabled: ptr.Of(false)},
+		{Name: "baz.timer", Content: "[Timer]\nOnCalendar=weekly", Enabled: ptr.Of(true)},
+	})
+
+	// the duplicated units within a single file are not merged
+	y = LimaYAML{SystemdUnits: []SystemdUnit{
+		{Name: "foo.service", Content: "[Service]"},
+		{Name: "foo.service", Content: "[Service]"},
+	}}
+	FillDefault(&y, &LimaYAML{}, &LimaYAML{}, filePath, false)
+	assert.Equal(t, len(y.SystemdUnits), 2)
+}
@@ -0,0 +1,63 @@
+package limayaml
+
+import (
+	"net"
+	"reflect"
+)
+
+// mergeLayer fills the fields of dst that are not set with the values of src, which has a lower precedence.
+// The result is used as the `d` parameter of FillDefault(), so the fields are combined the same way:
+// lists are concatenated with the higher precedence entries first, except for `mounts` and `networks`,
+// which are processed lowest priority first; `dns` is replaced as a whole; maps are merged, with the
+// entries of dst taking precedence.
+func mergeLayer(dst, src *LimaYAML) {
+	mounts := append(append([]Mount{}, src.Mounts...), dst.Mounts...)
+	networks := append(append([]Network{}, src.Networks...), dst.Networks...)
+	dns := dst.DNS
+	if len(dns) == 0 {
+		dns = src.DNS
+	}
+	mergeValue(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+	dst.Mounts, dst.Networks, dst.DNS = mounts, networks, dns
+}
+
+var ipType = reflect.TypeOf(net.IP{})
+
+func mergeValue(dst, src reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Struct:
+		for i := range dst.NumField() {
+			mergeValue(dst.Field(i), src.Field(i))
+		}
+	case reflect.Map:
+		if src.Len() == 0 {
+			return
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.MakeMapWithSize(dst.Type(), src.Len()))
+		}
+		iter := src.MapRange()
+		for iter.Next() {
+			if !dst.MapIndex(iter.Key()).IsValid() {
+				dst.SetMapIndex(iter.Key(), iter.Value())
+			}
+		}
+	case reflect.Slice:
+		if dst.Type() == ipType {
+			if dst.IsNil() {
+				dst.Set(src)
+			}
+			return
+		}
+		if src.Len() == 0 {
+			return
+		}
+		merged := reflect.MakeSlice(dst.Type(), 0, dst.Len()+src.Len())
+		dst.Set(reflect.AppendSlice(reflect.AppendSlice(merged, dst), src))
+	default:
+		// pointers are nil and scalars are zero when they are not set
+		if dst.IsZero() {
+			dst.Set(src)
+		}
+	}
+}
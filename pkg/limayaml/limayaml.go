@@ -16,7 +16,7 @@ type LimaYAML struct {
 	CPUType               CPUType       `yaml:"cpuType,omitempty" json:"cpuType,omitempty" jsonschema:"nullable"`
 	CPUs                  *int          `yaml:"cpus,omitempty" json:"cpus,omitempty" jsonschema:"nullable"`
 	Memory                *string       `yaml:"memory,omitempty" json:"memory,omitempty" jsonschema:"nullable"` // go-units.RAMInBytes
-	Disk                  *string       `yaml:"disk,omitempty" json:"disk,omitempty" jsonschema:"nullable"`     // go-units.RAMInBytes
+	Disk                  PrimaryDisk   `yaml:"disk,omitempty" json:"disk,omitempty"`
 	AdditionalDisks       []Disk        `yaml:"additionalDisks,omitempty" json:"additionalDisks,omitempty" jsonschema:"nullable"`
 	Mounts                []Mount       `yaml:"mounts,omitempty" json:"mounts,omitempty"`
 	MountTypesUnsupported []string      `yaml:"mountTypesUnsupported,omitempty" json:"mountTypesUnsupported,omitempty" jsonschema:"nullable"`
@@ -35,19 +35,43 @@ type LimaYAML struct {
 	CopyToHost            []CopyToHost  `yaml:"copyToHost,omitempty" json:"copyToHost,omitempty"`
 	Message               string        `yaml:"message,omitempty" json:"message,omitempty"`
 	Networks              []Network     `yaml:"networks,omitempty" json:"networks,omitempty" jsonschema:"nullable"`
+	// MTU is the default MTU for the guest network interfaces, including the builtin user-mode network.
+	MTU *uint32 `yaml:"mtu,omitempty" json:"mtu,omitempty" jsonschema:"nullable"`
+	// PortForwardsBindAddress is the host address used by the port forwarding rules that do not specify `hostIP`.
+	PortForwardsBindAddress net.IP `yaml:"portForwardsBindAddress,omitempty" json:"portForwardsBindAddress,omitempty" jsonschema:"nullable"`
+	// SocketForwards forwards guest unix sockets to host unix sockets.
+	SocketForwards []SocketForward `yaml:"socketForwards,omitempty" json:"socketForwards,omitempty"`
+	// HostHooks run commands on the host when the guest agent reports the events, e.g., a guest port being opened.
+	HostHooks []HostHook `yaml:"hostHooks,omitempty" json:"hostHooks,omitempty"`
+	// Tags are used for selecting multiple instances, e.g., `limactl start --tag ci`.
+	Tags []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Files are written by cloud-init before the provisioning scripts are executed.
+	Files []WriteFile `yaml:"files,omitempty" json:"files,omitempty"`
+	// SystemdUnits are installed into /etc/systemd/system after the system provisioning scripts are executed.
+	SystemdUnits []SystemdUnit `yaml:"systemdUnits,omitempty" json:"systemdUnits,omitempty"`
 	// `network` was deprecated in Lima v0.7.0, removed in Lima v0.14.0. Use `networks` instead.
 	Env          map[string]string `yaml:"env,omitempty" json:"env,omitempty"`
 	Param        map[string]string `yaml:"param,omitempty" json:"param,omitempty"`
 	DNS          []net.IP          `yaml:"dns,omitempty" json:"dns,omitempty"`
 	HostResolver HostResolver      `yaml:"hostResolver,omitempty" json:"hostResolver,omitempty"`
 	// `useHostResolver` was deprecated in Lima v0.8.1, removed in Lima v0.14.0. Use `hostResolver.enabled` instead.
-	PropagateProxyEnv    *bool          `yaml:"propagateProxyEnv,omitempty" json:"propagateProxyEnv,omitempty" jsonschema:"nullable"`
-	CACertificates       CACertificates `yaml:"caCerts,omitempty" json:"caCerts,omitempty"`
-	Rosetta              Rosetta        `yaml:"rosetta,omitempty" json:"rosetta,omitempty"`
-	Plain                *bool          `yaml:"plain,omitempty" json:"plain,omitempty" jsonschema:"nullable"`
-	TimeZone             *string        `yaml:"timezone,omitempty" json:"timezone,omitempty" jsonschema:"nullable"`
-	NestedVirtualization *bool          `yaml:"nestedVirtualization,omitempty" json:"nestedVirtualization,omitempty" jsonschema:"nullable"`
-	User                 User           `yaml:"user,omitempty" json:"user,omitempty"`
+	PropagateProxyEnv *bool          `yaml:"propagateProxyEnv,omitempty" json:"propagateProxyEnv,omitempty" jsonschema:"nullable"`
+	CACertificates    CACertificates `yaml:"caCerts,omitempty" json:"caCerts,omitempty"`
+	Rosetta           Rosetta        `yaml:"rosetta,omitempty" json:"rosetta,omitempty"`
+	Plain             *bool          `yaml:"plain,omitempty" json:"plain,omitempty" jsonschema:"nullable"`
+	// ReadOnly refuses the commands that mutate the running instance, e.g., copying files into the guest.
+	ReadOnly             *bool   `yaml:"readOnly,omitempty" json:"readOnly,omitempty" jsonschema:"nullable"`
+	TimeZone             *string `yaml:"timezone,omitempty" json:"timezone,omitempty" jsonschema:"nullable"`
+	Locale               *string `yaml:"locale,omitempty" json:"locale,omitempty" jsonschema:"nullable"`
+	NestedVirtualization *bool   `yaml:"nestedVirtualization,omitempty" json:"nestedVirtualization,omitempty" jsonschema:"nullable"`
+	WaitForCloudInit     *bool   `yaml:"waitForCloudInit,omitempty" json:"waitForCloudInit,omitempty" jsonschema:"nullable"`
+	HostAgentMetrics     *bool   `yaml:"hostAgentMetrics,omitempty" json:"hostAgentMetrics,omitempty" jsonschema:"nullable"`
+	PortForwardConflict  *string `yaml:"portForwardConflict,omitempty" json:"portForwardConflict,omitempty" jsonschema:"nullable"`
+	User                 User    `yaml:"user,omitempty" json:"user,omitempty"`
+	// GuestAgent configures the connection from the host agent to the guest agent.
+	GuestAgent GuestAgent `yaml:"guestAgent,omitempty" json:"guestAgent,omitempty"`
+	// CIData configures the image of the cloud-init NoCloud datasource.
+	CIData CIData `yaml:"cidata,omitempty" json:"cidata,omitempty"`
 }
 
 type (
@@ -85,11 +109,17 @@ var (
 )
 
 type User struct {
-	Name    *string `yaml:"name,omitempty" json:"name,omitempty" jsonschema:"nullable"`
-	Comment *string `yaml:"comment,omitempty" json:"comment,omitempty" jsonschema:"nullable"`
-	Home    *string `yaml:"home,omitempty" json:"home,omitempty" jsonschema:"nullable"`
-	Shell   *string `yaml:"shell,omitempty" json:"shell,omitempty" jsonschema:"nullable"`
-	UID     *uint32 `yaml:"uid,omitempty" json:"uid,omitempty" jsonschema:"nullable"`
+	Name    *string  `yaml:"name,omitempty" json:"name,omitempty" jsonschema:"nullable"`
+	Comment *string  `yaml:"comment,omitempty" json:"comment,omitempty" jsonschema:"nullable"`
+	Home    *string  `yaml:"home,omitempty" json:"home,omitempty" jsonschema:"nullable"`
+	Shell   *string  `yaml:"shell,omitempty" json:"shell,omitempty" jsonschema:"nullable"`
+	UID     *uint32  `yaml:"uid,omitempty" json:"uid,omitempty" jsonschema:"nullable"`
+	Groups  []string `yaml:"groups,omitempty" json:"groups,omitempty" jsonschema:"nullable"`
+	// Password is the password hash in the crypt(3) format, e.g., "$6$salt$hash".
+	// A plain text password is accepted only when InsecurePlainTextPassword is true.
+	Password                  *string `yaml:"password,omitempty" json:"password,omitempty" jsonschema:"nullable"`
+	InsecurePlainTextPassword *bool   `yaml:"insecurePlainTextPassword,omitempty" json:"insecurePlainTextPassword,omitempty" jsonschema:"nullable"`
+	LockPassword              *bool   `yaml:"lockPassword,omitempty" json:"lockPassword,omitempty" jsonschema:"nullable"`
 }
 
 type VMOpts struct {
@@ -98,6 +128,8 @@ type VMOpts struct {
 
 type QEMUOpts struct {
 	MinimumVersion *string `yaml:"minimumVersion,omitempty" json:"minimumVersion,omitempty" jsonschema:"nullable"`
+	// RuntimeResize allows changing the vCPUs and the memory of the running instance with `limactl config set`.
+	RuntimeResize *bool `yaml:"runtimeResize,omitempty" json:"runtimeResize,omitempty" jsonschema:"nullable"`
 }
 
 type Rosetta struct {
@@ -127,11 +159,21 @@ type Image struct {
 	Initrd *File   `yaml:"initrd,omitempty" json:"initrd,omitempty"`
 }
 
+// PrimaryDisk is the configuration of the primary disk.
+// `disk: SIZE` is a shorthand for `disk: {size: SIZE}`.
+type PrimaryDisk struct {
+	Size *string `yaml:"size,omitempty" json:"size,omitempty" jsonschema:"nullable"` // go-units.RAMInBytes
+	// AutoTrim runs `fstrim` in the guest before stopping the instance.
+	AutoTrim *bool `yaml:"autoTrim,omitempty" json:"autoTrim,omitempty" jsonschema:"nullable"`
+}
+
 type Disk struct {
 	Name   string   `yaml:"name" json:"name"` // REQUIRED
 	Format *bool    `yaml:"format,omitempty" json:"format,omitempty"`
 	FSType *string  `yaml:"fsType,omitempty" json:"fsType,omitempty"`
 	FSArgs []string `yaml:"fsArgs,omitempty" json:"fsArgs,omitempty"`
+	// ReadOnly attaches the disk as a read-only device, so that the disk can be shared across instances.
+	ReadOnly *bool `yaml:"readOnly,omitempty" json:"readOnly,omitempty"`
 }
 
 type Mount struct {
@@ -167,6 +209,21 @@ type Virtiofs struct {
 	QueueSize *int `yaml:"queueSize,omitempty" json:"queueSize,omitempty"`
 }
 
+type GuestAgent struct {
+	// Address overrides the connection to the guest agent provided by the driver,
+	// e.g., "vsock://3:2222" (see address.Parse in pkg/guestagent/address).
+	Address *string `yaml:"address,omitempty" json:"address,omitempty" jsonschema:"nullable"` // default: "" (decided by the driver)
+}
+
+type CIData struct {
+	// VolumeLabel is the volume label of the image.
+	// cloud-init only detects the NoCloud datasource with the label "cidata" (case-insensitive).
+	VolumeLabel *string `yaml:"volumeLabel,omitempty" json:"volumeLabel,omitempty" jsonschema:"nullable"` // default: "cidata"
+	// VFAT writes a VFAT image (cidata.vfat) instead of the ISO9660 image (cidata.iso),
+	// for the guests that can only read the NoCloud datasource from VFAT.
+	VFAT *bool `yaml:"vfat,omitempty" json:"vfat,omitempty" jsonschema:"nullable"` // default: false
+}
+
 type SSH struct {
 	LocalPort *int `yaml:"localPort,omitempty" json:"localPort,omitempty" jsonschema:"nullable"`
 
@@ -175,6 +232,20 @@ type SSH struct {
 	ForwardAgent      *bool `yaml:"forwardAgent,omitempty" json:"forwardAgent,omitempty" jsonschema:"nullable"`           // default: false
 	ForwardX11        *bool `yaml:"forwardX11,omitempty" json:"forwardX11,omitempty" jsonschema:"nullable"`               // default: false
 	ForwardX11Trusted *bool `yaml:"forwardX11Trusted,omitempty" json:"forwardX11Trusted,omitempty" jsonschema:"nullable"` // default: false
+
+	// AdditionalAuthorizedKeys are authorized in addition to $LIMA_HOME/_config/user.pub (and ~/.ssh/*.pub).
+	// Each entry is either an inline public key, or a path of a public key file on the host.
+	AdditionalAuthorizedKeys []string `yaml:"additionalAuthorizedKeys,omitempty" json:"additionalAuthorizedKeys,omitempty" jsonschema:"nullable"`
+
+	KeepAlive SSHKeepAlive `yaml:"keepAlive,omitempty" json:"keepAlive,omitempty"`
+}
+
+type SSHKeepAlive struct {
+	// Interval is the interval in seconds of the keepalive messages sent to the guest (ServerAliveInterval).
+	// 0 disables the keepalive messages.
+	Interval *int `yaml:"interval,omitempty" json:"interval,omitempty" jsonschema:"nullable"` // default: 30
+	// CountMax is the number of the keepalive messages sent without any response before disconnecting (ServerAliveCountMax).
+	CountMax *int `yaml:"countMax,omitempty" json:"countMax,omitempty" jsonschema:"nullable"` // default: 3
 }
 
 type Firmware struct {
@@ -212,11 +283,18 @@ const (
 	ProvisionModeAnsible    ProvisionMode = "ansible"
 )
 
+var ProvisionModes = []ProvisionMode{ProvisionModeSystem, ProvisionModeUser, ProvisionModeBoot, ProvisionModeDependency, ProvisionModeAnsible}
+
 type Provision struct {
 	Mode                            ProvisionMode `yaml:"mode,omitempty" json:"mode,omitempty" jsonschema:"default=system"`
 	SkipDefaultDependencyResolution *bool         `yaml:"skipDefaultDependencyResolution,omitempty" json:"skipDefaultDependencyResolution,omitempty"`
 	Script                          string        `yaml:"script" json:"script"`
 	Playbook                        string        `yaml:"playbook,omitempty" json:"playbook,omitempty"`
+	// Interpreter overrides the hash-bang line of the script, e.g., "/usr/bin/env python3".
+	Interpreter *string `yaml:"interpreter,omitempty" json:"interpreter,omitempty"`
+	// When is the condition on the facts of the guest, e.g., "arch == aarch64 && os == ubuntu".
+	// The script is skipped when the condition is not satisfied.
+	When *string `yaml:"when,omitempty" json:"when,omitempty"`
 }
 
 type Containerd struct {
@@ -231,6 +309,8 @@ const (
 	ProbeModeReadiness ProbeMode = "readiness"
 )
 
+var ProbeModes = []ProbeMode{ProbeModeReadiness}
+
 type Probe struct {
 	Mode        ProbeMode `yaml:"mode,omitempty" json:"mode,omitempty" jsonschema:"default=readiness"`
 	Description string    `yaml:"description,omitempty" json:"description,omitempty"`
@@ -246,6 +326,8 @@ const (
 	ProtoAny Proto = "any"
 )
 
+var Protos = []Proto{ProtoTCP, ProtoUDP, ProtoAny}
+
 type PortForward struct {
 	GuestIPMustBeZero bool   `yaml:"guestIPMustBeZero,omitempty" json:"guestIPMustBeZero,omitempty"`
 	GuestIP           net.IP `yaml:"guestIP,omitempty" json:"guestIP,omitempty"`
@@ -259,6 +341,42 @@ type PortForward struct {
 	Proto             Proto  `yaml:"proto,omitempty" json:"proto,omitempty"`
 	Reverse           bool   `yaml:"reverse,omitempty" json:"reverse,omitempty"`
 	Ignore            bool   `yaml:"ignore,omitempty" json:"ignore,omitempty"`
+	// Static forwards the port on start, without waiting for the guest to listen on the port.
+	Static bool `yaml:"static,omitempty" json:"static,omitempty"`
+}
+
+type PortForwardConflictPolicy = string
+
+const (
+	PortForwardConflictFail  PortForwardConflictPolicy = "fail"
+	PortForwardConflictSkip  PortForwardConflictPolicy = "skip"
+	PortForwardConflictRemap PortForwardConflictPolicy = "remap"
+)
+
+var PortForwardConflictPolicies = []PortForwardConflictPolicy{PortForwardConflictFail, PortForwardConflictSkip, PortForwardConflictRemap}
+
+type WriteFile struct {
+	Path string `yaml:"path" json:"path"`
+	// Content and Source are mutually exclusive. Source is a file on the host.
+	Content     *string `yaml:"content,omitempty" json:"content,omitempty" jsonschema:"nullable"`
+	Source      *string `yaml:"source,omitempty" json:"source,omitempty" jsonschema:"nullable"`
+	Owner       *string `yaml:"owner,omitempty" json:"owner,omitempty" jsonschema:"nullable"`
+	Permissions *string `yaml:"permissions,omitempty" json:"permissions,omitempty" jsonschema:"nullable"`
+}
+
+// SystemdUnitSuffixes are the suffixes of the names of the systemd units that can be specified in SystemdUnits.
+var SystemdUnitSuffixes = []string{".service", ".timer"}
+
+type SystemdUnit struct {
+	Name    string `yaml:"name" json:"name"` // e.g., "foo.service"
+	Content string `yaml:"content" json:"content"`
+	// Enabled units are enabled and started by `systemctl enable --now`.
+	Enabled *bool `yaml:"enabled,omitempty" json:"enabled,omitempty" jsonschema:"nullable"` // default: true
+}
+
+type SocketForward struct {
+	GuestSocket string `yaml:"guestSocket,omitempty" json:"guestSocket,omitempty"`
+	HostSocket  string `yaml:"hostSocket,omitempty" json:"hostSocket,omitempty"`
 }
 
 type CopyToHost struct {
@@ -267,6 +385,25 @@ type CopyToHost struct {
 	DeleteOnStop bool   `yaml:"deleteOnStop,omitempty" json:"deleteOnStop,omitempty"`
 }
 
+type HostHookEvent = string
+
+const (
+	HostHookEventPortOpened HostHookEvent = "portOpened"
+	HostHookEventPortClosed HostHookEvent = "portClosed"
+)
+
+var HostHookEvents = []HostHookEvent{HostHookEventPortOpened, HostHookEventPortClosed}
+
+type HostHook struct {
+	Event HostHookEvent `yaml:"event" json:"event"`
+	// GuestPortRange and Proto filter the guest ports of the event.
+	GuestPortRange [2]int `yaml:"guestPortRange,omitempty" json:"guestPortRange,omitempty"` // default: [1, 65535]
+	Proto          Proto  `yaml:"proto,omitempty" json:"proto,omitempty"`                   // default: "any"
+	// Command is executed on the host without a shell, with the privileges of the host user.
+	Command []string `yaml:"command" json:"command"`
+	Timeout *int     `yaml:"timeout,omitempty" json:"timeout,omitempty" jsonschema:"nullable"` // seconds, default: 60
+}
+
 type Network struct {
 	// `Lima` and `Socket` are mutually exclusive; exactly one is required
 	Lima string `yaml:"lima,omitempty" json:"lima,omitempty"`
@@ -278,6 +415,10 @@ type Network struct {
 	MACAddress string  `yaml:"macAddress,omitempty" json:"macAddress,omitempty"`
 	Interface  string  `yaml:"interface,omitempty" json:"interface,omitempty"`
 	Metric     *uint32 `yaml:"metric,omitempty" json:"metric,omitempty"`
+	MTU        *uint32 `yaml:"mtu,omitempty" json:"mtu,omitempty"`
+	// PortForwards=false hides the ports bound exclusively to the addresses of this interface from the port forwarding.
+	// The ports bound to the wildcard addresses (0.0.0.0 and ::) are still forwarded.
+	PortForwards *bool `yaml:"portForwards,omitempty" json:"portForwards,omitempty"` // default: true
 }
 
 type HostResolver struct {
@@ -11,24 +11,57 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+type options struct {
+	extraLayers []layer
+}
+
+type layer struct {
+	bytes []byte
+	label string
+}
+
+type Opt func(*options) error
+
+// WithExtraLayers adds YAML documents to be merged between the default file and the main file.
+// Later layers take precedence over earlier ones. The labels identify the layers in the warnings and errors.
+func WithExtraLayers(layers [][]byte, labels []string) Opt {
+	return func(o *options) error {
+		if len(layers) != len(labels) {
+			return fmt.Errorf("got %d extra layers but %d labels", len(layers), len(labels))
+		}
+		for i := range layers {
+			o.extraLayers = append(o.extraLayers, layer{bytes: layers[i], label: labels[i]})
+		}
+		return nil
+	}
+}
+
 // Load loads the yaml and fulfills unspecified fields with the default values.
+// Deprecated fields are migrated to their replacements, with a warning.
 //
 // Load does not validate. Use Validate for validation.
-func Load(b []byte, filePath string) (*LimaYAML, error) {
-	return load(b, filePath, false)
+func Load(b []byte, filePath string, opts ...Opt) (*LimaYAML, error) {
+	return load(b, filePath, false, opts...)
 }
 
 // LoadWithWarnings will call FillDefaults with warnings enabled (e.g. when
 // the username is not valid on Linux and must be replaced by "Lima").
 // It is called when creating or editing an instance.
-func LoadWithWarnings(b []byte, filePath string) (*LimaYAML, error) {
-	return load(b, filePath, true)
+func LoadWithWarnings(b []byte, filePath string, opts ...Opt) (*LimaYAML, error) {
+	return load(b, filePath, true, opts...)
 }
 
-func load(b []byte, filePath string, warn bool) (*LimaYAML, error) {
+func load(b []byte, filePath string, warn bool, opts ...Opt) (*LimaYAML, error) {
+	var options options
+	for _, f := range opts {
+		if err := f(&options); err != nil {
+			return nil, err
+		}
+	}
+
 	var y, d, o LimaYAML
 
-	if err := Unmarshal(b, &y, fmt.Sprintf("main file %q", filePath)); err != nil {
+	if err := unmarshalWithMigrations(b, &y, fmt.Sprintf("main file %q", filePath)); err != nil {
 		return nil, err
 	}
 	configDir, err := dirnames.LimaConfigDir()
@@ -40,18 +73,28 @@ func load(b []byte, filePath string, warn bool) (*LimaYAML, error) {
 	bytes, err := os.ReadFile(defaultPath)
 	if err == nil {
 		logrus.Debugf("Mixing %q into %q", defaultPath, filePath)
-		if err := Unmarshal(bytes, &d, fmt.Sprintf("default file %q", defaultPath)); err != nil {
+		if err := unmarshalWithMigrations(bytes, &d, fmt.Sprintf("default file %q", defaultPath)); err != nil {
 			return nil, err
 		}
 	} else if !errors.Is(err, os.ErrNotExist) {
 		return nil, err
 	}
 
+	for _, l := range options.extraLayers {
+		logrus.Debugf("Mixing extra layer %q into %q", l.label, filePath)
+		var e LimaYAML
+		if err := unmarshalWithMigrations(l.bytes, &e, fmt.Sprintf("extra layer %q", l.label)); err != nil {
+			return nil, err
+		}
+		mergeLayer(&e, &d)
+		d = e
+	}
+
 	overridePath := filepath.Join(configDir, filenames.Override)
 	bytes, err = os.ReadFile(overridePath)
 	if err == nil {
 		logrus.Debugf("Mixing %q into %q", overridePath, filePath)
-		if err := Unmarshal(bytes, &o, fmt.Sprintf("override file %q", overridePath)); err != nil {
+		if err := unmarshalWithMigrations(bytes, &o, fmt.Sprintf("override file %q", overridePath)); err != nil {
 			return nil, err
 		}
 	} else if !errors.Is(err, os.ErrNotExist) {
@@ -66,3 +109,12 @@ func load(b []byte, filePath string, warn bool) (*LimaYAML, error) {
 	FillDefault(&y, &d, &o, filePath, warn)
 	return &y, nil
 }
+
+// unmarshalWithMigrations rewrites the deprecated fields before unmarshaling the YAML.
+func unmarshalWithMigrations(b []byte, y *LimaYAML, comment string) error {
+	b, err := migrate(b, comment, migrations)
+	if err != nil {
+		return err
+	}
+	return Unmarshal(b, y, comment)
+}
@@ -1,8 +1,11 @@
 package limayaml
 
 import (
+	"strings"
 	"testing"
 
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"gotest.tools/v3/assert"
 )
 
@@ -31,6 +34,27 @@ provision:
 	assert.ErrorContains(t, err, "map key-value is pre-defined")
 }
 
+func TestLoadPrimaryDisk(t *testing.T) {
+	y, err := Load([]byte(`disk: 50GiB`), "disk.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, *y.Disk.Size, "50GiB")
+	assert.Equal(t, *y.Disk.AutoTrim, false)
+
+	y, err = Load([]byte(`disk: {size: 50GiB, autoTrim: true}`), "disk.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, *y.Disk.Size, "50GiB")
+	assert.Equal(t, *y.Disk.AutoTrim, true)
+
+	y, err = Load([]byte(`disk: {autoTrim: true}`), "disk.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, *y.Disk.Size, defaultDiskSizeAsString())
+	assert.Equal(t, *y.Disk.AutoTrim, true)
+
+	y, err = Load([]byte(`disk: null`), "disk.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, *y.Disk.Size, defaultDiskSizeAsString())
+}
+
 func TestLoadDiskString(t *testing.T) {
 	s := `
 additionalDisks:
@@ -65,3 +89,77 @@ additionalDisks:
 	assert.Equal(t, y.AdditionalDisks[0].FSArgs[0], "-i")
 	assert.Equal(t, y.AdditionalDisks[0].FSArgs[1], "size=512")
 }
+
+func TestLoadDeprecatedField(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+	warnedMigrations.Delete("useHostResolver")
+
+	s := `
+useHostResolver: false
+`
+	y, err := Load([]byte(s), "deprecated.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, *y.HostResolver.Enabled, false)
+
+	var warned bool
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.WarnLevel && strings.Contains(e.Message, "`useHostResolver` is deprecated; use `hostResolver.enabled` instead") {
+			warned = true
+		}
+	}
+	assert.Assert(t, warned, "expected a deprecation warning")
+}
+
+func TestLoadWithExtraLayers(t *testing.T) {
+	base := `
+cpus: 2
+memory: "2GiB"
+env:
+  A: base
+  B: base
+provision:
+- script: "#!/bin/sh\necho base"
+`
+	overlay := `
+cpus: 3
+env:
+  B: overlay
+provision:
+- script: "#!/bin/sh\necho overlay"
+`
+	s := `
+memory: "4GiB"
+provision:
+- script: "#!/bin/sh\necho main"
+`
+	y, err := Load([]byte(s), "main.yaml", WithExtraLayers([][]byte{[]byte(base), []byte(overlay)}, []string{"base", "overlay"}))
+	assert.NilError(t, err)
+	// later layers take precedence over earlier ones, and the main file takes precedence over all layers
+	assert.Equal(t, *y.CPUs, 3)
+	assert.Equal(t, *y.Memory, "4GiB")
+	assert.DeepEqual(t, y.Env, map[string]string{"A": "base", "B": "overlay"})
+	assert.Equal(t, len(y.Provision), 3)
+	assert.Equal(t, y.Provision[0].Script, "#!/bin/sh\necho main")
+	assert.Equal(t, y.Provision[1].Script, "#!/bin/sh\necho overlay")
+	assert.Equal(t, y.Provision[2].Script, "#!/bin/sh\necho base")
+
+	_, err = Load([]byte(s), "main.yaml", WithExtraLayers([][]byte{[]byte(base)}, nil))
+	assert.Error(t, err, "got 1 extra layers but 0 labels")
+}
+
+func TestLoadWithExtraLayersStrict(t *testing.T) {
+	hook := test.NewGlobal()
+	t.Cleanup(hook.Reset)
+
+	_, err := Load([]byte{}, "main.yaml", WithExtraLayers([][]byte{[]byte("cpus: 2\n"), []byte("cpu: 2\n")}, []string{"base", "overlay"}))
+	assert.NilError(t, err)
+
+	var comments []any
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.WarnLevel && strings.Contains(e.Message, "Non-strict YAML detected") {
+			comments = append(comments, e.Data["comment"])
+		}
+	}
+	assert.DeepEqual(t, comments, []any{`extra layer "overlay"`})
+}
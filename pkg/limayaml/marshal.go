@@ -1,9 +1,14 @@
 package limayaml
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/goccy/go-yaml"
+	"github.com/goccy/go-yaml/lexer"
+	"github.com/goccy/go-yaml/token"
 	"github.com/lima-vm/lima/pkg/yqutil"
 	"github.com/sirupsen/logrus"
 )
@@ -26,6 +31,54 @@ func Marshal(y *LimaYAML, stream bool) ([]byte, error) {
 	return b, nil
 }
 
+// Save marshals the struct and writes it to filePath atomically, so that the file is never left
+// partially written. The fields are written in the order of the struct; comments are not preserved.
+// The marshaled YAML is loaded again before writing, to make sure that it round-trips.
+func Save(y *LimaYAML, filePath string) error {
+	b, err := Marshal(y, false)
+	if err != nil {
+		return err
+	}
+	if _, err := Load(b, filePath); err != nil {
+		return fmt.Errorf("failed to load the marshaled YAML: %w", err)
+	}
+	perm := os.FileMode(0o644)
+	if st, err := os.Stat(filePath); err == nil {
+		perm = st.Mode().Perm()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	tmpF, err := os.CreateTemp(filepath.Dir(filePath), filepath.Base(filePath)+".lima-*.tmp")
+	if err != nil {
+		return err
+	}
+	tmp := tmpF.Name()
+	defer os.RemoveAll(tmp)
+	defer tmpF.Close()
+	if _, err := tmpF.Write(b); err != nil {
+		return err
+	}
+	if err := tmpF.Sync(); err != nil {
+		return err
+	}
+	if err := tmpF.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp, perm); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filePath)
+}
+
+func unmarshalPrimaryDisk(dst *PrimaryDisk, b []byte) error {
+	var s string
+	if err := yaml.Unmarshal(b, &s); err == nil {
+		*dst = PrimaryDisk{Size: &s}
+		return nil
+	}
+	return yaml.Unmarshal(b, dst)
+}
+
 func unmarshalDisk(dst *Disk, b []byte) error {
 	var s string
 	if err := yaml.Unmarshal(b, &s); err == nil {
@@ -35,8 +88,28 @@ func unmarshalDisk(dst *Disk, b []byte) error {
 	return yaml.Unmarshal(b, dst)
 }
 
+// hasAliases returns true when the YAML contains anchors, aliases, or merge keys.
+func hasAliases(data []byte) bool {
+	for _, tk := range lexer.Tokenize(string(data)) {
+		switch tk.Type {
+		case token.AnchorType, token.AliasType, token.MergeKeyType:
+			return true
+		}
+	}
+	return false
+}
+
 func Unmarshal(data []byte, v any, comment string) error {
-	if err := yaml.UnmarshalWithOptions(data, v, yaml.CustomUnmarshaler[Disk](unmarshalDisk)); err != nil {
+	// go-yaml and yq handle the merge keys differently, so they are expanded in advance
+	// to get consistent results from both.
+	if hasAliases(data) {
+		expanded, err := yqutil.ExpandAliases(data)
+		if err != nil {
+			return fmt.Errorf("failed to expand YAML aliases (%s): %w", comment, err)
+		}
+		data = expanded
+	}
+	if err := yaml.UnmarshalWithOptions(data, v, yaml.CustomUnmarshaler[Disk](unmarshalDisk), yaml.CustomUnmarshaler[PrimaryDisk](unmarshalPrimaryDisk)); err != nil {
 		return fmt.Errorf("failed to unmarshal YAML (%s): %w", comment, err)
 	}
 	// the go-yaml library doesn't catch all markup errors, unfortunately
@@ -44,7 +117,7 @@ func Unmarshal(data []byte, v any, comment string) error {
 	if err := yqutil.ValidateContent(data); err != nil {
 		return fmt.Errorf("failed to unmarshal YAML (%s): %w", comment, err)
 	}
-	if err := yaml.UnmarshalWithOptions(data, v, yaml.Strict(), yaml.CustomUnmarshaler[Disk](unmarshalDisk)); err != nil {
+	if err := yaml.UnmarshalWithOptions(data, v, yaml.Strict(), yaml.CustomUnmarshaler[Disk](unmarshalDisk), yaml.CustomUnmarshaler[PrimaryDisk](unmarshalPrimaryDisk)); err != nil {
 		logrus.WithField("comment", comment).WithError(err).Warn("Non-strict YAML detected; please check for typos")
 	}
 	return nil
@@ -1,8 +1,12 @@
 package limayaml
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 
+	"github.com/google/go-cmp/cmp/cmpopts"
 	"github.com/lima-vm/lima/pkg/ptr"
 	"gotest.tools/v3/assert"
 )
@@ -35,3 +39,93 @@ mounts:
 ...
 `)
 }
+
+// TestMarshalFilledDefaults ensures that the effective config printed by `limactl validate --fill-defaults`
+// can be loaded again without losing any value.
+func TestMarshalFilledDefaults(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	y, err := Load([]byte{}, "empty.yaml")
+	assert.NilError(t, err)
+	b, err := Marshal(y, false)
+	assert.NilError(t, err)
+	var y2 LimaYAML
+	err = Unmarshal(b, &y2, "filled.yaml")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, &y2, y, cmpopts.EquateEmpty())
+}
+
+func TestUnmarshalMergeKeys(t *testing.T) {
+	s := `
+mounts:
+- &tmp
+  location: /tmp/a
+  writable: true
+- <<: *tmp
+  location: /tmp/b
+- <<: *tmp
+  location: /tmp/c
+  writable: false
+`
+	var y LimaYAML
+	err := Unmarshal([]byte(s), &y, "merge.yaml")
+	assert.NilError(t, err)
+	assert.Equal(t, len(y.Mounts), 3)
+	assert.Equal(t, y.Mounts[0].Location, "/tmp/a")
+	assert.Equal(t, *y.Mounts[0].Writable, true)
+	assert.Equal(t, y.Mounts[1].Location, "/tmp/b")
+	assert.Equal(t, *y.Mounts[1].Writable, true)
+	// keys in the mapping take precedence over the merged keys
+	assert.Equal(t, y.Mounts[2].Location, "/tmp/c")
+	assert.Equal(t, *y.Mounts[2].Writable, false)
+}
+
+func TestHasAliases(t *testing.T) {
+	assert.Assert(t, hasAliases([]byte("a: &x 1\nb: *x\n")))
+	assert.Assert(t, hasAliases([]byte("a: &x {c: 1}\nb:\n  <<: *x\n")))
+	assert.Assert(t, !hasAliases([]byte("a: 1\nscript: |\n  echo foo && ls *\n")))
+}
+
+func TestSave(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	dir := t.TempDir()
+	filePath := filepath.Join(dir, "lima.yaml")
+
+	y, err := Load([]byte(`
+cpus: 3
+memory: 6GiB
+mounts:
+- location: /tmp/lima
+  writable: true
+provision:
+- mode: system
+  script: |
+    #!/bin/sh
+    echo hello
+`), filePath)
+	assert.NilError(t, err)
+	assert.NilError(t, Save(y, filePath))
+
+	b, err := os.ReadFile(filePath)
+	assert.NilError(t, err)
+	y2, err := Load(b, filePath)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, y2, y, cmpopts.EquateEmpty())
+
+	// overwriting an existing file keeps its permissions, and leaves no temporary file behind
+	assert.NilError(t, os.Chmod(filePath, 0o600))
+	*y.CPUs = 4
+	assert.NilError(t, Save(y, filePath))
+	st, err := os.Stat(filePath)
+	assert.NilError(t, err)
+	if runtime.GOOS != "windows" {
+		assert.Equal(t, st.Mode().Perm(), os.FileMode(0o600))
+	}
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	assert.Equal(t, len(entries), 1)
+	b, err = os.ReadFile(filePath)
+	assert.NilError(t, err)
+	y2, err = Load(b, filePath)
+	assert.NilError(t, err)
+	assert.Equal(t, *y2.CPUs, 4)
+}
@@ -0,0 +1,89 @@
+package limayaml
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/goccy/go-yaml"
+	"github.com/lima-vm/lima/pkg/yqutil"
+	"github.com/sirupsen/logrus"
+)
+
+// migration maps a deprecated key to its replacement.
+type migration struct {
+	// from is the dot-separated path of the deprecated key, e.g., "useHostResolver"
+	from string
+	// to is the dot-separated path of the replacement key, e.g., "hostResolver.enabled"
+	to string
+	// transform is the yq expression to convert the value of the deprecated key for the replacement key.
+	// An empty string means the value is copied as is.
+	transform string
+}
+
+// migrations are applied in order, before the YAML is unmarshaled into LimaYAML.
+var migrations = []migration{
+	{
+		// deprecated in Lima v0.8.1
+		from: "useHostResolver",
+		to:   "hostResolver.enabled",
+	},
+}
+
+// warnedMigrations records the deprecated keys that have been already warned.
+var warnedMigrations sync.Map
+
+// migrate rewrites the deprecated keys in the YAML to their replacements.
+// The YAML node tree is edited with yq, so the comments and the order of the other keys are retained.
+// The YAML is returned as is when it does not contain any deprecated key.
+func migrate(b []byte, comment string, migrations []migration) ([]byte, error) {
+	var root yaml.MapSlice
+	if err := yaml.UnmarshalWithOptions(b, &root, yaml.UseOrderedMap()); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", comment, err)
+	}
+	var exprs []string
+	for _, m := range migrations {
+		from := strings.Split(m.from, ".")
+		if _, ok := lookupMapSlice(root, from); !ok {
+			continue
+		}
+		if _, ok := lookupMapSlice(root, strings.Split(m.to, ".")); ok {
+			logrus.Warnf("Ignoring deprecated field `%s` in %s, as `%s` is also specified", m.from, comment, m.to)
+			exprs = append(exprs, fmt.Sprintf("del(.%s)", m.from))
+			continue
+		}
+		value := "." + m.from
+		if m.transform != "" {
+			value = fmt.Sprintf("(.%s | %s)", m.from, m.transform)
+		}
+		exprs = append(exprs, fmt.Sprintf(".%s = %s | del(.%s)", m.to, value, m.from))
+		if _, warned := warnedMigrations.LoadOrStore(m.from, true); !warned {
+			logrus.Warnf("Field `%s` is deprecated; use `%s` instead (%s)", m.from, m.to, comment)
+		}
+	}
+	if len(exprs) == 0 {
+		return b, nil
+	}
+	out, err := yqutil.EvaluateExpression(yqutil.Join(exprs), b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate the deprecated fields in %s: %w", comment, err)
+	}
+	return out, nil
+}
+
+func lookupMapSlice(m yaml.MapSlice, path []string) (any, bool) {
+	for _, item := range m {
+		if item.Key != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			return item.Value, true
+		}
+		child, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return nil, false
+		}
+		return lookupMapSlice(child, path[1:])
+	}
+	return nil, false
+}
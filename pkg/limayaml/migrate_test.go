@@ -0,0 +1,81 @@
+package limayaml
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/goccy/go-yaml"
+	"gotest.tools/v3/assert"
+)
+
+func TestMigrate(t *testing.T) {
+	testMigrations := []migration{
+		{from: "useHostResolver", to: "hostResolver.enabled"},
+		{
+			from:      "legacy.size",
+			to:        "size",
+			transform: `. + "iB"`,
+		},
+	}
+	cases := []struct {
+		name     string
+		in       string
+		expected string
+	}{
+		{
+			name:     "no deprecated fields",
+			in:       "cpus: 2\n",
+			expected: "cpus: 2\n",
+		},
+		{
+			name:     "top-level to nested",
+			in:       "cpus: 2\nuseHostResolver: false\n",
+			expected: "cpus: 2\nhostResolver:\n  enabled: false\n",
+		},
+		{
+			name:     "merged into the existing map",
+			in:       "hostResolver:\n  ipv6: true\nuseHostResolver: false\n",
+			expected: "hostResolver:\n  ipv6: true\n  enabled: false\n",
+		},
+		{
+			name:     "replacement takes precedence",
+			in:       "hostResolver:\n  enabled: true\nuseHostResolver: false\n",
+			expected: "hostResolver:\n  enabled: true\n",
+		},
+		{
+			name:     "nested with transform",
+			in:       "legacy:\n  size: 1G\n",
+			expected: "legacy: {}\nsize: 1GiB\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			b, err := migrate([]byte(tc.in), "test", testMigrations)
+			assert.NilError(t, err)
+			var actual, expected yaml.MapSlice
+			assert.NilError(t, yaml.UnmarshalWithOptions(b, &actual, yaml.UseOrderedMap()))
+			assert.NilError(t, yaml.UnmarshalWithOptions([]byte(tc.expected), &expected, yaml.UseOrderedMap()))
+			assert.DeepEqual(t, actual, expected)
+		})
+	}
+}
+
+func TestMigrateRetainsComments(t *testing.T) {
+	in := `# the number of CPUs
+cpus: 2
+useHostResolver: false
+# the size of the memory
+memory: 4GiB  # not 4GB
+`
+	b, err := migrate([]byte(in), "test", migrations)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.Contains(string(b), "# the number of CPUs\n"), string(b))
+	assert.Assert(t, strings.Contains(string(b), "# the size of the memory\n"), string(b))
+	assert.Assert(t, strings.Contains(string(b), "memory: 4GiB  # not 4GB\n"), string(b))
+	assert.Assert(t, !strings.Contains(string(b), "useHostResolver"), string(b))
+}
+
+func TestMigrateInvalid(t *testing.T) {
+	_, err := migrate([]byte("cpus: [2\n"), "test", migrations)
+	assert.ErrorContains(t, err, "failed to parse test")
+}
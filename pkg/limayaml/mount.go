@@ -0,0 +1,43 @@
+package limayaml
+
+import (
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/lima-vm/lima/pkg/localpathutil"
+)
+
+// GuestPathOfHostPath returns the guest path that corresponds to hostPath through the mounts,
+// or an empty string when hostPath is not under any mount.
+// When the mounts are nested, the innermost one is used.
+func GuestPathOfHostPath(mounts []Mount, hostPath string) string {
+	var (
+		res     string
+		longest = -1
+	)
+	for _, m := range mounts {
+		location, err := localpathutil.Expand(m.Location)
+		if err != nil {
+			continue
+		}
+		mountPoint := m.Location
+		if m.MountPoint != nil {
+			mountPoint = *m.MountPoint
+		}
+		// Expanded in the same way as the host agent does
+		mountPoint, err = localpathutil.Expand(mountPoint)
+		if err != nil {
+			continue
+		}
+		rel, err := filepath.Rel(location, hostPath)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if len(location) > longest {
+			longest = len(location)
+			res = path.Join(filepath.ToSlash(mountPoint), filepath.ToSlash(rel))
+		}
+	}
+	return res
+}
@@ -0,0 +1,35 @@
+package limayaml
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/ptr"
+	"gotest.tools/v3/assert"
+)
+
+func TestGuestPathOfHostPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	mounts := []Mount{
+		{Location: "~", MountPoint: ptr.Of("~")},
+		{Location: "/tmp/lima", MountPoint: ptr.Of("/mnt/lima")},
+		{Location: filepath.Join(home, "src"), MountPoint: ptr.Of("/src")},
+		{Location: "/opt/data"},
+	}
+
+	// Inside the mounts
+	assert.Equal(t, GuestPathOfHostPath(mounts, home), home)
+	assert.Equal(t, GuestPathOfHostPath(mounts, filepath.Join(home, "docs")), filepath.Join(home, "docs"))
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/tmp/lima"), "/mnt/lima")
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/tmp/lima/foo/bar"), "/mnt/lima/foo/bar")
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/opt/data/foo"), "/opt/data/foo")
+	// The innermost mount is used
+	assert.Equal(t, GuestPathOfHostPath(mounts, filepath.Join(home, "src", "lima")), "/src/lima")
+
+	// Outside the mounts
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/tmp"), "")
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/tmp/lima-foo"), "")
+	assert.Equal(t, GuestPathOfHostPath(mounts, "/"), "")
+	assert.Equal(t, GuestPathOfHostPath(nil, home), "")
+}
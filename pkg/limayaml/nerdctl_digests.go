@@ -0,0 +1,69 @@
+package limayaml
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path"
+
+	"github.com/goccy/go-yaml"
+	"github.com/opencontainers/go-digest"
+)
+
+// NerdctlDigestsEnv is the name of the environment variable that specifies the path of
+// a YAML (or JSON) file that maps the file names of the nerdctl-full archives to their digests, e.g.,
+//
+//	nerdctl-full-2.1.0-linux-amd64.tar.gz: sha256:...
+//
+// so that the archives of the nerdctl versions that are not shipped with Lima yet can be verified.
+const NerdctlDigestsEnv = "LIMA_NERDCTL_DIGESTS"
+
+// NerdctlDigests returns the digests of the nerdctl-full archives, keyed by the file names.
+// The digests in the file specified by $LIMA_NERDCTL_DIGESTS take precedence over the built-in ones.
+func NerdctlDigests() (map[string]digest.Digest, error) {
+	res := make(map[string]digest.Digest)
+	for _, f := range defaultContainerdArchives() {
+		res[path.Base(f.Location)] = f.Digest
+	}
+	if p := os.Getenv(NerdctlDigestsEnv); p != "" {
+		external, err := readNerdctlDigests(p)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load $%s: %w", NerdctlDigestsEnv, err)
+		}
+		maps.Copy(res, external)
+	}
+	return res, nil
+}
+
+func readNerdctlDigests(p string) (map[string]digest.Digest, error) {
+	b, err := os.ReadFile(p)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]digest.Digest
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %q as YAML: %w", p, err)
+	}
+	for name, d := range m {
+		if err := d.Validate(); err != nil {
+			return nil, fmt.Errorf("invalid digest %q for %q in %q: %w", d, name, p, err)
+		}
+	}
+	return m, nil
+}
+
+// FillNerdctlDigests returns a copy of the archives, with the missing digests filled from NerdctlDigests.
+func FillNerdctlDigests(archives []File) ([]File, error) {
+	digests, err := NerdctlDigests()
+	if err != nil {
+		return nil, err
+	}
+	res := make([]File, len(archives))
+	for i, f := range archives {
+		if f.Digest == "" {
+			f.Digest = digests[path.Base(f.Location)]
+		}
+		res[i] = f
+	}
+	return res, nil
+}
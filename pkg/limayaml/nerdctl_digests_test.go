@@ -0,0 +1,49 @@
+package limayaml
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/opencontainers/go-digest"
+	"gotest.tools/v3/assert"
+)
+
+func TestNerdctlDigests(t *testing.T) {
+	t.Setenv(NerdctlDigestsEnv, "")
+	builtin, err := NerdctlDigests()
+	assert.NilError(t, err)
+	assert.Equal(t, len(builtin), len(defaultContainerdArchives()))
+
+	const (
+		newAMD64 = "nerdctl-full-99.0.0-linux-amd64.tar.gz"
+		newARM64 = "nerdctl-full-99.0.0-linux-arm64.tar.gz"
+	)
+	overridden := defaultContainerdArchives()[0]
+	overriddenName := filepath.Base(overridden.Location)
+	newDigest := digest.FromString("nerdctl-full-99.0.0")
+	p := filepath.Join(t.TempDir(), "nerdctl-digests.yaml")
+	assert.NilError(t, os.WriteFile(p, []byte(newAMD64+": "+newDigest.String()+"\n"+overriddenName+": "+newDigest.String()+"\n"), 0o644))
+	t.Setenv(NerdctlDigestsEnv, p)
+	merged, err := NerdctlDigests()
+	assert.NilError(t, err)
+	assert.Equal(t, len(merged), len(builtin)+1)
+	assert.Equal(t, merged[newAMD64], newDigest)
+	assert.Equal(t, merged[overriddenName], newDigest)
+
+	archives := []File{
+		{Location: "https://github.com/containerd/nerdctl/releases/download/v99.0.0/" + newAMD64, Arch: X8664},
+		{Location: "https://github.com/containerd/nerdctl/releases/download/v99.0.0/" + newARM64, Arch: AARCH64},
+		{Location: "/tmp/" + newAMD64, Arch: X8664, Digest: builtin[overriddenName]},
+	}
+	filled, err := FillNerdctlDigests(archives)
+	assert.NilError(t, err)
+	assert.Equal(t, filled[0].Digest, newDigest)
+	assert.Equal(t, filled[1].Digest, digest.Digest(""))
+	assert.Equal(t, filled[2].Digest, builtin[overriddenName])
+	assert.Equal(t, archives[0].Digest, digest.Digest(""))
+
+	assert.NilError(t, os.WriteFile(p, []byte(newAMD64+": sha256:deadbeef\n"), 0o644))
+	_, err = NerdctlDigests()
+	assert.ErrorContains(t, err, "invalid digest")
+}
@@ -9,70 +9,162 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 	"unicode"
 
 	"github.com/containerd/containerd/identifiers"
 	"github.com/coreos/go-semver/semver"
 	"github.com/docker/go-units"
+	"github.com/lima-vm/lima/pkg/fatutil"
+	guestagentaddress "github.com/lima-vm/lima/pkg/guestagent/address"
 	"github.com/lima-vm/lima/pkg/localpathutil"
 	"github.com/lima-vm/lima/pkg/networks"
 	"github.com/lima-vm/lima/pkg/osutil"
+	"github.com/lima-vm/lima/pkg/provisioncond"
+	"github.com/lima-vm/lima/pkg/sshutil"
 	"github.com/lima-vm/lima/pkg/version"
 	"github.com/lima-vm/lima/pkg/version/versionutil"
 	"github.com/sirupsen/logrus"
 )
 
-func validateFileObject(f File, fieldName string) error {
+// FieldError is an error of a field in lima.yaml.
+type FieldError struct {
+	// Field is the YAML path of the field, e.g., "provision[2].mode".
+	Field string
+	// Value is the invalid value, or nil when not applicable.
+	Value any
+	// Err is the reason, e.g., "must be ...".
+	Err error
+}
+
+func (e *FieldError) Error() string {
+	return fmt.Sprintf("field `%s` %v", e.Field, e.Err)
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationErrors is the error returned by Validate, with an entry for each invalid field.
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	s := make([]string, len(errs))
+	for i, e := range errs {
+		s[i] = e.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+func (errs ValidationErrors) Unwrap() []error {
+	res := make([]error, len(errs))
+	for i, e := range errs {
+		res[i] = e
+	}
+	return res
+}
+
+// errorf appends a FieldError for the field with the value, with the reason formatted by fmt.Errorf.
+func (errs *ValidationErrors) errorf(field string, value any, format string, a ...any) {
+	*errs = append(*errs, &FieldError{Field: field, Value: value, Err: fmt.Errorf(format, a...)})
+}
+
+// add appends the FieldError, unless it is nil.
+func (errs *ValidationErrors) add(e *FieldError) {
+	if e != nil {
+		*errs = append(*errs, e)
+	}
+}
+
+func validateFileObject(f File, fieldName string) ValidationErrors {
+	var errs ValidationErrors
 	if !strings.Contains(f.Location, "://") {
 		if _, err := localpathutil.Expand(f.Location); err != nil {
-			return fmt.Errorf("field `%s.location` refers to an invalid local file path: %q: %w", fieldName, f.Location, err)
+			errs.errorf(fieldName+".location", f.Location, "refers to an invalid local file path: %q: %w", f.Location, err)
 		}
 		// f.Location does NOT need to be accessible, so we do NOT check os.Stat(f.Location)
 	}
 	switch f.Arch {
 	case X8664, AARCH64, ARMV7L, RISCV64:
 	default:
-		return fmt.Errorf("field `arch` must be %q, %q, %q, or %q; got %q", X8664, AARCH64, ARMV7L, RISCV64, f.Arch)
+		errs.errorf(fieldName+".arch", f.Arch, "must be %q, %q, %q, or %q; got %q", X8664, AARCH64, ARMV7L, RISCV64, f.Arch)
 	}
 	if f.Digest != "" {
 		if !f.Digest.Algorithm().Available() {
-			return fmt.Errorf("field `%s.digest` refers to an unavailable digest algorithm", fieldName)
-		}
-		if err := f.Digest.Validate(); err != nil {
-			return fmt.Errorf("field `%s.digest` is invalid: %s: %w", fieldName, f.Digest.String(), err)
+			errs.errorf(fieldName+".digest", f.Digest, "refers to an unavailable digest algorithm")
+		} else if err := f.Digest.Validate(); err != nil {
+			errs.errorf(fieldName+".digest", f.Digest, "is invalid: %s: %w", f.Digest.String(), err)
 		}
 	}
+	return errs
+}
+
+// localeRegex matches locale names like "C", "C.UTF-8", "en_US.UTF-8", and "sr_RS@latin".
+var localeRegex = regexp.MustCompile(`^(C|POSIX|[a-z]{2,3}(_[A-Z]{2})?)(\.[A-Za-z0-9-]+)?(@[A-Za-z0-9]+)?$`)
+
+// timeZoneRegex matches the syntax of the names in the zoneinfo database, such as "UTC" and "America/Argentina/Buenos_Aires".
+var timeZoneRegex = regexp.MustCompile(`^[A-Za-z0-9_+-]+(/[A-Za-z0-9_+-]+)*$`)
+
+// checkTimeZone returns an error if tz is not a name in the zoneinfo database.
+// Only the syntax of the name is checked when the zoneinfo database is not available on the host.
+func checkTimeZone(tz string) error {
+	// "Local" is a special name for time.LoadLocation, not a name in the zoneinfo database
+	if tz == "Local" || !timeZoneRegex.MatchString(tz) {
+		return fmt.Errorf("must be a name in the zoneinfo database, got %q", tz)
+	}
+	if _, err := time.LoadLocation("Etc/UTC"); err != nil {
+		logrus.WithError(err).Debugf("the zoneinfo database is not available, not looking up the timezone %q", tz)
+		return nil
+	}
+	if _, err := time.LoadLocation(tz); err != nil {
+		return fmt.Errorf("must be a name in the zoneinfo database, got %q: %w", tz, err)
+	}
+	return nil
+}
+
+func validateTimeZone(tz string) *FieldError {
+	if err := checkTimeZone(tz); err != nil {
+		return &FieldError{Field: "timezone", Value: tz, Err: err}
+	}
 	return nil
 }
 
+// Validate validates the LimaYAML filled by FillDefault.
+// The returned error is ValidationErrors, unless the builtin Lima version is broken.
 func Validate(y *LimaYAML, warn bool) error {
+	var errs ValidationErrors
 	if y.MinimumLimaVersion != nil {
 		if _, err := versionutil.Parse(*y.MinimumLimaVersion); err != nil {
-			return fmt.Errorf("field `minimumLimaVersion` must be a semvar value, got %q: %w", *y.MinimumLimaVersion, err)
-		}
-		limaVersion, err := versionutil.Parse(version.Version)
-		if err != nil {
-			return fmt.Errorf("can't parse builtin Lima version %q: %w", version.Version, err)
-		}
-		if versionutil.GreaterThan(*y.MinimumLimaVersion, limaVersion.String()) {
-			return fmt.Errorf("template requires Lima version %q; this is only %q", *y.MinimumLimaVersion, limaVersion.String())
+			errs.errorf("minimumLimaVersion", *y.MinimumLimaVersion, "must be a semvar value, got %q: %w", *y.MinimumLimaVersion, err)
+		} else {
+			limaVersion, err := versionutil.Parse(version.Version)
+			if err != nil {
+				return fmt.Errorf("can't parse builtin Lima version %q: %w", version.Version, err)
+			}
+			if versionutil.GreaterThan(*y.MinimumLimaVersion, limaVersion.String()) {
+				// The other fields may not be understood by this version, so they are not validated
+				errs.errorf("minimumLimaVersion", *y.MinimumLimaVersion, "requires Lima version %q; this is only %q", *y.MinimumLimaVersion, limaVersion.String())
+				return errs
+			}
 		}
 	}
 	if y.VMOpts.QEMU.MinimumVersion != nil {
 		if _, err := semver.NewVersion(*y.VMOpts.QEMU.MinimumVersion); err != nil {
-			return fmt.Errorf("field `vmOpts.qemu.minimumVersion` must be a semvar value, got %q: %w", *y.VMOpts.QEMU.MinimumVersion, err)
+			errs.errorf("vmOpts.qemu.minimumVersion", *y.VMOpts.QEMU.MinimumVersion, "must be a semvar value, got %q: %w", *y.VMOpts.QEMU.MinimumVersion, err)
 		}
 	}
 	switch *y.OS {
 	case LINUX:
 	default:
-		return fmt.Errorf("field `os` must be %q; got %q", LINUX, *y.OS)
+		errs.errorf("os", *y.OS, "must be %q; got %q", LINUX, *y.OS)
 	}
 	switch *y.Arch {
 	case X8664, AARCH64, ARMV7L, RISCV64:
 	default:
-		return fmt.Errorf("field `arch` must be %q, %q, %q or %q; got %q", X8664, AARCH64, ARMV7L, RISCV64, *y.Arch)
+		errs.errorf("arch", *y.Arch, "must be %q, %q, %q or %q; got %q", X8664, AARCH64, ARMV7L, RISCV64, *y.Arch)
 	}
 
 	switch *y.VMType {
@@ -82,36 +174,31 @@ func Validate(y *LimaYAML, warn bool) error {
 		// NOP
 	case VZ:
 		if !IsNativeArch(*y.Arch) {
-			return fmt.Errorf("field `arch` must be %q for VZ; got %q", NewArch(runtime.GOARCH), *y.Arch)
+			errs.errorf("arch", *y.Arch, "must be %q for VZ; got %q", NewArch(runtime.GOARCH), *y.Arch)
 		}
 	default:
-		return fmt.Errorf("field `vmType` must be %q, %q, %q; got %q", QEMU, VZ, WSL2, *y.VMType)
+		errs.errorf("vmType", *y.VMType, "must be %q, %q, %q; got %q", QEMU, VZ, WSL2, *y.VMType)
 	}
 
 	if len(y.Images) == 0 {
-		return errors.New("field `images` must be set")
+		errs.errorf("images", nil, "must be set")
 	}
 	for i, f := range y.Images {
-		if err := validateFileObject(f.File, fmt.Sprintf("images[%d]", i)); err != nil {
-			return err
-		}
+		field := fmt.Sprintf("images[%d]", i)
+		errs = append(errs, validateFileObject(f.File, field)...)
 		if f.Kernel != nil {
-			if err := validateFileObject(f.Kernel.File, fmt.Sprintf("images[%d].kernel", i)); err != nil {
-				return err
-			}
+			errs = append(errs, validateFileObject(f.Kernel.File, field+".kernel")...)
 			if f.Kernel.Arch != f.Arch {
-				return fmt.Errorf("images[%d].kernel has unexpected architecture %q, must be %q", i, f.Kernel.Arch, f.Arch)
+				errs.errorf(field+".kernel.arch", f.Kernel.Arch, "must be %q, got %q", f.Arch, f.Kernel.Arch)
 			}
 		}
 		if f.Initrd != nil {
-			if err := validateFileObject(*f.Initrd, fmt.Sprintf("images[%d].initrd", i)); err != nil {
-				return err
-			}
+			errs = append(errs, validateFileObject(*f.Initrd, field+".initrd")...)
 			if f.Kernel == nil {
-				return errors.New("initrd requires the kernel to be specified")
+				errs.errorf(field+".initrd", nil, "requires field `%s.kernel` to be specified", field)
 			}
 			if f.Initrd.Arch != f.Arch {
-				return fmt.Errorf("images[%d].initrd has unexpected architecture %q, must be %q", i, f.Initrd.Arch, f.Arch)
+				errs.errorf(field+".initrd.arch", f.Initrd.Arch, "must be %q, got %q", f.Arch, f.Initrd.Arch)
 			}
 		}
 	}
@@ -121,75 +208,131 @@ func Validate(y *LimaYAML, warn bool) error {
 		case AARCH64, X8664, ARMV7L, RISCV64:
 			// these are the only supported architectures
 		default:
-			return fmt.Errorf("field `cpuType` uses unsupported arch %q", arch)
+			errs.errorf("cpuType", arch, "uses unsupported arch %q", arch)
+		}
+	}
+
+	if y.TimeZone != nil && *y.TimeZone != "" {
+		errs.add(validateTimeZone(*y.TimeZone))
+	}
+	if y.Locale != nil && *y.Locale != "" && !localeRegex.MatchString(*y.Locale) {
+		errs.errorf("locale", *y.Locale, "must be a locale name like \"en_US.UTF-8\", got %q", *y.Locale)
+	}
+
+	if y.GuestAgent.Address != nil && *y.GuestAgent.Address != "" {
+		if addr, err := guestagentaddress.Parse(*y.GuestAgent.Address); err != nil {
+			errs.errorf("guestAgent.address", *y.GuestAgent.Address, "must be like \"unix:///PATH\", \"vsock://CID:PORT\", or \"tcp://HOST:PORT\": %w", err)
+		} else if addr.Transport == guestagentaddress.TransportVSock {
+			// Only QEMU on Linux hosts attaches a vsock device with the given CID (vhost-vsock-pci),
+			// and dialing vsock is not supported on the other hosts.
+			if *y.VMType != QEMU || runtime.GOOS != "linux" {
+				errs.errorf("guestAgent.address", *y.GuestAgent.Address, "must not use vsock, except for `vmType: qemu` on Linux hosts")
+			} else if addr.CID < 3 {
+				// CID 0, 1, and 2 are reserved for the hypervisor, the loopback, and the host
+				errs.errorf("guestAgent.address", *y.GuestAgent.Address, "must have a CID greater than or equal to 3, got %d", addr.CID)
+			}
+		}
+	}
+	if y.CIData.VolumeLabel != nil {
+		if err := ValidateCIDataVolumeLabel(*y.CIData.VolumeLabel, y.CIData.VFAT != nil && *y.CIData.VFAT); err != nil {
+			errs.add(&FieldError{Field: "cidata.volumeLabel", Value: *y.CIData.VolumeLabel, Err: err})
+		}
+	}
+	if y.PortForwardConflict != nil && !slices.Contains(PortForwardConflictPolicies, *y.PortForwardConflict) {
+		errs.errorf("portForwardConflict", *y.PortForwardConflict, "must be one of %v, got %q", PortForwardConflictPolicies, *y.PortForwardConflict)
+	}
+
+	if y.User.Name != nil && !osutil.IsValidUsername(*y.User.Name) {
+		errs.errorf("user.name", *y.User.Name, "must be a valid Linux username, got %q", *y.User.Name)
+	}
+	if y.User.UID != nil && *y.User.UID == 0 {
+		errs.errorf("user.uid", *y.User.UID, "must be a positive integer")
+	}
+	for i, group := range y.User.Groups {
+		if !osutil.IsValidUsername(group) {
+			errs.errorf(fmt.Sprintf("user.groups[%d]", i), group, "must be a valid Linux group name, got %q", group)
+		}
+	}
+	if y.User.Password != nil && *y.User.Password != "" && !isCryptHash(*y.User.Password) {
+		if y.User.InsecurePlainTextPassword == nil || !*y.User.InsecurePlainTextPassword {
+			// The value is not included, as it may be a plain text password
+			errs.errorf("user.password", nil, "must be a password hash such as \"$6$...\" (hint: run `mkpasswd -m sha-512` or `openssl passwd -6` to generate it),"+
+				" or set `user.insecurePlainTextPassword` to true to use a plain text password")
 		}
 	}
 
 	if *y.CPUs == 0 {
-		return errors.New("field `cpus` must be set")
+		errs.errorf("cpus", *y.CPUs, "must be set")
 	}
 
 	if _, err := units.RAMInBytes(*y.Memory); err != nil {
-		return fmt.Errorf("field `memory` has an invalid value: %w", err)
+		errs.errorf("memory", *y.Memory, "has an invalid value: %w", err)
 	}
 
-	if _, err := units.RAMInBytes(*y.Disk); err != nil {
-		return fmt.Errorf("field `memory` has an invalid value: %w", err)
+	if _, err := units.RAMInBytes(*y.Disk.Size); err != nil {
+		errs.errorf("disk.size", *y.Disk.Size, "has an invalid value: %w", err)
 	}
 
 	for i, disk := range y.AdditionalDisks {
+		field := fmt.Sprintf("additionalDisks[%d]", i)
 		if err := identifiers.Validate(disk.Name); err != nil {
-			return fmt.Errorf("field `additionalDisks[%d].name is invalid`: %w", i, err)
+			errs.errorf(field+".name", disk.Name, "is invalid: %w", err)
+		}
+		if disk.ReadOnly != nil && *disk.ReadOnly && disk.Format != nil && *disk.Format {
+			errs.errorf(field+".format", *disk.Format, "must not be true for a read-only disk")
 		}
 	}
 
 	for i, f := range y.Mounts {
+		field := fmt.Sprintf("mounts[%d]", i)
 		if !filepath.IsAbs(f.Location) && !strings.HasPrefix(f.Location, "~") {
-			return fmt.Errorf("field `mounts[%d].location` must be an absolute path, got %q",
-				i, f.Location)
-		}
-		loc, err := localpathutil.Expand(f.Location)
-		if err != nil {
-			return fmt.Errorf("field `mounts[%d].location` refers to an unexpandable path: %q: %w", i, f.Location, err)
-		}
-		st, err := os.Stat(loc)
-		if err != nil {
+			errs.errorf(field+".location", f.Location, "must be an absolute path, got %q", f.Location)
+		} else if loc, err := localpathutil.Expand(f.Location); err != nil {
+			errs.errorf(field+".location", f.Location, "refers to an unexpandable path: %q: %w", f.Location, err)
+		} else if st, err := os.Stat(loc); err != nil {
 			if !errors.Is(err, os.ErrNotExist) {
-				return fmt.Errorf("field `mounts[%d].location` refers to an inaccessible path: %q: %w", i, f.Location, err)
+				errs.errorf(field+".location", f.Location, "refers to an inaccessible path: %q: %w", f.Location, err)
 			}
 		} else if !st.IsDir() {
-			return fmt.Errorf("field `mounts[%d].location` refers to a non-directory path: %q: %w", i, f.Location, err)
+			errs.errorf(field+".location", f.Location, "refers to a non-directory path: %q", f.Location)
 		}
 
 		switch *f.MountPoint {
 		case "/", "/bin", "/dev", "/etc", "/home", "/opt", "/sbin", "/tmp", "/usr", "/var":
-			return fmt.Errorf("field `mounts[%d].mountPoint` must not be a system path such as /etc or /usr", i)
+			errs.errorf(field+".mountPoint", *f.MountPoint, "must not be a system path such as /etc or /usr")
 		// home directory defined in "cidata.iso:/user-data"
 		case *y.User.Home:
-			return fmt.Errorf("field `mounts[%d].mountPoint` is the reserved internal home directory %q", i, *y.User.Home)
+			errs.errorf(field+".mountPoint", *f.MountPoint, "is the reserved internal home directory %q", *y.User.Home)
 		}
 
 		if _, err := units.RAMInBytes(*f.NineP.Msize); err != nil {
-			return fmt.Errorf("field `msize` has an invalid value: %w", err)
+			errs.errorf(field+".9p.msize", *f.NineP.Msize, "has an invalid value: %w", err)
 		}
 	}
 
 	if *y.SSH.LocalPort != 0 {
-		if err := validatePort("ssh.localPort", *y.SSH.LocalPort); err != nil {
-			return err
+		errs.add(validatePort("ssh.localPort", *y.SSH.LocalPort))
+	}
+	if *y.SSH.KeepAlive.Interval < 0 {
+		errs.errorf("ssh.keepAlive.interval", *y.SSH.KeepAlive.Interval, "must not be negative, got %d", *y.SSH.KeepAlive.Interval)
+	}
+	if *y.SSH.KeepAlive.CountMax < 1 {
+		errs.errorf("ssh.keepAlive.countMax", *y.SSH.KeepAlive.CountMax, "must be a positive integer, got %d", *y.SSH.KeepAlive.CountMax)
+	}
+	for i, key := range y.SSH.AdditionalAuthorizedKeys {
+		if err := sshutil.ValidateAuthorizedKey(key); err != nil {
+			errs.errorf(fmt.Sprintf("ssh.additionalAuthorizedKeys[%d]", i), key, "is invalid: %w", err)
 		}
 	}
 
 	switch *y.MountType {
 	case REVSSHFS, NINEP, VIRTIOFS, WSLMount:
 	default:
-		return fmt.Errorf("field `mountType` must be %q or %q or %q, or %q, got %q", REVSSHFS, NINEP, VIRTIOFS, WSLMount, *y.MountType)
+		errs.errorf("mountType", *y.MountType, "must be %q or %q or %q, or %q, got %q", REVSSHFS, NINEP, VIRTIOFS, WSLMount, *y.MountType)
 	}
 
-	for _, f := range y.MountTypesUnsupported {
-		if f == *y.MountType {
-			return fmt.Errorf("field `mountType` must not be one of %v (`mountTypesUnsupported`), got %q", y.MountTypesUnsupported, *y.MountType)
-		}
+	if slices.Contains(y.MountTypesUnsupported, *y.MountType) {
+		errs.errorf("mountType", *y.MountType, "must not be one of %v (`mountTypesUnsupported`), got %q", y.MountTypesUnsupported, *y.MountType)
 	}
 
 	if warn && runtime.GOOS != "linux" {
@@ -203,28 +346,51 @@ func Validate(y *LimaYAML, warn bool) error {
 	// y.Firmware.LegacyBIOS is ignored for aarch64, but not a fatal error.
 
 	for i, p := range y.Provision {
+		field := fmt.Sprintf("provision[%d]", i)
 		switch p.Mode {
 		case ProvisionModeSystem, ProvisionModeUser, ProvisionModeBoot:
 			if p.SkipDefaultDependencyResolution != nil {
-				return fmt.Errorf("field `provision[%d].mode` cannot set skipDefaultDependencyResolution, only valid on scripts of type %q",
-					i, ProvisionModeDependency)
+				errs.errorf(field+".mode", p.Mode, "cannot set skipDefaultDependencyResolution, only valid on scripts of type %q",
+					ProvisionModeDependency)
 			}
 		case ProvisionModeDependency:
 		case ProvisionModeAnsible:
 		default:
-			return fmt.Errorf("field `provision[%d].mode` must one of %q, %q, %q, %q, or %q",
-				i, ProvisionModeSystem, ProvisionModeUser, ProvisionModeBoot, ProvisionModeDependency, ProvisionModeAnsible)
+			errs.errorf(field+".mode", p.Mode, "must one of %q, %q, %q, %q, or %q",
+				ProvisionModeSystem, ProvisionModeUser, ProvisionModeBoot, ProvisionModeDependency, ProvisionModeAnsible)
 		}
 		if p.Playbook != "" {
 			if p.Mode != ProvisionModeAnsible {
-				return fmt.Errorf("field `provision[%d].mode must be %q if playbook is set", i, ProvisionModeAnsible)
+				errs.errorf(field+".mode", p.Mode, "must be %q if playbook is set", ProvisionModeAnsible)
 			}
 			if p.Script != "" {
-				return fmt.Errorf("field `provision[%d].script must be empty if playbook is set", i)
+				errs.errorf(field+".script", nil, "must be empty if playbook is set")
 			}
 			playbook := p.Playbook
 			if _, err := os.Stat(playbook); err != nil {
-				return fmt.Errorf("field `provision[%d].playbook` refers to an inaccessible path: %q: %w", i, playbook, err)
+				errs.errorf(field+".playbook", playbook, "refers to an inaccessible path: %q: %w", playbook, err)
+			}
+		}
+		if p.Interpreter != nil {
+			switch p.Mode {
+			case ProvisionModeSystem, ProvisionModeUser, ProvisionModeDependency:
+			default:
+				errs.errorf(field+".interpreter", *p.Interpreter, "can only be set on scripts of type %q, %q, or %q",
+					ProvisionModeSystem, ProvisionModeUser, ProvisionModeDependency)
+			}
+			if err := validateInterpreter(*p.Interpreter); err != nil {
+				errs.errorf(field+".interpreter", *p.Interpreter, "is invalid: %w", err)
+			}
+		}
+		if p.When != nil {
+			switch p.Mode {
+			case ProvisionModeSystem, ProvisionModeUser, ProvisionModeDependency:
+			default:
+				errs.errorf(field+".when", *p.When, "can only be set on scripts of type %q, %q, or %q",
+					ProvisionModeSystem, ProvisionModeUser, ProvisionModeDependency)
+			}
+			if _, err := provisioncond.Parse(*p.When); err != nil {
+				errs.errorf(field+".when", *p.When, "is invalid: %w", err)
 			}
 		}
 		if strings.Contains(p.Script, "LIMA_CIDATA") {
@@ -234,220 +400,299 @@ func Validate(y *LimaYAML, warn bool) error {
 	needsContainerdArchives := (y.Containerd.User != nil && *y.Containerd.User) || (y.Containerd.System != nil && *y.Containerd.System)
 	if needsContainerdArchives {
 		if len(y.Containerd.Archives) == 0 {
-			return errors.New("field `containerd.archives` must be provided")
+			errs.errorf("containerd.archives", nil, "must be provided")
 		}
 		for i, f := range y.Containerd.Archives {
-			if err := validateFileObject(f, fmt.Sprintf("containerd.archives[%d]", i)); err != nil {
-				return err
-			}
+			errs = append(errs, validateFileObject(f, fmt.Sprintf("containerd.archives[%d]", i))...)
 		}
 	}
 	for i, p := range y.Probes {
+		field := fmt.Sprintf("probes[%d]", i)
 		if !strings.HasPrefix(p.Script, "#!") {
-			return fmt.Errorf("field `probe[%d].script` must start with a '#!' line", i)
+			errs.errorf(field+".script", nil, "must start with a '#!' line")
 		}
 		switch p.Mode {
 		case ProbeModeReadiness:
 		default:
-			return fmt.Errorf("field `probe[%d].mode` can only be %q", i, ProbeModeReadiness)
+			errs.errorf(field+".mode", p.Mode, "can only be %q", ProbeModeReadiness)
 		}
 	}
 	for i, rule := range y.PortForwards {
 		field := fmt.Sprintf("portForwards[%d]", i)
 		if rule.GuestIPMustBeZero && !rule.GuestIP.Equal(net.IPv4zero) {
-			return fmt.Errorf("field `%s.guestIPMustBeZero` can only be true when field `%s.guestIP` is 0.0.0.0", field, field)
+			errs.errorf(field+".guestIPMustBeZero", rule.GuestIPMustBeZero, "can only be true when field `%s.guestIP` is 0.0.0.0", field)
 		}
 		if rule.GuestPort != 0 {
 			if rule.GuestSocket != "" {
-				return fmt.Errorf("field `%s.guestPort` must be 0 when field `%s.guestSocket` is set", field, field)
+				errs.errorf(field+".guestPort", rule.GuestPort, "must be 0 when field `%s.guestSocket` is set", field)
 			}
 			if rule.GuestPort != rule.GuestPortRange[0] {
-				return fmt.Errorf("field `%s.guestPort` must match field `%s.guestPortRange[0]`", field, field)
+				errs.errorf(field+".guestPort", rule.GuestPort, "must match field `%s.guestPortRange[0]`", field)
 			}
 			// redundant validation to make sure the error contains the correct field name
-			if err := validatePort(field+".guestPort", rule.GuestPort); err != nil {
-				return err
-			}
+			errs.add(validatePort(field+".guestPort", rule.GuestPort))
 		}
 		if rule.HostPort != 0 {
 			if rule.HostSocket != "" {
-				return fmt.Errorf("field `%s.hostPort` must be 0 when field `%s.hostSocket` is set", field, field)
+				errs.errorf(field+".hostPort", rule.HostPort, "must be 0 when field `%s.hostSocket` is set", field)
 			}
 			if rule.HostPort != rule.HostPortRange[0] {
-				return fmt.Errorf("field `%s.hostPort` must match field `%s.hostPortRange[0]`", field, field)
+				errs.errorf(field+".hostPort", rule.HostPort, "must match field `%s.hostPortRange[0]`", field)
 			}
 			// redundant validation to make sure the error contains the correct field name
-			if err := validatePort(field+".hostPort", rule.HostPort); err != nil {
-				return err
-			}
+			errs.add(validatePort(field+".hostPort", rule.HostPort))
 		}
 		for j := 0; j < 2; j++ {
-			if err := validatePort(fmt.Sprintf("%s.guestPortRange[%d]", field, j), rule.GuestPortRange[j]); err != nil {
-				return err
-			}
-			if err := validatePort(fmt.Sprintf("%s.hostPortRange[%d]", field, j), rule.HostPortRange[j]); err != nil {
-				return err
-			}
+			errs.add(validatePort(fmt.Sprintf("%s.guestPortRange[%d]", field, j), rule.GuestPortRange[j]))
+			errs.add(validatePort(fmt.Sprintf("%s.hostPortRange[%d]", field, j), rule.HostPortRange[j]))
 		}
 		if rule.GuestPortRange[0] > rule.GuestPortRange[1] {
-			return fmt.Errorf("field `%s.guestPortRange[1]` must be greater than or equal to field `%s.guestPortRange[0]`", field, field)
+			errs.errorf(field+".guestPortRange[1]", rule.GuestPortRange[1], "must be greater than or equal to field `%s.guestPortRange[0]`", field)
 		}
 		if rule.HostPortRange[0] > rule.HostPortRange[1] {
-			return fmt.Errorf("field `%s.hostPortRange[1]` must be greater than or equal to field `%s.hostPortRange[0]`", field, field)
+			errs.errorf(field+".hostPortRange[1]", rule.HostPortRange[1], "must be greater than or equal to field `%s.hostPortRange[0]`", field)
 		}
 		if rule.GuestPortRange[1]-rule.GuestPortRange[0] != rule.HostPortRange[1]-rule.HostPortRange[0] {
-			return fmt.Errorf("field `%s.hostPortRange` must specify the same number of ports as field `%s.guestPortRange`", field, field)
+			errs.errorf(field+".hostPortRange", rule.HostPortRange, "must specify the same number of ports as field `%s.guestPortRange`", field)
 		}
 		if rule.GuestSocket != "" {
 			if !path.IsAbs(rule.GuestSocket) {
-				return fmt.Errorf("field `%s.guestSocket` must be an absolute path, but is %q", field, rule.GuestSocket)
+				errs.errorf(field+".guestSocket", rule.GuestSocket, "must be an absolute path, but is %q", rule.GuestSocket)
 			}
 			if rule.HostSocket == "" && rule.HostPortRange[1]-rule.HostPortRange[0] > 0 {
-				return fmt.Errorf("field `%s.guestSocket` can only be mapped to a single port or socket. not a range", field)
+				errs.errorf(field+".guestSocket", rule.GuestSocket, "can only be mapped to a single port or socket. not a range")
 			}
 		}
 		if rule.HostSocket != "" {
 			if !filepath.IsAbs(rule.HostSocket) {
 				// should be unreachable because FillDefault() will prepend the instance directory to relative names
-				return fmt.Errorf("field `%s.hostSocket` must be an absolute path, but is %q", field, rule.HostSocket)
+				errs.errorf(field+".hostSocket", rule.HostSocket, "must be an absolute path, but is %q", rule.HostSocket)
 			}
 			if rule.GuestSocket == "" && rule.GuestPortRange[1]-rule.GuestPortRange[0] > 0 {
-				return fmt.Errorf("field `%s.hostSocket` can only be mapped from a single port or socket. not a range", field)
+				errs.errorf(field+".hostSocket", rule.HostSocket, "can only be mapped from a single port or socket. not a range")
 			}
 		}
 		if len(rule.HostSocket) >= osutil.UnixPathMax {
-			return fmt.Errorf("field `%s.hostSocket` must be less than UNIX_PATH_MAX=%d characters, but is %d",
-				field, osutil.UnixPathMax, len(rule.HostSocket))
+			errs.errorf(field+".hostSocket", rule.HostSocket, "must be less than UNIX_PATH_MAX=%d characters, but is %d",
+				osutil.UnixPathMax, len(rule.HostSocket))
 		}
 		switch rule.Proto {
 		case ProtoTCP, ProtoUDP, ProtoAny:
 		default:
-			return fmt.Errorf("field `%s.proto` must be %q, %q, or %q", field, ProtoTCP, ProtoUDP, ProtoAny)
+			errs.errorf(field+".proto", rule.Proto, "must be %q, %q, or %q", ProtoTCP, ProtoUDP, ProtoAny)
 		}
-		if rule.Reverse && rule.GuestSocket == "" {
-			return fmt.Errorf("field `%s.reverse` must be %t", field, false)
+		if rule.Reverse && (rule.GuestSocket == "" || rule.HostSocket == "") {
+			errs.errorf(field+".reverse", rule.Reverse, "must be %t", false)
 		}
-		if rule.Reverse && rule.HostSocket == "" {
-			return fmt.Errorf("field `%s.reverse` must be %t", field, false)
+		if rule.Static {
+			if rule.GuestSocket != "" || rule.HostSocket != "" {
+				errs.errorf(field+".static", rule.Static, "can only be true for ports, not for sockets")
+			} else if rule.GuestPortRange[0] != rule.GuestPortRange[1] {
+				errs.errorf(field+".static", rule.Static, "can only be true for a single port, not for a range")
+			}
+			if rule.Proto != ProtoTCP {
+				errs.errorf(field+".static", rule.Static, "can only be true when field `%s.proto` is %q", field, ProtoTCP)
+			}
+			if rule.Ignore {
+				errs.errorf(field+".static", rule.Static, "and field `%s.ignore` must not be true at the same time", field)
+			}
 		}
 		// Not validating that the various GuestPortRanges and HostPortRanges are not overlapping. Rules will be
 		// processed sequentially and the first matching rule for a guest port determines forwarding behavior.
 	}
-	for i, rule := range y.CopyToHost {
-		field := fmt.Sprintf("CopyToHost[%d]", i)
-		if rule.GuestFile != "" {
-			if !path.IsAbs(rule.GuestFile) {
-				return fmt.Errorf("field `%s.guest` must be an absolute path, but is %q", field, rule.GuestFile)
+	for i, rule := range y.SocketForwards {
+		field := fmt.Sprintf("socketForwards[%d]", i)
+		if !path.IsAbs(rule.GuestSocket) {
+			errs.errorf(field+".guestSocket", rule.GuestSocket, "must be an absolute path, but is %q", rule.GuestSocket)
+		}
+		if !filepath.IsAbs(rule.HostSocket) {
+			// should be unreachable for non-empty names because FillDefault() will prepend the instance directory to relative names
+			errs.errorf(field+".hostSocket", rule.HostSocket, "must be an absolute path, but is %q", rule.HostSocket)
+		}
+		if len(rule.HostSocket) >= osutil.UnixPathMax {
+			errs.errorf(field+".hostSocket", rule.HostSocket, "must be less than UNIX_PATH_MAX=%d characters, but is %d",
+				osutil.UnixPathMax, len(rule.HostSocket))
+		}
+	}
+	for i, f := range y.Files {
+		field := fmt.Sprintf("files[%d]", i)
+		if !path.IsAbs(f.Path) {
+			errs.errorf(field+".path", f.Path, "must be an absolute path, but is %q", f.Path)
+		}
+		if f.Content != nil && f.Source != nil {
+			errs.errorf(field+".content", nil, "and field `%s.source` are mutually exclusive", field)
+		}
+		if f.Owner != nil && !isValidOwner(*f.Owner) {
+			errs.errorf(field+".owner", *f.Owner, "must be \"USER\" or \"USER:GROUP\", got %q", *f.Owner)
+		}
+		if f.Permissions != nil {
+			if _, err := ParsePermissions(*f.Permissions); err != nil {
+				errs.errorf(field+".permissions", *f.Permissions, "is invalid: %w", err)
 			}
 		}
-		if rule.HostFile != "" {
-			if !filepath.IsAbs(rule.HostFile) {
-				return fmt.Errorf("field `%s.host` must be an absolute path, but is %q", field, rule.HostFile)
+	}
+	for i, unit := range y.SystemdUnits {
+		field := fmt.Sprintf("systemdUnits[%d]", i)
+		if err := validateSystemdUnitName(unit.Name); err != nil {
+			errs.errorf(field+".name", unit.Name, "is invalid: %w", err)
+		}
+		for j := range i {
+			if y.SystemdUnits[j].Name == unit.Name {
+				errs.errorf(field+".name", unit.Name, "must be unique, %q is also specified in field `systemdUnits[%d].name`", unit.Name, j)
 			}
 		}
+		if strings.TrimSpace(unit.Content) == "" {
+			errs.errorf(field+".content", unit.Content, "must not be empty")
+		}
+	}
+	for i, rule := range y.CopyToHost {
+		field := fmt.Sprintf("copyToHost[%d]", i)
+		if rule.GuestFile != "" && !path.IsAbs(rule.GuestFile) {
+			errs.errorf(field+".guest", rule.GuestFile, "must be an absolute path, but is %q", rule.GuestFile)
+		}
+		if rule.HostFile != "" && !filepath.IsAbs(rule.HostFile) {
+			errs.errorf(field+".host", rule.HostFile, "must be an absolute path, but is %q", rule.HostFile)
+		}
+	}
+	for i, tag := range y.Tags {
+		if err := ValidateTag(tag); err != nil {
+			errs.errorf(fmt.Sprintf("tags[%d]", i), tag, "is invalid: %w", err)
+		}
+	}
+	for i, hook := range y.HostHooks {
+		field := fmt.Sprintf("hostHooks[%d]", i)
+		if !slices.Contains(HostHookEvents, hook.Event) {
+			errs.errorf(field+".event", hook.Event, "must be %q or %q, got %q", HostHookEventPortOpened, HostHookEventPortClosed, hook.Event)
+		}
+		for j := 0; j < 2; j++ {
+			errs.add(validatePort(fmt.Sprintf("%s.guestPortRange[%d]", field, j), hook.GuestPortRange[j]))
+		}
+		if hook.GuestPortRange[0] > hook.GuestPortRange[1] {
+			errs.errorf(field+".guestPortRange[1]", hook.GuestPortRange[1], "must be greater than or equal to field `%s.guestPortRange[0]`", field)
+		}
+		switch hook.Proto {
+		case ProtoTCP, ProtoUDP, ProtoAny:
+		default:
+			errs.errorf(field+".proto", hook.Proto, "must be %q, %q, or %q", ProtoTCP, ProtoUDP, ProtoAny)
+		}
+		if len(hook.Command) == 0 || hook.Command[0] == "" {
+			errs.errorf(field+".command", hook.Command, "must not be empty")
+		}
+		if hook.Timeout != nil && *hook.Timeout <= 0 {
+			errs.errorf(field+".timeout", *hook.Timeout, "must be positive, got %d", *hook.Timeout)
+		}
 	}
 
 	if y.HostResolver.Enabled != nil && *y.HostResolver.Enabled && len(y.DNS) > 0 {
-		return errors.New("field `dns` must be empty when field `HostResolver.Enabled` is true")
+		errs.errorf("dns", y.DNS, "must be empty when field `hostResolver.enabled` is true")
 	}
 
-	if err := validateNetwork(y); err != nil {
-		return err
-	}
+	errs = append(errs, validateNetwork(y)...)
 	if warn {
 		warnExperimental(y)
+		if exposed := nonLoopbackPortForwards(y); len(exposed) > 0 {
+			logrus.Warnf("Port forwards %v are bound to non-loopback host addresses; the forwarded guest ports will be reachable from other hosts", exposed)
+		}
 	}
 
 	// Validate Param settings
 	// Names must start with a letter, followed by any number of letters, digits, or underscores
 	validParamName := regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9_]*$`)
 	for param, value := range y.Param {
+		field := "param." + param
 		if !validParamName.MatchString(param) {
-			return fmt.Errorf("param %q name does not match regex %q", param, validParamName.String())
+			errs.errorf(field, value, "name does not match regex %q", validParamName.String())
 		}
 		for _, r := range value {
 			if !unicode.IsPrint(r) && r != '\t' && r != ' ' {
-				return fmt.Errorf("param %q value contains unprintable character %q", param, r)
+				errs.errorf(field, value, "value contains unprintable character %q", r)
+				break
 			}
 		}
 	}
 
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// MinMTU and MaxMTU are the bounds of the `mtu` fields.
+const (
+	MinMTU = 576
+	MaxMTU = 9000
+)
+
+func validateMTU(mtu *uint32, field string) *FieldError {
+	if mtu != nil && (*mtu < MinMTU || *mtu > MaxMTU) {
+		return &FieldError{Field: field, Value: *mtu, Err: fmt.Errorf("must be in the range [%d, %d], got %d", MinMTU, MaxMTU, *mtu)}
+	}
 	return nil
 }
 
-func validateNetwork(y *LimaYAML) error {
+func validateNetwork(y *LimaYAML) ValidationErrors {
+	var errs ValidationErrors
+	errs.add(validateMTU(y.MTU, "mtu"))
 	interfaceName := make(map[string]int)
 	for i, nw := range y.Networks {
 		field := fmt.Sprintf("networks[%d]", i)
 		switch {
 		case nw.Lima != "":
-			nwCfg, err := networks.LoadConfig()
-			if err != nil {
-				return err
-			}
-			if nwCfg.Check(nw.Lima) != nil {
-				return fmt.Errorf("field `%s.lima` references network %q which is not defined in networks.yaml", field, nw.Lima)
-			}
-			usernet, err := nwCfg.Usernet(nw.Lima)
-			if err != nil {
-				return err
-			}
-			if !usernet && runtime.GOOS != "darwin" {
-				return fmt.Errorf("field `%s.lima` is only supported on macOS right now", field)
+			if nwCfg, err := networks.LoadConfig(); err != nil {
+				errs.errorf(field+".lima", nw.Lima, "cannot be validated: %w", err)
+			} else if nwCfg.Check(nw.Lima) != nil {
+				errs.errorf(field+".lima", nw.Lima, "references network %q which is not defined in networks.yaml", nw.Lima)
+			} else if usernet, err := nwCfg.Usernet(nw.Lima); err != nil {
+				errs.errorf(field+".lima", nw.Lima, "cannot be validated: %w", err)
+			} else if !usernet && runtime.GOOS != "darwin" {
+				errs.errorf(field+".lima", nw.Lima, "is only supported on macOS right now")
 			}
 			if nw.Socket != "" {
-				return fmt.Errorf("field `%s.lima` and field `%s.socket` are mutually exclusive", field, field)
+				errs.errorf(field+".lima", nw.Lima, "and field `%s.socket` are mutually exclusive", field)
 			}
 			if nw.VZNAT != nil && *nw.VZNAT {
-				return fmt.Errorf("field `%s.lima` and field `%s.vzNAT` are mutually exclusive", field, field)
+				errs.errorf(field+".lima", nw.Lima, "and field `%s.vzNAT` are mutually exclusive", field)
 			}
 		case nw.Socket != "":
 			if nw.VZNAT != nil && *nw.VZNAT {
-				return fmt.Errorf("field `%s.socket` and field `%s.vzNAT` are mutually exclusive", field, field)
+				errs.errorf(field+".socket", nw.Socket, "and field `%s.vzNAT` are mutually exclusive", field)
 			}
 			if fi, err := os.Stat(nw.Socket); err != nil && !errors.Is(err, os.ErrNotExist) {
-				return err
+				errs.errorf(field+".socket", nw.Socket, "refers to an inaccessible path: %w", err)
 			} else if err == nil && fi.Mode()&os.ModeSocket == 0 {
-				return fmt.Errorf("field `%s.socket` %q points to a non-socket file", field, nw.Socket)
+				errs.errorf(field+".socket", nw.Socket, "%q points to a non-socket file", nw.Socket)
 			}
 		case nw.VZNAT != nil && *nw.VZNAT:
 			if y.VMType == nil || *y.VMType != VZ {
-				return fmt.Errorf("field `%s.vzNAT` requires `vmType` to be %q", field, VZ)
-			}
-			if nw.Lima != "" {
-				return fmt.Errorf("field `%s.vzNAT` and field `%s.lima` are mutually exclusive", field, field)
-			}
-			if nw.Socket != "" {
-				return fmt.Errorf("field `%s.vzNAT` and field `%s.socket` are mutually exclusive", field, field)
+				errs.errorf(field+".vzNAT", *nw.VZNAT, "requires `vmType` to be %q", VZ)
 			}
 		default:
-			return fmt.Errorf("field `%s.lima` or  field `%s.socket must be set", field, field)
+			errs.errorf(field+".lima", nil, "or field `%s.socket` must be set", field)
 		}
 		if nw.MACAddress != "" {
-			hw, err := net.ParseMAC(nw.MACAddress)
-			if err != nil {
-				return fmt.Errorf("field `vmnet.mac` invalid: %w", err)
-			}
-			if len(hw) != 6 {
-				return fmt.Errorf("field `%s.macAddress` must be a 48 bit (6 bytes) MAC address; actual length of %q is %d bytes", field, nw.MACAddress, len(hw))
+			if hw, err := net.ParseMAC(nw.MACAddress); err != nil {
+				errs.errorf(field+".macAddress", nw.MACAddress, "is invalid: %w", err)
+			} else if len(hw) != 6 {
+				errs.errorf(field+".macAddress", nw.MACAddress, "must be a 48 bit (6 bytes) MAC address; actual length of %q is %d bytes", nw.MACAddress, len(hw))
 			}
 		}
+		errs.add(validateMTU(nw.MTU, field+".mtu"))
 		// FillDefault() will make sure that nw.Interface is not the empty string
 		if len(nw.Interface) >= 16 {
-			return fmt.Errorf("field `%s.interface` must be less than 16 bytes, but is %d bytes: %q", field, len(nw.Interface), nw.Interface)
+			errs.errorf(field+".interface", nw.Interface, "must be less than 16 bytes, but is %d bytes: %q", len(nw.Interface), nw.Interface)
 		}
 		if strings.ContainsAny(nw.Interface, " \t\n/") {
-			return fmt.Errorf("field `%s.interface` must not contain whitespace or slashes", field)
+			errs.errorf(field+".interface", nw.Interface, "must not contain whitespace or slashes")
 		}
 		if nw.Interface == networks.SlirpNICName {
-			return fmt.Errorf("field `%s.interface` must not be set to %q because it is reserved for slirp", field, networks.SlirpNICName)
+			errs.errorf(field+".interface", nw.Interface, "must not be set to %q because it is reserved for slirp", networks.SlirpNICName)
 		}
 		if prev, ok := interfaceName[nw.Interface]; ok {
-			return fmt.Errorf("field `%s.interface` value %q has already been used by field `networks[%d].interface`", field, nw.Interface, prev)
+			errs.errorf(field+".interface", nw.Interface, "value %q has already been used by field `networks[%d].interface`", nw.Interface, prev)
 		}
 		interfaceName[nw.Interface] = i
 	}
-	return nil
+	return errs
 }
 
 // ValidateParamIsUsed checks if the keys in the `param` field are used in any script, probe, copyToHost, or portForward.
@@ -477,12 +722,24 @@ func ValidateParamIsUsed(y *LimaYAML) error {
 				break
 			}
 		}
+		for _, p := range y.HostHooks {
+			if slices.ContainsFunc(p.Command, re.MatchString) {
+				keyIsUsed = true
+				break
+			}
+		}
 		for _, p := range y.PortForwards {
 			if re.MatchString(p.GuestSocket) || re.MatchString(p.HostSocket) {
 				keyIsUsed = true
 				break
 			}
 		}
+		for _, p := range y.SocketForwards {
+			if re.MatchString(p.GuestSocket) || re.MatchString(p.HostSocket) {
+				keyIsUsed = true
+				break
+			}
+		}
 		for _, p := range y.Mounts {
 			if re.MatchString(p.Location) {
 				keyIsUsed = true
@@ -500,20 +757,155 @@ func ValidateParamIsUsed(y *LimaYAML) error {
 	return nil
 }
 
-func validatePort(field string, port int) error {
+func validatePort(field string, port int) *FieldError {
+	var err error
 	switch {
 	case port < 0:
-		return fmt.Errorf("field `%s` must be > 0", field)
+		err = errors.New("must be > 0")
 	case port == 0:
-		return fmt.Errorf("field `%s` must be set", field)
+		err = errors.New("must be set")
 	case port == 22:
-		return fmt.Errorf("field `%s` must not be 22", field)
+		err = errors.New("must not be 22")
 	case port > 65535:
-		return fmt.Errorf("field `%s` must be < 65536", field)
+		err = errors.New("must be < 65536")
+	default:
+		return nil
+	}
+	return &FieldError{Field: field, Value: port, Err: err}
+}
+
+// ParsePermissions parses an octal permission string like "0644" or "755".
+func ParsePermissions(s string) (os.FileMode, error) {
+	if len(s) < 3 || len(s) > 4 {
+		return 0, fmt.Errorf("permissions must be 3 or 4 octal digits, got %q", s)
+	}
+	perm, err := strconv.ParseUint(s, 8, 32)
+	if err != nil {
+		return 0, fmt.Errorf("permissions must be 3 or 4 octal digits, got %q", s)
+	}
+	mode := os.FileMode(perm & 0o777)
+	if perm&0o4000 != 0 {
+		mode |= os.ModeSetuid
+	}
+	if perm&0o2000 != 0 {
+		mode |= os.ModeSetgid
+	}
+	if perm&0o1000 != 0 {
+		mode |= os.ModeSticky
+	}
+	return mode, nil
+}
+
+var tagRegexp = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// ValidateTag validates the tag of an instance, e.g., "team-a".
+// A tag consists of lowercase alphanumeric characters and "._-", and starts with an alphanumeric character.
+func ValidateTag(tag string) error {
+	if len(tag) > 63 {
+		return fmt.Errorf("must not be longer than 63 bytes, got %d bytes", len(tag))
+	}
+	if !tagRegexp.MatchString(tag) {
+		return fmt.Errorf("must consist of lowercase alphanumeric characters and \"._-\", starting with an alphanumeric character, got %q", tag)
+	}
+	return nil
+}
+
+// maxISO9660LabelLength is the length of the volume identifier of ISO9660.
+const maxISO9660LabelLength = 32
+
+// cidataVolumeLabelRegexp is the portable subset of the characters allowed in the labels of both ISO9660 and VFAT.
+var cidataVolumeLabelRegexp = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// ValidateCIDataVolumeLabel validates the volume label of the cidata ISO9660 image, or the VFAT image when vfat is true.
+func ValidateCIDataVolumeLabel(label string, vfat bool) error {
+	if label == "" {
+		return errors.New("must not be empty")
+	}
+	maxLength := maxISO9660LabelLength
+	if vfat {
+		maxLength = fatutil.MaxLabelLength
+	}
+	if len(label) > maxLength {
+		return fmt.Errorf("must not be longer than %d characters, got %q", maxLength, label)
+	}
+	if !cidataVolumeLabelRegexp.MatchString(label) {
+		return fmt.Errorf("must only contain alphanumeric characters, '_', and '-', got %q", label)
+	}
+	return nil
+}
+
+// isValidOwner returns true for "USER" and "USER:GROUP".
+func isValidOwner(owner string) bool {
+	user, group, hasGroup := strings.Cut(owner, ":")
+	if !osutil.IsValidUsername(user) {
+		return false
+	}
+	return !hasGroup || osutil.IsValidUsername(group)
+}
+
+// cryptHashRegexp matches the password hashes of crypt(3), such as "$6$salt$hash" (SHA-512) and "$y$params$salt$hash" (yescrypt).
+var cryptHashRegexp = regexp.MustCompile(`^\$(1|2[abxy]|5|6|7|y|gy|sha1|md5)\$[^\s:]+$`)
+
+func isCryptHash(s string) bool {
+	return cryptHashRegexp.MatchString(s)
+}
+
+// maxInterpreterLen is the maximum length of the interpreter, so that the hash-bang line
+// fits in the 128-byte buffer of the Linux kernel prior to 5.1.
+const maxInterpreterLen = 125
+
+// validateInterpreter validates the interpreter of a provisioning script, which is used as the hash-bang line.
+func validateInterpreter(interpreter string) error {
+	if strings.ContainsAny(interpreter, "\r\n\x00") {
+		return errors.New("must be a single line")
+	}
+	if len(interpreter) > maxInterpreterLen {
+		return fmt.Errorf("must not be longer than %d bytes, got %d bytes", maxInterpreterLen, len(interpreter))
+	}
+	path, _, _ := strings.Cut(interpreter, " ")
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("must start with an absolute path (e.g., \"/usr/bin/env python3\"), got %q", interpreter)
 	}
 	return nil
 }
 
+// systemdUnitNameRegexp matches the characters allowed in the names of the systemd units.
+var systemdUnitNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9:_.@-]+$`)
+
+// validateSystemdUnitName validates the name of a systemd unit, e.g., "foo.service".
+func validateSystemdUnitName(name string) error {
+	if !slices.ContainsFunc(SystemdUnitSuffixes, func(suffix string) bool {
+		return strings.HasSuffix(name, suffix) && len(name) > len(suffix)
+	}) {
+		return fmt.Errorf("must end with one of %v, got %q", SystemdUnitSuffixes, name)
+	}
+	// systemd limits the unit names to 255 characters
+	if len(name) > 255 {
+		return fmt.Errorf("must not be longer than 255 bytes, got %d bytes", len(name))
+	}
+	if !systemdUnitNameRegexp.MatchString(name) {
+		return fmt.Errorf("must consist of alphanumeric characters and \":_.@-\", got %q", name)
+	}
+	return nil
+}
+
+// nonLoopbackPortForwards returns the fields of the port forwarding rules that listen on a non-loopback host address.
+// Socket forwards and ignored rules are not included.
+// The implicit rule that the host agent appends for all the remaining ports is reported as "portForwardsBindAddress".
+func nonLoopbackPortForwards(y *LimaYAML) []string {
+	var fields []string
+	for i, rule := range y.PortForwards {
+		if rule.Ignore || rule.HostSocket != "" || rule.HostIP == nil || rule.HostIP.IsLoopback() {
+			continue
+		}
+		fields = append(fields, fmt.Sprintf("portForwards[%d]", i))
+	}
+	if y.PortForwardsBindAddress != nil && !y.PortForwardsBindAddress.IsLoopback() {
+		fields = append(fields, "portForwardsBindAddress")
+	}
+	return fields
+}
+
 func warnExperimental(y *LimaYAML) {
 	if *y.MountType == VIRTIOFS && runtime.GOOS == "linux" {
 		logrus.Warn("`mountType: virtiofs` on Linux is experimental")
@@ -1,8 +1,13 @@
 package limayaml
 
 import (
+	"errors"
+	"net"
 	"os"
+	"path/filepath"
 	"runtime"
+	"slices"
+	"strings"
 	"testing"
 
 	"gotest.tools/v3/assert"
@@ -15,6 +20,35 @@ func TestValidateEmpty(t *testing.T) {
 	assert.Error(t, err, "field `images` must be set")
 }
 
+func TestValidateFieldErrors(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	user := `user: {uid: 1000}`
+
+	invalidMode := `provision: [{"mode": "user", "script": "true"}, {"mode": "foo", "script": "true"}]`
+	y, err := Load([]byte(invalidMode+"\n"+images+"\n"+user), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	var errs ValidationErrors
+	assert.Assert(t, errors.As(err, &errs))
+	assert.Equal(t, len(errs), 1)
+	assert.Equal(t, errs[0].Field, "provision[1].mode")
+	assert.Equal(t, errs[0].Value, ProvisionMode("foo"))
+
+	invalidArch := `arch: "foo"`
+	y, err = Load([]byte(invalidArch+"\n"+invalidMode+"\n"+images+"\n"+user), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Assert(t, errors.As(err, &errs))
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field
+	}
+	assert.Assert(t, slices.Contains(fields, "arch"), "fields: %v", fields)
+	assert.Assert(t, slices.Contains(fields, "provision[1].mode"), "fields: %v", fields)
+	assert.ErrorContains(t, err, "field `arch` must be")
+	assert.ErrorContains(t, err, "field `provision[1].mode` must one of")
+}
+
 // Note: can't embed symbolic links, use "os"
 
 func TestValidateDefault(t *testing.T) {
@@ -45,7 +79,48 @@ func TestValidateProbes(t *testing.T) {
 	assert.NilError(t, err)
 
 	err = Validate(y, false)
-	assert.Error(t, err, "field `probe[0].script` must start with a '#!' line")
+	assert.Error(t, err, "field `probes[0].script` must start with a '#!' line")
+}
+
+func TestValidateHostHooks(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	validHook := `hostHooks: [{"event": "portOpened", "guestPortRange": [8000, 8999], "command": ["/bin/true"]}]`
+	y, err := Load([]byte(validHook+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+
+	err = Validate(y, false)
+	assert.NilError(t, err)
+	assert.Equal(t, y.HostHooks[0].Proto, ProtoAny)
+	assert.Equal(t, *y.HostHooks[0].Timeout, 60)
+
+	for invalidHook, expected := range map[string]string{
+		`hostHooks: [{"event": "portOpen", "command": ["/bin/true"]}]`:                             "field `hostHooks[0].event` must be \"portOpened\" or \"portClosed\", got \"portOpen\"",
+		`hostHooks: [{"event": "portClosed"}]`:                                                     "field `hostHooks[0].command` must not be empty",
+		`hostHooks: [{"event": "portClosed", "command": ["/bin/true"], "proto": "sctp"}]`:          "field `hostHooks[0].proto` must be \"tcp\", \"udp\", or \"any\"",
+		`hostHooks: [{"event": "portClosed", "command": ["/bin/true"], "timeout": 0}]`:             "field `hostHooks[0].timeout` must be positive, got 0",
+		`hostHooks: [{"event": "portClosed", "command": ["/bin/true"], "guestPortRange": [2, 1]}]`: "field `hostHooks[0].guestPortRange[1]` must be greater than or equal to field `hostHooks[0].guestPortRange[0]`",
+	} {
+		y, err = Load([]byte(invalidHook+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+
+		err = Validate(y, false)
+		assert.Error(t, err, expected)
+	}
+}
+
+func TestValidateTag(t *testing.T) {
+	for _, tag := range []string{"ci", "team-a", "v1.0", "foo_bar", "0", strings.Repeat("a", 63)} {
+		assert.NilError(t, ValidateTag(tag), tag)
+	}
+	for _, tag := range []string{"", "Team-A", "-ci", ".ci", "team a", "team/a", "ci:1", strings.Repeat("a", 64)} {
+		assert.Assert(t, ValidateTag(tag) != nil, tag)
+	}
+
+	images := `images: [{"location": "/"}]`
+	y, err := Load([]byte(`tags: ["ci", "Team-A"]`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `tags[1]` is invalid: must consist of lowercase alphanumeric characters")
 }
 
 func TestValidateAdditionalDisks(t *testing.T) {
@@ -70,7 +145,28 @@ additionalDisks:
 	assert.NilError(t, err)
 
 	err = Validate(y, false)
-	assert.Error(t, err, "field `additionalDisks[0].name is invalid`: identifier must not be empty: invalid argument")
+	assert.Error(t, err, "field `additionalDisks[0].name` is invalid: identifier must not be empty: invalid argument")
+
+	readOnlyDisks := `
+additionalDisks:
+  - name: "dataset"
+    readOnly: true
+`
+	y, err = Load([]byte(readOnlyDisks+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+
+	readOnlyFormatDisks := `
+additionalDisks:
+  - name: "dataset"
+    readOnly: true
+    format: true
+`
+	y, err = Load([]byte(readOnlyFormatDisks+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+
+	err = Validate(y, false)
+	assert.Error(t, err, "field `additionalDisks[0].format` must not be true for a read-only disk")
 }
 
 func TestValidateParamName(t *testing.T) {
@@ -186,3 +282,399 @@ func TestValidateParamIsUsed(t *testing.T) {
 		assert.Error(t, err, "field `param` key \"rootFul\" is not used in any provision, probe, copyToHost, or portForward")
 	}
 }
+
+func TestValidateDisk(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`disk: 50GiB`,
+		`disk: {size: 50GiB, autoTrim: true}`,
+		`disk: {autoTrim: false}`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	y, err := Load([]byte(`disk: {size: 50GB-ish}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `disk.size` has an invalid value")
+}
+
+func TestValidateMTU(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`mtu: 1400`,
+		`mtu: 576`,
+		`mtu: 9000`,
+		`networks: [{"socket": "/nonexistent", "mtu": 1280}]`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	y, err := Load([]byte(`mtu: 575`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `mtu` must be in the range [576, 9000], got 575")
+
+	y, err = Load([]byte(`networks: [{"socket": "/nonexistent", "mtu": 9001}]`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `networks[0].mtu` must be in the range [576, 9000], got 9001")
+}
+
+func TestValidateUser(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`user: {"name": "foo", "uid": 1000}`,
+		`user: {"name": "_foo-bar1", "uid": 501}`,
+		`user: {"groups": ["docker", "kvm"]}`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	y, err := Load([]byte(`user: {"name": "Foo"}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `user.name` must be a valid Linux username, got \"Foo\"")
+
+	y, err = Load([]byte(`user: {"uid": 0}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `user.uid` must be a positive integer")
+
+	y, err = Load([]byte(`user: {"groups": ["docker", "kvm group"]}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `user.groups[1]` must be a valid Linux group name, got \"kvm group\"")
+}
+
+func TestValidateUserPassword(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`user: {"password": ""}`,
+		`user: {"password": "$6$rounds=4096$salt$hash"}`,
+		`user: {"password": "$y$j9T$salt$hash", "lockPassword": true}`,
+		`user: {"password": "$1$salt$hash"}`,
+		`user: {"password": "plain", "insecurePlainTextPassword": true}`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	for _, invalid := range []string{
+		`user: {"password": "plain"}`,
+		`user: {"password": "plain", "insecurePlainTextPassword": false}`,
+		`user: {"password": "$6$salt with space$hash"}`,
+		`user: {"password": "$9$salt$hash"}`,
+	} {
+		y, err := Load([]byte(invalid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.ErrorContains(t, err, "field `user.password` must be a password hash", invalid)
+	}
+}
+
+func TestValidateProvisionInterpreter(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`provision: [{"script": "print(1)", "interpreter": "/usr/bin/env python3"}]`,
+		`provision: [{"mode": "user", "script": "echo", "interpreter": "/bin/bash"}]`,
+		`provision: [{"mode": "dependency", "script": "echo", "interpreter": "/bin/bash -eux"}]`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	for invalid, expected := range map[string]string{
+		`provision: [{"script": "echo", "interpreter": "bash"}]`:                    "field `provision[0].interpreter` is invalid: must start with an absolute path",
+		`provision: [{"script": "echo", "interpreter": "/bin/bash\n/bin/sh"}]`:      "field `provision[0].interpreter` is invalid: must be a single line",
+		`provision: [{"mode": "boot", "script": "echo", "interpreter": "/bin/sh"}]`: "field `provision[0].interpreter` can only be set on scripts of type",
+	} {
+		y, err := Load([]byte(invalid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.ErrorContains(t, err, expected, invalid)
+	}
+}
+
+func TestValidateProvisionWhen(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`provision: [{"script": "echo", "when": "arch == aarch64"}]`,
+		`provision: [{"mode": "user", "script": "echo", "when": "os == ubuntu || os == debian"}]`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	for invalid, expected := range map[string]string{
+		`provision: [{"script": "echo", "when": "arch = aarch64"}]`:               "field `provision[0].when` is invalid: unexpected character",
+		`provision: [{"script": "echo", "when": "kernel == linux"}]`:              "field `provision[0].when` is invalid: unknown fact",
+		`provision: [{"mode": "boot", "script": "echo", "when": "os == alpine"}]`: "field `provision[0].when` can only be set on scripts of type",
+	} {
+		y, err := Load([]byte(invalid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.ErrorContains(t, err, expected, invalid)
+	}
+}
+
+func TestValidateSystemdUnitName(t *testing.T) {
+	for _, valid := range []string{"foo.service", "foo-bar_baz.timer", "foo@.service", "foo@bar.service", "a:b.service"} {
+		assert.NilError(t, validateSystemdUnitName(valid), valid)
+	}
+	for invalid, expected := range map[string]string{
+		"foo":                                 "must end with one of",
+		".service":                            "must end with one of",
+		"foo.socket":                          "must end with one of",
+		"foo.service.bak":                     "must end with one of",
+		"../foo.service":                      "must consist of",
+		"foo bar.service":                     "must consist of",
+		strings.Repeat("a", 256) + ".service": "must not be longer than 255 bytes",
+	} {
+		assert.ErrorContains(t, validateSystemdUnitName(invalid), expected, invalid)
+	}
+}
+
+func TestValidateSystemdUnits(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for invalid, expected := range map[string]string{
+		`systemdUnits: [{"name": "foo", "content": "[Service]"}]`:                                                          "field `systemdUnits[0].name` is invalid: must end with one of",
+		`systemdUnits: [{"name": "foo.service", "content": " "}]`:                                                          "field `systemdUnits[0].content` must not be empty",
+		`systemdUnits: [{"name": "foo.service", "content": "[Service]"}, {"name": "foo.service", "content": "[Service]"}]`: "field `systemdUnits[1].name` must be unique",
+	} {
+		y, err := Load([]byte(invalid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.ErrorContains(t, err, expected, invalid)
+	}
+}
+
+func TestValidateTimeZoneAndLocale(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`timezone: "Asia/Tokyo"`,
+		`timezone: "America/Argentina/Buenos_Aires"`,
+		`timezone: "UTC"`,
+		`timezone: ""`,
+		`locale: "C.UTF-8"`,
+		`locale: "en_US.UTF-8"`,
+		`locale: "sr_RS@latin"`,
+		`locale: ""`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	y, err := Load([]byte(`timezone: "Mars/Olympus_Mons"`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `timezone` must be a name in the zoneinfo database, got \"Mars/Olympus_Mons\"")
+
+	y, err = Load([]byte(`timezone: "Local"`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `timezone` must be a name in the zoneinfo database")
+
+	y, err = Load([]byte(`timezone: "Asia/Tokyo; rm -rf /"`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `timezone` must be a name in the zoneinfo database")
+
+	y, err = Load([]byte(`locale: "en_US.UTF-8; rm -rf /"`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `locale` must be a locale name like \"en_US.UTF-8\", got \"en_US.UTF-8; rm -rf /\"")
+}
+
+func TestPortForwardsBindAddress(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	portForwards := `portForwards: [{"guestPort": 80}, {"guestPort": 443, "hostIP": "127.0.0.1"}, {"guestSocket": "/run/foo.sock", "hostSocket": "foo.sock"}]`
+	// the relative hostSocket is placed in the instance directory, which has to be absolute
+	filePath := filepath.Join(t.TempDir(), "lima.yaml")
+
+	y, err := Load([]byte(portForwards+"\n"+images), filePath)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+	assert.Assert(t, y.PortForwardsBindAddress.Equal(IPv4loopback1))
+	assert.Assert(t, y.PortForwards[0].HostIP.Equal(IPv4loopback1))
+	assert.Equal(t, len(nonLoopbackPortForwards(y)), 0)
+
+	y, err = Load([]byte(`portForwardsBindAddress: "0.0.0.0"`+"\n"+portForwards+"\n"+images), filePath)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+	// the bind address only applies to the rules without hostIP
+	assert.Assert(t, y.PortForwards[0].HostIP.Equal(net.IPv4zero))
+	assert.Assert(t, y.PortForwards[1].HostIP.Equal(IPv4loopback1))
+	// the implicit rule for the remaining ports is bound to the address too
+	assert.DeepEqual(t, nonLoopbackPortForwards(y), []string{"portForwards[0]", "portForwardsBindAddress"})
+
+	y, err = Load([]byte(`portForwardsBindAddress: "0.0.0.0"`+"\n"+images), filePath)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+	assert.DeepEqual(t, nonLoopbackPortForwards(y), []string{"portForwardsBindAddress"})
+}
+
+func TestValidateStaticPortForwards(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	y, err := Load([]byte(`portForwards: [{"guestPort": 5432, "static": true}]`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+
+	testCases := map[string]string{
+		`{"guestPortRange": [4000, 4999], "static": true}`:                                "field `portForwards[0].static` can only be true for a single port, not for a range",
+		`{"guestSocket": "/run/foo.sock", "hostSocket": "/tmp/foo.sock", "static": true}`: "field `portForwards[0].static` can only be true for ports, not for sockets",
+		`{"guestPort": 53, "proto": "udp", "static": true}`:                               "field `portForwards[0].static` can only be true when field `portForwards[0].proto` is \"tcp\"",
+		`{"guestPort": 5432, "ignore": true, "static": true}`:                             "field `portForwards[0].static` and field `portForwards[0].ignore` must not be true at the same time",
+	}
+	for rule, expected := range testCases {
+		y, err := Load([]byte(`portForwards: [`+rule+`]`+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		assert.Error(t, Validate(y, false), expected, rule)
+	}
+}
+
+func TestValidateSocketForwards(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	// the relative hostSocket is placed in the instance directory, which has to be absolute
+	filePath := filepath.Join(t.TempDir(), "lima.yaml")
+	y, err := Load([]byte(`socketForwards: [{"guestSocket": "/run/user/{{.UID}}/docker.sock", "hostSocket": "docker.sock"}]`+"\n"+images), filePath)
+	assert.NilError(t, err)
+	assert.NilError(t, Validate(y, false))
+	assert.Equal(t, y.SocketForwards[0].HostSocket, filepath.Join(filepath.Dir(filePath), "sock", "docker.sock"))
+
+	y, err = Load([]byte(`socketForwards: [{"guestSocket": "docker.sock", "hostSocket": "docker.sock"}]`+"\n"+images), filePath)
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.Error(t, err, "field `socketForwards[0].guestSocket` must be an absolute path, but is \"docker.sock\"")
+}
+
+func TestParsePermissions(t *testing.T) {
+	for s, expected := range map[string]os.FileMode{
+		"644":  0o644,
+		"0600": 0o600,
+		"0755": 0o755,
+		"1777": 0o777 | os.ModeSticky,
+		"4755": 0o755 | os.ModeSetuid,
+		"2750": 0o750 | os.ModeSetgid,
+	} {
+		mode, err := ParsePermissions(s)
+		assert.NilError(t, err, s)
+		assert.Equal(t, mode, expected, s)
+	}
+	for _, s := range []string{"", "64", "0o644", "0888", "rw-r--r--", "00644"} {
+		_, err := ParsePermissions(s)
+		assert.ErrorContains(t, err, "permissions must be 3 or 4 octal digits", s)
+	}
+}
+
+func TestValidateFiles(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`files: [{"path": "/etc/foo.conf", "content": "foo", "owner": "root:root", "permissions": "0600"}]`,
+		`files: [{"path": "/etc/foo.conf", "source": "/tmp/foo.conf", "owner": "foo"}]`,
+		`files: [{"path": "/etc/empty"}]`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.NilError(t, err, valid)
+	}
+
+	for invalid, expected := range map[string]string{
+		`files: [{"path": "/etc/foo.conf", "content": "foo", "source": "/tmp/foo.conf"}]`: "field `files[0].content` and field `files[0].source` are mutually exclusive",
+		`files: [{"path": "etc/foo.conf", "content": "foo"}]`:                             "field `files[0].path` must be an absolute path, but is \"etc/foo.conf\"",
+		`files: [{"path": "/etc/foo.conf", "permissions": "0o644"}]`:                      "field `files[0].permissions` is invalid: permissions must be 3 or 4 octal digits, got \"0o644\"",
+		`files: [{"path": "/etc/foo.conf", "owner": "root:"}]`:                            "field `files[0].owner` must be \"USER\" or \"USER:GROUP\", got \"root:\"",
+	} {
+		y, err := Load([]byte(invalid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		err = Validate(y, false)
+		assert.Error(t, err, expected, invalid)
+	}
+}
+
+func TestValidateGuestAgentAddress(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	valid := []string{
+		`guestAgent: {address: "tcp://127.0.0.1:2222"}`,
+		`guestAgent: {address: "unix:///tmp/ga.sock"}`,
+		`guestAgent: {address: ""}`,
+	}
+	if runtime.GOOS == "linux" {
+		valid = append(valid, `guestAgent: {address: "vsock://3:2222"}`+"\nvmType: qemu")
+	}
+	for _, v := range valid {
+		y, err := Load([]byte(v+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		assert.NilError(t, Validate(y, false), v)
+	}
+
+	y, err := Load([]byte(`guestAgent: {address: "vsock://foo:2222"}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `guestAgent.address` must be like")
+
+	y, err = Load([]byte(`guestAgent: {address: "vsock://3:2222"}`+"\nvmType: vz\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `guestAgent.address` must not use vsock, except for `vmType: qemu` on Linux hosts")
+
+	y, err = Load([]byte(`guestAgent: {address: "vsock://2:2222"}`+"\nvmType: qemu\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	if runtime.GOOS == "linux" {
+		assert.ErrorContains(t, err, "field `guestAgent.address` must have a CID greater than or equal to 3, got 2")
+	} else {
+		assert.ErrorContains(t, err, "field `guestAgent.address` must not use vsock")
+	}
+}
+
+func TestValidateCIDataVolumeLabel(t *testing.T) {
+	assert.NilError(t, ValidateCIDataVolumeLabel("cidata", false))
+	assert.NilError(t, ValidateCIDataVolumeLabel("cidata", true))
+	assert.NilError(t, ValidateCIDataVolumeLabel("CIDATA", true))
+	assert.NilError(t, ValidateCIDataVolumeLabel("config-2", false))
+
+	assert.ErrorContains(t, ValidateCIDataVolumeLabel("", false), "must not be empty")
+	assert.NilError(t, ValidateCIDataVolumeLabel("cidata-for-the-test-harness", false))
+	assert.ErrorContains(t, ValidateCIDataVolumeLabel("cidata-for-the-test-harness", true), "must not be longer than 11 characters")
+	assert.NilError(t, ValidateCIDataVolumeLabel("cidata-for-the-test-harness-0123", false))
+	assert.ErrorContains(t, ValidateCIDataVolumeLabel("cidata-for-the-test-harness-01234", false), "must not be longer than 32 characters")
+	assert.ErrorContains(t, ValidateCIDataVolumeLabel("ci data", false), "must only contain")
+	assert.ErrorContains(t, ValidateCIDataVolumeLabel("ci.data", true), "must only contain")
+}
+
+func TestValidateCIData(t *testing.T) {
+	images := `images: [{"location": "/"}]`
+	for _, valid := range []string{
+		`cidata: {volumeLabel: "cidata"}`,
+		`cidata: {volumeLabel: "CIDATA", vfat: true}`,
+		`cidata: {volumeLabel: "lima-cidata-for-iso"}`,
+	} {
+		y, err := Load([]byte(valid+"\n"+images), "lima.yaml")
+		assert.NilError(t, err)
+		assert.NilError(t, Validate(y, false), valid)
+	}
+
+	y, err := Load([]byte(`cidata: {volumeLabel: "lima-cidata-for-iso", vfat: true}`+"\n"+images), "lima.yaml")
+	assert.NilError(t, err)
+	err = Validate(y, false)
+	assert.ErrorContains(t, err, "field `cidata.volumeLabel` must not be longer than 11 characters")
+}
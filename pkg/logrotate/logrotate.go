@@ -0,0 +1,157 @@
+// Package logrotate provides a writer that rotates a log file by size.
+package logrotate
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Writer appends to the file at the path, and rotates the file when the size would exceed maxSize.
+// The rotated files are named "<path>.1" (the newest) to "<path>.<maxFiles>" (the oldest).
+//
+// Each Write is written to a single file, so lines written with a single Write are never split across files.
+type Writer struct {
+	path     string
+	maxSize  int64
+	maxFiles int
+
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	disabled bool // set when the rotation failed, e.g., because the file is locked on Windows
+}
+
+// New opens the file at the path for appending.
+func New(path string, maxSize int64, maxFiles int) (*Writer, error) {
+	if maxSize <= 0 {
+		return nil, fmt.Errorf("maxSize must be positive, got %d", maxSize)
+	}
+	if maxFiles < 1 {
+		return nil, fmt.Errorf("maxFiles must be positive, got %d", maxFiles)
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	st, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &Writer{
+		path:     path,
+		maxSize:  maxSize,
+		maxFiles: maxFiles,
+		f:        f,
+		size:     st.Size(),
+	}, nil
+}
+
+// RotatedPath returns the path of the i-th rotated file. The first one is the newest.
+func RotatedPath(path string, i int) string {
+	return path + "." + strconv.Itoa(i)
+}
+
+func (w *Writer) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.disabled && w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if err := w.rotate(); err != nil {
+			// Keep writing to the current file, as the writer is typically used for the logs themselves
+			w.disabled = true
+			fmt.Fprintf(w.f, "failed to rotate %q, the log rotation is disabled: %v\n", w.path, err)
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// rotate must be called with w.mu held.
+// The current file is renamed before being closed, so the file is kept in use on failure.
+func (w *Writer) rotate() error {
+	for i := w.maxFiles - 1; i >= 1; i-- {
+		if err := os.Rename(RotatedPath(w.path, i), RotatedPath(w.path, i+1)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	if err := os.Rename(w.path, RotatedPath(w.path, 1)); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := w.f.Close(); err != nil {
+		logrus.WithError(err).Debugf("failed to close the rotated file %q", RotatedPath(w.path, 1))
+	}
+	w.f = f
+	w.size = 0
+	return nil
+}
+
+// Sync commits the current file to the stable storage.
+func (w *Writer) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Sync()
+}
+
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.f.Close()
+}
+
+// RotatedFiles returns the paths of the existing rotated files of the path, from the oldest to the newest.
+// The number of the files is not limited to maxFiles of the current Writer, as it may have been changed.
+func RotatedFiles(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return nil, err
+	}
+	prefix := filepath.Base(path) + "."
+	var indices []int
+	for _, e := range entries {
+		suffix, ok := strings.CutPrefix(e.Name(), prefix)
+		if !ok || e.IsDir() {
+			continue
+		}
+		i, err := strconv.Atoi(suffix)
+		if err != nil || i < 1 || strconv.Itoa(i) != suffix {
+			// Skip the unrelated files, such as "<path>.bak", and the non-canonical numbers, such as "<path>.01"
+			continue
+		}
+		indices = append(indices, i)
+	}
+	slices.Sort(indices)
+	slices.Reverse(indices)
+	res := make([]string, 0, len(indices))
+	for _, i := range indices {
+		res = append(res, RotatedPath(path, i))
+	}
+	return res, nil
+}
+
+// Remove removes the file at the path and its rotated files.
+// Missing files are not treated as an error.
+func Remove(path string) error {
+	rotated, err := RotatedFiles(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	for _, f := range append(rotated, path) {
+		if err := os.RemoveAll(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
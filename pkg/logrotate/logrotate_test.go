@@ -0,0 +1,75 @@
+package logrotate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func readFile(t *testing.T, path string) string {
+	t.Helper()
+	b, err := os.ReadFile(path)
+	assert.NilError(t, err)
+	return string(b)
+}
+
+func TestWriter(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ha.stdout.log")
+	assert.NilError(t, os.WriteFile(path, []byte("0000\n"), 0o644))
+
+	_, err := New(path, 0, 1)
+	assert.ErrorContains(t, err, "maxSize must be positive")
+	_, err = New(path, 10, 0)
+	assert.ErrorContains(t, err, "maxFiles must be positive")
+
+	w, err := New(path, 10, 2)
+	assert.NilError(t, err)
+	for _, line := range []string{"1111\n", "2222\n", "3333\n", "4444\n", "5555\n", "6666\n", "7777\n"} {
+		_, err := w.Write([]byte(line))
+		assert.NilError(t, err)
+	}
+	assert.NilError(t, w.Close())
+
+	// "0000\n" and "1111\n" are rotated out
+	assert.Equal(t, readFile(t, path), "6666\n7777\n")
+	assert.Equal(t, readFile(t, RotatedPath(path, 1)), "4444\n5555\n")
+	assert.Equal(t, readFile(t, RotatedPath(path, 2)), "2222\n3333\n")
+	_, err = os.Stat(RotatedPath(path, 3))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	// a single write larger than maxSize is not split
+	w, err = New(path, 10, 2)
+	assert.NilError(t, err)
+	_, err = w.Write([]byte("0123456789abcdef\n"))
+	assert.NilError(t, err)
+	assert.NilError(t, w.Close())
+	assert.Equal(t, readFile(t, path), "0123456789abcdef\n")
+	assert.Equal(t, readFile(t, RotatedPath(path, 1)), "6666\n7777\n")
+}
+
+func TestRotatedFilesAndRemove(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "ha.stderr.log")
+	for _, name := range []string{"ha.stderr.log", "ha.stderr.log.1", "ha.stderr.log.2", "ha.stderr.log.10", "ha.stderr.log.bak", "ha.stderr.log.01", "ha.stdout.log.1"} {
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	rotated, err := RotatedFiles(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, rotated, []string{RotatedPath(path, 10), RotatedPath(path, 2), RotatedPath(path, 1)})
+
+	assert.NilError(t, Remove(path))
+	entries, err := os.ReadDir(dir)
+	assert.NilError(t, err)
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	assert.DeepEqual(t, names, []string{"ha.stderr.log.01", "ha.stderr.log.bak", "ha.stdout.log.1"})
+
+	// missing files are fine
+	assert.NilError(t, Remove(path))
+	assert.NilError(t, Remove(filepath.Join(dir, "nonexistent", "ha.stderr.log")))
+}
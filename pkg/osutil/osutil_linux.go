@@ -1,7 +1,10 @@
 package osutil
 
 import (
+	"bytes"
 	"io/fs"
+	"os"
+	"strconv"
 	"syscall"
 )
 
@@ -30,3 +33,12 @@ type Signal syscall.Signal
 func SysKill(pid int, sig Signal) error {
 	return syscall.Kill(pid, syscall.Signal(sig))
 }
+
+// ProcessCommandLine returns the command line of the process, with the arguments joined by spaces.
+func ProcessCommandLine(pid int) (string, error) {
+	b, err := os.ReadFile("/proc/" + strconv.Itoa(pid) + "/cmdline")
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(bytes.ReplaceAll(b, []byte{0}, []byte{' '}))), nil
+}
@@ -3,7 +3,11 @@
 package osutil
 
 import (
+	"fmt"
 	"io/fs"
+	"os/exec"
+	"strconv"
+	"strings"
 	"syscall"
 )
 
@@ -32,3 +36,13 @@ type Signal syscall.Signal
 func SysKill(pid int, sig Signal) error {
 	return syscall.Kill(pid, syscall.Signal(sig))
 }
+
+// ProcessCommandLine returns the command line of the process, with the arguments joined by spaces.
+func ProcessCommandLine(pid int) (string, error) {
+	cmd := exec.Command("ps", "-o", "command=", "-p", strconv.Itoa(pid))
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run %v: %w", cmd.Args, err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,43 @@
+package osutil
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestProcessCommandLine(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ProcessCommandLine is not implemented on Windows")
+	}
+	cmdline, err := ProcessCommandLine(os.Getpid())
+	assert.NilError(t, err)
+	t.Log(cmdline)
+	assert.Assert(t, strings.Contains(cmdline, "-test."), "cmdline %q should contain the test flags", cmdline)
+}
+
+func TestProcessesUsingFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("ProcessesUsingFile is not implemented on Windows")
+	}
+	if _, err := exec.LookPath("lsof"); err != nil {
+		t.Skip("lsof is not installed")
+	}
+	path := filepath.Join(t.TempDir(), "file")
+	assert.NilError(t, os.WriteFile(path, nil, 0o644))
+	pids, err := ProcessesUsingFile(path)
+	assert.NilError(t, err)
+	assert.Equal(t, len(pids), 0)
+
+	f, err := os.Open(path)
+	assert.NilError(t, err)
+	defer f.Close()
+	pids, err = ProcessesUsingFile(path)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, pids, []int{os.Getpid()})
+}
@@ -4,9 +4,11 @@ package osutil
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"syscall"
 
@@ -32,3 +34,29 @@ func Sysctl(name string) (string, error) {
 	}
 	return strings.TrimSuffix(string(stdout), "\n"), nil
 }
+
+// ProcessesUsingFile returns the PIDs of the processes that have opened the file, using lsof.
+func ProcessesUsingFile(path string) ([]int, error) {
+	var stderrBuf bytes.Buffer
+	cmd := exec.Command("lsof", "-t", "--", path)
+	cmd.Stderr = &stderrBuf
+	stdout, err := cmd.Output()
+	if err != nil {
+		// lsof exits with 1 when no process is found, with the empty stderr
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(stdout) == 0 && stderrBuf.Len() == 0 {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to run %v: %w (stdout=%q, stderr=%q)", cmd.Args, err,
+			string(stdout), stderrBuf.String())
+	}
+	var pids []int
+	for _, f := range strings.Fields(string(stdout)) {
+		pid, err := strconv.Atoi(f)
+		if err != nil {
+			return nil, fmt.Errorf("unexpected output from %v: %q", cmd.Args, string(stdout))
+		}
+		pids = append(pids, pid)
+	}
+	return pids, nil
+}
@@ -53,3 +53,11 @@ func SignalName(sig os.Signal) string {
 func Sysctl(name string) (string, error) {
 	return "", errors.New("sysctl: unimplemented on Windows")
 }
+
+func ProcessCommandLine(_ int) (string, error) {
+	return "", errors.New("ProcessCommandLine: unimplemented on Windows")
+}
+
+func ProcessesUsingFile(_ string) ([]int, error) {
+	return nil, errors.New("ProcessesUsingFile: unimplemented on Windows")
+}
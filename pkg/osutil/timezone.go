@@ -0,0 +1,39 @@
+package osutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// TimeZone returns the name of the timezone of the host, as used by the zoneinfo database.
+// An empty string is returned if the timezone cannot be determined.
+func TimeZone() string {
+	// WSL2 will automatically set the timezone
+	if runtime.GOOS == "windows" {
+		return ""
+	}
+	return timeZone("/")
+}
+
+// timeZone returns the timezone from /etc/timezone or deduces it from the symlink target
+// of /etc/localtime, relative to root.
+func timeZone(root string) string {
+	tz, err := os.ReadFile(filepath.Join(root, "etc/timezone"))
+	if err == nil {
+		return strings.TrimSpace(string(tz))
+	}
+	zoneinfoFile, err := filepath.EvalSymlinks(filepath.Join(root, "etc/localtime"))
+	if err == nil {
+		for baseDir := filepath.Dir(zoneinfoFile); baseDir != "/"; baseDir = filepath.Dir(baseDir) {
+			if _, err = os.Stat(filepath.Join(baseDir, "Etc/UTC")); err == nil {
+				return strings.TrimPrefix(zoneinfoFile, baseDir+"/")
+			}
+		}
+		logrus.Warnf("could not locate zoneinfo directory from %q", zoneinfoFile)
+	}
+	return ""
+}
@@ -0,0 +1,39 @@
+package osutil
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestTimeZone(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the timezone is not inherited on Windows")
+	}
+
+	t.Run("etc/timezone", func(t *testing.T) {
+		root := t.TempDir()
+		assert.NilError(t, os.MkdirAll(filepath.Join(root, "etc"), 0o755))
+		assert.NilError(t, os.WriteFile(filepath.Join(root, "etc/timezone"), []byte("Asia/Tokyo\n"), 0o644))
+		assert.Equal(t, timeZone(root), "Asia/Tokyo")
+	})
+
+	t.Run("etc/localtime", func(t *testing.T) {
+		root := t.TempDir()
+		zoneinfoDir := filepath.Join(root, "usr/share/zoneinfo")
+		for _, zone := range []string{"Etc/UTC", "Europe/Berlin"} {
+			assert.NilError(t, os.MkdirAll(filepath.Join(zoneinfoDir, filepath.Dir(zone)), 0o755))
+			assert.NilError(t, os.WriteFile(filepath.Join(zoneinfoDir, zone), nil, 0o644))
+		}
+		assert.NilError(t, os.MkdirAll(filepath.Join(root, "etc"), 0o755))
+		assert.NilError(t, os.Symlink(filepath.Join(zoneinfoDir, "Europe/Berlin"), filepath.Join(root, "etc/localtime")))
+		assert.Equal(t, timeZone(root), "Europe/Berlin")
+	})
+
+	t.Run("none", func(t *testing.T) {
+		assert.Equal(t, timeZone(t.TempDir()), "")
+	})
+}
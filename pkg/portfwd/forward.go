@@ -1,12 +1,16 @@
 package portfwd
 
 import (
+	"cmp"
 	"context"
 	"net"
+	"slices"
 	"strings"
+	"sync"
 
 	"github.com/lima-vm/lima/pkg/guestagent/api"
 	guestagentclient "github.com/lima-vm/lima/pkg/guestagent/api/client"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
 	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/sirupsen/logrus"
 )
@@ -18,6 +22,9 @@ type Forwarder struct {
 	ignoreTCP         bool
 	ignoreUDP         bool
 	closableListeners *ClosableListeners
+
+	forwardsMu sync.Mutex
+	forwards   map[string]hostagentapi.PortForward // keyed by the protocol and the guest address
 }
 
 func NewPortForwarder(rules []limayaml.PortForward, ignoreTCP, ignoreUDP bool) *Forwarder {
@@ -26,6 +33,7 @@ func NewPortForwarder(rules []limayaml.PortForward, ignoreTCP, ignoreUDP bool) *
 		ignoreTCP:         ignoreTCP,
 		ignoreUDP:         ignoreUDP,
 		closableListeners: NewClosableListener(),
+		forwards:          make(map[string]hostagentapi.PortForward),
 	}
 }
 
@@ -35,17 +43,27 @@ func (fw *Forwarder) OnEvent(ctx context.Context, client *guestagentclient.Guest
 		if local == "" {
 			if !fw.ignoreTCP && f.Protocol == "tcp" {
 				logrus.Infof("Not forwarding TCP %s", remote)
+				fw.setForward(hostagentapi.PortForward{GuestAddr: remote, Protocol: f.Protocol, Status: hostagentapi.PortForwardSkipped, Reason: "ignored by the port forwarding rules"})
 			}
 			if !fw.ignoreUDP && f.Protocol == "udp" {
 				logrus.Infof("Not forwarding UDP %s", remote)
+				fw.setForward(hostagentapi.PortForward{GuestAddr: remote, Protocol: f.Protocol, Status: hostagentapi.PortForwardSkipped, Reason: "ignored by the port forwarding rules"})
 			}
 			continue
 		}
 		logrus.Infof("Forwarding %s from %s to %s", strings.ToUpper(f.Protocol), remote, local)
-		fw.closableListeners.Forward(ctx, client, f.Protocol, local, remote)
+		fwd := hostagentapi.PortForward{GuestAddr: remote, HostAddr: local, Protocol: f.Protocol, Status: hostagentapi.PortForwardActive}
+		if err := fw.closableListeners.Forward(ctx, client, f.Protocol, local, remote); err != nil {
+			fwd.Status = hostagentapi.PortForwardFailed
+			fwd.Reason = err.Error()
+		}
+		fw.setForward(fwd)
 	}
 	for _, f := range ev.LocalPortsRemoved {
 		local, remote := fw.forwardingAddresses(f)
+		fw.forwardsMu.Lock()
+		delete(fw.forwards, forwardKey(f.Protocol, remote))
+		fw.forwardsMu.Unlock()
 		if local == "" {
 			continue
 		}
@@ -54,6 +72,36 @@ func (fw *Forwarder) OnEvent(ctx context.Context, client *guestagentclient.Guest
 	}
 }
 
+func forwardKey(protocol, guestAddr string) string {
+	return protocol + "-" + guestAddr
+}
+
+func (fw *Forwarder) setForward(fwd hostagentapi.PortForward) {
+	fw.forwardsMu.Lock()
+	fw.forwards[forwardKey(fwd.Protocol, fwd.GuestAddr)] = fwd
+	fw.forwardsMu.Unlock()
+}
+
+// PortForwards returns the TCP and UDP port forwards with their status, sorted by the guest address and the protocol.
+func (fw *Forwarder) PortForwards() []hostagentapi.PortForward {
+	fw.forwardsMu.Lock()
+	defer fw.forwardsMu.Unlock()
+	res := make([]hostagentapi.PortForward, 0, len(fw.forwards))
+	for _, fwd := range fw.forwards {
+		res = append(res, fwd)
+	}
+	slices.SortFunc(res, func(a, b hostagentapi.PortForward) int {
+		return cmp.Or(strings.Compare(a.GuestAddr, b.GuestAddr), strings.Compare(a.Protocol, b.Protocol))
+	})
+	return res
+}
+
+// HostAddress returns the host address that the guest port is forwarded to, or "" if the port is not forwarded.
+func (fw *Forwarder) HostAddress(guest *api.IPPort) string {
+	hostAddr, _ := fw.forwardingAddresses(guest)
+	return hostAddr
+}
+
 func (fw *Forwarder) forwardingAddresses(guest *api.IPPort) (hostAddr, guestAddr string) {
 	guestIP := net.ParseIP(guest.Ip)
 	for _, rule := range fw.rules {
@@ -0,0 +1,70 @@
+package portfwd
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/guestagent/api"
+	guestagentclient "github.com/lima-vm/lima/pkg/guestagent/api/client"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"gotest.tools/v3/assert"
+)
+
+func TestForwarderPortForwards(t *testing.T) {
+	// A free TCP and UDP port on the host
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	freePort := l.Addr().(*net.TCPAddr).Port
+	assert.NilError(t, l.Close())
+	// A TCP port already in use on the host
+	inUse, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	t.Cleanup(func() { _ = inUse.Close() })
+	inUsePort := inUse.Addr().(*net.TCPAddr).Port
+
+	rule := func(guestPort, hostPort int, proto limayaml.Proto) limayaml.PortForward {
+		return limayaml.PortForward{
+			GuestIP:        IPv4loopback1,
+			GuestPortRange: [2]int{guestPort, guestPort},
+			HostIP:         IPv4loopback1,
+			HostPortRange:  [2]int{hostPort, hostPort},
+			Proto:          proto,
+		}
+	}
+	fw := NewPortForwarder([]limayaml.PortForward{
+		rule(8080, freePort, limayaml.ProtoAny),
+		rule(9090, inUsePort, limayaml.ProtoTCP),
+	}, false, false)
+
+	guestPorts := []*api.IPPort{
+		{Protocol: "tcp", Ip: "127.0.0.1", Port: 8080},
+		{Protocol: "udp", Ip: "127.0.0.1", Port: 8080},
+		{Protocol: "tcp", Ip: "127.0.0.1", Port: 9090},
+		{Protocol: "udp", Ip: "127.0.0.1", Port: 9090},
+	}
+	// the guest agent is never reached
+	client, err := guestagentclient.NewGuestAgentClient(func(context.Context) (net.Conn, error) {
+		return nil, errors.New("no guest agent")
+	})
+	assert.NilError(t, err)
+	ctx := context.Background()
+	fw.OnEvent(ctx, client, &api.Event{LocalPortsAdded: guestPorts})
+	t.Cleanup(func() { fw.OnEvent(ctx, client, &api.Event{LocalPortsRemoved: guestPorts}) })
+
+	forwards := fw.PortForwards()
+	assert.Equal(t, len(forwards), 4)
+	freeAddr := net.JoinHostPort("127.0.0.1", strconv.Itoa(freePort))
+	assert.DeepEqual(t, forwards[0], hostagentapi.PortForward{GuestAddr: "127.0.0.1:8080", HostAddr: freeAddr, Protocol: "tcp", Status: hostagentapi.PortForwardActive})
+	assert.DeepEqual(t, forwards[1], hostagentapi.PortForward{GuestAddr: "127.0.0.1:8080", HostAddr: freeAddr, Protocol: "udp", Status: hostagentapi.PortForwardActive})
+	assert.Equal(t, forwards[2].Protocol, "tcp")
+	assert.Equal(t, forwards[2].Status, hostagentapi.PortForwardFailed)
+	assert.Assert(t, forwards[2].Reason != "")
+	assert.DeepEqual(t, forwards[3], hostagentapi.PortForward{GuestAddr: "127.0.0.1:9090", Protocol: "udp", Status: hostagentapi.PortForwardSkipped, Reason: "ignored by the port forwarding rules"})
+
+	fw.OnEvent(ctx, client, &api.Event{LocalPortsRemoved: guestPorts})
+	assert.Equal(t, len(fw.PortForwards()), 0)
+}
@@ -31,15 +31,18 @@ func NewClosableListener() *ClosableListeners {
 	}
 }
 
+// Forward starts listening on the host address, and forwards the connections to the guest address in the background.
+// An error is returned when the host address cannot be listened on.
 func (p *ClosableListeners) Forward(ctx context.Context, client *guestagentclient.GuestAgentClient,
 	protocol string, hostAddress string, guestAddress string,
-) {
+) error {
 	switch protocol {
 	case "tcp", "tcp6":
-		go p.forwardTCP(ctx, client, hostAddress, guestAddress)
+		return p.forwardTCP(ctx, client, hostAddress, guestAddress)
 	case "udp", "udp6":
-		go p.forwardUDP(ctx, client, hostAddress, guestAddress)
+		return p.forwardUDP(ctx, client, hostAddress, guestAddress)
 	}
+	return nil
 }
 
 func (p *ClosableListeners) Remove(_ context.Context, protocol, hostAddress, guestAddress string) {
@@ -65,59 +68,65 @@ func (p *ClosableListeners) Remove(_ context.Context, protocol, hostAddress, gue
 	}
 }
 
-func (p *ClosableListeners) forwardTCP(ctx context.Context, client *guestagentclient.GuestAgentClient, hostAddress, guestAddress string) {
+func (p *ClosableListeners) forwardTCP(ctx context.Context, client *guestagentclient.GuestAgentClient, hostAddress, guestAddress string) error {
 	key := key("tcp", hostAddress, guestAddress)
 
 	p.listenersRW.Lock()
 	_, ok := p.listeners[key]
 	if ok {
 		p.listenersRW.Unlock()
-		return
+		return nil
 	}
 	tcpLis, err := Listen(ctx, p.listenConfig, hostAddress)
 	if err != nil {
 		logrus.Errorf("failed to listen to TCP connection: %v", err)
 		p.listenersRW.Unlock()
-		return
+		return err
 	}
-	defer p.Remove(ctx, "tcp", hostAddress, guestAddress)
 	p.listeners[key] = tcpLis
 	p.listenersRW.Unlock()
-	for {
-		conn, err := tcpLis.Accept()
-		if err != nil {
-			logrus.Errorf("failed to accept TCP connection: %v", err)
-			if strings.Contains(err.Error(), "pseudoloopback") {
-				// don't stop forwarding because the forwarder has rejected a non-local address
-				continue
+	go func() {
+		defer p.Remove(ctx, "tcp", hostAddress, guestAddress)
+		for {
+			conn, err := tcpLis.Accept()
+			if err != nil {
+				logrus.Errorf("failed to accept TCP connection: %v", err)
+				if strings.Contains(err.Error(), "pseudoloopback") {
+					// don't stop forwarding because the forwarder has rejected a non-local address
+					continue
+				}
+				return
 			}
-			return
+			go HandleTCPConnection(ctx, client, conn, guestAddress)
 		}
-		go HandleTCPConnection(ctx, client, conn, guestAddress)
-	}
+	}()
+	return nil
 }
 
-func (p *ClosableListeners) forwardUDP(ctx context.Context, client *guestagentclient.GuestAgentClient, hostAddress, guestAddress string) {
+func (p *ClosableListeners) forwardUDP(ctx context.Context, client *guestagentclient.GuestAgentClient, hostAddress, guestAddress string) error {
 	key := key("udp", hostAddress, guestAddress)
-	defer p.Remove(ctx, "udp", hostAddress, guestAddress)
 
 	p.udpListenersRW.Lock()
 	_, ok := p.udpListeners[key]
 	if ok {
 		p.udpListenersRW.Unlock()
-		return
+		return nil
 	}
 
 	udpConn, err := ListenPacket(ctx, p.listenConfig, hostAddress)
 	if err != nil {
 		logrus.Errorf("failed to listen udp: %v", err)
 		p.udpListenersRW.Unlock()
-		return
+		return err
 	}
 	p.udpListeners[key] = udpConn
 	p.udpListenersRW.Unlock()
 
-	HandleUDPConnection(ctx, client, udpConn, guestAddress)
+	go func() {
+		defer p.Remove(ctx, "udp", hostAddress, guestAddress)
+		HandleUDPConnection(ctx, client, udpConn, guestAddress)
+	}()
+	return nil
 }
 
 func key(protocol, hostAddress, guestAddress string) string {
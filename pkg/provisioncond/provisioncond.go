@@ -0,0 +1,170 @@
+// Package provisioncond parses the `when` conditions of the provisioning scripts,
+// and renders them into the shell scripts that are evaluated in the guest on boot.
+//
+// A condition compares the facts of the guest with the values, e.g.:
+//
+//	arch == aarch64 && os != alpine || os == ubuntu && os_version == 24.04
+//
+// "&&" binds tighter than "||". Parentheses are not supported.
+package provisioncond
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+const (
+	// FactArch is the architecture of the guest, as reported by `uname -m`, e.g., "aarch64".
+	FactArch = "arch"
+	// FactOS is the ID field of /etc/os-release, e.g., "ubuntu".
+	FactOS = "os"
+	// FactOSVersion is the VERSION_ID field of /etc/os-release, e.g., "24.04".
+	FactOSVersion = "os_version"
+)
+
+var Facts = []string{FactArch, FactOS, FactOSVersion}
+
+// Comparison compares a fact with a value.
+type Comparison struct {
+	Fact   string
+	Negate bool // "!=" instead of "=="
+	Value  string
+}
+
+// Condition is a disjunction of conjunctions of comparisons.
+type Condition [][]Comparison
+
+// Parse parses the condition.
+func Parse(s string) (Condition, error) {
+	tokens, err := tokenize(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, errors.New("must not be empty")
+	}
+	var (
+		cond Condition
+		conj []Comparison
+	)
+	for len(tokens) > 0 {
+		if len(tokens) < 3 {
+			return nil, fmt.Errorf("expected a comparison like \"%s == aarch64\", got %q", FactArch, strings.Join(tokens, " "))
+		}
+		fact, op, value := tokens[0], tokens[1], tokens[2]
+		if !slices.Contains(Facts, fact) {
+			return nil, fmt.Errorf("unknown fact %q (must be one of %v)", fact, Facts)
+		}
+		if op != "==" && op != "!=" {
+			return nil, fmt.Errorf("expected \"==\" or \"!=\" after %q, got %q", fact, op)
+		}
+		if isOperator(value) {
+			return nil, fmt.Errorf("expected a value after %q, got %q", fact+" "+op, value)
+		}
+		conj = append(conj, Comparison{Fact: fact, Negate: op == "!=", Value: value})
+		tokens = tokens[3:]
+		if len(tokens) == 0 {
+			break
+		}
+		switch tokens[0] {
+		case "&&":
+		case "||":
+			cond = append(cond, conj)
+			conj = nil
+		default:
+			return nil, fmt.Errorf("expected \"&&\" or \"||\", got %q", tokens[0])
+		}
+		tokens = tokens[1:]
+		if len(tokens) == 0 {
+			return nil, errors.New("must not end with an operator")
+		}
+	}
+	return append(cond, conj), nil
+}
+
+func isOperator(token string) bool {
+	switch token {
+	case "==", "!=", "&&", "||":
+		return true
+	}
+	return false
+}
+
+func isValueChar(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || strings.IndexByte("._+-", c) >= 0
+}
+
+// tokenize splits s into the operators and the words.
+// The words consist of alphanumeric characters and "._+-", so that they can be safely quoted in the shell.
+func tokenize(s string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case i+1 < len(s) && isOperator(s[i:i+2]):
+			tokens = append(tokens, s[i:i+2])
+			i += 2
+		case isValueChar(c):
+			j := i
+			for j < len(s) && isValueChar(s[j]) {
+				j++
+			}
+			tokens = append(tokens, s[i:j])
+			i = j
+		default:
+			return nil, fmt.Errorf("unexpected character %q at offset %d", c, i)
+		}
+	}
+	return tokens, nil
+}
+
+// String returns the canonical form of the condition.
+func (cond Condition) String() string {
+	disj := make([]string, len(cond))
+	for i, conj := range cond {
+		comparisons := make([]string, len(conj))
+		for j, c := range conj {
+			op := "=="
+			if c.Negate {
+				op = "!="
+			}
+			comparisons[j] = c.Fact + " " + op + " " + c.Value
+		}
+		disj[i] = strings.Join(comparisons, " && ")
+	}
+	return strings.Join(disj, " || ")
+}
+
+var shellFactVars = map[string]string{
+	FactArch:      "ARCH",
+	FactOS:        "OS",
+	FactOSVersion: "OS_VERSION",
+}
+
+// Shell returns the POSIX shell script that exits with zero if the condition is satisfied.
+func (cond Condition) Shell() string {
+	var sb strings.Builder
+	sb.WriteString("#!/bin/sh\n")
+	fmt.Fprintf(&sb, "# Generated from the condition: %s\n", cond)
+	sb.WriteString(`ARCH="$(uname -m)"` + "\n")
+	sb.WriteString(`OS="$(. /etc/os-release 2>/dev/null && echo "${ID:-}")"` + "\n")
+	sb.WriteString(`OS_VERSION="$(. /etc/os-release 2>/dev/null && echo "${VERSION_ID:-}")"` + "\n")
+	disj := make([]string, len(cond))
+	for i, conj := range cond {
+		comparisons := make([]string, len(conj))
+		for j, c := range conj {
+			op := "="
+			if c.Negate {
+				op = "!="
+			}
+			comparisons[j] = fmt.Sprintf(`[ "${%s}" %s '%s' ]`, shellFactVars[c.Fact], op, c.Value)
+		}
+		disj[i] = "{ " + strings.Join(comparisons, " && ") + "; }"
+	}
+	sb.WriteString(strings.Join(disj, " || ") + "\n")
+	return sb.String()
+}
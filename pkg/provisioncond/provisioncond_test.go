@@ -0,0 +1,73 @@
+package provisioncond
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestParse(t *testing.T) {
+	cond, err := Parse("arch==aarch64 && os != alpine || os == ubuntu && os_version == 24.04")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, cond, Condition{
+		{{Fact: FactArch, Value: "aarch64"}, {Fact: FactOS, Negate: true, Value: "alpine"}},
+		{{Fact: FactOS, Value: "ubuntu"}, {Fact: FactOSVersion, Value: "24.04"}},
+	})
+	assert.Equal(t, cond.String(), "arch == aarch64 && os != alpine || os == ubuntu && os_version == 24.04")
+
+	invalid := map[string]string{
+		"":                         "must not be empty",
+		"arch":                     "expected a comparison",
+		"kernel == linux":          "unknown fact",
+		"arch = aarch64":           "unexpected character",
+		"arch aarch64 x86_64":      "expected \"==\" or \"!=\"",
+		"arch == ==":               "expected a value",
+		"arch == aarch64 os == a":  "expected \"&&\" or \"||\"",
+		"arch == aarch64 &&":       "must not end with an operator",
+		"arch == 'aarch64'":        "unexpected character",
+		"arch == aarch64; reboot":  "unexpected character",
+		"arch == $(reboot)":        "unexpected character",
+		"(arch == aarch64)":        "unexpected character",
+		"arch == aarch64 || || os": "expected a comparison",
+	}
+	for s, expected := range invalid {
+		_, err := Parse(s)
+		assert.ErrorContains(t, err, expected, s)
+	}
+}
+
+// TestShell runs the rendered script with a fake `uname` that reports the architecture.
+func TestShell(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	run := func(t *testing.T, condition, arch string) bool {
+		t.Helper()
+		cond, err := Parse(condition)
+		assert.NilError(t, err)
+		dir := t.TempDir()
+		assert.NilError(t, os.WriteFile(filepath.Join(dir, "uname"), []byte("#!/bin/sh\necho "+arch+"\n"), 0o755))
+		script := filepath.Join(dir, "when")
+		assert.NilError(t, os.WriteFile(script, []byte(cond.Shell()), 0o755))
+		cmd := exec.Command("/bin/sh", script)
+		cmd.Env = append(os.Environ(), "PATH="+dir+string(filepath.ListSeparator)+os.Getenv("PATH"))
+		err = cmd.Run()
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return false
+		}
+		assert.NilError(t, err)
+		return true
+	}
+	assert.Assert(t, !run(t, "arch == aarch64", "x86_64"))
+	assert.Assert(t, run(t, "arch == aarch64", "aarch64"))
+	assert.Assert(t, run(t, "arch != aarch64", "x86_64"))
+	assert.Assert(t, run(t, "arch == aarch64 || arch == x86_64", "x86_64"))
+	assert.Assert(t, !run(t, "arch == x86_64 && os == no-such-os", "x86_64"))
+	assert.Assert(t, run(t, "arch == x86_64 && os != no-such-os", "x86_64"))
+}
@@ -3,8 +3,10 @@ package imgutil
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os/exec"
+	"strings"
 
 	"github.com/sirupsen/logrus"
 )
@@ -111,6 +113,32 @@ func GetInfo(f string) (*Info, error) {
 	return ParseInfo(stdout.Bytes())
 }
 
+// ErrImageInUse is returned by CheckNotInUse when the image is locked by another process.
+var ErrImageInUse = errors.New("image is in use by another process")
+
+// CheckNotInUse returns an error wrapping ErrImageInUse when the image is locked by another process, e.g., QEMU.
+// Unlike GetInfo, the image is opened without --force-share, so that qemu-img honors the image locking.
+func CheckNotInUse(f string) error {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("qemu-img", "info", "--output=json", f)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if isLockError(stderr.String()) {
+			return fmt.Errorf("%w: %q: %s", ErrImageInUse, f, strings.TrimSpace(stderr.String()))
+		}
+		return fmt.Errorf("failed to run %v: stdout=%q, stderr=%q: %w",
+			cmd.Args, stdout.String(), stderr.String(), err)
+	}
+	return nil
+}
+
+// isLockError returns true for the stderr of qemu-img that failed to lock the image, e.g.,
+// `qemu-img: Could not open 'diffdisk': Failed to get shared "write" lock`.
+func isLockError(stderr string) bool {
+	return strings.Contains(stderr, "Failed to get ") && strings.Contains(stderr, " lock")
+}
+
 func AcceptableAsBasedisk(info *Info) error {
 	switch info.Format {
 	case "qcow2", "raw":
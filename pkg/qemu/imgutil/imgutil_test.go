@@ -210,3 +210,11 @@ func TestParseInfo(t *testing.T) {
 		})
 	})
 }
+
+func TestIsLockError(t *testing.T) {
+	assert.Assert(t, isLockError(`qemu-img: Could not open '/Users/user/.lima/default/diffdisk': Failed to get shared "write" lock
+Is another process using the image [/Users/user/.lima/default/diffdisk]?
+`))
+	assert.Assert(t, isLockError(`qemu-img: Could not open 'diffdisk': Failed to get "consistent read" lock`))
+	assert.Assert(t, !isLockError(`qemu-img: Could not open 'diffdisk': Could not open 'diffdisk': No such file or directory`))
+}
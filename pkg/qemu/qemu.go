@@ -25,6 +25,7 @@ import (
 	"github.com/digitalocean/go-qemu/qmp/raw"
 	"github.com/docker/go-units"
 	"github.com/lima-vm/lima/pkg/fileutils"
+	guestagentaddress "github.com/lima-vm/lima/pkg/guestagent/address"
 	"github.com/lima-vm/lima/pkg/iso9660util"
 	"github.com/lima-vm/lima/pkg/limayaml"
 	"github.com/lima-vm/lima/pkg/localpathutil"
@@ -41,6 +42,8 @@ type Config struct {
 	InstanceDir  string
 	LimaYAML     *limayaml.LimaYAML
 	SSHLocalPort int
+	// Incoming makes QEMU wait for the vm state to be loaded via the QMP command "migrate-incoming".
+	Incoming bool
 }
 
 // MinimumQemuVersion is the minimum supported QEMU version.
@@ -53,7 +56,10 @@ func EnsureDisk(ctx context.Context, cfg Config) error {
 	diffDisk := filepath.Join(cfg.InstanceDir, filenames.DiffDisk)
 	if _, err := os.Stat(diffDisk); err == nil || !errors.Is(err, os.ErrNotExist) {
 		// disk is already ensured
-		return err
+		if err != nil {
+			return err
+		}
+		return checkDiskNotInUse(diffDisk)
 	}
 
 	baseDisk := filepath.Join(cfg.InstanceDir, filenames.BaseDisk)
@@ -64,7 +70,8 @@ func EnsureDisk(ctx context.Context, cfg Config) error {
 		var ensuredBaseDisk bool
 		errs := make([]error, len(cfg.LimaYAML.Images))
 		for i, f := range cfg.LimaYAML.Images {
-			if _, err := fileutils.DownloadFile(ctx, baseDisk, f.File, true, "the image", *cfg.LimaYAML.Arch); err != nil {
+			res, err := fileutils.Download(ctx, baseDisk, f.File, true, "the image", *cfg.LimaYAML.Arch)
+			if err != nil {
 				errs[i] = err
 				continue
 			}
@@ -86,6 +93,9 @@ func EnsureDisk(ctx context.Context, cfg Config) error {
 					continue
 				}
 			}
+			if err := store.WriteProvenanceImage(cfg.InstanceDir, &store.Artifact{Location: f.Location, Digest: res.Digest}); err != nil {
+				logrus.WithError(err).Warn("Failed to record the image in the provenance")
+			}
 			ensuredBaseDisk = true
 			break
 		}
@@ -93,7 +103,7 @@ func EnsureDisk(ctx context.Context, cfg Config) error {
 			return fileutils.Errors(errs)
 		}
 	}
-	diskSize, _ := units.RAMInBytes(*cfg.LimaYAML.Disk)
+	diskSize, _ := units.RAMInBytes(*cfg.LimaYAML.Disk.Size)
 	if diskSize == 0 {
 		return nil
 	}
@@ -123,6 +133,29 @@ func EnsureDisk(ctx context.Context, cfg Config) error {
 	return nil
 }
 
+// checkDiskNotInUse returns an error when the disk is locked by another process,
+// such as a QEMU process left running after the host agent has exited.
+// Running another VM with the same disk would corrupt the disk.
+func checkDiskNotInUse(disk string) error {
+	err := imgutil.CheckNotInUse(disk)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, imgutil.ErrImageInUse) {
+		// Not fatal here, as the disk is opened by QEMU later anyway
+		logrus.WithError(err).Warnf("Failed to check whether the disk %q is in use", disk)
+		return nil
+	}
+	hint := "the instance may be still running"
+	pids, pidsErr := osutil.ProcessesUsingFile(disk)
+	if pidsErr != nil {
+		logrus.WithError(pidsErr).Debugf("Failed to find the processes using the disk %q", disk)
+	} else if len(pids) > 0 {
+		hint = fmt.Sprintf("the disk is used by the process %v, which may be a QEMU process left by the previous run", pids)
+	}
+	return fmt.Errorf("refusing to start the instance, as the disk is in use (hint: %s): %w", hint, err)
+}
+
 func CreateDataDisk(dir, format string, size int) error {
 	dataDisk := filepath.Join(dir, filenames.DataDisk)
 	if _, err := os.Stat(dataDisk); err == nil || !errors.Is(err, fs.ErrNotExist) {
@@ -476,6 +509,15 @@ func audioDevice() string {
 	return "oss"
 }
 
+// smpArg returns the value of the `-smp` option.
+// With runtimeResize on x86_64, the vCPUs can be hot-added up to the number of the host CPUs, via the runtime config of the driver.
+func smpArg(arch limayaml.Arch, cpus, hostCPUs int, runtimeResize bool) string {
+	if runtimeResize && arch == limayaml.X8664 && hostCPUs > cpus {
+		return fmt.Sprintf("%d,sockets=1,cores=%d,threads=1,maxcpus=%d", cpus, hostCPUs, hostCPUs)
+	}
+	return fmt.Sprintf("%d,sockets=1,cores=%d,threads=1", cpus, cpus)
+}
+
 func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err error) {
 	y := cfg.LimaYAML
 	exe, args, err = Exe(*y.Arch)
@@ -586,8 +628,7 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	}
 
 	// SMP
-	args = appendArgsIfNoConflict(args, "-smp",
-		fmt.Sprintf("%d,sockets=1,cores=%d,threads=1", *y.CPUs, *y.CPUs))
+	args = appendArgsIfNoConflict(args, "-smp", smpArg(*y.Arch, *y.CPUs, runtime.NumCPU(), *y.VMOpts.QEMU.RuntimeResize))
 
 	// Firmware
 	legacyBIOS := *y.Firmware.LegacyBIOS
@@ -634,6 +675,7 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	baseDisk := filepath.Join(cfg.InstanceDir, filenames.BaseDisk)
 	diffDisk := filepath.Join(cfg.InstanceDir, filenames.DiffDisk)
 	extraDisks := []string{}
+	extraDisksReadOnly := []bool{}
 	for _, d := range y.AdditionalDisks {
 		diskName := d.Name
 		disk, err := store.InspectDisk(diskName)
@@ -642,6 +684,21 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 			return "", nil, err
 		}
 
+		if d.ReadOnly != nil && *d.ReadOnly {
+			// A read-only disk can be shared across instances, but it must not be
+			// attached while another instance may write to it.
+			if disk.Instance != "" && disk.InstanceDir != cfg.InstanceDir {
+				return "", nil, fmt.Errorf("could not attach disk %q as read-only, in use by instance %q", diskName, disk.Instance)
+			}
+			logrus.Infof("Mounting disk %q on %q (read-only)", diskName, disk.MountPoint)
+			if err := disk.LockReadOnly(cfg.InstanceDir); err != nil {
+				return "", nil, fmt.Errorf("could not lock disk %q as read-only: %w", diskName, err)
+			}
+			extraDisks = append(extraDisks, filepath.Join(disk.Dir, filenames.DataDisk))
+			extraDisksReadOnly = append(extraDisksReadOnly, true)
+			continue
+		}
+
 		if disk.Instance != "" {
 			if disk.InstanceDir != cfg.InstanceDir {
 				logrus.Errorf("could not attach disk %q, in use by instance %q", diskName, disk.Instance)
@@ -661,6 +718,7 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 		}
 		dataDisk := filepath.Join(disk.Dir, filenames.DataDisk)
 		extraDisks = append(extraDisks, dataDisk)
+		extraDisksReadOnly = append(extraDisksReadOnly, false)
 	}
 
 	isBaseDiskCDROM, err := iso9660util.IsISO9660(baseDisk)
@@ -673,7 +731,7 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	} else {
 		args = appendArgsIfNoConflict(args, "-boot", "order=c,splash-time=0,menu=on")
 	}
-	if diskSize, _ := units.RAMInBytes(*cfg.LimaYAML.Disk); diskSize > 0 {
+	if diskSize, _ := units.RAMInBytes(*cfg.LimaYAML.Disk.Size); diskSize > 0 {
 		args = append(args, "-drive", fmt.Sprintf("file=%s,if=virtio,discard=on", diffDisk))
 	} else if !isBaseDiskCDROM {
 		baseDiskInfo, err := imgutil.GetInfo(baseDisk)
@@ -688,15 +746,23 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 		}
 		args = append(args, "-drive", fmt.Sprintf("file=%s,format=%s,if=virtio,discard=on", baseDisk, baseDiskInfo.Format))
 	}
-	for _, extraDisk := range extraDisks {
-		args = append(args, "-drive", fmt.Sprintf("file=%s,if=virtio,discard=on", extraDisk))
+	for i, extraDisk := range extraDisks {
+		if extraDisksReadOnly[i] {
+			args = append(args, "-drive", fmt.Sprintf("file=%s,if=virtio,readonly=on", extraDisk))
+		} else {
+			args = append(args, "-drive", fmt.Sprintf("file=%s,if=virtio,discard=on", extraDisk))
+		}
 	}
 
 	// cloud-init
-	args = append(args,
-		"-drive", "id=cdrom0,if=none,format=raw,readonly=on,file="+filepath.Join(cfg.InstanceDir, filenames.CIDataISO),
-		"-device", "virtio-scsi-pci,id=scsi0",
-		"-device", "scsi-cd,bus=scsi0.0,drive=cdrom0")
+	if *y.CIData.VFAT {
+		args = append(args, "-drive", "if=virtio,format=raw,readonly=on,file="+filepath.Join(cfg.InstanceDir, filenames.CIDataVFAT))
+	} else {
+		args = append(args,
+			"-drive", "id=cdrom0,if=none,format=raw,readonly=on,file="+filepath.Join(cfg.InstanceDir, filenames.CIDataISO),
+			"-device", "virtio-scsi-pci,id=scsi0",
+			"-device", "scsi-cd,bus=scsi0.0,drive=cdrom0")
+	}
 
 	// Kernel
 	kernel := filepath.Join(cfg.InstanceDir, filenames.Kernel)
@@ -773,6 +839,11 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	// virtio-rng-pci accelerates starting up the OS, according to https://wiki.gentoo.org/wiki/QEMU/Options
 	args = append(args, "-device", "virtio-rng-pci")
 
+	if *y.VMOpts.QEMU.RuntimeResize {
+		// virtio-balloon-pci allows shrinking the memory at runtime, via the runtime config of the driver
+		args = append(args, "-device", "virtio-balloon-pci")
+	}
+
 	// Input
 	input := "mouse"
 
@@ -872,7 +943,17 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	args = append(args, "-device", "virtio-serial-pci,id=virtio-serial0,max_ports=1")
 	args = append(args, "-device", fmt.Sprintf("virtconsole,chardev=%s,id=console0", serialvChardev))
 
-	// We also want to enable vsock here, but QEMU does not support vsock for macOS hosts
+	// We also want to enable vsock here, but QEMU does not support vsock for macOS hosts.
+	// On Linux hosts, vsock is only enabled for `guestAgent.address: vsock://CID:PORT`.
+	if y.GuestAgent.Address != nil && *y.GuestAgent.Address != "" && runtime.GOOS == "linux" {
+		addr, err := guestagentaddress.Parse(*y.GuestAgent.Address)
+		if err != nil {
+			return "", nil, err
+		}
+		if addr.Transport == guestagentaddress.TransportVSock {
+			args = append(args, "-device", fmt.Sprintf("vhost-vsock-pci,id=vsock0,guest-cid=%d", addr.CID))
+		}
+	}
 
 	if *y.MountType == limayaml.NINEP || *y.MountType == limayaml.VIRTIOFS {
 		for i, f := range y.Mounts {
@@ -930,6 +1011,11 @@ func Cmdline(ctx context.Context, cfg Config) (exe string, args []string, err er
 	args = append(args, "-name", "lima-"+cfg.Name)
 	args = append(args, "-pidfile", filepath.Join(cfg.InstanceDir, filenames.PIDFile(*y.VMType)))
 
+	// Saved state
+	if cfg.Incoming {
+		args = append(args, "-incoming", "defer")
+	}
+
 	return exe, args, nil
 }
 
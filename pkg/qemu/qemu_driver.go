@@ -34,6 +34,9 @@ type LimaQemuDriver struct {
 	qWaitCh chan error
 
 	vhostCmds []*exec.Cmd
+
+	// stateSaved is set by SaveState, so that Stop kills QEMU without shutting down the guest.
+	stateSaved bool
 }
 
 func New(driver *driver.BaseDriver) *LimaQemuDriver {
@@ -59,8 +62,8 @@ func (l *LimaQemuDriver) CreateDisk(ctx context.Context) error {
 	return EnsureDisk(ctx, qCfg)
 }
 
-func (l *LimaQemuDriver) Start(ctx context.Context) (chan error, error) {
-	ctx, cancel := context.WithCancel(ctx)
+func (l *LimaQemuDriver) Start(parentCtx context.Context) (chan error, error) {
+	ctx, cancel := context.WithCancel(parentCtx)
 	defer func() {
 		if l.qCmd == nil {
 			cancel()
@@ -72,6 +75,7 @@ func (l *LimaQemuDriver) Start(ctx context.Context) (chan error, error) {
 		InstanceDir:  l.Instance.Dir,
 		LimaYAML:     l.Instance.Config,
 		SSHLocalPort: l.SSHLocalPort,
+		Incoming:     l.RestoreState,
 	}
 	qExe, qArgs, err := Cmdline(ctx, qCfg)
 	if err != nil {
@@ -185,6 +189,21 @@ func (l *LimaQemuDriver) Start(ctx context.Context) (chan error, error) {
 		l.qWaitCh <- qCmd.Wait()
 	}()
 	l.vhostCmds = vhostCmds
+	if l.RestoreState {
+		l.RestoreState = false
+		if err := l.restoreState(ctx); err != nil {
+			logrus.WithError(err).Warn("Failed to restore the saved state, falling back to booting the vm")
+			_ = l.killQEMU(ctx, 0, l.qCmd, l.qWaitCh)
+			l.qCmd = nil
+			cancel()
+			if l.PrepareColdBoot != nil {
+				if err := l.PrepareColdBoot(parentCtx); err != nil {
+					return nil, err
+				}
+			}
+			return l.Start(parentCtx)
+		}
+	}
 	go func() {
 		if usernetIndex := limayaml.FirstUsernetIndex(l.Instance.Config); usernetIndex != -1 {
 			client := usernet.NewClientByName(l.Instance.Config.Networks[usernetIndex].Lima)
@@ -197,8 +216,27 @@ func (l *LimaQemuDriver) Start(ctx context.Context) (chan error, error) {
 	return l.qWaitCh, nil
 }
 
-func (l *LimaQemuDriver) Stop(ctx context.Context) error {
-	return l.shutdownQEMU(ctx, 3*time.Minute, l.qCmd, l.qWaitCh)
+func (l *LimaQemuDriver) Stop(ctx context.Context, force bool) error {
+	if usernetIndex := limayaml.FirstUsernetIndex(l.Instance.Config); usernetIndex != -1 {
+		client := usernet.NewClientByName(l.Instance.Config.Networks[usernetIndex].Lima)
+		err := client.UnExposeSSH(l.SSHLocalPort)
+		if err != nil {
+			logrus.Warnf("Failed to remove SSH binding for port %d", l.SSHLocalPort)
+		}
+	}
+	if force {
+		logrus.Info("Forcibly killing QEMU")
+		return l.killQEMU(ctx, 0, l.qCmd, l.qWaitCh)
+	}
+	if l.stateSaved {
+		logrus.Info("Killing QEMU, as the state of the vm has been saved")
+		return l.killQEMU(ctx, 0, l.qCmd, l.qWaitCh)
+	}
+	timeout := 3 * time.Minute
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+	}
+	return l.shutdownQEMU(ctx, timeout, l.qCmd, l.qWaitCh)
 }
 
 func (l *LimaQemuDriver) ChangeDisplayPassword(_ context.Context, password string) error {
@@ -267,6 +305,66 @@ func (l *LimaQemuDriver) getVNCDisplayPort() (string, error) {
 	return *info.Service, nil
 }
 
+// qmpStatusInfo is the result of the QMP command "query-status".
+type qmpStatusInfo struct {
+	Running bool   `json:"running"`
+	Status  string `json:"status"`
+}
+
+// RunState returns the run state of the vm by the QMP command "query-status", e.g., "running", "paused", or "io-error".
+func (l *LimaQemuDriver) RunState(ctx context.Context) (string, error) {
+	type result struct {
+		status string
+		err    error
+	}
+	// QEMU serves only one QMP client at a time, so the connection may be blocked by another client
+	ch := make(chan result, 1)
+	go func() {
+		status, err := l.queryStatus()
+		ch <- result{status: status, err: err}
+	}()
+	select {
+	case <-ctx.Done():
+		return "", ctx.Err()
+	case r := <-ch:
+		return r.status, r.err
+	}
+}
+
+func (l *LimaQemuDriver) queryStatus() (string, error) {
+	var info qmpStatusInfo
+	if err := l.qmpCommand("query-status", nil, &info); err != nil {
+		return "", err
+	}
+	return info.Status, nil
+}
+
+// Pause pauses the vm by the QMP command "stop".
+func (l *LimaQemuDriver) Pause(_ context.Context) error {
+	logrus.Info("Sending QMP stop command")
+	return l.qmpCommand("stop", nil, nil)
+}
+
+// Resume resumes the vm by the QMP command "cont".
+func (l *LimaQemuDriver) Resume(_ context.Context) error {
+	logrus.Info("Sending QMP cont command")
+	return l.qmpCommand("cont", nil, nil)
+}
+
+// qmpCommand connects to the QMP socket of the instance, and runs the command with qmpRun.
+func (l *LimaQemuDriver) qmpCommand(command string, args, ret any) error {
+	qmpSockPath := filepath.Join(l.Instance.Dir, filenames.QMPSock)
+	qmpClient, err := qmp.NewSocketMonitor("unix", qmpSockPath, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	if err := qmpClient.Connect(); err != nil {
+		return err
+	}
+	defer func() { _ = qmpClient.Disconnect() }()
+	return qmpRun(qmpClient, command, args, ret)
+}
+
 func (l *LimaQemuDriver) removeVNCFiles() error {
 	vncfile := filepath.Join(l.Instance.Dir, filenames.VNCDisplayFile)
 	err := os.RemoveAll(vncfile)
@@ -295,13 +393,6 @@ func (l *LimaQemuDriver) killVhosts() error {
 func (l *LimaQemuDriver) shutdownQEMU(ctx context.Context, timeout time.Duration, qCmd *exec.Cmd, qWaitCh <-chan error) error {
 	// "power button" refers to ACPI on the most archs, except RISC-V
 	logrus.Info("Shutting down QEMU with the power button")
-	if usernetIndex := limayaml.FirstUsernetIndex(l.Instance.Config); usernetIndex != -1 {
-		client := usernet.NewClientByName(l.Instance.Config.Networks[usernetIndex].Lima)
-		err := client.UnExposeSSH(l.SSHLocalPort)
-		if err != nil {
-			logrus.Warnf("Failed to remove SSH binding for port %d", l.SSHLocalPort)
-		}
-	}
 	qmpSockPath := filepath.Join(l.Instance.Dir, filenames.QMPSock)
 	qmpClient, err := qmp.NewSocketMonitor("unix", qmpSockPath, 5*time.Second)
 	if err != nil {
@@ -331,7 +422,7 @@ func (l *LimaQemuDriver) shutdownQEMU(ctx context.Context, timeout time.Duration
 		return errors.Join(qWaitErr, l.killVhosts())
 	case <-deadline:
 	}
-	logrus.Warnf("QEMU did not exit in %v, forcibly killing QEMU", timeout)
+	logrus.Warnf("QEMU did not exit in %v, forcibly killing QEMU (forced stop)", timeout)
 	return l.killQEMU(ctx, timeout, qCmd, qWaitCh)
 }
 
@@ -366,7 +457,7 @@ func (l *LimaQemuDriver) DeleteSnapshot(_ context.Context, tag string) error {
 		InstanceDir: l.Instance.Dir,
 		LimaYAML:    l.Instance.Config,
 	}
-	return Del(qCfg, l.Instance.Status == store.StatusRunning, tag)
+	return Del(qCfg, isRunning(l.Instance), tag)
 }
 
 func (l *LimaQemuDriver) CreateSnapshot(_ context.Context, tag string) error {
@@ -375,7 +466,7 @@ func (l *LimaQemuDriver) CreateSnapshot(_ context.Context, tag string) error {
 		InstanceDir: l.Instance.Dir,
 		LimaYAML:    l.Instance.Config,
 	}
-	return Save(qCfg, l.Instance.Status == store.StatusRunning, tag)
+	return Save(qCfg, isRunning(l.Instance), tag)
 }
 
 func (l *LimaQemuDriver) ApplySnapshot(_ context.Context, tag string) error {
@@ -384,7 +475,7 @@ func (l *LimaQemuDriver) ApplySnapshot(_ context.Context, tag string) error {
 		InstanceDir: l.Instance.Dir,
 		LimaYAML:    l.Instance.Config,
 	}
-	return Load(qCfg, l.Instance.Status == store.StatusRunning, tag)
+	return Load(qCfg, isRunning(l.Instance), tag)
 }
 
 func (l *LimaQemuDriver) ListSnapshots(_ context.Context) (string, error) {
@@ -393,7 +484,7 @@ func (l *LimaQemuDriver) ListSnapshots(_ context.Context) (string, error) {
 		InstanceDir: l.Instance.Dir,
 		LimaYAML:    l.Instance.Config,
 	}
-	return List(qCfg, l.Instance.Status == store.StatusRunning)
+	return List(qCfg, isRunning(l.Instance))
 }
 
 func (l *LimaQemuDriver) GuestAgentConn(ctx context.Context) (net.Conn, error) {
@@ -453,3 +544,8 @@ func (a *qArgTemplateApplier) applyTemplate(qArg string) (string, error) {
 	}
 	return b.String(), nil
 }
+
+// isRunning returns true if the QEMU process of the instance is running, even when the vm is paused.
+func isRunning(inst *store.Instance) bool {
+	return inst.Status == store.StatusRunning || inst.Status == store.StatusPaused
+}
@@ -3,6 +3,7 @@ package qemu
 import (
 	"testing"
 
+	"github.com/lima-vm/lima/pkg/limayaml"
 	"gotest.tools/v3/assert"
 )
 
@@ -86,3 +87,12 @@ func TestParseQemuVersion(t *testing.T) {
 		assert.Equal(t, tc.expectedValue, v.String())
 	}
 }
+
+func TestSMPArg(t *testing.T) {
+	assert.Equal(t, smpArg(limayaml.X8664, 4, 8, true), "4,sockets=1,cores=8,threads=1,maxcpus=8")
+	assert.Equal(t, smpArg(limayaml.X8664, 4, 4, true), "4,sockets=1,cores=4,threads=1")
+	assert.Equal(t, smpArg(limayaml.X8664, 8, 4, true), "8,sockets=1,cores=8,threads=1")
+	assert.Equal(t, smpArg(limayaml.AARCH64, 4, 8, true), "4,sockets=1,cores=4,threads=1")
+	// The topology is not changed unless vmOpts.qemu.runtimeResize is enabled
+	assert.Equal(t, smpArg(limayaml.X8664, 4, 8, false), "4,sockets=1,cores=4,threads=1")
+}
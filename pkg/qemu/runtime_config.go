@@ -0,0 +1,242 @@
+package qemu
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/go-qemu/qmp"
+	"github.com/docker/go-units"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+// hotpluggedCPUIDPrefix is the prefix of the device IDs of the CPUs hot-added by RuntimeConfig.
+const hotpluggedCPUIDPrefix = "lima-cpu-"
+
+// runtimeResizeDisabledReason is the reason for rejecting the changes when `vmOpts.qemu.runtimeResize` is disabled.
+const runtimeResizeDisabledReason = "requires `vmOpts.qemu.runtimeResize: true` in lima.yaml (the instance has to be restarted after changing it)"
+
+// qmpHotpluggableCPU is an entry of the result of the QMP command "query-hotpluggable-cpus".
+type qmpHotpluggableCPU struct {
+	Type       string         `json:"type"`
+	VCPUsCount int            `json:"vcpus-count"`
+	Props      map[string]any `json:"props"`
+	// QOMPath is set when the CPU is present.
+	QOMPath string `json:"qom-path,omitempty"`
+}
+
+// qmpBalloonInfo is the result of the QMP command "query-balloon".
+type qmpBalloonInfo struct {
+	Actual int64 `json:"actual"`
+}
+
+// RuntimeConfig changes the number of the vCPUs by hot-plugging them, and the memory size by ballooning.
+//
+// The vCPUs can be hot-added up to the maximum of the `-smp` topology, and only the hot-added vCPUs can be removed.
+// The memory can be shrunk and grown back up to the memory size on boot.
+// The changes are not persisted to lima.yaml, and require `vmOpts.qemu.runtimeResize` to be enabled.
+func (l *LimaQemuDriver) RuntimeConfig(_ context.Context, config hostagentapi.DriverConfig) (hostagentapi.DriverConfig, error) {
+	if !*l.Instance.Config.VMOpts.QEMU.RuntimeResize {
+		if config.CPUs != nil {
+			return hostagentapi.DriverConfig{}, &hostagentapi.UnsupportedValueError{
+				Field:  "cpus",
+				Value:  strconv.Itoa(*config.CPUs),
+				Reason: runtimeResizeDisabledReason,
+			}
+		}
+		if config.Memory != nil {
+			return hostagentapi.DriverConfig{}, &hostagentapi.UnsupportedValueError{
+				Field:  "memory",
+				Value:  *config.Memory,
+				Reason: runtimeResizeDisabledReason,
+			}
+		}
+	}
+	qmpSockPath := filepath.Join(l.Instance.Dir, filenames.QMPSock)
+	qmpClient, err := qmp.NewSocketMonitor("unix", qmpSockPath, 5*time.Second)
+	if err != nil {
+		return hostagentapi.DriverConfig{}, err
+	}
+	if err := qmpClient.Connect(); err != nil {
+		return hostagentapi.DriverConfig{}, err
+	}
+	defer func() { _ = qmpClient.Disconnect() }()
+
+	if config.CPUs != nil {
+		if err := l.setCPUs(qmpClient, *config.CPUs); err != nil {
+			return hostagentapi.DriverConfig{}, err
+		}
+	}
+	if config.Memory != nil {
+		if err := l.setMemory(qmpClient, *config.Memory); err != nil {
+			return hostagentapi.DriverConfig{}, err
+		}
+	}
+
+	var effective hostagentapi.DriverConfig
+	var cpus []qmpHotpluggableCPU
+	if err := qmpRun(qmpClient, "query-hotpluggable-cpus", nil, &cpus); err != nil {
+		return hostagentapi.DriverConfig{}, err
+	}
+	presentCPUs := countPresentCPUs(cpus)
+	effective.CPUs = &presentCPUs
+	var balloon qmpBalloonInfo
+	if err := qmpRun(qmpClient, "query-balloon", nil, &balloon); err != nil {
+		// No balloon device, e.g., for the instance started by an older version of Lima
+		logrus.WithError(err).Debug("failed to query the balloon")
+		effective.Memory = l.Instance.Config.Memory
+	} else {
+		memory := units.BytesSize(float64(balloon.Actual))
+		effective.Memory = &memory
+	}
+	return effective, nil
+}
+
+func (l *LimaQemuDriver) setCPUs(qmpClient qmp.Monitor, target int) error {
+	var cpus []qmpHotpluggableCPU
+	if err := qmpRun(qmpClient, "query-hotpluggable-cpus", nil, &cpus); err != nil {
+		return err
+	}
+	add, del, err := planCPUHotplug(cpus, target)
+	if err != nil {
+		return err
+	}
+	for _, i := range add {
+		cpu := cpus[i]
+		id := hotpluggedCPUIDPrefix + strconv.Itoa(i)
+		args := map[string]any{
+			"driver": cpu.Type,
+			"id":     id,
+		}
+		for k, v := range cpu.Props {
+			args[k] = v
+		}
+		logrus.Infof("Adding vCPU %q", id)
+		if err := qmpRun(qmpClient, "device_add", args, nil); err != nil {
+			return fmt.Errorf("failed to add vCPU %q: %w", id, err)
+		}
+	}
+	for _, id := range del {
+		// The vCPU is removed asynchronously, after the guest acknowledges the removal
+		logrus.Infof("Removing vCPU %q", id)
+		if err := qmpRun(qmpClient, "device_del", map[string]any{"id": id}, nil); err != nil {
+			return fmt.Errorf("failed to remove vCPU %q: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (l *LimaQemuDriver) setMemory(qmpClient qmp.Monitor, memory string) error {
+	target, err := units.RAMInBytes(memory)
+	if err != nil {
+		return err
+	}
+	bootMemory, err := units.RAMInBytes(*l.Instance.Config.Memory)
+	if err != nil {
+		return err
+	}
+	if target > bootMemory {
+		return &hostagentapi.UnsupportedValueError{
+			Field:  "memory",
+			Value:  memory,
+			Reason: fmt.Sprintf("must not exceed the memory size on boot (%s)", *l.Instance.Config.Memory),
+		}
+	}
+	logrus.Infof("Changing the memory size to %s by ballooning", memory)
+	if err := qmpRun(qmpClient, "balloon", map[string]any{"value": target}, nil); err != nil {
+		return fmt.Errorf("failed to change the memory size (hint: the instance has to be restarted to enable the balloon device): %w", err)
+	}
+	return nil
+}
+
+// planCPUHotplug returns the indices of the vCPUs to be added, and the device IDs of the vCPUs to be removed,
+// for changing the number of the present vCPUs to target.
+// Only the vCPUs hot-added by RuntimeConfig can be removed.
+func planCPUHotplug(cpus []qmpHotpluggableCPU, target int) (add []int, del []string, _ error) {
+	present := countPresentCPUs(cpus)
+	minCPUs, maxCPUs := present, present
+	var removable []qmpHotpluggableCPU
+	for _, cpu := range cpus {
+		switch {
+		case cpu.QOMPath == "":
+			maxCPUs += cpu.VCPUsCount
+		case strings.HasPrefix(filepath.Base(cpu.QOMPath), hotpluggedCPUIDPrefix):
+			minCPUs -= cpu.VCPUsCount
+			removable = append(removable, cpu)
+		}
+	}
+	if target < minCPUs || target > maxCPUs {
+		return nil, nil, &hostagentapi.UnsupportedValueError{
+			Field:  "cpus",
+			Value:  strconv.Itoa(target),
+			Reason: fmt.Sprintf("must be between %d and %d", minCPUs, maxCPUs),
+		}
+	}
+	for i, cpu := range cpus {
+		if present >= target {
+			break
+		}
+		if cpu.QOMPath == "" {
+			add = append(add, i)
+			present += cpu.VCPUsCount
+		}
+	}
+	// Remove the most recently added ones first
+	for i := len(removable) - 1; i >= 0 && present > target; i-- {
+		del = append(del, filepath.Base(removable[i].QOMPath))
+		present -= removable[i].VCPUsCount
+	}
+	if present != target {
+		return nil, nil, &hostagentapi.UnsupportedValueError{
+			Field:  "cpus",
+			Value:  strconv.Itoa(target),
+			Reason: "must be a multiple of the number of the vCPUs per hot-pluggable unit",
+		}
+	}
+	return add, del, nil
+}
+
+func countPresentCPUs(cpus []qmpHotpluggableCPU) int {
+	var n int
+	for _, cpu := range cpus {
+		if cpu.QOMPath != "" {
+			n += cpu.VCPUsCount
+		}
+	}
+	return n
+}
+
+// qmpRun runs the QMP command, and decodes the "return" value into ret, unless ret is nil.
+func qmpRun(mon qmp.Monitor, command string, args, ret any) error {
+	req := struct {
+		Execute   string `json:"execute"`
+		Arguments any    `json:"arguments,omitempty"`
+	}{
+		Execute:   command,
+		Arguments: args,
+	}
+	b, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	resp, err := mon.Run(b)
+	if err != nil {
+		return err
+	}
+	if ret == nil {
+		return nil
+	}
+	var v struct {
+		Return json.RawMessage `json:"return"`
+	}
+	if err := json.Unmarshal(resp, &v); err != nil {
+		return err
+	}
+	return json.Unmarshal(v.Return, ret)
+}
@@ -0,0 +1,64 @@
+package qemu
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/driver"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store"
+	"gotest.tools/v3/assert"
+)
+
+func TestPlanCPUHotplug(t *testing.T) {
+	// 2 vCPUs present on boot, 1 vCPU hot-added, 1 vCPU not present
+	cpus := []qmpHotpluggableCPU{
+		{Type: "host-x86_64-cpu", VCPUsCount: 1, Props: map[string]any{"core-id": 3}},
+		{Type: "host-x86_64-cpu", VCPUsCount: 1, Props: map[string]any{"core-id": 2}, QOMPath: "/machine/peripheral/lima-cpu-1"},
+		{Type: "host-x86_64-cpu", VCPUsCount: 1, Props: map[string]any{"core-id": 1}, QOMPath: "/machine/unattached/device[1]"},
+		{Type: "host-x86_64-cpu", VCPUsCount: 1, Props: map[string]any{"core-id": 0}, QOMPath: "/machine/unattached/device[0]"},
+	}
+	assert.Equal(t, countPresentCPUs(cpus), 3)
+
+	add, del, err := planCPUHotplug(cpus, 3)
+	assert.NilError(t, err)
+	assert.Equal(t, len(add), 0)
+	assert.Equal(t, len(del), 0)
+
+	add, del, err = planCPUHotplug(cpus, 4)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, add, []int{0})
+	assert.Equal(t, len(del), 0)
+
+	add, del, err = planCPUHotplug(cpus, 2)
+	assert.NilError(t, err)
+	assert.Equal(t, len(add), 0)
+	assert.DeepEqual(t, del, []string{"lima-cpu-1"})
+
+	for _, target := range []int{1, 5} {
+		_, _, err = planCPUHotplug(cpus, target)
+		var valueErr *hostagentapi.UnsupportedValueError
+		assert.Assert(t, errors.As(err, &valueErr), err)
+		assert.Equal(t, valueErr.Field, "cpus")
+		assert.Equal(t, valueErr.Reason, "must be between 2 and 4")
+	}
+}
+
+func TestRuntimeConfigRuntimeResizeDisabled(t *testing.T) {
+	l := &LimaQemuDriver{BaseDriver: &driver.BaseDriver{Instance: &store.Instance{
+		Dir: t.TempDir(),
+		Config: &limayaml.LimaYAML{
+			VMOpts: limayaml.VMOpts{QEMU: limayaml.QEMUOpts{RuntimeResize: ptr.Of(false)}},
+		},
+	}}}
+	var unsupported *hostagentapi.UnsupportedValueError
+	_, err := l.RuntimeConfig(context.Background(), hostagentapi.DriverConfig{CPUs: ptr.Of(4)})
+	assert.Assert(t, errors.As(err, &unsupported), "%v", err)
+	assert.Equal(t, unsupported.Field, "cpus")
+	_, err = l.RuntimeConfig(context.Background(), hostagentapi.DriverConfig{Memory: ptr.Of("2GiB")})
+	assert.Assert(t, errors.As(err, &unsupported), "%v", err)
+	assert.Equal(t, unsupported.Field, "memory")
+}
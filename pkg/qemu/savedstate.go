@@ -0,0 +1,134 @@
+package qemu
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"al.essio.dev/pkg/shellescape"
+	"github.com/digitalocean/go-qemu/qmp"
+	"github.com/lima-vm/lima/pkg/store"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+// restoreStateTimeout is the timeout for loading the saved state on Start.
+const restoreStateTimeout = 10 * time.Minute
+
+// qmpMigrationInfo is the result of the QMP command "query-migrate".
+type qmpMigrationInfo struct {
+	Status    string `json:"status"`
+	ErrorDesc string `json:"error-desc,omitempty"`
+}
+
+// SaveState saves the state of the vm by migrating it to the savedstate file.
+// The vm remains in the "postmigrate" run state, until QEMU is killed by Stop.
+func (l *LimaQemuDriver) SaveState(ctx context.Context) error {
+	statePath := filepath.Join(l.Instance.Dir, filenames.SavedState)
+	tmpPath := statePath + ".tmp"
+	qmpSockPath := filepath.Join(l.Instance.Dir, filenames.QMPSock)
+	qmpClient, err := qmp.NewSocketMonitor("unix", qmpSockPath, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	if err := qmpClient.Connect(); err != nil {
+		return err
+	}
+	defer func() { _ = qmpClient.Disconnect() }()
+
+	logrus.Infof("Saving the state of the vm to %q", statePath)
+	uri := "exec:cat >" + shellescape.Quote(tmpPath)
+	if err := qmpRun(qmpClient, "migrate", map[string]any{"uri": uri}, nil); err != nil {
+		return err
+	}
+	if err := waitMigration(ctx, qmpClient); err != nil {
+		_ = qmpRun(qmpClient, "migrate_cancel", nil, nil)
+		_ = os.RemoveAll(tmpPath)
+		return fmt.Errorf("failed to save the state of the vm: %w", err)
+	}
+	if err := os.Rename(tmpPath, statePath); err != nil {
+		return err
+	}
+	l.stateSaved = true
+	return nil
+}
+
+// restoreState loads the saved state into QEMU launched with `-incoming defer`, and resumes the vm.
+// The saved state is removed regardless of the result, as it becomes stale once the vm has been running.
+func (l *LimaQemuDriver) restoreState(ctx context.Context) error {
+	defer func() {
+		if err := store.RemoveSavedState(l.Instance.Dir); err != nil {
+			logrus.WithError(err).Warn("Failed to remove the saved state")
+		}
+	}()
+	ctx, cancel := context.WithTimeout(ctx, restoreStateTimeout)
+	defer cancel()
+	statePath := filepath.Join(l.Instance.Dir, filenames.SavedState)
+	qmpSockPath := filepath.Join(l.Instance.Dir, filenames.QMPSock)
+	if err := waitFileExists(qmpSockPath, 30*time.Second); err != nil {
+		return err
+	}
+	qmpClient, err := qmp.NewSocketMonitor("unix", qmpSockPath, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	if err := qmpClient.Connect(); err != nil {
+		return err
+	}
+	defer func() { _ = qmpClient.Disconnect() }()
+
+	logrus.Infof("Restoring the state of the vm from %q", statePath)
+	uri := "exec:cat " + shellescape.Quote(statePath)
+	if err := qmpRun(qmpClient, "migrate-incoming", map[string]any{"uri": uri}, nil); err != nil {
+		return err
+	}
+	if err := waitMigration(ctx, qmpClient); err != nil {
+		return err
+	}
+	var status qmpStatusInfo
+	if err := qmpRun(qmpClient, "query-status", nil, &status); err != nil {
+		return err
+	}
+	if !status.Running {
+		return qmpRun(qmpClient, "cont", nil, nil)
+	}
+	return nil
+}
+
+// waitMigration polls the status of the migration until it completes.
+func waitMigration(ctx context.Context, qmpClient qmp.Monitor) error {
+	for {
+		var info qmpMigrationInfo
+		if err := qmpRun(qmpClient, "query-migrate", nil, &info); err != nil {
+			return err
+		}
+		done, err := migrationDone(info)
+		if done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// migrationDone returns true when the migration is no longer in progress,
+// with an error unless the migration has completed successfully.
+func migrationDone(info qmpMigrationInfo) (bool, error) {
+	switch info.Status {
+	case "completed":
+		return true, nil
+	case "failed", "cancelled":
+		if info.ErrorDesc != "" {
+			return true, fmt.Errorf("migration %s: %s", info.Status, info.ErrorDesc)
+		}
+		return true, fmt.Errorf("migration %s", info.Status)
+	default:
+		// "none", "setup", "active", etc.
+		return false, nil
+	}
+}
@@ -0,0 +1,27 @@
+package qemu
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestMigrationDone(t *testing.T) {
+	for _, status := range []string{"none", "setup", "active", "device"} {
+		done, err := migrationDone(qmpMigrationInfo{Status: status})
+		assert.Assert(t, !done, status)
+		assert.NilError(t, err, status)
+	}
+
+	done, err := migrationDone(qmpMigrationInfo{Status: "completed"})
+	assert.Assert(t, done)
+	assert.NilError(t, err)
+
+	done, err = migrationDone(qmpMigrationInfo{Status: "failed", ErrorDesc: "No space left on device"})
+	assert.Assert(t, done)
+	assert.Error(t, err, "migration failed: No space left on device")
+
+	done, err = migrationDone(qmpMigrationInfo{Status: "cancelled"})
+	assert.Assert(t, done)
+	assert.Error(t, err, "migration cancelled")
+}
@@ -0,0 +1,132 @@
+package sshutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/lima-vm/lima/pkg/identifierutil"
+	"github.com/lima-vm/lima/pkg/ioutilx"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
+)
+
+// HostKeyAlias returns the alias of the instance host key in the known_hosts file of the instance.
+// The alias is used instead of the address, as the SSH port of the instance may change on every start.
+func HostKeyAlias(instDir string) string {
+	return identifierutil.HostnameFromInstName(filepath.Base(instDir))
+}
+
+// EnsureHostKey generates the SSH host key of the instance, unless it already exists.
+//
+// The host key has to be injected into the guest via cidata. It is not checked until
+// ConfirmHostKey confirms that the guest actually presents it.
+func EnsureHostKey(instDir string) (created bool, err error) {
+	keyPath := filepath.Join(instDir, filenames.SSHHostKey)
+	if _, err := os.Stat(keyPath); err == nil {
+		return false, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	// The known_hosts file of the previous host key is stale
+	if err := os.RemoveAll(filepath.Join(instDir, filenames.SSHKnownHosts)); err != nil {
+		return false, err
+	}
+	// no passphrase
+	keygenCmd := exec.Command("ssh-keygen", "-t", "ed25519", "-q", "-N", "",
+		"-C", HostKeyAlias(instDir), "-f", keyPath)
+	logrus.Debugf("executing %v", keygenCmd.Args)
+	if out, err := keygenCmd.CombinedOutput(); err != nil {
+		return false, fmt.Errorf("failed to run %v: %q: %w", keygenCmd.Args, string(out), err)
+	}
+	return true, nil
+}
+
+// ConfirmHostKey checks with ssh-keyscan whether the guest presents the host key generated by EnsureHostKey,
+// and writes the known_hosts file of the instance if so, so that the host key is strictly checked from then on.
+// It returns true when the known_hosts file exists.
+//
+// Some guests do not apply the host key injected via cidata (e.g., Alpine with lima-init),
+// so their host key is never confirmed, and not checked.
+func ConfirmHostKey(ctx context.Context, instDir, address string, port int) (bool, error) {
+	knownHosts := filepath.Join(instDir, filenames.SSHKnownHosts)
+	if _, err := os.Stat(knownHosts); err == nil {
+		return true, nil
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return false, err
+	}
+	pubKey, err := os.ReadFile(filepath.Join(instDir, filenames.SSHHostKeyPub))
+	if err != nil {
+		return false, err
+	}
+	line, err := knownHostsLine(HostKeyAlias(instDir), string(pubKey))
+	if err != nil {
+		return false, err
+	}
+	keyscanCmd := exec.CommandContext(ctx, "ssh-keyscan", "-T", "10", "-t", "ed25519", "-p", strconv.Itoa(port), address)
+	logrus.Debugf("executing %v", keyscanCmd.Args)
+	out, err := keyscanCmd.Output()
+	if err != nil {
+		return false, fmt.Errorf("failed to run %v: %w", keyscanCmd.Args, err)
+	}
+	if !presentsHostKey(string(out), string(pubKey)) {
+		return false, nil
+	}
+	return true, os.WriteFile(knownHosts, []byte(line+"\n"), 0o644)
+}
+
+// presentsHostKey returns whether the output of ssh-keyscan ("HOST TYPE BASE64" lines) contains the public key.
+func presentsHostKey(keyscanOutput, pubKey string) bool {
+	want := strings.Fields(pubKey)
+	if len(want) < 2 {
+		return false
+	}
+	for _, line := range strings.Split(keyscanOutput, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[1] == want[0] && fields[2] == want[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// knownHostsLine returns the known_hosts line of the public key ("TYPE BASE64 [COMMENT]") for the host.
+// The comment is dropped.
+func knownHostsLine(host, pubKey string) (string, error) {
+	pubKey = strings.TrimSpace(pubKey)
+	fields := strings.Fields(pubKey)
+	if len(fields) < 2 || !detectValidPublicKey(pubKey) {
+		return "", fmt.Errorf("host key %q doesn't seem to be in ssh format", pubKey)
+	}
+	return host + " " + fields[0] + " " + fields[1], nil
+}
+
+// knownHostsOpts returns the options for strictly checking the host key with the known_hosts file of the instance,
+// or nil when the file does not exist, i.e., the host key is not confirmed by ConfirmHostKey yet.
+func knownHostsOpts(instDir string) ([]string, error) {
+	knownHosts := filepath.Join(instDir, filenames.SSHKnownHosts)
+	if _, err := os.Stat(knownHosts); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	userKnownHostsFile := fmt.Sprintf(`UserKnownHostsFile="%s"`, knownHosts)
+	if runtime.GOOS == "windows" {
+		userKnownHostsFile = fmt.Sprintf(`UserKnownHostsFile='%s'`, ioutilx.CanonicalWindowsPath(knownHosts))
+	}
+	return []string{
+		"StrictHostKeyChecking=yes",
+		userKnownHostsFile,
+		"HostKeyAlias=" + HostKeyAlias(instDir),
+	}, nil
+}
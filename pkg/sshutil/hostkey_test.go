@@ -0,0 +1,93 @@
+package sshutil
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+// A valid ed25519 public key, taken from the output of ssh-keygen
+const testHostPubKey = "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIIBAZ61rIM2jJ2tqvOzDNS4ZfSIYYyv0Ujl4oJm8/rMo lima-default"
+
+func TestKnownHostsLine(t *testing.T) {
+	line, err := knownHostsLine("lima-default", testHostPubKey+"\n")
+	assert.NilError(t, err)
+	assert.Equal(t, line, "lima-default ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIIBAZ61rIM2jJ2tqvOzDNS4ZfSIYYyv0Ujl4oJm8/rMo")
+
+	for _, pubKey := range []string{"", "ssh-ed25519", "ssh-ed25519 !!!", "ssh-rsa AAAAC3NzaC1lZDI1NTE5AAAAIIBAZ61rIM2jJ2tqvOzDNS4ZfSIYYyv0Ujl4oJm8/rMo"} {
+		_, err := knownHostsLine("lima-default", pubKey)
+		assert.ErrorContains(t, err, "doesn't seem to be in ssh format", pubKey)
+	}
+}
+
+func TestEnsureHostKey(t *testing.T) {
+	if _, err := exec.LookPath("ssh-keygen"); err != nil {
+		t.Skip("ssh-keygen is not installed")
+	}
+	instDir := filepath.Join(t.TempDir(), "default")
+	assert.NilError(t, os.MkdirAll(instDir, 0o700))
+
+	created, err := EnsureHostKey(instDir)
+	assert.NilError(t, err)
+	assert.Assert(t, created)
+	pubKey, err := os.ReadFile(filepath.Join(instDir, filenames.SSHHostKeyPub))
+	assert.NilError(t, err)
+	fields := strings.Fields(string(pubKey))
+	assert.Equal(t, len(fields), 3)
+	// The known_hosts file is only written by ConfirmHostKey
+	_, err = os.Stat(filepath.Join(instDir, filenames.SSHKnownHosts))
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+
+	// The existing host key is kept
+	created, err = EnsureHostKey(instDir)
+	assert.NilError(t, err)
+	assert.Assert(t, !created)
+	pubKey2, err := os.ReadFile(filepath.Join(instDir, filenames.SSHHostKeyPub))
+	assert.NilError(t, err)
+	assert.Equal(t, string(pubKey2), string(pubKey))
+}
+
+func TestPresentsHostKey(t *testing.T) {
+	keyscanOutput := `# 127.0.0.1:60022 SSH-2.0-OpenSSH_9.6
+[127.0.0.1]:60022 ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIIBAZ61rIM2jJ2tqvOzDNS4ZfSIYYyv0Ujl4oJm8/rMo
+`
+	assert.Assert(t, presentsHostKey(keyscanOutput, testHostPubKey))
+	// e.g., the guest ignores the injected host key
+	assert.Assert(t, !presentsHostKey(keyscanOutput, "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAIOtherKeyOtherKeyOtherKeyOtherKeyOtherKey12 lima-default"))
+	assert.Assert(t, !presentsHostKey("", testHostPubKey))
+}
+
+func TestSSHOptsWithKnownHosts(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	instDir := filepath.Join(limaHome, "default")
+	assert.NilError(t, os.MkdirAll(instDir, 0o700))
+
+	opts, err := SSHOpts("ssh", instDir, "foo")
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "StrictHostKeyChecking=no"))
+	assert.Assert(t, !slices.ContainsFunc(opts, func(o string) bool { return strings.HasPrefix(o, "HostKeyAlias=") }))
+
+	line, err := knownHostsLine(HostKeyAlias(instDir), testHostPubKey)
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.SSHKnownHosts), []byte(line+"\n"), 0o644))
+	opts, err = SSHOpts("ssh", instDir, "foo")
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "StrictHostKeyChecking=yes"))
+	assert.Assert(t, slices.Contains(opts, "HostKeyAlias=lima-default"))
+	assert.Assert(t, slices.ContainsFunc(opts, func(o string) bool {
+		return strings.HasPrefix(o, "UserKnownHostsFile=") && strings.Contains(o, filenames.SSHKnownHosts)
+	}))
+	for _, o := range []string{"StrictHostKeyChecking=no", "UserKnownHostsFile=/dev/null", "NoHostAuthenticationForLocalhost=yes"} {
+		assert.Assert(t, !slices.Contains(opts, o), o)
+	}
+}
@@ -0,0 +1,57 @@
+package sshutil
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+)
+
+// Environment variable that specifies the SSH jump host for connecting to the instances
+// running on a remote host, in the syntax of ProxyJump ("[USER@]HOST[:PORT]").
+const EnvSSHJumpHost = "LIMA_SSH_JUMP_HOST"
+
+const jumpHostDialTimeout = 10 * time.Second
+
+// jumpHostAddress returns the TCP address of the first hop of jumpHost.
+// jumpHost is in the syntax of ProxyJump: "[USER@]HOST[:PORT]" or "ssh://[USER@]HOST[:PORT]", separated by commas.
+func jumpHostAddress(jumpHost string) (string, error) {
+	if jumpHost == "" {
+		return "", errors.New("jump host must not be empty")
+	}
+	hop, _, _ := strings.Cut(jumpHost, ",")
+	hop = strings.TrimPrefix(hop, "ssh://")
+	if i := strings.LastIndex(hop, "@"); i >= 0 {
+		hop = hop[i+1:]
+	}
+	if hop == "" {
+		return "", fmt.Errorf("jump host %q has no host", jumpHost)
+	}
+	host, port, err := net.SplitHostPort(hop)
+	if err != nil {
+		// No port (HOST), or a bare IPv6 address
+		host, port = strings.TrimSuffix(strings.TrimPrefix(hop, "["), "]"), "22"
+	}
+	if host == "" || strings.ContainsAny(host, "[]/ ") {
+		return "", fmt.Errorf("jump host %q has an invalid host %q", jumpHost, host)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// CheckJumpHost checks that the first hop of jumpHost is reachable.
+//
+// The host is resolved without ~/.ssh/config, as the ssh commands are executed with "-F /dev/null".
+func CheckJumpHost(ctx context.Context, jumpHost string) error {
+	addr, err := jumpHostAddress(jumpHost)
+	if err != nil {
+		return err
+	}
+	dialer := net.Dialer{Timeout: jumpHostDialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return fmt.Errorf("jump host %q is not reachable (hint: check the host and the port, and note that the host aliases in ~/.ssh/config are not available): %w", jumpHost, err)
+	}
+	return conn.Close()
+}
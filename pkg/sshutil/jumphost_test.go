@@ -0,0 +1,43 @@
+package sshutil
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestJumpHostAddress(t *testing.T) {
+	for jumpHost, expected := range map[string]string{
+		"example.com":                  "example.com:22",
+		"foo@example.com":              "example.com:22",
+		"foo@example.com:2222":         "example.com:2222",
+		"ssh://foo@example.com:2222":   "example.com:2222",
+		"example.com,foo@example.org":  "example.com:22",
+		"192.168.5.1:2222":             "192.168.5.1:2222",
+		"::1":                          "[::1]:22",
+		"[::1]":                        "[::1]:22",
+		"foo@[2001:db8::1]:2222":       "[2001:db8::1]:2222",
+		"foo@bar@example.com":          "example.com:22",
+		"ssh://example.com,other:2222": "example.com:22",
+	} {
+		addr, err := jumpHostAddress(jumpHost)
+		assert.NilError(t, err, jumpHost)
+		assert.Equal(t, addr, expected, jumpHost)
+	}
+	for _, jumpHost := range []string{"", "foo@", ",example.com", "ssh://", "[::1]x"} {
+		_, err := jumpHostAddress(jumpHost)
+		assert.Assert(t, err != nil, jumpHost)
+	}
+}
+
+func TestCheckJumpHost(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NilError(t, err)
+	addr := l.Addr().String()
+	assert.NilError(t, CheckJumpHost(context.Background(), "foo@"+addr))
+
+	assert.NilError(t, l.Close())
+	assert.ErrorContains(t, CheckJumpHost(context.Background(), "foo@"+addr), "is not reachable")
+}
@@ -1,7 +1,6 @@
 package sshutil
 
 import (
-	"bytes"
 	"encoding/base64"
 	"encoding/binary"
 	"errors"
@@ -12,12 +11,15 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
 	"github.com/lima-vm/lima/pkg/ioutilx"
+	"github.com/lima-vm/lima/pkg/localpathutil"
 	"github.com/lima-vm/lima/pkg/lockutil"
 	"github.com/lima-vm/lima/pkg/osutil"
 	"github.com/lima-vm/lima/pkg/store/dirnames"
@@ -106,6 +108,16 @@ func DefaultPubKeys(loadDotSSH bool) ([]PubKey, error) {
 			return nil, err
 		}
 	}
+	return ExistingPubKeys(loadDotSSH)
+}
+
+// ExistingPubKeys is similar to DefaultPubKeys, but does not create $LIMA_HOME/_config/user.pub.
+// The returned error wraps os.ErrNotExist when the key does not exist yet.
+func ExistingPubKeys(loadDotSSH bool) ([]PubKey, error) {
+	configDir, err := dirnames.LimaConfigDir()
+	if err != nil {
+		return nil, err
+	}
 	entry, err := readPublicKey(filepath.Join(configDir, filenames.UserPublicKey))
 	if err != nil {
 		return nil, err
@@ -143,6 +155,62 @@ func DefaultPubKeys(loadDotSSH bool) ([]PubKey, error) {
 	return res, nil
 }
 
+var inlinePubKeyRegexp = regexp.MustCompile(`^(ssh|ecdsa|sk)-[a-z0-9@.-]+\s`)
+
+// IsInlinePubKey returns true if the `ssh.additionalAuthorizedKeys` entry seems to be an inline public key
+// rather than a file path.
+func IsInlinePubKey(entry string) bool {
+	return inlinePubKeyRegexp.MatchString(strings.TrimSpace(entry))
+}
+
+// ValidateAuthorizedKey validates a `ssh.additionalAuthorizedKeys` entry.
+// Inline public keys are parsed. Files are not read, as they might not exist yet.
+func ValidateAuthorizedKey(entry string) error {
+	if IsInlinePubKey(entry) {
+		if !detectValidPublicKey(strings.TrimSpace(entry)) {
+			return fmt.Errorf("public key %q doesn't seem to be in ssh format", entry)
+		}
+		return nil
+	}
+	_, err := localpathutil.Expand(entry)
+	return err
+}
+
+// AuthorizedKeys returns the public keys specified by `ssh.additionalAuthorizedKeys` entries.
+// Each entry is either an inline public key, or a path of a file containing one public key per line.
+func AuthorizedKeys(entries []string) ([]PubKey, error) {
+	var res []PubKey
+	for _, entry := range entries {
+		if IsInlinePubKey(entry) {
+			content := strings.TrimSpace(entry)
+			if !detectValidPublicKey(content) {
+				return nil, fmt.Errorf("public key %q doesn't seem to be in ssh format", entry)
+			}
+			res = append(res, PubKey{Content: content})
+			continue
+		}
+		f, err := localpathutil.Expand(entry)
+		if err != nil {
+			return nil, err
+		}
+		fileEntry, err := readPublicKey(f)
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(fileEntry.Content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			if !detectValidPublicKey(line) {
+				return nil, fmt.Errorf("public key file %q contains a line that doesn't seem to be in ssh format: %q", f, line)
+			}
+			res = append(res, PubKey{Filename: f, Content: line})
+		}
+	}
+	return res, nil
+}
+
 var sshInfo struct {
 	sync.Once
 	// aesAccelerated is set to true when AES acceleration is available.
@@ -225,7 +293,11 @@ func CommonOpts(sshPath string, useDotSSH bool) ([]string, error) {
 
 	sshInfo.Do(func() {
 		sshInfo.aesAccelerated = detectAESAcceleration()
-		sshInfo.openSSHVersion = DetectOpenSSHVersion(sshPath)
+		var err error
+		sshInfo.openSSHVersion, err = DetectOpenSSHVersion(sshPath)
+		if err != nil {
+			logrus.WithError(err).Warn("Failed to detect the OpenSSH version")
+		}
 	})
 
 	// Only OpenSSH version 8.1 and later support adding ciphers to the front of the default set
@@ -253,37 +325,186 @@ func CommonOpts(sshPath string, useDotSSH bool) ([]string, error) {
 	return opts, nil
 }
 
+type sshOptsOptions struct {
+	useDotSSH         bool
+	forwardAgent      bool
+	forwardX11        bool
+	forwardX11Trusted bool
+	noControlMaster   bool
+	jumpHost          string
+	keepAliveInterval int
+	keepAliveCountMax int
+	extraOpts         []string
+}
+
+// SSHOptsOpt is an option for SSHOpts.
+type SSHOptsOpt func(*sshOptsOptions) error
+
+// WithDotSSH specifies to use the private keys in ~/.ssh in addition to $LIMA_HOME/_config/user .
+func WithDotSSH(b bool) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.useDotSSH = b
+		return nil
+	}
+}
+
+// WithForwardAgent specifies to forward the connection to the ssh agent of the host ($SSH_AUTH_SOCK).
+//
+// Note that the users who can access the agent socket in the guest can use the keys of the host,
+// as long as the connection is open.
+func WithForwardAgent(b bool) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.forwardAgent = b
+		return nil
+	}
+}
+
+// WithForwardX11 specifies to forward X11.
+func WithForwardX11(b bool) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.forwardX11 = b
+		return nil
+	}
+}
+
+// WithForwardX11Trusted specifies to trust the forwarded X11 clients.
+func WithForwardX11Trusted(b bool) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.forwardX11Trusted = b
+		return nil
+	}
+}
+
+// WithoutControlMaster specifies not to share the multiplexed connection of the instance.
+//
+// The forwarding of the agent and X11 is only available via the multiplexed connection
+// when the master connection was opened with the forwarding, so WithoutControlMaster is needed
+// for enabling the forwarding that is not enabled in lima.yaml.
+func WithoutControlMaster() SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.noControlMaster = true
+		return nil
+	}
+}
+
+// WithJumpHost specifies to connect to the instance via the jump host ("[USER@]HOST[:PORT]"),
+// for the instances running on a remote host. The instance is connected at its SSH address and port
+// as seen from the jump host.
+//
+// The multiplexed connection of the instance is not shared, as its socket is on the remote host.
+func WithJumpHost(jumpHost string) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		if jumpHost == "" {
+			return nil
+		}
+		if _, err := jumpHostAddress(jumpHost); err != nil {
+			return err
+		}
+		o.jumpHost = jumpHost
+		o.noControlMaster = true
+		return nil
+	}
+}
+
+// WithKeepAlive specifies to send the keepalive messages to the guest every interval seconds,
+// and to disconnect after countMax messages without any response.
+// interval 0 disables the keepalive messages.
+//
+// The keepalive messages are only sent by the master connection, when the connection is multiplexed.
+func WithKeepAlive(interval, countMax int) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		if interval < 0 {
+			return fmt.Errorf("keepalive interval must not be negative, got %d", interval)
+		}
+		if interval > 0 && countMax < 1 {
+			return fmt.Errorf("keepalive count max must be positive, got %d", countMax)
+		}
+		o.keepAliveInterval = interval
+		o.keepAliveCountMax = countMax
+		return nil
+	}
+}
+
+// WithOptions appends the ssh options (KEY=VALUE), e.g., "RequestTTY=yes".
+func WithOptions(opts ...string) SSHOptsOpt {
+	return func(o *sshOptsOptions) error {
+		o.extraOpts = append(o.extraOpts, opts...)
+		return nil
+	}
+}
+
 // SSHOpts adds the following options to CommonOptions: User, ControlMaster, ControlPath, ControlPersist.
-func SSHOpts(sshPath, instDir, username string, useDotSSH, forwardAgent, forwardX11, forwardX11Trusted bool) ([]string, error) {
+// When the known_hosts file of the instance exists (see ConfirmHostKey), the host key is checked strictly with it.
+func SSHOpts(sshPath, instDir, username string, opts ...SSHOptsOpt) ([]string, error) {
+	var o sshOptsOptions
+	for _, f := range opts {
+		if err := f(&o); err != nil {
+			return nil, err
+		}
+	}
 	controlSock := filepath.Join(instDir, filenames.SSHSock)
 	if len(controlSock) >= osutil.UnixPathMax {
 		return nil, fmt.Errorf("socket path %q is too long: >= UNIX_PATH_MAX=%d", controlSock, osutil.UnixPathMax)
 	}
-	opts, err := CommonOpts(sshPath, useDotSSH)
+	res, err := CommonOpts(sshPath, o.useDotSSH)
 	if err != nil {
 		return nil, err
 	}
-	controlPath := fmt.Sprintf(`ControlPath="%s"`, controlSock)
-	if runtime.GOOS == "windows" {
-		controlSock = ioutilx.CanonicalWindowsPath(controlSock)
-		controlPath = fmt.Sprintf(`ControlPath='%s'`, controlSock)
+	hostKeyOpts, err := knownHostsOpts(instDir)
+	if err != nil {
+		return nil, err
 	}
-	opts = append(opts,
+	if len(hostKeyOpts) > 0 {
+		// The host key of the instance is known, so it can be checked strictly
+		res = slices.DeleteFunc(res, func(opt string) bool {
+			switch opt {
+			case "StrictHostKeyChecking=no", "UserKnownHostsFile=/dev/null", "NoHostAuthenticationForLocalhost=yes":
+				return true
+			}
+			return false
+		})
+		res = append(res, hostKeyOpts...)
+	}
+	res = append(res,
 		fmt.Sprintf("User=%s", username), // guest and host have the same username, but we should specify the username explicitly (#85)
-		"ControlMaster=auto",
-		controlPath,
-		"ControlPersist=yes",
 	)
-	if forwardAgent {
-		opts = append(opts, "ForwardAgent=yes")
+	if o.noControlMaster {
+		res = append(res,
+			"ControlMaster=no",
+			"ControlPath=none",
+		)
+	} else {
+		controlPath := fmt.Sprintf(`ControlPath="%s"`, controlSock)
+		if runtime.GOOS == "windows" {
+			controlSock = ioutilx.CanonicalWindowsPath(controlSock)
+			controlPath = fmt.Sprintf(`ControlPath='%s'`, controlSock)
+		}
+		res = append(res,
+			"ControlMaster=auto",
+			controlPath,
+			"ControlPersist=yes",
+		)
 	}
-	if forwardX11 {
-		opts = append(opts, "ForwardX11=yes")
+	if o.jumpHost != "" {
+		res = append(res, fmt.Sprintf("ProxyJump=%s", o.jumpHost))
 	}
-	if forwardX11Trusted {
-		opts = append(opts, "ForwardX11Trusted=yes")
+	if o.forwardAgent {
+		res = append(res, "ForwardAgent=yes")
 	}
-	return opts, nil
+	if o.forwardX11 {
+		res = append(res, "ForwardX11=yes")
+	}
+	if o.forwardX11Trusted {
+		res = append(res, "ForwardX11Trusted=yes")
+	}
+	if o.keepAliveInterval > 0 {
+		res = append(res,
+			fmt.Sprintf("ServerAliveInterval=%d", o.keepAliveInterval),
+			fmt.Sprintf("ServerAliveCountMax=%d", o.keepAliveCountMax),
+		)
+	}
+	res = append(res, o.extraOpts...)
+	return res, nil
 }
 
 // SSHArgsFromOpts returns ssh args from opts.
@@ -296,16 +517,31 @@ func SSHArgsFromOpts(opts []string) []string {
 	return args
 }
 
-func ParseOpenSSHVersion(version []byte) *semver.Version {
-	regex := regexp.MustCompile(`^OpenSSH_(\d+\.\d+)(?:p(\d+))?\b`)
-	matches := regex.FindSubmatch(version)
-	if len(matches) == 3 {
-		if len(matches[2]) == 0 {
-			matches[2] = []byte("0")
+// openSSHVersionRegex matches the version in the output of `ssh -V`, e.g.,
+// "OpenSSH_9.6p1 Ubuntu-3ubuntu13.5, OpenSSL 3.0.13 30 Jan 2024" or "OpenSSH_for_Windows_8.1p1, LibreSSL 3.0.2".
+// The output may be preceded by warning lines.
+var openSSHVersionRegex = regexp.MustCompile(`(?m)^\s*OpenSSH_(?:for_Windows_)?(\d+)\.(\d+)(?:p(\d+))?`)
+
+// ParseOpenSSHVersion parses the output of `ssh -V`.
+// The portable release number ("p1") is mapped to the patch version.
+func ParseOpenSSHVersion(version []byte) (*semver.Version, error) {
+	matches := openSSHVersionRegex.FindSubmatch(version)
+	if matches == nil {
+		return nil, fmt.Errorf("failed to parse OpenSSH version from %q", strings.TrimSpace(string(version)))
+	}
+	var v semver.Version
+	for i, field := range []*int64{&v.Major, &v.Minor, &v.Patch} {
+		s := string(matches[i+1])
+		if s == "" {
+			continue
 		}
-		return semver.New(fmt.Sprintf("%s.%s", matches[1], matches[2]))
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse OpenSSH version from %q: %w", strings.TrimSpace(string(version)), err)
+		}
+		*field = n
 	}
-	return &semver.Version{}
+	return &v, nil
 }
 
 // sshExecutable beyond path also records size and mtime, in the case of ssh upgrades.
@@ -316,42 +552,44 @@ type sshExecutable struct {
 }
 
 var (
-	// sshVersions caches the parsed version of each ssh executable, if it is needed again.
+	// sshVersions caches the parsed version of each ssh executable for the process lifetime.
 	sshVersions   = map[sshExecutable]*semver.Version{}
 	sshVersionsRW sync.RWMutex
 )
 
-func DetectOpenSSHVersion(ssh string) semver.Version {
-	var (
-		v      semver.Version
-		exe    sshExecutable
-		stderr bytes.Buffer
-	)
+// DetectOpenSSHVersion returns the version of the ssh executable.
+// The result is cached until the executable is replaced.
+func DetectOpenSSHVersion(ssh string) (semver.Version, error) {
 	path, err := exec.LookPath(ssh)
 	if err != nil {
-		logrus.Warnf("failed to find ssh executable: %v", err)
-	} else {
-		st, _ := os.Stat(path)
-		exe = sshExecutable{Path: path, Size: st.Size(), ModTime: st.ModTime()}
-		sshVersionsRW.RLock()
-		ver := sshVersions[exe]
-		sshVersionsRW.RUnlock()
-		if ver != nil {
-			return *ver
-		}
+		return semver.Version{}, fmt.Errorf("failed to find ssh executable: %w", err)
+	}
+	st, err := os.Stat(path)
+	if err != nil {
+		return semver.Version{}, err
+	}
+	exe := sshExecutable{Path: path, Size: st.Size(), ModTime: st.ModTime()}
+	sshVersionsRW.RLock()
+	ver := sshVersions[exe]
+	sshVersionsRW.RUnlock()
+	if ver != nil {
+		return *ver, nil
 	}
 	cmd := exec.Command(path, "-V")
-	cmd.Stderr = &stderr
-	if err := cmd.Run(); err != nil {
-		logrus.Warnf("failed to run %v: stderr=%q", cmd.Args, stderr.String())
-	} else {
-		v = *ParseOpenSSHVersion(stderr.Bytes())
-		logrus.Debugf("OpenSSH version %s detected", v)
-		sshVersionsRW.Lock()
-		sshVersions[exe] = &v
-		sshVersionsRW.Unlock()
+	// `ssh -V` prints the version to stderr, but wrappers may print it to stdout
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return semver.Version{}, fmt.Errorf("failed to run %v: %q: %w", cmd.Args, string(out), err)
+	}
+	ver, err = ParseOpenSSHVersion(out)
+	if err != nil {
+		return semver.Version{}, err
 	}
-	return v
+	logrus.Debugf("OpenSSH version %s detected", ver)
+	sshVersionsRW.Lock()
+	sshVersions[exe] = ver
+	sshVersionsRW.Unlock()
+	return *ver, nil
 }
 
 // detectValidPublicKey returns whether content represent a public key.
@@ -1,6 +1,10 @@
 package sshutil
 
 import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 
 	"github.com/coreos/go-semver/semver"
@@ -16,16 +20,40 @@ func TestDefaultPubKeys(t *testing.T) {
 }
 
 func TestParseOpenSSHVersion(t *testing.T) {
-	assert.Check(t, ParseOpenSSHVersion([]byte("OpenSSH_8.4p1 Ubuntu")).Equal(
-		semver.Version{Major: 8, Minor: 4, Patch: 1, PreRelease: "", Metadata: ""}))
-
-	assert.Check(t, ParseOpenSSHVersion([]byte("OpenSSH_7.6p1 Ubuntu")).LessThan(*semver.New("8.0.0")))
-
-	// macOS 10.15
-	assert.Check(t, ParseOpenSSHVersion([]byte("OpenSSH_8.1p1, LibreSSL 2.7.3")).Equal(*semver.New("8.1.1")))
+	testCases := []struct {
+		name     string
+		output   string
+		expected string
+	}{
+		{"Ubuntu 20.10", "OpenSSH_8.4p1 Ubuntu", "8.4.1"},
+		{"Ubuntu 18.04", "OpenSSH_7.6p1 Ubuntu-4ubuntu0.7, OpenSSL 1.0.2n  7 Dec 2017", "7.6.1"},
+		{"Ubuntu 24.04", "OpenSSH_9.6p1 Ubuntu-3ubuntu13.5, OpenSSL 3.0.13 30 Jan 2024\n", "9.6.1"},
+		{"Debian 12", "OpenSSH_9.2p1 Debian-2+deb12u3, OpenSSL 3.0.15 3 Sep 2024", "9.2.1"},
+		{"macOS 10.15", "OpenSSH_8.1p1, LibreSSL 2.7.3", "8.1.1"},
+		{"macOS 15", "OpenSSH_9.8p1, LibreSSL 3.3.6", "9.8.1"},
+		{"OpenBSD 5.8", "OpenSSH_7.0, LibreSSL", "7.0.0"},
+		{"OpenBSD 7.6", "OpenSSH_9.9, LibreSSL 4.0.0", "9.9.0"},
+		{"Windows 10", "OpenSSH_for_Windows_8.1p1, LibreSSL 3.0.2", "8.1.1"},
+		{"Windows 11", "OpenSSH_for_Windows_9.5p1, LibreSSL 3.8.2\r\n", "9.5.1"},
+		{"preceding warning", "Warning: Permanently added 'example.com' to the list of known hosts.\nOpenSSH_8.9p1, OpenSSL 3.0.2 15 Mar 2022", "8.9.1"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			v, err := ParseOpenSSHVersion([]byte(tc.output))
+			assert.NilError(t, err)
+			assert.Check(t, v.Equal(*semver.New(tc.expected)), "got %s", v)
+		})
+	}
 
-	// OpenBSD 5.8
-	assert.Check(t, ParseOpenSSHVersion([]byte("OpenSSH_7.0, LibreSSL")).Equal(*semver.New("7.0.0")))
+	for _, output := range []string{
+		"",
+		"Sun_SSH_1.1.5, SSH protocols 1.5/2.0, OpenSSL 0x0090704f",
+		"usage: ssh [-46AaCfGgKkMNnqsTtVvXxYy]",
+		"OpenSSH_",
+	} {
+		_, err := ParseOpenSSHVersion([]byte(output))
+		assert.ErrorContains(t, err, "failed to parse OpenSSH version", output)
+	}
 }
 
 func Test_detectValidPublicKey(t *testing.T) {
@@ -40,3 +68,127 @@ func Test_detectValidPublicKey(t *testing.T) {
 	assert.Check(t, !detectValidPublicKey("arbitrary content"))
 	assert.Check(t, !detectValidPublicKey(""))
 }
+
+func TestAuthorizedKeys(t *testing.T) {
+	const (
+		inlineKey = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAACQDf2IooTVPDBw== inline@example.com"
+		fileKey1  = "ssh-rsa AAAAB3NzaC1yc2EAAAADAQABAAAACQDf2IooTVPDBw== file1@example.com"
+		fileKey2  = "ssh-dss AAAAB3NzaC1kc3MAAACBAP/yAytaYzqXq01uTd5+1RC="
+	)
+	f := filepath.Join(t.TempDir(), "keys.pub")
+	assert.NilError(t, os.WriteFile(f, []byte("# comment\n"+fileKey1+"\n\n"+fileKey2+"\n"), 0o644))
+
+	keys, err := AuthorizedKeys([]string{inlineKey, f})
+	assert.NilError(t, err)
+	assert.DeepEqual(t, keys, []PubKey{
+		{Content: inlineKey},
+		{Filename: f, Content: fileKey1},
+		{Filename: f, Content: fileKey2},
+	})
+
+	_, err = AuthorizedKeys([]string{"ssh-rsa invalid"})
+	assert.ErrorContains(t, err, "doesn't seem to be in ssh format")
+
+	invalidFile := filepath.Join(t.TempDir(), "invalid.pub")
+	assert.NilError(t, os.WriteFile(invalidFile, []byte("arbitrary content\n"), 0o644))
+	_, err = AuthorizedKeys([]string{invalidFile})
+	assert.ErrorContains(t, err, "doesn't seem to be in ssh format")
+
+	_, err = AuthorizedKeys([]string{filepath.Join(t.TempDir(), "nonexistent.pub")})
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestExistingPubKeys(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+
+	_, err := ExistingPubKeys(false)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	_, err = os.Stat(filepath.Join(limaHome, "_config"))
+	assert.ErrorIs(t, err, os.ErrNotExist, "the key must not be generated")
+
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user.pub"), []byte("ssh-ed25519 AAAA lima\n"), 0o644))
+	keys, err := ExistingPubKeys(false)
+	assert.NilError(t, err)
+	assert.Equal(t, len(keys), 1)
+	assert.Equal(t, keys[0].Content, "ssh-ed25519 AAAA lima")
+}
+
+func TestValidateAuthorizedKey(t *testing.T) {
+	assert.NilError(t, ValidateAuthorizedKey("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAICs1tSO/jx8oc4O= user@example.com"))
+	assert.NilError(t, ValidateAuthorizedKey("~/.ssh/id_ci.pub"))
+	assert.ErrorContains(t, ValidateAuthorizedKey("ssh-ed25519 invalid"), "doesn't seem to be in ssh format")
+	assert.ErrorContains(t, ValidateAuthorizedKey("~foo/id_ci.pub"), "unexpandable path")
+}
+
+func TestSSHOpts(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	instDir := filepath.Join(limaHome, "default")
+
+	opts, err := SSHOpts("ssh", instDir, "foo")
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "User=foo"))
+	assert.Assert(t, slices.Contains(opts, "ControlMaster=auto"))
+	assert.Assert(t, !slices.Contains(opts, "ForwardAgent=yes"))
+
+	opts, err = SSHOpts("ssh", instDir, "foo", WithForwardAgent(true), WithForwardX11(true))
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "ControlMaster=auto"))
+	assert.Assert(t, slices.Contains(opts, "ForwardAgent=yes"))
+	assert.Assert(t, slices.Contains(opts, "ForwardX11=yes"))
+	assert.Assert(t, !slices.Contains(opts, "ForwardX11Trusted=yes"))
+
+	// The later option takes precedence
+	opts, err = SSHOpts("ssh", instDir, "foo", WithForwardAgent(true), WithForwardAgent(false), WithoutControlMaster())
+	assert.NilError(t, err)
+	assert.Assert(t, !slices.Contains(opts, "ForwardAgent=yes"))
+	assert.Assert(t, slices.Contains(opts, "ControlMaster=no"))
+	assert.Assert(t, slices.Contains(opts, "ControlPath=none"))
+	assert.Assert(t, !slices.Contains(opts, "ControlPersist=yes"))
+}
+
+func TestSSHOptsWithJumpHost(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	instDir := filepath.Join(limaHome, "default")
+
+	opts, err := SSHOpts("ssh", instDir, "foo", WithJumpHost(""))
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "ControlMaster=auto"))
+
+	opts, err = SSHOpts("ssh", instDir, "foo", WithJumpHost("bar@example.com:2222"))
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "ProxyJump=bar@example.com:2222"))
+	assert.Assert(t, slices.Contains(opts, "ControlMaster=no"))
+
+	_, err = SSHOpts("ssh", instDir, "foo", WithJumpHost("bar@"))
+	assert.ErrorContains(t, err, "has no host")
+}
+
+func TestSSHOptsWithKeepAlive(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	instDir := filepath.Join(limaHome, "default")
+
+	opts, err := SSHOpts("ssh", instDir, "foo", WithKeepAlive(30, 3))
+	assert.NilError(t, err)
+	assert.Assert(t, slices.Contains(opts, "ServerAliveInterval=30"))
+	assert.Assert(t, slices.Contains(opts, "ServerAliveCountMax=3"))
+
+	opts, err = SSHOpts("ssh", instDir, "foo", WithKeepAlive(30, 3), WithKeepAlive(0, 3))
+	assert.NilError(t, err)
+	assert.Assert(t, !slices.ContainsFunc(opts, func(o string) bool { return strings.HasPrefix(o, "ServerAlive") }))
+
+	_, err = SSHOpts("ssh", instDir, "foo", WithKeepAlive(-1, 3))
+	assert.ErrorContains(t, err, "must not be negative")
+	_, err = SSHOpts("ssh", instDir, "foo", WithKeepAlive(30, 0))
+	assert.ErrorContains(t, err, "must be positive")
+}
@@ -20,6 +20,8 @@ type Disk struct {
 	Instance    string `json:"instance"`
 	InstanceDir string `json:"instanceDir"`
 	MountPoint  string `json:"mountPoint"`
+	// ReadOnlyInstances are the names of the instances that attach the disk as read-only.
+	ReadOnlyInstances []string `json:"readOnlyInstances,omitempty"`
 }
 
 func InspectDisk(diskName string) (*Disk, error) {
@@ -53,6 +55,11 @@ func InspectDisk(diskName string) (*Disk, error) {
 		disk.InstanceDir = instDir
 	}
 
+	disk.ReadOnlyInstances, err = readOnlyInstances(diskDir)
+	if err != nil {
+		return nil, err
+	}
+
 	disk.MountPoint = fmt.Sprintf("/mnt/lima-%s", diskName)
 
 	return disk, nil
@@ -87,12 +94,78 @@ func inspectDiskWithQemuImg(fName string) (size int64, format string, _ error) {
 	return info.VSize, info.Format, nil
 }
 
+// readOnlyInstances returns the names of the instances that hold the read-only lock of the disk.
+func readOnlyInstances(diskDir string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Join(diskDir, filenames.InUseByReadOnly))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	return names, nil
+}
+
+// Lock locks the disk exclusively for the instance.
+// Lock fails if another instance holds the read-only lock of the disk.
 func (d *Disk) Lock(instanceDir string) error {
+	instNames, err := readOnlyInstances(d.Dir)
+	if err != nil {
+		return err
+	}
+	for _, instName := range instNames {
+		if instName != filepath.Base(instanceDir) {
+			return fmt.Errorf("disk %q is attached as read-only by instance %q", d.Name, instName)
+		}
+	}
 	inUseBy := filepath.Join(d.Dir, filenames.InUseBy)
 	return os.Symlink(instanceDir, inUseBy)
 }
 
+// LockReadOnly records the instance as a reader of the disk.
+// Multiple instances may hold the read-only lock at the same time,
+// but LockReadOnly fails if another instance holds the exclusive lock.
+func (d *Disk) LockReadOnly(instanceDir string) error {
+	instDir, err := os.Readlink(filepath.Join(d.Dir, filenames.InUseBy))
+	if err == nil && instDir != instanceDir {
+		return fmt.Errorf("disk %q is in use by instance %q", d.Name, filepath.Base(instDir))
+	}
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	readOnlyDir := filepath.Join(d.Dir, filenames.InUseByReadOnly)
+	if err := os.MkdirAll(readOnlyDir, 0o755); err != nil {
+		return err
+	}
+	inUseBy := filepath.Join(readOnlyDir, filepath.Base(instanceDir))
+	// Replace a stale lock left by the same instance
+	if err := os.Remove(inUseBy); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return os.Symlink(instanceDir, inUseBy)
+}
+
 func (d *Disk) Unlock() error {
 	inUseBy := filepath.Join(d.Dir, filenames.InUseBy)
 	return os.Remove(inUseBy)
 }
+
+// UnlockReadOnly releases the read-only lock held by the instance.
+func (d *Disk) UnlockReadOnly(instName string) error {
+	readOnlyDir := filepath.Join(d.Dir, filenames.InUseByReadOnly)
+	if err := os.Remove(filepath.Join(readOnlyDir, instName)); err != nil {
+		return err
+	}
+	instNames, err := readOnlyInstances(d.Dir)
+	if err != nil {
+		return err
+	}
+	if len(instNames) == 0 {
+		return os.Remove(readOnlyDir)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestDiskLockReadOnly(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	diskDir, err := DiskDir("data")
+	assert.NilError(t, err)
+	assert.NilError(t, os.MkdirAll(diskDir, 0o755))
+	disk := &Disk{Name: "data", Dir: diskDir}
+
+	instDir := func(name string) string {
+		return filepath.Join(t.TempDir(), name)
+	}
+	foo, bar, baz := instDir("foo"), instDir("bar"), instDir("baz")
+
+	assert.NilError(t, disk.LockReadOnly(foo))
+	assert.NilError(t, disk.LockReadOnly(bar))
+	// Locking again replaces the stale lock of the same instance
+	assert.NilError(t, disk.LockReadOnly(bar))
+	instNames, err := readOnlyInstances(diskDir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, instNames, []string{"bar", "foo"})
+
+	assert.ErrorContains(t, disk.Lock(baz), `attached as read-only by instance`)
+
+	assert.NilError(t, disk.UnlockReadOnly("foo"))
+	assert.NilError(t, disk.UnlockReadOnly("bar"))
+	_, err = os.Stat(filepath.Join(diskDir, filenames.InUseByReadOnly))
+	assert.ErrorIs(t, err, os.ErrNotExist)
+
+	assert.NilError(t, disk.Lock(baz))
+	assert.ErrorContains(t, disk.LockReadOnly(foo), `in use by instance "baz"`)
+}
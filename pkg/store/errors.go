@@ -0,0 +1,137 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+var (
+	// ErrInstanceNotFound is returned when the instance does not exist.
+	// Errors wrapping ErrInstanceNotFound also match os.ErrNotExist.
+	ErrInstanceNotFound = errors.New("instance does not exist")
+	// ErrInstanceStopped is returned when the instance needs to be running, but is stopped.
+	ErrInstanceStopped = errors.New("instance is stopped")
+	// ErrInstancePaused is returned when the instance needs to be running, but is paused.
+	ErrInstancePaused = errors.New("instance is paused")
+	// ErrInstanceBroken is returned when the instance needs to be running, but is broken.
+	ErrInstanceBroken = errors.New("instance is broken")
+	// ErrInstanceNotRunning is returned when the instance needs to be running, but is in another status,
+	// e.g., still being installed.
+	ErrInstanceNotRunning = errors.New("instance is not running")
+	// ErrInstanceReadOnly is returned when the instance needs to be mutated, but is read-only.
+	ErrInstanceReadOnly = errors.New("instance is read-only")
+)
+
+// instanceError carries a user-facing message with a hint, while matching
+// the sentinel errors via errors.Is.
+type instanceError struct {
+	msg  string
+	errs []error
+}
+
+func (e *instanceError) Error() string {
+	return e.msg
+}
+
+func (e *instanceError) Unwrap() []error {
+	return e.errs
+}
+
+func newInstanceNotFoundError(instName string, err error) error {
+	if err == nil {
+		err = os.ErrNotExist
+	}
+	return &instanceError{
+		msg:  fmt.Sprintf("instance %q does not exist, run `limactl create %s` to create a new instance", instName, instName),
+		errs: []error{ErrInstanceNotFound, err},
+	}
+}
+
+func newInstanceStoppedError(instName string) error {
+	return &instanceError{
+		msg:  fmt.Sprintf("instance %q is stopped, run `limactl start %s` to start the instance", instName, instName),
+		errs: []error{ErrInstanceStopped},
+	}
+}
+
+func newInstancePausedError(instName string) error {
+	return &instanceError{
+		msg:  fmt.Sprintf("instance %q is paused, run `limactl resume %s` to resume the instance", instName, instName),
+		errs: []error{ErrInstancePaused},
+	}
+}
+
+func newInstanceBrokenError(instName string, errs []error) error {
+	msg := fmt.Sprintf("instance %q is broken, so SSH is unavailable", instName)
+	if err := errors.Join(errs...); err != nil {
+		msg += fmt.Sprintf(": %v", err)
+	}
+	return &instanceError{
+		msg:  msg + fmt.Sprintf(" (hint: run `limactl stop -f %s` and `limactl start %s` to restart the instance)", instName, instName),
+		errs: []error{ErrInstanceBroken},
+	}
+}
+
+func newInstanceNotRunningError(instName string, status Status) error {
+	return &instanceError{
+		msg:  fmt.Sprintf("instance %q is not running (status: %q)", instName, status),
+		errs: []error{ErrInstanceNotRunning},
+	}
+}
+
+func newInstanceReadOnlyError(instName, op string) error {
+	return &instanceError{
+		msg:  fmt.Sprintf("instance %q is read-only, so %s is not allowed (hint: set `readOnly: false` in lima.yaml with `limactl edit %s`)", instName, op, instName),
+		errs: []error{ErrInstanceReadOnly},
+	}
+}
+
+// CheckWritable returns an error wrapping ErrInstanceReadOnly when the instance is read-only (`readOnly` in lima.yaml).
+// op describes the refused operation, e.g., "copying files into the guest".
+func CheckWritable(inst *Instance, op string) error {
+	if inst.Config != nil && inst.Config.ReadOnly != nil && *inst.Config.ReadOnly {
+		return newInstanceReadOnlyError(inst.Name, op)
+	}
+	return nil
+}
+
+// CheckRunning returns nil when the instance is running, otherwise an error that wraps
+// ErrInstanceStopped, ErrInstancePaused, ErrInstanceBroken, or ErrInstanceNotRunning.
+//
+// Unlike InspectRunning, CheckRunning rejects all the statuses other than StatusRunning.
+// A degraded instance is still running, so its reasons have to be checked by the caller.
+func CheckRunning(inst *Instance) error {
+	switch inst.Status {
+	case StatusRunning:
+		return nil
+	case StatusStopped:
+		return newInstanceStoppedError(inst.Name)
+	case StatusPaused:
+		return newInstancePausedError(inst.Name)
+	case StatusBroken:
+		return newInstanceBrokenError(inst.Name, inst.Errors)
+	default:
+		return newInstanceNotRunningError(inst.Name, inst.Status)
+	}
+}
+
+// InspectRunning is like Inspect, but also returns an error wrapping ErrInstanceStopped
+// when the instance is stopped, ErrInstancePaused when the instance is paused,
+// or ErrInstanceBroken when the instance is broken.
+// The returned instance always has a non-nil Config.
+func InspectRunning(instName string) (*Instance, error) {
+	inst, err := Inspect(instName)
+	if err != nil {
+		return nil, err
+	}
+	switch inst.Status {
+	case StatusStopped:
+		return nil, newInstanceStoppedError(instName)
+	case StatusPaused:
+		return nil, newInstancePausedError(instName)
+	case StatusBroken:
+		return nil, newInstanceBrokenError(instName, inst.Errors)
+	}
+	return inst, nil
+}
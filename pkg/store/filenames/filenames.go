@@ -21,15 +21,18 @@ const (
 	NetworksConfig = "networks.yaml"
 	Default        = "default.yaml"
 	Override       = "override.yaml"
+	TemplateRepos  = "template-repos.yaml"
 )
 
 // Filenames that may appear under an instance directory
 
 const (
 	LimaYAML             = "lima.yaml"
-	LimaVersion          = "lima-version" // Lima version used to create instance
+	LimaVersion          = "lima-version"    // Lima version used to create instance
+	Provenance           = "provenance.json" // artifacts used to build the instance, written by `limactl start`
 	CIDataISO            = "cidata.iso"
 	CIDataISODir         = "cidata"
+	CIDataVFAT           = "cidata.vfat"
 	CloudConfig          = "cloud-config.yaml"
 	BaseDisk             = "basedisk"
 	DiffDisk             = "diffdisk"
@@ -45,6 +48,9 @@ const (
 	SerialVirtioSock     = "serialv.sock"
 	SSHSock              = "ssh.sock"
 	SSHConfig            = "ssh.config"
+	SSHHostKey           = "ssh_host_ed25519_key"     // injected into the guest via cidata
+	SSHHostKeyPub        = "ssh_host_ed25519_key.pub" // public key of SSHHostKey
+	SSHKnownHosts        = "ssh_known_hosts"          // contains SSHHostKeyPub, referenced by UserKnownHostsFile
 	VhostSock            = "virtiofsd-%d.sock"
 	VNCDisplayFile       = "vncdisplay"
 	VNCPasswordFile      = "vncpassword"
@@ -58,6 +64,8 @@ const (
 	VzEfi                = "vz-efi"           // efi variable store
 	QemuEfiCodeFD        = "qemu-efi-code.fd" // efi code; not always created
 	AnsibleInventoryYAML = "ansible-inventory.yaml"
+	SavedState           = "savedstate"      // vm state saved by `limactl save`
+	SavedStateJSON       = "savedstate.json" // metadata of SavedState
 
 	// SocketDir is the default location for forwarded sockets with a relative paths in HostSocket.
 	SocketDir = "sock"
@@ -68,8 +76,9 @@ const (
 // Filenames used under a disk directory
 
 const (
-	DataDisk = "datadisk"
-	InUseBy  = "in_use_by"
+	DataDisk        = "datadisk"
+	InUseBy         = "in_use_by"
+	InUseByReadOnly = "in_use_by_readonly" // directory of symlinks to the instances that attach the disk as read-only
 )
 
 // LongestSock is the longest socket name.
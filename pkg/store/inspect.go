@@ -0,0 +1,67 @@
+package store
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/opencontainers/go-digest"
+)
+
+// InspectOutputVersion is the version of InspectOutput.
+const InspectOutputVersion = "v1"
+
+// InspectOutput is the stable JSON representation of Instance, for `limactl inspect --json`.
+//
+// Unlike Instance, the fields are decoupled from the internal structs.
+// Existing fields must not be renamed, removed, or changed in meaning without bumping InspectOutputVersion.
+type InspectOutput struct {
+	Version string `json:"version"`
+	Name    string `json:"name"`
+	Status  Status `json:"status"`
+	Dir     string `json:"dir"`
+	VMType  string `json:"vmType"`
+	Arch    string `json:"arch"`
+	CPUs    int    `json:"cpus"`
+	Memory  int64  `json:"memory"` // bytes
+	Disk    int64  `json:"disk"`   // bytes
+	// SSHAddress and SSHLocalPort are the address to connect to the SSH server of the guest.
+	// SSHLocalPort is 0 when the port is not assigned yet.
+	SSHAddress    string `json:"sshAddress"`
+	SSHLocalPort  int    `json:"sshLocalPort"`
+	SSHConfigFile string `json:"sshConfigFile"`
+	// ConfigDigest is the digest of lima.yaml, e.g., "sha256:...". Empty when lima.yaml cannot be read.
+	ConfigDigest string   `json:"configDigest"`
+	Protected    bool     `json:"protected"`
+	Errors       []string `json:"errors,omitempty"`
+}
+
+// NewInspectOutput converts inst to InspectOutput.
+func NewInspectOutput(inst *Instance) (*InspectOutput, error) {
+	out := &InspectOutput{
+		Version:       InspectOutputVersion,
+		Name:          inst.Name,
+		Status:        inst.Status,
+		Dir:           inst.Dir,
+		VMType:        inst.VMType,
+		Arch:          inst.Arch,
+		CPUs:          inst.CPUs,
+		Memory:        inst.Memory,
+		Disk:          inst.Disk,
+		SSHAddress:    inst.SSHAddress,
+		SSHLocalPort:  inst.SSHLocalPort,
+		SSHConfigFile: inst.SSHConfigFile,
+		Protected:     inst.Protected,
+	}
+	b, err := os.ReadFile(filepath.Join(inst.Dir, filenames.LimaYAML))
+	if err == nil {
+		out.ConfigDigest = digest.FromBytes(b).String()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	for _, err := range inst.Errors {
+		out.Errors = append(out.Errors, err.Error())
+	}
+	return out, nil
+}
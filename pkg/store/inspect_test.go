@@ -0,0 +1,50 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+// TestInspectOutput checks that the JSON shape of InspectOutput is stable.
+func TestInspectOutput(t *testing.T) {
+	instDir := t.TempDir()
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.LimaYAML), []byte("foo"), 0o644))
+	inst := &Instance{
+		Name:          "default",
+		Status:        StatusRunning,
+		Dir:           instDir,
+		VMType:        limayaml.QEMU,
+		Arch:          limayaml.X8664,
+		CPUs:          4,
+		Memory:        4 << 30,
+		Disk:          100 << 30,
+		SSHAddress:    "127.0.0.1",
+		SSHLocalPort:  60022,
+		SSHConfigFile: "/home/user/.lima/default/ssh.config",
+		Protected:     true,
+		Errors:        []error{errors.New("something went wrong")},
+	}
+	out, err := NewInspectOutput(inst)
+	assert.NilError(t, err)
+	assert.Equal(t, out.Dir, instDir)
+	out.Dir = "/home/user/.lima/default"
+
+	b, err := json.MarshalIndent(out, "", "  ")
+	assert.NilError(t, err)
+	golden, err := os.ReadFile(filepath.Join("testdata", "inspect-"+InspectOutputVersion+".json"))
+	assert.NilError(t, err)
+	assert.Equal(t, string(b)+"\n", string(golden))
+}
+
+func TestInspectOutputWithoutConfig(t *testing.T) {
+	out, err := NewInspectOutput(&Instance{Name: "foo", Dir: t.TempDir(), Status: StatusBroken})
+	assert.NilError(t, err)
+	assert.Equal(t, out.ConfigDigest, "")
+}
@@ -17,6 +17,7 @@ import (
 	"time"
 
 	"github.com/docker/go-units"
+	hostagentapi "github.com/lima-vm/lima/pkg/hostagent/api"
 	hostagentclient "github.com/lima-vm/lima/pkg/hostagent/api/client"
 	"github.com/lima-vm/lima/pkg/identifierutil"
 	"github.com/lima-vm/lima/pkg/limayaml"
@@ -36,6 +37,8 @@ const (
 	StatusBroken        Status = "Broken"
 	StatusStopped       Status = "Stopped"
 	StatusRunning       Status = "Running"
+	// StatusPaused is the status of the running instance paused by `limactl pause`.
+	StatusPaused Status = "Paused"
 )
 
 type Instance struct {
@@ -58,14 +61,20 @@ type Instance struct {
 	HostAgentPID    int                `json:"hostAgentPID,omitempty"`
 	DriverPID       int                `json:"driverPID,omitempty"`
 	Errors          []error            `json:"errors,omitempty"`
-	Config          *limayaml.LimaYAML `json:"config,omitempty"`
-	SSHAddress      string             `json:"sshAddress,omitempty"`
-	Protected       bool               `json:"protected"`
-	LimaVersion     string             `json:"limaVersion"`
-	Param           map[string]string  `json:"param,omitempty"`
+	// DegradedReasons are the reasons why the running instance is degraded, as reported by the host agent.
+	DegradedReasons []string `json:"degradedReasons,omitempty"`
+	// VMRunState is the run state of the running vm as reported by the hypervisor, e.g., "paused".
+	VMRunState  string             `json:"vmRunState,omitempty"`
+	Config      *limayaml.LimaYAML `json:"config,omitempty"`
+	SSHAddress  string             `json:"sshAddress,omitempty"`
+	Protected   bool               `json:"protected"`
+	LimaVersion string             `json:"limaVersion"`
+	Provenance  *Provenance        `json:"provenance,omitempty"`
+	Param       map[string]string  `json:"param,omitempty"`
 }
 
-// Inspect returns err only when the instance does not exist (os.ErrNotExist).
+// Inspect returns err only when the instance does not exist (ErrInstanceNotFound,
+// which also matches os.ErrNotExist).
 // Other errors are returned as *Instance.Errors.
 func Inspect(instName string) (*Instance, error) {
 	inst := &Instance{
@@ -85,8 +94,9 @@ func Inspect(instName string) (*Instance, error) {
 	y, err := LoadYAMLByFilePath(yamlPath)
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return nil, err
+			return nil, newInstanceNotFoundError(instName, err)
 		}
+		inst.Status = StatusBroken
 		inst.Errors = append(inst.Errors, err)
 		return inst, nil
 	}
@@ -118,6 +128,8 @@ func Inspect(instName string) (*Instance, error) {
 				inst.Errors = append(inst.Errors, fmt.Errorf("failed to get Info from %q: %w", haSock, err))
 			} else {
 				inst.SSHLocalPort = info.SSHLocalPort
+				inst.DegradedReasons = info.DegradedReasons
+				inst.VMRunState = info.VMRunState
 			}
 		}
 	}
@@ -127,7 +139,7 @@ func Inspect(instName string) (*Instance, error) {
 	if err == nil {
 		inst.Memory = memory
 	}
-	disk, err := units.RAMInBytes(*y.Disk)
+	disk, err := units.RAMInBytes(*y.Disk.Size)
 	if err == nil {
 		inst.Disk = disk
 	}
@@ -147,6 +159,9 @@ func Inspect(instName string) (*Instance, error) {
 	}
 
 	inspectStatus(instDir, inst, y)
+	if inst.Status == StatusRunning && inst.VMRunState == hostagentapi.VMRunStatePaused {
+		inst.Status = StatusPaused
+	}
 
 	tmpl, err := template.New("format").Parse(y.Message)
 	if err != nil {
@@ -178,6 +193,10 @@ func Inspect(instName string) (*Instance, error) {
 	} else if !errors.Is(err, os.ErrNotExist) {
 		inst.Errors = append(inst.Errors, err)
 	}
+	inst.Provenance, err = ReadProvenance(instDir)
+	if err != nil {
+		inst.Errors = append(inst.Errors, fmt.Errorf("failed to read the provenance: %w", err))
+	}
 	inst.Param = y.Param
 	return inst, nil
 }
@@ -282,6 +301,25 @@ type PrintOptions struct {
 	TerminalWidth int
 }
 
+func parseFormat(format string) (*template.Template, error) {
+	tmpl, err := template.New("format").Funcs(textutil.TemplateFuncMap).Parse(format)
+	if err != nil {
+		return nil, fmt.Errorf("invalid go template %q: %w", format, err)
+	}
+	return tmpl, nil
+}
+
+// ValidateFormat returns an error when format is not supported by PrintInstances,
+// so that the caller can fail before loading the instances.
+func ValidateFormat(format string) error {
+	switch format {
+	case "json", "yaml", "table":
+		return nil
+	}
+	_, err := parseFormat(format)
+	return err
+}
+
 // PrintInstances prints instances in a requested format to a given io.Writer.
 // Supported formats are "json", "yaml", "table", or a go template.
 func PrintInstances(w io.Writer, instances []*Instance, format string, options *PrintOptions) error {
@@ -394,9 +432,9 @@ func PrintInstances(w io.Writer, instances []*Instance, format string, options *
 	default:
 		// NOP
 	}
-	tmpl, err := template.New("format").Funcs(textutil.TemplateFuncMap).Parse(format)
+	tmpl, err := parseFormat(format)
 	if err != nil {
-		return fmt.Errorf("invalid go template: %w", err)
+		return err
 	}
 	for _, instance := range instances {
 		data, err := AddGlobalFields(instance)
@@ -406,7 +444,7 @@ func PrintInstances(w io.Writer, instances []*Instance, format string, options *
 		data.Message = strings.TrimSuffix(instance.Message, "\n")
 		err = tmpl.Execute(w, data)
 		if err != nil {
-			return err
+			return fmt.Errorf("failed to execute the go template for instance %q: %w", instance.Name, err)
 		}
 		fmt.Fprintln(w)
 	}
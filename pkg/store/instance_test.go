@@ -2,6 +2,8 @@ package store
 
 import (
 	"bytes"
+	"errors"
+	"os"
 	"os/user"
 	"path/filepath"
 	"runtime"
@@ -9,6 +11,8 @@ import (
 	"testing"
 
 	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store/filenames"
 	"gotest.tools/v3/assert"
 )
 
@@ -155,3 +159,108 @@ func TestPrintInstanceTableTwo(t *testing.T) {
 	assert.NilError(t, err)
 	assert.Equal(t, tableTwo, buf.String())
 }
+
+func TestPrintInstanceFormat(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	inst := instance
+	inst.Config = &limayaml.LimaYAML{Arch: &inst.Arch}
+	instances := []*Instance{&inst}
+
+	var buf bytes.Buffer
+	err := PrintInstances(&buf, instances, "{{.Name}} {{.Status}} {{.SSHLocalPort}} {{.Dir}} {{.Config.Arch}}", nil)
+	assert.NilError(t, err)
+	assert.Equal(t, buf.String(), "foo Stopped 0 dir "+goarch+"\n")
+
+	buf.Reset()
+	err = PrintInstances(&buf, instances, "json", nil)
+	assert.NilError(t, err)
+	assert.Assert(t, strings.HasPrefix(buf.String(), `{"name":"foo",`), buf.String())
+
+	err = PrintInstances(&buf, instances, "{{.NoSuchField}}", nil)
+	assert.ErrorContains(t, err, `instance "foo"`)
+}
+
+func TestValidateFormat(t *testing.T) {
+	for _, format := range []string{"json", "yaml", "table", "{{.Name}}", "{{json .}}", "name"} {
+		assert.NilError(t, ValidateFormat(format), format)
+	}
+	assert.ErrorContains(t, ValidateFormat("{{.Name"), "invalid go template")
+	assert.ErrorContains(t, ValidateFormat("{{nosuchfunc .Name}}"), "invalid go template")
+}
+
+func TestInspectNotFound(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	_, err := Inspect("foo")
+	assert.Assert(t, errors.Is(err, ErrInstanceNotFound))
+	assert.Assert(t, errors.Is(err, os.ErrNotExist))
+	assert.Assert(t, !errors.Is(err, ErrInstanceStopped))
+	assert.ErrorContains(t, err, "limactl create foo")
+
+	_, err = InspectRunning("foo")
+	assert.Assert(t, errors.Is(err, ErrInstanceNotFound))
+}
+
+func TestInspectRunningBroken(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	// lima.yaml is invalid, so Config is nil
+	assert.NilError(t, os.Mkdir(filepath.Join(limaHome, "foo"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "foo", filenames.LimaYAML), []byte("cpus: [\n"), 0o644))
+
+	inst, err := InspectRunning("foo")
+	assert.Assert(t, errors.Is(err, ErrInstanceBroken))
+	assert.Assert(t, inst == nil)
+}
+
+func TestInstanceStoppedError(t *testing.T) {
+	err := newInstanceStoppedError("foo")
+	assert.Assert(t, errors.Is(err, ErrInstanceStopped))
+	assert.Assert(t, !errors.Is(err, ErrInstanceNotFound))
+	assert.Assert(t, !errors.Is(err, os.ErrNotExist))
+	assert.ErrorContains(t, err, "limactl start foo")
+}
+
+func TestCheckRunning(t *testing.T) {
+	testCases := []struct {
+		status    Status
+		errs      []error
+		expected  error
+		errSubstr string
+	}{
+		{status: StatusRunning},
+		{status: StatusStopped, expected: ErrInstanceStopped, errSubstr: "limactl start foo"},
+		{status: StatusPaused, expected: ErrInstancePaused, errSubstr: "limactl resume foo"},
+		{status: StatusBroken, errs: []error{errors.New("host agent is running but driver is not")}, expected: ErrInstanceBroken, errSubstr: "SSH is unavailable: host agent is running but driver is not"},
+		{status: StatusBroken, expected: ErrInstanceBroken, errSubstr: "limactl stop -f foo"},
+		{status: StatusInstalling, expected: ErrInstanceNotRunning, errSubstr: `(status: "Installing")`},
+		{status: StatusUninitialized, expected: ErrInstanceNotRunning, errSubstr: `(status: "Uninitialized")`},
+		{status: StatusUnknown, expected: ErrInstanceNotRunning, errSubstr: `(status: "")`},
+	}
+	for _, tc := range testCases {
+		err := CheckRunning(&Instance{Name: "foo", Status: tc.status, Errors: tc.errs})
+		if tc.expected == nil {
+			assert.NilError(t, err, tc.status)
+			continue
+		}
+		assert.Assert(t, errors.Is(err, tc.expected), "status %q: %v", tc.status, err)
+		assert.ErrorContains(t, err, tc.errSubstr, tc.status)
+	}
+	// A degraded instance is still running
+	assert.NilError(t, CheckRunning(&Instance{Name: "foo", Status: StatusRunning, DegradedReasons: []string{"sshfs mount failed"}}))
+}
+
+func TestCheckWritable(t *testing.T) {
+	assert.NilError(t, CheckWritable(&Instance{Name: "foo"}, "provisioning"))
+	assert.NilError(t, CheckWritable(&Instance{Name: "foo", Config: &limayaml.LimaYAML{ReadOnly: ptr.Of(false)}}, "provisioning"))
+	err := CheckWritable(&Instance{Name: "foo", Config: &limayaml.LimaYAML{ReadOnly: ptr.Of(true)}}, "provisioning")
+	assert.Assert(t, errors.Is(err, ErrInstanceReadOnly), err)
+	assert.ErrorContains(t, err, `instance "foo" is read-only, so provisioning is not allowed`)
+	assert.ErrorContains(t, err, "limactl edit foo")
+}
+
+func TestInstancePausedError(t *testing.T) {
+	err := newInstancePausedError("foo")
+	assert.Assert(t, errors.Is(err, ErrInstancePaused))
+	assert.Assert(t, !errors.Is(err, ErrInstanceStopped))
+	assert.ErrorContains(t, err, "limactl resume foo")
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/opencontainers/go-digest"
+)
+
+// Provenance records the artifacts that the instance was built from.
+// It is written to the provenance.json file in the instance directory by `limactl start`.
+type Provenance struct {
+	// LimaVersion is the version of Lima that last started the instance.
+	LimaVersion string `json:"limaVersion"`
+	// Image is the base image that the disk was created from.
+	// Nil if the image could not be determined, e.g., for instances created with older versions of Lima.
+	Image *Artifact `json:"image,omitempty"`
+	// NerdctlArchive is the nerdctl archive that was last baked into the cidata.
+	NerdctlArchive *Artifact `json:"nerdctlArchive,omitempty"`
+}
+
+type Artifact struct {
+	Location string `json:"location"`
+	// Digest is the digest of the downloaded image (see downloader.Result),
+	// or the digest of the nerdctl archive specified in the config.
+	// Empty when the digest is not known.
+	Digest  digest.Digest `json:"digest,omitempty"`
+	Version string        `json:"version,omitempty"`
+}
+
+// ReadProvenance reads the provenance.json file in the instance directory.
+// It returns nil without an error when the file does not exist.
+func ReadProvenance(instDir string) (*Provenance, error) {
+	b, err := os.ReadFile(filepath.Join(instDir, filenames.Provenance))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var p Provenance
+	if err := json.Unmarshal(b, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// WriteProvenanceImage records the image that the disk has just been created from in the provenance.json file,
+// keeping the other fields. Called by the drivers after downloading the image.
+func WriteProvenanceImage(instDir string, image *Artifact) error {
+	p, err := ReadProvenance(instDir)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		p = &Provenance{}
+	}
+	p.Image = image
+	return WriteProvenance(instDir, p)
+}
+
+// WriteProvenance writes the provenance.json file in the instance directory.
+func WriteProvenance(instDir string, p *Provenance) error {
+	b, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(instDir, filenames.Provenance), append(b, '\n'), 0o644)
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"testing"
+
+	"gotest.tools/v3/assert"
+)
+
+func TestProvenance(t *testing.T) {
+	instDir := t.TempDir()
+	p, err := ReadProvenance(instDir)
+	assert.NilError(t, err)
+	assert.Assert(t, p == nil)
+
+	expected := &Provenance{
+		LimaVersion: "1.0.0",
+		Image: &Artifact{
+			Location: "https://example.com/image.img",
+			Digest:   "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+		},
+		NerdctlArchive: &Artifact{
+			Location: "https://example.com/nerdctl-full-2.0.3-linux-amd64.tar.gz",
+			Version:  "2.0.3",
+		},
+	}
+	assert.NilError(t, WriteProvenance(instDir, expected))
+	p, err = ReadProvenance(instDir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p, expected)
+}
+
+func TestWriteProvenanceImage(t *testing.T) {
+	instDir := t.TempDir()
+	image := &Artifact{
+		Location: "https://example.com/image.img",
+		Digest:   "sha256:0000000000000000000000000000000000000000000000000000000000000000",
+	}
+	assert.NilError(t, WriteProvenanceImage(instDir, image))
+	p, err := ReadProvenance(instDir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p, &Provenance{Image: image})
+
+	// the other fields are kept
+	p.LimaVersion = "1.0.0"
+	assert.NilError(t, WriteProvenance(instDir, p))
+	image = &Artifact{Location: "https://example.com/image2.img"}
+	assert.NilError(t, WriteProvenanceImage(instDir, image))
+	p, err = ReadProvenance(instDir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, p, &Provenance{LimaVersion: "1.0.0", Image: image})
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/lima-vm/lima/pkg/version"
+	"github.com/opencontainers/go-digest"
+)
+
+// SavedState is the metadata of the vm state saved by `limactl save`.
+// It is written to the savedstate.json file in the instance directory, after the vm state is written to the savedstate file.
+type SavedState struct {
+	// LimaVersion is the version of Lima that saved the state.
+	LimaVersion string `json:"limaVersion"`
+	Arch        string `json:"arch"`
+	VMType      string `json:"vmType"`
+	// LimaYAMLDigest is the digest of lima.yaml when the state was saved.
+	// The cidata is not regenerated on restoring the state, so lima.yaml must not be modified.
+	LimaYAMLDigest digest.Digest `json:"limaYAMLDigest"`
+	// UDPDNSLocalPort and TCPDNSLocalPort are the ports of the host resolver.
+	// They are embedded in the guest, so they have to be reused on restoring the state.
+	UDPDNSLocalPort int `json:"udpDNSLocalPort,omitempty"`
+	TCPDNSLocalPort int `json:"tcpDNSLocalPort,omitempty"`
+	// Disks are the disks attached to the vm when the state was saved.
+	// Restoring the memory over a modified disk, e.g., by `limactl snapshot apply` or `limactl disk resize`,
+	// can corrupt the guest filesystem.
+	Disks []SavedDisk `json:"disks,omitempty"`
+}
+
+// SavedDisk identifies the content of a disk by its size and its modification time.
+type SavedDisk struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+}
+
+// NewSavedState creates the metadata of the vm state of the instance, which is being saved by the current version of Lima.
+func NewSavedState(instDir string, y *limayaml.LimaYAML, udpDNSLocalPort, tcpDNSLocalPort int) (*SavedState, error) {
+	yamlDigest, err := limaYAMLDigest(instDir)
+	if err != nil {
+		return nil, err
+	}
+	disks, err := savedDisks(instDir, y)
+	if err != nil {
+		return nil, err
+	}
+	return &SavedState{
+		LimaVersion:     version.Version,
+		Arch:            *y.Arch,
+		VMType:          *y.VMType,
+		LimaYAMLDigest:  yamlDigest,
+		UDPDNSLocalPort: udpDNSLocalPort,
+		TCPDNSLocalPort: tcpDNSLocalPort,
+		Disks:           disks,
+	}, nil
+}
+
+// savedDisks returns the base disk, the diff disk, and the additional disks of the instance.
+// The disks are expected not to be written after the vm state has been saved.
+func savedDisks(instDir string, y *limayaml.LimaYAML) ([]SavedDisk, error) {
+	paths := []string{
+		filepath.Join(instDir, filenames.BaseDisk),
+		filepath.Join(instDir, filenames.DiffDisk),
+	}
+	for _, d := range y.AdditionalDisks {
+		diskDir, err := DiskDir(d.Name)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, filepath.Join(diskDir, filenames.DataDisk))
+	}
+	var disks []SavedDisk
+	for _, p := range paths {
+		st, err := os.Stat(p)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		disks = append(disks, SavedDisk{Path: p, Size: st.Size(), ModTime: st.ModTime()})
+	}
+	return disks, nil
+}
+
+func limaYAMLDigest(instDir string) (digest.Digest, error) {
+	b, err := os.ReadFile(filepath.Join(instDir, filenames.LimaYAML))
+	if err != nil {
+		return "", err
+	}
+	return digest.FromBytes(b), nil
+}
+
+// Check returns an error if the saved state cannot be restored by the current version of Lima,
+// e.g., because lima.yaml has been modified since the state was saved.
+func (s *SavedState) Check(instDir string, y *limayaml.LimaYAML) error {
+	if s.LimaVersion != version.Version {
+		return fmt.Errorf("the state was saved by Lima %q, not by the current version %q", s.LimaVersion, version.Version)
+	}
+	if s.Arch != *y.Arch {
+		return fmt.Errorf("the state was saved for arch %q, not for %q", s.Arch, *y.Arch)
+	}
+	if s.VMType != *y.VMType {
+		return fmt.Errorf("the state was saved for vmType %q, not for %q", s.VMType, *y.VMType)
+	}
+	yamlDigest, err := limaYAMLDigest(instDir)
+	if err != nil {
+		return err
+	}
+	if s.LimaYAMLDigest != yamlDigest {
+		return fmt.Errorf("%s has been modified since the state was saved", filenames.LimaYAML)
+	}
+	disks, err := savedDisks(instDir, y)
+	if err != nil {
+		return err
+	}
+	if len(disks) != len(s.Disks) {
+		return errors.New("the disks have been added or removed since the state was saved")
+	}
+	for i, d := range disks {
+		saved := s.Disks[i]
+		if d.Path != saved.Path || d.Size != saved.Size || !d.ModTime.Equal(saved.ModTime) {
+			return fmt.Errorf("disk %q has been modified since the state was saved", d.Path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(instDir, filenames.SavedState)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ReadSavedState reads the savedstate.json file in the instance directory.
+// It returns nil without an error when the file does not exist.
+func ReadSavedState(instDir string) (*SavedState, error) {
+	b, err := os.ReadFile(filepath.Join(instDir, filenames.SavedStateJSON))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var s SavedState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// WriteSavedState writes the savedstate.json file in the instance directory.
+func WriteSavedState(instDir string, s *SavedState) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(instDir, filenames.SavedStateJSON), append(b, '\n'), 0o644)
+}
+
+// RemoveSavedState removes the saved vm state and its metadata in the instance directory.
+func RemoveSavedState(instDir string) error {
+	// Remove the metadata first, so that a partially removed state is never restored
+	return errors.Join(
+		os.RemoveAll(filepath.Join(instDir, filenames.SavedStateJSON)),
+		os.RemoveAll(filepath.Join(instDir, filenames.SavedState)),
+	)
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestSavedState(t *testing.T) {
+	instDir := t.TempDir()
+	s, err := ReadSavedState(instDir)
+	assert.NilError(t, err)
+	assert.Assert(t, s == nil)
+
+	y := &limayaml.LimaYAML{Arch: ptr.Of(limayaml.X8664), VMType: ptr.Of(limayaml.QEMU)}
+	yamlPath := filepath.Join(instDir, filenames.LimaYAML)
+	assert.NilError(t, os.WriteFile(yamlPath, []byte("cpus: 2\n"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.SavedState), []byte("state"), 0o644))
+	expected, err := NewSavedState(instDir, y, 10053, 10054)
+	assert.NilError(t, err)
+	assert.NilError(t, WriteSavedState(instDir, expected))
+	s, err = ReadSavedState(instDir)
+	assert.NilError(t, err)
+	assert.DeepEqual(t, s, expected)
+	assert.NilError(t, s.Check(instDir, y))
+
+	aarch64 := &limayaml.LimaYAML{Arch: ptr.Of(limayaml.AARCH64), VMType: ptr.Of(limayaml.QEMU)}
+	assert.ErrorContains(t, s.Check(instDir, aarch64), "saved for arch")
+	vz := &limayaml.LimaYAML{Arch: ptr.Of(limayaml.X8664), VMType: ptr.Of(limayaml.VZ)}
+	assert.ErrorContains(t, s.Check(instDir, vz), "saved for vmType")
+	older := *s
+	older.LimaVersion = "0.0.1"
+	assert.ErrorContains(t, older.Check(instDir, y), "saved by Lima \"0.0.1\"")
+
+	assert.NilError(t, os.WriteFile(yamlPath, []byte("cpus: 4\n"), 0o644))
+	assert.ErrorContains(t, s.Check(instDir, y), "has been modified")
+
+	assert.NilError(t, RemoveSavedState(instDir))
+	s, err = ReadSavedState(instDir)
+	assert.NilError(t, err)
+	assert.Assert(t, s == nil)
+	_, err = os.Stat(filepath.Join(instDir, filenames.SavedState))
+	assert.Assert(t, os.IsNotExist(err))
+}
+
+func TestSavedStateDisks(t *testing.T) {
+	t.Setenv("LIMA_HOME", t.TempDir())
+	instDir := t.TempDir()
+	diskDir, err := DiskDir("data")
+	assert.NilError(t, err)
+	assert.NilError(t, os.MkdirAll(diskDir, 0o755))
+	dataDisk := filepath.Join(diskDir, filenames.DataDisk)
+	assert.NilError(t, os.WriteFile(dataDisk, []byte("data"), 0o644))
+	diffDisk := filepath.Join(instDir, filenames.DiffDisk)
+	assert.NilError(t, os.WriteFile(diffDisk, []byte("diff"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.LimaYAML), []byte("cpus: 2\n"), 0o644))
+	assert.NilError(t, os.WriteFile(filepath.Join(instDir, filenames.SavedState), []byte("state"), 0o644))
+
+	y := &limayaml.LimaYAML{
+		Arch:            ptr.Of(limayaml.X8664),
+		VMType:          ptr.Of(limayaml.QEMU),
+		AdditionalDisks: []limayaml.Disk{{Name: "data"}},
+	}
+	s, err := NewSavedState(instDir, y, 0, 0)
+	assert.NilError(t, err)
+	assert.Equal(t, len(s.Disks), 2)
+	assert.NilError(t, WriteSavedState(instDir, s))
+	s, err = ReadSavedState(instDir)
+	assert.NilError(t, err)
+	assert.NilError(t, s.Check(instDir, y))
+
+	// e.g., `limactl disk resize`
+	assert.NilError(t, os.WriteFile(dataDisk, []byte("resized"), 0o644))
+	assert.ErrorContains(t, s.Check(instDir, y), fmt.Sprintf("disk %q has been modified", dataDisk))
+
+	s, err = NewSavedState(instDir, y, 0, 0)
+	assert.NilError(t, err)
+	assert.NilError(t, s.Check(instDir, y))
+	// e.g., `limactl snapshot apply`, which does not change the size
+	future := time.Now().Add(time.Hour)
+	assert.NilError(t, os.Chtimes(diffDisk, future, future))
+	assert.ErrorContains(t, s.Check(instDir, y), fmt.Sprintf("disk %q has been modified", diffDisk))
+
+	s, err = NewSavedState(instDir, y, 0, 0)
+	assert.NilError(t, err)
+	y.AdditionalDisks = nil
+	assert.ErrorContains(t, s.Check(instDir, y), "added or removed")
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+
+	"github.com/lima-vm/lima/pkg/sshutil"
+)
+
+// SSHOpts returns the ssh options (KEY=VALUE) for connecting to the instance, with the user and
+// the forwarding and keepalive options of lima.yaml.
+// opts are applied after the options of lima.yaml, so they take precedence.
+//
+// The options do not contain the address and the port of the instance.
+func (inst *Instance) SSHOpts(sshPath string, opts ...sshutil.SSHOptsOpt) ([]string, error) {
+	if inst.Config == nil {
+		return nil, errors.New("instance config is not loaded")
+	}
+	y := inst.Config
+	o := []sshutil.SSHOptsOpt{
+		sshutil.WithDotSSH(*y.SSH.LoadDotSSHPubKeys),
+		sshutil.WithForwardAgent(*y.SSH.ForwardAgent),
+		sshutil.WithForwardX11(*y.SSH.ForwardX11),
+		sshutil.WithForwardX11Trusted(*y.SSH.ForwardX11Trusted),
+		sshutil.WithKeepAlive(*y.SSH.KeepAlive.Interval, *y.SSH.KeepAlive.CountMax),
+	}
+	return sshutil.SSHOpts(sshPath, inst.Dir, *y.User.Name, append(o, opts...)...)
+}
+
+// SSHCommand returns the ssh command for connecting to the instance.
+// The ssh executable can be overridden with $SSH (see sshutil.SSHArguments).
+// opts are passed to SSHOpts, and extraArgs are appended after the destination, e.g., "--", "uname", "-a".
+func (inst *Instance) SSHCommand(ctx context.Context, opts []sshutil.SSHOptsOpt, extraArgs ...string) (*exec.Cmd, error) {
+	arg0, arg0Args, err := sshutil.SSHArguments()
+	if err != nil {
+		return nil, err
+	}
+	sshOpts, err := inst.SSHOpts(arg0, opts...)
+	if err != nil {
+		return nil, err
+	}
+	args := append(arg0Args, sshutil.SSHArgsFromOpts(sshOpts)...)
+	args = append(args, "-p", strconv.Itoa(inst.SSHLocalPort), inst.SSHAddress)
+	args = append(args, extraArgs...)
+	return exec.CommandContext(ctx, arg0, args...), nil
+}
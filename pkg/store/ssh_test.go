@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"github.com/lima-vm/lima/pkg/ptr"
+	"github.com/lima-vm/lima/pkg/sshutil"
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestSSHCommand(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	t.Setenv(sshutil.EnvShellSSH, "ssh")
+	assert.NilError(t, os.MkdirAll(filepath.Join(limaHome, "_config"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "_config", "user"), nil, 0o600))
+	inst := &Instance{
+		Dir:          filepath.Join(limaHome, "default"),
+		SSHLocalPort: 60022,
+		SSHAddress:   "127.0.0.1",
+		Config: &limayaml.LimaYAML{
+			User: limayaml.User{Name: ptr.Of("foo")},
+			SSH: limayaml.SSH{
+				LoadDotSSHPubKeys: ptr.Of(false),
+				ForwardAgent:      ptr.Of(true),
+				ForwardX11:        ptr.Of(false),
+				ForwardX11Trusted: ptr.Of(false),
+				KeepAlive: limayaml.SSHKeepAlive{
+					Interval: ptr.Of(30),
+					CountMax: ptr.Of(3),
+				},
+			},
+		},
+	}
+
+	cmd, err := inst.SSHCommand(context.Background(), []sshutil.SSHOptsOpt{sshutil.WithOptions("LogLevel=ERROR")}, "--", "uname", "-a")
+	assert.NilError(t, err)
+	assert.Equal(t, cmd.Args[0], "ssh")
+	assert.Assert(t, slices.Contains(cmd.Args, "User=foo"))
+	assert.Assert(t, slices.ContainsFunc(cmd.Args, func(arg string) bool {
+		return strings.HasPrefix(arg, "ControlPath=") && strings.Contains(arg, filenames.SSHSock)
+	}))
+	assert.Assert(t, slices.Contains(cmd.Args, "ForwardAgent=yes"))
+	assert.Assert(t, slices.Contains(cmd.Args, "ServerAliveInterval=30"))
+	assert.Assert(t, slices.Contains(cmd.Args, "LogLevel=ERROR"))
+	assert.DeepEqual(t, cmd.Args[len(cmd.Args)-6:], []string{"-p", "60022", "127.0.0.1", "--", "uname", "-a"})
+
+	// The options take precedence over lima.yaml
+	opts, err := inst.SSHOpts("ssh", sshutil.WithForwardAgent(false))
+	assert.NilError(t, err)
+	assert.Assert(t, !slices.Contains(opts, "ForwardAgent=yes"))
+
+	_, err = (&Instance{}).SSHOpts("ssh")
+	assert.ErrorContains(t, err, "not loaded")
+}
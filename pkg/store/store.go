@@ -2,8 +2,11 @@ package store
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"slices"
 	"strings"
 
 	"github.com/containerd/containerd/identifiers"
@@ -68,6 +71,48 @@ func Instances() ([]string, error) {
 	return names, nil
 }
 
+// InstancesFiltered inspects the instances under LimaDir, and returns the ones for which pred returns true.
+// pred may be nil to return all the instances.
+//
+// The instances that cannot be loaded are not skipped, but returned with StatusBroken and the errors,
+// so that pred can decide whether to include them.
+func InstancesFiltered(pred func(*Instance) bool) ([]*Instance, error) {
+	names, err := Instances()
+	if err != nil {
+		return nil, err
+	}
+	var res []*Instance
+	for _, name := range names {
+		inst, err := Inspect(name)
+		if err != nil {
+			if !errors.Is(err, ErrInstanceNotFound) {
+				return nil, err
+			}
+			// The directory exists but lima.yaml does not
+			instDir, dirErr := InstanceDir(name)
+			if dirErr != nil {
+				return nil, dirErr
+			}
+			inst = &Instance{Name: name, Dir: instDir, Status: StatusBroken, Errors: []error{err}}
+		}
+		if pred == nil || pred(inst) {
+			res = append(res, inst)
+		}
+	}
+	return res, nil
+}
+
+// InstancesByTag returns the instances that have the tag in `tags` of lima.yaml.
+// The instances that cannot be loaded are skipped, as their tags are unknown.
+func InstancesByTag(tag string) ([]*Instance, error) {
+	if err := limayaml.ValidateTag(tag); err != nil {
+		return nil, fmt.Errorf("invalid tag %q: %w", tag, err)
+	}
+	return InstancesFiltered(func(inst *Instance) bool {
+		return inst.Config != nil && slices.Contains(inst.Config.Tags, tag)
+	})
+}
+
 func Disks() ([]string, error) {
 	limaDiskDir, err := dirnames.LimaDisksDir()
 	if err != nil {
@@ -87,6 +132,32 @@ func Disks() ([]string, error) {
 	return names, nil
 }
 
+// MaxInstanceNameLength is the maximum length of an instance name, so that the hostname
+// ("lima-" + name) fits in a DNS label (63 characters).
+const MaxInstanceNameLength = 63 - len("lima-")
+
+// instanceNameRegex matches lowercase alphanumeric components separated by '.', '_', or '-'.
+var instanceNameRegex = regexp.MustCompile(`^[a-z0-9]+(?:[._-][a-z0-9]+)*$`)
+
+// ValidateInstanceName validates the name of a new instance.
+// The name is used in the hostname, the filenames, and the SSH config, so it must consist
+// of lowercase alphanumeric components separated by '.', '_', or '-'.
+// Uppercase letters are rejected, as hostnames are case-insensitive and so is the
+// filesystem of macOS by default.
+//
+// The existing instances created by older versions of Lima may have names that do not
+// satisfy these constraints; such names are still accepted by InstanceDir.
+func ValidateInstanceName(name string) error {
+	if len(name) > MaxInstanceNameLength {
+		return fmt.Errorf("invalid instance name %q: must not be longer than %d characters", name, MaxInstanceNameLength)
+	}
+	if !instanceNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid instance name %q: must match %q (lowercase alphanumeric components separated by '.', '_', or '-')",
+			name, instanceNameRegex.String())
+	}
+	return nil
+}
+
 // InstanceDir returns the instance dir.
 // InstanceDir does not check whether the instance exists.
 func InstanceDir(name string) (string, error) {
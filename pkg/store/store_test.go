@@ -0,0 +1,113 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/store/filenames"
+	"gotest.tools/v3/assert"
+)
+
+func TestValidateInstanceName(t *testing.T) {
+	for _, name := range []string{
+		"default",
+		"docker",
+		"k8s",
+		"ubuntu-24.04",
+		"foo_bar",
+		"0",
+		strings.Repeat("a", MaxInstanceNameLength),
+	} {
+		assert.NilError(t, ValidateInstanceName(name), name)
+	}
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{name: "", expected: "must match"},
+		{name: "Default", expected: "must match"},
+		{name: "UPPER", expected: "must match"},
+		{name: "foo bar", expected: "must match"},
+		{name: "foo/bar", expected: "must match"},
+		{name: "../foo", expected: "must match"},
+		{name: "-foo", expected: "must match"},
+		{name: "foo-", expected: "must match"},
+		{name: "foo..bar", expected: "must match"},
+		{name: "foo:bar", expected: "must match"},
+		{name: "föö", expected: "must match"},
+		{name: strings.Repeat("a", MaxInstanceNameLength+1), expected: "must not be longer than 58 characters"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorContains(t, ValidateInstanceName(tc.name), tc.expected)
+		})
+	}
+}
+
+func TestInstancesFiltered(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	// not an instance
+	assert.NilError(t, os.Mkdir(filepath.Join(limaHome, "_config"), 0o755))
+	// lima.yaml is missing
+	assert.NilError(t, os.Mkdir(filepath.Join(limaHome, "missing"), 0o755))
+	// lima.yaml is invalid
+	assert.NilError(t, os.Mkdir(filepath.Join(limaHome, "invalid"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "invalid", filenames.LimaYAML), []byte("cpus: [\n"), 0o644))
+
+	instances, err := InstancesFiltered(nil)
+	assert.NilError(t, err)
+	assert.Equal(t, len(instances), 2)
+	for _, inst := range instances {
+		assert.Equal(t, inst.Status, StatusBroken, inst.Name)
+		assert.Assert(t, len(inst.Errors) > 0, inst.Name)
+	}
+
+	instances, err = InstancesFiltered(func(inst *Instance) bool {
+		return inst.Status == StatusRunning
+	})
+	assert.NilError(t, err)
+	assert.Equal(t, len(instances), 0)
+}
+
+func TestInstancesByTag(t *testing.T) {
+	limaHome := t.TempDir()
+	t.Setenv("LIMA_HOME", limaHome)
+	for name, tags := range map[string]string{
+		"foo": "[ci, team-a]",
+		"bar": "[team-a]",
+		"baz": "[]",
+	} {
+		assert.NilError(t, os.Mkdir(filepath.Join(limaHome, name), 0o755))
+		y := "images: [{location: /dummy.img}]\nuser: {uid: 1000}\ntags: " + tags + "\n"
+		assert.NilError(t, os.WriteFile(filepath.Join(limaHome, name, filenames.LimaYAML), []byte(y), 0o644))
+	}
+	// lima.yaml is invalid
+	assert.NilError(t, os.Mkdir(filepath.Join(limaHome, "invalid"), 0o755))
+	assert.NilError(t, os.WriteFile(filepath.Join(limaHome, "invalid", filenames.LimaYAML), []byte("tags: [\n"), 0o644))
+
+	names := func(instances []*Instance) []string {
+		var res []string
+		for _, inst := range instances {
+			res = append(res, inst.Name)
+		}
+		return res
+	}
+	instances, err := InstancesByTag("team-a")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names(instances), []string{"bar", "foo"})
+
+	instances, err = InstancesByTag("ci")
+	assert.NilError(t, err)
+	assert.DeepEqual(t, names(instances), []string{"foo"})
+
+	instances, err = InstancesByTag("team-b")
+	assert.NilError(t, err)
+	assert.Equal(t, len(instances), 0)
+
+	_, err = InstancesByTag("Team-A")
+	assert.ErrorContains(t, err, "invalid tag")
+}
@@ -0,0 +1,106 @@
+// Package tracing provides the OpenTelemetry spans of the start flow, so that the startup latency
+// (e.g., downloading the image vs creating the disk vs waiting for cloud-init) can be profiled.
+//
+// The tracing is enabled by setting $OTEL_EXPORTER_OTLP_ENDPOINT or $OTEL_EXPORTER_OTLP_TRACES_ENDPOINT,
+// e.g., "http://localhost:4318". The spans are exported with OTLP over HTTP, configured with the standard
+// $OTEL_EXPORTER_OTLP_* and $OTEL_* environment variables.
+// When the tracing is disabled, the global no-op tracer is used and no span is recorded.
+package tracing
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/lima-vm/lima/pkg/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/lima-vm/lima"
+
+// propagationKeys are the keys of the W3C trace context, propagated to the child processes
+// as the upper-cased environment variables (e.g., $TRACEPARENT).
+var propagationKeys = []string{"traceparent", "tracestate"}
+
+// Enabled returns true when an OTLP endpoint is configured.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init sets up the global tracer provider when Enabled returns true.
+// The returned function flushes the pending spans, and has to be called before the process exits.
+func Init(ctx context.Context, serviceName string) (shutdown func(context.Context) error, err error) {
+	shutdown = func(context.Context) error { return nil }
+	if !Enabled() {
+		return shutdown, nil
+	}
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return shutdown, err
+	}
+	// $OTEL_SERVICE_NAME and $OTEL_RESOURCE_ATTRIBUTES take precedence over the attributes here
+	res, err := resource.New(ctx,
+		resource.WithAttributes(
+			attribute.String("service.name", serviceName),
+			attribute.String("service.version", version.Version),
+		),
+		resource.WithTelemetrySDK(),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return shutdown, err
+	}
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	return tp.Shutdown, nil
+}
+
+// Start starts a span as a child of the span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends the span, marking it as failed when err is not nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Environ returns the environment variables that propagate the span in ctx to a child process,
+// e.g., "TRACEPARENT=00-...". Nothing is returned when the tracing is disabled.
+func Environ(ctx context.Context) []string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	var env []string
+	for _, k := range propagationKeys {
+		if v := carrier.Get(k); v != "" {
+			env = append(env, strings.ToUpper(k)+"="+v)
+		}
+	}
+	return env
+}
+
+// ContextFromEnv returns ctx with the span propagated from the parent process by Environ.
+func ContextFromEnv(ctx context.Context) context.Context {
+	carrier := propagation.MapCarrier{}
+	for _, k := range propagationKeys {
+		if v := os.Getenv(strings.ToUpper(k)); v != "" {
+			carrier.Set(k, v)
+		}
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
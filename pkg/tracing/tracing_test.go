@@ -0,0 +1,61 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	"gotest.tools/v3/assert"
+)
+
+func TestDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	assert.Assert(t, !Enabled())
+	shutdown, err := Init(context.Background(), "limactl")
+	assert.NilError(t, err)
+	assert.NilError(t, shutdown(context.Background()))
+
+	ctx, span := Start(context.Background(), "foo")
+	assert.Assert(t, !span.IsRecording())
+	assert.Equal(t, len(Environ(ctx)), 0)
+	End(span, nil)
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+		otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator())
+	})
+
+	ctx, parent := Start(context.Background(), "parent")
+	env := Environ(ctx)
+	assert.Equal(t, len(env), 1)
+	k, v, _ := strings.Cut(env[0], "=")
+	assert.Equal(t, k, "TRACEPARENT")
+	t.Setenv(k, v)
+
+	// simulates the child process
+	_, child := Start(ContextFromEnv(context.Background()), "child")
+	End(child, errors.New("failed"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	assert.Equal(t, len(spans), 2)
+	assert.Equal(t, spans[0].Name(), "child")
+	assert.Equal(t, spans[0].Parent().SpanID(), parent.SpanContext().SpanID())
+	assert.Equal(t, spans[0].Status().Code, codes.Error)
+	assert.Equal(t, spans[1].Name(), "parent")
+	assert.Equal(t, spans[1].Status().Code, codes.Unset)
+}
@@ -0,0 +1,122 @@
+package usrlocalsharelima
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	xzMagic   = []byte{0xfd, 0x37, 0x7a, 0x58, 0x5a, 0x00}
+)
+
+// openDecompressed opens the file, and decompresses it when it is compressed with gzip or xz.
+// The format is detected by the magic bytes, not by the extension.
+// xz is decompressed with the xz command, as the Go standard library does not support xz.
+func openDecompressed(p string) (io.ReadCloser, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(f)
+	// Peek returns a short slice with io.EOF for a file shorter than the magic bytes
+	header, err := br.Peek(len(xzMagic))
+	if err != nil && !errors.Is(err, io.EOF) {
+		f.Close()
+		return nil, err
+	}
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		logrus.Debugf("Decompressing %q with gzip", p)
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to decompress %q: %w", p, err)
+		}
+		return &readCloser{Reader: zr, closers: []io.Closer{zr, f}}, nil
+	case bytes.HasPrefix(header, xzMagic):
+		logrus.Debugf("Decompressing %q with xz", p)
+		r, err := newXZReader(br, p)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: r, closers: []io.Closer{r, f}}, nil
+	default:
+		return &readCloser{Reader: br, closers: []io.Closer{f}}, nil
+	}
+}
+
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (r *readCloser) Close() error {
+	var errs []error
+	for _, c := range r.closers {
+		errs = append(errs, c.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// xzReader reads the output of `xz -d`.
+// The failure of xz is returned by Read instead of io.EOF, so that a corrupted binary is not silently truncated.
+type xzReader struct {
+	cmd      *exec.Cmd
+	stdout   io.ReadCloser
+	stderr   bytes.Buffer
+	p        string
+	waitOnce sync.Once
+	waitErr  error
+}
+
+func newXZReader(r io.Reader, p string) (*xzReader, error) {
+	xr := &xzReader{p: p}
+	xr.cmd = exec.Command("xz", "-d") // -d --decompress
+	xr.cmd.Stdin = r
+	xr.cmd.Stderr = &xr.stderr
+	var err error
+	xr.stdout, err = xr.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := xr.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to decompress %q (hint: install xz): %w", p, err)
+	}
+	return xr, nil
+}
+
+func (xr *xzReader) wait() error {
+	xr.waitOnce.Do(func() {
+		if err := xr.cmd.Wait(); err != nil {
+			xr.waitErr = fmt.Errorf("failed to decompress %q: %w (stderr=%q)", xr.p, err, xr.stderr.String())
+		}
+	})
+	return xr.waitErr
+}
+
+func (xr *xzReader) Read(p []byte) (int, error) {
+	n, err := xr.stdout.Read(p)
+	if errors.Is(err, io.EOF) {
+		if waitErr := xr.wait(); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	return n, err
+}
+
+func (xr *xzReader) Close() error {
+	// Unblocks xz when the output is not fully read
+	_ = xr.stdout.Close()
+	return xr.wait()
+}
@@ -3,6 +3,7 @@ package usrlocalsharelima
 import (
 	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
@@ -61,15 +62,12 @@ func Dir() (string, error) {
 		logrus.Infof("debug mode detected, adding more guest agent candidates: %v", candidateForDebugBuild)
 	}
 	for _, gaCandidate := range gaCandidates {
-		if _, err := os.Stat(gaCandidate); err == nil {
-			return filepath.Dir(gaCandidate), nil
-		} else if !errors.Is(err, os.ErrNotExist) {
-			return "", err
-		}
-		if _, err := os.Stat(gaCandidate + ".gz"); err == nil {
-			return filepath.Dir(gaCandidate), nil
-		} else if !errors.Is(err, os.ErrNotExist) {
-			return "", err
+		for _, ext := range guestAgentBinaryExts {
+			if _, err := os.Stat(gaCandidate + ext); err == nil {
+				return filepath.Dir(gaCandidate), nil
+			} else if !errors.Is(err, os.ErrNotExist) {
+				return "", err
+			}
 		}
 	}
 
@@ -77,6 +75,12 @@ func Dir() (string, error) {
 		ostype, arch, self, gaCandidates)
 }
 
+// guestAgentBinaryExts are the extensions of the guest agent binary, probed in this order.
+// The compressed binaries are decompressed by OpenGuestAgentBinary.
+var guestAgentBinaryExts = []string{"", ".gz", ".xz"}
+
+// GuestAgentBinary returns the path of the guest agent binary, which may be compressed (".gz" or ".xz").
+// When no binary exists, the path of the uncompressed binary is returned without an error.
 func GuestAgentBinary(ostype limayaml.OS, arch limayaml.Arch) (string, error) {
 	if ostype == "" {
 		return "", errors.New("os must be set")
@@ -88,5 +92,27 @@ func GuestAgentBinary(ostype limayaml.OS, arch limayaml.Arch) (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return filepath.Join(dir, "lima-guestagent."+ostype+"-"+arch), nil
+	return findGuestAgentBinary(dir, ostype, arch)
+}
+
+func findGuestAgentBinary(dir string, ostype limayaml.OS, arch limayaml.Arch) (string, error) {
+	p := filepath.Join(dir, "lima-guestagent."+ostype+"-"+arch)
+	for _, ext := range guestAgentBinaryExts {
+		if _, err := os.Stat(p + ext); err == nil {
+			return p + ext, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+	}
+	return p, nil
+}
+
+// OpenGuestAgentBinary opens the guest agent binary.
+// A compressed binary is transparently decompressed, with the format detected by the magic bytes.
+func OpenGuestAgentBinary(ostype limayaml.OS, arch limayaml.Arch) (io.ReadCloser, error) {
+	p, err := GuestAgentBinary(ostype, arch)
+	if err != nil {
+		return nil, err
+	}
+	return openDecompressed(p)
 }
@@ -0,0 +1,109 @@
+package usrlocalsharelima
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/lima-vm/lima/pkg/limayaml"
+	"gotest.tools/v3/assert"
+)
+
+var fakeGuestAgent = []byte("\x7fELF fake lima-guestagent")
+
+func gzipped(t *testing.T, b []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(b)
+	assert.NilError(t, err)
+	assert.NilError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func readAll(t *testing.T, p string) []byte {
+	t.Helper()
+	r, err := openDecompressed(p)
+	assert.NilError(t, err)
+	b, err := io.ReadAll(r)
+	assert.NilError(t, err)
+	assert.NilError(t, r.Close())
+	return b
+}
+
+func TestFindGuestAgentBinary(t *testing.T) {
+	dir := t.TempDir()
+	base := filepath.Join(dir, "lima-guestagent.Linux-x86_64")
+
+	p, err := findGuestAgentBinary(dir, limayaml.LINUX, limayaml.X8664)
+	assert.NilError(t, err)
+	assert.Equal(t, p, base)
+
+	assert.NilError(t, os.WriteFile(base+".xz", nil, 0o644))
+	p, err = findGuestAgentBinary(dir, limayaml.LINUX, limayaml.X8664)
+	assert.NilError(t, err)
+	assert.Equal(t, p, base+".xz")
+
+	// .gz is preferred over .xz, as it does not need the xz command
+	assert.NilError(t, os.WriteFile(base+".gz", nil, 0o644))
+	p, err = findGuestAgentBinary(dir, limayaml.LINUX, limayaml.X8664)
+	assert.NilError(t, err)
+	assert.Equal(t, p, base+".gz")
+
+	assert.NilError(t, os.WriteFile(base, nil, 0o755))
+	p, err = findGuestAgentBinary(dir, limayaml.LINUX, limayaml.X8664)
+	assert.NilError(t, err)
+	assert.Equal(t, p, base)
+}
+
+func TestOpenDecompressed(t *testing.T) {
+	dir := t.TempDir()
+
+	plain := filepath.Join(dir, "plain")
+	assert.NilError(t, os.WriteFile(plain, fakeGuestAgent, 0o755))
+	assert.DeepEqual(t, readAll(t, plain), fakeGuestAgent)
+
+	gz := filepath.Join(dir, "lima-guestagent.Linux-x86_64.gz")
+	assert.NilError(t, os.WriteFile(gz, gzipped(t, fakeGuestAgent), 0o644))
+	assert.DeepEqual(t, readAll(t, gz), fakeGuestAgent)
+
+	// The format is detected by the magic bytes, not by the extension
+	misnamed := filepath.Join(dir, "lima-guestagent.Linux-x86_64.xz")
+	assert.NilError(t, os.WriteFile(misnamed, gzipped(t, fakeGuestAgent), 0o644))
+	assert.DeepEqual(t, readAll(t, misnamed), fakeGuestAgent)
+
+	// Shorter than the magic bytes
+	short := filepath.Join(dir, "short")
+	assert.NilError(t, os.WriteFile(short, []byte{0x1f}, 0o644))
+	assert.DeepEqual(t, readAll(t, short), []byte{0x1f})
+
+	empty := filepath.Join(dir, "empty")
+	assert.NilError(t, os.WriteFile(empty, nil, 0o644))
+	assert.Equal(t, len(readAll(t, empty)), 0)
+}
+
+func TestOpenDecompressedXZ(t *testing.T) {
+	if _, err := exec.LookPath("xz"); err != nil {
+		t.Skip("xz is not installed")
+	}
+	dir := t.TempDir()
+	xz := filepath.Join(dir, "lima-guestagent.Linux-x86_64.xz")
+	cmd := exec.Command("xz", "-c")
+	cmd.Stdin = bytes.NewReader(fakeGuestAgent)
+	out, err := cmd.Output()
+	assert.NilError(t, err)
+	assert.NilError(t, os.WriteFile(xz, out, 0o644))
+	assert.DeepEqual(t, readAll(t, xz), fakeGuestAgent)
+
+	// A corrupted archive is an error, not a truncated binary
+	assert.NilError(t, os.WriteFile(xz, out[:len(out)-8], 0o644))
+	r, err := openDecompressed(xz)
+	assert.NilError(t, err)
+	_, err = io.ReadAll(r)
+	assert.ErrorContains(t, err, "failed to decompress")
+	_ = r.Close()
+}
@@ -12,7 +12,9 @@ import (
 	"github.com/lima-vm/lima/pkg/fileutils"
 	"github.com/lima-vm/lima/pkg/iso9660util"
 	"github.com/lima-vm/lima/pkg/nativeimgutil"
+	"github.com/lima-vm/lima/pkg/store"
 	"github.com/lima-vm/lima/pkg/store/filenames"
+	"github.com/sirupsen/logrus"
 )
 
 func EnsureDisk(ctx context.Context, driver *driver.BaseDriver) error {
@@ -30,7 +32,8 @@ func EnsureDisk(ctx context.Context, driver *driver.BaseDriver) error {
 		var ensuredBaseDisk bool
 		errs := make([]error, len(driver.Instance.Config.Images))
 		for i, f := range driver.Instance.Config.Images {
-			if _, err := fileutils.DownloadFile(ctx, baseDisk, f.File, true, "the image", *driver.Instance.Config.Arch); err != nil {
+			res, err := fileutils.Download(ctx, baseDisk, f.File, true, "the image", *driver.Instance.Config.Arch)
+			if err != nil {
 				errs[i] = err
 				continue
 			}
@@ -53,6 +56,9 @@ func EnsureDisk(ctx context.Context, driver *driver.BaseDriver) error {
 					continue
 				}
 			}
+			if err := store.WriteProvenanceImage(driver.Instance.Dir, &store.Artifact{Location: f.Location, Digest: res.Digest}); err != nil {
+				logrus.WithError(err).Warn("Failed to record the image in the provenance")
+			}
 			ensuredBaseDisk = true
 			break
 		}
@@ -60,7 +66,7 @@ func EnsureDisk(ctx context.Context, driver *driver.BaseDriver) error {
 			return fileutils.Errors(errs)
 		}
 	}
-	diskSize, _ := units.RAMInBytes(*driver.Instance.Config.Disk)
+	diskSize, _ := units.RAMInBytes(*driver.Instance.Config.Disk.Size)
 	if diskSize == 0 {
 		return nil
 	}
@@ -435,6 +435,9 @@ func attachDisks(driver *driver.BaseDriver, vmConfig *vz.VirtualMachineConfigura
 	baseDiskPath := filepath.Join(driver.Instance.Dir, filenames.BaseDisk)
 	diffDiskPath := filepath.Join(driver.Instance.Dir, filenames.DiffDisk)
 	ciDataPath := filepath.Join(driver.Instance.Dir, filenames.CIDataISO)
+	if *driver.Instance.Config.CIData.VFAT {
+		ciDataPath = filepath.Join(driver.Instance.Dir, filenames.CIDataVFAT)
+	}
 	isBaseDiskCDROM, err := iso9660util.IsISO9660(baseDiskPath)
 	if err != nil {
 		return err
@@ -475,13 +478,27 @@ func attachDisks(driver *driver.BaseDriver, vmConfig *vz.VirtualMachineConfigura
 			return fmt.Errorf("failed to run load disk %q: %w", diskName, err)
 		}
 
-		if disk.Instance != "" {
-			return fmt.Errorf("failed to run attach disk %q, in use by instance %q", diskName, disk.Instance)
-		}
-		logrus.Infof("Mounting disk %q on %q", diskName, disk.MountPoint)
-		err = disk.Lock(driver.Instance.Dir)
-		if err != nil {
-			return fmt.Errorf("failed to run lock disk %q: %w", diskName, err)
+		readOnly := d.ReadOnly != nil && *d.ReadOnly
+		if readOnly {
+			// A read-only disk can be shared across instances, but it must not be
+			// attached while another instance may write to it.
+			if disk.Instance != "" && disk.InstanceDir != driver.Instance.Dir {
+				return fmt.Errorf("failed to run attach disk %q as read-only, in use by instance %q", diskName, disk.Instance)
+			}
+			logrus.Infof("Mounting disk %q on %q (read-only)", diskName, disk.MountPoint)
+			err = disk.LockReadOnly(driver.Instance.Dir)
+			if err != nil {
+				return fmt.Errorf("failed to run lock disk %q as read-only: %w", diskName, err)
+			}
+		} else {
+			if disk.Instance != "" {
+				return fmt.Errorf("failed to run attach disk %q, in use by instance %q", diskName, disk.Instance)
+			}
+			logrus.Infof("Mounting disk %q on %q", diskName, disk.MountPoint)
+			err = disk.Lock(driver.Instance.Dir)
+			if err != nil {
+				return fmt.Errorf("failed to run lock disk %q: %w", diskName, err)
+			}
 		}
 		extraDiskPath := filepath.Join(disk.Dir, filenames.DataDisk)
 		// ConvertToRaw is a NOP if no conversion is needed
@@ -489,7 +506,7 @@ func attachDisks(driver *driver.BaseDriver, vmConfig *vz.VirtualMachineConfigura
 		if err = nativeimgutil.ConvertToRaw(extraDiskPath, extraDiskPath, nil, true); err != nil {
 			return fmt.Errorf("failed to convert extra disk %q to a raw disk: %w", extraDiskPath, err)
 		}
-		extraDiskPathAttachment, err := vz.NewDiskImageStorageDeviceAttachmentWithCacheAndSync(extraDiskPath, false, diskImageCachingMode, vz.DiskImageSynchronizationModeFsync)
+		extraDiskPathAttachment, err := vz.NewDiskImageStorageDeviceAttachmentWithCacheAndSync(extraDiskPath, readOnly, diskImageCachingMode, vz.DiskImageSynchronizationModeFsync)
 		if err != nil {
 			return fmt.Errorf("failed to create disk attachment for extra disk %q: %w", extraDiskPath, err)
 		}
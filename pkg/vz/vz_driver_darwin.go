@@ -24,6 +24,7 @@ var knownYamlProperties = []string{
 	"Arch",
 	"Audio",
 	"CACertificates",
+	"CIData",
 	"Containerd",
 	"CopyToHost",
 	"CPUs",
@@ -42,6 +43,7 @@ var knownYamlProperties = []string{
 	"MountType",
 	"MountTypesUnsupported",
 	"MountInotify",
+	"MTU",
 	"NestedVirtualization",
 	"Networks",
 	"OS",
@@ -52,6 +54,7 @@ var knownYamlProperties = []string{
 	"PropagateProxyEnv",
 	"Provision",
 	"Rosetta",
+	"SocketForwards",
 	"SSH",
 	"TimeZone",
 	"UpgradePackages",
@@ -131,6 +134,7 @@ func (l *LimaVzDriver) Validate() error {
 			"Socket",
 			"MACAddress",
 			"Metric",
+			"MTU",
 			"Interface",
 		); len(unknown) > 0 {
 			logrus.Warnf("vmType %s: ignoring networks[%d]: %+v", *l.Instance.Config.VMType, i, unknown)
@@ -192,7 +196,11 @@ func (l *LimaVzDriver) RunGUI() error {
 	return fmt.Errorf("RunGUI is not supported for the given driver '%s' and display '%s'", "vz", *l.Instance.Config.Video.Display)
 }
 
-func (l *LimaVzDriver) Stop(_ context.Context) error {
+func (l *LimaVzDriver) Stop(ctx context.Context, force bool) error {
+	if force {
+		logrus.Info("Forcibly stopping VZ")
+		return l.machine.Stop()
+	}
 	logrus.Info("Shutting down VZ")
 	canStop := l.machine.CanRequestStop()
 
@@ -202,11 +210,22 @@ func (l *LimaVzDriver) Stop(_ context.Context) error {
 			return err
 		}
 
-		timeout := time.After(5 * time.Second)
+		timeoutDuration := 5 * time.Second
+		// The deadline is only set by `limactl stop --timeout`
+		deadline, hasDeadline := ctx.Deadline()
+		if hasDeadline {
+			timeoutDuration = time.Until(deadline)
+		}
+		timeout := time.After(timeoutDuration)
 		ticker := time.NewTicker(500 * time.Millisecond)
+		defer ticker.Stop()
 		for {
 			select {
 			case <-timeout:
+				if hasDeadline && l.machine.CanStop() {
+					logrus.Warnf("VZ did not stop in %v, forcibly stopping VZ (forced stop)", timeoutDuration)
+					return l.machine.Stop()
+				}
 				return errors.New("vz timeout while waiting for stop status")
 			case <-ticker.C:
 				l.machine.mu.Lock()
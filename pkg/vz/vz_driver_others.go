@@ -35,6 +35,6 @@ func (l *LimaVzDriver) Start(_ context.Context) (chan error, error) {
 	return nil, ErrUnsupported
 }
 
-func (l *LimaVzDriver) Stop(_ context.Context) error {
+func (l *LimaVzDriver) Stop(_ context.Context, _ bool) error {
 	return ErrUnsupported
 }
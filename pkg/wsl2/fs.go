@@ -8,6 +8,7 @@ import (
 
 	"github.com/lima-vm/lima/pkg/driver"
 	"github.com/lima-vm/lima/pkg/fileutils"
+	"github.com/lima-vm/lima/pkg/store"
 	"github.com/lima-vm/lima/pkg/store/filenames"
 	"github.com/sirupsen/logrus"
 )
@@ -19,10 +20,14 @@ func EnsureFs(ctx context.Context, driver *driver.BaseDriver) error {
 		var ensuredBaseDisk bool
 		errs := make([]error, len(driver.Instance.Config.Images))
 		for i, f := range driver.Instance.Config.Images {
-			if _, err := fileutils.DownloadFile(ctx, baseDisk, f.File, true, "the image", *driver.Instance.Config.Arch); err != nil {
+			res, err := fileutils.Download(ctx, baseDisk, f.File, true, "the image", *driver.Instance.Config.Arch)
+			if err != nil {
 				errs[i] = err
 				continue
 			}
+			if err := store.WriteProvenanceImage(driver.Instance.Dir, &store.Artifact{Location: f.Location, Digest: res.Digest}); err != nil {
+				logrus.WithError(err).Warn("Failed to record the image in the provenance")
+			}
 			ensuredBaseDisk = true
 			break
 		}
@@ -35,6 +35,6 @@ func (l *LimaWslDriver) Start(_ context.Context) (chan error, error) {
 	return nil, ErrUnsupported
 }
 
-func (l *LimaWslDriver) Stop(_ context.Context) error {
+func (l *LimaWslDriver) Stop(_ context.Context, _ bool) error {
 	return ErrUnsupported
 }
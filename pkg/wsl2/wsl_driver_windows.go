@@ -35,6 +35,7 @@ var knownYamlProperties = []string{
 	"Probes",
 	"PropagateProxyEnv",
 	"Provision",
+	"SocketForwards",
 	"SSH",
 	"VMType",
 }
@@ -152,7 +153,8 @@ func (l *LimaWslDriver) RunGUI() error {
 	return fmt.Errorf("RunGUI is not supported for the given driver '%s' and display '%s'", "wsl", *l.Instance.Config.Video.Display)
 }
 
-func (l *LimaWslDriver) Stop(ctx context.Context) error {
+// Stop terminates the WSL2 VM. WSL2 does not support graceful shutdown, so force is ignored.
+func (l *LimaWslDriver) Stop(ctx context.Context, _ bool) error {
 	logrus.Info("Shutting down WSL2 VM")
 	distroName := "lima-" + l.Instance.Name
 	return stopVM(ctx, distroName)
@@ -92,6 +92,12 @@ func EvaluateExpression(expression string, content []byte) ([]byte, error) {
 	return formatter.Format([]byte(out))
 }
 
+// ExpandAliases expands the anchors, aliases, and merge keys (`<<:`) in the content yaml,
+// so that the content is decoded in the same way regardless of the YAML library.
+func ExpandAliases(content []byte) ([]byte, error) {
+	return EvaluateExpression("explode(.)", content)
+}
+
 func Join(yqExprs []string) string {
 	if len(yqExprs) == 0 {
 		return ""
@@ -120,3 +120,24 @@ foo:
 	assert.NilError(t, err)
 	assert.Equal(t, strings.TrimSpace(expected), strings.TrimSpace(string(out)))
 }
+
+func TestExpandAliases(t *testing.T) {
+	content := `
+base: &base
+  a: 1
+  b: 2
+derived:
+  <<: *base
+  b: 3
+`
+	out, err := ExpandAliases([]byte(content))
+	assert.NilError(t, err)
+	t.Log(string(out))
+	assert.Assert(t, !strings.Contains(string(out), "<<"))
+	assert.Assert(t, !strings.Contains(string(out), "*base"))
+	assert.Assert(t, !strings.Contains(string(out), "&base"))
+	derived := string(out)[strings.Index(string(out), "derived:"):]
+	assert.Assert(t, strings.Contains(derived, "a: 1"))
+	assert.Assert(t, strings.Contains(derived, "b: 3"))
+	assert.Assert(t, !strings.Contains(derived, "b: 2"))
+}